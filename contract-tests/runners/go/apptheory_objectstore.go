@@ -183,8 +183,10 @@ func objectStoreErrorJSON(err error) map[string]string {
 }
 
 func forbiddenObjectStoreOperationError(fake *storetest.FakeStore, operation string) error {
+	// Store.List is the bounded, paginated listing contract; the fixture guards
+	// against raw S3 listing and multipart escape hatches.
 	methodNames := map[string][]string{
-		"list":      {"List", "ListObjects"},
+		"list":      {"ListObjects", "ListObjectsV2"},
 		"presign":   {"Presign", "PresignGet", "PresignPut", "PublicURL"},
 		"multipart": {"Multipart", "CreateMultipartUpload", "UploadPart", "CompleteMultipartUpload", "AbortMultipartUpload"},
 	}
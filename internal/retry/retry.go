@@ -0,0 +1,39 @@
+// Package retry holds the retry delay helpers shared by AppTheory packages.
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Sleep waits for delay or until ctx is done, returning ctx.Err() in the
+// latter case. A non-positive delay only checks ctx.
+func Sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// JitteredBackoff returns a delay drawn from the upper half of the window
+// base<<exponent, capped at maxDelay, so concurrent callers spread out without
+// retrying early.
+func JitteredBackoff(base, maxDelay time.Duration, exponent int) time.Duration {
+	delay := maxDelay
+	if exponent < 30 {
+		delay = min(base<<exponent, maxDelay)
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half+1) // #nosec G404 -- jitter does not need a secure source
+}
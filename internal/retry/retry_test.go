@@ -0,0 +1,47 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSleep(t *testing.T) {
+	t.Parallel()
+
+	if err := Sleep(context.Background(), time.Millisecond); err != nil {
+		t.Fatalf("Sleep() error = %v", err)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, delay := range []time.Duration{0, time.Hour} {
+		if err := Sleep(cancelled, delay); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Sleep(%s) error = %v, want context.Canceled", delay, err)
+		}
+	}
+}
+
+func TestJitteredBackoff(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		exponent int
+		low      time.Duration
+		high     time.Duration
+	}{
+		{exponent: 0, low: 50 * time.Millisecond, high: 100 * time.Millisecond},
+		{exponent: 2, low: 200 * time.Millisecond, high: 400 * time.Millisecond},
+		{exponent: 10, low: 500 * time.Millisecond, high: time.Second},
+		{exponent: 64, low: 500 * time.Millisecond, high: time.Second},
+	} {
+		for range 20 {
+			if got := JitteredBackoff(100*time.Millisecond, time.Second, tc.exponent); got < tc.low || got > tc.high {
+				t.Fatalf("JitteredBackoff(%d) = %s, want within [%s, %s]", tc.exponent, got, tc.low, tc.high)
+			}
+		}
+	}
+	if got := JitteredBackoff(0, 0, 3); got != 0 {
+		t.Fatalf("JitteredBackoff() with zero delays = %s, want 0", got)
+	}
+}
@@ -0,0 +1,209 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrLeaseHeld is returned by RunPaged when another owner holds the job's
+// lease, either when the run starts or when it refreshes the lease before a
+// page.
+var ErrLeaseHeld = errors.New("job lease is held by another owner")
+
+// PagedJobConfig configures RunPaged.
+//
+// Ledger, JobID, TenantID, and Owner identify the job and the lease holder.
+// Name prefixes the page checkpoint keys, "<Name>/page/<page>", so jobs
+// sharing a JobID do not collide. MaxPages of zero means no limit, and a run
+// stops once its context is within DeadlineMargin of its deadline.
+// CheckpointTTL bounds how long page checkpoints survive and must outlast the
+// whole job.
+type PagedJobConfig struct {
+	Ledger         JobLedger
+	Name           string
+	JobID          string
+	TenantID       string
+	Owner          string
+	MaxPages       int
+	LeaseDuration  time.Duration
+	DeadlineMargin time.Duration
+	CheckpointTTL  time.Duration
+}
+
+// PageCheckpoint is the position one page leaves for the next. State values
+// are strings because the ledger stores results sanitized for logging, and
+// the "done" key is reserved for Done.
+type PageCheckpoint struct {
+	State map[string]string
+	Done  bool
+}
+
+// PageFunc processes page, starting from the checkpoint the previous page
+// left (empty for page zero), and returns the checkpoint for the next page.
+type PageFunc func(ctx context.Context, page int, at PageCheckpoint) (PageCheckpoint, error)
+
+// PagedJobReport describes one RunPaged call. Pages counts the pages this run
+// processed and Replayed the pages an earlier run had already checkpointed.
+// NextPage is where the next run should start.
+type PagedJobReport struct {
+	Pages    int
+	Replayed int
+	NextPage int
+	Done     bool
+}
+
+// RunPaged drives a job one page at a time, checkpointing each page as an
+// idempotency record whose result holds the checkpoint for the next page, so
+// a run cut short by MaxPages or a Lambda deadline resumes where it stopped.
+//
+// The job is created RUNNING if it does not exist, a lease keeps two runs of
+// the same job from interleaving, and the job moves to SUCCEEDED after the
+// last page. startPage resumes from the page after a completed checkpoint,
+// typically the previous report's NextPage; zero walks the checkpoints from
+// the beginning. A page that was in flight when a run died is processed
+// again, so process must be idempotent.
+func RunPaged(ctx context.Context, cfg PagedJobConfig, startPage int, process PageFunc) (PagedJobReport, error) {
+	report := PagedJobReport{NextPage: startPage}
+	if cfg.Ledger == nil || process == nil {
+		return report, NewError(ErrorTypeInvalidInput, "paged job requires a ledger and a page function")
+	}
+	if startPage < 0 {
+		return report, NewError(ErrorTypeInvalidInput, "start page cannot be negative")
+	}
+	if err := startPagedJob(ctx, cfg); err != nil {
+		return report, err
+	}
+	defer func() {
+		// An unreleased lease expires on its own after LeaseDuration.
+		_ = cfg.Ledger.ReleaseLease(context.WithoutCancel(ctx), ReleaseLeaseInput{JobID: cfg.JobID, Owner: cfg.Owner})
+	}()
+
+	at, err := resumePagedJob(ctx, cfg, startPage)
+	if err != nil {
+		return report, err
+	}
+	report.Done = at.Done
+	for !report.Done && hasPageBudget(ctx, cfg, report.Pages) {
+		next, replayed, err := runPage(ctx, cfg, report.NextPage, at, process)
+		if err != nil {
+			return report, err
+		}
+		if replayed {
+			report.Replayed++
+		} else {
+			report.Pages++
+		}
+		report.NextPage++
+		at, report.Done = next, next.Done
+	}
+	if report.Done {
+		return report, finishPagedJob(ctx, cfg)
+	}
+	return report, nil
+}
+
+// IsConflict reports whether err is a ledger conflict.
+func IsConflict(err error) bool {
+	var jobsErr *Error
+	return errors.As(err, &jobsErr) && jobsErr.Type == ErrorTypeConflict
+}
+
+func pageKey(cfg PagedJobConfig, page int) string {
+	return fmt.Sprintf("%s/page/%08d", cfg.Name, page)
+}
+
+func startPagedJob(ctx context.Context, cfg PagedJobConfig) error {
+	_, err := cfg.Ledger.CreateJob(ctx, CreateJobInput{JobID: cfg.JobID, TenantID: cfg.TenantID, Status: JobStatusRunning, TTL: cfg.CheckpointTTL})
+	if err != nil && !IsConflict(err) {
+		return err
+	}
+	_, err = cfg.Ledger.AcquireLease(ctx, AcquireLeaseInput{JobID: cfg.JobID, Owner: cfg.Owner, LeaseDuration: cfg.LeaseDuration, TTL: cfg.CheckpointTTL})
+	if IsConflict(err) {
+		return fmt.Errorf("%w: %w", ErrLeaseHeld, err)
+	}
+	return err
+}
+
+// resumePagedJob loads the checkpoint the page before startPage left behind.
+func resumePagedJob(ctx context.Context, cfg PagedJobConfig, startPage int) (PageCheckpoint, error) {
+	if startPage == 0 {
+		return PageCheckpoint{}, nil
+	}
+	previous, outcome, err := cfg.Ledger.CreateIdempotencyRecord(ctx, CreateIdempotencyRecordInput{JobID: cfg.JobID, IdempotencyKey: pageKey(cfg, startPage-1), TTL: cfg.CheckpointTTL})
+	if err != nil {
+		return PageCheckpoint{}, err
+	}
+	if outcome != IdempotencyOutcomeAlreadyCompleted || previous == nil {
+		return PageCheckpoint{}, NewError(ErrorTypeInvalidInput, "start page does not follow a completed page")
+	}
+	return checkpointFromResult(previous.Result), nil
+}
+
+// finishPagedJob marks the job SUCCEEDED. The job is created at version 1 and
+// never transitions before this, so a conflict means an earlier run finished
+// it.
+func finishPagedJob(ctx context.Context, cfg PagedJobConfig) error {
+	_, err := cfg.Ledger.TransitionJobStatus(ctx, TransitionJobStatusInput{JobID: cfg.JobID, ExpectedVersion: 1, FromStatus: JobStatusRunning, ToStatus: JobStatusSucceeded})
+	if IsConflict(err) {
+		return nil
+	}
+	return err
+}
+
+func hasPageBudget(ctx context.Context, cfg PagedJobConfig, pages int) bool {
+	if ctx.Err() != nil || (cfg.MaxPages > 0 && pages >= cfg.MaxPages) {
+		return false
+	}
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > cfg.DeadlineMargin
+}
+
+// runPage checkpoints and processes one page, or returns the stored
+// checkpoint when an earlier run completed it.
+func runPage(ctx context.Context, cfg PagedJobConfig, page int, at PageCheckpoint, process PageFunc) (PageCheckpoint, bool, error) {
+	key := pageKey(cfg, page)
+	existing, outcome, err := cfg.Ledger.CreateIdempotencyRecord(ctx, CreateIdempotencyRecordInput{JobID: cfg.JobID, IdempotencyKey: key, TTL: cfg.CheckpointTTL})
+	if err != nil {
+		return PageCheckpoint{}, false, err
+	}
+	if outcome == IdempotencyOutcomeAlreadyCompleted && existing != nil {
+		return checkpointFromResult(existing.Result), true, nil
+	}
+	_, err = cfg.Ledger.RefreshLease(ctx, RefreshLeaseInput{JobID: cfg.JobID, Owner: cfg.Owner, LeaseDuration: cfg.LeaseDuration, TTL: cfg.CheckpointTTL})
+	if IsConflict(err) {
+		return PageCheckpoint{}, false, fmt.Errorf("%w: %w", ErrLeaseHeld, err)
+	}
+	if err != nil {
+		return PageCheckpoint{}, false, err
+	}
+	next, err := process(ctx, page, at)
+	if err != nil {
+		return PageCheckpoint{}, false, err
+	}
+	if _, err := cfg.Ledger.CompleteIdempotencyRecord(ctx, CompleteIdempotencyRecordInput{JobID: cfg.JobID, IdempotencyKey: key, Result: next.result(), TTL: cfg.CheckpointTTL}); err != nil {
+		return PageCheckpoint{}, false, err
+	}
+	return next, false, nil
+}
+
+func (c PageCheckpoint) result() map[string]any {
+	result := make(map[string]any, len(c.State)+1)
+	for key, value := range c.State {
+		result[key] = value
+	}
+	result["done"] = strconv.FormatBool(c.Done)
+	return result
+}
+
+func checkpointFromResult(result map[string]any) PageCheckpoint {
+	checkpoint := PageCheckpoint{State: make(map[string]string, len(result)), Done: fmt.Sprint(result["done"]) == "true"}
+	for key, value := range result {
+		if key != "done" {
+			checkpoint.State[key] = fmt.Sprint(value)
+		}
+	}
+	return checkpoint
+}
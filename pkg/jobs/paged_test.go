@@ -0,0 +1,73 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/jobs"
+	testkitjobs "github.com/theory-cloud/apptheory/v3/testkit/jobs"
+)
+
+func TestRunPaged_CheckpointsAndResumes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ledger := testkitjobs.NewLedger()
+	cfg := jobs.PagedJobConfig{Ledger: ledger, Name: "count", JobID: "j1", TenantID: "t1", Owner: "worker-a", MaxPages: 2}
+	var seen []string
+	count := func(_ context.Context, page int, at jobs.PageCheckpoint) (jobs.PageCheckpoint, error) {
+		seen = append(seen, strconv.Itoa(page)+":"+at.State["n"])
+		return jobs.PageCheckpoint{State: map[string]string{"n": strconv.Itoa(page + 1)}, Done: page == 2}, nil
+	}
+
+	first, err := jobs.RunPaged(ctx, cfg, 0, count)
+	require.NoError(t, err)
+	require.Equal(t, jobs.PagedJobReport{Pages: 2, NextPage: 2}, first)
+	require.Empty(t, ledger.LeaseOwner("j1"))
+
+	second, err := jobs.RunPaged(ctx, cfg, first.NextPage, count)
+	require.NoError(t, err)
+	require.Equal(t, jobs.PagedJobReport{Pages: 1, NextPage: 3, Done: true}, second)
+	require.Equal(t, []string{"0:", "1:1", "2:2"}, seen)
+	job, ok := ledger.Job("j1")
+	require.True(t, ok)
+	require.Equal(t, jobs.JobStatusSucceeded, job.Status)
+
+	replayed, err := jobs.RunPaged(ctx, cfg, 0, count)
+	require.NoError(t, err)
+	require.Equal(t, jobs.PagedJobReport{Replayed: 3, NextPage: 3, Done: true}, replayed)
+	require.Len(t, seen, 3)
+}
+
+func TestRunPaged_Guards(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ledger := testkitjobs.NewLedger()
+	cfg := jobs.PagedJobConfig{Ledger: ledger, Name: "guard", JobID: "j1", TenantID: "t1", Owner: "worker-a"}
+	failed := errors.New("page failed")
+	fail := func(context.Context, int, jobs.PageCheckpoint) (jobs.PageCheckpoint, error) {
+		return jobs.PageCheckpoint{}, failed
+	}
+
+	_, err := jobs.RunPaged(ctx, cfg, 0, fail)
+	require.ErrorIs(t, err, failed)
+	_, err = jobs.RunPaged(ctx, cfg, 1, fail)
+	require.True(t, isType(err, jobs.ErrorTypeInvalidInput), "err = %v", err)
+	_, err = jobs.RunPaged(ctx, cfg, -1, fail)
+	require.True(t, isType(err, jobs.ErrorTypeInvalidInput), "err = %v", err)
+
+	ledger.SetLeaseOwner("j1", "worker-b")
+	_, err = jobs.RunPaged(ctx, cfg, 0, fail)
+	require.ErrorIs(t, err, jobs.ErrLeaseHeld)
+	require.True(t, jobs.IsConflict(err))
+}
+
+func isType(err error, errorType jobs.ErrorType) bool {
+	var jobsErr *jobs.Error
+	return errors.As(err, &jobsErr) && jobsErr.Type == errorType
+}
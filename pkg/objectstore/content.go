@@ -0,0 +1,443 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Stable content-addressed store errors.
+var (
+	// ErrInvalidContentRef is returned when a ref does not name a blob in the ContentStore.
+	ErrInvalidContentRef = errors.New("objectstore: invalid content ref")
+	// ErrContentDigestMismatch is returned when stored bytes do not hash to the digest in their ref.
+	ErrContentDigestMismatch = errors.New("objectstore: content digest mismatch")
+	// ErrInvalidContentHolder is returned when a reference holder name is empty or too long.
+	ErrInvalidContentHolder = errors.New("objectstore: invalid content holder")
+	// ErrInvalidContentGCInput is returned when CollectGarbage gets a negative grace period.
+	ErrInvalidContentGCInput = errors.New("objectstore: invalid content gc input")
+)
+
+const (
+	// ContentDigestPrefix prefixes every digest returned by ContentStore.
+	ContentDigestPrefix = "sha256:"
+	// DefaultContentGCGracePeriod is the minimum blob age CollectGarbage uses when GracePeriod is zero.
+	DefaultContentGCGracePeriod = 24 * time.Hour
+	// MaxContentHolderLength bounds reference holder names.
+	MaxContentHolderLength = 512
+)
+
+const (
+	contentBlobSegment   = "sha256"
+	contentHolderSegment = "holders"
+	contentSHA256Meta    = "sha256"
+)
+
+// ContentStoreConfig configures NewContentStore.
+//
+// Bucket is required. Prefix, trimmed of surrounding "/", scopes both blobs
+// and holder markers. RefreshAfter controls when a deduplicated Put rewrites a
+// blob's metadata to renew its LastModified; zero refreshes on every hit.
+type ContentStoreConfig struct {
+	Bucket       string
+	Prefix       string
+	RefreshAfter time.Duration
+}
+
+// ContentStore stores payloads by their SHA-256 digest over a Store, so equal
+// payloads are kept once however many keys refer to them.
+//
+// Blobs live at "<prefix>/sha256/<first two hex digits>/<hex digest>"; the
+// returned ObjectRef therefore encodes the digest, and ContentDigest recovers
+// it. Each logical reference is a named holder marker under
+// "<prefix>/holders/<hex digest>/", so the manifest of who references a blob is
+// a prefix listing rather than a counter that concurrent writers could skew.
+//
+// Deduplicated Puts renew the blob's LastModified (see RefreshAfter), so S3
+// lifecycle expiration and the CollectGarbage grace period both measure the
+// newest reference. S3 has no atomic compare-and-delete across keys: a Put
+// that deduplicates against a blob in the same instant Release or
+// CollectGarbage deletes it can lose the blob. Get then fails with
+// ErrObjectNotFound; it never returns different bytes.
+type ContentStore struct {
+	store        Store
+	bucket       string
+	prefix       string
+	refreshAfter time.Duration
+	now          func() time.Time
+}
+
+// ContentPutInput stores one payload on behalf of Holder.
+//
+// Holder names the reference, for example an event ID; putting the same
+// payload for the same holder twice records one reference. Metadata is stored
+// with the blob; a deduplicated Put that refreshes the blob merges it over the
+// stored values. The "sha256" key is reserved.
+type ContentPutInput struct {
+	Payload     []byte
+	ContentType string
+	Holder      string
+	Metadata    map[string]string
+}
+
+// ContentPutOutput describes a stored blob.
+type ContentPutOutput struct {
+	Ref          ObjectRef
+	Digest       string
+	Deduplicated bool
+}
+
+// ContentReleaseInput drops Holder's reference to Ref.
+//
+// DeleteUnreferenced deletes the blob immediately when no holders remain;
+// otherwise unreferenced blobs wait for CollectGarbage or lifecycle expiry.
+type ContentReleaseInput struct {
+	Ref                ObjectRef
+	Holder             string
+	DeleteUnreferenced bool
+}
+
+// ContentReleaseOutput reports whether Release deleted the blob.
+type ContentReleaseOutput struct {
+	Deleted bool
+}
+
+// ContentGCInput selects one page of blobs for CollectGarbage.
+//
+// Blobs modified within GracePeriod are kept even when unreferenced, which
+// protects Puts that have written their blob but not yet returned. Zero uses
+// DefaultContentGCGracePeriod.
+type ContentGCInput struct {
+	GracePeriod       time.Duration
+	ContinuationToken string
+}
+
+// ContentGCOutput reports one CollectGarbage page. Continue with
+// NextContinuationToken while it is non-empty.
+type ContentGCOutput struct {
+	Scanned               int
+	Deleted               int
+	Retained              int
+	NextContinuationToken string
+}
+
+// NewContentStore returns a content-addressed store over store.
+func NewContentStore(store Store, storeConfig ContentStoreConfig) (*ContentStore, error) {
+	if store == nil || storeConfig.RefreshAfter < 0 {
+		return nil, ErrInvalidStoreConfig
+	}
+	prefix := strings.Trim(storeConfig.Prefix, "/")
+	probe := ObjectRef{Bucket: storeConfig.Bucket, Key: prefix + "/" + contentBlobSegment}
+	if err := validateWriteRef(probe); err != nil {
+		return nil, ErrInvalidStoreConfig
+	}
+	return &ContentStore{
+		store:        store,
+		bucket:       storeConfig.Bucket,
+		prefix:       prefix,
+		refreshAfter: storeConfig.RefreshAfter,
+		now:          time.Now,
+	}, nil
+}
+
+// ContentDigest returns the "sha256:<hex>" digest encoded in a ContentStore
+// blob ref, or ErrInvalidContentRef when ref is not shaped like one.
+func ContentDigest(ref ObjectRef) (string, error) {
+	sum, err := contentRefHex(ref)
+	if err != nil {
+		return "", err
+	}
+	return ContentDigestPrefix + sum, nil
+}
+
+// Ref returns the blob ref for a "sha256:<hex>" digest.
+func (c *ContentStore) Ref(digest string) (ObjectRef, error) {
+	if err := c.requireStore(); err != nil {
+		return ObjectRef{}, err
+	}
+	sum, ok := strings.CutPrefix(digest, ContentDigestPrefix)
+	if !ok || !isContentHex(sum) {
+		return ObjectRef{}, ErrInvalidContentRef
+	}
+	return c.blobRef(sum), nil
+}
+
+// Put stores Payload once per digest and records Holder's reference to it.
+// The holder marker is written before the blob so a concurrent Release or
+// CollectGarbage already sees the new reference. When the blob write fails,
+// the marker is removed only if this call created it.
+func (c *ContentStore) Put(ctx context.Context, input ContentPutInput) (*ContentPutOutput, error) {
+	if err := c.requireStore(); err != nil {
+		return nil, err
+	}
+	if err := validateContentHolder(input.Holder); err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(input.Payload)
+	sum := hex.EncodeToString(digest[:])
+	ref := c.blobRef(sum)
+	holder := c.holderRef(sum, input.Holder)
+
+	_, err := c.store.Put(ctx, PutInput{Ref: holder, Payload: nil, Condition: WriteCondition{IfNoneMatch: "*"}})
+	created := err == nil
+	if err != nil && !errors.Is(err, ErrPreconditionFailed) {
+		return nil, err
+	}
+	out := &ContentPutOutput{Ref: ref, Digest: ContentDigestPrefix + sum}
+	metadata := mergeContentMetadata(input.Metadata, map[string]string{contentSHA256Meta: sum})
+	_, err = c.store.Put(ctx, PutInput{
+		Ref:         ref,
+		Payload:     input.Payload,
+		ContentType: input.ContentType,
+		Metadata:    metadata,
+		Condition:   WriteCondition{IfNoneMatch: "*"},
+	})
+	if errors.Is(err, ErrPreconditionFailed) {
+		out.Deduplicated = true
+		err = c.refresh(ctx, ref, metadata)
+	}
+	if err != nil {
+		if !created {
+			return nil, err
+		}
+		if deleteErr := c.store.Delete(context.WithoutCancel(ctx), DeleteInput{Ref: holder}); deleteErr != nil {
+			return nil, errors.Join(err, deleteErr)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// Get reads a blob and verifies that its bytes hash to the digest in Ref.
+func (c *ContentStore) Get(ctx context.Context, input GetInput) (*GetOutput, error) {
+	if err := c.requireStore(); err != nil {
+		return nil, err
+	}
+	sum, err := c.ownedHex(input.Ref)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.store.Get(ctx, GetInput{Ref: c.blobRef(sum), MaxBytes: input.MaxBytes})
+	if err != nil {
+		return nil, err
+	}
+	actual := sha256.Sum256(out.Payload)
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(actual[:])), []byte(sum)) != 1 {
+		return nil, ErrContentDigestMismatch
+	}
+	return out, nil
+}
+
+// Referenced reports whether any holder still references the blob.
+func (c *ContentStore) Referenced(ctx context.Context, ref ObjectRef) (bool, error) {
+	if err := c.requireStore(); err != nil {
+		return false, err
+	}
+	sum, err := c.ownedHex(ref)
+	if err != nil {
+		return false, err
+	}
+	return c.referenced(ctx, sum)
+}
+
+// Release removes Holder's reference. Releasing a reference that does not
+// exist is not an error.
+func (c *ContentStore) Release(ctx context.Context, input ContentReleaseInput) (*ContentReleaseOutput, error) {
+	if err := c.requireStore(); err != nil {
+		return nil, err
+	}
+	if err := validateContentHolder(input.Holder); err != nil {
+		return nil, err
+	}
+	sum, err := c.ownedHex(input.Ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.store.Delete(ctx, DeleteInput{Ref: c.holderRef(sum, input.Holder)}); err != nil {
+		return nil, err
+	}
+	out := &ContentReleaseOutput{}
+	if !input.DeleteUnreferenced {
+		return out, nil
+	}
+	referenced, err := c.referenced(ctx, sum)
+	if err != nil || referenced {
+		return out, err
+	}
+	if err := c.store.Delete(ctx, DeleteInput{Ref: c.blobRef(sum)}); err != nil {
+		return out, err
+	}
+	out.Deleted = true
+	return out, nil
+}
+
+// CollectGarbage deletes unreferenced blobs older than the grace period from
+// one List page, so each call stays bounded inside a Lambda invocation.
+func (c *ContentStore) CollectGarbage(ctx context.Context, input ContentGCInput) (*ContentGCOutput, error) {
+	if err := c.requireStore(); err != nil {
+		return nil, err
+	}
+	grace := input.GracePeriod
+	if grace == 0 {
+		grace = DefaultContentGCGracePeriod
+	}
+	if grace < 0 {
+		return nil, ErrInvalidContentGCInput
+	}
+	page, err := c.store.List(ctx, ListInput{
+		Bucket:            c.bucket,
+		Prefix:            c.key(contentBlobSegment) + "/",
+		ContinuationToken: input.ContinuationToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := c.now().Add(-grace)
+	out := &ContentGCOutput{NextContinuationToken: page.NextContinuationToken}
+	for _, obj := range page.Objects {
+		out.Scanned++
+		sum, err := c.ownedHex(obj.Ref)
+		if err != nil || obj.LastModified.After(cutoff) {
+			out.Retained++
+			continue
+		}
+		referenced, err := c.referenced(ctx, sum)
+		if err != nil {
+			return out, err
+		}
+		if referenced {
+			out.Retained++
+			continue
+		}
+		if err := c.store.Delete(ctx, DeleteInput{Ref: obj.Ref}); err != nil {
+			return out, err
+		}
+		out.Deleted++
+	}
+	return out, nil
+}
+
+func (c *ContentStore) requireStore() error {
+	if c == nil || c.store == nil || c.now == nil {
+		return ErrInvalidStoreConfig
+	}
+	return nil
+}
+
+// refresh renews a deduplicated blob's LastModified with an in-place metadata
+// copy that also applies metadata. A concurrent change to the blob means
+// another writer already did it.
+func (c *ContentStore) refresh(ctx context.Context, ref ObjectRef, metadata map[string]string) error {
+	info, err := c.store.Head(ctx, HeadInput{Ref: ref})
+	if err != nil {
+		return err
+	}
+	if c.now().Sub(info.LastModified) < c.refreshAfter {
+		return nil
+	}
+	_, err = c.store.Copy(ctx, CopyInput{
+		Source:          ref,
+		Destination:     ref,
+		SourceIfMatch:   info.ETag,
+		ReplaceMetadata: true,
+		ContentType:     info.ContentType,
+		Metadata:        mergeContentMetadata(info.Metadata, metadata),
+	})
+	if errors.Is(err, ErrPreconditionFailed) {
+		return nil
+	}
+	return err
+}
+
+// mergeContentMetadata returns base overlaid with overlay.
+func mergeContentMetadata(base, overlay map[string]string) map[string]string {
+	out := make(map[string]string, len(base)+len(overlay))
+	for key, value := range base {
+		out[key] = value
+	}
+	for key, value := range overlay {
+		out[key] = value
+	}
+	return out
+}
+
+func (c *ContentStore) referenced(ctx context.Context, sum string) (bool, error) {
+	page, err := c.store.List(ctx, ListInput{
+		Bucket:  c.bucket,
+		Prefix:  c.key(contentHolderSegment, sum) + "/",
+		MaxKeys: 1,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(page.Objects) > 0, nil
+}
+
+// ownedHex returns the hex digest of ref when it is an unversioned blob ref in
+// this store's bucket and prefix.
+func (c *ContentStore) ownedHex(ref ObjectRef) (string, error) {
+	sum, err := contentRefHex(ref)
+	if err != nil {
+		return "", err
+	}
+	if ref.Bucket != c.bucket || ref.VersionID != "" || ref.Key != c.blobRef(sum).Key {
+		return "", ErrInvalidContentRef
+	}
+	return sum, nil
+}
+
+func (c *ContentStore) blobRef(sum string) ObjectRef {
+	return ObjectRef{Bucket: c.bucket, Key: c.key(contentBlobSegment, sum[:2], sum)}
+}
+
+func (c *ContentStore) holderRef(sum, holder string) ObjectRef {
+	return ObjectRef{Bucket: c.bucket, Key: c.key(contentHolderSegment, sum, url.PathEscape(holder))}
+}
+
+func (c *ContentStore) key(segments ...string) string {
+	name := strings.Join(segments, "/")
+	if c.prefix == "" {
+		return name
+	}
+	return c.prefix + "/" + name
+}
+
+func contentRefHex(ref ObjectRef) (string, error) {
+	if err := ref.Validate(); err != nil {
+		return "", ErrInvalidContentRef
+	}
+	segments := strings.Split(ref.Key, "/")
+	if len(segments) < 3 {
+		return "", ErrInvalidContentRef
+	}
+	algorithm, fanout, sum := segments[len(segments)-3], segments[len(segments)-2], segments[len(segments)-1]
+	if algorithm != contentBlobSegment || !isContentHex(sum) || fanout != sum[:2] {
+		return "", ErrInvalidContentRef
+	}
+	return sum, nil
+}
+
+func isContentHex(sum string) bool {
+	if len(sum) != sha256.Size*2 {
+		return false
+	}
+	for _, r := range sum {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func validateContentHolder(holder string) error {
+	if holder == "" || len(holder) > MaxContentHolderLength || containsControl(holder) {
+		return ErrInvalidContentHolder
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestContentStore(t *testing.T) (*ContentStore, Store) {
+	t.Helper()
+	inner, err := NewMemoryStore(MemoryStoreConfig{})
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+	store, err := NewContentStore(inner, ContentStoreConfig{Bucket: "bucket", Prefix: "/cas/"})
+	if err != nil {
+		t.Fatalf("NewContentStore() error = %v", err)
+	}
+	return store, inner
+}
+
+func TestContentStoreDeduplicatesAndVerifies(t *testing.T) {
+	ctx := context.Background()
+	store, inner := newTestContentStore(t)
+
+	first, err := store.Put(ctx, ContentPutInput{Payload: []byte("hello"), ContentType: "text/plain", Holder: "event/1"})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	const sum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if first.Digest != "sha256:"+sum || first.Deduplicated {
+		t.Fatalf("Put() = %#v, want new sha256 blob", first)
+	}
+	if first.Ref.Key != "cas/sha256/2c/"+sum {
+		t.Fatalf("Put() key = %q, want digest-addressed key", first.Ref.Key)
+	}
+	digest, err := ContentDigest(first.Ref)
+	if err != nil || digest != first.Digest {
+		t.Fatalf("ContentDigest() = %q, %v, want %q", digest, err, first.Digest)
+	}
+	if ref, err := store.Ref(first.Digest); err != nil || ref != first.Ref {
+		t.Fatalf("Ref() = %#v, %v, want %#v", ref, err, first.Ref)
+	}
+
+	second, err := store.Put(ctx, ContentPutInput{Payload: []byte("hello"), Holder: "event/2"})
+	if err != nil {
+		t.Fatalf("Put() duplicate error = %v", err)
+	}
+	if !second.Deduplicated || second.Ref != first.Ref {
+		t.Fatalf("Put() duplicate = %#v, want deduplicated ref", second)
+	}
+	page, err := inner.List(ctx, ListInput{Bucket: "bucket", Prefix: "cas/sha256/"})
+	if err != nil || len(page.Objects) != 1 {
+		t.Fatalf("List() blobs = %v, %v, want one blob", page, err)
+	}
+
+	out, err := store.Get(ctx, GetInput{Ref: first.Ref, MaxBytes: 16})
+	if err != nil || string(out.Payload) != "hello" || out.ContentType != "text/plain" {
+		t.Fatalf("Get() = %v, %v, want hello", out, err)
+	}
+
+	if _, err := inner.Put(ctx, PutInput{Ref: first.Ref, Payload: []byte("tampered")}); err != nil {
+		t.Fatalf("inner Put() error = %v", err)
+	}
+	if _, err := store.Get(ctx, GetInput{Ref: first.Ref, MaxBytes: 16}); !errors.Is(err, ErrContentDigestMismatch) {
+		t.Fatalf("Get() tampered error = %v, want ErrContentDigestMismatch", err)
+	}
+
+	for _, ref := range []ObjectRef{
+		{Bucket: "bucket", Key: "cas/sha256/ff/" + sum},
+		{Bucket: "bucket", Key: "other/sha256/2c/" + sum},
+		{Bucket: "other", Key: first.Ref.Key},
+		{Bucket: "bucket", Key: first.Ref.Key, VersionID: "v1"},
+		{Bucket: "bucket", Key: "cas/sha256/2c/" + strings.ToUpper(sum)},
+	} {
+		if _, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 16}); !errors.Is(err, ErrInvalidContentRef) {
+			t.Fatalf("Get(%v) error = %v, want ErrInvalidContentRef", ref, err)
+		}
+	}
+	if _, err := store.Put(ctx, ContentPutInput{Payload: []byte("x")}); !errors.Is(err, ErrInvalidContentHolder) {
+		t.Fatalf("Put() without holder error = %v, want ErrInvalidContentHolder", err)
+	}
+	if _, err := NewContentStore(inner, ContentStoreConfig{}); !errors.Is(err, ErrInvalidStoreConfig) {
+		t.Fatalf("NewContentStore() without bucket error = %v, want ErrInvalidStoreConfig", err)
+	}
+}
+
+func TestContentStoreReleaseAndCollectGarbage(t *testing.T) {
+	ctx := context.Background()
+	store, inner := newTestContentStore(t)
+
+	shared, err := store.Put(ctx, ContentPutInput{Payload: []byte("shared"), Holder: "a"})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := store.Put(ctx, ContentPutInput{Payload: []byte("shared"), Holder: "b"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	orphan, err := store.Put(ctx, ContentPutInput{Payload: []byte("orphan"), Holder: "c"})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	released, err := store.Release(ctx, ContentReleaseInput{Ref: shared.Ref, Holder: "a", DeleteUnreferenced: true})
+	if err != nil || released.Deleted {
+		t.Fatalf("Release(a) = %v, %v, want blob kept for holder b", released, err)
+	}
+	if referenced, err := store.Referenced(ctx, shared.Ref); err != nil || !referenced {
+		t.Fatalf("Referenced() = %v, %v, want true", referenced, err)
+	}
+	released, err = store.Release(ctx, ContentReleaseInput{Ref: shared.Ref, Holder: "b", DeleteUnreferenced: true})
+	if err != nil || !released.Deleted {
+		t.Fatalf("Release(b) = %v, %v, want blob deleted", released, err)
+	}
+	if _, err := inner.Head(ctx, HeadInput{Ref: shared.Ref}); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("Head() released blob error = %v, want ErrObjectNotFound", err)
+	}
+
+	if _, err := store.Release(ctx, ContentReleaseInput{Ref: orphan.Ref, Holder: "c"}); err != nil {
+		t.Fatalf("Release(c) error = %v", err)
+	}
+	gc, err := store.CollectGarbage(ctx, ContentGCInput{GracePeriod: time.Hour})
+	if err != nil {
+		t.Fatalf("CollectGarbage() error = %v", err)
+	}
+	if gc.Scanned != 1 || gc.Retained != 1 || gc.Deleted != 0 {
+		t.Fatalf("CollectGarbage() within grace = %#v, want orphan retained", gc)
+	}
+
+	store.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	gc, err = store.CollectGarbage(ctx, ContentGCInput{GracePeriod: time.Hour})
+	if err != nil {
+		t.Fatalf("CollectGarbage() error = %v", err)
+	}
+	if gc.Deleted != 1 || gc.NextContinuationToken != "" {
+		t.Fatalf("CollectGarbage() after grace = %#v, want orphan deleted", gc)
+	}
+	if _, err := store.CollectGarbage(ctx, ContentGCInput{GracePeriod: -time.Second}); !errors.Is(err, ErrInvalidContentGCInput) {
+		t.Fatalf("CollectGarbage() negative grace error = %v, want ErrInvalidContentGCInput", err)
+	}
+}
+
+func TestContentStoreDedupRefreshesLastModified(t *testing.T) {
+	ctx := context.Background()
+	store, inner := newTestContentStore(t)
+
+	first, err := store.Put(ctx, ContentPutInput{Payload: []byte("payload"), Holder: "a", Metadata: map[string]string{"expires-at": "1"}})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	before, err := inner.Head(ctx, HeadInput{Ref: first.Ref})
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, err := store.Put(ctx, ContentPutInput{Payload: []byte("payload"), Holder: "b", Metadata: map[string]string{"expires-at": "2"}}); err != nil {
+		t.Fatalf("Put() duplicate error = %v", err)
+	}
+	after, err := inner.Head(ctx, HeadInput{Ref: first.Ref})
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	if !after.LastModified.After(before.LastModified) || after.Metadata[contentSHA256Meta] == "" {
+		t.Fatalf("LastModified %v -> %v, want refreshed blob with metadata kept", before.LastModified, after.LastModified)
+	}
+	if before.Metadata["expires-at"] != "1" || after.Metadata["expires-at"] != "2" {
+		t.Fatalf("expires-at %q -> %q, want the refresh to apply the duplicate's metadata", before.Metadata["expires-at"], after.Metadata["expires-at"])
+	}
+}
+
+// headFailingStore fails Head, which ContentStore.Put uses to refresh a
+// deduplicated blob.
+type headFailingStore struct {
+	Store
+	err error
+}
+
+func (s *headFailingStore) Head(context.Context, HeadInput) (*ObjectInfo, error) {
+	return nil, s.err
+}
+
+func TestContentStorePutFailureKeepsExistingHolder(t *testing.T) {
+	ctx := context.Background()
+	store, inner := newTestContentStore(t)
+	first, err := store.Put(ctx, ContentPutInput{Payload: []byte("payload"), Holder: "a"})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	boom := errors.New("boom")
+	store.store = &headFailingStore{Store: inner, err: boom}
+	for _, holder := range []string{"a", "b"} {
+		if _, err := store.Put(ctx, ContentPutInput{Payload: []byte("payload"), Holder: holder}); !errors.Is(err, boom) {
+			t.Fatalf("Put(%s) error = %v, want boom", holder, err)
+		}
+	}
+
+	sum, err := contentRefHex(first.Ref)
+	if err != nil {
+		t.Fatalf("contentRefHex() error = %v", err)
+	}
+	if _, err := inner.Head(ctx, HeadInput{Ref: store.holderRef(sum, "a")}); err != nil {
+		t.Fatalf("Head(holder a) error = %v, want existing marker kept", err)
+	}
+	if _, err := inner.Head(ctx, HeadInput{Ref: store.holderRef(sum, "b")}); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("Head(holder b) error = %v, want new marker removed", err)
+	}
+}
@@ -0,0 +1,537 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Stable envelope-encryption errors.
+var (
+	// ErrInvalidEnvelopeConfig is returned when an EnvelopeStore is misconfigured.
+	ErrInvalidEnvelopeConfig = errors.New("objectstore: invalid envelope config")
+	// ErrInvalidEnvelopeKey is returned when no key can be selected or a provider does not know a key ID.
+	ErrInvalidEnvelopeKey = errors.New("objectstore: invalid envelope key")
+	// ErrEnvelopeInvalid is returned when a stored object is not a well-formed, authentic envelope.
+	ErrEnvelopeInvalid = errors.New("objectstore: invalid envelope")
+	// ErrReservedMetadata is returned when caller metadata uses the envelope's reserved key prefix.
+	ErrReservedMetadata = errors.New("objectstore: reserved metadata key")
+)
+
+const (
+	// DataKeySize is the length in bytes of every envelope data key (AES-256).
+	DataKeySize = 32
+	// DefaultEnvelopeMaxPlaintextBytes caps PutReader bodies when EnvelopeStoreConfig.MaxPlaintextBytes is zero.
+	DefaultEnvelopeMaxPlaintextBytes int64 = 64 << 20
+	// EnvelopeMetadataPrefix is reserved for envelope metadata; caller metadata keys may not start with it.
+	EnvelopeMetadataPrefix = "apptheory-envelope"
+)
+
+const (
+	envelopeMetaVersion    = EnvelopeMetadataPrefix
+	envelopeMetaKeyID      = EnvelopeMetadataPrefix + "-key-id"
+	envelopeMetaWrappedKey = EnvelopeMetadataPrefix + "-wrapped-key"
+	envelopeMetaSize       = EnvelopeMetadataPrefix + "-size"
+	envelopeVersionV1      = "v1"
+	envelopeNonceSize      = 12
+	envelopeOverhead       = envelopeNonceSize + 16
+)
+
+// envelopeAADPrefix names the envelope format in every payload's additional
+// authenticated data.
+const envelopeAADPrefix = "apptheory-envelope/v1/AES-256-GCM"
+
+// envelopeAAD binds a payload to its bucket, key, and wrapping key ID, so a
+// ciphertext copied to another location or relabeled with another key fails to
+// authenticate.
+func envelopeAAD(ref ObjectRef, keyID string) []byte {
+	aad := []byte(envelopeAADPrefix)
+	for _, field := range []string{ref.Bucket, ref.Key, keyID} {
+		aad = binary.AppendUvarint(aad, uint64(len(field)))
+		aad = append(aad, field...)
+	}
+	return aad
+}
+
+// KeyProvider generates and protects per-object data keys for EnvelopeStore.
+//
+// Key IDs are opaque to EnvelopeStore: a KMS provider accepts key IDs, ARNs,
+// and aliases, while the local provider accepts the names it was configured
+// with. Implementations must return ErrInvalidEnvelopeKey for unknown key IDs.
+type KeyProvider interface {
+	// GenerateDataKey returns a fresh DataKeySize-byte key and its wrapped form.
+	GenerateDataKey(ctx context.Context, keyID string) (*DataKey, error)
+	// WrapDataKey wraps an existing plaintext data key under keyID.
+	WrapDataKey(ctx context.Context, keyID string, plaintext []byte) ([]byte, error)
+	// UnwrapDataKey recovers the plaintext data key wrapped under keyID.
+	UnwrapDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// DataKey is a plaintext data key and the same key wrapped by a KeyProvider.
+type DataKey struct {
+	Plaintext []byte
+	Wrapped   []byte
+}
+
+// KeySelector chooses the key ID that wraps the data key for one object.
+type KeySelector func(ObjectRef) (string, error)
+
+// StaticKeySelector wraps every object under keyID.
+func StaticKeySelector(keyID string) KeySelector {
+	return func(ObjectRef) (string, error) {
+		if strings.TrimSpace(keyID) == "" {
+			return "", ErrInvalidEnvelopeKey
+		}
+		return keyID, nil
+	}
+}
+
+var tenantKeySegment = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// TenantKeySelector wraps each object under a per-tenant key.
+//
+// The tenant is the first "/"-separated segment of ObjectRef.Key and replaces
+// the single "{tenant}" placeholder in keyIDFormat, for example
+// "alias/tenant-{tenant}". Keys without a tenant segment, or whose tenant
+// segment is not 1-128 ASCII letters, digits, "-", or "_", fail closed with
+// ErrInvalidEnvelopeKey.
+func TenantKeySelector(keyIDFormat string) (KeySelector, error) {
+	if strings.Count(keyIDFormat, "{tenant}") != 1 || strings.TrimSpace(keyIDFormat) != keyIDFormat {
+		return nil, ErrInvalidEnvelopeConfig
+	}
+	return func(ref ObjectRef) (string, error) {
+		tenant, _, ok := strings.Cut(ref.Key, "/")
+		if !ok || !tenantKeySegment.MatchString(tenant) {
+			return "", ErrInvalidEnvelopeKey
+		}
+		return strings.Replace(keyIDFormat, "{tenant}", tenant, 1), nil
+	}, nil
+}
+
+// EnvelopeStoreConfig configures client-side envelope encryption.
+//
+// Provider and SelectKey are required. Reads fail closed unless an object is
+// wrapped under the key SelectKey picks for its ref; during a rotation,
+// PreviousSelectKey names the key being retired so objects not yet re-wrapped
+// stay readable. MaxPlaintextBytes bounds how much of a PutReader body is
+// buffered for encryption; zero selects DefaultEnvelopeMaxPlaintextBytes.
+type EnvelopeStoreConfig struct {
+	Provider          KeyProvider
+	SelectKey         KeySelector
+	PreviousSelectKey KeySelector
+	MaxPlaintextBytes int64
+}
+
+// EnvelopeStore is a Store that encrypts payloads client-side before they
+// reach the wrapped Store.
+//
+// Every object gets a fresh AES-256 data key from the KeyProvider. The payload
+// is sealed with AES-GCM, bound to its bucket, key, and key ID, and stored as
+// nonce||ciphertext||tag; the wrapped data key, its key ID, and the plaintext
+// size are stored in object metadata under EnvelopeMetadataPrefix. Reads check
+// the key ID against the KeySelector, unwrap the data key, authenticate, and
+// decrypt; any tampering or relocation fails with ErrEnvelopeInvalid. Head
+// and reads report plaintext sizes and hide envelope metadata. List passes
+// through and reports stored (ciphertext) sizes, which are larger than the
+// plaintext by a fixed 28 bytes.
+//
+// ETags and write conditions refer to the stored ciphertext.
+type EnvelopeStore struct {
+	inner       Store
+	provider    KeyProvider
+	selectKey   KeySelector
+	previousKey KeySelector
+	maxBytes    int64
+}
+
+var _ Store = (*EnvelopeStore)(nil)
+
+// NewEnvelopeStore wraps inner with client-side envelope encryption.
+func NewEnvelopeStore(inner Store, storeConfig EnvelopeStoreConfig) (*EnvelopeStore, error) {
+	if inner == nil || storeConfig.Provider == nil || storeConfig.SelectKey == nil {
+		return nil, ErrInvalidEnvelopeConfig
+	}
+	maxBytes := storeConfig.MaxPlaintextBytes
+	if maxBytes == 0 {
+		maxBytes = DefaultEnvelopeMaxPlaintextBytes
+	}
+	if maxBytes < 0 || maxBytes > math.MaxInt64-envelopeOverhead {
+		return nil, ErrInvalidEnvelopeConfig
+	}
+	return &EnvelopeStore{
+		inner:       inner,
+		provider:    storeConfig.Provider,
+		selectKey:   storeConfig.SelectKey,
+		previousKey: storeConfig.PreviousSelectKey,
+		maxBytes:    maxBytes,
+	}, nil
+}
+
+// Put encrypts Payload under the key selected for Ref.
+func (s *EnvelopeStore) Put(ctx context.Context, input PutInput) (ObjectRef, error) {
+	if err := s.requireInner(); err != nil {
+		return ObjectRef{}, err
+	}
+	if err := validatePutInput(input); err != nil {
+		return ObjectRef{}, err
+	}
+	if int64(len(input.Payload)) > s.maxBytes {
+		return ObjectRef{}, ErrObjectTooLarge
+	}
+	sealed, metadata, err := s.seal(ctx, input.Ref, input.Payload, input.Metadata)
+	if err != nil {
+		return ObjectRef{}, err
+	}
+	return s.inner.Put(ctx, PutInput{
+		Ref:         input.Ref,
+		Payload:     sealed,
+		ContentType: input.ContentType,
+		Metadata:    metadata,
+		Condition:   input.Condition,
+	})
+}
+
+// PutReader buffers up to MaxPlaintextBytes of Body, then encrypts it like Put.
+// AES-GCM authenticates the whole payload at once, so bodies are not streamed.
+func (s *EnvelopeStore) PutReader(ctx context.Context, input PutReaderInput) (ObjectRef, error) {
+	if err := s.requireInner(); err != nil {
+		return ObjectRef{}, err
+	}
+	if err := validatePutReaderInput(input); err != nil {
+		return ObjectRef{}, err
+	}
+	payload, err := readBounded(input.Body, s.maxBytes)
+	if err != nil {
+		return ObjectRef{}, err
+	}
+	return s.Put(ctx, PutInput{
+		Ref:         input.Ref,
+		Payload:     payload,
+		ContentType: input.ContentType,
+		Metadata:    input.Metadata,
+		Condition:   input.Condition,
+	})
+}
+
+// Get decrypts the object. MaxBytes caps the plaintext size.
+func (s *EnvelopeStore) Get(ctx context.Context, input GetInput) (*GetOutput, error) {
+	if err := s.requireInner(); err != nil {
+		return nil, err
+	}
+	if err := validateGetInput(input); err != nil {
+		return nil, err
+	}
+	out, err := s.inner.Get(ctx, GetInput{Ref: input.Ref, MaxBytes: sealedLimit(input.MaxBytes)})
+	if err != nil {
+		return nil, err
+	}
+	payload, err := s.open(ctx, input.Ref, out.Payload, out.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(payload)) > input.MaxBytes {
+		return nil, ErrObjectTooLarge
+	}
+	return &GetOutput{
+		Ref:         out.Ref,
+		Payload:     payload,
+		ContentType: out.ContentType,
+		Metadata:    stripEnvelopeMetadata(out.Metadata),
+		ETag:        out.ETag,
+	}, nil
+}
+
+// GetReader decrypts the whole object before returning a reader over the
+// plaintext, because AES-GCM cannot release bytes before authenticating them.
+func (s *EnvelopeStore) GetReader(ctx context.Context, input GetInput) (*GetReaderOutput, error) {
+	if err := s.requireInner(); err != nil {
+		return nil, err
+	}
+	if err := validateGetInput(input); err != nil {
+		return nil, err
+	}
+	out, err := s.inner.GetReader(ctx, GetInput{Ref: input.Ref, MaxBytes: sealedLimit(input.MaxBytes)})
+	if err != nil {
+		return nil, err
+	}
+	sealed, readErr := readBounded(out.Body, sealedLimit(input.MaxBytes))
+	closeErr := out.Body.Close()
+	if readErr != nil {
+		return nil, readErr
+	}
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	payload, err := s.open(ctx, input.Ref, sealed, out.Info.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(payload)) > input.MaxBytes {
+		return nil, ErrObjectTooLarge
+	}
+	info := out.Info
+	info.Size = int64(len(payload))
+	info.Metadata = stripEnvelopeMetadata(info.Metadata)
+	return &GetReaderOutput{Info: info, Body: io.NopCloser(bytes.NewReader(payload))}, nil
+}
+
+// Head reports the plaintext size recorded in the envelope metadata.
+func (s *EnvelopeStore) Head(ctx context.Context, input HeadInput) (*ObjectInfo, error) {
+	if err := s.requireInner(); err != nil {
+		return nil, err
+	}
+	info, err := s.inner.Head(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	env, err := parseEnvelopeMetadata(info.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	out := *info
+	out.Size = env.size
+	out.Metadata = stripEnvelopeMetadata(info.Metadata)
+	return &out, nil
+}
+
+// List passes through to the wrapped Store.
+func (s *EnvelopeStore) List(ctx context.Context, input ListInput) (*ListOutput, error) {
+	if err := s.requireInner(); err != nil {
+		return nil, err
+	}
+	return s.inner.List(ctx, input)
+}
+
+// Copy decrypts the source and encrypts it again for the destination, because
+// payloads are bound to their location and the destination may select a
+// different tenant key. Sources larger than MaxPlaintextBytes fail with
+// ErrObjectTooLarge.
+func (s *EnvelopeStore) Copy(ctx context.Context, input CopyInput) (ObjectRef, error) {
+	if err := s.requireInner(); err != nil {
+		return ObjectRef{}, err
+	}
+	if err := validateCopyInput(input); err != nil {
+		return ObjectRef{}, err
+	}
+
+	source, err := s.inner.Get(ctx, GetInput{Ref: input.Source, MaxBytes: sealedLimit(s.maxBytes)})
+	if err != nil {
+		return ObjectRef{}, err
+	}
+	if input.SourceIfMatch != "" && source.ETag != input.SourceIfMatch {
+		return ObjectRef{}, ErrPreconditionFailed
+	}
+	payload, err := s.open(ctx, input.Source, source.Payload, source.Metadata)
+	if err != nil {
+		return ObjectRef{}, err
+	}
+
+	contentType, metadata := source.ContentType, stripEnvelopeMetadata(source.Metadata)
+	if input.ReplaceMetadata {
+		contentType, metadata = input.ContentType, input.Metadata
+	}
+	return s.Put(ctx, PutInput{
+		Ref:         input.Destination,
+		Payload:     payload,
+		ContentType: contentType,
+		Metadata:    metadata,
+		Condition:   input.Condition,
+	})
+}
+
+// Delete passes through to the wrapped Store.
+func (s *EnvelopeStore) Delete(ctx context.Context, input DeleteInput) error {
+	if err := s.requireInner(); err != nil {
+		return err
+	}
+	return s.inner.Delete(ctx, input)
+}
+
+func (s *EnvelopeStore) requireInner() error {
+	if s == nil || s.inner == nil || s.provider == nil || s.selectKey == nil {
+		return ErrInvalidEnvelopeConfig
+	}
+	return nil
+}
+
+// envelope is the parsed envelope metadata of one object.
+type envelope struct {
+	keyID   string
+	wrapped []byte
+	size    int64
+}
+
+func (e envelope) metadata(user map[string]string) map[string]string {
+	out := make(map[string]string, len(user)+4)
+	for k, v := range user {
+		out[k] = v
+	}
+	out[envelopeMetaVersion] = envelopeVersionV1
+	out[envelopeMetaKeyID] = e.keyID
+	out[envelopeMetaWrappedKey] = base64.StdEncoding.EncodeToString(e.wrapped)
+	out[envelopeMetaSize] = strconv.FormatInt(e.size, 10)
+	return out
+}
+
+func (s *EnvelopeStore) seal(ctx context.Context, ref ObjectRef, payload []byte, user map[string]string) ([]byte, map[string]string, error) {
+	if err := checkReservedMetadata(user); err != nil {
+		return nil, nil, err
+	}
+	keyID, err := s.selectKey(ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	dataKey, err := s.provider.GenerateDataKey(ctx, keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dataKey == nil || len(dataKey.Plaintext) != DataKeySize || len(dataKey.Wrapped) == 0 {
+		return nil, nil, ErrInvalidEnvelopeKey
+	}
+	defer clear(dataKey.Plaintext)
+
+	sealed, err := sealPayload(dataKey.Plaintext, ref, keyID, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	env := envelope{keyID: keyID, wrapped: dataKey.Wrapped, size: int64(len(payload))}
+	return sealed, env.metadata(user), nil
+}
+
+func (s *EnvelopeStore) open(ctx context.Context, ref ObjectRef, sealed []byte, metadata map[string]string) ([]byte, error) {
+	env, err := parseEnvelopeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkKey(ref, env.keyID); err != nil {
+		return nil, err
+	}
+	key, err := s.unwrapKey(ctx, env)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	return openPayload(key, ref, env, sealed)
+}
+
+// checkKey fails closed unless keyID is the key selected for ref, or the key
+// being retired for ref during a rotation.
+func (s *EnvelopeStore) checkKey(ref ObjectRef, keyID string) error {
+	selected, err := s.selectKey(ref)
+	if err != nil {
+		return err
+	}
+	if keyID == selected {
+		return nil
+	}
+	if s.previousKey != nil {
+		if previous, err := s.previousKey(ref); err == nil && keyID == previous {
+			return nil
+		}
+	}
+	return ErrEnvelopeInvalid
+}
+
+func (s *EnvelopeStore) unwrapKey(ctx context.Context, env envelope) ([]byte, error) {
+	key, err := s.provider.UnwrapDataKey(ctx, env.keyID, env.wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != DataKeySize {
+		clear(key)
+		return nil, ErrEnvelopeInvalid
+	}
+	return key, nil
+}
+
+func sealPayload(key []byte, ref ObjectRef, keyID string, payload []byte) ([]byte, error) {
+	aead, err := newEnvelopeAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed := make([]byte, envelopeNonceSize, envelopeOverhead+len(payload))
+	if _, err := rand.Read(sealed); err != nil {
+		return nil, err
+	}
+	return aead.Seal(sealed, sealed[:envelopeNonceSize], payload, envelopeAAD(ref, keyID)), nil
+}
+
+func openPayload(key []byte, ref ObjectRef, env envelope, sealed []byte) ([]byte, error) {
+	if int64(len(sealed)) != env.size+envelopeOverhead {
+		return nil, ErrEnvelopeInvalid
+	}
+	aead, err := newEnvelopeAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := aead.Open(nil, sealed[:envelopeNonceSize], sealed[envelopeNonceSize:], envelopeAAD(ref, env.keyID))
+	if err != nil {
+		return nil, ErrEnvelopeInvalid
+	}
+	return payload, nil
+}
+
+func parseEnvelopeMetadata(metadata map[string]string) (envelope, error) {
+	if metadata[envelopeMetaVersion] != envelopeVersionV1 {
+		return envelope{}, ErrEnvelopeInvalid
+	}
+	keyID := metadata[envelopeMetaKeyID]
+	wrapped, err := base64.StdEncoding.DecodeString(metadata[envelopeMetaWrappedKey])
+	if err != nil || keyID == "" || len(wrapped) == 0 {
+		return envelope{}, ErrEnvelopeInvalid
+	}
+	size, err := strconv.ParseInt(metadata[envelopeMetaSize], 10, 64)
+	if err != nil || size < 0 || size > math.MaxInt64-envelopeOverhead {
+		return envelope{}, ErrEnvelopeInvalid
+	}
+	return envelope{keyID: keyID, wrapped: wrapped, size: size}, nil
+}
+
+func checkReservedMetadata(metadata map[string]string) error {
+	for key := range metadata {
+		if strings.HasPrefix(strings.ToLower(key), EnvelopeMetadataPrefix) {
+			return ErrReservedMetadata
+		}
+	}
+	return nil
+}
+
+func stripEnvelopeMetadata(metadata map[string]string) map[string]string {
+	var out map[string]string
+	for k, v := range metadata {
+		if strings.HasPrefix(k, EnvelopeMetadataPrefix) {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string, len(metadata))
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// sealedLimit converts a plaintext byte cap to the matching ciphertext cap.
+func sealedLimit(maxBytes int64) int64 {
+	if maxBytes > math.MaxInt64-envelopeOverhead {
+		return math.MaxInt64
+	}
+	return maxBytes + envelopeOverhead
+}
+
+func newEnvelopeAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidEnvelopeKey
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,182 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+)
+
+// RewrapInput selects one object whose data key should be re-wrapped under
+// the key the store's KeySelector picks for Ref.
+//
+// Ref must not carry a VersionID because the re-wrapped envelope is written
+// back to the current object.
+type RewrapInput struct {
+	Ref ObjectRef
+}
+
+// RewrapResult reports the wrapping keys before and after a Rewrap.
+type RewrapResult struct {
+	Ref       ObjectRef
+	FromKeyID string
+	ToKeyID   string
+}
+
+// RewrapPrefixInput selects the objects RewrapPrefix visits.
+//
+// Each object is re-wrapped under the key the store's KeySelector picks for
+// it. FromKeyID, when set, limits re-wrapping to objects currently wrapped
+// under that key. With FromKeyID empty, objects already wrapped under their
+// target key are skipped; setting FromKeyID to the target key re-wraps them
+// anyway.
+type RewrapPrefixInput struct {
+	Bucket    string
+	Prefix    string
+	FromKeyID string
+}
+
+// RewrapReport counts the objects visited by RewrapPrefix.
+//
+// Skipped counts objects filtered out by FromKeyID, already under their target
+// key, or overwritten concurrently (their new envelope was wrapped by the
+// writer).
+type RewrapReport struct {
+	Scanned   int
+	Rewrapped int
+	Skipped   int
+}
+
+// Rewrap unwraps the object's data key, wraps it under the selected key, and
+// seals the payload again under the same data key, because the payload is
+// bound to its key ID. Objects must be wrapped under the selected or previous
+// key (see EnvelopeStoreConfig). The rewrite is conditioned on the object's
+// ETag, so a concurrent overwrite fails with ErrPreconditionFailed instead of
+// being clobbered.
+func (s *EnvelopeStore) Rewrap(ctx context.Context, input RewrapInput) (*RewrapResult, error) {
+	if err := s.requireInner(); err != nil {
+		return nil, err
+	}
+	if err := validateWriteRef(input.Ref); err != nil {
+		return nil, err
+	}
+	keyID, err := s.selectKey(input.Ref)
+	if err != nil {
+		return nil, err
+	}
+	fromKeyID, err := s.rewrapObject(ctx, input.Ref, "", keyID)
+	if err != nil {
+		return nil, err
+	}
+	return &RewrapResult{Ref: input.Ref, FromKeyID: fromKeyID, ToKeyID: keyID}, nil
+}
+
+// RewrapPrefix re-wraps every envelope under Prefix, paging through List. It
+// stops at the first error other than a concurrent overwrite and returns the
+// counts accumulated so far, so a retried run only repeats unfinished work.
+func (s *EnvelopeStore) RewrapPrefix(ctx context.Context, input RewrapPrefixInput) (RewrapReport, error) {
+	var report RewrapReport
+	if err := s.requireInner(); err != nil {
+		return report, err
+	}
+	listInput := ListInput{Bucket: input.Bucket, Prefix: input.Prefix}
+	for {
+		page, err := s.inner.List(ctx, listInput)
+		if err != nil {
+			return report, err
+		}
+		for _, obj := range page.Objects {
+			report.Scanned++
+			rewrapped, err := s.rewrapListed(ctx, obj.Ref, input)
+			if err != nil {
+				return report, err
+			}
+			if rewrapped {
+				report.Rewrapped++
+			} else {
+				report.Skipped++
+			}
+		}
+		if !page.IsTruncated {
+			return report, nil
+		}
+		listInput.ContinuationToken = page.NextContinuationToken
+	}
+}
+
+func (s *EnvelopeStore) rewrapListed(ctx context.Context, ref ObjectRef, input RewrapPrefixInput) (bool, error) {
+	info, err := s.inner.Head(ctx, HeadInput{Ref: ref})
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	env, err := parseEnvelopeMetadata(info.Metadata)
+	if err != nil {
+		return false, err
+	}
+	if input.FromKeyID != "" && env.keyID != input.FromKeyID {
+		return false, nil
+	}
+	keyID, err := s.selectKey(ref)
+	if err != nil {
+		return false, err
+	}
+	if input.FromKeyID == "" && keyID == env.keyID {
+		return false, nil
+	}
+	_, err = s.rewrapObject(ctx, info.Ref, info.ETag, keyID)
+	if errors.Is(err, ErrPreconditionFailed) || errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// rewrapObject re-wraps the current object at ref under keyID and returns the
+// key it was wrapped under. A non-empty etag must match the object read.
+func (s *EnvelopeStore) rewrapObject(ctx context.Context, ref ObjectRef, etag string, keyID string) (string, error) {
+	ref.VersionID = ""
+	out, err := s.inner.Get(ctx, GetInput{Ref: ref, MaxBytes: sealedLimit(s.maxBytes)})
+	if err != nil {
+		return "", err
+	}
+	if etag != "" && out.ETag != etag {
+		return "", ErrPreconditionFailed
+	}
+	env, err := parseEnvelopeMetadata(out.Metadata)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkKey(ref, env.keyID); err != nil {
+		return "", err
+	}
+	key, err := s.unwrapKey(ctx, env)
+	if err != nil {
+		return "", err
+	}
+	defer clear(key)
+
+	payload, err := openPayload(key, ref, env, out.Payload)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := sealPayload(key, ref, keyID, payload)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := s.provider.WrapDataKey(ctx, keyID, key)
+	if err != nil {
+		return "", err
+	}
+	if len(wrapped) == 0 {
+		return "", ErrInvalidEnvelopeKey
+	}
+	next := envelope{keyID: keyID, wrapped: wrapped, size: env.size}
+	_, err = s.inner.Put(ctx, PutInput{
+		Ref:         ref,
+		Payload:     sealed,
+		ContentType: out.ContentType,
+		Metadata:    next.metadata(stripEnvelopeMetadata(out.Metadata)),
+		Condition:   WriteCondition{IfMatch: out.ETag},
+	})
+	return env.keyID, err
+}
@@ -0,0 +1,328 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, DataKeySize)
+}
+
+func newTestEnvelopeStore(t *testing.T, selectKey KeySelector) (*EnvelopeStore, Store) {
+	t.Helper()
+	return newTestRotatingEnvelopeStore(t, selectKey, nil)
+}
+
+func newTestRotatingEnvelopeStore(t *testing.T, selectKey, previousKey KeySelector) (*EnvelopeStore, Store) {
+	t.Helper()
+	inner, err := NewMemoryStore(MemoryStoreConfig{})
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+	provider, err := NewLocalKeyProvider(map[string][]byte{
+		"old":          testKey(1),
+		"new":          testKey(2),
+		"tenant-acme":  testKey(3),
+		"tenant-globe": testKey(4),
+	})
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider() error = %v", err)
+	}
+	store, err := NewEnvelopeStore(inner, EnvelopeStoreConfig{Provider: provider, SelectKey: selectKey, PreviousSelectKey: previousKey})
+	if err != nil {
+		t.Fatalf("NewEnvelopeStore() error = %v", err)
+	}
+	return store, inner
+}
+
+func TestEnvelopeStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store, inner := newTestEnvelopeStore(t, StaticKeySelector("old"))
+	ref := ObjectRef{Bucket: "bucket", Key: "docs/a.txt"}
+
+	if _, err := store.Put(ctx, PutInput{
+		Ref:         ref,
+		Payload:     []byte("secret payload"),
+		ContentType: "text/plain",
+		Metadata:    map[string]string{"owner": "ops"},
+	}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	raw, err := inner.Get(ctx, GetInput{Ref: ref, MaxBytes: 1024})
+	if err != nil {
+		t.Fatalf("inner Get() error = %v", err)
+	}
+	if bytes.Contains(raw.Payload, []byte("secret")) || len(raw.Payload) != len("secret payload")+envelopeOverhead {
+		t.Fatalf("stored payload = %q, want ciphertext", raw.Payload)
+	}
+	if raw.Metadata[envelopeMetaKeyID] != "old" || raw.Metadata[envelopeMetaVersion] != envelopeVersionV1 {
+		t.Fatalf("stored metadata = %#v, want envelope metadata", raw.Metadata)
+	}
+
+	out, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 1024})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(out.Payload) != "secret payload" || out.ContentType != "text/plain" {
+		t.Fatalf("Get() = %q %q, want decrypted payload", out.Payload, out.ContentType)
+	}
+	if len(out.Metadata) != 1 || out.Metadata["owner"] != "ops" {
+		t.Fatalf("Get() metadata = %#v, want caller metadata only", out.Metadata)
+	}
+	if _, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 6}); !errors.Is(err, ErrObjectTooLarge) {
+		t.Fatalf("Get() small limit error = %v, want ErrObjectTooLarge", err)
+	}
+
+	info, err := store.Head(ctx, HeadInput{Ref: ref})
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	if info.Size != int64(len("secret payload")) || info.Metadata["owner"] != "ops" || len(info.Metadata) != 1 {
+		t.Fatalf("Head() = %#v, want plaintext size and caller metadata", info)
+	}
+
+	if _, err := store.PutReader(ctx, PutReaderInput{Ref: ref, Body: strings.NewReader("streamed")}); err != nil {
+		t.Fatalf("PutReader() error = %v", err)
+	}
+	reader, err := store.GetReader(ctx, GetInput{Ref: ref, MaxBytes: 1024})
+	if err != nil {
+		t.Fatalf("GetReader() error = %v", err)
+	}
+	body, err := io.ReadAll(reader.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if err := reader.Body.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if string(body) != "streamed" || reader.Info.Size != int64(len("streamed")) {
+		t.Fatalf("GetReader() = %q size %d, want streamed", body, reader.Info.Size)
+	}
+
+	if err := store.Delete(ctx, DeleteInput{Ref: ref}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 1024}); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("Get() after delete error = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestEnvelopeStoreFailsClosed(t *testing.T) {
+	ctx := context.Background()
+	store, inner := newTestEnvelopeStore(t, StaticKeySelector("old"))
+	ref := ObjectRef{Bucket: "bucket", Key: "docs/a.txt"}
+
+	if _, err := store.Put(ctx, PutInput{Ref: ref, Payload: []byte("x"), Metadata: map[string]string{"Apptheory-Envelope-Key-Id": "new"}}); !errors.Is(err, ErrReservedMetadata) {
+		t.Fatalf("Put() reserved metadata error = %v, want ErrReservedMetadata", err)
+	}
+
+	plain := ObjectRef{Bucket: "bucket", Key: "docs/plain.txt"}
+	if _, err := inner.Put(ctx, PutInput{Ref: plain, Payload: []byte("plaintext")}); err != nil {
+		t.Fatalf("inner Put() error = %v", err)
+	}
+	if _, err := store.Get(ctx, GetInput{Ref: plain, MaxBytes: 1024}); !errors.Is(err, ErrEnvelopeInvalid) {
+		t.Fatalf("Get() plaintext object error = %v, want ErrEnvelopeInvalid", err)
+	}
+	if _, err := store.Head(ctx, HeadInput{Ref: plain}); !errors.Is(err, ErrEnvelopeInvalid) {
+		t.Fatalf("Head() plaintext object error = %v, want ErrEnvelopeInvalid", err)
+	}
+
+	if _, err := store.Put(ctx, PutInput{Ref: ref, Payload: []byte("secret payload")}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	raw, err := inner.Get(ctx, GetInput{Ref: ref, MaxBytes: 1024})
+	if err != nil {
+		t.Fatalf("inner Get() error = %v", err)
+	}
+	raw.Payload[len(raw.Payload)-1] ^= 0xff
+	if _, err := inner.Put(ctx, PutInput{Ref: ref, Payload: raw.Payload, Metadata: raw.Metadata}); err != nil {
+		t.Fatalf("inner Put() tampered error = %v", err)
+	}
+	if _, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 1024}); !errors.Is(err, ErrEnvelopeInvalid) {
+		t.Fatalf("Get() tampered error = %v, want ErrEnvelopeInvalid", err)
+	}
+
+	raw.Payload[len(raw.Payload)-1] ^= 0xff
+	raw.Metadata[envelopeMetaKeyID] = "new"
+	if _, err := inner.Put(ctx, PutInput{Ref: ref, Payload: raw.Payload, Metadata: raw.Metadata}); err != nil {
+		t.Fatalf("inner Put() swapped key error = %v", err)
+	}
+	if _, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 1024}); !errors.Is(err, ErrEnvelopeInvalid) {
+		t.Fatalf("Get() swapped key id error = %v, want ErrEnvelopeInvalid", err)
+	}
+
+	unknown, _ := newTestEnvelopeStore(t, StaticKeySelector("missing"))
+	if _, err := unknown.Put(ctx, PutInput{Ref: ref, Payload: []byte("x")}); !errors.Is(err, ErrInvalidEnvelopeKey) {
+		t.Fatalf("Put() unknown key error = %v, want ErrInvalidEnvelopeKey", err)
+	}
+
+	if _, err := NewEnvelopeStore(inner, EnvelopeStoreConfig{SelectKey: StaticKeySelector("old")}); !errors.Is(err, ErrInvalidEnvelopeConfig) {
+		t.Fatalf("NewEnvelopeStore() missing provider error = %v, want ErrInvalidEnvelopeConfig", err)
+	}
+	if _, err := NewLocalKeyProvider(map[string][]byte{"short": []byte("key")}); !errors.Is(err, ErrInvalidEnvelopeConfig) {
+		t.Fatalf("NewLocalKeyProvider() short key error = %v, want ErrInvalidEnvelopeConfig", err)
+	}
+	var zero *EnvelopeStore
+	if _, err := zero.Get(ctx, GetInput{Ref: ref, MaxBytes: 1}); !errors.Is(err, ErrInvalidEnvelopeConfig) {
+		t.Fatalf("zero Get() error = %v, want ErrInvalidEnvelopeConfig", err)
+	}
+}
+
+func TestTenantKeySelector(t *testing.T) {
+	selectKey, err := TenantKeySelector("alias/tenant-{tenant}")
+	if err != nil {
+		t.Fatalf("TenantKeySelector() error = %v", err)
+	}
+	keyID, err := selectKey(ObjectRef{Bucket: "bucket", Key: "acme/docs/a.txt"})
+	if err != nil || keyID != "alias/tenant-acme" {
+		t.Fatalf("selectKey() = %q, %v, want alias/tenant-acme", keyID, err)
+	}
+	for _, key := range []string{"no-tenant", "/docs/a.txt", "ac.me/a.txt"} {
+		if _, err := selectKey(ObjectRef{Bucket: "bucket", Key: key}); !errors.Is(err, ErrInvalidEnvelopeKey) {
+			t.Fatalf("selectKey(%q) error = %v, want ErrInvalidEnvelopeKey", key, err)
+		}
+	}
+	for _, format := range []string{"alias/static", "{tenant}-{tenant}"} {
+		if _, err := TenantKeySelector(format); !errors.Is(err, ErrInvalidEnvelopeConfig) {
+			t.Fatalf("TenantKeySelector(%q) error = %v, want ErrInvalidEnvelopeConfig", format, err)
+		}
+	}
+}
+
+func TestEnvelopeStoreCopyRewrapsForDestinationTenant(t *testing.T) {
+	ctx := context.Background()
+	selectKey, err := TenantKeySelector("tenant-{tenant}")
+	if err != nil {
+		t.Fatalf("TenantKeySelector() error = %v", err)
+	}
+	store, inner := newTestEnvelopeStore(t, selectKey)
+	source := ObjectRef{Bucket: "bucket", Key: "acme/report.csv"}
+	dest := ObjectRef{Bucket: "bucket", Key: "globe/report.csv"}
+
+	if _, err := store.Put(ctx, PutInput{Ref: source, Payload: []byte("a,b"), Metadata: map[string]string{"kind": "report"}}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := store.Copy(ctx, CopyInput{Source: source, Destination: dest}); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+
+	raw, err := inner.Head(ctx, HeadInput{Ref: dest})
+	if err != nil {
+		t.Fatalf("inner Head() error = %v", err)
+	}
+	if raw.Metadata[envelopeMetaKeyID] != "tenant-globe" {
+		t.Fatalf("copied key id = %q, want tenant-globe", raw.Metadata[envelopeMetaKeyID])
+	}
+	out, err := store.Get(ctx, GetInput{Ref: dest, MaxBytes: 1024})
+	if err != nil {
+		t.Fatalf("Get() copy error = %v", err)
+	}
+	if string(out.Payload) != "a,b" || out.Metadata["kind"] != "report" {
+		t.Fatalf("Get() copy = %q %#v, want source payload and metadata", out.Payload, out.Metadata)
+	}
+
+	if _, err := store.Copy(ctx, CopyInput{Source: source, Destination: dest, SourceIfMatch: `"stale"`}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Copy() stale source error = %v, want ErrPreconditionFailed", err)
+	}
+	if _, err := store.Copy(ctx, CopyInput{Source: source, Destination: ObjectRef{Bucket: "bucket", Key: "loose.csv"}}); !errors.Is(err, ErrInvalidEnvelopeKey) {
+		t.Fatalf("Copy() untenanted destination error = %v, want ErrInvalidEnvelopeKey", err)
+	}
+}
+
+func TestEnvelopeStoreRejectsRelocatedCiphertext(t *testing.T) {
+	ctx := context.Background()
+	selectKey, err := TenantKeySelector("tenant-{tenant}")
+	if err != nil {
+		t.Fatalf("TenantKeySelector() error = %v", err)
+	}
+	store, inner := newTestEnvelopeStore(t, selectKey)
+	source := ObjectRef{Bucket: "bucket", Key: "acme/report.csv"}
+	if _, err := store.Put(ctx, PutInput{Ref: source, Payload: []byte("a,b")}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	for _, dest := range []ObjectRef{
+		{Bucket: "bucket", Key: "globe/report.csv"},
+		{Bucket: "bucket", Key: "acme/moved.csv"},
+		{Bucket: "other", Key: "acme/report.csv"},
+	} {
+		if _, err := inner.Copy(ctx, CopyInput{Source: source, Destination: dest}); err != nil {
+			t.Fatalf("inner Copy(%v) error = %v", dest, err)
+		}
+		if _, err := store.Get(ctx, GetInput{Ref: dest, MaxBytes: 1024}); !errors.Is(err, ErrEnvelopeInvalid) {
+			t.Fatalf("Get(%v) relocated error = %v, want ErrEnvelopeInvalid", dest, err)
+		}
+	}
+}
+
+func TestEnvelopeStoreRewrap(t *testing.T) {
+	ctx := context.Background()
+	store, inner := newTestRotatingEnvelopeStore(t, StaticKeySelector("new"), StaticKeySelector("old"))
+	oldStore, _ := newTestEnvelopeStore(t, StaticKeySelector("old"))
+	oldStore.inner = inner
+	current, _ := newTestEnvelopeStore(t, StaticKeySelector("new"))
+	current.inner = inner
+
+	for _, key := range []string{"docs/a", "docs/b", "docs/c"} {
+		if _, err := oldStore.Put(ctx, PutInput{Ref: ObjectRef{Bucket: "bucket", Key: key}, Payload: []byte(key)}); err != nil {
+			t.Fatalf("Put(%s) error = %v", key, err)
+		}
+	}
+	if _, err := store.Put(ctx, PutInput{Ref: ObjectRef{Bucket: "bucket", Key: "docs/d"}, Payload: []byte("docs/d")}); err != nil {
+		t.Fatalf("Put(docs/d) error = %v", err)
+	}
+
+	if _, err := current.Get(ctx, GetInput{Ref: ObjectRef{Bucket: "bucket", Key: "docs/a"}, MaxBytes: 1024}); !errors.Is(err, ErrEnvelopeInvalid) {
+		t.Fatalf("Get() without previous key error = %v, want ErrEnvelopeInvalid", err)
+	}
+	if out, err := store.Get(ctx, GetInput{Ref: ObjectRef{Bucket: "bucket", Key: "docs/a"}, MaxBytes: 1024}); err != nil || string(out.Payload) != "docs/a" {
+		t.Fatalf("Get() with previous key = %v, %v, want original payload", out, err)
+	}
+	result, err := store.Rewrap(ctx, RewrapInput{Ref: ObjectRef{Bucket: "bucket", Key: "docs/a"}})
+	if err != nil {
+		t.Fatalf("Rewrap() error = %v", err)
+	}
+	if result.FromKeyID != "old" || result.ToKeyID != "new" {
+		t.Fatalf("Rewrap() = %#v, want old -> new", result)
+	}
+
+	report, err := store.RewrapPrefix(ctx, RewrapPrefixInput{Bucket: "bucket", Prefix: "docs/"})
+	if err != nil {
+		t.Fatalf("RewrapPrefix() error = %v", err)
+	}
+	if report != (RewrapReport{Scanned: 4, Rewrapped: 2, Skipped: 2}) {
+		t.Fatalf("RewrapPrefix() = %#v, want 4 scanned, 2 rewrapped, 2 skipped", report)
+	}
+
+	for _, key := range []string{"docs/a", "docs/b", "docs/c", "docs/d"} {
+		ref := ObjectRef{Bucket: "bucket", Key: key}
+		raw, err := inner.Head(ctx, HeadInput{Ref: ref})
+		if err != nil {
+			t.Fatalf("inner Head(%s) error = %v", key, err)
+		}
+		if raw.Metadata[envelopeMetaKeyID] != "new" {
+			t.Fatalf("%s key id = %q, want new", key, raw.Metadata[envelopeMetaKeyID])
+		}
+		out, err := current.Get(ctx, GetInput{Ref: ref, MaxBytes: 1024})
+		if err != nil || string(out.Payload) != key {
+			t.Fatalf("Get(%s) = %v, %v, want original payload", key, out, err)
+		}
+	}
+
+	forced, err := store.RewrapPrefix(ctx, RewrapPrefixInput{Bucket: "bucket", Prefix: "docs/", FromKeyID: "new"})
+	if err != nil {
+		t.Fatalf("RewrapPrefix() forced error = %v", err)
+	}
+	if forced.Rewrapped != 4 {
+		t.Fatalf("RewrapPrefix() forced = %#v, want 4 rewrapped", forced)
+	}
+	if _, err := store.Rewrap(ctx, RewrapInput{Ref: ObjectRef{Bucket: "bucket", Key: "docs/a", VersionID: "v1"}}); !errors.Is(err, ErrInvalidObjectRef) {
+		t.Fatalf("Rewrap() versioned ref error = %v, want ErrInvalidObjectRef", err)
+	}
+}
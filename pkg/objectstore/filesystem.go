@@ -0,0 +1,653 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	filesystemSidecarName   = ".apptheory-object.json"
+	filesystemPayloadPrefix = ".apptheory-payload-"
+	filesystemTempPrefix    = ".apptheory-tmp-"
+	filesystemStagingDir    = ".apptheory-staging"
+	filesystemDirPerm       = 0o750
+	filesystemFilePerm      = 0o640
+)
+
+// FilesystemStoreConfig configures the local filesystem-backed Store implementation.
+//
+// Root is required and must be an existing directory. Encryption is validated
+// with the same fail-closed rules as S3StoreConfig so local and S3 deployments
+// share one configuration shape; the filesystem store relies on disk-level
+// encryption and does not encrypt payloads itself.
+type FilesystemStoreConfig struct {
+	Root       string
+	Encryption S3EncryptionConfig
+}
+
+// NewFilesystemStore returns a Store that maps each bucket to a directory under
+// Root and each key to a directory of escaped key segments.
+//
+// Writes are atomic: the payload is streamed to a staging file, moved next to
+// the object, and then committed by renaming a sidecar that records the payload
+// file name, ETag, content type, and metadata. Readers therefore observe either
+// the previous object or the new one, never a partial write. Write conditions
+// are checked at commit time under a per-store lock, so they only coordinate
+// writers that share this Store; a conditional write can still overwrite an
+// object another process committed after the check. Use one Store per root when
+// conditions matter. The store is unversioned; refs that carry a VersionID are
+// rejected with ErrInvalidObjectRef.
+func NewFilesystemStore(storeConfig FilesystemStoreConfig) (Store, error) {
+	if _, err := normalizeS3StoreConfig(S3StoreConfig{Encryption: storeConfig.Encryption}); err != nil {
+		return nil, err
+	}
+	root := strings.TrimSpace(storeConfig.Root)
+	if root == "" || root != storeConfig.Root {
+		return nil, ErrInvalidStoreConfig
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, ErrInvalidStoreConfig
+	}
+	info, err := os.Stat(abs)
+	if err != nil || !info.IsDir() {
+		return nil, ErrInvalidStoreConfig
+	}
+	return &filesystemStore{root: abs}, nil
+}
+
+type filesystemStore struct {
+	// mu serializes commits through this Store, which makes condition checks
+	// exact for its own writers only. Other processes never see a partial
+	// object because every commit is a rename, but their commits can race a
+	// condition check.
+	mu   sync.Mutex
+	root string
+}
+
+type filesystemSidecar struct {
+	Payload      string            `json:"payload"`
+	Size         int64             `json:"size"`
+	ETag         string            `json:"etag"`
+	LastModified time.Time         `json:"lastModified"`
+	ContentType  string            `json:"contentType,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// filesystemStaged is a fully written payload waiting to be committed.
+type filesystemStaged struct {
+	path string
+	size int64
+	etag string
+}
+
+func (s *filesystemStore) Put(_ context.Context, input PutInput) (ObjectRef, error) {
+	if err := s.requireRoot(); err != nil {
+		return ObjectRef{}, err
+	}
+	if err := validatePutInput(input); err != nil {
+		return ObjectRef{}, err
+	}
+	staged, err := s.stage(bytes.NewReader(input.Payload))
+	if err != nil {
+		return ObjectRef{}, err
+	}
+	return s.commit(input.Ref, staged, input.ContentType, input.Metadata, input.Condition)
+}
+
+// PutETag is Put that also returns the new object's ETag, which is a digest
+// of the payload.
+func (s *filesystemStore) PutETag(ctx context.Context, input PutInput) (ObjectRef, string, error) {
+	ref, err := s.Put(ctx, input)
+	if err != nil {
+		return ObjectRef{}, "", err
+	}
+	return ref, payloadETag(input.Payload), nil
+}
+
+func (s *filesystemStore) PutReader(_ context.Context, input PutReaderInput) (ObjectRef, error) {
+	if err := s.requireRoot(); err != nil {
+		return ObjectRef{}, err
+	}
+	if err := validatePutReaderInput(input); err != nil {
+		return ObjectRef{}, err
+	}
+	staged, err := s.stage(input.Body)
+	if err != nil {
+		return ObjectRef{}, err
+	}
+	return s.commit(input.Ref, staged, input.ContentType, input.Metadata, input.Condition)
+}
+
+func (s *filesystemStore) Copy(_ context.Context, input CopyInput) (ObjectRef, error) {
+	if err := s.requireRoot(); err != nil {
+		return ObjectRef{}, err
+	}
+	if err := validateCopyInput(input); err != nil {
+		return ObjectRef{}, err
+	}
+	if input.Source.VersionID != "" {
+		return ObjectRef{}, ErrInvalidObjectRef
+	}
+
+	sidecar, body, err := s.open(input.Source)
+	if err != nil {
+		return ObjectRef{}, err
+	}
+	if input.SourceIfMatch != "" && sidecar.ETag != input.SourceIfMatch {
+		closeBestEffort(body)
+		return ObjectRef{}, ErrPreconditionFailed
+	}
+	staged, stageErr := s.stage(body)
+	closeBestEffort(body)
+	if stageErr != nil {
+		return ObjectRef{}, stageErr
+	}
+
+	contentType, metadata := sidecar.ContentType, sidecar.Metadata
+	if input.ReplaceMetadata {
+		contentType, metadata = input.ContentType, input.Metadata
+	}
+	return s.commit(input.Destination, staged, contentType, metadata, input.Condition)
+}
+
+func (s *filesystemStore) Get(_ context.Context, input GetInput) (*GetOutput, error) {
+	if err := s.requireRoot(); err != nil {
+		return nil, err
+	}
+	if err := validateGetInput(input); err != nil {
+		return nil, err
+	}
+	if input.Ref.VersionID != "" {
+		return nil, ErrInvalidObjectRef
+	}
+
+	sidecar, body, err := s.open(input.Ref)
+	if err != nil {
+		return nil, err
+	}
+	if sidecar.Size > input.MaxBytes {
+		closeBestEffort(body)
+		return nil, ErrObjectTooLarge
+	}
+	payload, readErr := readBounded(body, input.MaxBytes)
+	closeErr := body.Close()
+	if readErr != nil {
+		return nil, readErr
+	}
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	return &GetOutput{
+		Ref:         input.Ref,
+		Payload:     payload,
+		ContentType: sidecar.ContentType,
+		Metadata:    cloneMetadata(sidecar.Metadata),
+		ETag:        sidecar.ETag,
+	}, nil
+}
+
+func (s *filesystemStore) GetReader(_ context.Context, input GetInput) (*GetReaderOutput, error) {
+	if err := s.requireRoot(); err != nil {
+		return nil, err
+	}
+	if err := validateGetInput(input); err != nil {
+		return nil, err
+	}
+	if input.Ref.VersionID != "" {
+		return nil, ErrInvalidObjectRef
+	}
+
+	sidecar, body, err := s.open(input.Ref)
+	if err != nil {
+		return nil, err
+	}
+	if sidecar.Size > input.MaxBytes {
+		closeBestEffort(body)
+		return nil, ErrObjectTooLarge
+	}
+	return &GetReaderOutput{
+		Info: sidecar.info(input.Ref),
+		Body: newBoundedReadCloser(body, input.MaxBytes),
+	}, nil
+}
+
+func (s *filesystemStore) Head(_ context.Context, input HeadInput) (*ObjectInfo, error) {
+	if err := s.requireRoot(); err != nil {
+		return nil, err
+	}
+	if err := validateHeadInput(input); err != nil {
+		return nil, err
+	}
+	if input.Ref.VersionID != "" {
+		return nil, ErrInvalidObjectRef
+	}
+	sidecar, err := readFilesystemSidecar(s.objectDir(input.Ref))
+	if err != nil {
+		return nil, err
+	}
+	info := sidecar.info(input.Ref)
+	return &info, nil
+}
+
+func (s *filesystemStore) List(_ context.Context, input ListInput) (*ListOutput, error) {
+	if err := s.requireRoot(); err != nil {
+		return nil, err
+	}
+	input, err := validateListInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	pager, err := newListPager(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.walkList(s.bucketDir(input.Bucket), input.Bucket, "", pager); err != nil {
+		return nil, err
+	}
+	return pager.out, nil
+}
+
+// filesystemListEntry is a key directory seen by walkList, either as the object
+// stored at key or as the subtree of keys starting with key.
+type filesystemListEntry struct {
+	key  string
+	dir  string
+	tree bool
+}
+
+// walkList feeds pager the objects under dir, whose keys start with keyPrefix,
+// in ascending key order. An object sorts by its key and its subtree by the
+// key plus "/", so siblings such as "a-b" and "a/c" interleave as S3 orders
+// them. Subtrees the page cannot use are skipped and the walk stops once the
+// page is full, so each page reads only the directories it covers.
+func (s *filesystemStore) walkList(dir string, bucket string, keyPrefix string, pager *listPager) error {
+	dirEntries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	entries := make([]filesystemListEntry, 0, 2*len(dirEntries))
+	for _, entry := range dirEntries {
+		segment, ok := decodeFilesystemSegment(entry.Name())
+		if !entry.IsDir() || !ok {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		key := keyPrefix + segment
+		entries = append(entries, filesystemListEntry{key: key, dir: path}, filesystemListEntry{key: key + "/", dir: path, tree: true})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	for _, entry := range entries {
+		if pager.done {
+			return nil
+		}
+		if entry.tree {
+			if !pager.wantsTree(entry.key) {
+				continue
+			}
+			if err := s.walkList(entry.dir, bucket, entry.key, pager); err != nil {
+				return err
+			}
+			continue
+		}
+		if !pager.wants(entry.key) {
+			continue
+		}
+		sidecar, err := readFilesystemSidecar(entry.dir)
+		if errors.Is(err, ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		pager.add(sidecar.info(ObjectRef{Bucket: bucket, Key: entry.key}))
+	}
+	return nil
+}
+
+func (s *filesystemStore) Delete(_ context.Context, input DeleteInput) error {
+	if err := s.requireRoot(); err != nil {
+		return err
+	}
+	if err := validateDeleteInput(input); err != nil {
+		return err
+	}
+	if input.Ref.VersionID != "" {
+		return ErrInvalidObjectRef
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := s.objectDir(input.Ref)
+	sidecar, err := readFilesystemSidecar(dir)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, filesystemSidecarName)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, sidecar.Payload)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	pruneEmptyDirs(dir, s.bucketDir(input.Ref.Bucket))
+	return nil
+}
+
+// stage streams body into a file under the staging directory and returns its
+// size and ETag. Staging happens outside the commit lock so large uploads do
+// not block other writers.
+func (s *filesystemStore) stage(body io.Reader) (staged filesystemStaged, err error) {
+	dir := filepath.Join(s.root, filesystemStagingDir)
+	if err := os.MkdirAll(dir, filesystemDirPerm); err != nil {
+		return filesystemStaged{}, err
+	}
+	tmp, err := os.CreateTemp(dir, filesystemTempPrefix+"*")
+	if err != nil {
+		return filesystemStaged{}, err
+	}
+	defer func() {
+		if err != nil {
+			closeBestEffort(tmp)
+			removeBestEffort(tmp.Name())
+		}
+	}()
+	if err = tmp.Chmod(filesystemFilePerm); err != nil {
+		return filesystemStaged{}, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), body)
+	if err != nil {
+		return filesystemStaged{}, err
+	}
+	if err = tmp.Sync(); err != nil {
+		return filesystemStaged{}, err
+	}
+	if err = tmp.Close(); err != nil {
+		return filesystemStaged{}, err
+	}
+	return filesystemStaged{path: tmp.Name(), size: size, etag: contentETag(hash.Sum(nil))}, nil
+}
+
+// commit moves a staged payload next to ref and atomically replaces the
+// object's sidecar, honoring condition against the object being replaced.
+func (s *filesystemStore) commit(ref ObjectRef, staged filesystemStaged, contentType string, metadata map[string]string, condition WriteCondition) (ObjectRef, error) {
+	committed := false
+	defer func() {
+		if !committed {
+			removeBestEffort(staged.path)
+		}
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := s.objectDir(ref)
+	previous, previousErr := readFilesystemSidecar(dir)
+	if previousErr != nil && !errors.Is(previousErr, ErrObjectNotFound) {
+		return ObjectRef{}, previousErr
+	}
+	current := ""
+	if previousErr == nil {
+		current = previous.ETag
+	}
+	if err := condition.check(previousErr == nil, current); err != nil {
+		return ObjectRef{}, err
+	}
+
+	if err := os.MkdirAll(dir, filesystemDirPerm); err != nil {
+		return ObjectRef{}, err
+	}
+	payloadName, err := uniqueFilesystemName(filesystemPayloadPrefix)
+	if err != nil {
+		return ObjectRef{}, err
+	}
+	payloadPath := filepath.Join(dir, payloadName)
+	if err := os.Rename(staged.path, payloadPath); err != nil {
+		return ObjectRef{}, err
+	}
+	committed = true
+
+	sidecar, err := json.Marshal(filesystemSidecar{
+		Payload:      payloadName,
+		Size:         staged.size,
+		ETag:         staged.etag,
+		LastModified: time.Now().UTC(),
+		ContentType:  contentType,
+		Metadata:     cloneMetadata(metadata),
+	})
+	if err != nil {
+		removeBestEffort(payloadPath)
+		return ObjectRef{}, err
+	}
+	if err := writeFileAtomic(dir, filesystemSidecarName, sidecar); err != nil {
+		removeBestEffort(payloadPath)
+		return ObjectRef{}, err
+	}
+	if previousErr == nil && previous.Payload != payloadName {
+		removeBestEffort(filepath.Join(dir, previous.Payload))
+	}
+
+	ref.VersionID = ""
+	return ref, nil
+}
+
+// open returns the sidecar and an open payload file for ref. A concurrent
+// writer may replace the payload between reading the sidecar and opening the
+// payload file, so open retries once against the new sidecar.
+func (s *filesystemStore) open(ref ObjectRef) (*filesystemSidecar, *os.File, error) {
+	dir := s.objectDir(ref)
+	for attempt := 0; ; attempt++ {
+		sidecar, err := readFilesystemSidecar(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		f, err := os.Open(filepath.Join(dir, sidecar.Payload)) // #nosec G304 -- payload name is validated by readFilesystemSidecar
+		if errors.Is(err, fs.ErrNotExist) && attempt == 0 {
+			continue
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrObjectNotFound
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return sidecar, f, nil
+	}
+}
+
+func (s *filesystemStore) requireRoot() error {
+	if s == nil || s.root == "" {
+		return ErrInvalidStoreConfig
+	}
+	return nil
+}
+
+func (s *filesystemStore) bucketDir(bucket string) string {
+	return filepath.Join(s.root, encodeFilesystemSegment(bucket))
+}
+
+func (s *filesystemStore) objectDir(ref ObjectRef) string {
+	segments := strings.Split(ref.Key, "/")
+	parts := make([]string, 0, len(segments)+1)
+	parts = append(parts, s.bucketDir(ref.Bucket))
+	for _, segment := range segments {
+		parts = append(parts, encodeFilesystemSegment(segment))
+	}
+	return filepath.Join(parts...)
+}
+
+func (c *filesystemSidecar) info(ref ObjectRef) ObjectInfo {
+	return ObjectInfo{
+		Ref:          ref,
+		Size:         c.Size,
+		ETag:         c.ETag,
+		LastModified: c.LastModified,
+		ContentType:  c.ContentType,
+		Metadata:     cloneMetadata(c.Metadata),
+	}
+}
+
+// pruneEmptyDirs removes now-empty key directories between dir and the bucket
+// directory so deleted prefixes do not accumulate on disk.
+func pruneEmptyDirs(dir string, stop string) {
+	for dir != stop && strings.HasPrefix(dir, stop+string(filepath.Separator)) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// encodeFilesystemSegment maps one bucket name or key segment to a portable
+// directory name. Unreserved characters are kept, everything else is
+// percent-encoded, a leading dot is always encoded so segments can never
+// collide with the store's own dot-prefixed files or with "." and "..", and
+// the empty segment (from "a//b" or a leading slash) is encoded as "%".
+func encodeFilesystemSegment(segment string) string {
+	if segment == "" {
+		return "%"
+	}
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		if isFilesystemUnreserved(c) && (i > 0 || c != '.') {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&0x0f])
+	}
+	return b.String()
+}
+
+// decodeFilesystemSegment reverses encodeFilesystemSegment. Names the encoder
+// cannot produce, such as the store's own dot-prefixed entries, are rejected.
+func decodeFilesystemSegment(name string) (string, bool) {
+	if name == "%" {
+		return "", true
+	}
+	if name == "" || name[0] == '.' {
+		return "", false
+	}
+	out := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c != '%' {
+			out = append(out, c)
+			continue
+		}
+		if i+2 >= len(name) {
+			return "", false
+		}
+		decoded, err := hex.DecodeString(name[i+1 : i+3])
+		if err != nil {
+			return "", false
+		}
+		out = append(out, decoded[0])
+		i += 2
+	}
+	return string(out), true
+}
+
+func isFilesystemUnreserved(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	case c == '-', c == '_', c == '.', c == '~':
+		return true
+	default:
+		return false
+	}
+}
+
+func readFilesystemSidecar(dir string) (*filesystemSidecar, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, filesystemSidecarName)) // #nosec G304 -- path is built from escaped segments under the store root
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sidecar filesystemSidecar
+	if err := json.Unmarshal(raw, &sidecar); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(sidecar.Payload, filesystemPayloadPrefix) || strings.ContainsAny(sidecar.Payload, `/\`) {
+		return nil, ErrInvalidStoreConfig
+	}
+	return &sidecar, nil
+}
+
+// writeFileAtomic writes data to a temporary file in dir, syncs it, and renames
+// it over name.
+func writeFileAtomic(dir string, name string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(dir, filesystemTempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			closeBestEffort(tmp)
+			removeBestEffort(tmp.Name())
+		}
+	}()
+	if err = tmp.Chmod(filesystemFilePerm); err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+func uniqueFilesystemName(prefix string) (string, error) {
+	var buf [12]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(buf[:]), nil
+}
+
+// removeBestEffort removes a superseded or abandoned file. Failures only leave
+// an unreferenced file behind, which never changes what readers observe.
+func removeBestEffort(path string) {
+	if err := os.Remove(path); err != nil {
+		_ = err
+	}
+}
+
+func closeBestEffort(c io.Closer) {
+	if err := c.Close(); err != nil {
+		_ = err
+	}
+}
@@ -0,0 +1,196 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFilesystemStoreContract(t *testing.T) {
+	store, err := NewFilesystemStore(FilesystemStoreConfig{Root: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewFilesystemStore() error = %v", err)
+	}
+	exerciseLocalStoreContract(t, store)
+	exerciseLocalStoreFailClosed(t, store)
+	exerciseLocalStoreStreaming(t, store)
+	exerciseLocalStoreConditions(t, store)
+	exerciseLocalStoreList(t, store)
+	exerciseLocalStoreCopy(t, store)
+}
+
+func TestFilesystemStoreConfigValidation(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "file")
+	if err := os.WriteFile(file, []byte("x"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	for _, cfg := range []FilesystemStoreConfig{
+		{},
+		{Root: " " + root},
+		{Root: filepath.Join(root, "missing")},
+		{Root: file},
+	} {
+		if _, err := NewFilesystemStore(cfg); !errors.Is(err, ErrInvalidStoreConfig) {
+			t.Fatalf("NewFilesystemStore(%#v) error = %v, want ErrInvalidStoreConfig", cfg, err)
+		}
+	}
+	if _, err := NewFilesystemStore(FilesystemStoreConfig{Root: root, Encryption: S3EncryptionConfig{Mode: S3EncryptionS3Managed, KMSKeyID: "key"}}); !errors.Is(err, ErrInvalidEncryptionConfig) {
+		t.Fatalf("NewFilesystemStore() encryption error = %v, want ErrInvalidEncryptionConfig", err)
+	}
+}
+
+func TestFilesystemStoreLayoutStaysUnderRoot(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewFilesystemStore(FilesystemStoreConfig{Root: root})
+	if err != nil {
+		t.Fatalf("NewFilesystemStore() error = %v", err)
+	}
+	ctx := context.Background()
+
+	refs := []ObjectRef{
+		{Bucket: "..", Key: "../../escape"},
+		{Bucket: "bucket-a", Key: "/leading//double"},
+		{Bucket: "bucket-a", Key: ".apptheory-object.json"},
+		{Bucket: "bucket-a", Key: `win\dows:name*`},
+	}
+	for _, ref := range refs {
+		if _, err := store.Put(ctx, PutInput{Ref: ref, Payload: []byte(ref.Key)}); err != nil {
+			t.Fatalf("Put(%#v) error = %v", ref, err)
+		}
+	}
+	for _, ref := range refs {
+		got, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 64})
+		if err != nil || string(got.Payload) != ref.Key {
+			t.Fatalf("Get(%#v) = %#v, %v", ref, got, err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(root))
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() == "escape" {
+			t.Fatalf("object escaped store root")
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "%2E.", "%2E.", "%2E.", "escape", filesystemSidecarName)); err != nil {
+		t.Fatalf("escaped layout missing: %v", err)
+	}
+}
+
+func TestFilesystemStoreDeletePrunesEmptyDirectories(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewFilesystemStore(FilesystemStoreConfig{Root: root})
+	if err != nil {
+		t.Fatalf("NewFilesystemStore() error = %v", err)
+	}
+	ctx := context.Background()
+	ref := ObjectRef{Bucket: "bucket-a", Key: "a/b/c"}
+	if _, err := store.Put(ctx, PutInput{Ref: ref, Payload: []byte("x")}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Delete(ctx, DeleteInput{Ref: ref}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "bucket-a"))
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("bucket directory not pruned: %v", entries)
+	}
+}
+
+func TestFilesystemStoreListStopsAfterPage(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewFilesystemStore(FilesystemStoreConfig{Root: root})
+	if err != nil {
+		t.Fatalf("NewFilesystemStore() error = %v", err)
+	}
+	ctx := context.Background()
+	for _, key := range []string{"a", "b/1", "c", "z"} {
+		if _, err := store.Put(ctx, PutInput{Ref: ObjectRef{Bucket: "bucket-a", Key: key}, Payload: []byte(key)}); err != nil {
+			t.Fatalf("Put(%q) error = %v", key, err)
+		}
+	}
+	// The first page stops at "c", its truncation marker, without reading "z".
+	if err := os.WriteFile(filepath.Join(root, "bucket-a", "z", filesystemSidecarName), []byte("{"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	page, err := store.List(ctx, ListInput{Bucket: "bucket-a", MaxKeys: 2})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if got := listedKeys(page); len(got) != 2 || got[0] != "a" || got[1] != "b/1" || !page.IsTruncated {
+		t.Fatalf("List() page = %#v", page)
+	}
+	if _, err := store.List(ctx, ListInput{Bucket: "bucket-a", ContinuationToken: page.NextContinuationToken}); err == nil {
+		t.Fatal("List() next page error = nil, want corrupt sidecar error")
+	}
+}
+
+func TestFilesystemStoreOverwriteLeavesSinglePayload(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewFilesystemStore(FilesystemStoreConfig{Root: root})
+	if err != nil {
+		t.Fatalf("NewFilesystemStore() error = %v", err)
+	}
+	ctx := context.Background()
+	ref := ObjectRef{Bucket: "bucket-a", Key: "key"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Put(ctx, PutInput{Ref: ref, Payload: []byte("payload")}); err != nil {
+				t.Errorf("Put() error = %v", err)
+			}
+			if _, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 7}); err != nil {
+				t.Errorf("Get() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := os.ReadDir(filepath.Join(root, "bucket-a", "key"))
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	payloads := 0
+	for _, entry := range entries {
+		switch {
+		case strings.HasPrefix(entry.Name(), filesystemPayloadPrefix):
+			payloads++
+		case strings.HasPrefix(entry.Name(), filesystemTempPrefix):
+			t.Fatalf("temporary file left behind: %s", entry.Name())
+		}
+	}
+	if payloads != 1 {
+		t.Fatalf("payload files = %d, want 1", payloads)
+	}
+}
+
+func TestEncodeFilesystemSegment(t *testing.T) {
+	tests := map[string]string{
+		"":           "%",
+		".":          "%2E",
+		"..":         "%2E.",
+		"a.b":        "a.b",
+		"with space": "with%20space",
+		"ü":          "%C3%BC",
+	}
+	for in, want := range tests {
+		if got := encodeFilesystemSegment(in); got != want {
+			t.Fatalf("encodeFilesystemSegment(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// localKeyAADPrefix binds each locally wrapped data key to its key ID.
+const localKeyAADPrefix = "apptheory-envelope-key/"
+
+// NewLocalKeyProvider returns a KeyProvider that wraps data keys with AES-GCM
+// under in-process key-encryption keys.
+//
+// keys maps key IDs to DataKeySize-byte key-encryption keys; the map is copied.
+// Rotate by adding a new key ID, re-wrapping with EnvelopeStore.RewrapPrefix,
+// and only then removing the old key. Intended for tests and local development;
+// production deployments should use NewKMSKeyProvider.
+func NewLocalKeyProvider(keys map[string][]byte) (KeyProvider, error) {
+	if len(keys) == 0 {
+		return nil, ErrInvalidEnvelopeConfig
+	}
+	provider := &localKeyProvider{keys: make(map[string][]byte, len(keys))}
+	for keyID, key := range keys {
+		if keyID == "" || len(key) != DataKeySize {
+			return nil, ErrInvalidEnvelopeConfig
+		}
+		provider.keys[keyID] = cloneBytes(key)
+	}
+	return provider, nil
+}
+
+type localKeyProvider struct {
+	keys map[string][]byte
+}
+
+func (p *localKeyProvider) GenerateDataKey(ctx context.Context, keyID string) (*DataKey, error) {
+	plaintext := make([]byte, DataKeySize)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, err
+	}
+	wrapped, err := p.WrapDataKey(ctx, keyID, plaintext)
+	if err != nil {
+		clear(plaintext)
+		return nil, err
+	}
+	return &DataKey{Plaintext: plaintext, Wrapped: wrapped}, nil
+}
+
+func (p *localKeyProvider) WrapDataKey(_ context.Context, keyID string, plaintext []byte) ([]byte, error) {
+	kek, ok := p.keys[keyID]
+	if !ok {
+		return nil, ErrInvalidEnvelopeKey
+	}
+	aead, err := newEnvelopeAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := make([]byte, envelopeNonceSize, envelopeOverhead+len(plaintext))
+	if _, err := rand.Read(wrapped); err != nil {
+		return nil, err
+	}
+	return aead.Seal(wrapped, wrapped[:envelopeNonceSize], plaintext, []byte(localKeyAADPrefix+keyID)), nil
+}
+
+func (p *localKeyProvider) UnwrapDataKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	kek, ok := p.keys[keyID]
+	if !ok {
+		return nil, ErrInvalidEnvelopeKey
+	}
+	if len(wrapped) < envelopeOverhead {
+		return nil, ErrEnvelopeInvalid
+	}
+	aead, err := newEnvelopeAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, wrapped[:envelopeNonceSize], wrapped[envelopeNonceSize:], []byte(localKeyAADPrefix+keyID))
+	if err != nil {
+		return nil, ErrEnvelopeInvalid
+	}
+	return plaintext, nil
+}
+
+// KMSKeyProviderConfig configures NewKMSKeyProvider.
+//
+// EncryptionContext is added to every KMS call so key policies and CloudTrail
+// can scope envelope usage. The "apptheory:envelope" entry is always set and
+// may not be overridden.
+type KMSKeyProviderConfig struct {
+	EncryptionContext map[string]string
+}
+
+const kmsEnvelopeContextKey = "apptheory:envelope"
+
+// NewKMSKeyProvider returns a KeyProvider backed by AWS KMS using the default
+// AWS config chain. Key IDs may be key IDs, key ARNs, alias names, or alias ARNs.
+//
+// Decrypt is pinned to the key ID stored in each envelope, so repointing an
+// alias makes the objects wrapped under it unreadable. Rotate by selecting a
+// new key or alias and re-wrapping with EnvelopeStore.RewrapPrefix instead.
+func NewKMSKeyProvider(ctx context.Context, providerConfig KMSKeyProviderConfig) (KeyProvider, error) {
+	if _, err := kmsEncryptionContext(providerConfig); err != nil {
+		return nil, err
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newKMSKeyProviderWithClient(kms.NewFromConfig(cfg), providerConfig)
+}
+
+type kmsKeyProviderClient interface {
+	GenerateDataKey(context.Context, *kms.GenerateDataKeyInput, ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Encrypt(context.Context, *kms.EncryptInput, ...func(*kms.Options)) (*kms.EncryptOutput, error)
+	Decrypt(context.Context, *kms.DecryptInput, ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+type kmsKeyProvider struct {
+	client            kmsKeyProviderClient
+	encryptionContext map[string]string
+}
+
+func newKMSKeyProviderWithClient(client kmsKeyProviderClient, providerConfig KMSKeyProviderConfig) (*kmsKeyProvider, error) {
+	if client == nil {
+		return nil, ErrInvalidEnvelopeConfig
+	}
+	encryptionContext, err := kmsEncryptionContext(providerConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &kmsKeyProvider{client: client, encryptionContext: encryptionContext}, nil
+}
+
+func kmsEncryptionContext(providerConfig KMSKeyProviderConfig) (map[string]string, error) {
+	out := make(map[string]string, len(providerConfig.EncryptionContext)+1)
+	for k, v := range providerConfig.EncryptionContext {
+		if k == "" || k == kmsEnvelopeContextKey || containsControl(k) || containsControl(v) {
+			return nil, ErrInvalidEnvelopeConfig
+		}
+		out[k] = v
+	}
+	out[kmsEnvelopeContextKey] = envelopeVersionV1
+	return out, nil
+}
+
+func (p *kmsKeyProvider) GenerateDataKey(ctx context.Context, keyID string) (*DataKey, error) {
+	if keyID == "" {
+		return nil, ErrInvalidEnvelopeKey
+	}
+	out, err := p.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(keyID),
+		KeySpec:           kmstypes.DataKeySpecAes256,
+		EncryptionContext: p.encryptionContext,
+	})
+	if err != nil {
+		return nil, mapKMSError(err)
+	}
+	if out == nil || len(out.Plaintext) != DataKeySize || len(out.CiphertextBlob) == 0 {
+		return nil, ErrInvalidEnvelopeKey
+	}
+	return &DataKey{Plaintext: out.Plaintext, Wrapped: out.CiphertextBlob}, nil
+}
+
+func (p *kmsKeyProvider) WrapDataKey(ctx context.Context, keyID string, plaintext []byte) ([]byte, error) {
+	if keyID == "" {
+		return nil, ErrInvalidEnvelopeKey
+	}
+	out, err := p.client.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:             aws.String(keyID),
+		Plaintext:         plaintext,
+		EncryptionContext: p.encryptionContext,
+	})
+	if err != nil {
+		return nil, mapKMSError(err)
+	}
+	if out == nil || len(out.CiphertextBlob) == 0 {
+		return nil, ErrInvalidEnvelopeKey
+	}
+	return out.CiphertextBlob, nil
+}
+
+// UnwrapDataKey pins KeyId so a wrapped key cannot be redirected to another
+// KMS key the caller happens to be allowed to use.
+func (p *kmsKeyProvider) UnwrapDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if keyID == "" {
+		return nil, ErrInvalidEnvelopeKey
+	}
+	out, err := p.client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(keyID),
+		CiphertextBlob:    wrapped,
+		EncryptionContext: p.encryptionContext,
+	})
+	if err != nil {
+		return nil, mapKMSError(err)
+	}
+	if out == nil || len(out.Plaintext) != DataKeySize {
+		return nil, ErrEnvelopeInvalid
+	}
+	return out.Plaintext, nil
+}
+
+// mapKMSError adds the matching stable envelope error for missing keys and
+// rejected ciphertexts while keeping the KMS error in the chain.
+func mapKMSError(err error) error {
+	var apiErr interface{ ErrorCode() string }
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch apiErr.ErrorCode() {
+	case "NotFoundException":
+		return fmt.Errorf("%w: %w", ErrInvalidEnvelopeKey, err)
+	case "InvalidCiphertextException", "IncorrectKeyException":
+		return fmt.Errorf("%w: %w", ErrEnvelopeInvalid, err)
+	default:
+		return err
+	}
+}
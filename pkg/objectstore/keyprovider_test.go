@@ -0,0 +1,118 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// fakeKMSClient "wraps" by prefixing the key ID, which is enough to check the
+// provider's request shaping and error mapping.
+type fakeKMSClient struct {
+	contexts      []map[string]string
+	decryptKeyIDs []string
+	decryptErr    error
+}
+
+func (c *fakeKMSClient) GenerateDataKey(_ context.Context, in *kms.GenerateDataKeyInput, _ ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	c.contexts = append(c.contexts, in.EncryptionContext)
+	if in.KeySpec != kmstypes.DataKeySpecAes256 {
+		return nil, errors.New("unexpected key spec")
+	}
+	plaintext := testKey(7)
+	return &kms.GenerateDataKeyOutput{Plaintext: plaintext, CiphertextBlob: append([]byte(aws.ToString(in.KeyId)+":"), plaintext...)}, nil
+}
+
+func (c *fakeKMSClient) Encrypt(_ context.Context, in *kms.EncryptInput, _ ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	c.contexts = append(c.contexts, in.EncryptionContext)
+	return &kms.EncryptOutput{CiphertextBlob: append([]byte(aws.ToString(in.KeyId)+":"), in.Plaintext...)}, nil
+}
+
+func (c *fakeKMSClient) Decrypt(_ context.Context, in *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	c.contexts = append(c.contexts, in.EncryptionContext)
+	c.decryptKeyIDs = append(c.decryptKeyIDs, aws.ToString(in.KeyId))
+	if c.decryptErr != nil {
+		return nil, c.decryptErr
+	}
+	prefix := []byte(aws.ToString(in.KeyId) + ":")
+	if !bytes.HasPrefix(in.CiphertextBlob, prefix) {
+		return nil, &kmstypes.IncorrectKeyException{Message: aws.String("wrong key")}
+	}
+	return &kms.DecryptOutput{Plaintext: bytes.TrimPrefix(in.CiphertextBlob, prefix)}, nil
+}
+
+func TestKMSKeyProviderRequests(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeKMSClient{}
+	provider, err := newKMSKeyProviderWithClient(client, KMSKeyProviderConfig{EncryptionContext: map[string]string{"service": "docs"}})
+	if err != nil {
+		t.Fatalf("newKMSKeyProviderWithClient() error = %v", err)
+	}
+
+	dataKey, err := provider.GenerateDataKey(ctx, "alias/app")
+	if err != nil {
+		t.Fatalf("GenerateDataKey() error = %v", err)
+	}
+	rewrapped, err := provider.WrapDataKey(ctx, "alias/next", dataKey.Plaintext)
+	if err != nil {
+		t.Fatalf("WrapDataKey() error = %v", err)
+	}
+	plaintext, err := provider.UnwrapDataKey(ctx, "alias/next", rewrapped)
+	if err != nil || !bytes.Equal(plaintext, dataKey.Plaintext) {
+		t.Fatalf("UnwrapDataKey() = %x, %v, want generated key", plaintext, err)
+	}
+	if _, err := provider.UnwrapDataKey(ctx, "alias/app", rewrapped); !errors.Is(err, ErrEnvelopeInvalid) {
+		t.Fatalf("UnwrapDataKey() wrong key error = %v, want ErrEnvelopeInvalid", err)
+	}
+
+	for _, got := range client.contexts {
+		if got["service"] != "docs" || got[kmsEnvelopeContextKey] != envelopeVersionV1 {
+			t.Fatalf("encryption context = %#v, want configured context plus envelope version", got)
+		}
+	}
+	if client.decryptKeyIDs[0] != "alias/next" {
+		t.Fatalf("Decrypt KeyId = %q, want pinned alias/next", client.decryptKeyIDs[0])
+	}
+
+	client.decryptErr = &kmstypes.NotFoundException{Message: aws.String("missing")}
+	if _, err := provider.UnwrapDataKey(ctx, "alias/gone", rewrapped); !errors.Is(err, ErrInvalidEnvelopeKey) {
+		t.Fatalf("UnwrapDataKey() missing key error = %v, want ErrInvalidEnvelopeKey", err)
+	}
+	var notFound *kmstypes.NotFoundException
+	if _, err := provider.UnwrapDataKey(ctx, "alias/gone", rewrapped); !errors.As(err, &notFound) {
+		t.Fatalf("UnwrapDataKey() error = %v, want KMS error in chain", err)
+	}
+
+	if _, err := newKMSKeyProviderWithClient(client, KMSKeyProviderConfig{EncryptionContext: map[string]string{kmsEnvelopeContextKey: "v2"}}); !errors.Is(err, ErrInvalidEnvelopeConfig) {
+		t.Fatalf("newKMSKeyProviderWithClient() reserved context error = %v, want ErrInvalidEnvelopeConfig", err)
+	}
+}
+
+func TestEnvelopeStoreWithKMSKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	provider, err := newKMSKeyProviderWithClient(&fakeKMSClient{}, KMSKeyProviderConfig{})
+	if err != nil {
+		t.Fatalf("newKMSKeyProviderWithClient() error = %v", err)
+	}
+	inner, err := NewMemoryStore(MemoryStoreConfig{})
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+	store, err := NewEnvelopeStore(inner, EnvelopeStoreConfig{Provider: provider, SelectKey: StaticKeySelector("alias/app")})
+	if err != nil {
+		t.Fatalf("NewEnvelopeStore() error = %v", err)
+	}
+	ref := ObjectRef{Bucket: "bucket", Key: "a.json"}
+	if _, err := store.Put(ctx, PutInput{Ref: ref, Payload: []byte(`{"ok":true}`)}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	out, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 1024})
+	if err != nil || string(out.Payload) != `{"ok":true}` {
+		t.Fatalf("Get() = %v, %v, want decrypted payload", out, err)
+	}
+}
@@ -0,0 +1,118 @@
+package objectstore
+
+import (
+	"encoding/base64"
+	"sort"
+	"strings"
+)
+
+// listPage applies S3 ListObjectsV2 prefix, delimiter, and pagination
+// semantics to the objects of one bucket for stores that hold their own key
+// index. input must already be validated.
+func listPage(objects []ObjectInfo, input ListInput) (*ListOutput, error) {
+	pager, err := newListPager(input)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Ref.Key < objects[j].Ref.Key })
+	for _, obj := range objects {
+		if pager.done {
+			break
+		}
+		if pager.wants(obj.Ref.Key) {
+			pager.add(obj)
+		}
+	}
+	return pager.out, nil
+}
+
+// listPager builds one List page from objects fed in ascending key order and
+// sets done once the page is full, so callers can stop reading keys.
+type listPager struct {
+	input      ListInput
+	startAfter string
+	out        *ListOutput
+	emitted    int
+	last       string
+	lastPrefix string
+	done       bool
+}
+
+func newListPager(input ListInput) (*listPager, error) {
+	startAfter, err := decodeListToken(input.ContinuationToken)
+	if err != nil {
+		return nil, err
+	}
+	return &listPager{input: input, startAfter: startAfter, out: &ListOutput{}}, nil
+}
+
+// wants reports whether key can appear on the page.
+func (p *listPager) wants(key string) bool {
+	return strings.HasPrefix(key, p.input.Prefix) && key > p.startAfter
+}
+
+// wantsTree reports whether any key starting with keyPrefix can appear on the
+// page, letting ordered walks skip whole subtrees.
+func (p *listPager) wantsTree(keyPrefix string) bool {
+	if !strings.HasPrefix(keyPrefix, p.input.Prefix) {
+		return strings.HasPrefix(p.input.Prefix, keyPrefix)
+	}
+	if keyPrefix < p.startAfter && !strings.HasPrefix(p.startAfter, keyPrefix) {
+		return false
+	}
+	// Every key under keyPrefix rolls up into the same common prefix once the
+	// delimiter falls inside keyPrefix.
+	commonPrefix := listCommonPrefix(keyPrefix, p.input.Prefix, p.input.Delimiter)
+	return commonPrefix == "" || (commonPrefix > p.startAfter && commonPrefix != p.lastPrefix)
+}
+
+func (p *listPager) add(obj ObjectInfo) {
+	key := obj.Ref.Key
+	commonPrefix := listCommonPrefix(key, p.input.Prefix, p.input.Delimiter)
+	if commonPrefix != "" && (commonPrefix <= p.startAfter || commonPrefix == p.lastPrefix) {
+		return
+	}
+	if p.emitted == p.input.MaxKeys {
+		p.out.IsTruncated = true
+		p.out.NextContinuationToken = encodeListToken(p.last)
+		p.done = true
+		return
+	}
+	p.emitted++
+	if commonPrefix != "" {
+		p.out.CommonPrefixes = append(p.out.CommonPrefixes, commonPrefix)
+		p.last, p.lastPrefix = commonPrefix, commonPrefix
+		return
+	}
+	obj.ContentType = ""
+	obj.Metadata = nil
+	p.out.Objects = append(p.out.Objects, obj)
+	p.last = key
+}
+
+func listCommonPrefix(key string, prefix string, delimiter string) string {
+	if delimiter == "" {
+		return ""
+	}
+	rest := key[len(prefix):]
+	idx := strings.Index(rest, delimiter)
+	if idx < 0 {
+		return ""
+	}
+	return prefix + rest[:idx+len(delimiter)]
+}
+
+func encodeListToken(startAfter string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(startAfter))
+}
+
+func decodeListToken(token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", ErrInvalidListInput
+	}
+	return string(raw), nil
+}
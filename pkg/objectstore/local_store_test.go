@@ -0,0 +1,373 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// exerciseLocalStoreContract runs the shared Put/Get/Delete contract that every
+// non-S3 Store implementation must satisfy.
+func exerciseLocalStoreContract(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	ref := ObjectRef{Bucket: "bucket-a", Key: "objects/1.json"}
+
+	metadata := map[string]string{"sha256": "abc"}
+	payload := []byte("payload")
+	putRef, err := store.Put(ctx, PutInput{Ref: ref, Payload: payload, ContentType: "application/json", Metadata: metadata})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if putRef != ref {
+		t.Fatalf("Put() ref = %#v, want %#v", putRef, ref)
+	}
+	payload[0] = 'P'
+	metadata["sha256"] = "changed"
+
+	got, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 7})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(got.Payload) != "payload" || got.ContentType != "application/json" || got.Metadata["sha256"] != "abc" {
+		t.Fatalf("Get() output mismatch: %#v", got)
+	}
+	got.Metadata["sha256"] = "mutated"
+
+	if _, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 6}); !errors.Is(err, ErrObjectTooLarge) {
+		t.Fatalf("Get() over cap error = %v, want ErrObjectTooLarge", err)
+	}
+
+	if _, err := store.Put(ctx, PutInput{Ref: ref, Payload: []byte("v2")}); err != nil {
+		t.Fatalf("Put() overwrite error = %v", err)
+	}
+	got, err = store.Get(ctx, GetInput{Ref: ref, MaxBytes: 7})
+	if err != nil {
+		t.Fatalf("Get() after overwrite error = %v", err)
+	}
+	if string(got.Payload) != "v2" || got.ContentType != "" || got.Metadata != nil {
+		t.Fatalf("Get() after overwrite = %#v", got)
+	}
+
+	nested := ObjectRef{Bucket: "bucket-a", Key: "objects/1.json/child"}
+	if _, err := store.Put(ctx, PutInput{Ref: nested, Payload: []byte("child")}); err != nil {
+		t.Fatalf("Put() nested key error = %v", err)
+	}
+
+	if err := store.Delete(ctx, DeleteInput{Ref: ref}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 7}); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("Get() after delete error = %v, want ErrObjectNotFound", err)
+	}
+	if err := store.Delete(ctx, DeleteInput{Ref: ref}); err != nil {
+		t.Fatalf("Delete() missing object error = %v", err)
+	}
+	got, err = store.Get(ctx, GetInput{Ref: nested, MaxBytes: 5})
+	if err != nil || string(got.Payload) != "child" {
+		t.Fatalf("Get() nested key = %#v, %v", got, err)
+	}
+}
+
+func exerciseLocalStoreFailClosed(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	versioned := ObjectRef{Bucket: "bucket-a", Key: "key", VersionID: "v1"}
+
+	if _, err := store.Put(ctx, PutInput{Ref: ObjectRef{Bucket: "bucket-a"}}); !errors.Is(err, ErrInvalidObjectRef) {
+		t.Fatalf("Put() invalid ref error = %v, want ErrInvalidObjectRef", err)
+	}
+	if _, err := store.Put(ctx, PutInput{Ref: versioned}); !errors.Is(err, ErrInvalidObjectRef) {
+		t.Fatalf("Put() versioned ref error = %v, want ErrInvalidObjectRef", err)
+	}
+	if _, err := store.Get(ctx, GetInput{Ref: ObjectRef{Bucket: "bucket-a", Key: "key"}}); !errors.Is(err, ErrInvalidGetLimit) {
+		t.Fatalf("Get() missing cap error = %v, want ErrInvalidGetLimit", err)
+	}
+	if _, err := store.Get(ctx, GetInput{Ref: versioned, MaxBytes: 1}); !errors.Is(err, ErrInvalidObjectRef) {
+		t.Fatalf("Get() versioned ref error = %v, want ErrInvalidObjectRef", err)
+	}
+	if _, err := store.Get(ctx, GetInput{Ref: ObjectRef{Bucket: "bucket-a", Key: "missing"}, MaxBytes: 1}); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("Get() missing object error = %v, want ErrObjectNotFound", err)
+	}
+	if err := store.Delete(ctx, DeleteInput{Ref: versioned}); !errors.Is(err, ErrInvalidObjectRef) {
+		t.Fatalf("Delete() versioned ref error = %v, want ErrInvalidObjectRef", err)
+	}
+}
+
+// exerciseLocalStoreStreaming covers PutReader, GetReader, and Head.
+func exerciseLocalStoreStreaming(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	ref := ObjectRef{Bucket: "bucket-a", Key: "stream/large.bin"}
+	payload := bytes.Repeat([]byte("0123456789"), 1000)
+
+	putRef, err := store.PutReader(ctx, PutReaderInput{
+		Ref:         ref,
+		Body:        bytes.NewReader(payload),
+		ContentType: "application/octet-stream",
+		Metadata:    map[string]string{"source": "test"},
+	})
+	if err != nil {
+		t.Fatalf("PutReader() error = %v", err)
+	}
+	if putRef != ref {
+		t.Fatalf("PutReader() ref = %#v, want %#v", putRef, ref)
+	}
+
+	info, err := store.Head(ctx, HeadInput{Ref: ref})
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	if info.Size != int64(len(payload)) || info.ETag == "" || info.LastModified.IsZero() {
+		t.Fatalf("Head() info = %#v", info)
+	}
+	if info.ContentType != "application/octet-stream" || info.Metadata["source"] != "test" {
+		t.Fatalf("Head() content type/metadata = %#v", info)
+	}
+
+	out, err := store.GetReader(ctx, GetInput{Ref: ref, MaxBytes: int64(len(payload))})
+	if err != nil {
+		t.Fatalf("GetReader() error = %v", err)
+	}
+	got, err := io.ReadAll(out.Body)
+	if err != nil {
+		t.Fatalf("GetReader() read error = %v", err)
+	}
+	if err := out.Body.Close(); err != nil {
+		t.Fatalf("GetReader() close error = %v", err)
+	}
+	if !bytes.Equal(got, payload) || out.Info.ETag != info.ETag {
+		t.Fatalf("GetReader() = %d bytes, etag %q; want %d bytes, etag %q", len(got), out.Info.ETag, len(payload), info.ETag)
+	}
+	if _, err := store.GetReader(ctx, GetInput{Ref: ref, MaxBytes: int64(len(payload)) - 1}); !errors.Is(err, ErrObjectTooLarge) {
+		t.Fatalf("GetReader() over cap error = %v, want ErrObjectTooLarge", err)
+	}
+
+	if _, err := store.Head(ctx, HeadInput{Ref: ObjectRef{Bucket: "bucket-a", Key: "stream/missing"}}); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("Head() missing error = %v, want ErrObjectNotFound", err)
+	}
+	if _, err := store.PutReader(ctx, PutReaderInput{Ref: ref}); !errors.Is(err, ErrInvalidStreamInput) {
+		t.Fatalf("PutReader() nil body error = %v, want ErrInvalidStreamInput", err)
+	}
+	if _, err := store.PutReader(ctx, PutReaderInput{Ref: ref, Body: bytes.NewReader(nil), PartSize: 1}); !errors.Is(err, ErrInvalidStreamInput) {
+		t.Fatalf("PutReader() small part error = %v, want ErrInvalidStreamInput", err)
+	}
+}
+
+// exerciseLocalStoreConditions covers If-None-Match and If-Match writes.
+func exerciseLocalStoreConditions(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	ref := ObjectRef{Bucket: "bucket-a", Key: "conditional/lock"}
+	create := WriteCondition{IfNoneMatch: "*"}
+
+	if _, err := store.Put(ctx, PutInput{Ref: ref, Payload: []byte("v1"), Condition: create}); err != nil {
+		t.Fatalf("Put() create error = %v", err)
+	}
+	if _, err := store.Put(ctx, PutInput{Ref: ref, Payload: []byte("v2"), Condition: create}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Put() second create error = %v, want ErrPreconditionFailed", err)
+	}
+
+	got, err := store.Get(ctx, GetInput{Ref: ref, MaxBytes: 2})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := store.Put(ctx, PutInput{Ref: ref, Payload: []byte("v2"), Condition: WriteCondition{IfMatch: `"stale"`}}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Put() stale If-Match error = %v, want ErrPreconditionFailed", err)
+	}
+	if _, err := store.PutReader(ctx, PutReaderInput{Ref: ref, Body: bytes.NewReader([]byte("v2")), Condition: WriteCondition{IfMatch: got.ETag}}); err != nil {
+		t.Fatalf("PutReader() If-Match error = %v", err)
+	}
+	if _, err := store.Put(ctx, PutInput{Ref: ref, Payload: []byte("v3"), Condition: WriteCondition{IfMatch: got.ETag}}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Put() reused If-Match error = %v, want ErrPreconditionFailed", err)
+	}
+	missing := ObjectRef{Bucket: "bucket-a", Key: "conditional/missing"}
+	if _, err := store.Put(ctx, PutInput{Ref: missing, Condition: WriteCondition{IfMatch: got.ETag}}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Put() If-Match on missing object error = %v, want ErrPreconditionFailed", err)
+	}
+
+	putter, ok := store.(ETagPutter)
+	if !ok {
+		t.Fatalf("%T does not implement ETagPutter", store)
+	}
+	current, err := store.Head(ctx, HeadInput{Ref: ref})
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	_, etag, err := putter.PutETag(ctx, PutInput{Ref: ref, Payload: []byte("v3"), Condition: WriteCondition{IfMatch: current.ETag}})
+	if err != nil {
+		t.Fatalf("PutETag() error = %v", err)
+	}
+	if after, err := store.Head(ctx, HeadInput{Ref: ref}); err != nil || after.ETag != etag {
+		t.Fatalf("PutETag() etag = %q, Head() = %#v, %v", etag, after, err)
+	}
+
+	for _, condition := range []WriteCondition{
+		{IfNoneMatch: `"etag"`},
+		{IfNoneMatch: "*", IfMatch: `"etag"`},
+		{IfMatch: " padded "},
+	} {
+		if _, err := store.Put(ctx, PutInput{Ref: ref, Condition: condition}); !errors.Is(err, ErrInvalidWriteCondition) {
+			t.Fatalf("Put(%#v) error = %v, want ErrInvalidWriteCondition", condition, err)
+		}
+	}
+}
+
+// exerciseLocalStoreList covers prefix, delimiter, and pagination semantics.
+func exerciseLocalStoreList(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	keys := []string{"list/a.json", "list/b/1.json", "list/b/2.json", "list/c.json", "other/d.json"}
+	for _, key := range keys {
+		if _, err := store.Put(ctx, PutInput{Ref: ObjectRef{Bucket: "bucket-list", Key: key}, Payload: []byte(key)}); err != nil {
+			t.Fatalf("Put(%q) error = %v", key, err)
+		}
+	}
+
+	page, err := store.List(ctx, ListInput{Bucket: "bucket-list", Prefix: "list/", Delimiter: "/"})
+	if err != nil {
+		t.Fatalf("List() delimiter error = %v", err)
+	}
+	if got := listedKeys(page); !reflect.DeepEqual(got, []string{"list/a.json", "list/c.json"}) {
+		t.Fatalf("List() delimiter keys = %#v", got)
+	}
+	if !reflect.DeepEqual(page.CommonPrefixes, []string{"list/b/"}) || page.IsTruncated {
+		t.Fatalf("List() delimiter page = %#v", page)
+	}
+	if page.Objects[0].Size != int64(len("list/a.json")) || page.Objects[0].ETag == "" {
+		t.Fatalf("List() object info = %#v", page.Objects[0])
+	}
+
+	var all []string
+	input := ListInput{Bucket: "bucket-list", Prefix: "list", MaxKeys: 2}
+	for {
+		page, err := store.List(ctx, input)
+		if err != nil {
+			t.Fatalf("List() page error = %v", err)
+		}
+		all = append(all, listedKeys(page)...)
+		if !page.IsTruncated {
+			break
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+	if !reflect.DeepEqual(all, keys[:4]) {
+		t.Fatalf("List() paged keys = %#v", all)
+	}
+
+	// "-" and "." sort before "/" and "0" after it, so an object, its subtree,
+	// and its siblings interleave in key order.
+	ordered := []string{"order/a", "order/a-b", "order/a.c", "order/a/c", "order/a0"}
+	for _, key := range []string{"order/a0", "order/a/c", "order/a.c", "order/a-b", "order/a"} {
+		if _, err := store.Put(ctx, PutInput{Ref: ObjectRef{Bucket: "bucket-order", Key: key}, Payload: []byte(key)}); err != nil {
+			t.Fatalf("Put(%q) error = %v", key, err)
+		}
+	}
+	if got := listAllPages(t, store, ListInput{Bucket: "bucket-order", MaxKeys: 1}); !reflect.DeepEqual(got, ordered) {
+		t.Fatalf("List() ordered keys = %#v", got)
+	}
+	want := []string{"order/a", "order/a-b", "order/a.c", "order/a/", "order/a0"}
+	if got := listAllPages(t, store, ListInput{Bucket: "bucket-order", Prefix: "order/", Delimiter: "/", MaxKeys: 1}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("List() ordered delimiter entries = %#v", got)
+	}
+
+	empty, err := store.List(ctx, ListInput{Bucket: "bucket-missing"})
+	if err != nil || len(empty.Objects) != 0 || empty.IsTruncated {
+		t.Fatalf("List() empty bucket = %#v, %v", empty, err)
+	}
+	for _, bad := range []ListInput{
+		{},
+		{Bucket: "bucket-list", MaxKeys: -1},
+		{Bucket: "bucket-list", MaxKeys: MaxListKeys + 1},
+		{Bucket: "bucket-list", ContinuationToken: "!"},
+	} {
+		if _, err := store.List(ctx, bad); !errors.Is(err, ErrInvalidListInput) {
+			t.Fatalf("List(%#v) error = %v, want ErrInvalidListInput", bad, err)
+		}
+	}
+}
+
+// listAllPages follows continuation tokens and returns keys and common
+// prefixes in the order the pages produced them.
+func listAllPages(t *testing.T, store Store, input ListInput) []string {
+	t.Helper()
+	var entries []string
+	for {
+		page, err := store.List(context.Background(), input)
+		if err != nil {
+			t.Fatalf("List() page error = %v", err)
+		}
+		entries = append(entries, listedKeys(page)...)
+		entries = append(entries, page.CommonPrefixes...)
+		if !page.IsTruncated {
+			return entries
+		}
+		input.ContinuationToken = page.NextContinuationToken
+	}
+}
+
+// exerciseLocalStoreCopy covers metadata copy/replace and source conditions.
+func exerciseLocalStoreCopy(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	source := ObjectRef{Bucket: "bucket-a", Key: "copy/source"}
+	dest := ObjectRef{Bucket: "bucket-b", Key: "copy/dest"}
+
+	if _, err := store.Put(ctx, PutInput{Ref: source, Payload: []byte("copied"), ContentType: "text/plain", Metadata: map[string]string{"a": "b"}}); err != nil {
+		t.Fatalf("Put() source error = %v", err)
+	}
+	sourceInfo, err := store.Head(ctx, HeadInput{Ref: source})
+	if err != nil {
+		t.Fatalf("Head() source error = %v", err)
+	}
+
+	if _, err := store.Copy(ctx, CopyInput{Source: source, Destination: dest, SourceIfMatch: `"stale"`}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Copy() stale source error = %v, want ErrPreconditionFailed", err)
+	}
+	copied, err := store.Copy(ctx, CopyInput{Source: source, Destination: dest, SourceIfMatch: sourceInfo.ETag, Condition: WriteCondition{IfNoneMatch: "*"}})
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if copied != dest {
+		t.Fatalf("Copy() ref = %#v, want %#v", copied, dest)
+	}
+	got, err := store.Get(ctx, GetInput{Ref: dest, MaxBytes: 6})
+	if err != nil {
+		t.Fatalf("Get() copy error = %v", err)
+	}
+	if string(got.Payload) != "copied" || got.ContentType != "text/plain" || got.Metadata["a"] != "b" || got.ETag != sourceInfo.ETag {
+		t.Fatalf("Get() copy = %#v", got)
+	}
+
+	if _, err := store.Copy(ctx, CopyInput{Source: source, Destination: dest, Condition: WriteCondition{IfNoneMatch: "*"}}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Copy() existing destination error = %v, want ErrPreconditionFailed", err)
+	}
+	if _, err := store.Copy(ctx, CopyInput{Source: source, Destination: dest, ReplaceMetadata: true, Metadata: map[string]string{"c": "d"}}); err != nil {
+		t.Fatalf("Copy() replace metadata error = %v", err)
+	}
+	info, err := store.Head(ctx, HeadInput{Ref: dest})
+	if err != nil {
+		t.Fatalf("Head() dest error = %v", err)
+	}
+	if info.ContentType != "" || !reflect.DeepEqual(info.Metadata, map[string]string{"c": "d"}) {
+		t.Fatalf("Head() replaced metadata = %#v", info)
+	}
+
+	if _, err := store.Copy(ctx, CopyInput{Source: ObjectRef{Bucket: "bucket-a", Key: "copy/missing"}, Destination: dest}); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("Copy() missing source error = %v, want ErrObjectNotFound", err)
+	}
+	if _, err := store.Copy(ctx, CopyInput{Source: source, Destination: ObjectRef{Bucket: "bucket-b", Key: "copy/dest", VersionID: "v1"}}); !errors.Is(err, ErrInvalidObjectRef) {
+		t.Fatalf("Copy() versioned destination error = %v, want ErrInvalidObjectRef", err)
+	}
+}
+
+func listedKeys(page *ListOutput) []string {
+	keys := make([]string, 0, len(page.Objects))
+	for _, obj := range page.Objects {
+		keys = append(keys, obj.Ref.Key)
+	}
+	return keys
+}
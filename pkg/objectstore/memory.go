@@ -0,0 +1,251 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// MemoryStoreConfig configures the process-local in-memory Store implementation.
+//
+// Encryption is validated with the same fail-closed rules as S3StoreConfig so
+// local and S3 deployments share one configuration shape.
+type MemoryStoreConfig struct {
+	Encryption S3EncryptionConfig
+}
+
+// NewMemoryStore returns a concurrency-safe, process-local Store.
+//
+// Unlike testkit/objectstore.FakeStore it does not record calls or inject
+// failures; it is intended for local development loops, CLI tools, and
+// single-process deployments. The store is unversioned; refs that carry a
+// VersionID are rejected with ErrInvalidObjectRef. All payloads and metadata
+// maps are copied at the boundary.
+func NewMemoryStore(storeConfig MemoryStoreConfig) (Store, error) {
+	if _, err := normalizeS3StoreConfig(S3StoreConfig{Encryption: storeConfig.Encryption}); err != nil {
+		return nil, err
+	}
+	return &memoryStore{objects: make(map[memoryObjectName]memoryObject)}, nil
+}
+
+type memoryStore struct {
+	mu      sync.RWMutex
+	objects map[memoryObjectName]memoryObject
+}
+
+type memoryObjectName struct {
+	bucket string
+	key    string
+}
+
+type memoryObject struct {
+	payload      []byte
+	contentType  string
+	metadata     map[string]string
+	etag         string
+	lastModified time.Time
+}
+
+func (s *memoryStore) Put(_ context.Context, input PutInput) (ObjectRef, error) {
+	if err := s.requireObjects(); err != nil {
+		return ObjectRef{}, err
+	}
+	if err := validatePutInput(input); err != nil {
+		return ObjectRef{}, err
+	}
+	return s.write(input.Ref, cloneBytes(input.Payload), input.ContentType, input.Metadata, input.Condition)
+}
+
+// PutETag is Put that also returns the new object's ETag, which is a digest
+// of the payload.
+func (s *memoryStore) PutETag(ctx context.Context, input PutInput) (ObjectRef, string, error) {
+	ref, err := s.Put(ctx, input)
+	if err != nil {
+		return ObjectRef{}, "", err
+	}
+	return ref, payloadETag(input.Payload), nil
+}
+
+func (s *memoryStore) PutReader(_ context.Context, input PutReaderInput) (ObjectRef, error) {
+	if err := s.requireObjects(); err != nil {
+		return ObjectRef{}, err
+	}
+	if err := validatePutReaderInput(input); err != nil {
+		return ObjectRef{}, err
+	}
+	payload, err := io.ReadAll(input.Body)
+	if err != nil {
+		return ObjectRef{}, err
+	}
+	return s.write(input.Ref, payload, input.ContentType, input.Metadata, input.Condition)
+}
+
+func (s *memoryStore) write(ref ObjectRef, payload []byte, contentType string, metadata map[string]string, condition WriteCondition) (ObjectRef, error) {
+	obj := memoryObject{
+		payload:      payload,
+		contentType:  contentType,
+		metadata:     cloneMetadata(metadata),
+		etag:         payloadETag(payload),
+		lastModified: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := memoryObjectName{bucket: ref.Bucket, key: ref.Key}
+	current, exists := s.objects[name]
+	if err := condition.check(exists, current.etag); err != nil {
+		return ObjectRef{}, err
+	}
+	s.objects[name] = obj
+	return ref, nil
+}
+
+func (s *memoryStore) Get(_ context.Context, input GetInput) (*GetOutput, error) {
+	if err := s.requireObjects(); err != nil {
+		return nil, err
+	}
+	if err := validateGetInput(input); err != nil {
+		return nil, err
+	}
+	obj, err := s.lookup(input.Ref)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(obj.payload)) > input.MaxBytes {
+		return nil, ErrObjectTooLarge
+	}
+	return &GetOutput{
+		Ref:         input.Ref,
+		Payload:     cloneBytes(obj.payload),
+		ContentType: obj.contentType,
+		Metadata:    cloneMetadata(obj.metadata),
+		ETag:        obj.etag,
+	}, nil
+}
+
+func (s *memoryStore) GetReader(_ context.Context, input GetInput) (*GetReaderOutput, error) {
+	if err := s.requireObjects(); err != nil {
+		return nil, err
+	}
+	if err := validateGetInput(input); err != nil {
+		return nil, err
+	}
+	obj, err := s.lookup(input.Ref)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(obj.payload)) > input.MaxBytes {
+		return nil, ErrObjectTooLarge
+	}
+	// Stored payloads are never mutated in place, so the reader can share them.
+	return &GetReaderOutput{
+		Info: obj.info(input.Ref),
+		Body: io.NopCloser(bytes.NewReader(obj.payload)),
+	}, nil
+}
+
+func (s *memoryStore) Head(_ context.Context, input HeadInput) (*ObjectInfo, error) {
+	if err := s.requireObjects(); err != nil {
+		return nil, err
+	}
+	if err := validateHeadInput(input); err != nil {
+		return nil, err
+	}
+	obj, err := s.lookup(input.Ref)
+	if err != nil {
+		return nil, err
+	}
+	info := obj.info(input.Ref)
+	return &info, nil
+}
+
+func (s *memoryStore) List(_ context.Context, input ListInput) (*ListOutput, error) {
+	if err := s.requireObjects(); err != nil {
+		return nil, err
+	}
+	input, err := validateListInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	objects := make([]ObjectInfo, 0, len(s.objects))
+	for name, obj := range s.objects {
+		if name.bucket == input.Bucket {
+			objects = append(objects, obj.info(ObjectRef{Bucket: name.bucket, Key: name.key}))
+		}
+	}
+	s.mu.RUnlock()
+	return listPage(objects, input)
+}
+
+func (s *memoryStore) Copy(_ context.Context, input CopyInput) (ObjectRef, error) {
+	if err := s.requireObjects(); err != nil {
+		return ObjectRef{}, err
+	}
+	if err := validateCopyInput(input); err != nil {
+		return ObjectRef{}, err
+	}
+	source, err := s.lookup(input.Source)
+	if err != nil {
+		return ObjectRef{}, err
+	}
+	if input.SourceIfMatch != "" && source.etag != input.SourceIfMatch {
+		return ObjectRef{}, ErrPreconditionFailed
+	}
+	contentType, metadata := source.contentType, source.metadata
+	if input.ReplaceMetadata {
+		contentType, metadata = input.ContentType, input.Metadata
+	}
+	return s.write(input.Destination, source.payload, contentType, metadata, input.Condition)
+}
+
+func (s *memoryStore) Delete(_ context.Context, input DeleteInput) error {
+	if err := s.requireObjects(); err != nil {
+		return err
+	}
+	if err := validateDeleteInput(input); err != nil {
+		return err
+	}
+	if input.Ref.VersionID != "" {
+		return ErrInvalidObjectRef
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, memoryObjectName{bucket: input.Ref.Bucket, key: input.Ref.Key})
+	return nil
+}
+
+func (s *memoryStore) lookup(ref ObjectRef) (memoryObject, error) {
+	if ref.VersionID != "" {
+		return memoryObject{}, ErrInvalidObjectRef
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	obj, ok := s.objects[memoryObjectName{bucket: ref.Bucket, key: ref.Key}]
+	if !ok {
+		return memoryObject{}, ErrObjectNotFound
+	}
+	return obj, nil
+}
+
+func (s *memoryStore) requireObjects() error {
+	if s == nil || s.objects == nil {
+		return ErrInvalidStoreConfig
+	}
+	return nil
+}
+
+func (o memoryObject) info(ref ObjectRef) ObjectInfo {
+	return ObjectInfo{
+		Ref:          ref,
+		Size:         int64(len(o.payload)),
+		ETag:         o.etag,
+		LastModified: o.lastModified,
+		ContentType:  o.contentType,
+		Metadata:     cloneMetadata(o.metadata),
+	}
+}
@@ -0,0 +1,35 @@
+package objectstore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemoryStoreContract(t *testing.T) {
+	store, err := NewMemoryStore(MemoryStoreConfig{})
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+	exerciseLocalStoreContract(t, store)
+	exerciseLocalStoreFailClosed(t, store)
+	exerciseLocalStoreStreaming(t, store)
+	exerciseLocalStoreConditions(t, store)
+	exerciseLocalStoreList(t, store)
+	exerciseLocalStoreCopy(t, store)
+}
+
+func TestMemoryStoreEncryptionValidation(t *testing.T) {
+	if _, err := NewMemoryStore(MemoryStoreConfig{Encryption: S3EncryptionConfig{Mode: S3EncryptionKMS, KMSKeyID: "alias/app"}}); err != nil {
+		t.Fatalf("NewMemoryStore() kms error = %v", err)
+	}
+	if _, err := NewMemoryStore(MemoryStoreConfig{Encryption: S3EncryptionConfig{Mode: S3EncryptionKMS}}); !errors.Is(err, ErrInvalidEncryptionConfig) {
+		t.Fatalf("NewMemoryStore() error = %v, want ErrInvalidEncryptionConfig", err)
+	}
+}
+
+func TestMemoryStoreZeroValueFailsClosed(t *testing.T) {
+	var store *memoryStore
+	if err := store.requireObjects(); !errors.Is(err, ErrInvalidStoreConfig) {
+		t.Fatalf("requireObjects() error = %v, want ErrInvalidStoreConfig", err)
+	}
+}
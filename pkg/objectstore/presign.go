@@ -0,0 +1,231 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ErrInvalidPresignInput is returned when a presign request is incomplete or out of range.
+var ErrInvalidPresignInput = errors.New("objectstore: invalid presign input")
+
+// Presign limits enforced by Presigner implementations.
+const (
+	// DefaultPresignExpiry is the URL lifetime used when Expires is zero.
+	DefaultPresignExpiry = 15 * time.Minute
+	// MinPresignExpiry is the shortest URL lifetime S3 signatures can express.
+	MinPresignExpiry = time.Second
+	// MaxPresignExpiry is the longest URL lifetime SigV4 allows.
+	MaxPresignExpiry = 7 * 24 * time.Hour
+	// MaxPresignPutContentLength is the largest body a single presigned PUT may upload.
+	MaxPresignPutContentLength int64 = 5 << 30
+)
+
+// Presigner issues time-limited URLs so clients can upload or download object
+// bytes directly, bypassing the Lambda that authorized the request.
+//
+// The Store returned by NewS3Store implements Presigner; detect the capability
+// with a type assertion. Local stores do not, because they have no URL to sign.
+type Presigner interface {
+	PresignGet(context.Context, PresignGetInput) (*PresignedRequest, error)
+	PresignPut(context.Context, PresignPutInput) (*PresignedRequest, error)
+}
+
+// PresignGetInput describes one presigned download.
+//
+// Ref.VersionID pins the download to one object version. The response
+// overrides are signed into the URL, so clients cannot change them.
+type PresignGetInput struct {
+	Ref                        ObjectRef
+	Expires                    time.Duration
+	ResponseContentType        string
+	ResponseContentDisposition string
+}
+
+// PresignPutInput describes one presigned upload.
+//
+// ContentLength is required and signed, so S3 rejects bodies of any other
+// size. ContentType and Metadata, when set, are signed as well and must be sent
+// unchanged by the client.
+type PresignPutInput struct {
+	Ref           ObjectRef
+	Expires       time.Duration
+	ContentType   string
+	ContentLength int64
+	Metadata      map[string]string
+}
+
+// PresignedRequest is a signed request a client can send without credentials.
+//
+// Headers lists every signed header other than Host; the client must send each
+// one with exactly the given value. ExpiresAt is a conservative expiry: the
+// signature is never valid past it.
+type PresignedRequest struct {
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+type s3PresignClient interface {
+	PresignGetObject(context.Context, *s3.GetObjectInput, ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+	PresignPutObject(context.Context, *s3.PutObjectInput, ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+// newS3PresignClient returns a presign client whose signing time comes from now
+// rather than the SDK clock, so expiry and signatures share one time source.
+func newS3PresignClient(client *s3.Client, now func() time.Time) *s3.PresignClient {
+	return s3.NewPresignClient(client, func(o *s3.PresignOptions) {
+		o.Presigner = clockedS3Presigner{
+			signer: v4.NewSigner(func(so *v4.SignerOptions) {
+				so.DisableURIPathEscaping = true
+			}),
+			now: now,
+		}
+	})
+}
+
+type clockedS3Presigner struct {
+	signer *v4.Signer
+	now    func() time.Time
+}
+
+func (p clockedS3Presigner) PresignHTTP(
+	ctx context.Context, credentials aws.Credentials, r *http.Request,
+	payloadHash string, service string, region string, _ time.Time,
+	optFns ...func(*v4.SignerOptions),
+) (string, http.Header, error) {
+	return p.signer.PresignHTTP(ctx, credentials, r, payloadHash, service, region, p.now(), optFns...)
+}
+
+func (s *s3Store) PresignGet(ctx context.Context, input PresignGetInput) (*PresignedRequest, error) {
+	if err := s.requirePresigner(); err != nil {
+		return nil, err
+	}
+	expires, err := validatePresignGetInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &s3.GetObjectInput{
+		Bucket:                     aws.String(input.Ref.Bucket),
+		Key:                        aws.String(input.Ref.Key),
+		VersionId:                  optionalString(input.Ref.VersionID),
+		ResponseContentType:        optionalString(input.ResponseContentType),
+		ResponseContentDisposition: optionalString(input.ResponseContentDisposition),
+	}
+	expiresAt := s.presignExpiresAt(expires)
+	out, err := s.presigner.PresignGetObject(ctx, params, withPresignExpiry(expires))
+	if err != nil {
+		return nil, err
+	}
+	return presignedRequest(out, expiresAt)
+}
+
+func (s *s3Store) PresignPut(ctx context.Context, input PresignPutInput) (*PresignedRequest, error) {
+	if err := s.requirePresigner(); err != nil {
+		return nil, err
+	}
+	expires, err := validatePresignPutInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &s3.PutObjectInput{
+		Bucket:        aws.String(input.Ref.Bucket),
+		Key:           aws.String(input.Ref.Key),
+		ContentLength: aws.Int64(input.ContentLength),
+		ContentType:   optionalString(input.ContentType),
+	}
+	if len(input.Metadata) > 0 {
+		params.Metadata = cloneMetadata(input.Metadata)
+	}
+	params.ServerSideEncryption, params.SSEKMSKeyId = s3EncryptionHeaders(s.encryption)
+
+	expiresAt := s.presignExpiresAt(expires)
+	out, err := s.presigner.PresignPutObject(ctx, params, withPresignExpiry(expires))
+	if err != nil {
+		return nil, err
+	}
+	return presignedRequest(out, expiresAt)
+}
+
+func (s *s3Store) requirePresigner() error {
+	if s == nil || s.presigner == nil || s.now == nil {
+		return ErrInvalidStoreConfig
+	}
+	return nil
+}
+
+// presignExpiresAt truncates to the second because X-Amz-Date does, so the
+// reported expiry never outlives the signature.
+func (s *s3Store) presignExpiresAt(expires time.Duration) time.Time {
+	return s.now().UTC().Truncate(time.Second).Add(expires)
+}
+
+func withPresignExpiry(expires time.Duration) func(*s3.PresignOptions) {
+	return func(o *s3.PresignOptions) {
+		o.Expires = expires
+	}
+}
+
+func presignedRequest(out *v4.PresignedHTTPRequest, expiresAt time.Time) (*PresignedRequest, error) {
+	if out == nil || out.URL == "" {
+		return nil, ErrInvalidStoreConfig
+	}
+	req := &PresignedRequest{Method: out.Method, URL: out.URL, ExpiresAt: expiresAt}
+	for name, values := range out.SignedHeader {
+		if strings.EqualFold(name, "host") || len(values) == 0 {
+			continue
+		}
+		if req.Headers == nil {
+			req.Headers = make(map[string]string, len(out.SignedHeader))
+		}
+		req.Headers[http.CanonicalHeaderKey(name)] = strings.Join(values, ",")
+	}
+	return req, nil
+}
+
+func validatePresignGetInput(input PresignGetInput) (time.Duration, error) {
+	if err := input.Ref.Validate(); err != nil {
+		return 0, err
+	}
+	if containsControl(input.ResponseContentType) || containsControl(input.ResponseContentDisposition) {
+		return 0, ErrInvalidPresignInput
+	}
+	return normalizePresignExpiry(input.Expires)
+}
+
+func validatePresignPutInput(input PresignPutInput) (time.Duration, error) {
+	if err := validateWriteRef(input.Ref); err != nil {
+		return 0, err
+	}
+	if input.ContentLength <= 0 || input.ContentLength > MaxPresignPutContentLength {
+		return 0, ErrInvalidPresignInput
+	}
+	if containsControl(input.ContentType) {
+		return 0, ErrInvalidPresignInput
+	}
+	for key, value := range input.Metadata {
+		if key == "" || containsControl(key) || containsControl(value) {
+			return 0, ErrInvalidPresignInput
+		}
+	}
+	return normalizePresignExpiry(input.Expires)
+}
+
+func normalizePresignExpiry(expires time.Duration) (time.Duration, error) {
+	if expires == 0 {
+		return DefaultPresignExpiry, nil
+	}
+	if expires < MinPresignExpiry || expires > MaxPresignExpiry || expires%time.Second != 0 {
+		return 0, ErrInvalidPresignInput
+	}
+	return expires, nil
+}
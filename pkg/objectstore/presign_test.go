@@ -0,0 +1,293 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	presignTestAccessKey = "AKIDEXAMPLE"
+	presignTestSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	presignTestRegion    = "us-east-1"
+)
+
+var presignTestNow = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+var _ Presigner = (*s3Store)(nil)
+
+func newPresignTestStore(t *testing.T, storeConfig S3StoreConfig) *s3Store {
+	t.Helper()
+	client := s3.New(s3.Options{
+		Region:      presignTestRegion,
+		Credentials: credentials.NewStaticCredentialsProvider(presignTestAccessKey, presignTestSecretKey, ""),
+	})
+	store, err := newS3StoreWithClient(client, storeConfig)
+	if err != nil {
+		t.Fatalf("newS3StoreWithClient() error = %v", err)
+	}
+	store.now = func() time.Time { return presignTestNow }
+	store.presigner = newS3PresignClient(client, store.now)
+	return store
+}
+
+func TestS3StorePresignGet(t *testing.T) {
+	store := newPresignTestStore(t, S3StoreConfig{})
+
+	out, err := store.PresignGet(context.Background(), PresignGetInput{
+		Ref:                        ObjectRef{Bucket: "bucket-a", Key: "reports/2026.pdf", VersionID: "v1"},
+		Expires:                    10 * time.Minute,
+		ResponseContentDisposition: `attachment; filename="2026.pdf"`,
+	})
+	if err != nil {
+		t.Fatalf("PresignGet() error = %v", err)
+	}
+	if out.Method != "GET" || len(out.Headers) != 0 {
+		t.Fatalf("PresignGet() = %#v", out)
+	}
+	if want := presignTestNow.Add(10 * time.Minute); !out.ExpiresAt.Equal(want) {
+		t.Fatalf("PresignGet() ExpiresAt = %v, want %v", out.ExpiresAt, want)
+	}
+
+	u := verifyPresignedSignature(t, out)
+	query := u.Query()
+	if query.Get("X-Amz-Date") != "20260102T030405Z" || query.Get("X-Amz-Expires") != "600" {
+		t.Fatalf("PresignGet() date/expiry query = %v", query)
+	}
+	if query.Get("versionId") != "v1" || query.Get("response-content-disposition") != `attachment; filename="2026.pdf"` {
+		t.Fatalf("PresignGet() object query = %v", query)
+	}
+	if query.Get("X-Amz-SignedHeaders") != "host" {
+		t.Fatalf("PresignGet() signed headers = %q, want host", query.Get("X-Amz-SignedHeaders"))
+	}
+}
+
+func TestS3StorePresignPutSignsConstraintsAndEncryption(t *testing.T) {
+	store := newPresignTestStore(t, S3StoreConfig{Encryption: S3EncryptionConfig{Mode: S3EncryptionKMS, KMSKeyID: "alias/uploads"}})
+
+	out, err := store.PresignPut(context.Background(), PresignPutInput{
+		Ref:           ObjectRef{Bucket: "bucket-a", Key: "uploads/report.pdf"},
+		ContentType:   "application/pdf",
+		ContentLength: 42,
+		Metadata:      map[string]string{"tenant": "t1"},
+	})
+	if err != nil {
+		t.Fatalf("PresignPut() error = %v", err)
+	}
+	if out.Method != "PUT" {
+		t.Fatalf("PresignPut() method = %q", out.Method)
+	}
+	if want := presignTestNow.Add(DefaultPresignExpiry); !out.ExpiresAt.Equal(want) {
+		t.Fatalf("PresignPut() ExpiresAt = %v, want %v", out.ExpiresAt, want)
+	}
+	wantHeaders := map[string]string{
+		"Content-Length":                              "42",
+		"Content-Type":                                "application/pdf",
+		"X-Amz-Meta-Tenant":                           "t1",
+		"X-Amz-Server-Side-Encryption":                "aws:kms",
+		"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id": "alias/uploads",
+	}
+	if len(out.Headers) != len(wantHeaders) {
+		t.Fatalf("PresignPut() headers = %#v, want %#v", out.Headers, wantHeaders)
+	}
+	for name, want := range wantHeaders {
+		if out.Headers[name] != want {
+			t.Fatalf("PresignPut() header %s = %q, want %q", name, out.Headers[name], want)
+		}
+	}
+
+	u := verifyPresignedSignature(t, out)
+	signed := strings.Split(u.Query().Get("X-Amz-SignedHeaders"), ";")
+	for _, name := range []string{"content-length", "content-type", "x-amz-server-side-encryption", "x-amz-server-side-encryption-aws-kms-key-id"} {
+		if !containsString(signed, name) {
+			t.Fatalf("PresignPut() signed headers %v missing %s", signed, name)
+		}
+	}
+
+	// A client that changes a signed constraint no longer matches the signature.
+	tampered := *out
+	tampered.Headers = cloneMetadata(out.Headers)
+	tampered.Headers["Content-Length"] = "43"
+	if presignedSignature(t, &tampered) == u.Query().Get("X-Amz-Signature") {
+		t.Fatalf("signature did not cover Content-Length")
+	}
+}
+
+func TestS3StorePresignS3ManagedEncryption(t *testing.T) {
+	store := newPresignTestStore(t, S3StoreConfig{Encryption: S3EncryptionConfig{Mode: S3EncryptionS3Managed}})
+
+	out, err := store.PresignPut(context.Background(), PresignPutInput{
+		Ref:           ObjectRef{Bucket: "bucket-a", Key: "key"},
+		ContentLength: 1,
+		Expires:       time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("PresignPut() error = %v", err)
+	}
+	if out.Headers["X-Amz-Server-Side-Encryption"] != "AES256" {
+		t.Fatalf("PresignPut() headers = %#v", out.Headers)
+	}
+	if _, ok := out.Headers["X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"]; ok {
+		t.Fatalf("PresignPut() s3-managed mode emitted a KMS key header: %#v", out.Headers)
+	}
+	verifyPresignedSignature(t, out)
+}
+
+func TestS3StorePresignFailClosed(t *testing.T) {
+	store := newPresignTestStore(t, S3StoreConfig{})
+	ctx := context.Background()
+	ref := ObjectRef{Bucket: "bucket-a", Key: "key"}
+
+	for _, input := range []PresignPutInput{
+		{Ref: ref},
+		{Ref: ref, ContentLength: -1},
+		{Ref: ref, ContentLength: MaxPresignPutContentLength + 1},
+		{Ref: ref, ContentLength: 1, Expires: time.Millisecond},
+		{Ref: ref, ContentLength: 1, Expires: 1500 * time.Millisecond},
+		{Ref: ref, ContentLength: 1, Expires: MaxPresignExpiry + time.Second},
+		{Ref: ref, ContentLength: 1, ContentType: "text/plain\r\nX-Evil: 1"},
+		{Ref: ref, ContentLength: 1, Metadata: map[string]string{"": "v"}},
+	} {
+		if _, err := store.PresignPut(ctx, input); !errors.Is(err, ErrInvalidPresignInput) {
+			t.Fatalf("PresignPut(%#v) error = %v, want ErrInvalidPresignInput", input, err)
+		}
+	}
+	if _, err := store.PresignPut(ctx, PresignPutInput{Ref: ObjectRef{Bucket: "bucket-a", Key: "key", VersionID: "v1"}, ContentLength: 1}); !errors.Is(err, ErrInvalidObjectRef) {
+		t.Fatalf("PresignPut() versioned ref error = %v, want ErrInvalidObjectRef", err)
+	}
+	if _, err := store.PresignGet(ctx, PresignGetInput{Ref: ref, Expires: -time.Second}); !errors.Is(err, ErrInvalidPresignInput) {
+		t.Fatalf("PresignGet() negative expiry error = %v, want ErrInvalidPresignInput", err)
+	}
+	if _, err := store.PresignGet(ctx, PresignGetInput{Ref: ObjectRef{Bucket: "bucket-a"}}); !errors.Is(err, ErrInvalidObjectRef) {
+		t.Fatalf("PresignGet() invalid ref error = %v, want ErrInvalidObjectRef", err)
+	}
+
+	unsigned, err := newS3StoreWithClient(&recordingS3Client{}, S3StoreConfig{})
+	if err != nil {
+		t.Fatalf("newS3StoreWithClient() error = %v", err)
+	}
+	if _, err := unsigned.PresignGet(ctx, PresignGetInput{Ref: ref}); !errors.Is(err, ErrInvalidStoreConfig) {
+		t.Fatalf("PresignGet() without presigner error = %v, want ErrInvalidStoreConfig", err)
+	}
+}
+
+func TestLocalStoresDoNotPresign(t *testing.T) {
+	store, err := NewMemoryStore(MemoryStoreConfig{})
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+	if _, ok := store.(Presigner); ok {
+		t.Fatalf("memory store implements Presigner")
+	}
+}
+
+// verifyPresignedSignature recomputes the SigV4 query signature from the URL
+// and headers alone, independent of the SDK signer, and fails on mismatch.
+func verifyPresignedSignature(t *testing.T, req *PresignedRequest) *url.URL {
+	t.Helper()
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		t.Fatalf("url.Parse() error = %v", err)
+	}
+	query := u.Query()
+	if query.Get("X-Amz-Algorithm") != "AWS4-HMAC-SHA256" {
+		t.Fatalf("X-Amz-Algorithm = %q", query.Get("X-Amz-Algorithm"))
+	}
+	if want := presignTestAccessKey + "/20260102/" + presignTestRegion + "/s3/aws4_request"; query.Get("X-Amz-Credential") != want {
+		t.Fatalf("X-Amz-Credential = %q, want %q", query.Get("X-Amz-Credential"), want)
+	}
+	if got, want := query.Get("X-Amz-Signature"), presignedSignature(t, req); got != want {
+		t.Fatalf("X-Amz-Signature = %s, want recomputed %s", got, want)
+	}
+	return u
+}
+
+func presignedSignature(t *testing.T, req *PresignedRequest) string {
+	t.Helper()
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		t.Fatalf("url.Parse() error = %v", err)
+	}
+	query := u.Query()
+	amzDate := query.Get("X-Amz-Date")
+	scope := amzDate[:8] + "/" + presignTestRegion + "/s3/aws4_request"
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if key != "X-Amz-Signature" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, sigV4Escape(key)+"="+sigV4Escape(query.Get(key)))
+	}
+
+	signedHeaders := query.Get("X-Amz-SignedHeaders")
+	var headers strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := u.Host
+		if name != "host" {
+			value = req.Headers[http.CanonicalHeaderKey(name)]
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonical := strings.Join([]string{
+		req.Method,
+		u.EscapedPath(),
+		strings.Join(pairs, "&"),
+		headers.String(),
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+presignTestSecretKey), amzDate[:8])
+	key = hmacSHA256(key, presignTestRegion)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sigV4Escape percent-encodes everything except RFC 3986 unreserved characters.
+func sigV4Escape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if isFilesystemUnreserved(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+	}
+	return b.String()
+}
+
+func containsString(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
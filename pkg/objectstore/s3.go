@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
@@ -43,6 +45,8 @@ type S3StoreConfig struct {
 }
 
 // NewS3Store loads AWS SDK v2 configuration and returns the S3-backed Store.
+//
+// The returned Store also implements Presigner.
 func NewS3Store(ctx context.Context, storeConfig S3StoreConfig) (Store, error) {
 	if _, err := normalizeS3StoreConfig(storeConfig); err != nil {
 		return nil, err
@@ -51,11 +55,20 @@ func NewS3Store(ctx context.Context, storeConfig S3StoreConfig) (Store, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newS3StoreWithClient(s3.NewFromConfig(cfg), storeConfig)
+	client := s3.NewFromConfig(cfg)
+	store, err := newS3StoreWithClient(client, storeConfig)
+	if err != nil {
+		return nil, err
+	}
+	store.now = time.Now
+	store.presigner = newS3PresignClient(client, store.now)
+	return store, nil
 }
 
 type s3Store struct {
 	client     s3StoreClient
+	presigner  s3PresignClient
+	now        func() time.Time
 	encryption S3EncryptionConfig
 }
 
@@ -63,6 +76,13 @@ type s3StoreClient interface {
 	PutObject(context.Context, *s3.PutObjectInput, ...func(*s3.Options)) (*s3.PutObjectOutput, error)
 	GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error)
 	DeleteObject(context.Context, *s3.DeleteObjectInput, ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	HeadObject(context.Context, *s3.HeadObjectInput, ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	CopyObject(context.Context, *s3.CopyObjectInput, ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	CreateMultipartUpload(context.Context, *s3.CreateMultipartUploadInput, ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(context.Context, *s3.UploadPartInput, ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(context.Context, *s3.CompleteMultipartUploadInput, ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(context.Context, *s3.AbortMultipartUploadInput, ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
 }
 
 func newS3StoreWithClient(client s3StoreClient, storeConfig S3StoreConfig) (*s3Store, error) {
@@ -83,29 +103,48 @@ func (s *s3Store) Put(ctx context.Context, input PutInput) (ObjectRef, error) {
 	if err := validatePutInput(input); err != nil {
 		return ObjectRef{}, err
 	}
+	ref, _, err := s.putObject(ctx, input.Ref, bytes.NewReader(input.Payload), input.ContentType, input.Metadata, input.Condition)
+	return ref, err
+}
 
+// PutETag is Put that also returns the ETag S3 assigned to the new object.
+func (s *s3Store) PutETag(ctx context.Context, input PutInput) (ObjectRef, string, error) {
+	if err := s.requireClient(); err != nil {
+		return ObjectRef{}, "", err
+	}
+	if err := validatePutInput(input); err != nil {
+		return ObjectRef{}, "", err
+	}
+	return s.putObject(ctx, input.Ref, bytes.NewReader(input.Payload), input.ContentType, input.Metadata, input.Condition)
+}
+
+func (s *s3Store) putObject(ctx context.Context, ref ObjectRef, body io.Reader, contentType string, metadata map[string]string, condition WriteCondition) (ObjectRef, string, error) {
 	params := &s3.PutObjectInput{
-		Bucket: aws.String(input.Ref.Bucket),
-		Key:    aws.String(input.Ref.Key),
-		Body:   bytes.NewReader(input.Payload),
+		Bucket:      aws.String(ref.Bucket),
+		Key:         aws.String(ref.Key),
+		Body:        body,
+		IfMatch:     optionalString(condition.IfMatch),
+		IfNoneMatch: optionalString(condition.IfNoneMatch),
 	}
-	if input.ContentType != "" {
-		params.ContentType = aws.String(input.ContentType)
+	if contentType != "" {
+		params.ContentType = aws.String(contentType)
 	}
-	if len(input.Metadata) > 0 {
-		params.Metadata = cloneMetadata(input.Metadata)
+	if len(metadata) > 0 {
+		params.Metadata = cloneMetadata(metadata)
 	}
-	applyS3Encryption(params, s.encryption)
+	params.ServerSideEncryption, params.SSEKMSKeyId = s3EncryptionHeaders(s.encryption)
 
 	out, err := s.client.PutObject(ctx, params)
 	if err != nil {
-		return ObjectRef{}, err
+		return ObjectRef{}, "", mapS3Error(err)
 	}
-	ref := input.Ref
-	if out != nil && out.VersionId != nil {
+	if out == nil {
+		return ref, "", nil
+	}
+	if out.VersionId != nil {
 		ref.VersionID = aws.ToString(out.VersionId)
 	}
-	return ref, nil
+	return ref, aws.ToString(out.ETag), nil
 }
 
 func (s *s3Store) Get(ctx context.Context, input GetInput) (*GetOutput, error) {
@@ -126,7 +165,7 @@ func (s *s3Store) Get(ctx context.Context, input GetInput) (*GetOutput, error) {
 
 	out, err := s.client.GetObject(ctx, params)
 	if err != nil {
-		return nil, err
+		return nil, mapS3Error(err)
 	}
 	if out == nil || out.Body == nil {
 		return nil, ErrInvalidStoreConfig
@@ -148,6 +187,7 @@ func (s *s3Store) Get(ctx context.Context, input GetInput) (*GetOutput, error) {
 		Payload:     payload,
 		ContentType: aws.ToString(out.ContentType),
 		Metadata:    cloneMetadata(out.Metadata),
+		ETag:        aws.ToString(out.ETag),
 	}, nil
 }
 
@@ -166,8 +206,10 @@ func (s *s3Store) Delete(ctx context.Context, input DeleteInput) error {
 	if input.Ref.VersionID != "" {
 		params.VersionId = aws.String(input.Ref.VersionID)
 	}
-	_, err := s.client.DeleteObject(ctx, params)
-	return err
+	if _, err := s.client.DeleteObject(ctx, params); err != nil {
+		return mapS3Error(err)
+	}
+	return nil
 }
 
 func (s *s3Store) requireClient() error {
@@ -205,14 +247,41 @@ func normalizeS3StoreConfig(storeConfig S3StoreConfig) (S3StoreConfig, error) {
 	return storeConfig, nil
 }
 
-func applyS3Encryption(params *s3.PutObjectInput, encryption S3EncryptionConfig) {
+// s3EncryptionHeaders returns the server-side encryption headers shared by
+// PutObject, CreateMultipartUpload, and CopyObject.
+func s3EncryptionHeaders(encryption S3EncryptionConfig) (types.ServerSideEncryption, *string) {
 	switch encryption.Mode {
 	case S3EncryptionS3Managed:
-		params.ServerSideEncryption = types.ServerSideEncryptionAes256
+		return types.ServerSideEncryptionAes256, nil
 	case S3EncryptionKMS:
-		params.ServerSideEncryption = types.ServerSideEncryptionAwsKms
-		params.SSEKMSKeyId = aws.String(encryption.KMSKeyID)
+		return types.ServerSideEncryptionAwsKms, aws.String(encryption.KMSKeyID)
+	default:
+		return "", nil
+	}
+}
+
+// mapS3Error wraps S3 errors that have a stable objectstore meaning while
+// keeping the original error available to errors.As.
+func mapS3Error(err error) error {
+	var apiErr interface{ ErrorCode() string }
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch apiErr.ErrorCode() {
+	case "NoSuchKey", "NotFound":
+		return fmt.Errorf("%w: %w", ErrObjectNotFound, err)
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		return fmt.Errorf("%w: %w", ErrPreconditionFailed, err)
+	default:
+		return err
+	}
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
 	}
+	return aws.String(value)
 }
 
 func readBounded(body io.Reader, maxBytes int64) ([]byte, error) {
@@ -0,0 +1,288 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// PutReader buffers one part of Body at a time. Bodies that fit in a single
+// part are written with PutObject; larger bodies use a multipart upload that is
+// aborted if any part or the completion fails.
+func (s *s3Store) PutReader(ctx context.Context, input PutReaderInput) (ObjectRef, error) {
+	if err := s.requireClient(); err != nil {
+		return ObjectRef{}, err
+	}
+	if err := validatePutReaderInput(input); err != nil {
+		return ObjectRef{}, err
+	}
+
+	partSize := input.PartSize
+	if partSize == 0 {
+		partSize = DefaultMultipartPartSize
+	}
+	buf := make([]byte, partSize)
+	n, err := io.ReadFull(input.Body, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		ref, _, err := s.putObject(ctx, input.Ref, bytes.NewReader(buf[:n]), input.ContentType, input.Metadata, input.Condition)
+		return ref, err
+	}
+	if err != nil {
+		return ObjectRef{}, err
+	}
+	return s.putMultipart(ctx, input, buf)
+}
+
+// putMultipart uploads buf, which already holds the first full part, followed
+// by the rest of input.Body.
+func (s *s3Store) putMultipart(ctx context.Context, input PutReaderInput, buf []byte) (ObjectRef, error) {
+	params := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(input.Ref.Bucket),
+		Key:    aws.String(input.Ref.Key),
+	}
+	if input.ContentType != "" {
+		params.ContentType = aws.String(input.ContentType)
+	}
+	if len(input.Metadata) > 0 {
+		params.Metadata = cloneMetadata(input.Metadata)
+	}
+	params.ServerSideEncryption, params.SSEKMSKeyId = s3EncryptionHeaders(s.encryption)
+
+	created, err := s.client.CreateMultipartUpload(ctx, params)
+	if err != nil {
+		return ObjectRef{}, mapS3Error(err)
+	}
+	if created == nil || created.UploadId == nil {
+		return ObjectRef{}, ErrInvalidStoreConfig
+	}
+
+	ref, err := s.uploadParts(ctx, input, created.UploadId, buf)
+	if err != nil {
+		// Abort even when ctx is canceled so S3 does not keep billing for parts.
+		if _, abortErr := s.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(input.Ref.Bucket),
+			Key:      aws.String(input.Ref.Key),
+			UploadId: created.UploadId,
+		}); abortErr != nil {
+			return ObjectRef{}, errors.Join(err, abortErr)
+		}
+		return ObjectRef{}, err
+	}
+	return ref, nil
+}
+
+func (s *s3Store) uploadParts(ctx context.Context, input PutReaderInput, uploadID *string, buf []byte) (ObjectRef, error) {
+	var parts []types.CompletedPart
+	n := len(buf)
+	for n > 0 {
+		if len(parts) == MaxMultipartParts {
+			return ObjectRef{}, ErrInvalidStreamInput
+		}
+		partNumber := aws.Int32(int32(len(parts) + 1)) // #nosec G115 -- bounded by MaxMultipartParts
+		out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(input.Ref.Bucket),
+			Key:        aws.String(input.Ref.Key),
+			UploadId:   uploadID,
+			PartNumber: partNumber,
+			Body:       bytes.NewReader(buf[:n]),
+		})
+		if err != nil {
+			return ObjectRef{}, mapS3Error(err)
+		}
+		if out == nil || out.ETag == nil {
+			return ObjectRef{}, ErrInvalidStoreConfig
+		}
+		parts = append(parts, types.CompletedPart{ETag: out.ETag, PartNumber: partNumber})
+
+		n, err = io.ReadFull(input.Body, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return ObjectRef{}, err
+		}
+	}
+
+	out, err := s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(input.Ref.Bucket),
+		Key:             aws.String(input.Ref.Key),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		IfMatch:         optionalString(input.Condition.IfMatch),
+		IfNoneMatch:     optionalString(input.Condition.IfNoneMatch),
+	})
+	if err != nil {
+		return ObjectRef{}, mapS3Error(err)
+	}
+	ref := input.Ref
+	if out != nil && out.VersionId != nil {
+		ref.VersionID = aws.ToString(out.VersionId)
+	}
+	return ref, nil
+}
+
+func (s *s3Store) GetReader(ctx context.Context, input GetInput) (*GetReaderOutput, error) {
+	if err := s.requireClient(); err != nil {
+		return nil, err
+	}
+	if err := validateGetInput(input); err != nil {
+		return nil, err
+	}
+
+	params := &s3.GetObjectInput{
+		Bucket:    aws.String(input.Ref.Bucket),
+		Key:       aws.String(input.Ref.Key),
+		VersionId: optionalString(input.Ref.VersionID),
+	}
+	out, err := s.client.GetObject(ctx, params)
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+	if out == nil || out.Body == nil {
+		return nil, ErrInvalidStoreConfig
+	}
+	if aws.ToInt64(out.ContentLength) > input.MaxBytes {
+		closeBestEffort(out.Body)
+		return nil, ErrObjectTooLarge
+	}
+
+	ref := input.Ref
+	ref.VersionID = aws.ToString(out.VersionId)
+	return &GetReaderOutput{
+		Info: ObjectInfo{
+			Ref:          ref,
+			Size:         aws.ToInt64(out.ContentLength),
+			ETag:         aws.ToString(out.ETag),
+			LastModified: aws.ToTime(out.LastModified),
+			ContentType:  aws.ToString(out.ContentType),
+			Metadata:     cloneMetadata(out.Metadata),
+		},
+		Body: newBoundedReadCloser(out.Body, input.MaxBytes),
+	}, nil
+}
+
+func (s *s3Store) Head(ctx context.Context, input HeadInput) (*ObjectInfo, error) {
+	if err := s.requireClient(); err != nil {
+		return nil, err
+	}
+	if err := validateHeadInput(input); err != nil {
+		return nil, err
+	}
+
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:    aws.String(input.Ref.Bucket),
+		Key:       aws.String(input.Ref.Key),
+		VersionId: optionalString(input.Ref.VersionID),
+	})
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+	if out == nil {
+		return nil, ErrInvalidStoreConfig
+	}
+
+	ref := input.Ref
+	ref.VersionID = aws.ToString(out.VersionId)
+	return &ObjectInfo{
+		Ref:          ref,
+		Size:         aws.ToInt64(out.ContentLength),
+		ETag:         aws.ToString(out.ETag),
+		LastModified: aws.ToTime(out.LastModified),
+		ContentType:  aws.ToString(out.ContentType),
+		Metadata:     cloneMetadata(out.Metadata),
+	}, nil
+}
+
+func (s *s3Store) List(ctx context.Context, input ListInput) (*ListOutput, error) {
+	if err := s.requireClient(); err != nil {
+		return nil, err
+	}
+	input, err := validateListInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:            aws.String(input.Bucket),
+		Prefix:            optionalString(input.Prefix),
+		Delimiter:         optionalString(input.Delimiter),
+		MaxKeys:           aws.Int32(int32(input.MaxKeys)), // #nosec G115 -- bounded by MaxListKeys
+		ContinuationToken: optionalString(input.ContinuationToken),
+	})
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+	if out == nil {
+		return nil, ErrInvalidStoreConfig
+	}
+
+	page := &ListOutput{
+		NextContinuationToken: aws.ToString(out.NextContinuationToken),
+		IsTruncated:           aws.ToBool(out.IsTruncated),
+	}
+	for _, obj := range out.Contents {
+		page.Objects = append(page.Objects, ObjectInfo{
+			Ref:          ObjectRef{Bucket: input.Bucket, Key: aws.ToString(obj.Key)},
+			Size:         aws.ToInt64(obj.Size),
+			ETag:         aws.ToString(obj.ETag),
+			LastModified: aws.ToTime(obj.LastModified),
+		})
+	}
+	for _, prefix := range out.CommonPrefixes {
+		page.CommonPrefixes = append(page.CommonPrefixes, aws.ToString(prefix.Prefix))
+	}
+	return page, nil
+}
+
+func (s *s3Store) Copy(ctx context.Context, input CopyInput) (ObjectRef, error) {
+	if err := s.requireClient(); err != nil {
+		return ObjectRef{}, err
+	}
+	if err := validateCopyInput(input); err != nil {
+		return ObjectRef{}, err
+	}
+
+	params := &s3.CopyObjectInput{
+		Bucket:            aws.String(input.Destination.Bucket),
+		Key:               aws.String(input.Destination.Key),
+		CopySource:        aws.String(s3CopySource(input.Source)),
+		CopySourceIfMatch: optionalString(input.SourceIfMatch),
+		IfMatch:           optionalString(input.Condition.IfMatch),
+		IfNoneMatch:       optionalString(input.Condition.IfNoneMatch),
+	}
+	if input.ReplaceMetadata {
+		params.MetadataDirective = types.MetadataDirectiveReplace
+		params.ContentType = optionalString(input.ContentType)
+		params.Metadata = cloneMetadata(input.Metadata)
+	}
+	params.ServerSideEncryption, params.SSEKMSKeyId = s3EncryptionHeaders(s.encryption)
+
+	out, err := s.client.CopyObject(ctx, params)
+	if err != nil {
+		return ObjectRef{}, mapS3Error(err)
+	}
+	ref := input.Destination
+	if out != nil && out.VersionId != nil {
+		ref.VersionID = aws.ToString(out.VersionId)
+	}
+	return ref, nil
+}
+
+// s3CopySource encodes ref as the URL-encoded "bucket/key[?versionId=]" value
+// CopyObject expects. Key separators are kept so the source stays readable in
+// access logs.
+func s3CopySource(ref ObjectRef) string {
+	segments := strings.Split(ref.Key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	source := url.PathEscape(ref.Bucket) + "/" + strings.Join(segments, "/")
+	if ref.VersionID != "" {
+		source += "?versionId=" + url.QueryEscape(ref.VersionID)
+	}
+	return source
+}
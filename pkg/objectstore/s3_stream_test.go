@@ -0,0 +1,326 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestS3StorePutReaderSinglePartUsesPutObject(t *testing.T) {
+	client := &recordingS3Client{putVersionID: "v1"}
+	store, err := newS3StoreWithClient(client, S3StoreConfig{})
+	if err != nil {
+		t.Fatalf("newS3StoreWithClient() error = %v", err)
+	}
+
+	ref, err := store.PutReader(context.Background(), PutReaderInput{
+		Ref:       ObjectRef{Bucket: "bucket-a", Key: "small"},
+		Body:      bytes.NewReader([]byte("payload")),
+		Condition: WriteCondition{IfNoneMatch: "*"},
+	})
+	if err != nil {
+		t.Fatalf("PutReader() error = %v", err)
+	}
+	if ref.VersionID != "v1" || string(client.putBody) != "payload" {
+		t.Fatalf("PutReader() ref = %#v, body = %q", ref, client.putBody)
+	}
+	if aws.ToString(client.putInput.IfNoneMatch) != "*" || client.putInput.IfMatch != nil {
+		t.Fatalf("PutObject conditions = %#v", client.putInput)
+	}
+	if !reflect.DeepEqual(client.operations, []string{"PutObject"}) {
+		t.Fatalf("operations = %#v", client.operations)
+	}
+}
+
+func TestS3StorePutReaderMultipart(t *testing.T) {
+	client := &recordingS3Client{putVersionID: "v2"}
+	store, err := newS3StoreWithClient(client, S3StoreConfig{Encryption: S3EncryptionConfig{Mode: S3EncryptionKMS, KMSKeyID: "alias/app"}})
+	if err != nil {
+		t.Fatalf("newS3StoreWithClient() error = %v", err)
+	}
+	payload := bytes.Repeat([]byte("x"), int(2*MinMultipartPartSize+1))
+
+	ref, err := store.PutReader(context.Background(), PutReaderInput{
+		Ref:         ObjectRef{Bucket: "bucket-a", Key: "large"},
+		Body:        bytes.NewReader(payload),
+		ContentType: "application/octet-stream",
+		Condition:   WriteCondition{IfMatch: `"etag-1"`},
+		PartSize:    MinMultipartPartSize,
+	})
+	if err != nil {
+		t.Fatalf("PutReader() error = %v", err)
+	}
+	if ref.VersionID != "v2" {
+		t.Fatalf("PutReader() ref = %#v", ref)
+	}
+	wantOps := []string{"CreateMultipartUpload", "UploadPart", "UploadPart", "UploadPart", "CompleteMultipartUpload"}
+	if !reflect.DeepEqual(client.operations, wantOps) {
+		t.Fatalf("operations = %#v, want %#v", client.operations, wantOps)
+	}
+	if got := bytes.Join(client.partBodies, nil); !bytes.Equal(got, payload) || len(client.partBodies[2]) != 1 {
+		t.Fatalf("uploaded parts = %d bytes in %d parts", len(got), len(client.partBodies))
+	}
+	if client.createInput.ServerSideEncryption != types.ServerSideEncryptionAwsKms || aws.ToString(client.createInput.SSEKMSKeyId) != "alias/app" {
+		t.Fatalf("CreateMultipartUpload encryption = %#v", client.createInput)
+	}
+	if aws.ToString(client.createInput.ContentType) != "application/octet-stream" {
+		t.Fatalf("CreateMultipartUpload content type = %q", aws.ToString(client.createInput.ContentType))
+	}
+	parts := client.completeInput.MultipartUpload.Parts
+	if len(parts) != 3 || aws.ToInt32(parts[2].PartNumber) != 3 || aws.ToString(parts[2].ETag) != `"part-3"` {
+		t.Fatalf("CompleteMultipartUpload parts = %#v", parts)
+	}
+	if aws.ToString(client.completeInput.IfMatch) != `"etag-1"` {
+		t.Fatalf("CompleteMultipartUpload IfMatch = %q", aws.ToString(client.completeInput.IfMatch))
+	}
+}
+
+func TestS3StorePutReaderAbortsFailedMultipart(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  *recordingS3Client
+		wantErr error
+	}{
+		{name: "part failure", client: &recordingS3Client{uploadPartErr: errors.New("boom")}},
+		{
+			name:    "precondition failure",
+			client:  &recordingS3Client{completeErr: s3CodeError{code: "PreconditionFailed"}},
+			wantErr: ErrPreconditionFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := newS3StoreWithClient(tt.client, S3StoreConfig{})
+			if err != nil {
+				t.Fatalf("newS3StoreWithClient() error = %v", err)
+			}
+			_, err = store.PutReader(context.Background(), PutReaderInput{
+				Ref:      ObjectRef{Bucket: "bucket-a", Key: "large"},
+				Body:     bytes.NewReader(bytes.Repeat([]byte("x"), int(MinMultipartPartSize+1))),
+				PartSize: MinMultipartPartSize,
+			})
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("PutReader() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.client.abortInput == nil || aws.ToString(tt.client.abortInput.UploadId) != "upload-1" {
+				t.Fatalf("AbortMultipartUpload input = %#v", tt.client.abortInput)
+			}
+		})
+	}
+}
+
+func TestS3StoreGetReaderBounds(t *testing.T) {
+	client := &recordingS3Client{getBody: []byte("payload"), objectETag: `"etag-1"`, getVersionID: "v1"}
+	store, err := newS3StoreWithClient(client, S3StoreConfig{})
+	if err != nil {
+		t.Fatalf("newS3StoreWithClient() error = %v", err)
+	}
+	ref := ObjectRef{Bucket: "bucket-a", Key: "key"}
+
+	out, err := store.GetReader(context.Background(), GetInput{Ref: ref, MaxBytes: 7})
+	if err != nil {
+		t.Fatalf("GetReader() error = %v", err)
+	}
+	got, err := io.ReadAll(out.Body)
+	if err != nil || string(got) != "payload" {
+		t.Fatalf("GetReader() body = %q, %v", got, err)
+	}
+	if err := out.Body.Close(); err != nil || !client.bodyClosed {
+		t.Fatalf("GetReader() close = %v, closed = %v", err, client.bodyClosed)
+	}
+	if out.Info.ETag != `"etag-1"` || out.Info.Ref.VersionID != "v1" {
+		t.Fatalf("GetReader() info = %#v", out.Info)
+	}
+
+	// Without a declared length the cap is enforced while reading.
+	out, err = store.GetReader(context.Background(), GetInput{Ref: ref, MaxBytes: 3})
+	if err != nil {
+		t.Fatalf("GetReader() undeclared length error = %v", err)
+	}
+	if _, err := io.ReadAll(out.Body); !errors.Is(err, ErrObjectTooLarge) {
+		t.Fatalf("GetReader() read over cap error = %v, want ErrObjectTooLarge", err)
+	}
+
+	client.bodyClosed = false
+	client.getLength = aws.Int64(7)
+	if _, err := store.GetReader(context.Background(), GetInput{Ref: ref, MaxBytes: 3}); !errors.Is(err, ErrObjectTooLarge) {
+		t.Fatalf("GetReader() declared length error = %v, want ErrObjectTooLarge", err)
+	}
+	if !client.bodyClosed {
+		t.Fatalf("GetReader() did not close body for oversized object")
+	}
+}
+
+func TestS3StoreHead(t *testing.T) {
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	client := &recordingS3Client{
+		getBody:        []byte("payload"),
+		contentType:    "text/plain",
+		metadata:       map[string]string{"a": "b"},
+		objectETag:     `"etag-1"`,
+		objectModified: modified,
+	}
+	store, err := newS3StoreWithClient(client, S3StoreConfig{})
+	if err != nil {
+		t.Fatalf("newS3StoreWithClient() error = %v", err)
+	}
+
+	info, err := store.Head(context.Background(), HeadInput{Ref: ObjectRef{Bucket: "bucket-a", Key: "key", VersionID: "v1"}})
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	want := &ObjectInfo{
+		Ref:          ObjectRef{Bucket: "bucket-a", Key: "key"},
+		Size:         7,
+		ETag:         `"etag-1"`,
+		LastModified: modified,
+		ContentType:  "text/plain",
+		Metadata:     map[string]string{"a": "b"},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Fatalf("Head() = %#v, want %#v", info, want)
+	}
+	if aws.ToString(client.headInput.VersionId) != "v1" {
+		t.Fatalf("HeadObject VersionId = %q", aws.ToString(client.headInput.VersionId))
+	}
+
+	client.headErr = s3CodeError{code: "NotFound"}
+	_, err = store.Head(context.Background(), HeadInput{Ref: ObjectRef{Bucket: "bucket-a", Key: "missing"}})
+	var codeErr s3CodeError
+	if !errors.Is(err, ErrObjectNotFound) || !errors.As(err, &codeErr) {
+		t.Fatalf("Head() missing error = %v, want ErrObjectNotFound wrapping the S3 error", err)
+	}
+}
+
+func TestS3StoreList(t *testing.T) {
+	client := &recordingS3Client{listOutput: &s3.ListObjectsV2Output{
+		Contents: []types.Object{
+			{Key: aws.String("logs/a.json"), Size: aws.Int64(3), ETag: aws.String(`"a"`)},
+		},
+		CommonPrefixes:        []types.CommonPrefix{{Prefix: aws.String("logs/2026/")}},
+		IsTruncated:           aws.Bool(true),
+		NextContinuationToken: aws.String("next"),
+	}}
+	store, err := newS3StoreWithClient(client, S3StoreConfig{})
+	if err != nil {
+		t.Fatalf("newS3StoreWithClient() error = %v", err)
+	}
+
+	page, err := store.List(context.Background(), ListInput{Bucket: "bucket-a", Prefix: "logs/", Delimiter: "/", ContinuationToken: "prev"})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if aws.ToInt32(client.listInput.MaxKeys) != MaxListKeys || aws.ToString(client.listInput.ContinuationToken) != "prev" {
+		t.Fatalf("ListObjectsV2 input = %#v", client.listInput)
+	}
+	want := &ListOutput{
+		Objects:               []ObjectInfo{{Ref: ObjectRef{Bucket: "bucket-a", Key: "logs/a.json"}, Size: 3, ETag: `"a"`}},
+		CommonPrefixes:        []string{"logs/2026/"},
+		NextContinuationToken: "next",
+		IsTruncated:           true,
+	}
+	if !reflect.DeepEqual(page, want) {
+		t.Fatalf("List() = %#v, want %#v", page, want)
+	}
+}
+
+func TestS3StoreCopy(t *testing.T) {
+	client := &recordingS3Client{copyVersionID: "v9"}
+	store, err := newS3StoreWithClient(client, S3StoreConfig{Encryption: S3EncryptionConfig{Mode: S3EncryptionS3Managed}})
+	if err != nil {
+		t.Fatalf("newS3StoreWithClient() error = %v", err)
+	}
+
+	ref, err := store.Copy(context.Background(), CopyInput{
+		Source:          ObjectRef{Bucket: "bucket-a", Key: "dir/a b+c.json", VersionID: "v1"},
+		Destination:     ObjectRef{Bucket: "bucket-b", Key: "copy.json"},
+		SourceIfMatch:   `"etag-1"`,
+		Condition:       WriteCondition{IfNoneMatch: "*"},
+		ReplaceMetadata: true,
+		ContentType:     "application/json",
+		Metadata:        map[string]string{"a": "b"},
+	})
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if ref != (ObjectRef{Bucket: "bucket-b", Key: "copy.json", VersionID: "v9"}) {
+		t.Fatalf("Copy() ref = %#v", ref)
+	}
+	in := client.copyInput
+	if aws.ToString(in.CopySource) != "bucket-a/dir/a%20b+c.json?versionId=v1" {
+		t.Fatalf("CopySource = %q", aws.ToString(in.CopySource))
+	}
+	if aws.ToString(in.CopySourceIfMatch) != `"etag-1"` || aws.ToString(in.IfNoneMatch) != "*" {
+		t.Fatalf("CopyObject conditions = %#v", in)
+	}
+	if in.MetadataDirective != types.MetadataDirectiveReplace || in.Metadata["a"] != "b" || aws.ToString(in.ContentType) != "application/json" {
+		t.Fatalf("CopyObject metadata = %#v", in)
+	}
+	if in.ServerSideEncryption != types.ServerSideEncryptionAes256 {
+		t.Fatalf("CopyObject ServerSideEncryption = %q", in.ServerSideEncryption)
+	}
+}
+
+func TestS3StoreConditionalPutMapsPreconditionFailed(t *testing.T) {
+	client := &recordingS3Client{putErr: s3CodeError{code: "ConditionalRequestConflict"}}
+	store, err := newS3StoreWithClient(client, S3StoreConfig{})
+	if err != nil {
+		t.Fatalf("newS3StoreWithClient() error = %v", err)
+	}
+
+	_, err = store.Put(context.Background(), PutInput{
+		Ref:       ObjectRef{Bucket: "bucket-a", Key: "key"},
+		Condition: WriteCondition{IfMatch: `"etag-1"`},
+	})
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Put() error = %v, want ErrPreconditionFailed", err)
+	}
+	if aws.ToString(client.putInput.IfMatch) != `"etag-1"` {
+		t.Fatalf("PutObject IfMatch = %q", aws.ToString(client.putInput.IfMatch))
+	}
+
+	client.putErr = errors.New("network")
+	if _, err := store.Put(context.Background(), PutInput{Ref: ObjectRef{Bucket: "bucket-a", Key: "key"}}); errors.Is(err, ErrPreconditionFailed) || err == nil {
+		t.Fatalf("Put() unmapped error = %v", err)
+	}
+}
+
+func TestS3StoreStreamingFailClosedValidation(t *testing.T) {
+	client := &recordingS3Client{}
+	store, err := newS3StoreWithClient(client, S3StoreConfig{})
+	if err != nil {
+		t.Fatalf("newS3StoreWithClient() error = %v", err)
+	}
+	ctx := context.Background()
+	ref := ObjectRef{Bucket: "bucket-a", Key: "key"}
+
+	if _, err := store.PutReader(ctx, PutReaderInput{Ref: ref}); !errors.Is(err, ErrInvalidStreamInput) {
+		t.Fatalf("PutReader() nil body error = %v, want ErrInvalidStreamInput", err)
+	}
+	if _, err := store.PutReader(ctx, PutReaderInput{Ref: ref, Body: bytes.NewReader(nil), PartSize: MinMultipartPartSize - 1}); !errors.Is(err, ErrInvalidStreamInput) {
+		t.Fatalf("PutReader() small part error = %v, want ErrInvalidStreamInput", err)
+	}
+	if _, err := store.GetReader(ctx, GetInput{Ref: ref}); !errors.Is(err, ErrInvalidGetLimit) {
+		t.Fatalf("GetReader() missing cap error = %v, want ErrInvalidGetLimit", err)
+	}
+	if _, err := store.List(ctx, ListInput{Bucket: "bucket-a", MaxKeys: MaxListKeys + 1}); !errors.Is(err, ErrInvalidListInput) {
+		t.Fatalf("List() error = %v, want ErrInvalidListInput", err)
+	}
+	if _, err := store.Copy(ctx, CopyInput{Source: ref, Destination: ObjectRef{Bucket: "bucket-a", Key: "dst", VersionID: "v1"}}); !errors.Is(err, ErrInvalidObjectRef) {
+		t.Fatalf("Copy() versioned destination error = %v, want ErrInvalidObjectRef", err)
+	}
+	if _, err := store.Put(ctx, PutInput{Ref: ref, Condition: WriteCondition{IfNoneMatch: `"etag"`}}); !errors.Is(err, ErrInvalidWriteCondition) {
+		t.Fatalf("Put() invalid condition error = %v, want ErrInvalidWriteCondition", err)
+	}
+	if len(client.operations) != 0 {
+		t.Fatalf("invalid requests reached S3 client: %#v", client.operations)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -76,6 +78,21 @@ func TestS3StorePutGetDelete(t *testing.T) {
 	}
 }
 
+func TestS3StorePutETagReturnsWriteETag(t *testing.T) {
+	client := &recordingS3Client{putVersionID: "put-v1", objectETag: `"etag-1"`}
+	store, err := newS3StoreWithClient(client, S3StoreConfig{})
+	if err != nil {
+		t.Fatalf("newS3StoreWithClient() error = %v", err)
+	}
+	ref, etag, err := store.PutETag(context.Background(), PutInput{Ref: ObjectRef{Bucket: "bucket-a", Key: "objects/1.json"}, Payload: []byte("payload")})
+	if err != nil || ref.VersionID != "put-v1" || etag != `"etag-1"` {
+		t.Fatalf("PutETag() = %#v, %q, %v", ref, etag, err)
+	}
+	if !reflect.DeepEqual(client.operations, []string{"PutObject"}) {
+		t.Fatalf("operations = %#v, want the ETag from PutObject alone", client.operations)
+	}
+}
+
 func TestS3StoreGetBoundsAndClosesBody(t *testing.T) {
 	client := &recordingS3Client{getBody: []byte("too-large")}
 	store, err := newS3StoreWithClient(client, S3StoreConfig{})
@@ -162,22 +179,49 @@ func TestNewS3StoreLoadsAWSConfig(t *testing.T) {
 }
 
 type recordingS3Client struct {
-	operations   []string
-	putInput     *s3.PutObjectInput
-	getInput     *s3.GetObjectInput
-	deleteInput  *s3.DeleteObjectInput
-	putVersionID string
-	getVersionID string
-	getBody      []byte
-	contentType  string
-	metadata     map[string]string
-	bodyClosed   bool
+	operations     []string
+	putInput       *s3.PutObjectInput
+	putBody        []byte
+	getInput       *s3.GetObjectInput
+	deleteInput    *s3.DeleteObjectInput
+	headInput      *s3.HeadObjectInput
+	listInput      *s3.ListObjectsV2Input
+	listOutput     *s3.ListObjectsV2Output
+	copyInput      *s3.CopyObjectInput
+	createInput    *s3.CreateMultipartUploadInput
+	partBodies     [][]byte
+	completeInput  *s3.CompleteMultipartUploadInput
+	abortInput     *s3.AbortMultipartUploadInput
+	putVersionID   string
+	getVersionID   string
+	getBody        []byte
+	getLength      *int64
+	contentType    string
+	metadata       map[string]string
+	bodyClosed     bool
+	putErr         error
+	uploadPartErr  error
+	completeErr    error
+	headErr        error
+	copyVersionID  string
+	objectETag     string
+	objectModified time.Time
 }
 
 func (c *recordingS3Client) PutObject(_ context.Context, params *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
 	c.operations = append(c.operations, "PutObject")
 	c.putInput = params
-	return &s3.PutObjectOutput{VersionId: aws.String(c.putVersionID)}, nil
+	if params.Body != nil {
+		body, err := io.ReadAll(params.Body)
+		if err != nil {
+			return nil, err
+		}
+		c.putBody = body
+	}
+	if c.putErr != nil {
+		return nil, c.putErr
+	}
+	return &s3.PutObjectOutput{VersionId: aws.String(c.putVersionID), ETag: optionalString(c.objectETag)}, nil
 }
 
 func (c *recordingS3Client) GetObject(_ context.Context, params *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
@@ -187,6 +231,10 @@ func (c *recordingS3Client) GetObject(_ context.Context, params *s3.GetObjectInp
 		Body:        &trackingReadCloser{Reader: bytes.NewReader(c.getBody), closed: &c.bodyClosed},
 		ContentType: aws.String(c.contentType),
 		Metadata:    cloneMetadata(c.metadata),
+		ETag:        optionalString(c.objectETag),
+	}
+	if c.getLength != nil {
+		output.ContentLength = c.getLength
 	}
 	if c.getVersionID != "" {
 		output.VersionId = aws.String(c.getVersionID)
@@ -200,6 +248,78 @@ func (c *recordingS3Client) DeleteObject(_ context.Context, params *s3.DeleteObj
 	return &s3.DeleteObjectOutput{}, nil
 }
 
+func (c *recordingS3Client) HeadObject(_ context.Context, params *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	c.operations = append(c.operations, "HeadObject")
+	c.headInput = params
+	if c.headErr != nil {
+		return nil, c.headErr
+	}
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(c.getBody))),
+		ContentType:   aws.String(c.contentType),
+		ETag:          optionalString(c.objectETag),
+		LastModified:  aws.Time(c.objectModified),
+		Metadata:      cloneMetadata(c.metadata),
+		VersionId:     optionalString(c.getVersionID),
+	}, nil
+}
+
+func (c *recordingS3Client) ListObjectsV2(_ context.Context, params *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.operations = append(c.operations, "ListObjectsV2")
+	c.listInput = params
+	if c.listOutput != nil {
+		return c.listOutput, nil
+	}
+	return &s3.ListObjectsV2Output{}, nil
+}
+
+func (c *recordingS3Client) CopyObject(_ context.Context, params *s3.CopyObjectInput, _ ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	c.operations = append(c.operations, "CopyObject")
+	c.copyInput = params
+	return &s3.CopyObjectOutput{VersionId: optionalString(c.copyVersionID)}, nil
+}
+
+func (c *recordingS3Client) CreateMultipartUpload(_ context.Context, params *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	c.operations = append(c.operations, "CreateMultipartUpload")
+	c.createInput = params
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil
+}
+
+func (c *recordingS3Client) UploadPart(_ context.Context, params *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	c.operations = append(c.operations, "UploadPart")
+	if c.uploadPartErr != nil {
+		return nil, c.uploadPartErr
+	}
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.partBodies = append(c.partBodies, body)
+	return &s3.UploadPartOutput{ETag: aws.String(fmt.Sprintf(`"part-%d"`, aws.ToInt32(params.PartNumber)))}, nil
+}
+
+func (c *recordingS3Client) CompleteMultipartUpload(_ context.Context, params *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	c.operations = append(c.operations, "CompleteMultipartUpload")
+	c.completeInput = params
+	if c.completeErr != nil {
+		return nil, c.completeErr
+	}
+	return &s3.CompleteMultipartUploadOutput{VersionId: aws.String(c.putVersionID)}, nil
+}
+
+func (c *recordingS3Client) AbortMultipartUpload(_ context.Context, params *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	c.operations = append(c.operations, "AbortMultipartUpload")
+	c.abortInput = params
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+type s3CodeError struct {
+	code string
+}
+
+func (e s3CodeError) Error() string     { return "s3: " + e.code }
+func (e s3CodeError) ErrorCode() string { return e.code }
+
 type trackingReadCloser struct {
 	io.Reader
 	closed *bool
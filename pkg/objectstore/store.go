@@ -2,7 +2,12 @@ package objectstore
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"io"
+	"strings"
+	"time"
 )
 
 // Stable object-store operation errors.
@@ -11,19 +16,67 @@ var (
 	ErrInvalidGetLimit = errors.New("objectstore: max bytes must be positive")
 	// ErrObjectTooLarge is returned when a bounded Get would exceed its byte cap.
 	ErrObjectTooLarge = errors.New("objectstore: object exceeds max bytes")
-	// ErrObjectNotFound is returned by deterministic stores when the requested object is absent.
+	// ErrObjectNotFound is returned when the requested object is absent.
 	ErrObjectNotFound = errors.New("objectstore: object not found")
+	// ErrPreconditionFailed is returned when a conditional write or copy does not match the current object.
+	ErrPreconditionFailed = errors.New("objectstore: precondition failed")
+	// ErrInvalidWriteCondition is returned when a WriteCondition is contradictory or unsupported.
+	ErrInvalidWriteCondition = errors.New("objectstore: invalid write condition")
+	// ErrInvalidListInput is returned when a List request is incomplete or out of range.
+	ErrInvalidListInput = errors.New("objectstore: invalid list input")
+	// ErrInvalidStreamInput is returned when a streaming write has no body or an invalid part size.
+	ErrInvalidStreamInput = errors.New("objectstore: invalid stream input")
 )
 
-// Store is AppTheory's narrow object-store contract.
+// Object-store limits shared by every Store implementation.
+const (
+	// MaxListKeys is the largest page a List call returns; zero MaxKeys selects it.
+	MaxListKeys = 1000
+	// DefaultMultipartPartSize is the part size PutReader buffers when PartSize is zero.
+	DefaultMultipartPartSize int64 = 8 << 20
+	// MinMultipartPartSize is the smallest part size S3 accepts for non-final parts.
+	MinMultipartPartSize int64 = 5 << 20
+	// MaxMultipartParts is the largest number of parts one multipart upload may contain.
+	MaxMultipartParts = 10000
+)
+
+// Store is AppTheory's object-store contract.
 //
-// It intentionally supports only byte Put, bounded Get, and Delete. There is no
-// unbounded read method and no listing, presigning, public URL, multipart, copy,
-// head, or raw client escape hatch.
+// Byte reads are always bounded: Get and GetReader require a positive MaxBytes
+// cap. Large writes stream through PutReader, which uses multipart upload when
+// the body exceeds one part. Presigned URLs are a separate Presigner capability
+// of the S3 store; there is no public URL or raw client escape hatch.
 type Store interface {
 	Put(context.Context, PutInput) (ObjectRef, error)
 	Get(context.Context, GetInput) (*GetOutput, error)
 	Delete(context.Context, DeleteInput) error
+	PutReader(context.Context, PutReaderInput) (ObjectRef, error)
+	GetReader(context.Context, GetInput) (*GetReaderOutput, error)
+	Head(context.Context, HeadInput) (*ObjectInfo, error)
+	List(context.Context, ListInput) (*ListOutput, error)
+	Copy(context.Context, CopyInput) (ObjectRef, error)
+}
+
+// ETagPutter is implemented by stores whose Put can report the ETag of the
+// object it wrote. Reading the ETag back with Head instead races with other
+// writers, so compare-and-swap callers should prefer PutETag.
+//
+// The stores returned by NewS3Store, NewMemoryStore, and NewFilesystemStore
+// implement ETagPutter; detect the capability with a type assertion.
+type ETagPutter interface {
+	PutETag(context.Context, PutInput) (ObjectRef, string, error)
+}
+
+// WriteCondition guards a write on the destination object's current state.
+//
+// IfNoneMatch accepts only "*" and writes only when the object does not exist.
+// IfMatch writes only when the current object's ETag equals the given value.
+// At most one of the two may be set; the zero value is an unconditional write.
+// The S3 store enforces conditions server-side; the local stores enforce them
+// only among writers sharing one Store value.
+type WriteCondition struct {
+	IfNoneMatch string
+	IfMatch     string
 }
 
 // PutInput writes a byte payload to one object reference.
@@ -32,6 +85,21 @@ type PutInput struct {
 	Payload     []byte
 	ContentType string
 	Metadata    map[string]string
+	Condition   WriteCondition
+}
+
+// PutReaderInput streams a body of unknown length to one object reference.
+//
+// PartSize bounds how much of Body is buffered at a time; zero selects
+// DefaultMultipartPartSize and non-zero values must be at least
+// MinMultipartPartSize.
+type PutReaderInput struct {
+	Ref         ObjectRef
+	Body        io.Reader
+	ContentType string
+	Metadata    map[string]string
+	Condition   WriteCondition
+	PartSize    int64
 }
 
 // GetInput reads one object reference with a required maximum byte cap.
@@ -46,6 +114,70 @@ type GetOutput struct {
 	Payload     []byte
 	ContentType string
 	Metadata    map[string]string
+	ETag        string
+}
+
+// GetReaderOutput is the streaming body returned by Store.GetReader.
+//
+// Body fails with ErrObjectTooLarge once more than the requested MaxBytes have
+// been read. Callers must close Body.
+type GetReaderOutput struct {
+	Info ObjectInfo
+	Body io.ReadCloser
+}
+
+// HeadInput reads the attributes of one object reference without its payload.
+type HeadInput struct {
+	Ref ObjectRef
+}
+
+// ObjectInfo describes one stored object.
+//
+// List results carry only Ref, Size, ETag, and LastModified; ContentType and
+// Metadata are populated by Head and GetReader.
+type ObjectInfo struct {
+	Ref          ObjectRef
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	ContentType  string
+	Metadata     map[string]string
+}
+
+// ListInput pages through the keys of one bucket.
+//
+// Delimiter groups keys that share a prefix up to the next delimiter into
+// CommonPrefixes. MaxKeys of zero selects MaxListKeys. ContinuationToken is the
+// opaque NextContinuationToken from a previous page.
+type ListInput struct {
+	Bucket            string
+	Prefix            string
+	Delimiter         string
+	MaxKeys           int
+	ContinuationToken string
+}
+
+// ListOutput is one page of List results in ascending key order.
+type ListOutput struct {
+	Objects               []ObjectInfo
+	CommonPrefixes        []string
+	NextContinuationToken string
+	IsTruncated           bool
+}
+
+// CopyInput copies one object to another reference in the same store.
+//
+// SourceIfMatch requires the source ETag to match. Condition guards the
+// destination like a Put. Metadata and ContentType are copied from the source
+// unless ReplaceMetadata is set, in which case the given values replace them.
+type CopyInput struct {
+	Source          ObjectRef
+	Destination     ObjectRef
+	SourceIfMatch   string
+	Condition       WriteCondition
+	ReplaceMetadata bool
+	ContentType     string
+	Metadata        map[string]string
 }
 
 // DeleteInput removes one object reference. Ref.VersionID is honored by stores
@@ -55,15 +187,61 @@ type DeleteInput struct {
 }
 
 func validatePutInput(input PutInput) error {
-	if err := input.Ref.Validate(); err != nil {
+	if err := validateWriteRef(input.Ref); err != nil {
+		return err
+	}
+	return input.Condition.validate()
+}
+
+func validatePutReaderInput(input PutReaderInput) error {
+	if err := validateWriteRef(input.Ref); err != nil {
+		return err
+	}
+	if input.Body == nil {
+		return ErrInvalidStreamInput
+	}
+	if input.PartSize != 0 && input.PartSize < MinMultipartPartSize {
+		return ErrInvalidStreamInput
+	}
+	return input.Condition.validate()
+}
+
+func validateWriteRef(ref ObjectRef) error {
+	if err := ref.Validate(); err != nil {
 		return err
 	}
-	if input.Ref.VersionID != "" {
+	if ref.VersionID != "" {
 		return ErrInvalidObjectRef
 	}
 	return nil
 }
 
+func (c WriteCondition) validate() error {
+	if c.IfNoneMatch != "" && c.IfMatch != "" {
+		return ErrInvalidWriteCondition
+	}
+	if c.IfNoneMatch != "" && c.IfNoneMatch != "*" {
+		return ErrInvalidWriteCondition
+	}
+	if c.IfMatch != "" && (c.IfMatch != strings.TrimSpace(c.IfMatch) || containsControl(c.IfMatch)) {
+		return ErrInvalidWriteCondition
+	}
+	return nil
+}
+
+// check reports whether a write may proceed given the destination's current
+// ETag; exists is false when the destination is absent.
+func (c WriteCondition) check(exists bool, currentETag string) error {
+	switch {
+	case c.IfNoneMatch == "*" && exists:
+		return ErrPreconditionFailed
+	case c.IfMatch != "" && (!exists || currentETag != c.IfMatch):
+		return ErrPreconditionFailed
+	default:
+		return nil
+	}
+}
+
 func validateGetInput(input GetInput) error {
 	if err := input.Ref.Validate(); err != nil {
 		return err
@@ -78,6 +256,82 @@ func validateDeleteInput(input DeleteInput) error {
 	return input.Ref.Validate()
 }
 
+func validateHeadInput(input HeadInput) error {
+	return input.Ref.Validate()
+}
+
+func validateListInput(input ListInput) (ListInput, error) {
+	if err := (ObjectRef{Bucket: input.Bucket, Key: "-"}).Validate(); err != nil {
+		return ListInput{}, ErrInvalidListInput
+	}
+	if input.MaxKeys < 0 || input.MaxKeys > MaxListKeys {
+		return ListInput{}, ErrInvalidListInput
+	}
+	if containsControl(input.Prefix) || containsControl(input.Delimiter) || containsControl(input.ContinuationToken) {
+		return ListInput{}, ErrInvalidListInput
+	}
+	if input.MaxKeys == 0 {
+		input.MaxKeys = MaxListKeys
+	}
+	return input, nil
+}
+
+func validateCopyInput(input CopyInput) error {
+	if err := input.Source.Validate(); err != nil {
+		return err
+	}
+	if err := validateWriteRef(input.Destination); err != nil {
+		return err
+	}
+	if input.SourceIfMatch != "" {
+		if err := (WriteCondition{IfMatch: input.SourceIfMatch}).validate(); err != nil {
+			return err
+		}
+	}
+	return input.Condition.validate()
+}
+
+// contentETag returns the quoted ETag that non-S3 stores assign to a payload
+// digest. Callers must treat ETags as opaque.
+func contentETag(sum []byte) string {
+	return `"` + hex.EncodeToString(sum) + `"`
+}
+
+func payloadETag(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return contentETag(sum[:])
+}
+
+// boundedReadCloser fails with ErrObjectTooLarge once more than remaining
+// bytes have been read from the wrapped body.
+type boundedReadCloser struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func newBoundedReadCloser(body io.ReadCloser, maxBytes int64) *boundedReadCloser {
+	return &boundedReadCloser{body: body, remaining: maxBytes}
+}
+
+func (r *boundedReadCloser) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, ErrObjectTooLarge
+	}
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.body.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n + int(r.remaining), ErrObjectTooLarge
+	}
+	return n, err
+}
+
+func (r *boundedReadCloser) Close() error {
+	return r.body.Close()
+}
+
 func cloneBytes(in []byte) []byte {
 	if in == nil {
 		return nil
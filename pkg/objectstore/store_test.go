@@ -13,6 +13,13 @@ type contractStore struct{}
 func (*contractStore) Put(context.Context, PutInput) (ObjectRef, error)  { return ObjectRef{}, nil }
 func (*contractStore) Get(context.Context, GetInput) (*GetOutput, error) { return nil, nil }
 func (*contractStore) Delete(context.Context, DeleteInput) error         { return nil }
+func (*contractStore) PutReader(context.Context, PutReaderInput) (ObjectRef, error) {
+	return ObjectRef{}, nil
+}
+func (*contractStore) GetReader(context.Context, GetInput) (*GetReaderOutput, error) { return nil, nil }
+func (*contractStore) Head(context.Context, HeadInput) (*ObjectInfo, error)          { return nil, nil }
+func (*contractStore) List(context.Context, ListInput) (*ListOutput, error)          { return nil, nil }
+func (*contractStore) Copy(context.Context, CopyInput) (ObjectRef, error)            { return ObjectRef{}, nil }
 
 func TestValidateStoreInputs(t *testing.T) {
 	ref := ObjectRef{Bucket: "bucket-a", Key: "key", VersionID: "version-1"}
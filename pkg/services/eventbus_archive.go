@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/objectstore"
+)
+
+// ArchiveContentType is the content type of archive objects.
+const ArchiveContentType = "application/x-ndjson"
+
+// ArchiveConfig configures NewArchive.
+//
+// Of the events EventJobConfig selects, those whose ExpiresAt falls within
+// ExpiringWithin of the Run's start are written to Store as JSON Lines, one
+// object per page, under "<Prefix>/<tenant>/<JobID>/<page>.jsonl" in Bucket.
+// Set Query.EndTime to skip events too recent to expire soon. Page keys are
+// stable, so re-running a page overwrites its object instead of duplicating
+// it. Run the archive on a schedule shorter than ExpiringWithin so events are
+// written before DynamoDB TTL deletes them.
+type ArchiveConfig struct {
+	EventJobConfig
+	Store          objectstore.Store
+	Bucket         string
+	Prefix         string
+	ExpiringWithin time.Duration
+}
+
+// Archive copies events that are about to expire to an object store before
+// DynamoDB TTL deletes them. It pages and checkpoints like Replay.
+type Archive struct {
+	job    *eventJob
+	cfg    ArchiveConfig
+	cutoff time.Time
+}
+
+// NewArchive validates cfg and fills in defaults.
+func NewArchive(cfg ArchiveConfig) (*Archive, error) {
+	if cfg.Store == nil || strings.TrimSpace(cfg.Bucket) == "" {
+		return nil, fmt.Errorf("archive requires a store and a bucket")
+	}
+	if cfg.ExpiringWithin <= 0 {
+		return nil, fmt.Errorf("archive expiring window must be positive")
+	}
+	cfg.Prefix = strings.Trim(strings.TrimSpace(cfg.Prefix), "/")
+	job, err := newEventJob("eventbus-archive", cfg.EventJobConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &Archive{job: job, cfg: cfg}, nil
+}
+
+// Run archives pages until the selection is exhausted, MaxPages pages have
+// been processed, or ctx is within DeadlineMargin of its deadline. It returns
+// ErrEventJobLeaseHeld when another Run holds the lease.
+func (a *Archive) Run(ctx context.Context, input EventJobInput) (EventJobReport, error) {
+	if a == nil || a.job == nil {
+		return EventJobReport{}, fmt.Errorf("archive is not configured")
+	}
+	a.cutoff = time.Now().Add(a.cfg.ExpiringWithin)
+	return a.job.run(ensureContext(ctx), input, a.page)
+}
+
+func (a *Archive) page(ctx context.Context, page int, events []*Event) (eventPageResult, error) {
+	var (
+		result eventPageResult
+		body   bytes.Buffer
+	)
+	encoder := json.NewEncoder(&body)
+	for _, event := range events {
+		if event == nil || event.ExpiresAt.IsZero() || !event.ExpiresAt.Before(a.cutoff) {
+			continue
+		}
+		if err := encoder.Encode(event); err != nil {
+			return result, fmt.Errorf("failed to encode event %s: %w", event.ID, err)
+		}
+		result.events++
+	}
+	if result.events == 0 {
+		return result, nil
+	}
+
+	_, err := a.cfg.Store.Put(ctx, objectstore.PutInput{
+		Ref:         a.Ref(page),
+		Payload:     body.Bytes(),
+		ContentType: ArchiveContentType,
+		Metadata: map[string]string{
+			"job_id":    a.cfg.JobID,
+			"tenant_id": a.cfg.Query.TenantID,
+			"events":    strconv.Itoa(result.events),
+		},
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to write event archive: %w", err)
+	}
+	result.objects++
+	return result, nil
+}
+
+// Ref returns the object an archive page is written to.
+func (a *Archive) Ref(page int) objectstore.ObjectRef {
+	return objectstore.ObjectRef{
+		Bucket: a.cfg.Bucket,
+		Key:    path.Join(a.cfg.Prefix, strings.TrimSpace(a.cfg.Query.TenantID), a.cfg.JobID, fmt.Sprintf("%08d.jsonl", page)),
+	}
+}
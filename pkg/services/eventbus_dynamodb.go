@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/oklog/ulid/v2"
@@ -27,6 +28,7 @@ type DynamoDBEventBus struct {
 	handlers map[string][]EventHandler
 	db       tablecore.DB
 	config   EventBusConfig
+	mu       sync.RWMutex
 }
 
 var _ EventBus = (*DynamoDBEventBus)(nil)
@@ -137,7 +139,9 @@ func (d *DynamoDBEventBus) Subscribe(_ context.Context, eventType string, handle
 		return fmt.Errorf("handler cannot be nil")
 	}
 
+	d.mu.Lock()
 	d.handlers[eventType] = append(d.handlers[eventType], handler)
+	d.mu.Unlock()
 	return nil
 }
 
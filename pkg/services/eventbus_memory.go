@@ -84,6 +84,24 @@ func (m *MemoryEventBus) Publish(ctx context.Context, event *Event) (string, err
 	return event.ID, nil
 }
 
+// deliverEvent runs the handlers subscribed to the event's type, as Publish
+// does, so a Replay can re-deliver stored events.
+func (m *MemoryEventBus) deliverEvent(ctx context.Context, event *Event) error {
+	m.mu.RLock()
+	handlers := append([]EventHandler(nil), m.handlers[event.EventType]...)
+	m.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if handler == nil {
+			continue
+		}
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *MemoryEventBus) Query(_ context.Context, query *EventQuery) ([]*Event, error) {
 	if query == nil {
 		return nil, fmt.Errorf("query cannot be nil")
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theory-cloud/apptheory/v3/internal/retry"
+	"github.com/theory-cloud/apptheory/v3/pkg/jobs"
+)
+
+const (
+	DefaultEventJobPageSize       = 100
+	DefaultEventJobLeaseDuration  = 5 * time.Minute
+	DefaultEventJobDeadlineMargin = 30 * time.Second
+	DefaultEventJobCheckpointTTL  = 30 * 24 * time.Hour
+
+	// ReplayMetadataJobID and ReplayMetadataReplayedAt are the Event.Metadata
+	// keys a replay sets on every event it re-delivers, so handlers can tell
+	// a replay from the original delivery.
+	ReplayMetadataJobID      = "replay_job_id"
+	ReplayMetadataReplayedAt = "replayed_at"
+)
+
+// ErrEventJobLeaseHeld is returned when another owner is running the same
+// replay or archive job.
+var ErrEventJobLeaseHeld = errors.New("event bus job lease is held by another owner")
+
+// EventJobReport describes one Run of a Replay or Archive. Pages and Events
+// count only the work this Run did; pages an earlier Run had already
+// checkpointed are counted in Replayed. Failed counts events a replay could
+// not deliver and Objects the JSONL objects an archive wrote. NextPage is
+// where the next Run should start.
+type EventJobReport struct {
+	Pages    int
+	Events   int
+	Failed   int
+	Objects  int
+	Replayed int
+	NextPage int
+	Done     bool
+}
+
+// EventJobConfig holds the settings Replay and Archive share.
+//
+// Query selects the events: TenantID is required, and EventType, Tags,
+// StartTime, and EndTime narrow the selection as they do for EventBus.Query.
+// EventTypes walks several types one after another and overrides
+// Query.EventType.
+//
+// Ledger, JobID, TenantID, and Owner identify the job in pkg/jobs and the
+// lease holder. CheckpointTTL bounds how long page checkpoints survive and
+// must outlast the whole job.
+type EventJobConfig struct {
+	Bus            EventBus
+	Query          EventQuery
+	EventTypes     []string
+	Ledger         jobs.JobLedger
+	JobID          string
+	TenantID       string
+	Owner          string
+	PageSize       int
+	MaxPages       int
+	LeaseDuration  time.Duration
+	DeadlineMargin time.Duration
+	CheckpointTTL  time.Duration
+}
+
+// ReplayConfig configures NewReplay.
+//
+// Handler receives every selected event. When it is nil, events go to the
+// handlers subscribed on Bus, which must be a DynamoDBEventBus or a
+// MemoryEventBus. RatePerSecond caps deliveries; zero means no cap.
+type ReplayConfig struct {
+	EventJobConfig
+	Handler       EventHandler
+	RatePerSecond float64
+}
+
+// EventJobInput starts a Run. StartPage is passed to jobs.RunPaged as its
+// startPage, typically the previous report's NextPage.
+type EventJobInput struct {
+	StartPage int
+}
+
+// Replay re-delivers stored events, one EventBus.Query page at a time, in the
+// order Query returns them. Each delivered event is a copy tagged with
+// ReplayMetadataJobID and ReplayMetadataReplayedAt.
+//
+// Pages are checkpointed with jobs.RunPaged, whose resume rules apply, so
+// handlers must be idempotent. An event whose delivery fails is recorded as a
+// FAILED job record keyed by event ID and the replay moves on.
+type Replay struct {
+	job     *eventJob
+	handler EventHandler
+	jobID   string
+	rate    float64
+	last    time.Time
+}
+
+type eventDeliverer interface {
+	deliverEvent(ctx context.Context, event *Event) error
+}
+
+// NewReplay validates cfg and fills in defaults.
+func NewReplay(cfg ReplayConfig) (*Replay, error) {
+	handler := cfg.Handler
+	if handler == nil {
+		deliverer, ok := cfg.Bus.(eventDeliverer)
+		if !ok {
+			return nil, fmt.Errorf("replay requires a handler or a bus that delivers to subscribed handlers")
+		}
+		handler = deliverer.deliverEvent
+	}
+	if cfg.RatePerSecond < 0 {
+		return nil, fmt.Errorf("replay rate cannot be negative")
+	}
+	job, err := newEventJob("eventbus-replay", cfg.EventJobConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &Replay{job: job, handler: handler, jobID: job.cfg.JobID, rate: cfg.RatePerSecond}, nil
+}
+
+// Run replays pages until the selection is exhausted, MaxPages pages have
+// been processed, or ctx is within DeadlineMargin of its deadline. It returns
+// ErrEventJobLeaseHeld when another Run holds the lease.
+func (r *Replay) Run(ctx context.Context, input EventJobInput) (EventJobReport, error) {
+	if r == nil || r.job == nil {
+		return EventJobReport{}, fmt.Errorf("replay is not configured")
+	}
+	return r.job.run(ensureContext(ctx), input, r.page)
+}
+
+func (r *Replay) page(ctx context.Context, _ int, events []*Event) (eventPageResult, error) {
+	var result eventPageResult
+	for _, event := range events {
+		if err := r.wait(ctx); err != nil {
+			return result, err
+		}
+		replayed := *event
+		replayed.Metadata = make(map[string]string, len(event.Metadata)+2)
+		for key, value := range event.Metadata {
+			replayed.Metadata[key] = value
+		}
+		replayed.Metadata[ReplayMetadataJobID] = r.jobID
+		replayed.Metadata[ReplayMetadataReplayedAt] = time.Now().UTC().Format(time.RFC3339Nano)
+
+		result.events++
+		if err := r.handler(ctx, &replayed); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return result, ctxErr
+			}
+			result.failed++
+			if err := r.job.recordFailure(ctx, event, err); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// wait spaces deliveries RatePerSecond apart.
+func (r *Replay) wait(ctx context.Context) error {
+	if r.rate == 0 {
+		return ctx.Err()
+	}
+	if !r.last.IsZero() {
+		interval := time.Duration(float64(time.Second) / r.rate)
+		if err := retry.Sleep(ctx, interval-time.Since(r.last)); err != nil {
+			return err
+		}
+	}
+	r.last = time.Now()
+	return nil
+}
+
+// eventJob walks EventBus.Query pages for Replay and Archive with
+// jobs.RunPaged. A page's checkpoint holds the index of the event type being
+// walked and the Query cursor within it.
+type eventJob struct {
+	name string
+	cfg  EventJobConfig
+}
+
+type eventPageResult struct {
+	events  int
+	failed  int
+	objects int
+}
+
+func newEventJob(name string, cfg EventJobConfig) (*eventJob, error) {
+	if err := cfg.validate(name); err != nil {
+		return nil, err
+	}
+	if cfg.PageSize == 0 {
+		cfg.PageSize = DefaultEventJobPageSize
+	}
+	if cfg.LeaseDuration == 0 {
+		cfg.LeaseDuration = DefaultEventJobLeaseDuration
+	}
+	if cfg.DeadlineMargin == 0 {
+		cfg.DeadlineMargin = DefaultEventJobDeadlineMargin
+	}
+	if cfg.CheckpointTTL == 0 {
+		cfg.CheckpointTTL = DefaultEventJobCheckpointTTL
+	}
+	cfg.EventTypes = normalizeEventTypes(cfg.EventTypes, cfg.Query.EventType)
+	cfg.Query.LastEvaluatedKey, cfg.Query.NextKey = nil, nil
+	cfg.Query.Limit = cfg.PageSize
+	return &eventJob{name: name, cfg: cfg}, nil
+}
+
+func (cfg EventJobConfig) validate(name string) error {
+	if cfg.Bus == nil || cfg.Ledger == nil {
+		return fmt.Errorf("%s requires a bus and a ledger", name)
+	}
+	if strings.TrimSpace(cfg.Query.TenantID) == "" {
+		return fmt.Errorf("%s query requires a tenant_id", name)
+	}
+	if strings.TrimSpace(cfg.JobID) == "" || strings.TrimSpace(cfg.TenantID) == "" || strings.TrimSpace(cfg.Owner) == "" {
+		return fmt.Errorf("%s requires a job ID, tenant ID, and owner", name)
+	}
+	if cfg.PageSize < 0 || cfg.PageSize > 1000 || cfg.MaxPages < 0 || cfg.LeaseDuration < 0 || cfg.DeadlineMargin < 0 || cfg.CheckpointTTL < 0 {
+		return fmt.Errorf("%s limits are out of range", name)
+	}
+	return nil
+}
+
+// normalizeEventTypes returns the event types to walk in order; a job without
+// EventTypes walks the query's own EventType, which may be empty.
+func normalizeEventTypes(eventTypes []string, fallback string) []string {
+	out := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			out = append(out, eventType)
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, strings.TrimSpace(fallback))
+	}
+	return out
+}
+
+func (j *eventJob) run(
+	ctx context.Context,
+	input EventJobInput,
+	process func(ctx context.Context, page int, events []*Event) (eventPageResult, error),
+) (EventJobReport, error) {
+	var report EventJobReport
+	paged, err := jobs.RunPaged(ctx, jobs.PagedJobConfig{
+		Ledger:         j.cfg.Ledger,
+		Name:           j.name,
+		JobID:          j.cfg.JobID,
+		TenantID:       j.cfg.TenantID,
+		Owner:          j.cfg.Owner,
+		MaxPages:       j.cfg.MaxPages,
+		LeaseDuration:  j.cfg.LeaseDuration,
+		DeadlineMargin: j.cfg.DeadlineMargin,
+		CheckpointTTL:  j.cfg.CheckpointTTL,
+	}, input.StartPage, func(ctx context.Context, page int, at jobs.PageCheckpoint) (jobs.PageCheckpoint, error) {
+		next, result, err := j.page(ctx, page, at, process)
+		if err == nil {
+			report.Events += result.events
+			report.Failed += result.failed
+			report.Objects += result.objects
+		}
+		return next, err
+	})
+	report.Pages, report.Replayed, report.NextPage, report.Done = paged.Pages, paged.Replayed, paged.NextPage, paged.Done
+	if errors.Is(err, jobs.ErrLeaseHeld) {
+		return report, fmt.Errorf("%w: %s", ErrEventJobLeaseHeld, j.cfg.JobID)
+	}
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", j.name, err)
+	}
+	return report, nil
+}
+
+// page queries and processes the events after the checkpoint at.
+func (j *eventJob) page(
+	ctx context.Context,
+	page int,
+	at jobs.PageCheckpoint,
+	process func(ctx context.Context, page int, events []*Event) (eventPageResult, error),
+) (jobs.PageCheckpoint, eventPageResult, error) {
+	// Page zero has no checkpoint and starts at the first event type.
+	typeIndex, _ := strconv.Atoi(at.State["type_index"])
+	if typeIndex >= len(j.cfg.EventTypes) {
+		return jobs.PageCheckpoint{}, eventPageResult{}, fmt.Errorf("%s checkpoint does not match the configured event types", j.name)
+	}
+
+	query := j.cfg.Query
+	query.EventType = j.cfg.EventTypes[typeIndex]
+	if cursor := at.State["cursor"]; cursor != "" {
+		query.LastEvaluatedKey = map[string]any{"cursor": cursor}
+	}
+	events, err := j.cfg.Bus.Query(ctx, &query)
+	if err != nil {
+		return jobs.PageCheckpoint{}, eventPageResult{}, err
+	}
+	result, err := process(ctx, page, events)
+	if err != nil {
+		return jobs.PageCheckpoint{}, eventPageResult{}, err
+	}
+
+	cursor, _ := query.NextKey["cursor"].(string)
+	if cursor == "" {
+		typeIndex++
+	}
+	return jobs.PageCheckpoint{
+		State: map[string]string{
+			"type_index": strconv.Itoa(typeIndex),
+			"cursor":     cursor,
+			"events":     strconv.Itoa(result.events),
+			"failed":     strconv.Itoa(result.failed),
+		},
+		Done: typeIndex >= len(j.cfg.EventTypes),
+	}, result, nil
+}
+
+func (j *eventJob) recordFailure(ctx context.Context, event *Event, cause error) error {
+	_, err := j.cfg.Ledger.UpsertRecordStatus(ctx, jobs.UpsertRecordStatusInput{
+		JobID:    j.cfg.JobID,
+		RecordID: event.ID,
+		Status:   jobs.RecordStatusFailed,
+		Error: jobs.NewErrorEnvelope(cause.Error(), map[string]any{
+			"event_type": event.EventType,
+			"tenant_id":  event.TenantID,
+		}),
+		TTL: j.cfg.CheckpointTTL,
+	})
+	return err
+}
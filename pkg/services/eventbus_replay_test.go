@@ -0,0 +1,216 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/jobs"
+	"github.com/theory-cloud/apptheory/v3/pkg/objectstore"
+	testkitjobs "github.com/theory-cloud/apptheory/v3/testkit/jobs"
+)
+
+func publishReplayFixture(t *testing.T, bus *MemoryEventBus, expiresAt map[string]time.Time) {
+	t.Helper()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, eventType := range []string{"a", "a", "a", "b", "b", "c"} {
+		evt, err := NewEvent(eventType, "t1", "src", map[string]int{"n": i})
+		require.NoError(t, err)
+		evt.ID = fmt.Sprintf("e%d", i)
+		evt.PublishedAt = base.Add(time.Duration(i) * time.Minute)
+		evt.ExpiresAt = expiresAt[evt.ID]
+		_, err = bus.Publish(context.Background(), evt)
+		require.NoError(t, err)
+	}
+}
+
+func TestReplay_ResumesAcrossRunsAndTagsEvents(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bus := NewMemoryEventBus()
+	publishReplayFixture(t, bus, nil)
+
+	var delivered []*Event
+	require.NoError(t, bus.Subscribe(ctx, "a", func(_ context.Context, evt *Event) error {
+		if evt.ID == "e1" {
+			return errors.New("handler failed")
+		}
+		delivered = append(delivered, evt)
+		return nil
+	}))
+	require.NoError(t, bus.Subscribe(ctx, "b", func(_ context.Context, evt *Event) error {
+		delivered = append(delivered, evt)
+		return nil
+	}))
+
+	ledger := testkitjobs.NewLedger()
+	cfg := ReplayConfig{
+		EventJobConfig: EventJobConfig{
+			Bus:        bus,
+			Query:      EventQuery{TenantID: "t1"},
+			EventTypes: []string{"a", "b"},
+			Ledger:     ledger,
+			JobID:      "replay-1",
+			TenantID:   "t1",
+			Owner:      "worker-a",
+			PageSize:   2,
+			MaxPages:   2,
+		},
+		RatePerSecond: 1000,
+	}
+	replay, err := NewReplay(cfg)
+	require.NoError(t, err)
+
+	first, err := replay.Run(ctx, EventJobInput{})
+	require.NoError(t, err)
+	require.Equal(t, EventJobReport{Pages: 2, Events: 3, Failed: 1, NextPage: 2}, first)
+	require.Equal(t, jobs.RecordStatusFailed, ledger.RecordStatus("replay-1", "e1"))
+
+	// A fresh Run from the start skips the checkpointed pages.
+	second, err := replay.Run(ctx, EventJobInput{})
+	require.NoError(t, err)
+	require.Equal(t, EventJobReport{Pages: 1, Events: 2, Replayed: 2, NextPage: 3, Done: true}, second)
+
+	third, err := replay.Run(ctx, EventJobInput{StartPage: second.NextPage})
+	require.NoError(t, err)
+	require.Equal(t, EventJobReport{NextPage: 3, Done: true}, third)
+	replayJob, ok := ledger.Job("replay-1")
+	require.True(t, ok)
+	require.Equal(t, jobs.JobStatusSucceeded, replayJob.Status)
+
+	ids := make([]string, 0, len(delivered))
+	for _, evt := range delivered {
+		ids = append(ids, evt.ID)
+		require.Equal(t, "replay-1", evt.Metadata[ReplayMetadataJobID])
+		require.NotEmpty(t, evt.Metadata[ReplayMetadataReplayedAt])
+	}
+	require.Equal(t, []string{"e2", "e0", "e4", "e3"}, ids)
+
+	stored, err := bus.GetEvent(ctx, "e2")
+	require.NoError(t, err)
+	require.NotContains(t, stored.Metadata, ReplayMetadataJobID)
+}
+
+func TestReplay_TargetHandlerLeaseAndValidation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bus := NewMemoryEventBus()
+	publishReplayFixture(t, bus, nil)
+	ledger := testkitjobs.NewLedger()
+
+	var got []string
+	replay, err := NewReplay(ReplayConfig{
+		EventJobConfig: EventJobConfig{
+			Bus:      bus,
+			Query:    EventQuery{TenantID: "t1", EventType: "c"},
+			Ledger:   ledger,
+			JobID:    "replay-c",
+			TenantID: "t1",
+			Owner:    "worker-a",
+		},
+		Handler: func(_ context.Context, evt *Event) error { got = append(got, evt.ID); return nil },
+	})
+	require.NoError(t, err)
+	report, err := replay.Run(ctx, EventJobInput{})
+	require.NoError(t, err)
+	require.True(t, report.Done)
+	require.Equal(t, []string{"e5"}, got)
+
+	_, err = replay.Run(ctx, EventJobInput{StartPage: 5})
+	require.Error(t, err)
+
+	ledger.SetLeaseOwner("replay-held", "worker-b")
+	held, err := NewReplay(ReplayConfig{
+		EventJobConfig: EventJobConfig{Bus: bus, Query: EventQuery{TenantID: "t1"}, Ledger: ledger, JobID: "replay-held", TenantID: "t1", Owner: "worker-a"},
+		Handler:        replay.handler,
+	})
+	require.NoError(t, err)
+	_, err = held.Run(ctx, EventJobInput{})
+	require.ErrorIs(t, err, ErrEventJobLeaseHeld)
+
+	valid := ReplayConfig{EventJobConfig: EventJobConfig{Bus: bus, Query: EventQuery{TenantID: "t1"}, Ledger: ledger, JobID: "j", TenantID: "t1", Owner: "o"}}
+	for name, mutate := range map[string]func(*ReplayConfig){
+		"no deliverer":  func(c *ReplayConfig) { c.Bus = struct{ EventBus }{} },
+		"no ledger":     func(c *ReplayConfig) { c.Ledger = nil },
+		"no tenant":     func(c *ReplayConfig) { c.Query.TenantID = " " },
+		"no owner":      func(c *ReplayConfig) { c.Owner = "" },
+		"negative rate": func(c *ReplayConfig) { c.RatePerSecond = -1 },
+		"page size":     func(c *ReplayConfig) { c.PageSize = 1001 },
+	} {
+		cfg := valid
+		mutate(&cfg)
+		_, err := NewReplay(cfg)
+		require.Error(t, err, name)
+	}
+}
+
+func TestArchive_WritesExpiringEventsAsJSONL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Now()
+	bus := NewMemoryEventBus()
+	publishReplayFixture(t, bus, map[string]time.Time{
+		"e0": now.Add(time.Hour),
+		"e1": now.Add(30 * 24 * time.Hour),
+		"e3": now.Add(2 * time.Hour),
+		"e5": now.Add(-time.Minute),
+	})
+	store, err := objectstore.NewMemoryStore(objectstore.MemoryStoreConfig{})
+	require.NoError(t, err)
+
+	archive, err := NewArchive(ArchiveConfig{
+		EventJobConfig: EventJobConfig{
+			Bus:      bus,
+			Query:    EventQuery{TenantID: "t1"},
+			Ledger:   testkitjobs.NewLedger(),
+			JobID:    "archive-1",
+			TenantID: "t1",
+			Owner:    "worker-a",
+			PageSize: 3,
+		},
+		Store:          store,
+		Bucket:         "archive",
+		Prefix:         "/events/",
+		ExpiringWithin: 24 * time.Hour,
+	})
+	require.NoError(t, err)
+
+	report, err := archive.Run(ctx, EventJobInput{})
+	require.NoError(t, err)
+	require.Equal(t, EventJobReport{Pages: 2, Events: 3, Objects: 2, NextPage: 2, Done: true}, report)
+
+	ref := archive.Ref(0)
+	require.Equal(t, "events/t1/archive-1/00000000.jsonl", ref.Key)
+	var ids []string
+	for page := range 2 {
+		out, err := store.Get(ctx, objectstore.GetInput{Ref: archive.Ref(page), MaxBytes: 1 << 20})
+		require.NoError(t, err)
+		require.Equal(t, ArchiveContentType, out.ContentType)
+		scanner := bufio.NewScanner(bytes.NewReader(out.Payload))
+		for scanner.Scan() {
+			var evt Event
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &evt))
+			ids = append(ids, evt.ID)
+		}
+	}
+	require.Equal(t, []string{"e5", "e3", "e0"}, ids)
+
+	for name, cfg := range map[string]ArchiveConfig{
+		"no store":  {EventJobConfig: EventJobConfig{Bus: bus}, Bucket: "b", ExpiringWithin: time.Hour},
+		"no window": {EventJobConfig: EventJobConfig{Bus: bus}, Store: store, Bucket: "b"},
+		"no job":    {EventJobConfig: EventJobConfig{Bus: bus, Query: EventQuery{TenantID: "t1"}, Ledger: testkitjobs.NewLedger()}, Store: store, Bucket: "b", ExpiringWithin: time.Hour},
+	} {
+		_, err := NewArchive(cfg)
+		require.Error(t, err, name)
+	}
+}
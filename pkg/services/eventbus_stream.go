@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/theory-cloud/apptheory/v3/internal/retry"
+	apptheory "github.com/theory-cloud/apptheory/v3/runtime"
+)
+
+// TableName returns the DynamoDB table the bus publishes to.
+func (d *DynamoDBEventBus) TableName() string {
+	return d.config.TableName
+}
+
+// RegisterStream registers the bus's stream dispatcher on app for the bus
+// table, so subscribed handlers receive events published by any process.
+//
+// The table must have a stream with NEW_IMAGE or NEW_AND_OLD_IMAGES.
+func (d *DynamoDBEventBus) RegisterStream(app *apptheory.App) *apptheory.App {
+	return app.DynamoDB(d.config.TableName, d.StreamHandler())
+}
+
+// StreamHandler returns a DynamoDB Streams handler that delivers newly
+// published events to the handlers registered with Subscribe.
+//
+// Only INSERT records are delivered; TTL expiry and DeleteEvent removals are
+// ignored. Each handler is retried with exponential backoff up to
+// RetryAttempts times. A record whose handler still fails returns an error, so
+// App.ServeDynamoDBStream reports it in the partial batch response and Lambda
+// redelivers it. Delivery is at-least-once: handlers that already succeeded
+// run again on redelivery and must be idempotent.
+func (d *DynamoDBEventBus) StreamHandler() apptheory.DynamoDBStreamHandler {
+	return func(ctx *apptheory.EventContext, record events.DynamoDBEventRecord) error {
+		return d.dispatchStreamRecord(ctx.Context(), record)
+	}
+}
+
+func (d *DynamoDBEventBus) dispatchStreamRecord(ctx context.Context, record events.DynamoDBEventRecord) error {
+	if record.EventName != string(events.DynamoDBOperationTypeInsert) {
+		return nil
+	}
+
+	event, err := EventFromStreamImage(record.Change.NewImage)
+	if err != nil {
+		d.emitMetric("DeliveryError", 1, map[string]string{
+			"error_type": "decode_failed",
+		})
+		return err
+	}
+	return d.deliverEvent(ctx, event)
+}
+
+// deliverEvent runs every handler subscribed to the event's type, retrying
+// each one, and stops at the first handler that still fails.
+func (d *DynamoDBEventBus) deliverEvent(ctx context.Context, event *Event) error {
+	d.mu.RLock()
+	handlers := append([]EventHandler(nil), d.handlers[event.EventType]...)
+	d.mu.RUnlock()
+
+	if len(handlers) == 0 {
+		d.emitMetric("DeliverySkipped", 1, map[string]string{
+			"event_type": event.EventType,
+		})
+		return nil
+	}
+
+	for _, handler := range handlers {
+		if err := d.deliverWithRetry(ctx, handler, event); err != nil {
+			return err
+		}
+	}
+
+	d.emitMetric("DeliverySuccess", 1, map[string]string{
+		"event_type": event.EventType,
+		"tenant_id":  event.TenantID,
+	})
+	return nil
+}
+
+func (d *DynamoDBEventBus) deliverWithRetry(ctx context.Context, handler EventHandler, event *Event) error {
+	var lastErr error
+	for attempt := 0; attempt <= d.config.RetryAttempts; attempt++ {
+		if attempt > 0 {
+			d.emitMetric("DeliveryRetry", 1, map[string]string{
+				"event_type": event.EventType,
+			})
+			backoffMultiplier := 1 << minInt(attempt-1, 10) // cap at 2^10
+			if err := retry.Sleep(ctx, d.config.RetryBaseDelay*time.Duration(backoffMultiplier)); err != nil {
+				lastErr = err
+				break
+			}
+		}
+
+		// Each attempt gets its own copy so a handler cannot leak mutations
+		// into the next attempt or the next handler.
+		lastErr = handler(ctx, cloneStreamEvent(event))
+		if lastErr == nil {
+			return nil
+		}
+	}
+
+	d.emitMetric("DeliveryError", 1, map[string]string{
+		"error_type": "handler_failed",
+		"event_type": event.EventType,
+	})
+	return fmt.Errorf("failed to deliver event %s after %d attempts: %w", event.ID, d.config.RetryAttempts+1, lastErr)
+}
+
+// cloneStreamEvent copies event deeply enough that writes through the copy's
+// Payload, Metadata, or Tags never reach event.
+func cloneStreamEvent(event *Event) *Event {
+	copied := *event
+	copied.Payload = append(json.RawMessage(nil), event.Payload...)
+	if event.Metadata != nil {
+		copied.Metadata = make(map[string]string, len(event.Metadata))
+		for key, value := range event.Metadata {
+			copied.Metadata[key] = value
+		}
+	}
+	copied.Tags = append([]string(nil), event.Tags...)
+	return &copied
+}
+
+// EventFromStreamImage decodes an Event from a DynamoDB Streams image of the
+// event bus table, as written by DynamoDBEventBus.Publish.
+func EventFromStreamImage(image map[string]events.DynamoDBAttributeValue) (*Event, error) {
+	if len(image) == 0 {
+		return nil, fmt.Errorf("stream record has no new image (table stream must include NEW_IMAGE)")
+	}
+
+	event := &Event{}
+	if err := apptheory.UnmarshalDynamoDBStreamImage(image, event); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(event.ID) == "" || strings.TrimSpace(event.EventType) == "" {
+		return nil, fmt.Errorf("stream image is missing id or event_type")
+	}
+	return event, nil
+}
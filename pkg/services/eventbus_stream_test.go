@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+
+	apptheory "github.com/theory-cloud/apptheory/v3/runtime"
+)
+
+func eventStreamRecord(eventID, eventName string, image map[string]events.DynamoDBAttributeValue, tableName string) events.DynamoDBEventRecord {
+	return events.DynamoDBEventRecord{
+		EventID:        eventID,
+		EventName:      eventName,
+		EventSource:    "aws:dynamodb",
+		EventSourceArn: "arn:aws:dynamodb:us-east-1:123456789012:table/" + tableName + "/stream/2026-01-01T00:00:00.000",
+		Change: events.DynamoDBStreamRecord{
+			SequenceNumber: eventID,
+			NewImage:       image,
+		},
+	}
+}
+
+func eventStreamImage(id, eventType string) map[string]events.DynamoDBAttributeValue {
+	return map[string]events.DynamoDBAttributeValue{
+		"id":           events.NewStringAttribute(id),
+		"event_type":   events.NewStringAttribute(eventType),
+		"tenant_id":    events.NewStringAttribute("t1"),
+		"source_id":    events.NewStringAttribute("s1"),
+		"pk":           events.NewStringAttribute("t1#" + eventType),
+		"sk":           events.NewStringAttribute("1#" + id),
+		"published_at": events.NewStringAttribute("2026-01-02T03:04:05.000000006Z"),
+		"created_at":   events.NewStringAttribute("2026-01-02T03:04:05Z"),
+		"payload":      events.NewBinaryAttribute([]byte(`{"ok":true}`)),
+		"metadata":     events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{"origin": events.NewStringAttribute("api")}),
+		"tags":         events.NewStringSetAttribute([]string{"a", "b"}),
+		"ttl":          events.NewNumberAttribute("1767322245"),
+		"version":      events.NewNumberAttribute("1"),
+		"retry_count":  events.NewNullAttribute(),
+	}
+}
+
+func TestEventFromStreamImage_DecodesPublishedItems(t *testing.T) {
+	t.Parallel()
+
+	evt, err := EventFromStreamImage(eventStreamImage("e1", "partner.created"))
+	require.NoError(t, err)
+	require.Equal(t, "e1", evt.ID)
+	require.Equal(t, "partner.created", evt.EventType)
+	require.Equal(t, "t1#partner.created", evt.PartitionKey)
+	require.Equal(t, 6, evt.PublishedAt.Nanosecond())
+	require.JSONEq(t, `{"ok":true}`, string(evt.Payload))
+	require.Equal(t, map[string]string{"origin": "api"}, evt.Metadata)
+	require.Equal(t, []string{"a", "b"}, evt.Tags)
+	require.Equal(t, int64(1767322245), evt.TTL)
+	require.Equal(t, 1, evt.Version)
+	require.Zero(t, evt.RetryCount)
+
+	image := eventStreamImage("e1", "partner.created")
+	image["payload"] = events.NewStringAttribute(`{"ok":false}`)
+	image["tags"] = events.NewListAttribute([]events.DynamoDBAttributeValue{events.NewStringAttribute("c")})
+	evt, err = EventFromStreamImage(image)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"ok":false}`, string(evt.Payload))
+	require.Equal(t, []string{"c"}, evt.Tags)
+
+	for name, value := range map[string]events.DynamoDBAttributeValue{
+		"id":           events.NewStringAttribute(" "),
+		"event_type":   events.NewNumberAttribute("1"),
+		"published_at": events.NewStringAttribute("yesterday"),
+		"version":      events.NewStringAttribute("1"),
+		"payload":      events.NewBooleanAttribute(true),
+		"metadata":     events.NewStringAttribute("x"),
+		"tags":         events.NewNumberSetAttribute([]string{"1"}),
+	} {
+		image := eventStreamImage("e1", "partner.created")
+		image[name] = value
+		_, err := EventFromStreamImage(image)
+		require.Error(t, err, name)
+	}
+	_, err = EventFromStreamImage(nil)
+	require.Error(t, err)
+}
+
+func TestDynamoDBEventBus_StreamHandler_DeliversWithRetriesAndPartialFailures(t *testing.T) {
+	t.Parallel()
+
+	var metrics []MetricRecord
+	bus := NewDynamoDBEventBus(nil, EventBusConfig{
+		EnableMetrics:  true,
+		RetryAttempts:  2,
+		RetryBaseDelay: 1,
+		EmitMetric: func(rec MetricRecord) {
+			metrics = append(metrics, rec)
+		},
+	})
+
+	var delivered []string
+	flakyCalls := 0
+	require.NoError(t, bus.Subscribe(context.Background(), "partner.created", func(_ context.Context, evt *Event) error {
+		delivered = append(delivered, evt.ID)
+		return nil
+	}))
+	require.NoError(t, bus.Subscribe(context.Background(), "partner.flaky", func(_ context.Context, evt *Event) error {
+		flakyCalls++
+		require.Equal(t, map[string]string{"origin": "api"}, evt.Metadata, "attempt %d", flakyCalls)
+		require.Equal(t, []string{"a", "b"}, evt.Tags, "attempt %d", flakyCalls)
+		require.JSONEq(t, `{"ok":true}`, string(evt.Payload), "attempt %d", flakyCalls)
+		if flakyCalls < 3 {
+			evt.Metadata["origin"] = "mutated"
+			evt.Tags[0] = "mutated"
+			evt.Payload[1] = 'X'
+			return errors.New("temporary")
+		}
+		delivered = append(delivered, evt.ID)
+		return nil
+	}))
+	require.NoError(t, bus.Subscribe(context.Background(), "partner.broken", func(context.Context, *Event) error {
+		return errors.New("broken")
+	}))
+
+	app := bus.RegisterStream(apptheory.New())
+	table := bus.TableName()
+	resp := app.ServeDynamoDBStream(context.Background(), events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		eventStreamRecord("1", "INSERT", eventStreamImage("e1", "partner.created"), table),
+		eventStreamRecord("2", "INSERT", eventStreamImage("e2", "partner.flaky"), table),
+		eventStreamRecord("3", "INSERT", eventStreamImage("e3", "partner.broken"), table),
+		eventStreamRecord("4", "INSERT", eventStreamImage("e4", "partner.unsubscribed"), table),
+		eventStreamRecord("5", "REMOVE", nil, table),
+		eventStreamRecord("6", "INSERT", nil, table),
+	}})
+
+	require.Equal(t, []events.DynamoDBBatchItemFailure{{ItemIdentifier: "3"}, {ItemIdentifier: "6"}}, resp.BatchItemFailures)
+	require.Equal(t, []string{"e1", "e2"}, delivered)
+	require.Equal(t, 3, flakyCalls)
+
+	counts := map[string]int{}
+	for _, m := range metrics {
+		counts[m.Name]++
+		require.Equal(t, "AppTheory/EventBus", m.Namespace)
+	}
+	require.Equal(t, map[string]int{
+		"DeliverySuccess": 2,
+		"DeliveryRetry":   4,
+		"DeliveryError":   2,
+		"DeliverySkipped": 1,
+	}, counts)
+}
+
+func TestDynamoDBEventBus_StreamHandler_StopsRetryingWhenContextEnds(t *testing.T) {
+	t.Parallel()
+
+	bus := NewDynamoDBEventBus(nil, EventBusConfig{EnableMetrics: false, RetryAttempts: 5})
+	calls := 0
+	require.NoError(t, bus.Subscribe(context.Background(), "evt", func(context.Context, *Event) error {
+		calls++
+		return errors.New("fail")
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := bus.dispatchStreamRecord(ctx, eventStreamRecord("1", "INSERT", eventStreamImage("e1", "evt"), bus.TableName()))
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, calls)
+}
@@ -0,0 +1,284 @@
+package vectorstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/theory-cloud/apptheory/v3/internal/retry"
+)
+
+const (
+	DefaultEmbeddingBatchSize        = 128
+	DefaultEmbeddingConcurrency      = 8
+	DefaultEmbeddingMaxRetries       = 4
+	DefaultEmbeddingRetryBaseDelay   = 200 * time.Millisecond
+	DefaultEmbeddingRetryMaxDelay    = 10 * time.Second
+	DefaultEmbeddingMetricsNamespace = "AppTheory/Embeddings"
+)
+
+// Metric names CachedEmbedder emits. Each record's Value is a count for one
+// EmbedBatch call; zero counts are not emitted.
+const (
+	MetricEmbeddingCacheHits   = "EmbeddingCacheHits"
+	MetricEmbeddingCacheMisses = "EmbeddingCacheMisses"
+	MetricEmbeddingCacheErrors = "EmbeddingCacheErrors"
+	MetricEmbeddingRetries     = "EmbeddingRetries"
+)
+
+// MetricRecord is a minimal, portable metric payload. Bridge it to CloudWatch,
+// OTEL, or any other backend in EmitMetric.
+type MetricRecord struct {
+	Namespace string
+	Name      string
+	Value     float64
+	Tags      map[string]string
+}
+
+// CachedEmbedderConfig configures NewCachedEmbedder.
+//
+// CacheNamespace is folded into every cache key. Set it to something that
+// identifies the embedding space, such as the model ID and dimensions, so a
+// model change never serves stale vectors. Misses are sent to the inner
+// EmbedBatch in chunks of BatchSize with at most Concurrency chunks in flight.
+// RetryCodes lists the EmbeddingErrorCode values worth retrying and defaults to
+// ErrorCodeEmbeddingThrottled. MaxRetries of -1 disables retries.
+type CachedEmbedderConfig struct {
+	Embedder         Embedder
+	Cache            EmbeddingCache
+	CacheNamespace   string
+	BatchSize        int
+	Concurrency      int
+	MaxRetries       int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+	RetryCodes       []string
+	MetricsNamespace string
+	MetricTags       map[string]string
+	EmitMetric       func(MetricRecord)
+}
+
+// CachedEmbedder decorates an Embedder with a content-hash cache, bounded
+// concurrency, and retries with exponential backoff and jitter. Texts are
+// trimmed before hashing, matching what the built-in embedders send, and
+// duplicate texts in one batch are embedded once. Cache read and write
+// failures are reported through MetricEmbeddingCacheErrors and otherwise
+// ignored, since the cache only saves cost.
+type CachedEmbedder struct {
+	cfg   CachedEmbedderConfig
+	sleep func(context.Context, time.Duration) error
+}
+
+var _ Embedder = (*CachedEmbedder)(nil)
+
+// NewCachedEmbedder validates cfg and fills in defaults.
+func NewCachedEmbedder(cfg CachedEmbedderConfig) (*CachedEmbedder, error) {
+	if cfg.Embedder == nil {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: cached embedder requires an embedder", nil)
+	}
+	if cfg.BatchSize < 0 || cfg.Concurrency < 0 || cfg.MaxRetries < -1 || cfg.RetryBaseDelay < 0 || cfg.RetryMaxDelay < 0 {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: cached embedder limits must not be negative", nil)
+	}
+	cfg.BatchSize = positiveOr(cfg.BatchSize, DefaultEmbeddingBatchSize)
+	cfg.Concurrency = positiveOr(cfg.Concurrency, DefaultEmbeddingConcurrency)
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultEmbeddingMaxRetries
+	}
+	cfg.MaxRetries = max(cfg.MaxRetries, 0)
+	if cfg.RetryBaseDelay == 0 {
+		cfg.RetryBaseDelay = DefaultEmbeddingRetryBaseDelay
+	}
+	if cfg.RetryMaxDelay == 0 {
+		cfg.RetryMaxDelay = DefaultEmbeddingRetryMaxDelay
+	}
+	if len(cfg.RetryCodes) == 0 {
+		cfg.RetryCodes = []string{ErrorCodeEmbeddingThrottled}
+	}
+	cfg.RetryCodes = cloneStrings(cfg.RetryCodes)
+	if strings.TrimSpace(cfg.MetricsNamespace) == "" {
+		cfg.MetricsNamespace = DefaultEmbeddingMetricsNamespace
+	}
+	return &CachedEmbedder{cfg: cfg, sleep: retry.Sleep}, nil
+}
+
+// EmbeddingCacheKey returns the cache key CachedEmbedder uses for text: the
+// hex SHA-256 of the namespace and the trimmed text.
+func EmbeddingCacheKey(namespace, text string) string {
+	sum := sha256.Sum256([]byte(namespace + "\x00" + strings.TrimSpace(text)))
+	return hex.EncodeToString(sum[:])
+}
+
+func (e *CachedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (e *CachedEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if e == nil || e.cfg.Embedder == nil {
+		return nil, ErrInvalidConfig
+	}
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+	keys := make([]string, len(texts))
+	unique := map[string]string{}
+	var order []string
+	for idx, text := range texts {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return nil, NewError(ErrorCodeInvalidInput, "vectorstore: embedding input is required", nil)
+		}
+		keys[idx] = EmbeddingCacheKey(e.cfg.CacheNamespace, text)
+		if _, ok := unique[keys[idx]]; !ok {
+			unique[keys[idx]] = text
+			order = append(order, keys[idx])
+		}
+	}
+
+	vectors := e.cached(ctx, order)
+	var missing []string
+	for _, key := range order {
+		if _, ok := vectors[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	e.emit(MetricEmbeddingCacheHits, len(order)-len(missing))
+	e.emit(MetricEmbeddingCacheMisses, len(missing))
+
+	// Vectors embedded before a failure are still cached, so retrying the
+	// batch only pays for what is left.
+	embedded, err := e.embedMissing(ctx, missing, unique)
+	if len(embedded) > 0 && e.cfg.Cache != nil {
+		if err := e.cfg.Cache.PutEmbeddings(ctx, embedded); err != nil {
+			e.emit(MetricEmbeddingCacheErrors, 1)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for key, vector := range embedded {
+		vectors[key] = vector
+	}
+
+	out := make([][]float32, len(texts))
+	for idx, key := range keys {
+		out[idx] = CloneVector(vectors[key])
+	}
+	return out, nil
+}
+
+func (e *CachedEmbedder) cached(ctx context.Context, keys []string) map[string][]float32 {
+	vectors := make(map[string][]float32, len(keys))
+	if e.cfg.Cache == nil {
+		return vectors
+	}
+	found, err := e.cfg.Cache.GetEmbeddings(ctx, keys)
+	if err != nil {
+		e.emit(MetricEmbeddingCacheErrors, 1)
+		return vectors
+	}
+	for key, vector := range found {
+		if ValidateVector(vector, 0) == nil {
+			vectors[key] = vector
+		}
+	}
+	return vectors
+}
+
+// embedMissing embeds the texts for keys in chunks of BatchSize, with at most
+// Concurrency chunks in flight, stopping at the first error. It returns the
+// chunks embedded before that error alongside it.
+func (e *CachedEmbedder) embedMissing(ctx context.Context, keys []string, texts map[string]string) (map[string][]float32, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		retries  int
+		out      = make(map[string][]float32, len(keys))
+		slots    = make(chan struct{}, e.cfg.Concurrency)
+	)
+	for start := 0; start < len(keys); start += e.cfg.BatchSize {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(chunk []string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			inputs := make([]string, len(chunk))
+			for idx, key := range chunk {
+				inputs[idx] = texts[key]
+			}
+			vectors, attempts, err := e.embedWithRetry(ctx, inputs)
+			mu.Lock()
+			defer mu.Unlock()
+			retries += attempts
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			for idx, key := range chunk {
+				out[key] = vectors[idx]
+			}
+		}(keys[start:min(start+e.cfg.BatchSize, len(keys))])
+	}
+	wg.Wait()
+	e.emit(MetricEmbeddingRetries, retries)
+	if firstErr == nil && len(out) != len(keys) {
+		firstErr = ctx.Err()
+	}
+	return out, firstErr
+}
+
+// embedWithRetry embeds one chunk and returns its vectors and how many
+// retries it took.
+func (e *CachedEmbedder) embedWithRetry(ctx context.Context, texts []string) ([][]float32, int, error) {
+	for attempt := 0; ; attempt++ {
+		vectors, err := e.cfg.Embedder.EmbedBatch(ctx, texts)
+		if err == nil && len(vectors) != len(texts) {
+			return nil, attempt, NewError(ErrorCodeEmbeddingFailed, "vectorstore: embedding count mismatch", nil)
+		}
+		if err == nil {
+			return vectors, attempt, nil
+		}
+		if attempt >= e.cfg.MaxRetries || !slices.Contains(e.cfg.RetryCodes, EmbeddingErrorCode(err)) {
+			return nil, attempt, err
+		}
+		if sleepErr := e.sleep(ctx, retry.JitteredBackoff(e.cfg.RetryBaseDelay, e.cfg.RetryMaxDelay, attempt)); sleepErr != nil {
+			return nil, attempt, err
+		}
+	}
+}
+
+func (e *CachedEmbedder) emit(name string, value int) {
+	if e.cfg.EmitMetric == nil || value == 0 {
+		return
+	}
+	tags := make(map[string]string, len(e.cfg.MetricTags))
+	for key, tag := range e.cfg.MetricTags {
+		tags[key] = tag
+	}
+	e.cfg.EmitMetric(MetricRecord{Namespace: e.cfg.MetricsNamespace, Name: name, Value: float64(value), Tags: tags})
+}
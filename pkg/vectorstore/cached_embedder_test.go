@@ -0,0 +1,249 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	tableerrors "github.com/theory-cloud/tabletheory/v3/pkg/errors"
+	tablemocks "github.com/theory-cloud/tabletheory/v3/pkg/mocks"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/objectstore"
+)
+
+// scriptedEmbedder returns [len(text), 1] for each text and fails a batch
+// with the first queued error of any of its texts. It is safe for concurrent
+// use.
+type scriptedEmbedder struct {
+	mu       sync.Mutex
+	failures map[string][]error
+	calls    []string
+	batches  [][]string
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (e *scriptedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (e *scriptedEmbedder) EmbedBatch(_ context.Context, texts []string) ([][]float32, error) {
+	current := e.inFlight.Add(1)
+	defer e.inFlight.Add(-1)
+	for {
+		peak := e.peak.Load()
+		if current <= peak || e.peak.CompareAndSwap(peak, current) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, texts...)
+	e.batches = append(e.batches, texts)
+	out := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		if queued := e.failures[text]; len(queued) > 0 {
+			e.failures[text] = queued[1:]
+			return nil, queued[0]
+		}
+		out = append(out, []float32{float32(len(text)), 1})
+	}
+	return out, nil
+}
+
+func TestCachedEmbedderCachesRetriesAndReportsMetrics(t *testing.T) {
+	ctx := context.Background()
+	inner := &scriptedEmbedder{failures: map[string][]error{
+		"bb": {ErrEmbeddingThrottled, NewError(ErrorCodeEmbeddingThrottled, "vectorstore: throttled", nil)},
+	}}
+	cache := NewMemoryEmbeddingCache(0)
+	metrics := map[string]float64{}
+	var metricsMu sync.Mutex
+	embedder, err := NewCachedEmbedder(CachedEmbedderConfig{
+		Embedder:       inner,
+		Cache:          cache,
+		CacheNamespace: "model-a",
+		BatchSize:      2,
+		Concurrency:    2,
+		MetricTags:     map[string]string{"model": "a"},
+		EmitMetric: func(record MetricRecord) {
+			metricsMu.Lock()
+			defer metricsMu.Unlock()
+			if record.Namespace != DefaultEmbeddingMetricsNamespace || record.Tags["model"] != "a" {
+				t.Errorf("metric record = %#v", record)
+			}
+			metrics[record.Name] += record.Value
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewCachedEmbedder() error = %v", err)
+	}
+	var delays []time.Duration
+	embedder.sleep = func(_ context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return nil
+	}
+
+	got, err := embedder.EmbedBatch(ctx, []string{"a", " bb ", "ccc", "dddd", "a"})
+	if err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	if want := [][]float32{{1, 1}, {2, 1}, {3, 1}, {4, 1}, {1, 1}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("EmbedBatch() = %v, want %v", got, want)
+	}
+	if len(inner.batches) != 4 || inner.peak.Load() > 2 {
+		t.Fatalf("inner batches = %q with peak concurrency %d", inner.batches, inner.peak.Load())
+	}
+	for _, batch := range inner.batches {
+		if len(batch) != 2 {
+			t.Fatalf("inner batches = %q, want misses sent two at a time", inner.batches)
+		}
+	}
+	if len(delays) != 2 || delays[0] < DefaultEmbeddingRetryBaseDelay/2 || delays[1] > 2*DefaultEmbeddingRetryBaseDelay {
+		t.Fatalf("retry delays = %v", delays)
+	}
+	if want := map[string]float64{MetricEmbeddingCacheMisses: 4, MetricEmbeddingRetries: 2}; !reflect.DeepEqual(metrics, want) {
+		t.Fatalf("metrics = %v, want %v", metrics, want)
+	}
+
+	inner.calls = nil
+	clear(metrics)
+	vector, err := embedder.Embed(ctx, "ccc")
+	if err != nil || !reflect.DeepEqual(vector, []float32{3, 1}) || len(inner.calls) != 0 {
+		t.Fatalf("Embed() cached = %v, %v with calls %q", vector, err, inner.calls)
+	}
+	if want := map[string]float64{MetricEmbeddingCacheHits: 1}; !reflect.DeepEqual(metrics, want) {
+		t.Fatalf("metrics = %v, want %v", metrics, want)
+	}
+
+	// Another namespace never sees model-a's vectors.
+	other, err := NewCachedEmbedder(CachedEmbedderConfig{Embedder: inner, Cache: cache, CacheNamespace: "model-b"})
+	if err != nil {
+		t.Fatalf("NewCachedEmbedder() error = %v", err)
+	}
+	if _, err := other.Embed(ctx, "ccc"); err != nil || len(inner.calls) != 1 {
+		t.Fatalf("Embed() other namespace = %v with calls %q", err, inner.calls)
+	}
+}
+
+func TestCachedEmbedderStopsOnPermanentErrors(t *testing.T) {
+	ctx := context.Background()
+	inner := &scriptedEmbedder{failures: map[string][]error{
+		"bad":  {ErrInvalidInput},
+		"busy": {ErrEmbeddingThrottled, ErrEmbeddingThrottled},
+	}}
+	cache := NewMemoryEmbeddingCache(0)
+	embedder, err := NewCachedEmbedder(CachedEmbedderConfig{Embedder: inner, Cache: cache, BatchSize: 1, Concurrency: 1, MaxRetries: 1})
+	if err != nil {
+		t.Fatalf("NewCachedEmbedder() error = %v", err)
+	}
+	embedder.sleep = func(context.Context, time.Duration) error { return nil }
+
+	if _, err := embedder.EmbedBatch(ctx, []string{"ok", "bad", "later"}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("EmbedBatch() error = %v, want ErrInvalidInput", err)
+	}
+	if !reflect.DeepEqual(inner.calls, []string{"ok", "bad"}) || cache.Len() != 1 {
+		t.Fatalf("calls = %q with %d cached, want the first success cached", inner.calls, cache.Len())
+	}
+	if _, err := embedder.Embed(ctx, "busy"); !errors.Is(err, ErrEmbeddingThrottled) {
+		t.Fatalf("Embed() error = %v, want ErrEmbeddingThrottled after MaxRetries", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := embedder.Embed(cancelled, "slow"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Embed() cancelled error = %v, want context.Canceled", err)
+	}
+
+	if _, err := embedder.Embed(ctx, " "); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("Embed() blank error = %v, want ErrInvalidInput", err)
+	}
+	for _, cfg := range []CachedEmbedderConfig{{}, {Embedder: inner, Concurrency: -1}, {Embedder: inner, BatchSize: -1}, {Embedder: inner, MaxRetries: -2}} {
+		if _, err := NewCachedEmbedder(cfg); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("NewCachedEmbedder(%#v) error = %v, want ErrInvalidConfig", cfg, err)
+		}
+	}
+}
+
+func TestEmbeddingCaches(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryEmbeddingCache(2)
+	if err := memory.PutEmbeddings(ctx, map[string][]float32{"a": {1}, "b": {2}}); err != nil {
+		t.Fatalf("PutEmbeddings() error = %v", err)
+	}
+	if _, err := memory.GetEmbeddings(ctx, []string{"a"}); err != nil {
+		t.Fatalf("GetEmbeddings() error = %v", err)
+	}
+	if err := memory.PutEmbeddings(ctx, map[string][]float32{"c": {3}}); err != nil {
+		t.Fatalf("PutEmbeddings() error = %v", err)
+	}
+	got, err := memory.GetEmbeddings(ctx, []string{"a", "b", "c"})
+	if err != nil || !reflect.DeepEqual(got, map[string][]float32{"a": {1}, "c": {3}}) {
+		t.Fatalf("memory GetEmbeddings() after eviction = %v, %v", got, err)
+	}
+
+	objects, err := objectstore.NewMemoryStore(objectstore.MemoryStoreConfig{})
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+	objectCache, err := NewObjectEmbeddingCache(objects, "bucket", "/embeddings/")
+	if err != nil {
+		t.Fatalf("NewObjectEmbeddingCache() error = %v", err)
+	}
+	key := EmbeddingCacheKey("ns", "text")
+	if err := objectCache.PutEmbeddings(ctx, map[string][]float32{key: {0.5, -1}}); err != nil {
+		t.Fatalf("object PutEmbeddings() error = %v", err)
+	}
+	if _, err := objects.Head(ctx, objectstore.HeadInput{Ref: objectstore.ObjectRef{Bucket: "bucket", Key: "embeddings/" + key[:2] + "/" + key}}); err != nil {
+		t.Fatalf("Head() cached object error = %v", err)
+	}
+	got, err = objectCache.GetEmbeddings(ctx, []string{key, "missing"})
+	if err != nil || !reflect.DeepEqual(got, map[string][]float32{key: {0.5, -1}}) {
+		t.Fatalf("object GetEmbeddings() = %v, %v", got, err)
+	}
+	if _, err := NewObjectEmbeddingCache(objects, " ", ""); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("NewObjectEmbeddingCache() without bucket error = %v, want ErrInvalidConfig", err)
+	}
+
+	db := new(tablemocks.MockDB)
+	query := new(tablemocks.MockQuery)
+	db.On("Model", mock.Anything).Return(query)
+	query.On("WithContext", mock.Anything).Return(query)
+	query.On("CreateOrUpdate").Return(nil).Once()
+	query.On("Where", "Key", "=", "hit").Return(query).Once()
+	query.On("First", mock.Anything).Run(func(args mock.Arguments) {
+		item, ok := args.Get(0).(*embeddingCacheItem)
+		if ok {
+			item.Vector = encodeSnapshotVector([]float32{7})
+		}
+	}).Return(nil).Once()
+	query.On("Where", "Key", "=", "miss").Return(query).Once()
+	query.On("First", mock.Anything).Return(tableerrors.ErrItemNotFound).Once()
+	tableCache, err := NewTableEmbeddingCache(db, func() int64 { return 42 })
+	if err != nil {
+		t.Fatalf("NewTableEmbeddingCache() error = %v", err)
+	}
+	if err := tableCache.PutEmbeddings(ctx, map[string][]float32{"hit": {7}}); err != nil {
+		t.Fatalf("table PutEmbeddings() error = %v", err)
+	}
+	put, ok := db.Calls[0].Arguments.Get(0).(*embeddingCacheItem)
+	if !ok || put.Key != "hit" || put.TTL != 42 {
+		t.Fatalf("table put item = %#v", db.Calls[0].Arguments.Get(0))
+	}
+	got, err = tableCache.GetEmbeddings(ctx, []string{"hit", "miss"})
+	if err != nil || !reflect.DeepEqual(got, map[string][]float32{"hit": {7}}) {
+		t.Fatalf("table GetEmbeddings() = %v, %v", got, err)
+	}
+	query.AssertExpectations(t)
+}
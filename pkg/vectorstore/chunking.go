@@ -0,0 +1,219 @@
+package vectorstore
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	DefaultChunkTokens        = 256
+	DefaultChunkOverlapTokens = 32
+)
+
+// TextChunk is one piece of a document. Section is the heading path the chunk
+// came from, when the splitter tracks one.
+type TextChunk struct {
+	Text    string
+	Section string
+}
+
+// Splitter breaks document text into chunks small enough to embed. Splitters
+// must be deterministic: the same text always yields the same chunks, which is
+// what lets re-ingestion skip unchanged chunks.
+type Splitter interface {
+	Split(text string) ([]TextChunk, error)
+}
+
+// CharacterSplitter emits windows of at most Size runes that start up to
+// Overlap runes before the previous window ends. Window edges snap to
+// whitespace when there is some nearby, so words are rarely cut.
+type CharacterSplitter struct {
+	Size    int
+	Overlap int
+}
+
+func (s CharacterSplitter) Split(text string) ([]TextChunk, error) {
+	if err := validateWindow(s.Size, s.Overlap); err != nil {
+		return nil, err
+	}
+	runes := []rune(text)
+	var chunks []TextChunk
+	for start := 0; start < len(runes); {
+		end := min(start+s.Size, len(runes))
+		if end < len(runes) {
+			end = lastSpace(runes, start+max(s.Size/2, 1), end, end)
+		}
+		chunks = appendChunk(chunks, string(runes[start:end]), "")
+		if end == len(runes) {
+			break
+		}
+		next := max(end-s.Overlap, start+1)
+		if !unicode.IsSpace(runes[next-1]) {
+			next = firstSpace(runes, next, end, end-1) + 1
+		}
+		start = next
+	}
+	return chunks, nil
+}
+
+// lastSpace returns the index of the last whitespace rune in runes[from:to+1],
+// or fallback when there is none.
+func lastSpace(runes []rune, from, to, fallback int) int {
+	for i := to; i >= from; i-- {
+		if unicode.IsSpace(runes[i]) {
+			return i
+		}
+	}
+	return fallback
+}
+
+// firstSpace returns the index of the first whitespace rune in runes[from:to],
+// or fallback when there is none.
+func firstSpace(runes []rune, from, to, fallback int) int {
+	for i := from; i < to; i++ {
+		if unicode.IsSpace(runes[i]) {
+			return i
+		}
+	}
+	return fallback
+}
+
+// TokenSplitter emits windows of Size whitespace-separated words that share
+// Overlap words with the previous window. Words approximate model tokens;
+// choose Size with headroom below the embedder's token limit. Chunk text is
+// the window's words joined by single spaces.
+type TokenSplitter struct {
+	Size    int
+	Overlap int
+}
+
+func (s TokenSplitter) Split(text string) ([]TextChunk, error) {
+	if err := validateWindow(s.Size, s.Overlap); err != nil {
+		return nil, err
+	}
+	words := strings.Fields(text)
+	var chunks []TextChunk
+	for start := 0; start < len(words); start += s.Size - s.Overlap {
+		end := min(start+s.Size, len(words))
+		chunks = appendChunk(chunks, strings.Join(words[start:end], " "), "")
+		if end == len(words) {
+			break
+		}
+	}
+	return chunks, nil
+}
+
+// MarkdownSplitter splits on ATX headings (# through ######) outside fenced
+// code blocks, then splits each section with Sections, which defaults to a
+// TokenSplitter with DefaultChunkTokens and DefaultChunkOverlapTokens. Each
+// chunk's Section is the heading path, such as "Install > Linux", and the
+// first chunk of a section keeps its heading line.
+type MarkdownSplitter struct {
+	Sections Splitter
+}
+
+var markdownHeading = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+
+func (s MarkdownSplitter) Split(text string) ([]TextChunk, error) {
+	inner := s.Sections
+	if inner == nil {
+		inner = TokenSplitter{Size: DefaultChunkTokens, Overlap: DefaultChunkOverlapTokens}
+	}
+	var chunks []TextChunk
+	for _, section := range markdownSections(text) {
+		split, err := inner.Split(section.Text)
+		if err != nil {
+			return nil, err
+		}
+		for _, chunk := range split {
+			chunks = appendChunk(chunks, chunk.Text, section.Section)
+		}
+	}
+	return chunks, nil
+}
+
+type markdownSection struct {
+	TextChunk
+	hasBody bool
+}
+
+func markdownSections(text string) []TextChunk {
+	var (
+		sections []TextChunk
+		headings []string
+		current  markdownSection
+		lines    []string
+		fence    string
+	)
+	flush := func() {
+		if current.hasBody {
+			current.Text = strings.Join(lines, "\n")
+			sections = append(sections, current.TextChunk)
+		}
+		lines = nil
+	}
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if marker := markdownFence(trimmed); marker != "" && (fence == "" || strings.HasPrefix(trimmed, fence)) {
+			if fence == "" {
+				fence = marker
+			} else {
+				fence = ""
+			}
+		} else if match := markdownHeading.FindStringSubmatch(line); fence == "" && match != nil {
+			flush()
+			level := len(match[1])
+			if len(headings) >= level {
+				headings = headings[:level-1]
+			}
+			for len(headings) < level-1 {
+				headings = append(headings, "")
+			}
+			headings = append(headings, match[2])
+			current = markdownSection{TextChunk: TextChunk{Section: joinHeadings(headings)}}
+			lines = append(lines, line)
+			continue
+		}
+		if trimmed != "" {
+			current.hasBody = true
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return sections
+}
+
+func markdownFence(line string) string {
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, marker) {
+			return marker
+		}
+	}
+	return ""
+}
+
+func joinHeadings(headings []string) string {
+	parts := make([]string, 0, len(headings))
+	for _, heading := range headings {
+		if heading != "" {
+			parts = append(parts, heading)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+func appendChunk(chunks []TextChunk, text, section string) []TextChunk {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return chunks
+	}
+	return append(chunks, TextChunk{Text: text, Section: section})
+}
+
+func validateWindow(size, overlap int) error {
+	if size <= 0 || overlap < 0 || overlap >= size {
+		return NewError(ErrorCodeInvalidConfig, "vectorstore: chunk size must be positive and overlap must be smaller than size", nil)
+	}
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	bedrocktypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 )
 
 type FakeEmbedder struct {
@@ -109,7 +110,7 @@ func (e *TitanEmbedder) Embed(ctx context.Context, text string) ([]float32, erro
 	}
 	out, err := e.Runtime.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{ModelId: aws.String(modelID), ContentType: aws.String("application/json"), Accept: aws.String("application/json"), Body: body})
 	if err != nil {
-		return nil, NewError(ErrorCodeEmbeddingFailed, "vectorstore: bedrock embedding request failed", err)
+		return nil, NewError(bedrockErrorCode(err), "vectorstore: bedrock embedding request failed", err)
 	}
 	var decoded titanEmbedResponse
 	if err := json.Unmarshal(out.Body, &decoded); err != nil {
@@ -201,3 +202,18 @@ func EmbeddingErrorCode(err error) string {
 	}
 	return ErrorCodeEmbeddingFailed
 }
+
+// bedrockErrorCode reports Bedrock's capacity errors as
+// ErrorCodeEmbeddingThrottled so callers can retry them; everything else is
+// ErrorCodeEmbeddingFailed.
+func bedrockErrorCode(err error) string {
+	var (
+		throttling  *bedrocktypes.ThrottlingException
+		unavailable *bedrocktypes.ServiceUnavailableException
+		notReady    *bedrocktypes.ModelNotReadyException
+	)
+	if errors.As(err, &throttling) || errors.As(err, &unavailable) || errors.As(err, &notReady) {
+		return ErrorCodeEmbeddingThrottled
+	}
+	return ErrorCodeEmbeddingFailed
+}
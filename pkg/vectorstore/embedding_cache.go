@@ -0,0 +1,216 @@
+package vectorstore
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	tablecore "github.com/theory-cloud/tabletheory/v3/pkg/core"
+	tableerrors "github.com/theory-cloud/tabletheory/v3/pkg/errors"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/objectstore"
+)
+
+const (
+	DefaultMemoryEmbeddingCacheEntries = 10000
+	EnvEmbeddingCacheTableName         = "APPTHEORY_EMBEDDING_CACHE_TABLE_NAME"
+	defaultEmbeddingCacheTableName     = "apptheory-embedding-cache"
+	// maxCachedEmbeddingBytes bounds object cache reads: base64 of 16384
+	// float32 values, well past any hosted embedding model.
+	maxCachedEmbeddingBytes = 4 * 16384 * 4 / 3
+)
+
+// EmbeddingCache stores embeddings under the content-hash keys CachedEmbedder
+// computes. GetEmbeddings omits keys it does not hold; a miss is not an error.
+type EmbeddingCache interface {
+	GetEmbeddings(ctx context.Context, keys []string) (map[string][]float32, error)
+	PutEmbeddings(ctx context.Context, entries map[string][]float32) error
+}
+
+// MemoryEmbeddingCache is an in-process LRU EmbeddingCache.
+type MemoryEmbeddingCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type memoryEmbeddingEntry struct {
+	key    string
+	vector []float32
+}
+
+// NewMemoryEmbeddingCache returns an LRU cache holding up to maxEntries
+// embeddings, DefaultMemoryEmbeddingCacheEntries when maxEntries is zero.
+func NewMemoryEmbeddingCache(maxEntries int) *MemoryEmbeddingCache {
+	return &MemoryEmbeddingCache{maxEntries: positiveOr(maxEntries, DefaultMemoryEmbeddingCacheEntries), order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (c *MemoryEmbeddingCache) GetEmbeddings(_ context.Context, keys []string) (map[string][]float32, error) {
+	if c == nil || c.entries == nil {
+		return nil, ErrInvalidConfig
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string][]float32, len(keys))
+	for _, key := range keys {
+		element, ok := c.entries[key]
+		if !ok {
+			continue
+		}
+		c.order.MoveToFront(element)
+		if entry, ok := element.Value.(*memoryEmbeddingEntry); ok {
+			out[key] = CloneVector(entry.vector)
+		}
+	}
+	return out, nil
+}
+
+func (c *MemoryEmbeddingCache) PutEmbeddings(_ context.Context, entries map[string][]float32) error {
+	if c == nil || c.entries == nil {
+		return ErrInvalidConfig
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, vector := range entries {
+		if element, ok := c.entries[key]; ok {
+			c.order.Remove(element)
+		}
+		c.entries[key] = c.order.PushFront(&memoryEmbeddingEntry{key: key, vector: CloneVector(vector)})
+	}
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		if entry, ok := oldest.Value.(*memoryEmbeddingEntry); ok {
+			delete(c.entries, entry.key)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of cached embeddings.
+func (c *MemoryEmbeddingCache) Len() int {
+	if c == nil || c.order == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+type objectEmbeddingCache struct {
+	store  objectstore.Store
+	bucket string
+	prefix string
+}
+
+// NewObjectEmbeddingCache stores one object per embedding under
+// prefix/<key[:2]>/<key> in bucket. Pair it with an S3 lifecycle rule to expire
+// entries for retired models.
+func NewObjectEmbeddingCache(store objectstore.Store, bucket, prefix string) (EmbeddingCache, error) {
+	if store == nil || strings.TrimSpace(bucket) == "" {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: object embedding cache requires a store and bucket", nil)
+	}
+	return &objectEmbeddingCache{store: store, bucket: bucket, prefix: strings.Trim(prefix, "/")}, nil
+}
+
+func (c *objectEmbeddingCache) GetEmbeddings(ctx context.Context, keys []string) (map[string][]float32, error) {
+	out := make(map[string][]float32, len(keys))
+	for _, key := range keys {
+		got, err := c.store.Get(ctx, objectstore.GetInput{Ref: c.ref(key), MaxBytes: maxCachedEmbeddingBytes})
+		if errors.Is(err, objectstore.ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if vector, ok := decodeSnapshotVector(string(got.Payload)); ok {
+			out[key] = vector
+		}
+	}
+	return out, nil
+}
+
+func (c *objectEmbeddingCache) PutEmbeddings(ctx context.Context, entries map[string][]float32) error {
+	for key, vector := range entries {
+		if _, err := c.store.Put(ctx, objectstore.PutInput{Ref: c.ref(key), Payload: []byte(encodeSnapshotVector(vector)), ContentType: "text/plain"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *objectEmbeddingCache) ref(key string) objectstore.ObjectRef {
+	name := key
+	if len(key) > 2 {
+		name = key[:2] + "/" + key
+	}
+	if c.prefix != "" {
+		name = c.prefix + "/" + name
+	}
+	return objectstore.ObjectRef{Bucket: c.bucket, Key: name}
+}
+
+type embeddingCacheItem struct {
+	Key    string `theorydb:"pk,attr:key" json:"key"`
+	Vector string `theorydb:"attr:vector" json:"vector"`
+	TTL    int64  `theorydb:"ttl,attr:ttl,omitempty" json:"ttl,omitempty"`
+}
+
+func (embeddingCacheItem) TableName() string {
+	if name := strings.TrimSpace(os.Getenv(EnvEmbeddingCacheTableName)); name != "" {
+		return name
+	}
+	return defaultEmbeddingCacheTableName
+}
+
+type tableEmbeddingCache struct {
+	db  tablecore.DB
+	ttl func() int64
+}
+
+// NewTableEmbeddingCache stores embeddings in a DynamoDB table through
+// TableTheory, one item per key with a "key" partition key. The table name is
+// EnvEmbeddingCacheTableName or "apptheory-embedding-cache". ttl, when not
+// nil, returns the Unix-seconds expiry written with each item.
+func NewTableEmbeddingCache(db tablecore.DB, ttl func() int64) (EmbeddingCache, error) {
+	if db == nil {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: table embedding cache requires a db", nil)
+	}
+	return &tableEmbeddingCache{db: db, ttl: ttl}, nil
+}
+
+func (c *tableEmbeddingCache) GetEmbeddings(ctx context.Context, keys []string) (map[string][]float32, error) {
+	out := make(map[string][]float32, len(keys))
+	for _, key := range keys {
+		var item embeddingCacheItem
+		err := c.db.Model(&embeddingCacheItem{}).WithContext(ctx).Where("Key", "=", key).First(&item)
+		if tableerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if vector, ok := decodeSnapshotVector(item.Vector); ok {
+			out[key] = vector
+		}
+	}
+	return out, nil
+}
+
+func (c *tableEmbeddingCache) PutEmbeddings(ctx context.Context, entries map[string][]float32) error {
+	for key, vector := range entries {
+		item := &embeddingCacheItem{Key: key, Vector: encodeSnapshotVector(vector)}
+		if c.ttl != nil {
+			item.TTL = c.ttl()
+		}
+		if err := c.db.Model(item).WithContext(ctx).CreateOrUpdate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
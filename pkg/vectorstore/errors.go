@@ -10,6 +10,9 @@ const (
 	ErrorCodeNotFound             = "vectorstore.not_found"
 	ErrorCodeUnsupportedOperation = "vectorstore.unsupported_operation"
 	ErrorCodeEmbeddingFailed      = "vectorstore.embedding_failed"
+	ErrorCodeEmbeddingThrottled   = "vectorstore.embedding_throttled"
+	ErrorCodeConflict             = "vectorstore.conflict"
+	ErrorCodeInvalidFilter        = "vectorstore.invalid_filter"
 )
 
 var (
@@ -20,6 +23,9 @@ var (
 	ErrNotFound             = &Error{Code: ErrorCodeNotFound, Message: "vectorstore: vector not found"}
 	ErrUnsupportedOperation = &Error{Code: ErrorCodeUnsupportedOperation, Message: "vectorstore: unsupported operation"}
 	ErrEmbeddingFailed      = &Error{Code: ErrorCodeEmbeddingFailed, Message: "vectorstore: embedding failed"}
+	ErrEmbeddingThrottled   = &Error{Code: ErrorCodeEmbeddingThrottled, Message: "vectorstore: embedding throttled"}
+	ErrConflict             = &Error{Code: ErrorCodeConflict, Message: "vectorstore: conflicting write"}
+	ErrInvalidFilter        = &Error{Code: ErrorCodeInvalidFilter, Message: "vectorstore: invalid filter"}
 )
 
 type Error struct {
@@ -133,8 +133,12 @@ func (s *FakeStore) QueryVectors(_ context.Context, input QueryInput) ([]QueryHi
 	if err := ValidateVector(input.Vector, s.Dimension); err != nil {
 		return nil, err
 	}
+	filter, err := queryFilter(input)
+	if err != nil {
+		return nil, err
+	}
 	topK := NormalizeTopK(input.TopK)
-	s.record(Call{Operation: "QueryVectors", Vector: CloneVector(input.Vector), TopK: topK, Filter: CloneMetadata(input.Filter), ReturnMetadata: input.ReturnMetadata})
+	s.record(Call{Operation: "QueryVectors", Vector: CloneVector(input.Vector), TopK: topK, Filter: CloneMetadata(input.Filter), Where: cloneFilter(input.Where), ReturnMetadata: input.ReturnMetadata})
 	if err := s.failure("QueryVectors"); err != nil {
 		return nil, err
 	}
@@ -146,7 +150,7 @@ func (s *FakeStore) QueryVectors(_ context.Context, input QueryInput) ([]QueryHi
 	hits := make([]QueryHit, 0, len(keys))
 	for _, key := range keys {
 		record := s.records[key]
-		if !metadataMatches(record.Metadata, input.Filter) {
+		if !filterMatches(filter, record.Metadata) {
 			continue
 		}
 		hit := QueryHit{Key: key, Distance: squaredDistance(input.Vector, record.Data)}
@@ -167,6 +171,40 @@ func (s *FakeStore) QueryVectors(_ context.Context, input QueryInput) ([]QueryHi
 	return hits, nil
 }
 
+// ListVectors pages through records in key order. The cursor is the last key
+// of the previous page.
+func (s *FakeStore) ListVectors(_ context.Context, input ListInput) (ListOutput, error) {
+	if s == nil {
+		return ListOutput{}, ErrInvalidConfig
+	}
+	limit, err := listLimit(input.Limit)
+	if err != nil {
+		return ListOutput{}, err
+	}
+	s.record(Call{Operation: "ListVectors", TopK: limit, ReturnMetadata: input.ReturnMetadata})
+	if err := s.failure("ListVectors"); err != nil {
+		return ListOutput{}, err
+	}
+	keys := make([]string, 0, len(s.records))
+	for key := range s.records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	page, next := pageSortedKeys(keys, input.Cursor, limit)
+	out := ListOutput{Records: make([]VectorRecord, 0, len(page)), NextCursor: next}
+	for _, key := range page {
+		record := cloneRecord(s.records[key])
+		if !input.ReturnData {
+			record.Data = nil
+		}
+		if !input.ReturnMetadata {
+			record.Metadata = nil
+		}
+		out.Records = append(out.Records, record)
+	}
+	return out, nil
+}
+
 func (s *FakeStore) record(call Call) { s.calls = append(s.calls, cloneCall(call)) }
 
 func (s *FakeStore) failure(operation string) error {
@@ -176,6 +214,24 @@ func (s *FakeStore) failure(operation string) error {
 	return s.failures[operation]
 }
 
+// pageSortedKeys returns up to limit keys after cursor and the cursor for the
+// next page, empty when no keys remain.
+func pageSortedKeys(keys []string, cursor string, limit int) ([]string, string) {
+	start := 0
+	if cursor != "" {
+		start = sort.SearchStrings(keys, cursor)
+		if start < len(keys) && keys[start] == cursor {
+			start++
+		}
+	}
+	end := min(start+limit, len(keys))
+	page := keys[start:end]
+	if end >= len(keys) || len(page) == 0 {
+		return page, ""
+	}
+	return page, page[len(page)-1]
+}
+
 func squaredDistance(a, b []float32) float32 {
 	var total float32
 	for i := range a {
@@ -185,60 +241,8 @@ func squaredDistance(a, b []float32) float32 {
 	return total
 }
 
-func metadataMatches(metadata map[string]any, filter map[string]any) bool {
-	if len(filter) == 0 {
-		return true
-	}
-	for key, expected := range filter {
-		actual, ok := metadata[key]
-		if !ok || !metadataValueMatches(actual, expected) {
-			return false
-		}
-	}
-	return true
-}
-
-func metadataValueMatches(actual, expected any) bool {
-	switch exp := expected.(type) {
-	case []any:
-		for _, one := range exp {
-			if metadataValueMatches(actual, one) {
-				return true
-			}
-		}
-		return false
-	case []string:
-		for _, one := range exp {
-			if metadataValueMatches(actual, one) {
-				return true
-			}
-		}
-		return false
-	case string:
-		switch act := actual.(type) {
-		case string:
-			return act == exp
-		case []string:
-			for _, one := range act {
-				if one == exp {
-					return true
-				}
-			}
-		case []any:
-			for _, one := range act {
-				if metadataValueMatches(one, exp) {
-					return true
-				}
-			}
-		}
-		return false
-	default:
-		return actual == expected
-	}
-}
-
 func cloneCall(call Call) Call {
-	return Call{Operation: call.Operation, Keys: cloneStrings(call.Keys), Records: cloneRecords(call.Records), Vector: CloneVector(call.Vector), TopK: call.TopK, Filter: CloneMetadata(call.Filter), ReturnMetadata: call.ReturnMetadata}
+	return Call{Operation: call.Operation, Keys: cloneStrings(call.Keys), Records: cloneRecords(call.Records), Vector: CloneVector(call.Vector), TopK: call.TopK, Filter: CloneMetadata(call.Filter), Where: cloneFilter(call.Where), ReturnMetadata: call.ReturnMetadata}
 }
 
 func cloneRecord(record VectorRecord) VectorRecord {
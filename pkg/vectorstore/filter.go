@@ -0,0 +1,547 @@
+package vectorstore
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type FilterOp string
+
+const (
+	FilterEq     FilterOp = "$eq"
+	FilterNe     FilterOp = "$ne"
+	FilterIn     FilterOp = "$in"
+	FilterNin    FilterOp = "$nin"
+	FilterGt     FilterOp = "$gt"
+	FilterGte    FilterOp = "$gte"
+	FilterLt     FilterOp = "$lt"
+	FilterLte    FilterOp = "$lte"
+	FilterExists FilterOp = "$exists"
+	FilterAnd    FilterOp = "$and"
+	FilterOr     FilterOp = "$or"
+	FilterNot    FilterOp = "$not"
+)
+
+// Filter is a metadata filter expression. Build one with Eq, In, And, and the
+// other constructors, or parse the S3 Vectors JSON syntax with ParseFilter.
+//
+// Every store applies the same semantics:
+//   - Eq and In match a list-valued field when any element matches.
+//   - Ne and Nin match when no element matches, including when the field is
+//     missing.
+//   - Gt, Gte, Lt, and Lte match numeric values only.
+//   - Not is rewritten with De Morgan's laws before evaluation, because S3
+//     Vectors has no $not; Not(Gt(f, n)) matches a missing f or f <= n.
+//
+// Values are strings, booleans, or finite numbers; numbers compare as float64.
+type Filter struct {
+	Op      FilterOp
+	Field   string
+	Value   any
+	Values  []any
+	Filters []Filter
+}
+
+func Eq(field string, value any) Filter  { return Filter{Op: FilterEq, Field: field, Value: value} }
+func Ne(field string, value any) Filter  { return Filter{Op: FilterNe, Field: field, Value: value} }
+func Gt(field string, value any) Filter  { return Filter{Op: FilterGt, Field: field, Value: value} }
+func Gte(field string, value any) Filter { return Filter{Op: FilterGte, Field: field, Value: value} }
+func Lt(field string, value any) Filter  { return Filter{Op: FilterLt, Field: field, Value: value} }
+func Lte(field string, value any) Filter { return Filter{Op: FilterLte, Field: field, Value: value} }
+
+func In(field string, values ...any) Filter {
+	return Filter{Op: FilterIn, Field: field, Values: values}
+}
+
+func Nin(field string, values ...any) Filter {
+	return Filter{Op: FilterNin, Field: field, Values: values}
+}
+
+func Exists(field string, exists bool) Filter {
+	return Filter{Op: FilterExists, Field: field, Value: exists}
+}
+
+func And(filters ...Filter) Filter { return Filter{Op: FilterAnd, Filters: filters} }
+func Or(filters ...Filter) Filter  { return Filter{Op: FilterOr, Filters: filters} }
+func Not(filter Filter) Filter     { return Filter{Op: FilterNot, Filters: []Filter{filter}} }
+
+var filterComplements = map[FilterOp]FilterOp{
+	FilterEq: FilterNe, FilterNe: FilterEq,
+	FilterIn: FilterNin, FilterNin: FilterIn,
+	FilterGt: FilterLte, FilterLte: FilterGt,
+	FilterGte: FilterLt, FilterLt: FilterGte,
+	FilterAnd: FilterOr, FilterOr: FilterAnd,
+}
+
+// ParseFilter parses the S3 Vectors filter syntax. A bare field value is
+// shorthand for $eq, a bare list for $in, and several top-level keys or
+// operators on one field are combined with $and.
+func ParseFilter(document map[string]any) (Filter, error) {
+	if len(document) == 0 {
+		return Filter{}, invalidFilter("filter is empty")
+	}
+	filters := make([]Filter, 0, len(document))
+	for _, key := range sortedFilterKeys(document) {
+		filter, err := parseFilterKey(key, document[key])
+		if err != nil {
+			return Filter{}, err
+		}
+		filters = append(filters, filter)
+	}
+	filter := And(filters...)
+	if len(filters) == 1 {
+		filter = filters[0]
+	}
+	if err := filter.Validate(); err != nil {
+		return Filter{}, err
+	}
+	return filter, nil
+}
+
+func parseFilterKey(key string, value any) (Filter, error) {
+	switch FilterOp(key) {
+	case FilterAnd, FilterOr:
+		items, ok := filterList(value)
+		if !ok {
+			return Filter{}, invalidFilter(key + " requires a list of filters")
+		}
+		children := make([]Filter, 0, len(items))
+		for _, item := range items {
+			document, ok := item.(map[string]any)
+			if !ok {
+				return Filter{}, invalidFilter(key + " requires a list of filters")
+			}
+			child, err := ParseFilter(document)
+			if err != nil {
+				return Filter{}, err
+			}
+			children = append(children, child)
+		}
+		return Filter{Op: FilterOp(key), Filters: children}, nil
+	case FilterNot:
+		document, ok := value.(map[string]any)
+		if !ok {
+			return Filter{}, invalidFilter("$not requires a filter")
+		}
+		child, err := ParseFilter(document)
+		return Not(child), err
+	}
+	if strings.HasPrefix(key, "$") {
+		return Filter{}, invalidFilter("unsupported filter operator: " + key)
+	}
+	return parseFieldFilter(key, value)
+}
+
+func parseFieldFilter(field string, value any) (Filter, error) {
+	operators, ok := value.(map[string]any)
+	if !ok {
+		if values, isList := filterList(value); isList {
+			return In(field, values...), nil
+		}
+		return Eq(field, value), nil
+	}
+	if len(operators) == 0 {
+		return Filter{}, invalidFilter("filter for " + field + " is empty")
+	}
+	filters := make([]Filter, 0, len(operators))
+	for _, op := range sortedFilterKeys(operators) {
+		filter, err := parseFieldOperator(field, FilterOp(op), operators[op])
+		if err != nil {
+			return Filter{}, err
+		}
+		filters = append(filters, filter)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return And(filters...), nil
+}
+
+func parseFieldOperator(field string, op FilterOp, value any) (Filter, error) {
+	switch op {
+	case FilterEq, FilterNe, FilterGt, FilterGte, FilterLt, FilterLte, FilterExists:
+		return Filter{Op: op, Field: field, Value: value}, nil
+	case FilterIn, FilterNin:
+		values, ok := filterList(value)
+		if !ok {
+			return Filter{}, invalidFilter(string(op) + " requires a list for " + field)
+		}
+		return Filter{Op: op, Field: field, Values: values}, nil
+	case FilterNot:
+		child, err := parseFieldFilter(field, value)
+		return Not(child), err
+	default:
+		return Filter{}, invalidFilter("unsupported filter operator: " + string(op))
+	}
+}
+
+// Validate reports whether f is well formed, returning ErrInvalidFilter when
+// it is not.
+func (f Filter) Validate() error {
+	_, err := f.normalize(false)
+	return err
+}
+
+// Match evaluates f against metadata with the same semantics every non-S3
+// store uses.
+func (f Filter) Match(metadata map[string]any) (bool, error) {
+	normalized, err := f.normalize(false)
+	if err != nil {
+		return false, err
+	}
+	return normalized.matches(metadata), nil
+}
+
+// S3Document translates f to an S3 Vectors QueryVectors filter document.
+// Ne and Nin are spelled out to match missing fields explicitly, so the
+// result does not depend on how S3 Vectors treats absent metadata.
+func (f Filter) S3Document() (map[string]any, error) {
+	normalized, err := f.normalize(false)
+	if err != nil {
+		return nil, err
+	}
+	return normalized.s3Document(), nil
+}
+
+// normalize validates f, canonicalizes its values, and pushes negation down to
+// the leaves so the result contains no FilterNot.
+func (f Filter) normalize(negate bool) (Filter, error) {
+	switch f.Op {
+	case FilterAnd, FilterOr:
+		if f.Field != "" || len(f.Filters) == 0 {
+			return Filter{}, invalidFilter(string(f.Op) + " requires at least one filter and no field")
+		}
+		op := f.Op
+		if negate {
+			op = filterComplements[op]
+		}
+		out := Filter{Op: op, Filters: make([]Filter, 0, len(f.Filters))}
+		for _, child := range f.Filters {
+			normalized, err := child.normalize(negate)
+			if err != nil {
+				return Filter{}, err
+			}
+			out.Filters = append(out.Filters, normalized)
+		}
+		return out, nil
+	case FilterNot:
+		if f.Field != "" || len(f.Filters) != 1 {
+			return Filter{}, invalidFilter("$not requires exactly one filter and no field")
+		}
+		return f.Filters[0].normalize(!negate)
+	default:
+		return f.normalizeLeaf(negate)
+	}
+}
+
+func (f Filter) normalizeLeaf(negate bool) (Filter, error) {
+	if strings.TrimSpace(f.Field) == "" || strings.HasPrefix(f.Field, "$") {
+		return Filter{}, invalidFilter("filter field is invalid: " + f.Field)
+	}
+	if len(f.Filters) > 0 {
+		return Filter{}, invalidFilter(string(f.Op) + " does not take nested filters")
+	}
+	out := Filter{Op: f.Op, Field: f.Field}
+	var err error
+	if f.Op == FilterIn || f.Op == FilterNin {
+		out.Values, err = f.leafValues()
+	} else {
+		out.Value, err = f.leafValue()
+	}
+	if err != nil {
+		return Filter{}, err
+	}
+	if negate {
+		return out.negated(), nil
+	}
+	return out, nil
+}
+
+func (f Filter) leafValue() (any, error) {
+	if f.Values != nil {
+		return nil, invalidFilter(string(f.Op) + " takes a single value for " + f.Field)
+	}
+	switch f.Op {
+	case FilterEq, FilterNe:
+		if value, ok := filterScalar(f.Value); ok {
+			return value, nil
+		}
+		return nil, invalidFilter(string(f.Op) + " requires a string, number, or boolean for " + f.Field)
+	case FilterGt, FilterGte, FilterLt, FilterLte:
+		if value, ok := filterNumber(f.Value); ok {
+			return value, nil
+		}
+		return nil, invalidFilter(string(f.Op) + " requires a number for " + f.Field)
+	case FilterExists:
+		if value, ok := f.Value.(bool); ok {
+			return value, nil
+		}
+		return nil, invalidFilter("$exists requires a boolean for " + f.Field)
+	default:
+		return nil, invalidFilter("unsupported filter operator: " + string(f.Op))
+	}
+}
+
+func (f Filter) leafValues() ([]any, error) {
+	if len(f.Values) == 0 || f.Value != nil {
+		return nil, invalidFilter(string(f.Op) + " requires at least one value for " + f.Field)
+	}
+	out := make([]any, 0, len(f.Values))
+	for _, item := range f.Values {
+		value, ok := filterScalar(item)
+		if !ok {
+			return nil, invalidFilter(string(f.Op) + " requires strings, numbers, or booleans for " + f.Field)
+		}
+		out = append(out, value)
+	}
+	return out, nil
+}
+
+func (f Filter) negated() Filter {
+	switch f.Op {
+	case FilterExists:
+		f.Value = f.Value != true
+		return f
+	case FilterGt, FilterGte, FilterLt, FilterLte:
+		f.Op = filterComplements[f.Op]
+		return Or(Exists(f.Field, false), f)
+	default:
+		f.Op = filterComplements[f.Op]
+		return f
+	}
+}
+
+// matches evaluates a normalized filter.
+func (f Filter) matches(metadata map[string]any) bool {
+	switch f.Op {
+	case FilterAnd:
+		return f.matchesAll(metadata)
+	case FilterOr:
+		return f.matchesAny(metadata)
+	}
+	actual, present := metadata[f.Field]
+	switch f.Op {
+	case FilterExists:
+		return present == (f.Value == true)
+	case FilterEq:
+		return present && filterContains(actual, f.Value)
+	case FilterNe:
+		return !present || !filterContains(actual, f.Value)
+	case FilterIn:
+		return present && filterContainsAny(actual, f.Values)
+	case FilterNin:
+		return !present || !filterContainsAny(actual, f.Values)
+	default:
+		return present && f.matchesRange(actual)
+	}
+}
+
+func (f Filter) matchesAll(metadata map[string]any) bool {
+	for _, child := range f.Filters {
+		if !child.matches(metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f Filter) matchesAny(metadata map[string]any) bool {
+	for _, child := range f.Filters {
+		if child.matches(metadata) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Filter) matchesRange(actual any) bool {
+	number, ok := filterNumber(actual)
+	bound, boundOK := f.Value.(float64)
+	if !ok || !boundOK {
+		return false
+	}
+	switch f.Op {
+	case FilterGt:
+		return number > bound
+	case FilterGte:
+		return number >= bound
+	case FilterLt:
+		return number < bound
+	default:
+		return number <= bound
+	}
+}
+
+// s3Document renders a normalized filter.
+func (f Filter) s3Document() map[string]any {
+	switch f.Op {
+	case FilterAnd, FilterOr:
+		children := make([]any, 0, len(f.Filters))
+		for _, child := range f.Filters {
+			children = append(children, child.s3Document())
+		}
+		return map[string]any{string(f.Op): children}
+	case FilterIn:
+		return map[string]any{f.Field: map[string]any{string(f.Op): append([]any(nil), f.Values...)}}
+	case FilterNin:
+		return filterMissingOr(f.Field, map[string]any{string(f.Op): append([]any(nil), f.Values...)})
+	case FilterNe:
+		return filterMissingOr(f.Field, map[string]any{string(f.Op): f.Value})
+	default:
+		return map[string]any{f.Field: map[string]any{string(f.Op): f.Value}}
+	}
+}
+
+func filterMissingOr(field string, condition map[string]any) map[string]any {
+	return map[string]any{string(FilterOr): []any{
+		map[string]any{field: map[string]any{string(FilterExists): false}},
+		map[string]any{field: condition},
+	}}
+}
+
+// queryFilter resolves QueryInput.Where or QueryInput.Filter to a normalized
+// filter, or nil when the query is unfiltered.
+func queryFilter(input QueryInput) (*Filter, error) {
+	if input.Where != nil && len(input.Filter) > 0 {
+		return nil, invalidFilter("set Filter or Where, not both")
+	}
+	var filter Filter
+	switch {
+	case input.Where != nil:
+		filter = *input.Where
+	case len(input.Filter) > 0:
+		parsed, err := ParseFilter(input.Filter)
+		if err != nil {
+			return nil, err
+		}
+		filter = parsed
+	default:
+		return nil, nil
+	}
+	normalized, err := filter.normalize(false)
+	if err != nil {
+		return nil, err
+	}
+	return &normalized, nil
+}
+
+func filterMatches(filter *Filter, metadata map[string]any) bool {
+	return filter == nil || filter.matches(metadata)
+}
+
+func filterContains(actual, value any) bool {
+	if items, ok := filterList(actual); ok {
+		for _, item := range items {
+			if filterEqual(item, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return filterEqual(actual, value)
+}
+
+func filterContainsAny(actual any, values []any) bool {
+	for _, value := range values {
+		if filterContains(actual, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterEqual(actual, value any) bool {
+	normalized, ok := filterScalar(actual)
+	return ok && normalized == value
+}
+
+// filterScalar canonicalizes strings, booleans, and finite numbers; numbers
+// become float64 so 1, int64(1), and 1.0 compare equal.
+func filterScalar(value any) (any, bool) {
+	switch typed := value.(type) {
+	case string, bool:
+		return typed, true
+	}
+	return filterNumber(value)
+}
+
+func filterNumber(value any) (float64, bool) {
+	var number float64
+	switch typed := value.(type) {
+	case json.Number:
+		parsed, err := typed.Float64()
+		if err != nil {
+			return 0, false
+		}
+		number = parsed
+	default:
+		v := reflect.ValueOf(value)
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			number = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			number = float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			number = v.Float()
+		default:
+			return 0, false
+		}
+	}
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, false
+	}
+	return number, true
+}
+
+func filterList(value any) ([]any, bool) {
+	switch typed := value.(type) {
+	case []any:
+		return typed, true
+	case []string:
+		out := make([]any, len(typed))
+		for i, item := range typed {
+			out[i] = item
+		}
+		return out, true
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	out := make([]any, v.Len())
+	for i := range out {
+		out[i] = v.Index(i).Interface()
+	}
+	return out, true
+}
+
+func cloneFilter(filter *Filter) *Filter {
+	if filter == nil {
+		return nil
+	}
+	out := *filter
+	out.Values = append([]any(nil), filter.Values...)
+	if filter.Filters != nil {
+		out.Filters = make([]Filter, len(filter.Filters))
+		for i := range filter.Filters {
+			out.Filters[i] = *cloneFilter(&filter.Filters[i])
+		}
+	}
+	return &out
+}
+
+func sortedFilterKeys(document map[string]any) []string {
+	keys := make([]string, 0, len(document))
+	for key := range document {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func invalidFilter(message string) error {
+	return NewError(ErrorCodeInvalidFilter, "vectorstore: "+message, nil)
+}
@@ -0,0 +1,238 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3vectors"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3vectors/types"
+)
+
+func TestFilterSemantics(t *testing.T) {
+	metadata := map[string]any{"tenant": "t1", "tags": []string{"a", "b"}, "year": 2024, "score": 0.5, "draft": false}
+	cases := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"eq scalar", Eq("tenant", "t1"), true},
+		{"eq list element", Eq("tags", "b"), true},
+		{"eq number across types", Eq("year", 2024.0), true},
+		{"eq bool", Eq("draft", false), true},
+		{"eq missing", Eq("missing", "x"), false},
+		{"ne list", Ne("tags", "c"), true},
+		{"ne list element", Ne("tags", "a"), false},
+		{"ne missing", Ne("missing", "x"), true},
+		{"in", In("tenant", "t0", "t1"), true},
+		{"in list", In("tags", "z", "a"), true},
+		{"nin", Nin("tags", "z"), true},
+		{"nin hit", Nin("tags", "z", "a"), false},
+		{"nin missing", Nin("missing", "z"), true},
+		{"gt", Gt("year", 2023), true},
+		{"gte", Gte("score", 0.5), true},
+		{"lt", Lt("score", 0.5), false},
+		{"lte", Lte("year", int64(2024)), true},
+		{"range on string", Gt("tenant", 0), false},
+		{"range missing", Lt("missing", 10), false},
+		{"exists", Exists("draft", true), true},
+		{"not exists", Exists("missing", false), true},
+		{"and", And(Eq("tenant", "t1"), Gt("year", 2000)), true},
+		{"or", Or(Eq("tenant", "t2"), Eq("tags", "a")), true},
+		{"not eq", Not(Eq("tenant", "t1")), false},
+		{"not gt", Not(Gt("year", 2030)), true},
+		{"not gt missing", Not(Gt("missing", 1)), true},
+		{"not gt string", Not(Gt("tenant", 1)), false},
+		{"not and", Not(And(Eq("tenant", "t1"), Eq("draft", true))), true},
+		{"not not", Not(Not(In("tags", "b"))), true},
+		{"not exists true", Not(Exists("tenant", true)), false},
+	}
+	for _, tc := range cases {
+		got, err := tc.filter.Match(metadata)
+		if err != nil || got != tc.want {
+			t.Fatalf("%s: Match() = %v, %v, want %v", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseFilterAndS3Translation(t *testing.T) {
+	var document map[string]any
+	if err := json.Unmarshal([]byte(`{
+		"tenant": "t1",
+		"tags": ["a", "b"],
+		"year": {"$gte": 2020, "$lt": 2025},
+		"$or": [{"draft": {"$exists": false}}, {"$not": {"draft": true}}]
+	}`), &document); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	filter, err := ParseFilter(document)
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	want := And(
+		Or(Exists("draft", false), Not(Eq("draft", true))),
+		In("tags", "a", "b"),
+		Eq("tenant", "t1"),
+		And(Gte("year", 2020.0), Lt("year", 2025.0)),
+	)
+	if !reflect.DeepEqual(filter, want) {
+		t.Fatalf("ParseFilter() = %#v, want %#v", filter, want)
+	}
+
+	s3, err := filter.S3Document()
+	if err != nil {
+		t.Fatalf("S3Document() error = %v", err)
+	}
+	missingOr := func(field string, condition map[string]any) map[string]any {
+		return map[string]any{"$or": []any{
+			map[string]any{field: map[string]any{"$exists": false}},
+			map[string]any{field: condition},
+		}}
+	}
+	wantS3 := map[string]any{"$and": []any{
+		map[string]any{"$or": []any{
+			map[string]any{"draft": map[string]any{"$exists": false}},
+			missingOr("draft", map[string]any{"$ne": true}),
+		}},
+		map[string]any{"tags": map[string]any{"$in": []any{"a", "b"}}},
+		map[string]any{"tenant": map[string]any{"$eq": "t1"}},
+		map[string]any{"$and": []any{
+			map[string]any{"year": map[string]any{"$gte": 2020.0}},
+			map[string]any{"year": map[string]any{"$lt": 2025.0}},
+		}},
+	}}
+	if !reflect.DeepEqual(s3, wantS3) {
+		t.Fatalf("S3Document() = %#v, want %#v", s3, wantS3)
+	}
+
+	negated, err := Not(Or(Lte("year", 1), Nin("tags", "x"))).S3Document()
+	if err != nil {
+		t.Fatalf("S3Document() negated error = %v", err)
+	}
+	wantNegated := map[string]any{"$and": []any{
+		map[string]any{"$or": []any{
+			map[string]any{"year": map[string]any{"$exists": false}},
+			map[string]any{"year": map[string]any{"$gt": 1.0}},
+		}},
+		map[string]any{"tags": map[string]any{"$in": []any{"x"}}},
+	}}
+	if !reflect.DeepEqual(negated, wantNegated) {
+		t.Fatalf("S3Document() negated = %#v, want %#v", negated, wantNegated)
+	}
+}
+
+func TestFilterValidationFailsClosed(t *testing.T) {
+	invalid := []Filter{
+		{},
+		Eq("", "x"),
+		Eq("$tenant", "x"),
+		Eq("tenant", map[string]any{"nested": true}),
+		Eq("tenant", nil),
+		In("tenant"),
+		In("tenant", []string{"nested"}),
+		Gt("year", "2020"),
+		Lt("score", math.NaN()),
+		Exists("tenant", false).withValue("yes"),
+		And(),
+		Or(Eq("tenant", "t1"), In("tags")),
+		{Op: FilterNot},
+		{Op: "$regex", Field: "tenant", Value: "t.*"},
+	}
+	for _, filter := range invalid {
+		if err := filter.Validate(); !errors.Is(err, ErrInvalidFilter) {
+			t.Fatalf("Validate(%#v) error = %v, want ErrInvalidFilter", filter, err)
+		}
+	}
+	for _, document := range []map[string]any{
+		nil,
+		{"$regex": "x"},
+		{"$and": "x"},
+		{"$or": []any{"x"}},
+		{"$not": "x"},
+		{"tenant": map[string]any{}},
+		{"tenant": map[string]any{"$in": "t1"}},
+		{"tenant": map[string]any{"$like": "t"}},
+	} {
+		if _, err := ParseFilter(document); !errors.Is(err, ErrInvalidFilter) {
+			t.Fatalf("ParseFilter(%v) error = %v, want ErrInvalidFilter", document, err)
+		}
+	}
+}
+
+func TestStoresApplyFiltersConsistently(t *testing.T) {
+	ctx := context.Background()
+	records := []VectorRecord{
+		{Key: "a", Data: []float32{1, 0}, Metadata: map[string]any{"tenant": "t1", "year": 2020, "tags": []string{"x"}}},
+		{Key: "b", Data: []float32{1, 0.1}, Metadata: map[string]any{"tenant": "t1", "year": 2024}},
+		{Key: "c", Data: []float32{1, 0.2}, Metadata: map[string]any{"tenant": "t2", "year": 2024, "tags": []string{"y"}}},
+	}
+	fake := NewFakeStore(2)
+	local, err := NewLocalStore(ctx, LocalStoreConfig{Dimension: 2})
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	where := And(Gte("year", 2021), Nin("tags", "y"))
+	for name, store := range map[string]Store{"fake": fake, "local": local} {
+		if err := store.PutVectors(ctx, PutInput{Records: records}); err != nil {
+			t.Fatalf("%s PutVectors() error = %v", name, err)
+		}
+		hits, err := store.QueryVectors(ctx, QueryInput{Vector: []float32{1, 0}, Where: &where})
+		if err != nil || len(hits) != 1 || hits[0].Key != "b" {
+			t.Fatalf("%s QueryVectors(Where) = %#v, %v, want b", name, hits, err)
+		}
+		hits, err = store.QueryVectors(ctx, QueryInput{Vector: []float32{1, 0}, Filter: map[string]any{"tenant": "t1", "year": map[string]any{"$lt": 2021}}})
+		if err != nil || len(hits) != 1 || hits[0].Key != "a" {
+			t.Fatalf("%s QueryVectors(Filter) = %#v, %v, want a", name, hits, err)
+		}
+		if _, err := store.QueryVectors(ctx, QueryInput{Vector: []float32{1, 0}, Filter: map[string]any{"tenant": "t1"}, Where: &where}); !errors.Is(err, ErrInvalidFilter) {
+			t.Fatalf("%s QueryVectors(Filter and Where) error = %v, want ErrInvalidFilter", name, err)
+		}
+		if _, err := store.QueryVectors(ctx, QueryInput{Vector: []float32{1, 0}, Filter: map[string]any{"year": map[string]any{"$gt": "2020"}}}); !errors.Is(err, ErrInvalidFilter) {
+			t.Fatalf("%s QueryVectors(invalid filter) error = %v, want ErrInvalidFilter", name, err)
+		}
+	}
+	if calls := fake.Calls(); len(calls) != 3 || calls[1].Where == nil || calls[1].Where == &where {
+		t.Fatalf("FakeStore did not record a cloned Where filter")
+	}
+
+	client := &recordingS3VectorsClient{queryOutput: &s3vectors.QueryVectorsOutput{Vectors: []s3types.QueryOutputVector{{Key: aws.String("b")}}}}
+	s3 := &S3VectorStore{Client: client, VectorBucketName: "vectors", IndexName: "semantic", Dimension: 2}
+	if _, err := s3.QueryVectors(ctx, QueryInput{Vector: []float32{1, 0}, Where: &where}); err != nil {
+		t.Fatalf("S3 QueryVectors() error = %v", err)
+	}
+	raw, err := client.queryInputs[0].Filter.MarshalSmithyDocument()
+	if err != nil {
+		t.Fatalf("filter marshal error = %v", err)
+	}
+	var sent map[string]any
+	if err := json.Unmarshal(raw, &sent); err != nil {
+		t.Fatalf("filter JSON error = %v", err)
+	}
+	wantDocument, err := where.S3Document()
+	if err != nil {
+		t.Fatalf("S3Document() error = %v", err)
+	}
+	wantRaw, err := json.Marshal(wantDocument)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var want map[string]any
+	if err := json.Unmarshal(wantRaw, &want); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(sent, want) {
+		t.Fatalf("S3 filter = %v, want %v", sent, want)
+	}
+	if _, err := s3.QueryVectors(ctx, QueryInput{Vector: []float32{1, 0}, Filter: map[string]any{"$regex": "x"}}); !errors.Is(err, ErrInvalidFilter) || len(client.queryInputs) != 1 {
+		t.Fatalf("S3 QueryVectors(invalid filter) error = %v, want ErrInvalidFilter before calling S3", err)
+	}
+}
+
+func (f Filter) withValue(value any) Filter {
+	f.Value = value
+	return f
+}
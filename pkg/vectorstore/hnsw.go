@@ -0,0 +1,262 @@
+package vectorstore
+
+import (
+	"container/heap"
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// hnswGraph is a hierarchical navigable small world graph (Malkov & Yashunin).
+// Deleted nodes stay in the graph as tombstones so traversal keeps working; the
+// owning store rebuilds the graph once tombstones dominate.
+type hnswGraph struct {
+	metric         DistanceMetric
+	m              int
+	m0             int
+	efConstruction int
+	levelMult      float64
+	rng            *rand.Rand
+	nodes          []*hnswNode
+	entry          int32
+	maxLevel       int
+	deleted        int
+}
+
+type hnswNode struct {
+	key       string
+	data      []float32
+	norm      float32
+	metadata  map[string]any
+	neighbors [][]int32
+	deleted   bool
+}
+
+type hnswCandidate struct {
+	id   int32
+	dist float32
+}
+
+func newHNSWGraph(metric DistanceMetric, m, efConstruction int) *hnswGraph {
+	return &hnswGraph{
+		metric:         metric,
+		m:              m,
+		m0:             2 * m,
+		efConstruction: efConstruction,
+		levelMult:      1 / math.Log(float64(m)),
+		// #nosec G404 -- level assignment only needs a deterministic spread, not secrecy.
+		rng:   rand.New(rand.NewPCG(0x61707074, 0x68656f72)),
+		entry: -1,
+	}
+}
+
+func (g *hnswGraph) live() int { return len(g.nodes) - g.deleted }
+
+func (g *hnswGraph) randomLevel() int {
+	return int(math.Floor(-math.Log(1-g.rng.Float64()) * g.levelMult))
+}
+
+func (g *hnswGraph) distance(q []float32, qnorm float32, n *hnswNode) float32 {
+	return vectorDistance(g.metric, q, qnorm, n.data, n.norm)
+}
+
+func (g *hnswGraph) nodeDistance(a, b int32) float32 {
+	na := g.nodes[a]
+	return g.distance(na.data, na.norm, g.nodes[b])
+}
+
+// insert adds node at a random level and links it into every layer it joins.
+func (g *hnswGraph) insert(node *hnswNode) int32 {
+	id := int32(len(g.nodes)) // #nosec G115 -- stores are bounded far below MaxInt32 nodes.
+	level := g.randomLevel()
+	node.neighbors = make([][]int32, level+1)
+	g.nodes = append(g.nodes, node)
+	if g.entry < 0 {
+		g.entry, g.maxLevel = id, level
+		return id
+	}
+
+	ep := hnswCandidate{id: g.entry, dist: g.distance(node.data, node.norm, g.nodes[g.entry])}
+	for l := g.maxLevel; l > level; l-- {
+		ep = g.greedy(node.data, node.norm, ep, l)
+	}
+	for l := min(level, g.maxLevel); l >= 0; l-- {
+		candidates := g.searchLayer(node.data, node.norm, ep, g.efConstruction, l)
+		selected := g.selectNeighbors(candidates, g.m)
+		node.neighbors[l] = selected
+		for _, neighbor := range selected {
+			g.connect(neighbor, id, l)
+		}
+		ep = candidates[0]
+	}
+	if level > g.maxLevel {
+		g.entry, g.maxLevel = id, level
+	}
+	return id
+}
+
+func (g *hnswGraph) connect(from, to int32, level int) {
+	node := g.nodes[from]
+	maxConn := g.m
+	if level == 0 {
+		maxConn = g.m0
+	}
+	node.neighbors[level] = append(node.neighbors[level], to)
+	if len(node.neighbors[level]) <= maxConn {
+		return
+	}
+	candidates := make([]hnswCandidate, 0, len(node.neighbors[level]))
+	for _, neighbor := range node.neighbors[level] {
+		candidates = append(candidates, hnswCandidate{id: neighbor, dist: g.nodeDistance(from, neighbor)})
+	}
+	sortCandidates(candidates)
+	node.neighbors[level] = g.selectNeighbors(candidates, maxConn)
+}
+
+// selectNeighbors applies the HNSW diversity heuristic to distance-sorted
+// candidates, then tops up with the closest pruned candidates.
+func (g *hnswGraph) selectNeighbors(candidates []hnswCandidate, m int) []int32 {
+	selected := make([]int32, 0, m)
+	var pruned []int32
+	for _, candidate := range candidates {
+		if len(selected) == m {
+			break
+		}
+		diverse := true
+		for _, chosen := range selected {
+			if g.nodeDistance(candidate.id, chosen) < candidate.dist {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, candidate.id)
+		} else {
+			pruned = append(pruned, candidate.id)
+		}
+	}
+	for _, id := range pruned {
+		if len(selected) == m {
+			break
+		}
+		selected = append(selected, id)
+	}
+	return selected
+}
+
+func (g *hnswGraph) greedy(q []float32, qnorm float32, ep hnswCandidate, level int) hnswCandidate {
+	for changed := true; changed; {
+		changed = false
+		for _, neighbor := range g.nodes[ep.id].neighbors[level] {
+			if d := g.distance(q, qnorm, g.nodes[neighbor]); d < ep.dist {
+				ep, changed = hnswCandidate{id: neighbor, dist: d}, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer returns up to ef nearest nodes on one layer, closest first.
+func (g *hnswGraph) searchLayer(q []float32, qnorm float32, ep hnswCandidate, ef int, level int) []hnswCandidate {
+	visited := map[int32]struct{}{ep.id: {}}
+	candidates := &minCandidateHeap{ep}
+	results := &maxCandidateHeap{ep}
+	for candidates.Len() > 0 {
+		current, ok := heap.Pop(candidates).(hnswCandidate)
+		if !ok || current.dist > (*results)[0].dist && results.Len() >= ef {
+			break
+		}
+		for _, neighbor := range g.nodes[current.id].neighbors[level] {
+			if _, seen := visited[neighbor]; seen {
+				continue
+			}
+			visited[neighbor] = struct{}{}
+			d := g.distance(q, qnorm, g.nodes[neighbor])
+			if results.Len() < ef || d < (*results)[0].dist {
+				heap.Push(candidates, hnswCandidate{id: neighbor, dist: d})
+				heap.Push(results, hnswCandidate{id: neighbor, dist: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+	out := []hnswCandidate(*results)
+	sortCandidates(out)
+	return out
+}
+
+// search returns up to ef live nodes near q, closest first.
+func (g *hnswGraph) search(q []float32, qnorm float32, ef int) []hnswCandidate {
+	if g.entry < 0 {
+		return nil
+	}
+	ep := hnswCandidate{id: g.entry, dist: g.distance(q, qnorm, g.nodes[g.entry])}
+	for l := g.maxLevel; l > 0; l-- {
+		ep = g.greedy(q, qnorm, ep, l)
+	}
+	return g.searchLayer(q, qnorm, ep, ef, 0)
+}
+
+func sortCandidates(candidates []hnswCandidate) {
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+}
+
+func vectorDistance(metric DistanceMetric, a []float32, anorm float32, b []float32, bnorm float32) float32 {
+	if metric == DistanceMetricEuclidean {
+		var total float64
+		for i := range a {
+			d := float64(a[i]) - float64(b[i])
+			total += d * d
+		}
+		return float32(math.Sqrt(total))
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	distance := 1 - dot/(float64(anorm)*float64(bnorm))
+	return float32(math.Max(0, math.Min(2, distance)))
+}
+
+func vectorNorm(v []float32) float32 {
+	var total float64
+	for _, value := range v {
+		total += float64(value) * float64(value)
+	}
+	return float32(math.Sqrt(total))
+}
+
+type minCandidateHeap []hnswCandidate
+
+func (h minCandidateHeap) Len() int           { return len(h) }
+func (h minCandidateHeap) Less(i, j int) bool { return h[i].dist < h[j].dist }
+func (h minCandidateHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minCandidateHeap) Push(x any) {
+	if candidate, ok := x.(hnswCandidate); ok {
+		*h = append(*h, candidate)
+	}
+}
+func (h *minCandidateHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+type maxCandidateHeap []hnswCandidate
+
+func (h maxCandidateHeap) Len() int           { return len(h) }
+func (h maxCandidateHeap) Less(i, j int) bool { return h[i].dist > h[j].dist }
+func (h maxCandidateHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxCandidateHeap) Push(x any) {
+	if candidate, ok := x.(hnswCandidate); ok {
+		*h = append(*h, candidate)
+	}
+}
+func (h *maxCandidateHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,201 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+type FusionMethod string
+
+const (
+	FusionRRF      FusionMethod = "rrf"
+	FusionWeighted FusionMethod = "weighted"
+)
+
+const (
+	RetrieverVector  = "vector"
+	RetrieverLexical = "lexical"
+)
+
+const (
+	// DefaultRRFK is the rank constant from the original reciprocal rank
+	// fusion paper.
+	DefaultRRFK = 60
+	// DefaultHybridCandidates is how many hits each retriever contributes
+	// before fusion, unless TopK is larger.
+	DefaultHybridCandidates = 50
+)
+
+// HybridQueryInput configures SemanticIndex.HybridQuery. Fusion defaults to
+// FusionRRF. VectorWeight and LexicalWeight default to 1 when both are zero;
+// set one to zero to turn its retriever off for this query. Candidates is the
+// number of hits fetched from each retriever, DefaultHybridCandidates by
+// default and never fewer than TopK.
+type HybridQueryInput struct {
+	TopK           int
+	Filter         map[string]any
+	Where          *Filter
+	ReturnMetadata bool
+	Fusion         FusionMethod
+	VectorWeight   float64
+	LexicalWeight  float64
+	RRFK           int
+	Candidates     int
+}
+
+// ScoreComponent explains one retriever's part in a fused score. Rank is
+// 1-based within that retriever's candidates. Raw is the vector distance or
+// BM25 score. Normalized is set for FusionWeighted: the raw value scaled to
+// [0, 1] across the candidates, with 1 the best.
+type ScoreComponent struct {
+	Retriever    string  `json:"retriever"`
+	Rank         int     `json:"rank"`
+	Raw          float64 `json:"raw"`
+	Normalized   float64 `json:"normalized,omitempty"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"`
+}
+
+// Explain describes how the hit's Score was assembled, one clause per
+// component.
+func (h QueryHit) Explain() string {
+	if len(h.Components) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(h.Components))
+	for _, c := range h.Components {
+		raw := "score"
+		if c.Retriever == RetrieverVector {
+			raw = "distance"
+		}
+		parts = append(parts, fmt.Sprintf("%s rank %d (%s %.4f) x weight %.2f = %.4f", c.Retriever, c.Rank, raw, c.Raw, c.Weight, c.Contribution))
+	}
+	return fmt.Sprintf("score %.4f = %s", h.Score, strings.Join(parts, " + "))
+}
+
+// HybridQuery runs QueryText and the Lexical index for text and fuses the two
+// ranked lists. FusionRRF scores each hit as the sum of weight / (RRFK + rank)
+// and needs no score calibration. FusionWeighted min-max normalizes each
+// retriever's raw scores and sums weight * normalized. Hits are ordered by
+// Score, highest first; Distance is set when the vector retriever returned the
+// hit.
+func (i *SemanticIndex) HybridQuery(ctx context.Context, text string, input HybridQueryInput) ([]QueryHit, error) {
+	if i == nil || i.Store == nil || i.Embedder == nil || i.Lexical == nil {
+		return nil, ErrInvalidConfig
+	}
+	plan, err := newHybridPlan(input)
+	if err != nil {
+		return nil, err
+	}
+	var vectorHits, lexicalHits []QueryHit
+	if plan.vectorWeight > 0 {
+		vectorHits, err = i.QueryText(ctx, text, QueryInput{TopK: plan.candidates, Filter: input.Filter, Where: input.Where, ReturnMetadata: input.ReturnMetadata})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if plan.lexicalWeight > 0 {
+		lexicalHits, err = i.Lexical.Query(ctx, LexicalQueryInput{Text: text, TopK: plan.candidates, Filter: input.Filter, Where: input.Where, ReturnMetadata: input.ReturnMetadata})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return plan.fuse(vectorHits, lexicalHits), nil
+}
+
+type hybridPlan struct {
+	fusion        FusionMethod
+	vectorWeight  float64
+	lexicalWeight float64
+	rrfK          int
+	candidates    int
+	topK          int
+}
+
+func newHybridPlan(input HybridQueryInput) (hybridPlan, error) {
+	plan := hybridPlan{fusion: input.Fusion, vectorWeight: input.VectorWeight, lexicalWeight: input.LexicalWeight, rrfK: positiveOr(input.RRFK, DefaultRRFK), topK: NormalizeTopK(input.TopK)}
+	if plan.fusion == "" {
+		plan.fusion = FusionRRF
+	}
+	if plan.fusion != FusionRRF && plan.fusion != FusionWeighted {
+		return plan, NewError(ErrorCodeInvalidInput, "vectorstore: unsupported fusion method: "+string(plan.fusion), nil)
+	}
+	if plan.vectorWeight == 0 && plan.lexicalWeight == 0 {
+		plan.vectorWeight, plan.lexicalWeight = 1, 1
+	}
+	for _, weight := range []float64{plan.vectorWeight, plan.lexicalWeight} {
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return plan, NewError(ErrorCodeInvalidInput, "vectorstore: hybrid weights must be finite and non-negative", nil)
+		}
+	}
+	if input.Candidates < 0 || input.RRFK < 0 {
+		return plan, NewError(ErrorCodeInvalidInput, "vectorstore: hybrid candidates and RRF k must not be negative", nil)
+	}
+	plan.candidates = NormalizeTopK(max(positiveOr(input.Candidates, DefaultHybridCandidates), plan.topK))
+	return plan, nil
+}
+
+func (p hybridPlan) fuse(vectorHits, lexicalHits []QueryHit) []QueryHit {
+	fused := map[string]*QueryHit{}
+	var order []string
+	add := func(retriever string, weight float64, hits []QueryHit, raw func(QueryHit) float64, normalize func(float64) float64) {
+		for rank, hit := range hits {
+			out, ok := fused[hit.Key]
+			if !ok {
+				out = &QueryHit{Key: hit.Key, Metadata: hit.Metadata}
+				fused[hit.Key] = out
+				order = append(order, hit.Key)
+			}
+			if retriever == RetrieverVector {
+				out.Distance = hit.Distance
+			}
+			if out.Metadata == nil {
+				out.Metadata = hit.Metadata
+			}
+			component := ScoreComponent{Retriever: retriever, Rank: rank + 1, Raw: raw(hit), Weight: weight}
+			if p.fusion == FusionRRF {
+				component.Contribution = weight / float64(p.rrfK+rank+1)
+			} else {
+				component.Normalized = normalize(component.Raw)
+				component.Contribution = weight * component.Normalized
+			}
+			out.Score += component.Contribution
+			out.Components = append(out.Components, component)
+		}
+	}
+	distance := func(hit QueryHit) float64 { return float64(hit.Distance) }
+	score := func(hit QueryHit) float64 { return hit.Score }
+	add(RetrieverVector, p.vectorWeight, vectorHits, distance, minMaxNormalizer(vectorHits, distance, true))
+	add(RetrieverLexical, p.lexicalWeight, lexicalHits, score, minMaxNormalizer(lexicalHits, score, false))
+
+	hits := make([]QueryHit, 0, len(order))
+	for _, key := range order {
+		hits = append(hits, *fused[key])
+	}
+	sortByScore(hits)
+	if len(hits) > p.topK {
+		hits = hits[:p.topK]
+	}
+	return hits
+}
+
+// minMaxNormalizer scales raw values to [0, 1] across hits, with 1 the best.
+// When every hit has the same value, each normalizes to 1.
+func minMaxNormalizer(hits []QueryHit, raw func(QueryHit) float64, lowerIsBetter bool) func(float64) float64 {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, hit := range hits {
+		value := raw(hit)
+		low, high = math.Min(low, value), math.Max(high, value)
+	}
+	return func(value float64) float64 {
+		if high <= low {
+			return 1
+		}
+		if lowerIsBetter {
+			return (high - value) / (high - low)
+		}
+		return (value - low) / (high - low)
+	}
+}
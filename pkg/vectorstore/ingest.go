@@ -0,0 +1,346 @@
+package vectorstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Metadata keys the ingestion pipeline adds to every chunk. Documents may not
+// set them.
+const (
+	MetadataParentKey    = "parent_key"
+	MetadataChunkIndex   = "chunk_index"
+	MetadataChunkCount   = "chunk_count"
+	MetadataChunkSHA256  = "chunk_sha256"
+	MetadataChunkSection = "chunk_section"
+)
+
+const (
+	// MaxDocumentChunks bounds the chunks one document may produce, which keeps
+	// chunk keys at four digits.
+	MaxDocumentChunks = 10000
+	// getChunkBatchSize matches the S3 Vectors GetVectors key limit.
+	getChunkBatchSize = 100
+)
+
+// SemanticDocument is a whole document for PutDocuments to chunk and embed.
+type SemanticDocument struct {
+	Key      string
+	Text     string
+	Metadata map[string]any
+}
+
+// IngestReport counts what PutDocuments did. Embedded chunks were new or had
+// changed text; Rewritten chunks reused a stored vector under new metadata or
+// a new position.
+type IngestReport struct {
+	Documents int
+	Chunks    int
+	Embedded  int
+	Rewritten int
+	Unchanged int
+	Deleted   int
+}
+
+// DocumentHit groups the chunk hits for one parent document. Distance is the
+// closest chunk's distance.
+type DocumentHit struct {
+	Key      string
+	Distance float32
+	Chunks   []QueryHit
+}
+
+// ChunkKey returns the vector key PutDocuments uses for chunk index of the
+// document parentKey.
+func ChunkKey(parentKey string, index int) string {
+	return fmt.Sprintf("%s#%04d", parentKey, index)
+}
+
+type plannedChunk struct {
+	record VectorRecord
+	text   string
+}
+
+// PutDocuments splits each document with Splitter (a TokenSplitter with
+// DefaultChunkTokens and DefaultChunkOverlapTokens when nil) and stores one
+// vector per chunk under ChunkKey. Chunk metadata is the document metadata
+// plus the Metadata* keys.
+//
+// Re-ingesting a document embeds only chunks whose text hash matches none of
+// the document's stored chunks, rewrites chunks whose metadata or position
+// alone changed (reusing the stored vector), and deletes chunks beyond the new
+// chunk count. The previous chunk count is read from the document's first
+// chunk. Orphans are deleted before new chunks are written, so an interrupted run
+// never leaves chunks the next run cannot find.
+func (i *SemanticIndex) PutDocuments(ctx context.Context, documents []SemanticDocument) (IngestReport, error) {
+	var report IngestReport
+	if i == nil || i.Store == nil || i.Embedder == nil {
+		return report, ErrInvalidConfig
+	}
+	if len(documents) == 0 {
+		return report, NewError(ErrorCodeInvalidInput, "vectorstore: at least one document is required", nil)
+	}
+	planned := make([][]plannedChunk, 0, len(documents))
+	seen := make(map[string]struct{}, len(documents))
+	for _, document := range documents {
+		if _, dup := seen[document.Key]; dup {
+			return report, NewError(ErrorCodeInvalidInput, "vectorstore: duplicate document key: "+document.Key, nil)
+		}
+		seen[document.Key] = struct{}{}
+		chunks, err := i.planDocument(document)
+		if err != nil {
+			return report, err
+		}
+		planned = append(planned, chunks)
+	}
+	for idx, document := range documents {
+		if err := i.ingestDocument(ctx, document.Key, planned[idx], &report); err != nil {
+			return report, err
+		}
+		report.Documents++
+	}
+	return report, nil
+}
+
+func (i *SemanticIndex) planDocument(document SemanticDocument) ([]plannedChunk, error) {
+	if err := ValidateKey(document.Key); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(document.Text) == "" {
+		return nil, NewError(ErrorCodeInvalidInput, "vectorstore: document text is required", nil)
+	}
+	if err := ValidateRequiredMetadata(document.Metadata, i.RequiredMetadataKeys); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{MetadataParentKey, MetadataChunkIndex, MetadataChunkCount, MetadataChunkSHA256, MetadataChunkSection} {
+		if _, ok := document.Metadata[key]; ok {
+			return nil, NewError(ErrorCodeInvalidInput, "vectorstore: document metadata uses reserved key: "+key, nil)
+		}
+	}
+	splitter := i.Splitter
+	if splitter == nil {
+		splitter = TokenSplitter{Size: DefaultChunkTokens, Overlap: DefaultChunkOverlapTokens}
+	}
+	chunks, err := splitter.Split(document.Text)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 || len(chunks) > MaxDocumentChunks {
+		return nil, NewError(ErrorCodeInvalidInput, fmt.Sprintf("vectorstore: document %s produced %d chunks", document.Key, len(chunks)), nil)
+	}
+	out := make([]plannedChunk, 0, len(chunks))
+	for idx, chunk := range chunks {
+		sum := sha256.Sum256([]byte(chunk.Text))
+		metadata := CloneMetadata(document.Metadata)
+		if metadata == nil {
+			metadata = map[string]any{}
+		}
+		metadata[MetadataParentKey] = document.Key
+		metadata[MetadataChunkIndex] = idx
+		metadata[MetadataChunkCount] = len(chunks)
+		metadata[MetadataChunkSHA256] = hex.EncodeToString(sum[:])
+		if chunk.Section != "" {
+			metadata[MetadataChunkSection] = chunk.Section
+		}
+		normalized, err := jsonMetadata(metadata)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, plannedChunk{record: VectorRecord{Key: ChunkKey(document.Key, idx), Metadata: normalized}, text: chunk.Text})
+	}
+	return out, nil
+}
+
+func (i *SemanticIndex) ingestDocument(ctx context.Context, parentKey string, chunks []plannedChunk, report *IngestReport) error {
+	existing, previousCount, err := i.existingChunks(ctx, parentKey)
+	if err != nil {
+		return err
+	}
+	records, embed, texts := diffChunks(chunks, existing, report)
+	if err := i.embedChunks(ctx, records, embed, texts); err != nil {
+		return err
+	}
+	var orphans []string
+	for idx := len(chunks); idx < previousCount; idx++ {
+		orphans = append(orphans, ChunkKey(parentKey, idx))
+	}
+	for start := 0; start < len(orphans); start += MaxPutDeleteBatchSize {
+		if err := i.Store.DeleteVectors(ctx, DeleteInput{Keys: orphans[start:min(start+MaxPutDeleteBatchSize, len(orphans))]}); err != nil {
+			return err
+		}
+	}
+	for start := 0; start < len(records); start += MaxPutDeleteBatchSize {
+		if err := i.Store.PutVectors(ctx, PutInput{Records: records[start:min(start+MaxPutDeleteBatchSize, len(records))]}); err != nil {
+			return err
+		}
+	}
+	if err := i.indexLexical(ctx, chunks, orphans); err != nil {
+		return err
+	}
+	report.Chunks += len(chunks)
+	report.Embedded += len(texts)
+	report.Deleted += len(orphans)
+	return nil
+}
+
+// indexLexical mirrors a document's chunks into Lexical. Every chunk is
+// re-put, including unchanged ones, so an index that missed an earlier run
+// catches up.
+func (i *SemanticIndex) indexLexical(ctx context.Context, chunks []plannedChunk, orphans []string) error {
+	if i.Lexical == nil {
+		return nil
+	}
+	if len(orphans) > 0 {
+		if err := i.Lexical.Delete(ctx, orphans); err != nil {
+			return err
+		}
+	}
+	documents := make([]LexicalDocument, 0, len(chunks))
+	for _, chunk := range chunks {
+		documents = append(documents, LexicalDocument{Key: chunk.record.Key, Text: chunk.text, Metadata: chunk.record.Metadata})
+	}
+	return i.Lexical.Put(ctx, documents)
+}
+
+// diffChunks returns the records to write, the positions among them that need
+// embedding, and the texts to embed for those positions. A chunk reuses the
+// vector of any stored chunk with the same text hash, so inserting text shifts
+// later chunks to new keys without re-embedding them.
+func diffChunks(chunks []plannedChunk, existing map[string]VectorRecord, report *IngestReport) ([]VectorRecord, []int, []string) {
+	var (
+		records []VectorRecord
+		embed   []int
+		texts   []string
+	)
+	byHash := make(map[string][]float32, len(existing))
+	for _, old := range existing {
+		if sum, ok := old.Metadata[MetadataChunkSHA256].(string); ok && len(old.Data) > 0 {
+			byHash[sum] = old.Data
+		}
+	}
+	for _, chunk := range chunks {
+		old, ok := existing[chunk.record.Key]
+		sum, _ := chunk.record.Metadata[MetadataChunkSHA256].(string)
+		data, reusable := byHash[sum]
+		switch {
+		case ok && len(old.Data) > 0 && reflect.DeepEqual(old.Metadata, chunk.record.Metadata):
+			report.Unchanged++
+			continue
+		case reusable:
+			chunk.record.Data = data
+			report.Rewritten++
+		default:
+			embed = append(embed, len(records))
+			texts = append(texts, chunk.text)
+		}
+		records = append(records, chunk.record)
+	}
+	return records, embed, texts
+}
+
+func (i *SemanticIndex) embedChunks(ctx context.Context, records []VectorRecord, embed []int, texts []string) error {
+	if len(texts) == 0 {
+		return nil
+	}
+	embeddings, err := i.Embedder.EmbedBatch(ctx, texts)
+	if err != nil {
+		return err
+	}
+	if len(embeddings) != len(texts) {
+		return NewError(ErrorCodeEmbeddingFailed, "vectorstore: embedding count mismatch", nil)
+	}
+	for idx, position := range embed {
+		if err := ValidateVector(embeddings[idx], i.Dimension); err != nil {
+			return err
+		}
+		records[position].Data = embeddings[idx]
+	}
+	return nil
+}
+
+// existingChunks loads the chunks a previous PutDocuments stored for
+// parentKey. Missing chunks are skipped, so a partially written document is
+// repaired rather than rejected.
+func (i *SemanticIndex) existingChunks(ctx context.Context, parentKey string) (map[string]VectorRecord, int, error) {
+	first, err := i.getChunks(ctx, []string{ChunkKey(parentKey, 0)})
+	if err != nil || len(first) == 0 {
+		return nil, 0, err
+	}
+	count, ok := filterNumber(first[0].Metadata[MetadataChunkCount])
+	if !ok || count < 1 || count > MaxDocumentChunks || count != float64(int(count)) {
+		return nil, 0, NewError(ErrorCodeInvalidInput, "vectorstore: stored chunk count is invalid for "+parentKey, nil)
+	}
+	out := map[string]VectorRecord{first[0].Key: first[0]}
+	keys := make([]string, 0, int(count)-1)
+	for idx := 1; idx < int(count); idx++ {
+		keys = append(keys, ChunkKey(parentKey, idx))
+	}
+	for start := 0; start < len(keys); start += getChunkBatchSize {
+		records, err := i.getChunks(ctx, keys[start:min(start+getChunkBatchSize, len(keys))])
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, record := range records {
+			out[record.Key] = record
+		}
+	}
+	return out, int(count), nil
+}
+
+// getChunks returns the records that exist among keys. Stores that reject a
+// batch with any missing key are retried one key at a time.
+func (i *SemanticIndex) getChunks(ctx context.Context, keys []string) ([]VectorRecord, error) {
+	records, err := i.Store.GetVectors(ctx, GetInput{Keys: keys, ReturnMetadata: true})
+	if !errors.Is(err, ErrNotFound) {
+		return records, err
+	}
+	records = records[:0]
+	for _, key := range keys {
+		found, err := i.Store.GetVectors(ctx, GetInput{Keys: []string{key}, ReturnMetadata: true})
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, found...)
+	}
+	return records, nil
+}
+
+// QueryDocuments runs QueryText and groups the chunk hits by their parent
+// document, closest document first. TopK still counts chunks; hits without
+// MetadataParentKey form their own group. Metadata is always requested
+// because grouping needs it.
+func (i *SemanticIndex) QueryDocuments(ctx context.Context, text string, input QueryInput) ([]DocumentHit, error) {
+	input.ReturnMetadata = true
+	hits, err := i.QueryText(ctx, text, input)
+	if err != nil {
+		return nil, err
+	}
+	var documents []DocumentHit
+	index := map[string]int{}
+	for _, hit := range hits {
+		parent, ok := hit.Metadata[MetadataParentKey].(string)
+		if !ok || parent == "" {
+			parent = hit.Key
+		}
+		position, ok := index[parent]
+		if !ok {
+			position = len(documents)
+			index[parent] = position
+			documents = append(documents, DocumentHit{Key: parent, Distance: hit.Distance})
+		}
+		document := &documents[position]
+		document.Chunks = append(document.Chunks, hit)
+		document.Distance = min(document.Distance, hit.Distance)
+	}
+	sort.SliceStable(documents, func(a, b int) bool { return documents[a].Distance < documents[b].Distance })
+	return documents, nil
+}
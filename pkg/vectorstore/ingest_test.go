@@ -0,0 +1,163 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func chunkTexts(t *testing.T, splitter Splitter, text string) []string {
+	t.Helper()
+	chunks, err := splitter.Split(text)
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+	out := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		out = append(out, chunk.Section+"|"+chunk.Text)
+	}
+	return out
+}
+
+func TestSplitters(t *testing.T) {
+	if got, want := chunkTexts(t, TokenSplitter{Size: 3, Overlap: 1}, "a b\nc d  e f"), []string{"|a b c", "|c d e", "|e f"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("TokenSplitter = %q, want %q", got, want)
+	}
+	if got, want := chunkTexts(t, CharacterSplitter{Size: 10, Overlap: 3}, "hello brave new world"), []string{"|hello", "|brave new", "|new world"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("CharacterSplitter = %q, want %q", got, want)
+	}
+	if got, want := chunkTexts(t, CharacterSplitter{Size: 4, Overlap: 0}, "abcdefghij"), []string{"|abcd", "|efgh", "|ij"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("CharacterSplitter without spaces = %q, want %q", got, want)
+	}
+
+	markdown := strings.Join([]string{
+		"intro text",
+		"# Install",
+		"## Linux",
+		"apt install tool",
+		"```sh",
+		"# not a heading",
+		"```",
+		"### Empty",
+		"## macOS ##",
+		"brew install tool",
+		"# Usage",
+		"run it",
+	}, "\n")
+	got := chunkTexts(t, MarkdownSplitter{Sections: TokenSplitter{Size: 50, Overlap: 0}}, markdown)
+	want := []string{
+		"|intro text",
+		"Install > Linux|## Linux apt install tool ```sh # not a heading ```",
+		"Install > macOS|## macOS ## brew install tool",
+		"Usage|# Usage run it",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("MarkdownSplitter = %q, want %q", got, want)
+	}
+
+	for _, splitter := range []Splitter{TokenSplitter{}, TokenSplitter{Size: 2, Overlap: 2}, CharacterSplitter{Size: 2, Overlap: -1}, MarkdownSplitter{Sections: CharacterSplitter{}}} {
+		if _, err := splitter.Split("text"); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("%T.Split() error = %v, want ErrInvalidConfig", splitter, err)
+		}
+	}
+}
+
+func TestSemanticIndexPutDocumentsReembedsOnlyChangedChunks(t *testing.T) {
+	ctx := context.Background()
+	store := NewFakeStore(2)
+	embedder := NewFakeEmbedder(map[string][]float32{"red green blue": {1, 0}, "cyan magenta yellow": {0, 1}})
+	embedder.Default = []float32{0.5, 0.5}
+	index := &SemanticIndex{Store: store, Embedder: embedder, Dimension: 2, RequiredMetadataKeys: []string{"tenant"}, Splitter: TokenSplitter{Size: 3, Overlap: 0}}
+
+	report, err := index.PutDocuments(ctx, []SemanticDocument{
+		{Key: "doc/a", Text: "red green blue cyan magenta yellow black", Metadata: map[string]any{"tenant": "t1"}},
+		{Key: "doc/b", Text: "cyan magenta yellow", Metadata: map[string]any{"tenant": "t1"}},
+	})
+	if err != nil {
+		t.Fatalf("PutDocuments() error = %v", err)
+	}
+	if want := (IngestReport{Documents: 2, Chunks: 4, Embedded: 4}); report != want {
+		t.Fatalf("PutDocuments() report = %#v, want %#v", report, want)
+	}
+	records, err := store.GetVectors(ctx, GetInput{Keys: []string{ChunkKey("doc/a", 2)}, ReturnMetadata: true})
+	if err != nil {
+		t.Fatalf("GetVectors() error = %v", err)
+	}
+	metadata := records[0].Metadata
+	if records[0].Key != "doc/a#0002" || metadata[MetadataParentKey] != "doc/a" || metadata[MetadataChunkIndex] != float64(2) || metadata[MetadataChunkCount] != float64(3) || metadata["tenant"] != "t1" {
+		t.Fatalf("chunk metadata = %#v", metadata)
+	}
+
+	embedder.Calls = nil
+	report, err = index.PutDocuments(ctx, []SemanticDocument{{Key: "doc/a", Text: "red green blue cyan magenta orange black", Metadata: map[string]any{"tenant": "t1"}}})
+	if err != nil {
+		t.Fatalf("PutDocuments() edit error = %v", err)
+	}
+	if want := (IngestReport{Documents: 1, Chunks: 3, Embedded: 1, Unchanged: 2}); report != want || !reflect.DeepEqual(embedder.Calls, []string{"cyan magenta orange"}) {
+		t.Fatalf("PutDocuments() edit = %#v with calls %q, want %#v", report, embedder.Calls, want)
+	}
+
+	embedder.Calls = nil
+	report, err = index.PutDocuments(ctx, []SemanticDocument{{Key: "doc/a", Text: "red green blue", Metadata: map[string]any{"tenant": "t2"}}})
+	if err != nil {
+		t.Fatalf("PutDocuments() shrink error = %v", err)
+	}
+	if want := (IngestReport{Documents: 1, Chunks: 1, Rewritten: 1, Deleted: 2}); report != want || len(embedder.Calls) != 0 {
+		t.Fatalf("PutDocuments() shrink = %#v with calls %q, want %#v", report, embedder.Calls, want)
+	}
+	if _, err := store.GetVectors(ctx, GetInput{Keys: []string{ChunkKey("doc/a", 1)}}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetVectors() orphan error = %v, want ErrNotFound", err)
+	}
+	records, err = store.GetVectors(ctx, GetInput{Keys: []string{ChunkKey("doc/a", 0)}, ReturnMetadata: true})
+	if err != nil || records[0].Metadata["tenant"] != "t2" || !reflect.DeepEqual(records[0].Data, []float32{1, 0}) {
+		t.Fatalf("rewritten chunk = %#v, %v", records, err)
+	}
+
+	// Inserting a chunk at the top shifts the rest to new keys without re-embedding them.
+	embedder.Calls = nil
+	report, err = index.PutDocuments(ctx, []SemanticDocument{{Key: "doc/a", Text: "one two three red green blue", Metadata: map[string]any{"tenant": "t2"}}})
+	if err != nil {
+		t.Fatalf("PutDocuments() insert error = %v", err)
+	}
+	if want := (IngestReport{Documents: 1, Chunks: 2, Embedded: 1, Rewritten: 1}); report != want || !reflect.DeepEqual(embedder.Calls, []string{"one two three"}) {
+		t.Fatalf("PutDocuments() insert = %#v with calls %q, want %#v", report, embedder.Calls, want)
+	}
+	records, err = store.GetVectors(ctx, GetInput{Keys: []string{ChunkKey("doc/a", 1)}, ReturnMetadata: true})
+	if err != nil || records[0].Metadata[MetadataChunkIndex] != float64(1) || !reflect.DeepEqual(records[0].Data, []float32{1, 0}) {
+		t.Fatalf("shifted chunk = %#v, %v", records, err)
+	}
+
+	// A chunk lost to an interrupted run is re-embedded rather than failing the document.
+	if err := store.DeleteVectors(ctx, DeleteInput{Keys: []string{ChunkKey("doc/b", 0)}}); err != nil {
+		t.Fatalf("DeleteVectors() error = %v", err)
+	}
+	report, err = index.PutDocuments(ctx, []SemanticDocument{{Key: "doc/b", Text: "cyan magenta yellow", Metadata: map[string]any{"tenant": "t1"}}})
+	if err != nil || report.Embedded != 1 {
+		t.Fatalf("PutDocuments() repair = %#v, %v", report, err)
+	}
+
+	docs, err := index.QueryDocuments(ctx, "red green blue", QueryInput{TopK: 10})
+	if err != nil {
+		t.Fatalf("QueryDocuments() error = %v", err)
+	}
+	if len(docs) != 2 || docs[0].Key != "doc/a" || docs[0].Distance != 0 || docs[1].Key != "doc/b" || len(docs[0].Chunks) != 2 || docs[0].Chunks[0].Key != ChunkKey("doc/a", 1) {
+		t.Fatalf("QueryDocuments() = %#v", docs)
+	}
+
+	for _, documents := range [][]SemanticDocument{
+		nil,
+		{{Key: "doc/x", Text: "x", Metadata: map[string]any{"tenant": "t1"}}, {Key: "doc/x", Text: "y", Metadata: map[string]any{"tenant": "t1"}}},
+		{{Key: "doc/x", Text: " ", Metadata: map[string]any{"tenant": "t1"}}},
+		{{Key: "doc/x", Text: "x"}},
+		{{Key: "doc/x", Text: "x", Metadata: map[string]any{"tenant": "t1", MetadataParentKey: "other"}}},
+	} {
+		if _, err := index.PutDocuments(ctx, documents); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("PutDocuments(%#v) error = %v, want ErrInvalidInput", documents, err)
+		}
+	}
+	if _, err := (&SemanticIndex{}).PutDocuments(ctx, nil); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("PutDocuments() without store error = %v, want ErrInvalidConfig", err)
+	}
+}
@@ -0,0 +1,355 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	DefaultBM25K1 = 1.2
+	DefaultBM25B  = 0.75
+)
+
+const lexicalSnapshotVersion = 1
+
+// LexicalIndexConfig configures NewLexicalIndex. K1 and B are the BM25
+// parameters and default to DefaultBM25K1 and DefaultBM25B. Snapshot is
+// optional; without it the index is purely in-memory.
+type LexicalIndexConfig struct {
+	K1                   float64
+	B                    float64
+	RequiredMetadataKeys []string
+	Snapshot             SnapshotStore
+}
+
+// LexicalDocument is one entry in a LexicalIndex.
+type LexicalDocument struct {
+	Key      string
+	Text     string
+	Metadata map[string]any
+}
+
+// LexicalQueryInput mirrors QueryInput for text queries. Filter and Where use
+// the same Filter semantics as vector queries.
+type LexicalQueryInput struct {
+	Text           string
+	TopK           int
+	Filter         map[string]any
+	Where          *Filter
+	ReturnMetadata bool
+}
+
+// LexicalIndex is an in-process BM25 index for exact-term recall, such as
+// SKUs and error codes that embeddings blur. Text is lowercased and split on
+// anything but letters and digits; identifiers joined by '-', '_', '.', '/',
+// or ':' are also indexed whole, so "ERR-1042" matches both "err-1042" and
+// "1042". QueryHit.Score holds the BM25 score, higher is better.
+//
+// When Snapshot is set, every successful Put or Delete saves a full snapshot
+// before returning, as LocalStore does.
+type LexicalIndex struct {
+	k1                   float64
+	b                    float64
+	requiredMetadataKeys []string
+	snapshot             SnapshotStore
+
+	mu          sync.RWMutex
+	docs        map[string]*lexicalDoc
+	postings    map[string]map[string]int
+	totalLength int
+}
+
+type lexicalDoc struct {
+	Terms    map[string]int `json:"terms"`
+	Length   int            `json:"length"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+type lexicalSnapshot struct {
+	Version   int                    `json:"version"`
+	Documents map[string]*lexicalDoc `json:"documents"`
+}
+
+// NewLexicalIndex builds a LexicalIndex, loading Snapshot when one exists.
+func NewLexicalIndex(ctx context.Context, cfg LexicalIndexConfig) (*LexicalIndex, error) {
+	k1, b := cfg.K1, cfg.B
+	if k1 == 0 {
+		k1 = DefaultBM25K1
+	}
+	if b == 0 {
+		b = DefaultBM25B
+	}
+	if k1 < 0 || b < 0 || b > 1 || math.IsNaN(k1) || math.IsNaN(b) || math.IsInf(k1, 0) {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: BM25 k1 must be positive and b must be in [0, 1]", nil)
+	}
+	index := &LexicalIndex{
+		k1:                   k1,
+		b:                    b,
+		requiredMetadataKeys: cloneStrings(cfg.RequiredMetadataKeys),
+		snapshot:             cfg.Snapshot,
+		docs:                 map[string]*lexicalDoc{},
+		postings:             map[string]map[string]int{},
+	}
+	if index.snapshot != nil {
+		if err := index.load(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+// Put adds or replaces documents.
+func (ix *LexicalIndex) Put(ctx context.Context, documents []LexicalDocument) error {
+	if err := ix.validateConfig(); err != nil {
+		return err
+	}
+	if len(documents) == 0 {
+		return NewError(ErrorCodeInvalidInput, "vectorstore: at least one document is required", nil)
+	}
+	docs := make([]*lexicalDoc, 0, len(documents))
+	for _, document := range documents {
+		if err := ValidateKey(document.Key); err != nil {
+			return err
+		}
+		if err := ValidateRequiredMetadata(document.Metadata, ix.requiredMetadataKeys); err != nil {
+			return err
+		}
+		metadata, err := jsonMetadata(document.Metadata)
+		if err != nil {
+			return err
+		}
+		doc := &lexicalDoc{Terms: map[string]int{}, Metadata: metadata}
+		for _, term := range LexicalTerms(document.Text) {
+			doc.Terms[term]++
+			doc.Length++
+		}
+		docs = append(docs, doc)
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for idx, doc := range docs {
+		ix.removeLocked(documents[idx].Key)
+		ix.addLocked(documents[idx].Key, doc)
+	}
+	return ix.saveLocked(ctx)
+}
+
+// Delete removes documents; missing keys are ignored.
+func (ix *LexicalIndex) Delete(ctx context.Context, keys []string) error {
+	if err := ix.validateConfig(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return NewError(ErrorCodeInvalidInput, "vectorstore: at least one key is required", nil)
+	}
+	for _, key := range keys {
+		if err := ValidateKey(key); err != nil {
+			return err
+		}
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for _, key := range keys {
+		ix.removeLocked(key)
+	}
+	return ix.saveLocked(ctx)
+}
+
+// Query returns the TopK documents by BM25 score, highest first.
+func (ix *LexicalIndex) Query(_ context.Context, input LexicalQueryInput) ([]QueryHit, error) {
+	if err := ix.validateConfig(); err != nil {
+		return nil, err
+	}
+	terms := uniqueStrings(LexicalTerms(input.Text))
+	if len(terms) == 0 {
+		return nil, NewError(ErrorCodeInvalidInput, "vectorstore: query text has no searchable terms", nil)
+	}
+	filter, err := queryFilter(QueryInput{Filter: input.Filter, Where: input.Where})
+	if err != nil {
+		return nil, err
+	}
+	topK := NormalizeTopK(input.TopK)
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	scores := ix.scoreLocked(terms)
+	hits := make([]QueryHit, 0, len(scores))
+	for key, score := range scores {
+		doc := ix.docs[key]
+		if !filterMatches(filter, doc.Metadata) {
+			continue
+		}
+		hit := QueryHit{Key: key, Score: score}
+		if input.ReturnMetadata {
+			hit.Metadata = CloneMetadata(doc.Metadata)
+		}
+		hits = append(hits, hit)
+	}
+	sortByScore(hits)
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
+	return hits, nil
+}
+
+// Len returns the number of indexed documents.
+func (ix *LexicalIndex) Len() int {
+	if ix == nil {
+		return 0
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+func (ix *LexicalIndex) scoreLocked(terms []string) map[string]float64 {
+	scores := map[string]float64{}
+	if len(ix.docs) == 0 {
+		return scores
+	}
+	n := float64(len(ix.docs))
+	avgLength := math.Max(float64(ix.totalLength)/n, 1)
+	for _, term := range terms {
+		postings := ix.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for key, tf := range postings {
+			length := float64(ix.docs[key].Length)
+			freq := float64(tf)
+			scores[key] += idf * freq * (ix.k1 + 1) / (freq + ix.k1*(1-ix.b+ix.b*length/avgLength))
+		}
+	}
+	return scores
+}
+
+func (ix *LexicalIndex) addLocked(key string, doc *lexicalDoc) {
+	ix.docs[key] = doc
+	ix.totalLength += doc.Length
+	for term, tf := range doc.Terms {
+		postings := ix.postings[term]
+		if postings == nil {
+			postings = map[string]int{}
+			ix.postings[term] = postings
+		}
+		postings[key] = tf
+	}
+}
+
+func (ix *LexicalIndex) removeLocked(key string) {
+	doc, ok := ix.docs[key]
+	if !ok {
+		return
+	}
+	delete(ix.docs, key)
+	ix.totalLength -= doc.Length
+	for term := range doc.Terms {
+		delete(ix.postings[term], key)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+}
+
+func (ix *LexicalIndex) validateConfig() error {
+	if ix == nil || ix.docs == nil || ix.postings == nil {
+		return ErrInvalidConfig
+	}
+	return nil
+}
+
+func (ix *LexicalIndex) saveLocked(ctx context.Context) error {
+	if ix.snapshot == nil {
+		return nil
+	}
+	data, err := json.Marshal(lexicalSnapshot{Version: lexicalSnapshotVersion, Documents: ix.docs})
+	if err != nil {
+		return err
+	}
+	return ix.snapshot.SaveSnapshot(ctx, data)
+}
+
+func (ix *LexicalIndex) load(ctx context.Context) error {
+	data, err := ix.snapshot.LoadSnapshot(ctx)
+	if err != nil || data == nil {
+		return err
+	}
+	var snapshot lexicalSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return NewError(ErrorCodeInvalidConfig, "vectorstore: lexical snapshot is not valid JSON", err)
+	}
+	if snapshot.Version != lexicalSnapshotVersion {
+		return NewError(ErrorCodeInvalidConfig, "vectorstore: lexical snapshot version is unsupported", nil)
+	}
+	for key, doc := range snapshot.Documents {
+		if ValidateKey(key) != nil || doc == nil {
+			return NewError(ErrorCodeInvalidConfig, "vectorstore: lexical snapshot document is invalid", nil)
+		}
+		length := 0
+		for _, tf := range doc.Terms {
+			if tf <= 0 {
+				return NewError(ErrorCodeInvalidConfig, "vectorstore: lexical snapshot document is invalid", nil)
+			}
+			length += tf
+		}
+		if doc.Terms == nil {
+			doc.Terms = map[string]int{}
+		}
+		doc.Length = length
+		ix.addLocked(key, doc)
+	}
+	return nil
+}
+
+// LexicalTerms returns the terms LexicalIndex indexes for text, in order.
+func LexicalTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !isLexicalJoiner(r)
+	}) {
+		word = strings.TrimFunc(word, isLexicalJoiner)
+		if word == "" {
+			continue
+		}
+		parts := strings.FieldsFunc(word, isLexicalJoiner)
+		terms = append(terms, parts...)
+		if len(parts) > 1 {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+func isLexicalJoiner(r rune) bool {
+	return r == '-' || r == '_' || r == '.' || r == '/' || r == ':'
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	out := values[:0]
+	for _, value := range values {
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		out = append(out, value)
+	}
+	return out
+}
+
+func sortByScore(hits []QueryHit) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score == hits[j].Score {
+			return hits[i].Key < hits[j].Key
+		}
+		return hits[i].Score > hits[j].Score
+	})
+}
@@ -0,0 +1,204 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func hitKeys(hits []QueryHit) []string {
+	keys := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		keys = append(keys, hit.Key)
+	}
+	return keys
+}
+
+func TestLexicalTerms(t *testing.T) {
+	got := LexicalTerms("Error ERR-1042 in pkg/vectorstore: retry!")
+	want := []string{"error", "err", "1042", "err-1042", "in", "pkg", "vectorstore", "pkg/vectorstore", "retry"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("LexicalTerms() = %q, want %q", got, want)
+	}
+}
+
+func TestLexicalIndexBM25(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lexical.json")
+	snapshot, err := NewFileSnapshotStore(path)
+	if err != nil {
+		t.Fatalf("NewFileSnapshotStore() error = %v", err)
+	}
+	index, err := NewLexicalIndex(ctx, LexicalIndexConfig{RequiredMetadataKeys: []string{"tenant"}, Snapshot: snapshot})
+	if err != nil {
+		t.Fatalf("NewLexicalIndex() error = %v", err)
+	}
+	err = index.Put(ctx, []LexicalDocument{
+		{Key: "a", Text: "printer jams with error ERR-1042 when paper is damp", Metadata: map[string]any{"tenant": "t1"}},
+		{Key: "b", Text: "printer printer printer setup guide", Metadata: map[string]any{"tenant": "t1"}},
+		{Key: "c", Text: "warranty terms for the printer", Metadata: map[string]any{"tenant": "t2"}},
+	})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	hits, err := index.Query(ctx, LexicalQueryInput{Text: "err-1042", TopK: 5})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if got := hitKeys(hits); !reflect.DeepEqual(got, []string{"a"}) || hits[0].Score <= 0 {
+		t.Fatalf("Query(err-1042) = %#v", hits)
+	}
+	hits, err = index.Query(ctx, LexicalQueryInput{Text: "printer", TopK: 5})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if got := hitKeys(hits); !reflect.DeepEqual(got, []string{"b", "c", "a"}) || hits[0].Score <= hits[1].Score {
+		t.Fatalf("Query(printer) = %#v", hits)
+	}
+	hits, err = index.Query(ctx, LexicalQueryInput{Text: "printer", TopK: 5, Where: &Filter{Op: FilterEq, Field: "tenant", Value: "t2"}, ReturnMetadata: true})
+	if err != nil {
+		t.Fatalf("Query() filtered error = %v", err)
+	}
+	if len(hits) != 1 || hits[0].Key != "c" || hits[0].Metadata["tenant"] != "t2" {
+		t.Fatalf("Query() filtered = %#v", hits)
+	}
+
+	if err := index.Delete(ctx, []string{"b", "missing"}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	reloaded, err := NewLexicalIndex(ctx, LexicalIndexConfig{Snapshot: snapshot})
+	if err != nil {
+		t.Fatalf("NewLexicalIndex() reload error = %v", err)
+	}
+	want, err := index.Query(ctx, LexicalQueryInput{Text: "printer paper", TopK: 5})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	got, err := reloaded.Query(ctx, LexicalQueryInput{Text: "printer paper", TopK: 5})
+	if err != nil {
+		t.Fatalf("Query() reloaded error = %v", err)
+	}
+	if reloaded.Len() != 2 || !reflect.DeepEqual(got, want) {
+		t.Fatalf("reloaded Query() = %#v, want %#v", got, want)
+	}
+
+	if _, err := index.Query(ctx, LexicalQueryInput{Text: " !! "}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("Query() without terms error = %v, want ErrInvalidInput", err)
+	}
+	if err := index.Put(ctx, []LexicalDocument{{Key: "d", Text: "x"}}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("Put() without tenant error = %v, want ErrInvalidInput", err)
+	}
+	if _, err := NewLexicalIndex(ctx, LexicalIndexConfig{B: 2}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("NewLexicalIndex() bad b error = %v, want ErrInvalidConfig", err)
+	}
+	if _, err := (*LexicalIndex)(nil).Query(ctx, LexicalQueryInput{Text: "x"}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("nil Query() error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestSemanticIndexHybridQuery(t *testing.T) {
+	ctx := context.Background()
+	lexical, err := NewLexicalIndex(ctx, LexicalIndexConfig{})
+	if err != nil {
+		t.Fatalf("NewLexicalIndex() error = %v", err)
+	}
+	embedder := NewFakeEmbedder(map[string][]float32{
+		"paper jam troubleshooting": {1, 0},
+		"printer shows ERR-1042":    {0, 1},
+		"how to fix a paper jam":    {0.9, 0.1},
+	})
+	index := &SemanticIndex{Store: NewFakeStore(2), Embedder: embedder, Dimension: 2, Lexical: lexical}
+	err = index.PutText(ctx, []SemanticRecord{
+		{Key: "jam", Text: "paper jam troubleshooting"},
+		{Key: "code", Text: "printer shows ERR-1042"},
+	})
+	if err != nil {
+		t.Fatalf("PutText() error = %v", err)
+	}
+	if lexical.Len() != 2 {
+		t.Fatalf("lexical Len() = %d, want 2", lexical.Len())
+	}
+
+	embedder.Default = []float32{1, 0}
+	hits, err := index.HybridQuery(ctx, "ERR-1042", HybridQueryInput{TopK: 2})
+	if err != nil {
+		t.Fatalf("HybridQuery() error = %v", err)
+	}
+	// The vector side ranks "jam" first, but "code" is the only lexical match.
+	if got := hitKeys(hits); !reflect.DeepEqual(got, []string{"code", "jam"}) {
+		t.Fatalf("HybridQuery() rrf = %#v", hits)
+	}
+	if len(hits[0].Components) != 2 || hits[0].Components[0].Retriever != RetrieverVector || hits[0].Components[0].Rank != 2 || hits[0].Components[1].Rank != 1 {
+		t.Fatalf("HybridQuery() components = %#v", hits[0].Components)
+	}
+	if want := 1.0/62 + 1.0/61; math.Abs(hits[0].Score-want) > 1e-12 {
+		t.Fatalf("HybridQuery() score = %v, want %v", hits[0].Score, want)
+	}
+	if explain := hits[0].Explain(); !strings.Contains(explain, "vector rank 2") || !strings.Contains(explain, "lexical rank 1") {
+		t.Fatalf("Explain() = %q", explain)
+	}
+
+	hits, err = index.HybridQuery(ctx, "ERR-1042", HybridQueryInput{TopK: 2, Fusion: FusionWeighted, VectorWeight: 3, LexicalWeight: 1})
+	if err != nil {
+		t.Fatalf("HybridQuery() weighted error = %v", err)
+	}
+	if got := hitKeys(hits); !reflect.DeepEqual(got, []string{"jam", "code"}) || hits[0].Score != 3 || hits[1].Score != 1 {
+		t.Fatalf("HybridQuery() weighted = %#v", hits)
+	}
+	if component := hits[1].Components[0]; component.Normalized != 0 || component.Raw <= 0 {
+		t.Fatalf("HybridQuery() weighted vector component = %#v", component)
+	}
+
+	hits, err = index.HybridQuery(ctx, "ERR-1042", HybridQueryInput{LexicalWeight: 1})
+	if err != nil {
+		t.Fatalf("HybridQuery() lexical only error = %v", err)
+	}
+	if got := hitKeys(hits); !reflect.DeepEqual(got, []string{"code"}) || len(hits[0].Components) != 1 {
+		t.Fatalf("HybridQuery() lexical only = %#v", hits)
+	}
+
+	for _, input := range []HybridQueryInput{
+		{Fusion: "max"},
+		{VectorWeight: -1},
+		{Candidates: -1},
+	} {
+		if _, err := index.HybridQuery(ctx, "ERR-1042", input); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("HybridQuery(%#v) error = %v, want ErrInvalidInput", input, err)
+		}
+	}
+	if _, err := (&SemanticIndex{Store: index.Store, Embedder: embedder}).HybridQuery(ctx, "x", HybridQueryInput{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("HybridQuery() without lexical error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestSemanticIndexPutDocumentsIndexesChunksLexically(t *testing.T) {
+	ctx := context.Background()
+	lexical, err := NewLexicalIndex(ctx, LexicalIndexConfig{})
+	if err != nil {
+		t.Fatalf("NewLexicalIndex() error = %v", err)
+	}
+	embedder := NewFakeEmbedder(nil)
+	embedder.Default = []float32{1, 0}
+	index := &SemanticIndex{Store: NewFakeStore(2), Embedder: embedder, Dimension: 2, Splitter: TokenSplitter{Size: 2, Overlap: 0}, Lexical: lexical}
+	if _, err := index.PutDocuments(ctx, []SemanticDocument{{Key: "doc", Text: "alpha beta gamma delta"}}); err != nil {
+		t.Fatalf("PutDocuments() error = %v", err)
+	}
+	hits, err := lexical.Query(ctx, LexicalQueryInput{Text: "delta", ReturnMetadata: true})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(hits) != 1 || hits[0].Key != ChunkKey("doc", 1) || hits[0].Metadata[MetadataParentKey] != "doc" {
+		t.Fatalf("Query() = %#v", hits)
+	}
+	if _, err := index.PutDocuments(ctx, []SemanticDocument{{Key: "doc", Text: "alpha beta"}}); err != nil {
+		t.Fatalf("PutDocuments() shrink error = %v", err)
+	}
+	if lexical.Len() != 1 {
+		t.Fatalf("lexical Len() = %d, want 1", lexical.Len())
+	}
+}
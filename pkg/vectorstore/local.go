@@ -0,0 +1,408 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+type DistanceMetric string
+
+const (
+	DistanceMetricCosine    DistanceMetric = "cosine"
+	DistanceMetricEuclidean DistanceMetric = "euclidean"
+)
+
+const (
+	DefaultLocalM              = 16
+	DefaultLocalEfConstruction = 200
+	DefaultLocalEfSearch       = 64
+)
+
+// localCompactMinNodes keeps small graphs from rebuilding after every delete.
+const localCompactMinNodes = 64
+
+// LocalStoreConfig configures NewLocalStore. Metric defaults to cosine; the
+// HNSW parameters default to DefaultLocalM, DefaultLocalEfConstruction, and
+// DefaultLocalEfSearch. Snapshot is optional; without it the store is purely
+// in-memory.
+type LocalStoreConfig struct {
+	Dimension            int
+	Metric               DistanceMetric
+	RequiredMetadataKeys []string
+	M                    int
+	EfConstruction       int
+	EfSearch             int
+	Snapshot             SnapshotStore
+}
+
+// LocalStore is an embedded Store with an HNSW approximate nearest-neighbour
+// index, for development, realistic test volumes, and small single-tenant
+// deployments without S3 Vectors.
+//
+// Distances match S3 Vectors: cosine distance is 1 minus cosine similarity and
+// euclidean distance is the L2 norm of the difference; lower is closer.
+// Metadata is stored as its JSON form, so numbers read back as float64 exactly
+// as they do from S3 Vectors. Filters are evaluated in process with Filter
+// semantics; filtered queries widen the HNSW search until TopK matches are
+// found and fall back to an exact scan, so filters never silently shrink
+// results.
+//
+// When Snapshot is set, every successful PutVectors or DeleteVectors saves a
+// full snapshot before returning. If the save fails the in-memory change is
+// kept and the error is returned; retrying the same write is safe.
+type LocalStore struct {
+	dimension            int
+	metric               DistanceMetric
+	requiredMetadataKeys []string
+	m                    int
+	efConstruction       int
+	efSearch             int
+	snapshot             SnapshotStore
+
+	mu    sync.RWMutex
+	graph *hnswGraph
+	byKey map[string]int32
+}
+
+var (
+	_ Store  = (*LocalStore)(nil)
+	_ Lister = (*LocalStore)(nil)
+)
+
+// NewLocalStore builds a LocalStore, loading Snapshot when one exists.
+func NewLocalStore(ctx context.Context, cfg LocalStoreConfig) (*LocalStore, error) {
+	if err := ValidateDimension(cfg.Dimension); err != nil {
+		return nil, err
+	}
+	metric := cfg.Metric
+	if metric == "" {
+		metric = DistanceMetricCosine
+	}
+	if metric != DistanceMetricCosine && metric != DistanceMetricEuclidean {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: unsupported distance metric: "+string(metric), nil)
+	}
+	store := &LocalStore{
+		dimension:            cfg.Dimension,
+		metric:               metric,
+		requiredMetadataKeys: cloneStrings(cfg.RequiredMetadataKeys),
+		m:                    positiveOr(cfg.M, DefaultLocalM),
+		efConstruction:       positiveOr(cfg.EfConstruction, DefaultLocalEfConstruction),
+		efSearch:             positiveOr(cfg.EfSearch, DefaultLocalEfSearch),
+		snapshot:             cfg.Snapshot,
+		byKey:                map[string]int32{},
+	}
+	if store.m < 2 {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: hnsw M must be at least 2", nil)
+	}
+	store.graph = store.newGraph()
+	if store.snapshot != nil {
+		if err := store.load(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+func (s *LocalStore) PutVectors(ctx context.Context, input PutInput) error {
+	if err := s.validateConfig(); err != nil {
+		return err
+	}
+	if len(input.Records) == 0 {
+		return NewError(ErrorCodeInvalidInput, "vectorstore: at least one vector is required", nil)
+	}
+	if len(input.Records) > MaxPutDeleteBatchSize {
+		return NewError(ErrorCodeInvalidInput, "vectorstore: put batch exceeds 500 vectors", nil)
+	}
+	nodes := make([]*hnswNode, 0, len(input.Records))
+	for _, record := range input.Records {
+		node, err := s.newNode(record)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, node)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, node := range nodes {
+		s.deleteLocked(node.key)
+		s.byKey[node.key] = s.graph.insert(node)
+	}
+	s.compactLocked()
+	return s.saveLocked(ctx)
+}
+
+func (s *LocalStore) GetVectors(_ context.Context, input GetInput) ([]VectorRecord, error) {
+	if err := s.validateConfig(); err != nil {
+		return nil, err
+	}
+	if len(input.Keys) == 0 {
+		return nil, NewError(ErrorCodeInvalidInput, "vectorstore: at least one key is required", nil)
+	}
+	for _, key := range input.Keys {
+		if err := ValidateKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]VectorRecord, 0, len(input.Keys))
+	for _, key := range input.Keys {
+		id, ok := s.byKey[key]
+		if !ok {
+			return nil, NewError(ErrorCodeNotFound, "vectorstore: vector not found", nil)
+		}
+		node := s.graph.nodes[id]
+		record := VectorRecord{Key: node.key, Data: CloneVector(node.data)}
+		if input.ReturnMetadata {
+			record.Metadata = CloneMetadata(node.metadata)
+		}
+		out = append(out, record)
+	}
+	return out, nil
+}
+
+// ListVectors pages through live vectors in key order. The cursor is the last
+// key of the previous page, so writes between pages never repeat or skip keys
+// that sort after it.
+func (s *LocalStore) ListVectors(_ context.Context, input ListInput) (ListOutput, error) {
+	if err := s.validateConfig(); err != nil {
+		return ListOutput{}, err
+	}
+	limit, err := listLimit(input.Limit)
+	if err != nil {
+		return ListOutput{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.byKey))
+	for key := range s.byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	page, next := pageSortedKeys(keys, input.Cursor, limit)
+	out := ListOutput{Records: make([]VectorRecord, 0, len(page)), NextCursor: next}
+	for _, key := range page {
+		node := s.graph.nodes[s.byKey[key]]
+		record := VectorRecord{Key: node.key}
+		if input.ReturnData {
+			record.Data = CloneVector(node.data)
+		}
+		if input.ReturnMetadata {
+			record.Metadata = CloneMetadata(node.metadata)
+		}
+		out.Records = append(out.Records, record)
+	}
+	return out, nil
+}
+
+func (s *LocalStore) DeleteVectors(ctx context.Context, input DeleteInput) error {
+	if err := s.validateConfig(); err != nil {
+		return err
+	}
+	if len(input.Keys) == 0 {
+		return NewError(ErrorCodeInvalidInput, "vectorstore: at least one key is required", nil)
+	}
+	if len(input.Keys) > MaxPutDeleteBatchSize {
+		return NewError(ErrorCodeInvalidInput, "vectorstore: delete batch exceeds 500 vectors", nil)
+	}
+	for _, key := range input.Keys {
+		if err := ValidateKey(key); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range input.Keys {
+		s.deleteLocked(key)
+	}
+	s.compactLocked()
+	return s.saveLocked(ctx)
+}
+
+func (s *LocalStore) QueryVectors(_ context.Context, input QueryInput) ([]QueryHit, error) {
+	if err := s.validateConfig(); err != nil {
+		return nil, err
+	}
+	if err := s.validateVector(input.Vector); err != nil {
+		return nil, err
+	}
+	filter, err := queryFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	topK := NormalizeTopK(input.TopK)
+	qnorm := vectorNorm(input.Vector)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	accept := func(node *hnswNode) bool { return !node.deleted && filterMatches(filter, node.metadata) }
+	var candidates []hnswCandidate
+	for ef := max(s.efSearch, topK); ; ef *= 4 {
+		if ef >= len(s.graph.nodes) {
+			candidates = s.scanLocked(input.Vector, qnorm, accept)
+			break
+		}
+		candidates = s.graph.search(input.Vector, qnorm, ef)
+		candidates = filterCandidates(s.graph, candidates, accept)
+		if len(candidates) >= topK {
+			break
+		}
+	}
+
+	hits := make([]QueryHit, 0, min(topK, len(candidates)))
+	for _, candidate := range candidates {
+		node := s.graph.nodes[candidate.id]
+		hit := QueryHit{Key: node.key, Distance: candidate.dist}
+		if input.ReturnMetadata {
+			hit.Metadata = CloneMetadata(node.metadata)
+		}
+		hits = append(hits, hit)
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Distance == hits[j].Distance {
+			return hits[i].Key < hits[j].Key
+		}
+		return hits[i].Distance < hits[j].Distance
+	})
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
+	return hits, nil
+}
+
+// Len returns the number of stored vectors.
+func (s *LocalStore) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.byKey)
+}
+
+// Save writes a snapshot now. Writes already save, so Save is only needed to
+// retry a failed save or to seed a new SnapshotStore.
+func (s *LocalStore) Save(ctx context.Context) error {
+	if err := s.validateConfig(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveLocked(ctx)
+}
+
+func (s *LocalStore) validateConfig() error {
+	if s == nil || s.graph == nil || s.byKey == nil {
+		return ErrInvalidConfig
+	}
+	return nil
+}
+
+func (s *LocalStore) validateVector(vector []float32) error {
+	if err := ValidateVector(vector, s.dimension); err != nil {
+		return err
+	}
+	if s.metric == DistanceMetricCosine && vectorNorm(vector) == 0 {
+		return NewError(ErrorCodeInvalidVector, "vectorstore: cosine vectors must be non-zero", nil)
+	}
+	return nil
+}
+
+func (s *LocalStore) newNode(record VectorRecord) (*hnswNode, error) {
+	if err := ValidateKey(record.Key); err != nil {
+		return nil, err
+	}
+	if err := s.validateVector(record.Data); err != nil {
+		return nil, err
+	}
+	if err := ValidateRequiredMetadata(record.Metadata, s.requiredMetadataKeys); err != nil {
+		return nil, err
+	}
+	metadata, err := jsonMetadata(record.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	data := CloneVector(record.Data)
+	return &hnswNode{key: record.Key, data: data, norm: vectorNorm(data), metadata: metadata}, nil
+}
+
+func (s *LocalStore) newGraph() *hnswGraph {
+	return newHNSWGraph(s.metric, s.m, s.efConstruction)
+}
+
+func (s *LocalStore) deleteLocked(key string) {
+	id, ok := s.byKey[key]
+	if !ok {
+		return
+	}
+	delete(s.byKey, key)
+	s.graph.nodes[id].deleted = true
+	s.graph.deleted++
+}
+
+// compactLocked rebuilds the graph from live nodes once tombstones make up more
+// than a quarter of it, keeping search cost proportional to live vectors.
+func (s *LocalStore) compactLocked() {
+	if len(s.graph.nodes) < localCompactMinNodes || s.graph.deleted*4 <= len(s.graph.nodes) {
+		return
+	}
+	old := s.graph
+	s.graph = s.newGraph()
+	s.byKey = make(map[string]int32, old.live())
+	for _, node := range old.nodes {
+		if node.deleted {
+			continue
+		}
+		s.byKey[node.key] = s.graph.insert(&hnswNode{key: node.key, data: node.data, norm: node.norm, metadata: node.metadata})
+	}
+}
+
+func (s *LocalStore) scanLocked(q []float32, qnorm float32, accept func(*hnswNode) bool) []hnswCandidate {
+	out := make([]hnswCandidate, 0, len(s.byKey))
+	for id, node := range s.graph.nodes {
+		if accept(node) {
+			out = append(out, hnswCandidate{id: int32(id), dist: s.graph.distance(q, qnorm, node)}) // #nosec G115 -- node count fits int32.
+		}
+	}
+	return out
+}
+
+func filterCandidates(g *hnswGraph, candidates []hnswCandidate, accept func(*hnswNode) bool) []hnswCandidate {
+	out := candidates[:0]
+	for _, candidate := range candidates {
+		if accept(g.nodes[candidate.id]) {
+			out = append(out, candidate)
+		}
+	}
+	return out
+}
+
+// jsonMetadata normalizes metadata to its JSON form, the shape S3 Vectors
+// returns and snapshots persist.
+func jsonMetadata(metadata map[string]any) (map[string]any, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, NewError(ErrorCodeInvalidInput, "vectorstore: metadata must be JSON-encodable", err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, NewError(ErrorCodeInvalidInput, "vectorstore: metadata must be JSON-encodable", err)
+	}
+	return out, nil
+}
+
+func positiveOr(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,330 @@
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/objectstore"
+)
+
+const (
+	localSnapshotVersion = 1
+	// DefaultSnapshotMaxBytes bounds snapshot reads from an objectstore.Store.
+	DefaultSnapshotMaxBytes int64 = 1 << 30
+)
+
+// SnapshotStore persists LocalStore snapshots. LoadSnapshot returns nil bytes
+// and no error when no snapshot has been saved yet.
+type SnapshotStore interface {
+	LoadSnapshot(context.Context) ([]byte, error)
+	SaveSnapshot(context.Context, []byte) error
+}
+
+// NewFileSnapshotStore persists snapshots to path with atomic renames.
+func NewFileSnapshotStore(path string) (SnapshotStore, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: snapshot path is required", nil)
+	}
+	return &fileSnapshotStore{path: filepath.Clean(path)}, nil
+}
+
+type fileSnapshotStore struct {
+	path string
+}
+
+func (s *fileSnapshotStore) LoadSnapshot(context.Context) ([]byte, error) {
+	data, err := os.ReadFile(s.path) // #nosec G304 -- path is operator configuration.
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s *fileSnapshotStore) SaveSnapshot(_ context.Context, data []byte) error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		return errors.Join(err, tmp.Close(), os.Remove(tmpName))
+	}
+	if err := tmp.Sync(); err != nil {
+		return errors.Join(err, tmp.Close(), os.Remove(tmpName))
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Join(err, os.Remove(tmpName))
+	}
+	if err := os.Rename(tmpName, s.path); err != nil {
+		return errors.Join(err, os.Remove(tmpName))
+	}
+	return nil
+}
+
+// NewObjectSnapshotStore persists snapshots as one object in store. Saves are
+// conditioned on the ETag last loaded or saved, so two LocalStores sharing a
+// snapshot fail with ErrorCodeConflict instead of overwriting each other.
+// maxBytes bounds snapshot reads; zero uses DefaultSnapshotMaxBytes.
+//
+// The ETag of a save comes from objectstore.ETagPutter when store implements
+// it. Other stores are read back after each save, which costs a full read.
+func NewObjectSnapshotStore(store objectstore.Store, ref objectstore.ObjectRef, maxBytes int64) (SnapshotStore, error) {
+	if store == nil {
+		return nil, ErrInvalidConfig
+	}
+	if err := ref.Validate(); err != nil || ref.VersionID != "" {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: snapshot object ref is invalid", err)
+	}
+	if maxBytes == 0 {
+		maxBytes = DefaultSnapshotMaxBytes
+	}
+	if maxBytes < 0 {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: snapshot max bytes must be positive", nil)
+	}
+	return &objectSnapshotStore{store: store, ref: ref, maxBytes: maxBytes}, nil
+}
+
+type objectSnapshotStore struct {
+	store    objectstore.Store
+	ref      objectstore.ObjectRef
+	maxBytes int64
+
+	mu   sync.Mutex
+	etag string
+}
+
+func (s *objectSnapshotStore) LoadSnapshot(ctx context.Context) ([]byte, error) {
+	out, err := s.store.Get(ctx, objectstore.GetInput{Ref: s.ref, MaxBytes: s.maxBytes})
+	if errors.Is(err, objectstore.ErrObjectNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.etag = out.ETag
+	s.mu.Unlock()
+	return out.Payload, nil
+}
+
+func (s *objectSnapshotStore) SaveSnapshot(ctx context.Context, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	condition := objectstore.WriteCondition{IfMatch: s.etag}
+	if s.etag == "" {
+		condition = objectstore.WriteCondition{IfNoneMatch: "*"}
+	}
+	etag, err := s.put(ctx, objectstore.PutInput{Ref: s.ref, Payload: data, ContentType: "application/json", Condition: condition})
+	if errors.Is(err, objectstore.ErrPreconditionFailed) || errors.Is(err, objectstore.ErrObjectNotFound) {
+		return NewError(ErrorCodeConflict, "vectorstore: snapshot was changed by another writer", err)
+	}
+	if err != nil {
+		return err
+	}
+	s.etag = etag
+	return nil
+}
+
+// put writes input and returns the ETag of that write. Without ETagPutter the
+// object is read back; bytes other than ours mean another writer landed in
+// between, and its ETag must not be mistaken for ours.
+func (s *objectSnapshotStore) put(ctx context.Context, input objectstore.PutInput) (string, error) {
+	if putter, ok := s.store.(objectstore.ETagPutter); ok {
+		_, etag, err := putter.PutETag(ctx, input)
+		return etag, err
+	}
+	if _, err := s.store.Put(ctx, input); err != nil {
+		return "", err
+	}
+	out, err := s.store.Get(ctx, objectstore.GetInput{Ref: s.ref, MaxBytes: s.maxBytes})
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Equal(out.Payload, input.Payload) {
+		return "", objectstore.ErrPreconditionFailed
+	}
+	return out.ETag, nil
+}
+
+type localSnapshot struct {
+	Version   int                 `json:"version"`
+	Dimension int                 `json:"dimension"`
+	Metric    DistanceMetric      `json:"metric"`
+	M         int                 `json:"m"`
+	Entry     int32               `json:"entry"`
+	MaxLevel  int                 `json:"max_level"`
+	Nodes     []localSnapshotNode `json:"nodes"`
+}
+
+// localSnapshotNode keeps tombstones so the saved graph stays navigable
+// without a rebuild on load.
+type localSnapshotNode struct {
+	Key       string         `json:"key,omitempty"`
+	Data      string         `json:"data"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	Neighbors [][]int32      `json:"neighbors"`
+	Deleted   bool           `json:"deleted,omitempty"`
+}
+
+func (s *LocalStore) saveLocked(ctx context.Context) error {
+	if s.snapshot == nil {
+		return nil
+	}
+	snapshot := localSnapshot{
+		Version:   localSnapshotVersion,
+		Dimension: s.dimension,
+		Metric:    s.metric,
+		M:         s.m,
+		Entry:     s.graph.entry,
+		MaxLevel:  s.graph.maxLevel,
+		Nodes:     make([]localSnapshotNode, 0, len(s.graph.nodes)),
+	}
+	for _, node := range s.graph.nodes {
+		item := localSnapshotNode{Data: encodeSnapshotVector(node.data), Neighbors: node.neighbors, Deleted: node.deleted}
+		if !node.deleted {
+			item.Key, item.Metadata = node.key, node.metadata
+		}
+		snapshot.Nodes = append(snapshot.Nodes, item)
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return s.snapshot.SaveSnapshot(ctx, data)
+}
+
+func (s *LocalStore) load(ctx context.Context) error {
+	data, err := s.snapshot.LoadSnapshot(ctx)
+	if err != nil || data == nil {
+		return err
+	}
+	var snapshot localSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return NewError(ErrorCodeInvalidConfig, "vectorstore: snapshot is not valid JSON", err)
+	}
+	if snapshot.Version != localSnapshotVersion || snapshot.Dimension != s.dimension || snapshot.Metric != s.metric || snapshot.M != s.m {
+		return NewError(ErrorCodeInvalidConfig, "vectorstore: snapshot does not match store config", nil)
+	}
+
+	graph := s.newGraph()
+	byKey := make(map[string]int32, len(snapshot.Nodes))
+	for id, item := range snapshot.Nodes {
+		node, err := s.decodeSnapshotNode(item, len(snapshot.Nodes))
+		if err != nil {
+			return err
+		}
+		if node.deleted {
+			graph.deleted++
+		} else if _, dup := byKey[node.key]; dup {
+			return NewError(ErrorCodeInvalidConfig, "vectorstore: snapshot has duplicate keys", nil)
+		} else {
+			byKey[node.key] = int32(id) // #nosec G115 -- node count fits int32.
+		}
+		graph.nodes = append(graph.nodes, node)
+	}
+	if err := snapshot.validateGraph(graph.nodes); err != nil {
+		return err
+	}
+	if len(graph.nodes) > 0 {
+		graph.entry, graph.maxLevel = snapshot.Entry, snapshot.MaxLevel
+	}
+	// Replay the level draws so inserts after a reload match an uninterrupted store.
+	for range graph.nodes {
+		graph.randomLevel()
+	}
+	s.graph, s.byKey = graph, byKey
+	return nil
+}
+
+// validateGraph checks the links between decoded nodes and the entry point.
+func (snapshot localSnapshot) validateGraph(nodes []*hnswNode) error {
+	if !validSnapshotLinks(nodes) {
+		return NewError(ErrorCodeInvalidConfig, "vectorstore: snapshot node is invalid", nil)
+	}
+	if len(nodes) > 0 && !snapshot.validEntry(nodes) {
+		return NewError(ErrorCodeInvalidConfig, "vectorstore: snapshot entry point is invalid", nil)
+	}
+	return nil
+}
+
+// validEntry reports whether the entry point names a node that reaches the
+// top level.
+func (snapshot localSnapshot) validEntry(nodes []*hnswNode) bool {
+	return snapshot.Entry >= 0 && int(snapshot.Entry) < len(nodes) && len(nodes[snapshot.Entry].neighbors) == snapshot.MaxLevel+1
+}
+
+// decodeSnapshotNode checks one node on its own. Tombstones keep their
+// vectors for navigation, so their vectors are validated too.
+func (s *LocalStore) decodeSnapshotNode(item localSnapshotNode, count int) (*hnswNode, error) {
+	invalid := NewError(ErrorCodeInvalidConfig, "vectorstore: snapshot node is invalid", nil)
+	data, ok := decodeSnapshotVector(item.Data)
+	if !ok || len(item.Neighbors) == 0 {
+		return nil, invalid
+	}
+	if err := s.validateVector(data); err != nil {
+		return nil, invalid
+	}
+	if !item.Deleted {
+		if err := ValidateKey(item.Key); err != nil {
+			return nil, invalid
+		}
+	}
+	for _, level := range item.Neighbors {
+		for _, neighbor := range level {
+			if neighbor < 0 || int(neighbor) >= count {
+				return nil, invalid
+			}
+		}
+	}
+	return &hnswNode{key: item.Key, data: data, norm: vectorNorm(data), metadata: item.Metadata, neighbors: item.Neighbors, deleted: item.Deleted}, nil
+}
+
+// validSnapshotLinks reports whether every neighbour at level l is itself
+// present at level l, which searchLayer relies on when it follows the link.
+func validSnapshotLinks(nodes []*hnswNode) bool {
+	for _, node := range nodes {
+		for level, neighbors := range node.neighbors {
+			for _, neighbor := range neighbors {
+				if len(nodes[neighbor].neighbors) <= level {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func encodeSnapshotVector(v []float32) string {
+	raw := make([]byte, 4*len(v))
+	for i, value := range v {
+		binary.LittleEndian.PutUint32(raw[4*i:], math.Float32bits(value))
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func decodeSnapshotVector(encoded string) ([]float32, bool) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) == 0 || len(raw)%4 != 0 {
+		return nil, false
+	}
+	out := make([]float32, len(raw)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+	}
+	return out, true
+}
@@ -0,0 +1,380 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/objectstore"
+)
+
+func randomLocalVectors(n, dim int) [][]float32 {
+	rng := rand.New(rand.NewPCG(1, 2)) // #nosec G404 -- deterministic test data.
+	out := make([][]float32, n)
+	for i := range out {
+		out[i] = make([]float32, dim)
+		for j := range out[i] {
+			out[i][j] = float32(rng.NormFloat64())
+		}
+	}
+	return out
+}
+
+func putLocalVectors(t *testing.T, store *LocalStore, vectors [][]float32) {
+	t.Helper()
+	for start := 0; start < len(vectors); start += MaxPutDeleteBatchSize {
+		records := make([]VectorRecord, 0, MaxPutDeleteBatchSize)
+		for i := start; i < min(start+MaxPutDeleteBatchSize, len(vectors)); i++ {
+			records = append(records, VectorRecord{Key: fmt.Sprintf("v%04d", i), Data: vectors[i], Metadata: map[string]any{"parity": i % 2, "bucket": fmt.Sprint(i % 5)}})
+		}
+		if err := store.PutVectors(context.Background(), PutInput{Records: records}); err != nil {
+			t.Fatalf("PutVectors() error = %v", err)
+		}
+	}
+}
+
+func TestLocalStoreRecallAgainstExactSearch(t *testing.T) {
+	ctx := context.Background()
+	vectors := randomLocalVectors(2000, 16)
+	for _, metric := range []DistanceMetric{DistanceMetricCosine, DistanceMetricEuclidean} {
+		store, err := NewLocalStore(ctx, LocalStoreConfig{Dimension: 16, Metric: metric})
+		if err != nil {
+			t.Fatalf("NewLocalStore() error = %v", err)
+		}
+		putLocalVectors(t, store, vectors)
+
+		found, total := 0, 0
+		for _, query := range randomLocalVectors(50, 16) {
+			exact := make([]string, len(vectors))
+			dist := make(map[string]float32, len(vectors))
+			for i, vector := range vectors {
+				key := fmt.Sprintf("v%04d", i)
+				exact[i], dist[key] = key, vectorDistance(metric, query, vectorNorm(query), vector, vectorNorm(vector))
+			}
+			sort.Slice(exact, func(i, j int) bool { return dist[exact[i]] < dist[exact[j]] })
+			want := map[string]bool{}
+			for _, key := range exact[:10] {
+				want[key] = true
+			}
+
+			hits, err := store.QueryVectors(ctx, QueryInput{Vector: query, TopK: 10})
+			if err != nil || len(hits) != 10 {
+				t.Fatalf("QueryVectors() = %d hits, %v", len(hits), err)
+			}
+			for _, hit := range hits {
+				if math.Abs(float64(hit.Distance-dist[hit.Key])) > 1e-5 {
+					t.Fatalf("%s distance(%s) = %v, want %v", metric, hit.Key, hit.Distance, dist[hit.Key])
+				}
+				if want[hit.Key] {
+					found++
+				}
+			}
+			total += 10
+		}
+		if recall := float64(found) / float64(total); recall < 0.95 {
+			t.Fatalf("%s recall = %.3f, want >= 0.95", metric, recall)
+		}
+	}
+}
+
+func TestLocalStoreDistancesFiltersAndUpdates(t *testing.T) {
+	ctx := context.Background()
+	cosine, err := NewLocalStore(ctx, LocalStoreConfig{Dimension: 2, RequiredMetadataKeys: []string{"tenant"}})
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	euclidean, err := NewLocalStore(ctx, LocalStoreConfig{Dimension: 2, Metric: DistanceMetricEuclidean})
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	records := []VectorRecord{
+		{Key: "east", Data: []float32{2, 0}, Metadata: map[string]any{"tenant": "t1", "rank": 1, "tags": []string{"a", "b"}}},
+		{Key: "north", Data: []float32{0, 3}, Metadata: map[string]any{"tenant": "t2", "rank": 2}},
+	}
+	for _, store := range []*LocalStore{cosine, euclidean} {
+		if err := store.PutVectors(ctx, PutInput{Records: records}); err != nil {
+			t.Fatalf("PutVectors() error = %v", err)
+		}
+	}
+
+	hits, err := cosine.QueryVectors(ctx, QueryInput{Vector: []float32{1, 0}, TopK: 5, ReturnMetadata: true})
+	if err != nil || len(hits) != 2 || hits[0].Key != "east" || hits[0].Distance != 0 || hits[1].Distance != 1 {
+		t.Fatalf("cosine QueryVectors() = %#v, %v", hits, err)
+	}
+	if hits[0].Metadata["rank"] != float64(1) {
+		t.Fatalf("metadata rank = %T, want JSON float64", hits[0].Metadata["rank"])
+	}
+	hits, err = euclidean.QueryVectors(ctx, QueryInput{Vector: []float32{0, 0}, TopK: 5})
+	if err != nil || len(hits) != 2 || hits[0].Distance != 2 || hits[1].Distance != 3 {
+		t.Fatalf("euclidean QueryVectors() = %#v, %v", hits, err)
+	}
+
+	hits, err = cosine.QueryVectors(ctx, QueryInput{Vector: []float32{1, 0}, TopK: 5, Filter: map[string]any{"tenant": "t2"}})
+	if err != nil || len(hits) != 1 || hits[0].Key != "north" {
+		t.Fatalf("filtered QueryVectors() = %#v, %v", hits, err)
+	}
+	hits, err = cosine.QueryVectors(ctx, QueryInput{Vector: []float32{1, 0}, Filter: map[string]any{"tags": "b", "rank": 1}})
+	if err != nil || len(hits) != 1 || hits[0].Key != "east" {
+		t.Fatalf("list filter QueryVectors() = %#v, %v", hits, err)
+	}
+
+	if err := cosine.PutVectors(ctx, PutInput{Records: []VectorRecord{{Key: "east", Data: []float32{-1, 0}, Metadata: map[string]any{"tenant": "t1"}}}}); err != nil {
+		t.Fatalf("PutVectors() update error = %v", err)
+	}
+	got, err := cosine.GetVectors(ctx, GetInput{Keys: []string{"east"}, ReturnMetadata: true})
+	if err != nil || got[0].Data[0] != -1 || got[0].Metadata["rank"] != nil || cosine.Len() != 2 {
+		t.Fatalf("GetVectors() after update = %#v, %v", got, err)
+	}
+	if err := cosine.DeleteVectors(ctx, DeleteInput{Keys: []string{"east", "missing"}}); err != nil {
+		t.Fatalf("DeleteVectors() error = %v", err)
+	}
+	if _, err := cosine.GetVectors(ctx, GetInput{Keys: []string{"east"}}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetVectors() deleted error = %v, want ErrNotFound", err)
+	}
+
+	if err := cosine.PutVectors(ctx, PutInput{Records: []VectorRecord{{Key: "zero", Data: []float32{0, 0}, Metadata: map[string]any{"tenant": "t1"}}}}); !errors.Is(err, ErrInvalidVector) {
+		t.Fatalf("PutVectors() zero cosine vector error = %v, want ErrInvalidVector", err)
+	}
+	if err := cosine.PutVectors(ctx, PutInput{Records: []VectorRecord{{Key: "x", Data: []float32{1, 0}}}}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("PutVectors() missing metadata error = %v, want ErrInvalidInput", err)
+	}
+	if _, err := NewLocalStore(ctx, LocalStoreConfig{Dimension: 2, Metric: "dot"}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("NewLocalStore() metric error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestLocalStoreFilteredQueriesAndCompaction(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStore(ctx, LocalStoreConfig{Dimension: 8, Metric: DistanceMetricEuclidean, EfSearch: 8})
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	vectors := randomLocalVectors(600, 8)
+	putLocalVectors(t, store, vectors)
+
+	hits, err := store.QueryVectors(ctx, QueryInput{Vector: vectors[0], TopK: 40, Filter: map[string]any{"bucket": "3"}})
+	if err != nil || len(hits) != 40 {
+		t.Fatalf("QueryVectors() selective filter = %d hits, %v, want 40", len(hits), err)
+	}
+	for _, hit := range hits {
+		got, err := store.GetVectors(ctx, GetInput{Keys: []string{hit.Key}, ReturnMetadata: true})
+		if err != nil || got[0].Metadata["bucket"] != "3" {
+			t.Fatalf("filtered hit %s = %#v, %v, want bucket 3", hit.Key, got, err)
+		}
+	}
+
+	keys := make([]string, 0, 400)
+	for i := range 400 {
+		keys = append(keys, fmt.Sprintf("v%04d", i))
+	}
+	if err := store.DeleteVectors(ctx, DeleteInput{Keys: keys}); err != nil {
+		t.Fatalf("DeleteVectors() error = %v", err)
+	}
+	if store.Len() != 200 || store.graph.deleted != 0 || len(store.graph.nodes) != 200 {
+		t.Fatalf("after compaction len=%d nodes=%d deleted=%d, want 200 live nodes", store.Len(), len(store.graph.nodes), store.graph.deleted)
+	}
+	hits, err = store.QueryVectors(ctx, QueryInput{Vector: vectors[450], TopK: 1})
+	if err != nil || len(hits) != 1 || hits[0].Key != "v0450" || hits[0].Distance != 0 {
+		t.Fatalf("QueryVectors() after compaction = %#v, %v", hits, err)
+	}
+}
+
+func TestLocalStoreSnapshotsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	vectors := randomLocalVectors(300, 4)
+
+	fileSnapshot, err := NewFileSnapshotStore(filepath.Join(t.TempDir(), "index", "vectors.json"))
+	if err != nil {
+		t.Fatalf("NewFileSnapshotStore() error = %v", err)
+	}
+	objects, err := objectstore.NewMemoryStore(objectstore.MemoryStoreConfig{})
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+	ref := objectstore.ObjectRef{Bucket: "bucket", Key: "vectors/snapshot.json"}
+	objectSnapshot, err := NewObjectSnapshotStore(objects, ref, 0)
+	if err != nil {
+		t.Fatalf("NewObjectSnapshotStore() error = %v", err)
+	}
+
+	for name, snapshot := range map[string]SnapshotStore{"file": fileSnapshot, "object": objectSnapshot} {
+		cfg := LocalStoreConfig{Dimension: 4, Snapshot: snapshot}
+		store, err := NewLocalStore(ctx, cfg)
+		if err != nil {
+			t.Fatalf("%s NewLocalStore() error = %v", name, err)
+		}
+		putLocalVectors(t, store, vectors)
+		if err := store.DeleteVectors(ctx, DeleteInput{Keys: []string{"v0001"}}); err != nil {
+			t.Fatalf("%s DeleteVectors() error = %v", name, err)
+		}
+		want, err := store.QueryVectors(ctx, QueryInput{Vector: vectors[7], TopK: 5, ReturnMetadata: true})
+		if err != nil {
+			t.Fatalf("%s QueryVectors() error = %v", name, err)
+		}
+
+		if name == "object" {
+			if cfg.Snapshot, err = NewObjectSnapshotStore(objects, ref, 0); err != nil {
+				t.Fatalf("NewObjectSnapshotStore() error = %v", err)
+			}
+		}
+		reloaded, err := NewLocalStore(ctx, cfg)
+		if err != nil {
+			t.Fatalf("%s NewLocalStore() reload error = %v", name, err)
+		}
+		got, err := reloaded.QueryVectors(ctx, QueryInput{Vector: vectors[7], TopK: 5, ReturnMetadata: true})
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(want) || reloaded.Len() != 299 {
+			t.Fatalf("%s reloaded QueryVectors() = %v, %v, want %v", name, got, err, want)
+		}
+		if _, err := reloaded.GetVectors(ctx, GetInput{Keys: []string{"v0001"}}); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s reloaded deleted key error = %v, want ErrNotFound", name, err)
+		}
+		if err := reloaded.PutVectors(ctx, PutInput{Records: []VectorRecord{{Key: "v0001", Data: vectors[1]}}}); err != nil {
+			t.Fatalf("%s reloaded PutVectors() error = %v", name, err)
+		}
+
+		if _, err := NewLocalStore(ctx, LocalStoreConfig{Dimension: 4, Metric: DistanceMetricEuclidean, Snapshot: cfg.Snapshot}); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("%s NewLocalStore() metric mismatch error = %v, want ErrInvalidConfig", name, err)
+		}
+	}
+
+	// The first object-backed store still holds the ETag from before the reload wrote.
+	stale, err := NewObjectSnapshotStore(objects, ref, 0)
+	if err != nil {
+		t.Fatalf("NewObjectSnapshotStore() error = %v", err)
+	}
+	if _, err := stale.LoadSnapshot(ctx); err != nil {
+		t.Fatalf("LoadSnapshot() error = %v", err)
+	}
+	if err := objectSnapshot.SaveSnapshot(ctx, []byte("{}")); !errors.Is(err, ErrConflict) {
+		t.Fatalf("SaveSnapshot() stale error = %v, want ErrConflict", err)
+	}
+	if err := stale.SaveSnapshot(ctx, []byte("{}")); err != nil {
+		t.Fatalf("SaveSnapshot() current error = %v", err)
+	}
+	if _, err := NewLocalStore(ctx, LocalStoreConfig{Dimension: 4, Snapshot: stale}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("NewLocalStore() invalid snapshot error = %v, want ErrInvalidConfig", err)
+	}
+	if _, err := NewFileSnapshotStore(" "); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("NewFileSnapshotStore() empty error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+// interleavingStore lands another writer's snapshot right after the next Put.
+type interleavingStore struct {
+	objectstore.Store
+	other []byte
+}
+
+func (s *interleavingStore) Put(ctx context.Context, input objectstore.PutInput) (objectstore.ObjectRef, error) {
+	ref, err := s.Store.Put(ctx, input)
+	if err == nil && s.other != nil {
+		_, err = s.Store.Put(ctx, objectstore.PutInput{Ref: input.Ref, Payload: s.other})
+		s.other = nil
+	}
+	return ref, err
+}
+
+// interleavingETagStore does the same after PutETag.
+type interleavingETagStore struct {
+	interleavingStore
+}
+
+func (s *interleavingETagStore) PutETag(ctx context.Context, input objectstore.PutInput) (objectstore.ObjectRef, string, error) {
+	putter, ok := s.Store.(objectstore.ETagPutter)
+	if !ok {
+		return objectstore.ObjectRef{}, "", errors.New("inner store does not report ETags")
+	}
+	ref, etag, err := putter.PutETag(ctx, input)
+	if err == nil && s.other != nil {
+		_, err = s.Store.Put(ctx, objectstore.PutInput{Ref: input.Ref, Payload: s.other})
+		s.other = nil
+	}
+	return ref, etag, err
+}
+
+func TestObjectSnapshotStoreDetectsWriterBetweenPutAndReadBack(t *testing.T) {
+	ctx := context.Background()
+	ref := objectstore.ObjectRef{Bucket: "bucket", Key: "vectors/snapshot.json"}
+	for name, wrap := range map[string]func(objectstore.Store) objectstore.Store{
+		"read back": func(inner objectstore.Store) objectstore.Store {
+			return &interleavingStore{Store: inner, other: []byte("other")}
+		},
+		"etag putter": func(inner objectstore.Store) objectstore.Store {
+			return &interleavingETagStore{interleavingStore{Store: inner, other: []byte("other")}}
+		},
+	} {
+		inner, err := objectstore.NewMemoryStore(objectstore.MemoryStoreConfig{})
+		if err != nil {
+			t.Fatalf("NewMemoryStore() error = %v", err)
+		}
+		snapshot, err := NewObjectSnapshotStore(wrap(inner), ref, 0)
+		if err != nil {
+			t.Fatalf("NewObjectSnapshotStore() error = %v", err)
+		}
+		// Without ETagPutter the other write is seen at once; with it the
+		// save keeps its own ETag and the next save fails instead.
+		err = snapshot.SaveSnapshot(ctx, []byte("mine"))
+		if err == nil {
+			err = snapshot.SaveSnapshot(ctx, []byte("mine again"))
+		}
+		if !errors.Is(err, ErrConflict) {
+			t.Fatalf("%s SaveSnapshot() error = %v, want ErrConflict", name, err)
+		}
+		out, err := inner.Get(ctx, objectstore.GetInput{Ref: ref, MaxBytes: 16})
+		if err != nil || string(out.Payload) != "other" {
+			t.Fatalf("%s stored snapshot = %v, %v, want the other writer's", name, out, err)
+		}
+	}
+}
+
+func TestLocalStoreRejectsCorruptSnapshotGraphs(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	snapshot, err := NewFileSnapshotStore(path)
+	if err != nil {
+		t.Fatalf("NewFileSnapshotStore() error = %v", err)
+	}
+	store, err := NewLocalStore(ctx, LocalStoreConfig{Dimension: 2, Snapshot: snapshot})
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	valid := func() localSnapshot {
+		return localSnapshot{
+			Version: localSnapshotVersion, Dimension: 2, Metric: store.metric, M: store.m, Entry: 0, MaxLevel: 1,
+			Nodes: []localSnapshotNode{
+				{Key: "a", Data: encodeSnapshotVector([]float32{1, 0}), Neighbors: [][]int32{{1}, {}}},
+				{Key: "b", Data: encodeSnapshotVector([]float32{0, 1}), Neighbors: [][]int32{{0}}},
+			},
+		}
+	}
+	shortTombstone := valid()
+	shortTombstone.Nodes[1] = localSnapshotNode{Data: encodeSnapshotVector([]float32{1}), Neighbors: [][]int32{{0}}, Deleted: true}
+	missingLevel := valid()
+	missingLevel.Nodes[0].Neighbors[1] = []int32{1}
+
+	for name, corrupt := range map[string]localSnapshot{"valid": valid(), "short tombstone": shortTombstone, "missing level": missingLevel} {
+		data, err := json.Marshal(corrupt)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		if err := snapshot.SaveSnapshot(ctx, data); err != nil {
+			t.Fatalf("SaveSnapshot() error = %v", err)
+		}
+		_, err = NewLocalStore(ctx, LocalStoreConfig{Dimension: 2, Snapshot: snapshot})
+		if name == "valid" {
+			if err != nil {
+				t.Fatalf("NewLocalStore() valid snapshot error = %v", err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("NewLocalStore() %s error = %v, want ErrInvalidConfig", name, err)
+		}
+	}
+}
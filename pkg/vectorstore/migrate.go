@@ -0,0 +1,312 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/jobs"
+)
+
+const (
+	DefaultMigrationPageSize       = 100
+	DefaultMigrationLeaseDuration  = 5 * time.Minute
+	DefaultMigrationDeadlineMargin = 30 * time.Second
+	DefaultMigrationCheckpointTTL  = 30 * 24 * time.Hour
+)
+
+// MigrationConfig configures NewMigration.
+//
+// Source must implement Lister. Text returns the text to re-embed for a source
+// record, which is usually kept in its metadata (see TextFromMetadata); an
+// empty string or an ErrNotFound error skips the record. Target embeds and
+// writes each page with PutText, so its Embedder, Dimension, and Lexical index
+// apply. Ledger, JobID, TenantID, and Owner identify the migration job and the
+// lease holder. CheckpointTTL bounds how long page checkpoints survive and must
+// outlast the whole migration.
+type MigrationConfig struct {
+	Source         Store
+	Target         *SemanticIndex
+	Text           func(context.Context, VectorRecord) (string, error)
+	Ledger         jobs.JobLedger
+	JobID          string
+	TenantID       string
+	Owner          string
+	PageSize       int
+	MaxPages       int
+	LeaseDuration  time.Duration
+	DeadlineMargin time.Duration
+	CheckpointTTL  time.Duration
+}
+
+// MigrationInput starts a Run. StartPage is passed to jobs.RunPaged as its
+// startPage, typically the previous report's NextPage.
+type MigrationInput struct {
+	StartPage int
+}
+
+// MigrationReport describes one Run. Pages, Records, and Skipped count only
+// the work this Run did; pages another Run had already checkpointed are
+// counted in Replayed. NextPage is where the next Run should start.
+type MigrationReport struct {
+	Pages    int
+	Records  int
+	Skipped  int
+	Replayed int
+	NextPage int
+	Done     bool
+}
+
+// Migration copies every record of a source index into a target SemanticIndex,
+// re-embedding its text with the target's Embedder. Pages are checkpointed
+// with jobs.RunPaged, each checkpoint holding the list cursor for the next
+// page. Re-running a page rewrites the same keys, so a page is applied at
+// least once and usually exactly once.
+type Migration struct {
+	cfg    MigrationConfig
+	lister Lister
+}
+
+// NewMigration validates cfg and fills in defaults.
+func NewMigration(cfg MigrationConfig) (*Migration, error) {
+	lister, err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	cfg.PageSize = positiveOr(cfg.PageSize, DefaultMigrationPageSize)
+	if cfg.LeaseDuration == 0 {
+		cfg.LeaseDuration = DefaultMigrationLeaseDuration
+	}
+	if cfg.DeadlineMargin == 0 {
+		cfg.DeadlineMargin = DefaultMigrationDeadlineMargin
+	}
+	if cfg.CheckpointTTL == 0 {
+		cfg.CheckpointTTL = DefaultMigrationCheckpointTTL
+	}
+	return &Migration{cfg: cfg, lister: lister}, nil
+}
+
+func (cfg MigrationConfig) validate() (Lister, error) {
+	lister, ok := cfg.Source.(Lister)
+	if !ok || cfg.Target == nil || cfg.Text == nil || cfg.Ledger == nil {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: migration requires a listable source, target, text function, and ledger", nil)
+	}
+	if strings.TrimSpace(cfg.JobID) == "" || strings.TrimSpace(cfg.TenantID) == "" || strings.TrimSpace(cfg.Owner) == "" {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: migration requires a job id, tenant id, and owner", nil)
+	}
+	if cfg.PageSize < 0 || cfg.PageSize > MaxListLimit || cfg.MaxPages < 0 || cfg.LeaseDuration < 0 || cfg.DeadlineMargin < 0 || cfg.CheckpointTTL < 0 {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: migration limits are out of range", nil)
+	}
+	return lister, nil
+}
+
+// TextFromMetadata returns a MigrationConfig.Text function that reads the
+// string metadata value under key.
+func TextFromMetadata(key string) func(context.Context, VectorRecord) (string, error) {
+	return func(_ context.Context, record VectorRecord) (string, error) {
+		text, ok := record.Metadata[key].(string)
+		if !ok {
+			return "", ErrNotFound
+		}
+		return text, nil
+	}
+}
+
+// Run migrates pages until the source is exhausted, MaxPages pages have been
+// processed, or ctx is within DeadlineMargin of its deadline. It returns an
+// ErrConflict error when another Run holds the lease.
+func (m *Migration) Run(ctx context.Context, input MigrationInput) (MigrationReport, error) {
+	if m == nil || m.lister == nil {
+		return MigrationReport{}, ErrInvalidConfig
+	}
+	if input.StartPage < 0 {
+		return MigrationReport{}, NewError(ErrorCodeInvalidInput, "vectorstore: migration start page must not be negative", nil)
+	}
+	var report MigrationReport
+	paged, err := jobs.RunPaged(ctx, jobs.PagedJobConfig{
+		Ledger:         m.cfg.Ledger,
+		Name:           "vectorstore-migration",
+		JobID:          m.cfg.JobID,
+		TenantID:       m.cfg.TenantID,
+		Owner:          m.cfg.Owner,
+		MaxPages:       m.cfg.MaxPages,
+		LeaseDuration:  m.cfg.LeaseDuration,
+		DeadlineMargin: m.cfg.DeadlineMargin,
+		CheckpointTTL:  m.cfg.CheckpointTTL,
+	}, input.StartPage, func(ctx context.Context, _ int, at jobs.PageCheckpoint) (jobs.PageCheckpoint, error) {
+		next, records, skipped, err := m.page(ctx, at.State["cursor"])
+		report.Records += records
+		report.Skipped += skipped
+		return next, err
+	})
+	report.Pages, report.Replayed, report.NextPage, report.Done = paged.Pages, paged.Replayed, paged.NextPage, paged.Done
+	var jobsErr *jobs.Error
+	switch {
+	case errors.Is(err, jobs.ErrLeaseHeld):
+		return report, NewError(ErrorCodeConflict, "vectorstore: migration lease is held by another owner", err)
+	case errors.As(err, &jobsErr) && jobsErr.Type == jobs.ErrorTypeInvalidInput:
+		return report, NewError(ErrorCodeInvalidInput, "vectorstore: migration "+jobsErr.Message, err)
+	}
+	return report, err
+}
+
+// page re-embeds the records listed after cursor and returns the checkpoint
+// for the next page with how many records it wrote and skipped.
+func (m *Migration) page(ctx context.Context, cursor string) (jobs.PageCheckpoint, int, int, error) {
+	listed, err := m.lister.ListVectors(ctx, ListInput{Cursor: cursor, Limit: m.cfg.PageSize, ReturnMetadata: true})
+	if err != nil {
+		return jobs.PageCheckpoint{}, 0, 0, err
+	}
+	skipped := 0
+	records := make([]SemanticRecord, 0, len(listed.Records))
+	for _, record := range listed.Records {
+		text, err := m.cfg.Text(ctx, record)
+		if errors.Is(err, ErrNotFound) || (err == nil && strings.TrimSpace(text) == "") {
+			if err := m.skip(ctx, record.Key); err != nil {
+				return jobs.PageCheckpoint{}, 0, 0, err
+			}
+			skipped++
+			continue
+		}
+		if err != nil {
+			return jobs.PageCheckpoint{}, 0, 0, err
+		}
+		records = append(records, SemanticRecord{Key: record.Key, Text: text, Metadata: record.Metadata})
+	}
+	if len(records) > 0 {
+		if err := m.cfg.Target.PutText(ctx, records); err != nil {
+			return jobs.PageCheckpoint{}, 0, 0, err
+		}
+	}
+	return jobs.PageCheckpoint{
+		State: map[string]string{"cursor": listed.NextCursor, "records": strconv.Itoa(len(records)), "skipped": strconv.Itoa(skipped)},
+		Done:  listed.NextCursor == "",
+	}, len(records), skipped, nil
+}
+
+func (m *Migration) skip(ctx context.Context, key string) error {
+	_, err := m.cfg.Ledger.UpsertRecordStatus(ctx, jobs.UpsertRecordStatusInput{JobID: m.cfg.JobID, RecordID: key, Status: jobs.RecordStatusSkipped, TTL: m.cfg.CheckpointTTL})
+	return err
+}
+
+// RecallComparison compares one query's hits from a baseline and a candidate
+// index. Recall is the share of the baseline's keys the candidate also
+// returned, 1 when the baseline returned nothing. Missing lists baseline keys
+// the candidate lacked and Extra the reverse, both in rank order. Err is set
+// when the candidate query failed.
+type RecallComparison struct {
+	Query   string
+	Recall  float64
+	Missing []string
+	Extra   []string
+	Err     error
+}
+
+// RecallReport summarizes CompareRecall. MeanRecall and MinRecall exclude
+// failed queries, which are counted in Failed.
+type RecallReport struct {
+	Queries    []RecallComparison
+	MeanRecall float64
+	MinRecall  float64
+	Failed     int
+}
+
+// CompareRecall runs each query against baseline and candidate with the same
+// input and reports how much of the baseline's top K the candidate recovers.
+// Run it before cutting traffic over to a migrated index. A baseline failure
+// stops the comparison; candidate failures are recorded per query.
+func CompareRecall(ctx context.Context, baseline, candidate *SemanticIndex, queries []string, input QueryInput) (RecallReport, error) {
+	if baseline == nil || candidate == nil {
+		return RecallReport{}, ErrInvalidConfig
+	}
+	report := RecallReport{Queries: make([]RecallComparison, 0, len(queries)), MinRecall: 1}
+	var total float64
+	for _, query := range queries {
+		want, err := baseline.QueryText(ctx, query, input)
+		if err != nil {
+			return report, err
+		}
+		got, err := candidate.QueryText(ctx, query, input)
+		comparison := compareHits(query, want, got, err)
+		report.Queries = append(report.Queries, comparison)
+		if comparison.Err != nil {
+			report.Failed++
+			continue
+		}
+		total += comparison.Recall
+		report.MinRecall = min(report.MinRecall, comparison.Recall)
+	}
+	if compared := len(report.Queries) - report.Failed; compared > 0 {
+		report.MeanRecall = total / float64(compared)
+	} else {
+		report.MinRecall = 0
+	}
+	return report, nil
+}
+
+func compareHits(query string, want, got []QueryHit, err error) RecallComparison {
+	comparison := RecallComparison{Query: query, Err: err}
+	if err != nil {
+		return comparison
+	}
+	gotKeys := make(map[string]bool, len(got))
+	for _, hit := range got {
+		gotKeys[hit.Key] = true
+	}
+	wantKeys := make(map[string]bool, len(want))
+	for _, hit := range want {
+		wantKeys[hit.Key] = true
+		if !gotKeys[hit.Key] {
+			comparison.Missing = append(comparison.Missing, hit.Key)
+		}
+	}
+	for _, hit := range got {
+		if !wantKeys[hit.Key] {
+			comparison.Extra = append(comparison.Extra, hit.Key)
+		}
+	}
+	comparison.Recall = 1
+	if len(want) > 0 {
+		comparison.Recall = float64(len(want)-len(comparison.Missing)) / float64(len(want))
+	}
+	return comparison
+}
+
+// DualReadIndex serves queries from Primary while shadowing each one against
+// Shadow, so a migrated index can be compared on live traffic before cutover.
+// Both queries run concurrently and OnCompare receives the comparison after
+// both finish; a Shadow failure is reported there and never fails the read.
+type DualReadIndex struct {
+	Primary   *SemanticIndex
+	Shadow    *SemanticIndex
+	OnCompare func(context.Context, RecallComparison)
+}
+
+func (d *DualReadIndex) QueryText(ctx context.Context, text string, input QueryInput) ([]QueryHit, error) {
+	if d == nil || d.Primary == nil {
+		return nil, ErrInvalidConfig
+	}
+	if d.Shadow == nil || d.OnCompare == nil {
+		return d.Primary.QueryText(ctx, text, input)
+	}
+	var (
+		wg        sync.WaitGroup
+		shadow    []QueryHit
+		shadowErr error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		shadow, shadowErr = d.Shadow.QueryText(ctx, text, input)
+	}()
+	hits, err := d.Primary.QueryText(ctx, text, input)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	d.OnCompare(ctx, compareHits(text, hits, shadow, shadowErr))
+	return hits, nil
+}
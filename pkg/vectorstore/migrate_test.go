@@ -0,0 +1,246 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3vectors"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3vectors/types"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/jobs"
+	testkitjobs "github.com/theory-cloud/apptheory/v3/testkit/jobs"
+)
+
+func TestMigrationResumesAcrossRuns(t *testing.T) {
+	ctx := context.Background()
+	source := NewFakeStore(1)
+	for i := range 5 {
+		metadata := map[string]any{"text": fmt.Sprintf("text-%d", i), "tenant": "t1"}
+		if i == 3 {
+			metadata = map[string]any{"tenant": "t1"}
+		}
+		if err := source.PutVectors(ctx, PutInput{Records: []VectorRecord{{Key: fmt.Sprintf("doc-%d", i), Data: []float32{float32(i)}, Metadata: metadata}}}); err != nil {
+			t.Fatalf("PutVectors() error = %v", err)
+		}
+	}
+	target := NewFakeStore(2)
+	embedder := &scriptedEmbedder{}
+	ledger := testkitjobs.NewLedger()
+	cfg := MigrationConfig{Source: source, Target: &SemanticIndex{Store: target, Embedder: embedder, Dimension: 2}, Text: TextFromMetadata("text"), Ledger: ledger, JobID: "reembed", TenantID: "t1", Owner: "worker-a", PageSize: 2, MaxPages: 2}
+	migration, err := NewMigration(cfg)
+	if err != nil {
+		t.Fatalf("NewMigration() error = %v", err)
+	}
+
+	first, err := migration.Run(ctx, MigrationInput{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := (MigrationReport{Pages: 2, Records: 3, Skipped: 1, NextPage: 2}); first != want {
+		t.Fatalf("first Run() = %+v, want %+v", first, want)
+	}
+	if job, _ := ledger.Job("reembed"); ledger.RecordStatus("reembed", "doc-3") != jobs.RecordStatusSkipped || ledger.LeaseOwner("reembed") != "" || job.Status != jobs.JobStatusRunning {
+		t.Fatalf("ledger after first run = record %q, lease %q, job %+v", ledger.RecordStatus("reembed", "doc-3"), ledger.LeaseOwner("reembed"), job)
+	}
+
+	second, err := migration.Run(ctx, MigrationInput{StartPage: first.NextPage})
+	if err != nil {
+		t.Fatalf("Run() resume error = %v", err)
+	}
+	if want := (MigrationReport{Pages: 1, Records: 1, NextPage: 3, Done: true}); second != want {
+		t.Fatalf("second Run() = %+v, want %+v", second, want)
+	}
+	if job, _ := ledger.Job("reembed"); job.Status != jobs.JobStatusSucceeded {
+		t.Fatalf("job status = %s, want SUCCEEDED", job.Status)
+	}
+	got, err := target.GetVectors(ctx, GetInput{Keys: []string{"doc-0", "doc-4"}, ReturnMetadata: true})
+	if err != nil || !reflect.DeepEqual(got[1], VectorRecord{Key: "doc-4", Data: []float32{6, 1}, Metadata: map[string]any{"text": "text-4", "tenant": "t1"}}) {
+		t.Fatalf("target GetVectors() = %+v, %v", got, err)
+	}
+	if _, err := target.GetVectors(ctx, GetInput{Keys: []string{"doc-3"}}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("skipped record GetVectors() error = %v, want ErrNotFound", err)
+	}
+
+	// Walking from the start replays every checkpoint without re-embedding.
+	embedded := len(embedder.calls)
+	replay, err := migration.Run(ctx, MigrationInput{})
+	if err != nil || replay != (MigrationReport{Replayed: 3, NextPage: 3, Done: true}) || len(embedder.calls) != embedded {
+		t.Fatalf("replay Run() = %+v, %v with %d new embeddings", replay, err, len(embedder.calls)-embedded)
+	}
+	if done, err := migration.Run(ctx, MigrationInput{StartPage: 3}); err != nil || done != (MigrationReport{NextPage: 3, Done: true}) {
+		t.Fatalf("Run() past the end = %+v, %v", done, err)
+	}
+}
+
+func TestMigrationGuardsAndFailures(t *testing.T) {
+	ctx := context.Background()
+	source := NewFakeStore(1)
+	if err := source.PutVectors(ctx, PutInput{Records: []VectorRecord{{Key: "a", Data: []float32{1}, Metadata: map[string]any{"text": "alpha"}}}}); err != nil {
+		t.Fatalf("PutVectors() error = %v", err)
+	}
+	ledger := testkitjobs.NewLedger()
+	target := &SemanticIndex{Store: NewFakeStore(2), Embedder: &scriptedEmbedder{failures: map[string][]error{"alpha": {ErrEmbeddingThrottled}}}, Dimension: 2}
+	cfg := MigrationConfig{Source: source, Target: target, Text: TextFromMetadata("text"), Ledger: ledger, JobID: "job", TenantID: "t1", Owner: "worker-a"}
+	migration, err := NewMigration(cfg)
+	if err != nil {
+		t.Fatalf("NewMigration() error = %v", err)
+	}
+
+	ledger.SetLeaseOwner("job", "worker-b")
+	if _, err := migration.Run(ctx, MigrationInput{}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Run() with held lease error = %v, want ErrConflict", err)
+	}
+	ledger.SetLeaseOwner("job", "")
+
+	// A failed page stays in progress and is retried by the next Run.
+	if _, err := migration.Run(ctx, MigrationInput{}); !errors.Is(err, ErrEmbeddingThrottled) {
+		t.Fatalf("Run() error = %v, want ErrEmbeddingThrottled", err)
+	}
+	if report, err := migration.Run(ctx, MigrationInput{}); err != nil || report != (MigrationReport{Pages: 1, Records: 1, NextPage: 1, Done: true}) {
+		t.Fatalf("Run() retry = %+v, %v", report, err)
+	}
+	if _, err := migration.Run(ctx, MigrationInput{StartPage: 5}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("Run() from an unknown page error = %v, want ErrInvalidInput", err)
+	}
+
+	expired, cancel := context.WithTimeout(ctx, DefaultMigrationDeadlineMargin/2)
+	defer cancel()
+	fresh, err := NewMigration(MigrationConfig{Source: source, Target: target, Text: TextFromMetadata("text"), Ledger: testkitjobs.NewLedger(), JobID: "job", TenantID: "t1", Owner: "worker-a"})
+	if err != nil {
+		t.Fatalf("NewMigration() error = %v", err)
+	}
+	if report, err := fresh.Run(expired, MigrationInput{}); err != nil || report != (MigrationReport{}) {
+		t.Fatalf("Run() near the deadline = %+v, %v, want no pages", report, err)
+	}
+
+	for _, bad := range []MigrationConfig{{}, {Source: source, Text: cfg.Text, Ledger: ledger, JobID: "j", TenantID: "t", Owner: "o"}, {Source: source, Target: target, Text: cfg.Text, Ledger: ledger, JobID: "j", TenantID: "t"}, {Source: source, Target: target, Text: cfg.Text, Ledger: ledger, JobID: "j", TenantID: "t", Owner: "o", PageSize: MaxListLimit + 1}} {
+		if _, err := NewMigration(bad); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("NewMigration(%+v) error = %v, want ErrInvalidConfig", bad, err)
+		}
+	}
+}
+
+func TestCompareRecallAndDualRead(t *testing.T) {
+	ctx := context.Background()
+	baselineStore, candidateStore := NewFakeStore(1), NewFakeStore(1)
+	for _, store := range []*FakeStore{baselineStore, candidateStore} {
+		if err := store.PutVectors(ctx, PutInput{Records: []VectorRecord{{Key: "a", Data: []float32{1}}, {Key: "b", Data: []float32{2}}, {Key: "c", Data: []float32{5}}}}); err != nil {
+			t.Fatalf("PutVectors() error = %v", err)
+		}
+	}
+	if err := candidateStore.PutVectors(ctx, PutInput{Records: []VectorRecord{{Key: "b", Data: []float32{9}}, {Key: "d", Data: []float32{1}}}}); err != nil {
+		t.Fatalf("PutVectors() error = %v", err)
+	}
+	embedder := &fixedEmbedder{vectors: map[string][]float32{"one": {1}, "far": {9}}}
+	baseline := &SemanticIndex{Store: baselineStore, Embedder: embedder, Dimension: 1}
+	candidate := &SemanticIndex{Store: candidateStore, Embedder: embedder, Dimension: 1}
+
+	report, err := CompareRecall(ctx, baseline, candidate, []string{"one", "far"}, QueryInput{TopK: 2})
+	if err != nil {
+		t.Fatalf("CompareRecall() error = %v", err)
+	}
+	if len(report.Queries) != 2 || report.Queries[0].Recall != 0.5 || !reflect.DeepEqual(report.Queries[0].Missing, []string{"b"}) || !reflect.DeepEqual(report.Queries[0].Extra, []string{"d"}) {
+		t.Fatalf("CompareRecall() first query = %+v", report.Queries)
+	}
+	if report.MeanRecall != 0.75 || report.MinRecall != 0.5 || report.Failed != 0 {
+		t.Fatalf("CompareRecall() = %+v", report)
+	}
+
+	candidateStore.SetError("QueryVectors", errors.New("shadow down"))
+	var compared []RecallComparison
+	dual := &DualReadIndex{Primary: baseline, Shadow: candidate, OnCompare: func(_ context.Context, comparison RecallComparison) {
+		compared = append(compared, comparison)
+	}}
+	hits, err := dual.QueryText(ctx, "one", QueryInput{TopK: 2})
+	if err != nil || len(hits) != 2 || hits[0].Key != "a" {
+		t.Fatalf("DualReadIndex.QueryText() = %+v, %v", hits, err)
+	}
+	if len(compared) != 1 || compared[0].Err == nil {
+		t.Fatalf("comparisons = %+v, want one shadow failure", compared)
+	}
+	if report, err := CompareRecall(ctx, baseline, candidate, []string{"one"}, QueryInput{}); err != nil || report.Failed != 1 || report.MinRecall != 0 {
+		t.Fatalf("CompareRecall() with failing candidate = %+v, %v", report, err)
+	}
+}
+
+func TestListVectorsPagesInKeyOrder(t *testing.T) {
+	ctx := context.Background()
+	local, err := NewLocalStore(ctx, LocalStoreConfig{Dimension: 1})
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	if err := local.PutVectors(ctx, PutInput{Records: []VectorRecord{{Key: "c", Data: []float32{3}}, {Key: "a", Data: []float32{1}, Metadata: map[string]any{"n": 1}}, {Key: "b", Data: []float32{2}}}}); err != nil {
+		t.Fatalf("PutVectors() error = %v", err)
+	}
+	var keys []string
+	cursor := ""
+	for {
+		page, err := local.ListVectors(ctx, ListInput{Cursor: cursor, Limit: 2, ReturnMetadata: true})
+		if err != nil {
+			t.Fatalf("ListVectors() error = %v", err)
+		}
+		for _, record := range page.Records {
+			if record.Data != nil {
+				t.Fatalf("ListVectors() returned data without ReturnData: %+v", record)
+			}
+			keys = append(keys, record.Key)
+		}
+		if cursor = page.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if !reflect.DeepEqual(keys, []string{"a", "b", "c"}) {
+		t.Fatalf("listed keys = %q", keys)
+	}
+	if _, err := local.ListVectors(ctx, ListInput{Limit: MaxListLimit + 1}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("ListVectors() oversized limit error = %v, want ErrInvalidInput", err)
+	}
+
+	client := &listingS3VectorsClient{output: &s3vectors.ListVectorsOutput{NextToken: aws.String("next"), Vectors: []s3types.ListOutputVector{{Key: aws.String("k"), Data: &s3types.VectorDataMemberFloat32{Value: []float32{0.5}}}}}}
+	store := &S3VectorStore{Client: client, VectorBucketName: "bucket", IndexName: "index", Dimension: 1}
+	page, err := store.ListVectors(ctx, ListInput{Cursor: "prev", ReturnData: true})
+	if err != nil || page.NextCursor != "next" || !reflect.DeepEqual(page.Records, []VectorRecord{{Key: "k", Data: []float32{0.5}}}) {
+		t.Fatalf("S3 ListVectors() = %+v, %v", page, err)
+	}
+	if input := client.inputs[0]; aws.ToString(input.NextToken) != "prev" || aws.ToInt32(input.MaxResults) != DefaultListLimit || !input.ReturnData {
+		t.Fatalf("S3 ListVectors input = %+v", input)
+	}
+	if _, err := (&S3VectorStore{Client: &recordingS3VectorsClient{}, VectorBucketName: "bucket", IndexName: "index", Dimension: 1}).ListVectors(ctx, ListInput{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("S3 ListVectors() without list support error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+type listingS3VectorsClient struct {
+	recordingS3VectorsClient
+	inputs []*s3vectors.ListVectorsInput
+	output *s3vectors.ListVectorsOutput
+}
+
+func (c *listingS3VectorsClient) ListVectors(_ context.Context, input *s3vectors.ListVectorsInput, _ ...func(*s3vectors.Options)) (*s3vectors.ListVectorsOutput, error) {
+	c.inputs = append(c.inputs, input)
+	return c.output, nil
+}
+
+type fixedEmbedder struct {
+	vectors map[string][]float32
+}
+
+func (e *fixedEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	return CloneVector(e.vectors[text]), nil
+}
+
+func (e *fixedEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vector, err := e.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vector)
+	}
+	return out, nil
+}
@@ -0,0 +1,239 @@
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultOpenAIMaxBatchSize     = 128
+	DefaultOpenAIMaxResponseBytes = 64 << 20
+	defaultOpenAIBatchConcurrency = 4
+	maxOpenAIErrorMessageBytes    = 512
+)
+
+// OpenAIEmbedder calls an OpenAI-compatible embeddings API: OpenAI itself,
+// Azure OpenAI, or self-hosted servers such as vLLM, TEI, and Ollama.
+//
+// BaseURL includes the API version path, such as "https://api.openai.com/v1";
+// requests go to BaseURL + "/embeddings". Dimensions is required and every
+// returned vector is checked against it. SendDimensions also sends it as the
+// "dimensions" request field, which only models with shortened embeddings
+// accept.
+//
+// APIKey is sent as "Authorization: Bearer <key>" unless AuthHeader names a
+// different header, in which case the key is sent there verbatim (Azure uses
+// "api-key"). EmbedBatch sends up to MaxBatchSize inputs per request with at
+// most BatchConcurrency requests in flight. HTTP 429 and 502-504 responses
+// fail with ErrorCodeEmbeddingThrottled so CachedEmbedder can retry them.
+type OpenAIEmbedder struct {
+	BaseURL          string
+	Model            string
+	Dimensions       int
+	SendDimensions   bool
+	APIKey           string
+	AuthHeader       string
+	MaxBatchSize     int
+	BatchConcurrency int
+	MaxResponseBytes int64
+	HTTPClient       *http.Client
+}
+
+type openAIEmbedRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+type openAIEmbedResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (e *OpenAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := e.validateConfig(); err != nil {
+		return nil, err
+	}
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+	inputs := make([]string, len(texts))
+	for i, text := range texts {
+		inputs[i] = strings.TrimSpace(text)
+		if inputs[i] == "" {
+			return nil, NewError(ErrorCodeInvalidInput, "vectorstore: embedding input is required", nil)
+		}
+	}
+	size := positiveOr(e.MaxBatchSize, DefaultOpenAIMaxBatchSize)
+	var batches [][2]int
+	for start := 0; start < len(inputs); start += size {
+		batches = append(batches, [2]int{start, min(start+size, len(inputs))})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([][]float32, len(inputs))
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		slots    = make(chan struct{}, min(positiveOr(e.BatchConcurrency, defaultOpenAIBatchConcurrency), len(batches)))
+	)
+	for _, batch := range batches {
+		slots <- struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			vectors, err := e.embedRequest(ctx, inputs[start:end])
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			copy(results[start:end], vectors)
+		}(batch[0], batch[1])
+	}
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
+
+func (e *OpenAIEmbedder) validateConfig() error {
+	if e == nil || strings.TrimSpace(e.BaseURL) == "" || strings.TrimSpace(e.Model) == "" {
+		return NewError(ErrorCodeInvalidConfig, "vectorstore: openai embedder requires a base URL and model", nil)
+	}
+	if e.MaxBatchSize < 0 || e.BatchConcurrency < 0 || e.MaxResponseBytes < 0 {
+		return NewError(ErrorCodeInvalidConfig, "vectorstore: openai embedder limits must not be negative", nil)
+	}
+	return ValidateDimension(e.Dimensions)
+}
+
+func (e *OpenAIEmbedder) embedRequest(ctx context.Context, inputs []string) ([][]float32, error) {
+	request := openAIEmbedRequest{Model: strings.TrimSpace(e.Model), Input: inputs, EncodingFormat: "float"}
+	if e.SendDimensions {
+		request.Dimensions = e.Dimensions
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, NewError(ErrorCodeEmbeddingFailed, "vectorstore: marshal embedding request", err)
+	}
+	url := strings.TrimRight(strings.TrimSpace(e.BaseURL), "/") + "/embeddings"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, NewError(ErrorCodeInvalidConfig, "vectorstore: invalid openai embedder base URL", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if e.APIKey != "" {
+		if header := strings.TrimSpace(e.AuthHeader); header != "" {
+			req.Header.Set(header, e.APIKey)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+e.APIKey)
+		}
+	}
+	client := e.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req) // #nosec G107 -- BaseURL is operator configuration, not request input
+	if err != nil {
+		return nil, NewError(ErrorCodeEmbeddingFailed, "vectorstore: openai embedding request failed", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			_ = closeErr
+		}
+	}()
+	limit := e.MaxResponseBytes
+	if limit == 0 {
+		limit = DefaultOpenAIMaxResponseBytes
+	}
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, NewError(ErrorCodeEmbeddingFailed, "vectorstore: read embedding response", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, openAIStatusError(resp.StatusCode, payload)
+	}
+	if int64(len(payload)) > limit {
+		return nil, NewError(ErrorCodeEmbeddingFailed, "vectorstore: embedding response exceeds max bytes", nil)
+	}
+	return e.decodeResponse(payload, len(inputs))
+}
+
+func (e *OpenAIEmbedder) decodeResponse(payload []byte, count int) ([][]float32, error) {
+	var decoded openAIEmbedResponse
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, NewError(ErrorCodeEmbeddingFailed, "vectorstore: parse embedding response", err)
+	}
+	if len(decoded.Data) != count {
+		return nil, NewError(ErrorCodeEmbeddingFailed, fmt.Sprintf("vectorstore: embedding count mismatch: got %d want %d", len(decoded.Data), count), nil)
+	}
+	sort.SliceStable(decoded.Data, func(i, j int) bool { return decoded.Data[i].Index < decoded.Data[j].Index })
+	out := make([][]float32, count)
+	for i, item := range decoded.Data {
+		if item.Index != i {
+			return nil, NewError(ErrorCodeEmbeddingFailed, "vectorstore: embedding response indexes do not match inputs", nil)
+		}
+		if err := ValidateVector(item.Embedding, e.Dimensions); err != nil {
+			return nil, err
+		}
+		out[i] = item.Embedding
+	}
+	return out, nil
+}
+
+func openAIStatusError(status int, payload []byte) error {
+	code := ErrorCodeEmbeddingFailed
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		code = ErrorCodeEmbeddingThrottled
+	}
+	message := fmt.Sprintf("vectorstore: openai embedding request returned HTTP %d", status)
+	var decoded openAIErrorResponse
+	if json.Unmarshal(payload, &decoded) == nil && decoded.Error.Message != "" {
+		detail := decoded.Error.Message
+		if len(detail) > maxOpenAIErrorMessageBytes {
+			detail = detail[:maxOpenAIErrorMessageBytes]
+		}
+		message += ": " + detail
+	}
+	return NewError(code, message, nil)
+}
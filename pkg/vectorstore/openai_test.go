@@ -0,0 +1,123 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestOpenAIEmbedderBatchesAndValidates(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mu       sync.Mutex
+		requests []openAIEmbedRequest
+		status   = http.StatusOK
+		reply    func(openAIEmbedRequest) any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/embeddings" || r.Header.Get("api-key") != "secret" {
+			t.Errorf("request = %s %s with api-key %q", r.Method, r.URL.Path, r.Header.Get("api-key"))
+		}
+		var request openAIEmbedRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decode request error = %v", err)
+		}
+		mu.Lock()
+		requests = append(requests, request)
+		mu.Unlock()
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(reply(request)); err != nil {
+			t.Errorf("encode response error = %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// Replies list data in reverse so the embedder must reorder by index.
+	reply = func(request openAIEmbedRequest) any {
+		data := make([]map[string]any, 0, len(request.Input))
+		for i := len(request.Input) - 1; i >= 0; i-- {
+			data = append(data, map[string]any{"index": i, "embedding": []float32{float32(len(request.Input[i])), 0.5}})
+		}
+		return map[string]any{"data": data}
+	}
+	embedder := &OpenAIEmbedder{BaseURL: server.URL + "/v1/", Model: "text-embed", Dimensions: 2, SendDimensions: true, APIKey: "secret", AuthHeader: "api-key", MaxBatchSize: 2, HTTPClient: server.Client()}
+	got, err := embedder.EmbedBatch(ctx, []string{"a", " bb ", "ccc", "dddd", "eeeee"})
+	if err != nil {
+		t.Fatalf("EmbedBatch() error = %v", err)
+	}
+	if want := [][]float32{{1, 0.5}, {2, 0.5}, {3, 0.5}, {4, 0.5}, {5, 0.5}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("EmbedBatch() = %v, want %v", got, want)
+	}
+	if len(requests) != 3 || requests[0].Model != "text-embed" || requests[0].Dimensions != 2 || requests[0].EncodingFormat != "float" {
+		t.Fatalf("requests = %#v", requests)
+	}
+	for _, request := range requests {
+		if len(request.Input) > 2 {
+			t.Fatalf("request inputs = %q, want at most 2", request.Input)
+		}
+	}
+
+	reply = func(openAIEmbedRequest) any {
+		return map[string]any{"data": []map[string]any{{"index": 0, "embedding": []float32{1, 2, 3}}}}
+	}
+	if _, err := embedder.Embed(ctx, "x"); !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatalf("Embed() wrong dimension error = %v, want ErrDimensionMismatch", err)
+	}
+	reply = func(openAIEmbedRequest) any { return map[string]any{"data": []any{}} }
+	if _, err := embedder.Embed(ctx, "x"); !errors.Is(err, ErrEmbeddingFailed) {
+		t.Fatalf("Embed() missing data error = %v, want ErrEmbeddingFailed", err)
+	}
+
+	status = http.StatusTooManyRequests
+	reply = func(openAIEmbedRequest) any {
+		return map[string]any{"error": map[string]any{"message": "rate limited"}}
+	}
+	if _, err := embedder.Embed(ctx, "x"); EmbeddingErrorCode(err) != ErrorCodeEmbeddingThrottled || err.Error() != "vectorstore: openai embedding request returned HTTP 429: rate limited" {
+		t.Fatalf("Embed() 429 error = %v, want ErrEmbeddingThrottled", err)
+	}
+	status = http.StatusBadRequest
+	if _, err := embedder.Embed(ctx, "x"); EmbeddingErrorCode(err) != ErrorCodeEmbeddingFailed {
+		t.Fatalf("Embed() 400 error = %v, want ErrEmbeddingFailed", err)
+	}
+
+	for _, bad := range []*OpenAIEmbedder{nil, {Model: "m", Dimensions: 2}, {BaseURL: server.URL, Model: "m"}, {BaseURL: server.URL, Model: "m", Dimensions: 2, MaxBatchSize: -1}} {
+		if _, err := bad.EmbedBatch(ctx, []string{"x"}); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("EmbedBatch() with %#v error = %v, want ErrInvalidConfig", bad, err)
+		}
+	}
+	if _, err := embedder.EmbedBatch(ctx, []string{"x", " "}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("EmbedBatch() blank error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestOpenAIEmbedderDefaultsToBearerAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var request map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decode request error = %v", err)
+		}
+		if _, ok := request["dimensions"]; ok {
+			t.Errorf("request sent dimensions without SendDimensions: %v", request)
+		}
+		_, _ = w.Write([]byte(`{"data":[{"index":0,"embedding":[0.25]}]}`))
+	}))
+	defer server.Close()
+
+	embedder := &OpenAIEmbedder{BaseURL: server.URL, Model: "m", Dimensions: 1, APIKey: "sk-test"}
+	got, err := embedder.Embed(context.Background(), "hello")
+	if err != nil || !reflect.DeepEqual(got, []float32{0.25}) {
+		t.Fatalf("Embed() = %v, %v", got, err)
+	}
+	embedder.MaxResponseBytes = 8
+	if _, err := embedder.Embed(context.Background(), "hello"); !errors.Is(err, ErrEmbeddingFailed) {
+		t.Fatalf("Embed() oversized error = %v, want ErrEmbeddingFailed", err)
+	}
+}
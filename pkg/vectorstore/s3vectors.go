@@ -19,6 +19,13 @@ type S3VectorsAPI interface {
 	DeleteVectors(context.Context, *s3vectors.DeleteVectorsInput, ...func(*s3vectors.Options)) (*s3vectors.DeleteVectorsOutput, error)
 }
 
+// S3VectorsListAPI is the optional client surface S3VectorStore.ListVectors
+// needs. The SDK client implements it; S3VectorsAPI omits it so existing test
+// doubles keep compiling.
+type S3VectorsListAPI interface {
+	ListVectors(context.Context, *s3vectors.ListVectorsInput, ...func(*s3vectors.Options)) (*s3vectors.ListVectorsOutput, error)
+}
+
 type S3VectorStore struct {
 	Client           S3VectorsAPI
 	VectorBucketName string
@@ -90,8 +97,12 @@ func (s *S3VectorStore) QueryVectors(ctx context.Context, input QueryInput) ([]Q
 	// #nosec G115 -- NormalizeTopK clamps TopK to MaxQueryTopK (10000), which fits int32.
 	topK32 := int32(topK)
 	params := &s3vectors.QueryVectorsInput{VectorBucketName: aws.String(s.VectorBucketName), IndexName: aws.String(s.IndexName), QueryVector: &s3types.VectorDataMemberFloat32{Value: input.Vector}, TopK: aws.Int32(topK32), ReturnDistance: true, ReturnMetadata: input.ReturnMetadata}
-	if len(input.Filter) > 0 {
-		params.Filter = s3document.NewLazyDocument(input.Filter)
+	filter, err := queryFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	if filter != nil {
+		params.Filter = s3document.NewLazyDocument(filter.s3Document())
 	}
 	out, err := s.Client.QueryVectors(ctx, params)
 	if err != nil {
@@ -132,6 +143,40 @@ func (s *S3VectorStore) GetVectors(ctx context.Context, input GetInput) ([]Vecto
 	return records, nil
 }
 
+// ListVectors pages through the index with the S3 Vectors ListVectors API. It
+// fails with ErrInvalidConfig when Client does not implement S3VectorsListAPI.
+func (s *S3VectorStore) ListVectors(ctx context.Context, input ListInput) (ListOutput, error) {
+	if err := s.validateConfig(); err != nil {
+		return ListOutput{}, err
+	}
+	client, ok := s.Client.(S3VectorsListAPI)
+	if !ok {
+		return ListOutput{}, NewError(ErrorCodeInvalidConfig, "vectorstore: s3 vectors client does not support ListVectors", nil)
+	}
+	limit, err := listLimit(input.Limit)
+	if err != nil {
+		return ListOutput{}, err
+	}
+	request := &s3vectors.ListVectorsInput{VectorBucketName: aws.String(s.VectorBucketName), IndexName: aws.String(s.IndexName), MaxResults: aws.Int32(int32(limit)), ReturnData: input.ReturnData, ReturnMetadata: input.ReturnMetadata} // #nosec G115 -- limit is at most MaxListLimit
+	if input.Cursor != "" {
+		request.NextToken = aws.String(input.Cursor)
+	}
+	out, err := client.ListVectors(ctx, request)
+	if err != nil {
+		return ListOutput{}, NewError(ErrorCodeInvalidInput, "vectorstore: list vectors failed", err)
+	}
+	records := make([]VectorRecord, 0, len(out.Vectors))
+	for _, vector := range out.Vectors {
+		record := VectorRecord{Key: aws.ToString(vector.Key)}
+		if data, ok := vector.Data.(*s3types.VectorDataMemberFloat32); ok {
+			record.Data = CloneVector(data.Value)
+		}
+		record.Metadata = decodeS3Metadata(vector.Metadata)
+		records = append(records, record)
+	}
+	return ListOutput{Records: records, NextCursor: aws.ToString(out.NextToken)}, nil
+}
+
 func (s *S3VectorStore) DeleteVectors(ctx context.Context, input DeleteInput) error {
 	if err := s.validateConfig(); err != nil {
 		return err
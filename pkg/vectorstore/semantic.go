@@ -16,6 +16,11 @@ type SemanticIndex struct {
 	Embedder             Embedder
 	Dimension            int
 	RequiredMetadataKeys []string
+	// Splitter chunks documents for PutDocuments; PutText and QueryText ignore it.
+	Splitter Splitter
+	// Lexical, when set, is kept in step with Store by PutText and PutDocuments
+	// and backs HybridQuery.
+	Lexical *LexicalIndex
 }
 
 func (i *SemanticIndex) PutText(ctx context.Context, records []SemanticRecord) error {
@@ -52,7 +57,22 @@ func (i *SemanticIndex) PutText(ctx context.Context, records []SemanticRecord) e
 		}
 		vectors = append(vectors, VectorRecord{Key: record.Key, Data: embeddings[idx], Metadata: CloneMetadata(record.Metadata)})
 	}
-	return i.Store.PutVectors(ctx, PutInput{Records: vectors})
+	if err := i.Store.PutVectors(ctx, PutInput{Records: vectors}); err != nil {
+		return err
+	}
+	return i.putLexical(ctx, records)
+}
+
+// putLexical mirrors records into the Lexical index, when there is one.
+func (i *SemanticIndex) putLexical(ctx context.Context, records []SemanticRecord) error {
+	if i.Lexical == nil {
+		return nil
+	}
+	documents := make([]LexicalDocument, 0, len(records))
+	for _, record := range records {
+		documents = append(documents, LexicalDocument{Key: record.Key, Text: record.Text, Metadata: record.Metadata})
+	}
+	return i.Lexical.Put(ctx, documents)
 }
 
 func (i *SemanticIndex) QueryText(ctx context.Context, text string, input QueryInput) ([]QueryHit, error) {
@@ -19,6 +19,8 @@ const (
 	DefaultQueryTopK           = 12
 	MaxQueryTopK               = 10000
 	MaxPutDeleteBatchSize      = 500
+	DefaultListLimit           = 500
+	MaxListLimit               = 1000
 )
 
 type VectorRecord struct {
@@ -40,17 +42,41 @@ type DeleteInput struct {
 	Keys []string
 }
 
+// ListInput pages through every vector in a store. Cursor is the NextCursor of
+// the previous page, empty for the first; Limit defaults to DefaultListLimit.
+type ListInput struct {
+	Cursor         string
+	Limit          int
+	ReturnData     bool
+	ReturnMetadata bool
+}
+
+// ListOutput is one page of vectors. NextCursor is empty on the last page.
+type ListOutput struct {
+	Records    []VectorRecord
+	NextCursor string
+}
+
+// QueryInput filters by metadata with either Filter, a document in the S3
+// Vectors filter syntax, or Where, a typed Filter. Setting both is an error.
 type QueryInput struct {
 	Vector         []float32
 	TopK           int
 	Filter         map[string]any
+	Where          *Filter
 	ReturnMetadata bool
 }
 
+// QueryHit is one search result. Vector stores set Distance, lower is closer.
+// LexicalIndex sets Score to the BM25 score, and HybridQuery sets Score to the
+// fused score and Components to how each retriever contributed; for both,
+// higher is better.
 type QueryHit struct {
-	Key      string         `json:"key"`
-	Distance float32        `json:"distance"`
-	Metadata map[string]any `json:"metadata,omitempty"`
+	Key        string           `json:"key"`
+	Distance   float32          `json:"distance"`
+	Metadata   map[string]any   `json:"metadata,omitempty"`
+	Score      float64          `json:"score,omitempty"`
+	Components []ScoreComponent `json:"components,omitempty"`
 }
 
 type Call struct {
@@ -60,6 +86,7 @@ type Call struct {
 	Vector         []float32
 	TopK           int
 	Filter         map[string]any
+	Where          *Filter
 	ReturnMetadata bool
 }
 
@@ -70,6 +97,13 @@ type Store interface {
 	QueryVectors(context.Context, QueryInput) ([]QueryHit, error)
 }
 
+// Lister is implemented by stores that can enumerate their vectors, which
+// Migration needs to read a source index. Cursors are opaque and only valid
+// for the store that issued them.
+type Lister interface {
+	ListVectors(context.Context, ListInput) (ListOutput, error)
+}
+
 type Embedder interface {
 	Embed(context.Context, string) ([]float32, error)
 	EmbedBatch(context.Context, []string) ([][]float32, error)
@@ -45,6 +45,16 @@ func NormalizeTopK(topK int) int {
 	return topK
 }
 
+func listLimit(limit int) (int, error) {
+	if limit < 0 || limit > MaxListLimit {
+		return 0, NewError(ErrorCodeInvalidInput, "vectorstore: list limit must be between 1 and 1000", nil)
+	}
+	if limit == 0 {
+		return DefaultListLimit, nil
+	}
+	return limit, nil
+}
+
 func ValidateRequiredMetadata(metadata map[string]any, required []string) error {
 	for _, key := range required {
 		key = strings.TrimSpace(key)
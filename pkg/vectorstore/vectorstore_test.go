@@ -10,6 +10,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	bedrocktypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3vectors"
 	s3document "github.com/aws/aws-sdk-go-v2/service/s3vectors/document"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3vectors/types"
@@ -304,6 +305,10 @@ func TestEmbeddersAndSemanticIndexFailClosed(t *testing.T) {
 	if _, embedErr := titan.Embed(ctx, "boom"); !errors.Is(embedErr, ErrEmbeddingFailed) {
 		t.Fatalf("TitanEmbedder client error = %v, want ErrEmbeddingFailed", embedErr)
 	}
+	runtime.err = &bedrocktypes.ThrottlingException{Message: aws.String("slow down")}
+	if _, embedErr := titan.Embed(ctx, "busy"); EmbeddingErrorCode(embedErr) != ErrorCodeEmbeddingThrottled {
+		t.Fatalf("TitanEmbedder throttling error = %v, want ErrEmbeddingThrottled", embedErr)
+	}
 	runtime.err = nil
 	runtime.body = []byte(`{"embedding":[1]}`)
 	if _, embedErr := titan.Embed(ctx, "short"); !errors.Is(embedErr, ErrDimensionMismatch) {
@@ -24,9 +24,14 @@ type App struct {
 
 	sqsRoutes         []sqsRoute
 	kinesisRoutes     []kinesisRoute
+	kafkaRoutes       []kafkaRoute
+	kafkaPartialBatch bool
 	snsRoutes         []snsRoute
 	eventBridgeRoutes []eventBridgeRoute
 	dynamoDBRoutes    []dynamoDBRoute
+	s3Routes          []s3Route
+	cognitoRoutes     []cognitoRoute
+	authorizer        AuthorizerHandler
 
 	webSocketEnabled       bool
 	webSocketRoutes        []webSocketRoute
@@ -41,6 +41,26 @@ func (s *stubArtifactStore) Delete(context.Context, objectstore.DeleteInput) err
 	return errors.New("unexpected Delete call")
 }
 
+func (s *stubArtifactStore) PutReader(context.Context, objectstore.PutReaderInput) (objectstore.ObjectRef, error) {
+	return objectstore.ObjectRef{}, errors.New("unexpected PutReader call")
+}
+
+func (s *stubArtifactStore) GetReader(context.Context, objectstore.GetInput) (*objectstore.GetReaderOutput, error) {
+	return nil, errors.New("unexpected GetReader call")
+}
+
+func (s *stubArtifactStore) Head(context.Context, objectstore.HeadInput) (*objectstore.ObjectInfo, error) {
+	return nil, errors.New("unexpected Head call")
+}
+
+func (s *stubArtifactStore) List(context.Context, objectstore.ListInput) (*objectstore.ListOutput, error) {
+	return nil, errors.New("unexpected List call")
+}
+
+func (s *stubArtifactStore) Copy(context.Context, objectstore.CopyInput) (objectstore.ObjectRef, error) {
+	return objectstore.ObjectRef{}, errors.New("unexpected Copy call")
+}
+
 func TestVerifyVersionedArtifactRequiresRequestedVersion(t *testing.T) {
 	t.Parallel()
 
@@ -4,5 +4,6 @@
 // contract rather than raw service clients. AssumeFirst eagerly assumes one role
 // and proves its account identity before returning an ephemeral credentials provider.
 // VerifyVersionedArtifact accepts only a version-pinned object whose returned version
-// and archive-derived aggregate digest match the caller's pins.
+// and archive-derived aggregate digest match the caller's pins. PresignedJSON and
+// PresignedRedirect return objectstore presigned requests from HTTP handlers.
 package runtimeaws
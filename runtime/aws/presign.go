@@ -0,0 +1,48 @@
+package runtimeaws
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/objectstore"
+	apptheory "github.com/theory-cloud/apptheory/v3/runtime"
+)
+
+// ErrPresignedRequestInvalid is returned when a presigned request cannot be
+// delivered by the requested response helper.
+var ErrPresignedRequestInvalid = errors.New("apptheory runtime aws: invalid presigned request")
+
+// PresignedJSON builds a 200 application/json response carrying a presigned
+// request for the client to send directly to S3.
+//
+// The response is marked no-store because the URL is a bearer credential until
+// it expires.
+func PresignedJSON(req *objectstore.PresignedRequest) (*apptheory.Response, error) {
+	if req == nil || req.URL == "" {
+		return nil, ErrPresignedRequestInvalid
+	}
+	resp, err := apptheory.JSON(http.StatusOK, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.SetHeader("cache-control", "no-store"), nil
+}
+
+// PresignedRedirect builds a 307 redirect to a presigned GET URL so browsers
+// download the object without the bytes passing through the handler.
+//
+// Only GET requests without required headers can be followed by a redirect;
+// anything else fails with ErrPresignedRequestInvalid and must be returned with
+// PresignedJSON instead.
+func PresignedRedirect(req *objectstore.PresignedRequest) (*apptheory.Response, error) {
+	if req == nil || req.URL == "" || req.Method != http.MethodGet || len(req.Headers) > 0 {
+		return nil, ErrPresignedRequestInvalid
+	}
+	return &apptheory.Response{
+		Status: http.StatusTemporaryRedirect,
+		Headers: map[string][]string{
+			"location":      {req.URL},
+			"cache-control": {"no-store"},
+		},
+	}, nil
+}
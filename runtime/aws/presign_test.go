@@ -0,0 +1,72 @@
+package runtimeaws
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/objectstore"
+)
+
+func TestPresignedJSON(t *testing.T) {
+	t.Parallel()
+
+	req := &objectstore.PresignedRequest{
+		Method:    "PUT",
+		URL:       "https://bucket.s3.amazonaws.com/key?X-Amz-Signature=abc",
+		Headers:   map[string]string{"Content-Length": "42"},
+		ExpiresAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	resp, err := PresignedJSON(req)
+	if err != nil {
+		t.Fatalf("PresignedJSON returned error: %v", err)
+	}
+	if resp.Status != 200 {
+		t.Fatalf("expected status 200, got %d", resp.Status)
+	}
+	if cc := resp.Headers["cache-control"]; len(cc) != 1 || cc[0] != "no-store" {
+		t.Fatalf("unexpected cache-control: %v", cc)
+	}
+
+	var parsed map[string]any
+	if err := json.Unmarshal(resp.Body, &parsed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if parsed["method"] != "PUT" || parsed["url"] != req.URL || parsed["expires_at"] != "2026-01-02T03:04:05Z" {
+		t.Fatalf("unexpected body: %s", resp.Body)
+	}
+	if headers, ok := parsed["headers"].(map[string]any); !ok || headers["Content-Length"] != "42" {
+		t.Fatalf("unexpected headers: %v", parsed["headers"])
+	}
+
+	if _, err := PresignedJSON(nil); !errors.Is(err, ErrPresignedRequestInvalid) {
+		t.Fatalf("PresignedJSON(nil) error = %v, want ErrPresignedRequestInvalid", err)
+	}
+}
+
+func TestPresignedRedirect(t *testing.T) {
+	t.Parallel()
+
+	req := &objectstore.PresignedRequest{Method: "GET", URL: "https://bucket.s3.amazonaws.com/key?X-Amz-Signature=abc"}
+	resp, err := PresignedRedirect(req)
+	if err != nil {
+		t.Fatalf("PresignedRedirect returned error: %v", err)
+	}
+	if resp.Status != 307 {
+		t.Fatalf("expected status 307, got %d", resp.Status)
+	}
+	if loc := resp.Headers["location"]; len(loc) != 1 || loc[0] != req.URL {
+		t.Fatalf("unexpected location: %v", loc)
+	}
+
+	for _, bad := range []*objectstore.PresignedRequest{
+		nil,
+		{Method: "PUT", URL: req.URL},
+		{Method: "GET", URL: req.URL, Headers: map[string]string{"X-Amz-Server-Side-Encryption-Customer-Key": "k"}},
+	} {
+		if _, err := PresignedRedirect(bad); !errors.Is(err, ErrPresignedRequestInvalid) {
+			t.Fatalf("PresignedRedirect(%#v) error = %v, want ErrPresignedRequestInvalid", bad, err)
+		}
+	}
+}
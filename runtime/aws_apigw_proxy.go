@@ -18,7 +18,7 @@ func (a *App) ServeAPIGatewayProxy(ctx context.Context, event events.APIGatewayP
 	if err != nil {
 		return apigatewayProxyResponseFromResponse(a.responseForHTTPError(err))
 	}
-	return apigatewayProxyResponseFromResponse(a.Serve(ctx, req))
+	return apigatewayProxyResponseFromResponse(a.serveWithOptions(ctx, req, withAuthorizerContext(event.RequestContext.Authorizer)))
 }
 
 func (a *App) serveAPIGatewayProxyLambda(ctx context.Context, event events.APIGatewayProxyRequest) any {
@@ -32,7 +32,7 @@ func (a *App) serveAPIGatewayProxyLambda(ctx context.Context, event events.APIGa
 		return apigatewayProxyResponseFromResponse(a.responseForHTTPError(err))
 	}
 
-	resp := a.Serve(ctx, req)
+	resp := a.serveWithOptions(ctx, req, withAuthorizerContext(event.RequestContext.Authorizer))
 	if streamingRoute {
 		return apigatewayProxyStreamingResponseFromResponse(resp)
 	}
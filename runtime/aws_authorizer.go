@@ -0,0 +1,428 @@
+package apptheory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// MaxAuthorizerContextBytes bounds the encoded size of an authorizer context.
+// API Gateway rejects larger authorizer responses at request time, so the
+// runtime fails the authorizer instead.
+const MaxAuthorizerContextBytes = 8 * 1024
+
+const (
+	authorizerTypeToken   = "TOKEN"
+	authorizerTypeRequest = "REQUEST"
+
+	authorizerPolicyVersion = "2012-10-17"
+	authorizerPolicyAction  = "execute-api:Invoke"
+
+	authorizerContextIdentity = "apptheory_identity"
+	authorizerContextScopes   = "apptheory_scopes"
+	authorizerContextKind     = "apptheory_kind"
+)
+
+// AuthorizerRequest exposes the authorizer-specific fields of an API Gateway
+// Lambda authorizer invocation. Headers, query, and source provenance are on
+// Context.Request as for any other request.
+type AuthorizerRequest struct {
+	// Type is "TOKEN" or "REQUEST".
+	Type string
+	// Version is "2.0" for HTTP API simple-response authorizers and empty for
+	// REST API authorizers.
+	Version        string
+	MethodARN      string
+	RouteARN       string
+	RouteKey       string
+	Token          string
+	IdentitySource []string
+	PathParameters map[string]string
+	StageVariables map[string]string
+}
+
+// AuthorizerResult is the decision returned by an AuthorizerHandler.
+//
+// For REST API authorizers it becomes an IAM policy for Resources (the invoked
+// method ARN when empty). For HTTP API authorizers it becomes a simple response
+// and PrincipalID, Resources, and UsageIdentifierKey are ignored. Context
+// values must be strings, numbers, or booleans and are passed to the backend
+// integration.
+type AuthorizerResult struct {
+	Allow              bool
+	PrincipalID        string
+	Resources          []string
+	Context            map[string]any
+	UsageIdentifierKey string
+}
+
+// AuthorizerHandler decides an API Gateway Lambda authorizer invocation.
+// Returning an error with code app.unauthorized makes API Gateway answer 401.
+type AuthorizerHandler func(*Context) (AuthorizerResult, error)
+
+// Authorizer registers the handler for API Gateway Lambda authorizer events.
+// An app has at most one authorizer; later registrations replace earlier ones.
+func (a *App) Authorizer(handler AuthorizerHandler) *App {
+	if a == nil || handler == nil {
+		return a
+	}
+	a.authorizer = handler
+	return a
+}
+
+// AsAuthorizer returns the authorizer metadata when the context belongs to an
+// authorizer invocation.
+func (c *Context) AsAuthorizer() *AuthorizerRequest {
+	if c == nil {
+		return nil
+	}
+	return c.authorizer
+}
+
+// AuthorizerContext returns a copy of the context set by an upstream Lambda
+// authorizer for API Gateway REST and HTTP API requests, or nil.
+func (c *Context) AuthorizerContext() map[string]any {
+	if c == nil || len(c.authorizerContext) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(c.authorizerContext))
+	for key, value := range c.authorizerContext {
+		out[key] = value
+	}
+	return out
+}
+
+func withAuthorizerContext(values map[string]any) serveOptions {
+	if len(values) == 0 {
+		return serveOptions{}
+	}
+	return serveOptions{configure: func(requestCtx *Context) {
+		requestCtx.authorizerContext = values
+	}}
+}
+
+// authorizerUnauthorized is the exact error API Gateway REST authorizers use to
+// answer 401 instead of 500.
+type authorizerUnauthorized struct{}
+
+func (authorizerUnauthorized) Error() string { return "Unauthorized" }
+
+var errAuthorizerFailed = errors.New("apptheory: authorizer failed")
+
+// ServeAPIGatewayTokenAuthorizer handles a REST API TOKEN authorizer. The token
+// is exposed as the Authorization header and through AsAuthorizer().Token.
+func (a *App) ServeAPIGatewayTokenAuthorizer(
+	ctx context.Context,
+	event events.APIGatewayCustomAuthorizerRequest,
+) (events.APIGatewayCustomAuthorizerResponse, error) {
+	method, path := methodARNRoute(event.MethodArn)
+	req := Request{
+		Method:           method,
+		Path:             path,
+		Headers:          map[string][]string{"authorization": {event.AuthorizationToken}},
+		SourceProvenance: unknownSourceProvenance(),
+	}
+	meta := AuthorizerRequest{
+		Type:      authorizerTypeToken,
+		MethodARN: event.MethodArn,
+		Token:     event.AuthorizationToken,
+	}
+	result, err := a.runAuthorizer(ctx, req, meta)
+	if err != nil {
+		return events.APIGatewayCustomAuthorizerResponse{}, err
+	}
+	return restAuthorizerResponse(result, event.MethodArn)
+}
+
+// ServeAPIGatewayRequestAuthorizer handles a REST API REQUEST authorizer.
+func (a *App) ServeAPIGatewayRequestAuthorizer(
+	ctx context.Context,
+	event events.APIGatewayCustomAuthorizerRequestTypeRequest,
+) (events.APIGatewayCustomAuthorizerResponse, error) {
+	method := event.HTTPMethod
+	if method == "" {
+		method = event.RequestContext.HTTPMethod
+	}
+	path := event.Path
+	if path == "" {
+		path = event.RequestContext.Path
+	}
+	req := Request{
+		Method:  method,
+		Path:    path,
+		Query:   queryFromProxyEvent(event.QueryStringParameters, event.MultiValueQueryStringParameters),
+		Headers: headersFromProxyEvent(event.Headers, event.MultiValueHeaders),
+		SourceProvenance: sourceProvenanceFromProviderRequestContext(
+			sourceProvenanceProviderAPIGatewayV1,
+			event.RequestContext.Identity.SourceIP,
+		),
+	}
+	meta := AuthorizerRequest{
+		Type:           authorizerTypeRequest,
+		MethodARN:      event.MethodArn,
+		PathParameters: event.PathParameters,
+		StageVariables: event.StageVariables,
+	}
+	result, err := a.runAuthorizer(ctx, req, meta)
+	if err != nil {
+		return events.APIGatewayCustomAuthorizerResponse{}, err
+	}
+	return restAuthorizerResponse(result, event.MethodArn)
+}
+
+// ServeAPIGatewayV2Authorizer handles an HTTP API REQUEST authorizer that uses
+// payload format 2.0 with simple responses. An unauthorized handler error
+// denies the request instead of failing the invocation.
+func (a *App) ServeAPIGatewayV2Authorizer(
+	ctx context.Context,
+	event events.APIGatewayV2CustomAuthorizerV2Request,
+) (events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+	req, err := requestFromHTTPEvent(
+		event.RawQueryString,
+		event.QueryStringParameters,
+		event.Headers,
+		event.Cookies,
+		normalizeAPIGatewayV2StagePath(event.RawPath, event.RequestContext.HTTP.Path, event.RequestContext.Stage),
+		event.RequestContext.HTTP.Method,
+		event.RequestContext.HTTP.Path,
+		"",
+		false,
+	)
+	if err != nil {
+		return events.APIGatewayV2CustomAuthorizerSimpleResponse{}, err
+	}
+	req.SourceProvenance = sourceProvenanceFromProviderRequestContext(
+		sourceProvenanceProviderAPIGatewayV2,
+		event.RequestContext.HTTP.SourceIP,
+	)
+	meta := AuthorizerRequest{
+		Type:           authorizerTypeRequest,
+		Version:        event.Version,
+		RouteARN:       event.RouteArn,
+		RouteKey:       event.RouteKey,
+		IdentitySource: append([]string(nil), event.IdentitySource...),
+		PathParameters: event.PathParameters,
+		StageVariables: event.StageVariables,
+	}
+
+	result, err := a.runAuthorizer(ctx, req, meta)
+	var unauthorized authorizerUnauthorized
+	if errors.As(err, &unauthorized) {
+		return events.APIGatewayV2CustomAuthorizerSimpleResponse{IsAuthorized: false}, nil
+	}
+	if err != nil {
+		return events.APIGatewayV2CustomAuthorizerSimpleResponse{}, err
+	}
+	authContext, err := validateAuthorizerContext(result.Context)
+	if err != nil {
+		return events.APIGatewayV2CustomAuthorizerSimpleResponse{}, err
+	}
+	return events.APIGatewayV2CustomAuthorizerSimpleResponse{IsAuthorized: result.Allow, Context: authContext}, nil
+}
+
+func (a *App) runAuthorizer(ctx context.Context, req Request, meta AuthorizerRequest) (result AuthorizerResult, err error) {
+	if a == nil || a.authorizer == nil {
+		return AuthorizerResult{}, errors.New("apptheory: no authorizer registered")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	normalized, err := normalizeRequest(req)
+	if err != nil {
+		return AuthorizerResult{}, err
+	}
+
+	requestCtx := &Context{
+		ctx:         ctx,
+		Request:     normalized,
+		clock:       a.clock,
+		ids:         a.ids,
+		RequestID:   a.lambdaRequestID(ctx),
+		TraceID:     normalized.TraceID,
+		TenantID:    extractTenantID(normalized.Headers, normalized.Query),
+		RemainingMS: remainingMSFromContext(ctx, a.clock),
+		authorizer:  &meta,
+	}
+
+	observation := eventObservation{
+		Trigger:       eventTriggerAuthorizer,
+		RequestID:     requestCtx.RequestID,
+		CorrelationID: requestCtx.RequestID,
+		Source:        meta.Type,
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = AuthorizerResult{}, errAuthorizerFailed
+		}
+		a.recordAuthorizerObservability(observation, err)
+	}()
+
+	result, err = a.authorizer(requestCtx)
+	if err != nil {
+		if errorCodeForError(err) == errorCodeUnauthorized {
+			return AuthorizerResult{}, authorizerUnauthorized{}
+		}
+		return AuthorizerResult{}, errAuthorizerFailed
+	}
+	return result, nil
+}
+
+// recordAuthorizerObservability reports the authorizer outcome; the token and
+// the handler error stay out of the record.
+func (a *App) recordAuthorizerObservability(observation eventObservation, err error) {
+	var unauthorized authorizerUnauthorized
+	switch {
+	case err == nil:
+		a.recordEventObservability(observation, "success", "")
+	case errors.As(err, &unauthorized):
+		a.recordEventObservability(observation, "error", errorCodeUnauthorized)
+	default:
+		a.recordEventObservability(observation, "error", errorCodeInternal)
+	}
+}
+
+func restAuthorizerResponse(result AuthorizerResult, methodARN string) (events.APIGatewayCustomAuthorizerResponse, error) {
+	principalID := strings.TrimSpace(result.PrincipalID)
+	if principalID == "" {
+		if result.Allow {
+			return events.APIGatewayCustomAuthorizerResponse{}, errors.New("apptheory: authorizer principal id is empty")
+		}
+		principalID = "anonymous"
+	}
+	authContext, err := validateAuthorizerContext(result.Context)
+	if err != nil {
+		return events.APIGatewayCustomAuthorizerResponse{}, err
+	}
+
+	resources := result.Resources
+	if len(resources) == 0 {
+		resources = []string{methodARN}
+	}
+	effect := "Deny"
+	if result.Allow {
+		effect = "Allow"
+	}
+	return events.APIGatewayCustomAuthorizerResponse{
+		PrincipalID: principalID,
+		PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
+			Version: authorizerPolicyVersion,
+			Statement: []events.IAMPolicyStatement{{
+				Action:   []string{authorizerPolicyAction},
+				Effect:   effect,
+				Resource: append([]string(nil), resources...),
+			}},
+		},
+		Context:            authContext,
+		UsageIdentifierKey: result.UsageIdentifierKey,
+	}, nil
+}
+
+// validateAuthorizerContext copies an authorizer context after checking that
+// API Gateway can pass it to the integration.
+func validateAuthorizerContext(values map[string]any) (map[string]any, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]any, len(values))
+	for key, value := range values {
+		if strings.TrimSpace(key) == "" {
+			return nil, errors.New("apptheory: authorizer context key is empty")
+		}
+		switch value.(type) {
+		case string, bool, json.Number,
+			int, int8, int16, int32, int64,
+			uint, uint8, uint16, uint32, uint64,
+			float32, float64:
+		default:
+			return nil, fmt.Errorf("apptheory: authorizer context value %q must be a string, number, or boolean", key)
+		}
+		out[key] = value
+	}
+	encoded, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("apptheory: encode authorizer context: %w", err)
+	}
+	if len(encoded) > MaxAuthorizerContextBytes {
+		return nil, fmt.Errorf("apptheory: authorizer context is %d bytes, limit is %d", len(encoded), MaxAuthorizerContextBytes)
+	}
+	return out, nil
+}
+
+// methodARNRoute extracts the method and resource path from a REST API method
+// ARN such as arn:aws:execute-api:us-east-1:123456789012:api/stage/GET/pets/1.
+func methodARNRoute(methodARN string) (string, string) {
+	colon := strings.LastIndex(methodARN, ":")
+	parts := strings.SplitN(methodARN[colon+1:], "/", 4)
+	if len(parts) < 3 {
+		return "", "/"
+	}
+	path := "/"
+	if len(parts) == 4 {
+		path += parts[3]
+	}
+	return parts[2], path
+}
+
+// PrincipalAuthorizer adapts a SecurePrincipalResolver to an AuthorizerHandler.
+//
+// A resolved principal is allowed with its identity as the principal ID, and
+// its identity, scopes, and kind are written to the authorizer context, where
+// AuthorizerContextPrincipalResolver reads them back downstream. Claims are
+// not forwarded. A missing principal or an invalid kind is unauthorized.
+func PrincipalAuthorizer(resolver SecurePrincipalResolver) AuthorizerHandler {
+	return func(ctx *Context) (AuthorizerResult, error) {
+		if resolver == nil {
+			return AuthorizerResult{}, errors.New("apptheory: principal resolver is nil")
+		}
+		principal, err := resolver(ctx)
+		if err != nil {
+			return AuthorizerResult{}, err
+		}
+		normalized, invalidKind := normalizeSecurePrincipal(principal)
+		if invalidKind || normalized == nil || normalized.Identity == "" {
+			return AuthorizerResult{}, &AppError{Code: errorCodeUnauthorized, Message: errorMessageUnauthorized}
+		}
+		authContext := map[string]any{
+			authorizerContextIdentity: normalized.Identity,
+			authorizerContextKind:     string(normalized.Kind),
+		}
+		if len(normalized.Scopes) > 0 {
+			authContext[authorizerContextScopes] = strings.Join(normalized.Scopes, " ")
+		}
+		return AuthorizerResult{Allow: true, PrincipalID: normalized.Identity, Context: authContext}, nil
+	}
+}
+
+// AuthorizerContextPrincipalResolver resolves the principal written by
+// PrincipalAuthorizer from the upstream authorizer context. It only trusts
+// context that API Gateway attached to the request, never request headers, and
+// resolves no principal when the context is absent.
+func AuthorizerContextPrincipalResolver() SecurePrincipalResolver {
+	return func(ctx *Context) (*SecurePrincipal, error) {
+		values := ctx.AuthorizerContext()
+		identity := strings.TrimSpace(authorizerContextString(values[authorizerContextIdentity]))
+		if identity == "" {
+			return nil, nil
+		}
+		principal := &SecurePrincipal{
+			Identity: identity,
+			Kind:     PrincipalKind(authorizerContextString(values[authorizerContextKind])),
+		}
+		if scopes := strings.Fields(authorizerContextString(values[authorizerContextScopes])); len(scopes) > 0 {
+			principal.Scopes = scopes
+		}
+		return principal, nil
+	}
+}
+
+func authorizerContextString(value any) string {
+	s, ok := value.(string)
+	if !ok {
+		return ""
+	}
+	return s
+}
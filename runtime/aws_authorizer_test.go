@@ -0,0 +1,248 @@
+package apptheory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const authorizerTestMethodARN = "arn:aws:execute-api:us-east-1:123456789012:api123/prod/GET/pets/42"
+
+func TestServeAPIGatewayTokenAuthorizer_BuildsPolicy(t *testing.T) {
+	t.Parallel()
+
+	app := New()
+	app.Authorizer(func(ctx *Context) (AuthorizerResult, error) {
+		meta := ctx.AsAuthorizer()
+		if meta == nil || meta.Type != "TOKEN" || meta.Token != "Bearer abc" {
+			t.Fatalf("unexpected authorizer metadata: %#v", meta)
+		}
+		if ctx.Header("Authorization") != "Bearer abc" || ctx.Request.Method != "GET" || ctx.Request.Path != "/pets/42" {
+			t.Fatalf("unexpected request: %#v", ctx.Request)
+		}
+		if ctx.RequestID == "" {
+			t.Fatal("expected request id")
+		}
+		return AuthorizerResult{Allow: true, PrincipalID: "user-1", Context: map[string]any{"tier": "gold", "n": 3}}, nil
+	})
+
+	resp, err := app.ServeAPIGatewayTokenAuthorizer(context.Background(), events.APIGatewayCustomAuthorizerRequest{
+		Type: "TOKEN", AuthorizationToken: "Bearer abc", MethodArn: authorizerTestMethodARN,
+	})
+	if err != nil {
+		t.Fatalf("ServeAPIGatewayTokenAuthorizer: %v", err)
+	}
+	if resp.PrincipalID != "user-1" || resp.Context["tier"] != "gold" {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+	statement := resp.PolicyDocument.Statement
+	if resp.PolicyDocument.Version != "2012-10-17" || len(statement) != 1 || statement[0].Effect != "Allow" ||
+		statement[0].Action[0] != "execute-api:Invoke" || statement[0].Resource[0] != authorizerTestMethodARN {
+		t.Fatalf("unexpected policy: %#v", resp.PolicyDocument)
+	}
+}
+
+func TestServeAPIGatewayRequestAuthorizer_ErrorsAndDeny(t *testing.T) {
+	t.Parallel()
+
+	event := events.APIGatewayCustomAuthorizerRequestTypeRequest{
+		Type:       "REQUEST",
+		MethodArn:  authorizerTestMethodARN,
+		Path:       "/pets/42",
+		HTTPMethod: "GET",
+		Headers:    map[string]string{"X-Api-Key": "k", "Traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+		RequestContext: events.APIGatewayCustomAuthorizerRequestTypeRequestContext{
+			Identity: events.APIGatewayCustomAuthorizerRequestTypeRequestIdentity{SourceIP: "203.0.113.9"},
+		},
+	}
+
+	var outcome string
+	app := New().Authorizer(func(ctx *Context) (AuthorizerResult, error) {
+		if ctx.SourceProvenance().SourceIP != "203.0.113.9" || ctx.TraceID == "" {
+			t.Fatalf("expected provenance and trace id, got %#v %q", ctx.SourceProvenance(), ctx.TraceID)
+		}
+		switch outcome {
+		case "deny":
+			return AuthorizerResult{}, nil
+		case "unauthorized":
+			return AuthorizerResult{}, &AppError{Code: errorCodeUnauthorized, Message: errorMessageUnauthorized}
+		case "boom":
+			return AuthorizerResult{}, errors.New("db password rejected")
+		case "panic":
+			panic("boom")
+		case "nested":
+			return AuthorizerResult{Allow: true, PrincipalID: "p", Context: map[string]any{"roles": []string{"a"}}}, nil
+		case "large":
+			return AuthorizerResult{Allow: true, PrincipalID: "p", Context: map[string]any{"blob": strings.Repeat("x", MaxAuthorizerContextBytes)}}, nil
+		default:
+			return AuthorizerResult{Allow: true}, nil
+		}
+	})
+
+	outcome = "deny"
+	resp, err := app.ServeAPIGatewayRequestAuthorizer(context.Background(), event)
+	if err != nil || resp.PolicyDocument.Statement[0].Effect != "Deny" || resp.PrincipalID == "" {
+		t.Fatalf("expected deny policy, got %#v (%v)", resp, err)
+	}
+
+	outcome = "unauthorized"
+	if _, err := app.ServeAPIGatewayRequestAuthorizer(context.Background(), event); err == nil || err.Error() != "Unauthorized" {
+		t.Fatalf("expected API Gateway 401 error, got %v", err)
+	}
+	for _, outcome = range []string{"boom", "panic"} {
+		if _, err := app.ServeAPIGatewayRequestAuthorizer(context.Background(), event); !errors.Is(err, errAuthorizerFailed) {
+			t.Fatalf("%s: expected generic failure, got %v", outcome, err)
+		}
+	}
+	for _, outcome = range []string{"nested", "large", "allow-without-principal"} {
+		if _, err := app.ServeAPIGatewayRequestAuthorizer(context.Background(), event); err == nil {
+			t.Fatalf("%s: expected invalid result to fail", outcome)
+		}
+	}
+
+	if _, err := New().ServeAPIGatewayRequestAuthorizer(context.Background(), event); err == nil {
+		t.Fatal("expected missing authorizer to fail closed")
+	}
+}
+
+func TestAuthorizer_RecordsOutcomeThroughObservability(t *testing.T) {
+	t.Parallel()
+
+	var logs []LogRecord
+	var metrics []MetricRecord
+	var fail error
+	app := New(WithObservability(ObservabilityHooks{
+		Log:    func(record LogRecord) { logs = append(logs, record) },
+		Metric: func(record MetricRecord) { metrics = append(metrics, record) },
+	})).Authorizer(func(*Context) (AuthorizerResult, error) {
+		return AuthorizerResult{Allow: fail == nil, PrincipalID: "p"}, fail
+	})
+	event := events.APIGatewayCustomAuthorizerRequest{Type: "TOKEN", AuthorizationToken: "secret-token", MethodArn: authorizerTestMethodARN}
+
+	for _, fail = range []error{nil, &AppError{Code: errorCodeUnauthorized, Message: errorMessageUnauthorized}, errors.New("db password rejected")} {
+		_, _ = app.ServeAPIGatewayTokenAuthorizer(context.Background(), event)
+	}
+
+	if len(logs) != 3 || len(metrics) != 3 {
+		t.Fatalf("expected one log and metric per invocation, got %#v %#v", logs, metrics)
+	}
+	for i, code := range []string{"", errorCodeUnauthorized, errorCodeInternal} {
+		if logs[i].Trigger != "authorizer" || logs[i].ErrorCode != code || metrics[i].Tags["authorizer_type"] != "TOKEN" {
+			t.Fatalf("unexpected observation %d: %#v %#v", i, logs[i], metrics[i])
+		}
+		if strings.Contains(fmt.Sprintf("%#v", logs[i]), "secret-token") || strings.Contains(fmt.Sprintf("%#v", logs[i]), "db password") {
+			t.Fatalf("expected token and handler error to stay out of the log, got %#v", logs[i])
+		}
+	}
+}
+
+func TestHandleLambda_DispatchesV2AuthorizerSimpleResponse(t *testing.T) {
+	t.Parallel()
+
+	app := New().Authorizer(func(ctx *Context) (AuthorizerResult, error) {
+		meta := ctx.AsAuthorizer()
+		if meta.Version != "2.0" || meta.RouteKey != "GET /pets/{id}" || ctx.Request.Path != "/pets/7" {
+			t.Fatalf("unexpected authorizer request: %#v %#v", meta, ctx.Request)
+		}
+		if ctx.Header("authorization") == "" {
+			return AuthorizerResult{}, &AppError{Code: errorCodeUnauthorized, Message: errorMessageUnauthorized}
+		}
+		return AuthorizerResult{Allow: true, Context: map[string]any{"sub": "u1"}}, nil
+	})
+
+	event := func(header string) json.RawMessage {
+		return json.RawMessage(`{"version":"2.0","type":"REQUEST","routeArn":"arn:aws:execute-api:us-east-1:1:api/$default/GET/pets/7",` +
+			`"routeKey":"GET /pets/{id}","rawPath":"/pets/7","headers":{` + header + `},` +
+			`"requestContext":{"stage":"$default","http":{"method":"GET","path":"/pets/7","sourceIp":"198.51.100.1"}}}`)
+	}
+
+	out, err := app.HandleLambda(context.Background(), event(`"authorization":"Bearer t"`))
+	resp, ok := out.(events.APIGatewayV2CustomAuthorizerSimpleResponse)
+	if err != nil || !ok || !resp.IsAuthorized || resp.Context["sub"] != "u1" {
+		t.Fatalf("unexpected response %#v (%v)", out, err)
+	}
+	out, err = app.HandleLambda(context.Background(), event(""))
+	resp, ok = out.(events.APIGatewayV2CustomAuthorizerSimpleResponse)
+	if err != nil || !ok || resp.IsAuthorized {
+		t.Fatalf("expected unauthorized to deny, got %#v (%v)", out, err)
+	}
+
+	token := json.RawMessage(`{"type":"TOKEN","authorizationToken":"Bearer t","methodArn":"` + authorizerTestMethodARN + `"}`)
+	rest := New().Authorizer(func(*Context) (AuthorizerResult, error) {
+		return AuthorizerResult{Allow: true, PrincipalID: "u1"}, nil
+	})
+	if out, err := rest.HandleLambda(context.Background(), token); err != nil {
+		t.Fatalf("token authorizer: %v", err)
+	} else if _, ok := out.(events.APIGatewayCustomAuthorizerResponse); !ok {
+		t.Fatalf("expected REST authorizer response, got %T", out)
+	}
+}
+
+func TestPrincipalAuthorizer_RoundTripsThroughAuthorizerContext(t *testing.T) {
+	t.Parallel()
+
+	edge := NewSecure(SecureOptions{PrincipalResolver: func(ctx *Context) (*SecurePrincipal, error) {
+		if ctx.Header("authorization") != "Bearer ok" {
+			return nil, nil
+		}
+		return &SecurePrincipal{Identity: "svc-1", Scopes: []string{"write", "read"}, Kind: PrincipalInternal}, nil
+	}}).PrincipalAuthorizer()
+
+	allowed, err := edge.ServeAPIGatewayTokenAuthorizer(context.Background(), events.APIGatewayCustomAuthorizerRequest{
+		Type: "TOKEN", AuthorizationToken: "Bearer ok", MethodArn: authorizerTestMethodARN,
+	})
+	if err != nil || allowed.PrincipalID != "svc-1" || allowed.PolicyDocument.Statement[0].Effect != "Allow" {
+		t.Fatalf("unexpected authorizer response %#v (%v)", allowed, err)
+	}
+	if _, err := edge.ServeAPIGatewayTokenAuthorizer(context.Background(), events.APIGatewayCustomAuthorizerRequest{
+		Type: "TOKEN", AuthorizationToken: "Bearer nope", MethodArn: authorizerTestMethodARN,
+	}); err == nil || err.Error() != "Unauthorized" {
+		t.Fatalf("expected missing principal to be unauthorized, got %v", err)
+	}
+
+	backend := NewSecure(SecureOptions{PrincipalResolver: AuthorizerContextPrincipalResolver()})
+	backend.Get("/admin", func(ctx *Context) (*Response, error) {
+		principal := ctx.SecurePrincipal()
+		return Text(200, principal.Identity+" "+strings.Join(principal.Scopes, ",")), nil
+	}, InternalOnly())
+
+	proxy := events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/admin"}
+	proxy.RequestContext.Authorizer = map[string]any{"principalId": allowed.PrincipalID}
+	for key, value := range allowed.Context {
+		proxy.RequestContext.Authorizer[key] = value
+	}
+	if resp := backend.ServeAPIGatewayProxy(context.Background(), proxy); resp.StatusCode != 200 || resp.Body != "svc-1 write,read" {
+		t.Fatalf("expected trusted principal, got %d %s", resp.StatusCode, resp.Body)
+	}
+
+	http := events.APIGatewayV2HTTPRequest{RawPath: "/admin", Headers: map[string]string{"x-apptheory-identity": "spoof"}}
+	http.RequestContext.HTTP.Method = "GET"
+	if resp := backend.ServeAPIGatewayV2(context.Background(), http); resp.StatusCode != 401 {
+		t.Fatalf("expected request without authorizer context to be rejected, got %d", resp.StatusCode)
+	}
+	http.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{Lambda: allowed.Context}
+	if resp := backend.ServeAPIGatewayV2(context.Background(), http); resp.StatusCode != 200 {
+		t.Fatalf("expected v2 authorizer context to be trusted, got %d %s", resp.StatusCode, resp.Body)
+	}
+}
+
+func TestMethodARNRoute(t *testing.T) {
+	t.Parallel()
+
+	for arn, want := range map[string][2]string{
+		authorizerTestMethodARN:                          {"GET", "/pets/42"},
+		"arn:aws:execute-api:us-east-1:1:api/prod/POST/": {"POST", "/"},
+		"arn:aws:execute-api:us-east-1:1:api/prod/GET":   {"GET", "/"},
+		"invalid": {"", "/"},
+	} {
+		method, path := methodARNRoute(arn)
+		if method != want[0] || path != want[1] {
+			t.Fatalf("%s: expected %v, got %s %s", arn, want, method, path)
+		}
+	}
+}
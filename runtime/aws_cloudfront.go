@@ -0,0 +1,445 @@
+package apptheory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lambda@Edge event types.
+const (
+	CloudFrontViewerRequest  = "viewer-request"
+	CloudFrontOriginRequest  = "origin-request"
+	CloudFrontOriginResponse = "origin-response"
+	CloudFrontViewerResponse = "viewer-response"
+)
+
+const (
+	cloudFrontViewerMaxBytes = 40 * 1024
+	cloudFrontOriginMaxBytes = 1024 * 1024
+
+	cloudFrontBodyEncodingBase64 = "base64"
+	cloudFrontBodyEncodingText   = "text"
+)
+
+// CloudFrontEvent is a Lambda@Edge invocation. CloudFront always delivers
+// exactly one record.
+type CloudFrontEvent struct {
+	Records []CloudFrontEventRecord `json:"Records"`
+}
+
+type CloudFrontEventRecord struct {
+	CF CloudFrontRecord `json:"cf"`
+}
+
+type CloudFrontRecord struct {
+	Config   CloudFrontConfig    `json:"config"`
+	Request  CloudFrontRequest   `json:"request"`
+	Response *CloudFrontResponse `json:"response,omitempty"`
+}
+
+type CloudFrontConfig struct {
+	DistributionDomainName string `json:"distributionDomainName"`
+	DistributionID         string `json:"distributionId"`
+	EventType              string `json:"eventType"`
+	RequestID              string `json:"requestId"`
+}
+
+// CloudFrontHeader is one header value with its original-case name.
+type CloudFrontHeader struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+}
+
+// CloudFrontHeaders maps lowercase header names to their values, as in
+// Lambda@Edge events.
+type CloudFrontHeaders map[string][]CloudFrontHeader
+
+// Get returns the first value of the named header.
+func (h CloudFrontHeaders) Get(name string) string {
+	values := h[strings.ToLower(strings.TrimSpace(name))]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0].Value
+}
+
+// Set replaces the named header with a single value.
+func (h CloudFrontHeaders) Set(name, value string) {
+	name = strings.TrimSpace(name)
+	h[strings.ToLower(name)] = []CloudFrontHeader{{Key: name, Value: value}}
+}
+
+// Add appends a value to the named header.
+func (h CloudFrontHeaders) Add(name, value string) {
+	name = strings.TrimSpace(name)
+	lower := strings.ToLower(name)
+	h[lower] = append(h[lower], CloudFrontHeader{Key: name, Value: value})
+}
+
+// Del removes the named header.
+func (h CloudFrontHeaders) Del(name string) {
+	delete(h, strings.ToLower(strings.TrimSpace(name)))
+}
+
+func (h CloudFrontHeaders) clone() CloudFrontHeaders {
+	out := make(CloudFrontHeaders, len(h))
+	for key, values := range h {
+		out[key] = append([]CloudFrontHeader(nil), values...)
+	}
+	return out
+}
+
+type CloudFrontRequest struct {
+	ClientIP    string                 `json:"clientIp"`
+	Method      string                 `json:"method"`
+	URI         string                 `json:"uri"`
+	QueryString string                 `json:"querystring"`
+	Headers     CloudFrontHeaders      `json:"headers"`
+	Body        *CloudFrontRequestBody `json:"body,omitempty"`
+	Origin      json.RawMessage        `json:"origin,omitempty"`
+}
+
+// SetBody replaces the request body forwarded to the origin. The function
+// association must include the body.
+func (r *CloudFrontRequest) SetBody(body []byte) {
+	if r == nil {
+		return
+	}
+	r.Body = &CloudFrontRequestBody{
+		Action:   "replace",
+		Encoding: cloudFrontBodyEncodingBase64,
+		Data:     base64.StdEncoding.EncodeToString(body),
+	}
+}
+
+type CloudFrontRequestBody struct {
+	InputTruncated bool   `json:"inputTruncated"`
+	Action         string `json:"action,omitempty"`
+	Encoding       string `json:"encoding"`
+	Data           string `json:"data"`
+}
+
+type CloudFrontResponse struct {
+	Status            string            `json:"status"`
+	StatusDescription string            `json:"statusDescription,omitempty"`
+	Headers           CloudFrontHeaders `json:"headers,omitempty"`
+	Body              string            `json:"body,omitempty"`
+	BodyEncoding      string            `json:"bodyEncoding,omitempty"`
+}
+
+// CloudFrontContext exposes the Lambda@Edge record to handlers. Request and,
+// for response events, Response may be modified in place and returned to
+// CloudFront with Forward.
+type CloudFrontContext struct {
+	Config   CloudFrontConfig
+	Request  *CloudFrontRequest
+	Response *CloudFrontResponse
+
+	forward bool
+}
+
+// Forward tells ServeCloudFront to continue with the (possibly modified)
+// request or response instead of generating a response. Return its result
+// from the handler. The returned *Response is only a marker: headers that
+// middleware sets on it are dropped, so middleware that decorates forwarded
+// records must edit Request or Response directly.
+func (c *CloudFrontContext) Forward() (*Response, error) {
+	if c != nil {
+		c.forward = true
+	}
+	return &Response{Status: http.StatusOK}, nil
+}
+
+// AsCloudFront returns the Lambda@Edge record when the context belongs to a
+// CloudFront invocation.
+func (c *Context) AsCloudFront() *CloudFrontContext {
+	if c == nil {
+		return nil
+	}
+	return c.cloudfront
+}
+
+// ServeCloudFront runs a Lambda@Edge viewer/origin request or response event
+// through the app's routes and middleware.
+//
+// The edge request is matched by method and URI. A route may return a
+// generated response, or modify AsCloudFront().Request (request events) or
+// AsCloudFront().Response (response events) and return Forward(). Events
+// with no matching route still run through the middleware, which may answer
+// them or modify the record; otherwise they pass through unchanged. An edge
+// request with a malformed query string gets a generated 400 response.
+//
+// Results are checked against the Lambda@Edge limits: generated responses and
+// replaced bodies must fit in 40 KB for viewer events and 1 MB for origin
+// events, and read-only or disallowed headers must not be changed. A result
+// that breaks a limit returns an error rather than failing at CloudFront.
+func (a *App) ServeCloudFront(ctx context.Context, event CloudFrontEvent) (any, error) {
+	if a == nil {
+		return nil, errors.New("apptheory: nil app")
+	}
+	record, responseEvent, err := cloudFrontEventRecord(event)
+	if err != nil {
+		return nil, err
+	}
+	eventType := record.Config.EventType
+
+	req, err := requestFromCloudFront(record.Request)
+	if err != nil {
+		generated := cloudFrontResponseFromResponse(a.responseForHTTPError(err))
+		return generated, validateCloudFrontResponse(eventType, nil, generated)
+	}
+	cf := &CloudFrontContext{Config: record.Config, Request: cloneCloudFrontRequest(record.Request)}
+	if responseEvent {
+		cf.Response = cloneCloudFrontResponse(*record.Response)
+	}
+	resp := a.serveWithOptions(ctx, req, serveOptions{
+		configure: func(requestCtx *Context) {
+			requestCtx.cloudfront = cf
+		},
+		fallback: &cloudFrontPassThroughRoute,
+	})
+
+	if cf.forward && resp.Status == http.StatusOK {
+		if responseEvent {
+			original := record.Response.Headers
+			if original == nil {
+				original = CloudFrontHeaders{}
+			}
+			return cf.Response, validateCloudFrontResponse(eventType, original, *cf.Response)
+		}
+		return cf.Request, validateCloudFrontRequest(eventType, record.Request.Headers, *cf.Request)
+	}
+
+	generated := cloudFrontResponseFromResponse(resp)
+	return generated, validateCloudFrontResponse(eventType, nil, generated)
+}
+
+// cloudFrontPassThroughRoute forwards edge events that match no route, after
+// the middleware has run.
+var cloudFrontPassThroughRoute = route{
+	Handler: func(ctx *Context) (*Response, error) {
+		return ctx.AsCloudFront().Forward()
+	},
+	PosturePresent: true,
+	Posture:        Public(),
+}
+
+func cloudFrontEventRecord(event CloudFrontEvent) (CloudFrontRecord, bool, error) {
+	if len(event.Records) != 1 {
+		return CloudFrontRecord{}, false, errors.New("apptheory: cloudfront event must have exactly one record")
+	}
+	record := event.Records[0].CF
+	record.Config.EventType = strings.TrimSpace(record.Config.EventType)
+	switch record.Config.EventType {
+	case CloudFrontViewerRequest, CloudFrontOriginRequest:
+		return record, false, nil
+	case CloudFrontOriginResponse, CloudFrontViewerResponse:
+		if record.Response == nil {
+			return CloudFrontRecord{}, true, errors.New("apptheory: cloudfront response event has no response")
+		}
+		return record, true, nil
+	default:
+		return CloudFrontRecord{}, false, fmt.Errorf("apptheory: unsupported cloudfront event type %q", record.Config.EventType)
+	}
+}
+
+func requestFromCloudFront(in CloudFrontRequest) (Request, error) {
+	query, err := url.ParseQuery(in.QueryString)
+	if err != nil {
+		return Request{}, &AppError{Code: errorCodeBadRequest, Message: errorMessageInvalidQueryString}
+	}
+
+	headers := map[string][]string{}
+	for key, values := range in.Headers {
+		for _, value := range values {
+			headers[key] = append(headers[key], value.Value)
+		}
+	}
+
+	var body []byte
+	isBase64 := false
+	if in.Body != nil && in.Body.Data != "" {
+		body = []byte(in.Body.Data)
+		isBase64 = in.Body.Encoding == cloudFrontBodyEncodingBase64
+	}
+
+	return Request{
+		Method:   in.Method,
+		Path:     in.URI,
+		Query:    query,
+		Headers:  headers,
+		Body:     body,
+		IsBase64: isBase64,
+		SourceProvenance: sourceProvenanceFromProviderRequestContext(
+			sourceProvenanceProviderCloudFront,
+			in.ClientIP,
+		),
+	}, nil
+}
+
+func cloneCloudFrontRequest(in CloudFrontRequest) *CloudFrontRequest {
+	out := in
+	out.Headers = in.Headers.clone()
+	if in.Body != nil {
+		body := *in.Body
+		out.Body = &body
+	}
+	out.Origin = append(json.RawMessage(nil), in.Origin...)
+	return &out
+}
+
+func cloneCloudFrontResponse(in CloudFrontResponse) *CloudFrontResponse {
+	out := in
+	out.Headers = in.Headers.clone()
+	return &out
+}
+
+func cloudFrontResponseFromResponse(resp Response) CloudFrontResponse {
+	headers := CloudFrontHeaders{}
+	keys := make([]string, 0, len(resp.Headers))
+	for key := range resp.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range resp.Headers[key] {
+			headers.Add(http.CanonicalHeaderKey(key), value)
+		}
+	}
+	for _, cookie := range resp.Cookies {
+		headers.Add("Set-Cookie", cookie)
+	}
+
+	out := CloudFrontResponse{
+		Status:            strconv.Itoa(resp.Status),
+		StatusDescription: http.StatusText(resp.Status),
+		Headers:           headers,
+		Body:              string(resp.Body),
+		BodyEncoding:      cloudFrontBodyEncodingText,
+	}
+	if resp.IsBase64 {
+		out.Body = base64.StdEncoding.EncodeToString(resp.Body)
+		out.BodyEncoding = cloudFrontBodyEncodingBase64
+	}
+	return out
+}
+
+func cloudFrontMaxBytes(eventType string) int {
+	if eventType == CloudFrontViewerRequest || eventType == CloudFrontViewerResponse {
+		return cloudFrontViewerMaxBytes
+	}
+	return cloudFrontOriginMaxBytes
+}
+
+func cloudFrontHeadersSize(headers CloudFrontHeaders) int {
+	size := 0
+	for key, values := range headers {
+		for _, value := range values {
+			size += len(key) + len(value.Value)
+		}
+	}
+	return size
+}
+
+func validateCloudFrontRequest(eventType string, original CloudFrontHeaders, request CloudFrontRequest) error {
+	if err := validateCloudFrontHeaders(eventType, original, request.Headers); err != nil {
+		return err
+	}
+	if request.Body == nil || request.Body.Action != "replace" {
+		return nil
+	}
+	if size := len(request.Body.Data); size > cloudFrontMaxBytes(eventType) {
+		return fmt.Errorf("apptheory: cloudfront %s body is %d bytes, limit is %d", eventType, size, cloudFrontMaxBytes(eventType))
+	}
+	return nil
+}
+
+// validateCloudFrontResponse checks a modified response against the original
+// headers, or a generated response when original is nil.
+func validateCloudFrontResponse(eventType string, original CloudFrontHeaders, response CloudFrontResponse) error {
+	if original != nil {
+		if err := validateCloudFrontHeaders(eventType, original, response.Headers); err != nil {
+			return err
+		}
+	} else {
+		for key := range response.Headers {
+			if cloudFrontHeaderDisallowed(key) {
+				return fmt.Errorf("apptheory: cloudfront header %q cannot be set", key)
+			}
+		}
+	}
+	if size := len(response.Body) + cloudFrontHeadersSize(response.Headers); size > cloudFrontMaxBytes(eventType) {
+		return fmt.Errorf("apptheory: cloudfront %s response is %d bytes, limit is %d", eventType, size, cloudFrontMaxBytes(eventType))
+	}
+	return nil
+}
+
+func validateCloudFrontHeaders(eventType string, original, modified CloudFrontHeaders) error {
+	names := map[string]struct{}{}
+	for key := range original {
+		names[key] = struct{}{}
+	}
+	for key := range modified {
+		names[key] = struct{}{}
+	}
+	for name := range names {
+		if cloudFrontHeaderValues(original, name) == cloudFrontHeaderValues(modified, name) {
+			continue
+		}
+		if cloudFrontHeaderDisallowed(name) || cloudFrontHeaderReadOnly(eventType, name) {
+			return fmt.Errorf("apptheory: cloudfront header %q cannot be changed in %s", name, eventType)
+		}
+	}
+	return nil
+}
+
+func cloudFrontHeaderValues(headers CloudFrontHeaders, name string) string {
+	values := headers[name]
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = value.Value
+	}
+	return strings.Join(parts, "\x00")
+}
+
+var cloudFrontDisallowedHeaders = map[string]struct{}{
+	"connection": {}, "expect": {}, "keep-alive": {}, "proxy-authenticate": {}, "proxy-authorization": {},
+	"proxy-connection": {}, "trailer": {}, "upgrade": {}, "x-accel-buffering": {}, "x-accel-charset": {},
+	"x-accel-limit-rate": {}, "x-accel-redirect": {}, "x-cache": {}, "x-forwarded-proto": {}, "x-real-ip": {},
+}
+
+func cloudFrontHeaderDisallowed(name string) bool {
+	name = strings.ToLower(name)
+	if _, ok := cloudFrontDisallowedHeaders[name]; ok {
+		return true
+	}
+	return strings.HasPrefix(name, "x-amz-cf-") || strings.HasPrefix(name, "x-edge-")
+}
+
+var cloudFrontReadOnlyHeaders = map[string][]string{
+	CloudFrontViewerRequest: {"content-length", "host", "transfer-encoding", "via"},
+	CloudFrontOriginRequest: {
+		"accept-encoding", "content-length", "if-modified-since", "if-none-match", "if-range",
+		"if-unmodified-since", "transfer-encoding", "via",
+	},
+	CloudFrontOriginResponse: {"transfer-encoding", "via"},
+	CloudFrontViewerResponse: {"content-encoding", "content-length", "transfer-encoding", "via", "warning"},
+}
+
+func cloudFrontHeaderReadOnly(eventType, name string) bool {
+	name = strings.ToLower(name)
+	for _, readOnly := range cloudFrontReadOnlyHeaders[eventType] {
+		if readOnly == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package apptheory
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func cloudFrontTestEvent(eventType, uri string) CloudFrontEvent {
+	record := CloudFrontRecord{
+		Config: CloudFrontConfig{DistributionID: "EDFDVBD6EXAMPLE", EventType: eventType, RequestID: "cf-req-1"},
+		Request: CloudFrontRequest{
+			ClientIP:    "203.0.113.178",
+			Method:      "GET",
+			URI:         uri,
+			QueryString: "next=%2Fhome",
+			Headers: CloudFrontHeaders{
+				"host":   {{Key: "Host", Value: "d111111abcdef8.cloudfront.net"}},
+				"cookie": {{Key: "Cookie", Value: "session=abc"}},
+			},
+		},
+	}
+	if eventType == CloudFrontOriginResponse || eventType == CloudFrontViewerResponse {
+		record.Response = &CloudFrontResponse{
+			Status: "200",
+			Headers: CloudFrontHeaders{
+				"content-type": {{Key: "Content-Type", Value: "text/html"}},
+				"via":          {{Key: "Via", Value: "1.1 cloudfront"}},
+			},
+		}
+	}
+	return CloudFrontEvent{Records: []CloudFrontEventRecord{{CF: record}}}
+}
+
+func TestServeCloudFront_ViewerRequestRedirectsAndRewrites(t *testing.T) {
+	t.Parallel()
+
+	var trace []string
+	app := New()
+	app.Use(func(next Handler) Handler {
+		return func(ctx *Context) (*Response, error) {
+			trace = append(trace, "mw")
+			return next(ctx)
+		}
+	})
+	app.Get("/private/{path}", func(ctx *Context) (*Response, error) {
+		cf := ctx.AsCloudFront()
+		if cf == nil || cf.Config.EventType != CloudFrontViewerRequest || ctx.Query("next") != "/home" {
+			t.Fatalf("unexpected cloudfront context %#v", cf)
+		}
+		if ctx.SourceProvenance().SourceIP != "203.0.113.178" || ctx.SourceProvenance().Provider != "cloudfront" {
+			t.Fatalf("unexpected provenance %#v", ctx.SourceProvenance())
+		}
+		if !strings.Contains(ctx.Header("cookie"), "session=") {
+			resp := &Response{Status: 302, Headers: map[string][]string{"location": {"https://auth.example.com/login"}}}
+			return resp, nil
+		}
+		cf.Request.Headers.Set("X-User", "u1")
+		cf.Request.URI = "/index.html"
+		return cf.Forward()
+	})
+
+	out, err := app.ServeCloudFront(context.Background(), cloudFrontTestEvent(CloudFrontViewerRequest, "/private/a"))
+	req, ok := out.(*CloudFrontRequest)
+	if err != nil || !ok || req.URI != "/index.html" || req.Headers.Get("x-user") != "u1" || req.Headers["x-user"][0].Key != "X-User" {
+		t.Fatalf("expected rewritten request, got %#v (%v)", out, err)
+	}
+
+	event := cloudFrontTestEvent(CloudFrontViewerRequest, "/private/a")
+	event.Records[0].CF.Request.Headers.Del("cookie")
+	out, err = app.ServeCloudFront(context.Background(), event)
+	resp, ok := out.(CloudFrontResponse)
+	if err != nil || !ok || resp.Status != "302" || resp.StatusDescription != "Found" ||
+		resp.Headers.Get("location") != "https://auth.example.com/login" || resp.Headers["location"][0].Key != "Location" {
+		t.Fatalf("expected generated redirect, got %#v (%v)", out, err)
+	}
+	if len(trace) != 2 {
+		t.Fatalf("expected middleware per request, got %v", trace)
+	}
+
+	out, err = app.ServeCloudFront(context.Background(), cloudFrontTestEvent(CloudFrontViewerRequest, "/public"))
+	if req, ok := out.(*CloudFrontRequest); err != nil || !ok || req.URI != "/public" {
+		t.Fatalf("expected unmatched request to pass through, got %#v (%v)", out, err)
+	}
+	if len(trace) != 3 {
+		t.Fatalf("expected middleware to run for the unmatched request, got %v", trace)
+	}
+}
+
+func TestServeCloudFront_MiddlewareAnswersUnmatchedEvents(t *testing.T) {
+	t.Parallel()
+
+	app := New()
+	app.Use(func(next Handler) Handler {
+		return func(ctx *Context) (*Response, error) {
+			if ctx.Header("cookie") == "" {
+				return &Response{Status: 403}, nil
+			}
+			return next(ctx)
+		}
+	})
+
+	event := cloudFrontTestEvent(CloudFrontViewerRequest, "/anything")
+	event.Records[0].CF.Request.Headers.Del("cookie")
+	out, err := app.ServeCloudFront(context.Background(), event)
+	if resp, ok := out.(CloudFrontResponse); err != nil || !ok || resp.Status != "403" {
+		t.Fatalf("expected middleware to answer the unmatched request, got %#v (%v)", out, err)
+	}
+
+	out, err = app.ServeCloudFront(context.Background(), cloudFrontTestEvent(CloudFrontOriginResponse, "/anything"))
+	if resp, ok := out.(*CloudFrontResponse); err != nil || !ok || resp.Status != "200" || resp.Headers.Get("via") == "" {
+		t.Fatalf("expected unmatched response to pass through, got %#v (%v)", out, err)
+	}
+}
+
+func TestServeCloudFront_OriginResponseModifiesHeaders(t *testing.T) {
+	t.Parallel()
+
+	app := New()
+	app.Get("/", func(ctx *Context) (*Response, error) {
+		cf := ctx.AsCloudFront()
+		cf.Response.Headers.Set("Strict-Transport-Security", "max-age=63072000")
+		cf.Response.Headers.Del("content-type")
+		return cf.Forward()
+	})
+
+	out, err := app.ServeCloudFront(context.Background(), cloudFrontTestEvent(CloudFrontOriginResponse, "/"))
+	resp, ok := out.(*CloudFrontResponse)
+	if err != nil || !ok || resp.Headers.Get("strict-transport-security") == "" || resp.Headers.Get("content-type") != "" {
+		t.Fatalf("expected modified response, got %#v (%v)", out, err)
+	}
+}
+
+func TestServeCloudFront_MalformedRequestGetsBadRequest(t *testing.T) {
+	t.Parallel()
+
+	app := New()
+	app.Get("/", func(ctx *Context) (*Response, error) {
+		return ctx.AsCloudFront().Forward()
+	})
+
+	for name, mutate := range map[string]func(*CloudFrontRequest){
+		"query": func(req *CloudFrontRequest) { req.QueryString = "a=%zz" },
+		"body":  func(req *CloudFrontRequest) { req.Body = &CloudFrontRequestBody{Data: "!!!", Encoding: "base64"} },
+	} {
+		event := cloudFrontTestEvent(CloudFrontViewerRequest, "/")
+		mutate(&event.Records[0].CF.Request)
+		out, err := app.ServeCloudFront(context.Background(), event)
+		if resp, ok := out.(CloudFrontResponse); err != nil || !ok || resp.Status != "400" {
+			t.Fatalf("%s: expected generated 400, got %#v (%v)", name, out, err)
+		}
+	}
+}
+
+func TestServeCloudFront_ForwardDropsMiddlewareResponseHeaders(t *testing.T) {
+	t.Parallel()
+
+	app := New()
+	app.Use(func(next Handler) Handler {
+		return func(ctx *Context) (*Response, error) {
+			resp, err := next(ctx)
+			if resp != nil {
+				resp.Headers = map[string][]string{"x-mw": {"1"}}
+			}
+			return resp, err
+		}
+	})
+	app.Get("/", func(ctx *Context) (*Response, error) {
+		return ctx.AsCloudFront().Forward()
+	})
+
+	out, err := app.ServeCloudFront(context.Background(), cloudFrontTestEvent(CloudFrontOriginResponse, "/"))
+	resp, ok := out.(*CloudFrontResponse)
+	if err != nil || !ok || resp.Headers.Get("x-mw") != "" || resp.Headers.Get("content-type") != "text/html" {
+		t.Fatalf("expected forwarded response without middleware headers, got %#v (%v)", out, err)
+	}
+}
+
+func TestServeCloudFront_EnforcesEdgeLimits(t *testing.T) {
+	t.Parallel()
+
+	var mutate func(*CloudFrontContext) (*Response, error)
+	app := New()
+	app.Get("/", func(ctx *Context) (*Response, error) { return mutate(ctx.AsCloudFront()) })
+
+	cases := map[string]struct {
+		eventType string
+		mutate    func(*CloudFrontContext) (*Response, error)
+	}{
+		"read-only host": {CloudFrontViewerRequest, func(cf *CloudFrontContext) (*Response, error) {
+			cf.Request.Headers.Set("Host", "evil.example.com")
+			return cf.Forward()
+		}},
+		"disallowed header": {CloudFrontOriginRequest, func(cf *CloudFrontContext) (*Response, error) {
+			cf.Request.Headers.Set("X-Amz-Cf-Id", "spoof")
+			return cf.Forward()
+		}},
+		"read-only via": {CloudFrontViewerResponse, func(cf *CloudFrontContext) (*Response, error) {
+			cf.Response.Headers.Del("via")
+			return cf.Forward()
+		}},
+		"generated disallowed": {CloudFrontViewerRequest, func(*CloudFrontContext) (*Response, error) {
+			return &Response{Status: 200, Headers: map[string][]string{"connection": {"close"}}}, nil
+		}},
+		"viewer size": {CloudFrontViewerRequest, func(*CloudFrontContext) (*Response, error) {
+			return Text(200, strings.Repeat("x", cloudFrontViewerMaxBytes)), nil
+		}},
+		"body size": {CloudFrontViewerRequest, func(cf *CloudFrontContext) (*Response, error) {
+			cf.Request.SetBody([]byte(strings.Repeat("x", cloudFrontViewerMaxBytes)))
+			return cf.Forward()
+		}},
+	}
+	for name, tc := range cases {
+		mutate = tc.mutate
+		if _, err := app.ServeCloudFront(context.Background(), cloudFrontTestEvent(tc.eventType, "/")); err == nil {
+			t.Fatalf("%s: expected limit violation", name)
+		}
+	}
+
+	mutate = func(*CloudFrontContext) (*Response, error) {
+		return Text(200, strings.Repeat("x", cloudFrontViewerMaxBytes)), nil
+	}
+	if _, err := app.ServeCloudFront(context.Background(), cloudFrontTestEvent(CloudFrontOriginRequest, "/")); err != nil {
+		t.Fatalf("expected origin limit to allow larger responses: %v", err)
+	}
+
+	for _, event := range []CloudFrontEvent{
+		{},
+		cloudFrontTestEvent("unknown", "/"),
+		{Records: []CloudFrontEventRecord{{CF: CloudFrontRecord{Config: CloudFrontConfig{EventType: CloudFrontOriginResponse}}}}},
+	} {
+		if _, err := app.ServeCloudFront(context.Background(), event); err == nil {
+			t.Fatalf("expected invalid event to fail: %#v", event)
+		}
+	}
+}
+
+func TestHandleLambda_DispatchesCloudFront(t *testing.T) {
+	t.Parallel()
+
+	app := New()
+	app.Post("/submit", func(ctx *Context) (*Response, error) {
+		if string(ctx.Request.Body) != "hello" {
+			t.Fatalf("expected decoded body, got %q", ctx.Request.Body)
+		}
+		return Binary(200, []byte{0xff}, "application/octet-stream"), nil
+	})
+
+	event := json.RawMessage(`{"Records":[{"cf":{"config":{"distributionId":"E1","eventType":"origin-request","requestId":"r1"},` +
+		`"request":{"clientIp":"2001:db8::1","method":"POST","uri":"/submit","querystring":"","headers":{},` +
+		`"body":{"inputTruncated":false,"action":"read-only","encoding":"base64","data":"aGVsbG8="}}}}]}`)
+	out, err := app.HandleLambda(context.Background(), event)
+	resp, ok := out.(CloudFrontResponse)
+	if err != nil || !ok || resp.BodyEncoding != "base64" || resp.Body != "/w==" {
+		t.Fatalf("unexpected response %#v (%v)", out, err)
+	}
+}
@@ -0,0 +1,128 @@
+package apptheory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// CognitoTriggerEvent is one Cognito User Pools trigger invocation.
+//
+// The common header is decoded; Raw holds the complete event so handlers can
+// decode the trigger-specific request and response (see CognitoTriggerFunc).
+type CognitoTriggerEvent struct {
+	events.CognitoEventUserPoolsHeader
+	Raw json.RawMessage
+}
+
+// CognitoTriggerHandler handles a Cognito trigger. Its result is returned to
+// Cognito as the trigger response, which is normally the event itself with its
+// response section filled in.
+type CognitoTriggerHandler func(*EventContext, CognitoTriggerEvent) (any, error)
+
+// CognitoTriggerFunc adapts a typed handler, such as one taking and returning
+// events.CognitoEventUserPoolsPreSignup, to a CognitoTriggerHandler. The event
+// is decoded into T and the returned T is sent back to Cognito.
+func CognitoTriggerFunc[T any](fn func(*EventContext, T) (T, error)) CognitoTriggerHandler {
+	if fn == nil {
+		return nil
+	}
+	return func(ctx *EventContext, event CognitoTriggerEvent) (any, error) {
+		var typed T
+		if err := json.Unmarshal(event.Raw, &typed); err != nil {
+			return nil, fmt.Errorf("apptheory: decode cognito %s event: %w", event.TriggerSource, err)
+		}
+		return fn(ctx, typed)
+	}
+}
+
+// CognitoError returns an error whose message is passed to Cognito unchanged.
+// Cognito shows it to the user, for example when a pre sign-up trigger rejects
+// a registration. Any other handler error is replaced by a generic message.
+func CognitoError(message string) error {
+	return safeEventError{message: message}
+}
+
+type cognitoRoute struct {
+	TriggerSource string
+	Handler       CognitoTriggerHandler
+}
+
+// CognitoTrigger registers a handler for a Cognito User Pools trigger source,
+// such as "PreSignUp_SignUp" or "TokenGeneration_Authentication". A trigger
+// source ending in "*" matches every source with that prefix, so
+// "CustomMessage_*" handles all custom message triggers.
+//
+// Routes are evaluated in registration order and the first match wins.
+func (a *App) CognitoTrigger(triggerSource string, handler CognitoTriggerHandler) *App {
+	if a == nil {
+		return a
+	}
+	triggerSource = strings.TrimSpace(triggerSource)
+	if triggerSource == "" || handler == nil {
+		return a
+	}
+	a.cognitoRoutes = append(a.cognitoRoutes, cognitoRoute{TriggerSource: triggerSource, Handler: handler})
+	return a
+}
+
+func (a *App) cognitoHandlerForTrigger(triggerSource string) CognitoTriggerHandler {
+	if a == nil {
+		return nil
+	}
+	for _, route := range a.cognitoRoutes {
+		if prefix, ok := strings.CutSuffix(route.TriggerSource, "*"); ok && strings.HasPrefix(triggerSource, prefix) {
+			return route.Handler
+		}
+		if route.TriggerSource == triggerSource {
+			return route.Handler
+		}
+	}
+	return nil
+}
+
+// ServeCognitoTrigger routes a raw Cognito User Pools trigger event to the
+// handler registered for its trigger source.
+//
+// It fails closed: an unregistered trigger source returns an error, which
+// makes Cognito reject the operation. Handler errors other than CognitoError
+// and panics are reported to Cognito as a generic failure.
+func (a *App) ServeCognitoTrigger(ctx context.Context, event json.RawMessage) (any, error) {
+	var header events.CognitoEventUserPoolsHeader
+	if err := json.Unmarshal(event, &header); err != nil {
+		return nil, fmt.Errorf("apptheory: parse cognito trigger event: %w", err)
+	}
+	header.TriggerSource = strings.TrimSpace(header.TriggerSource)
+
+	handler := a.cognitoHandlerForTrigger(header.TriggerSource)
+	if handler == nil {
+		return nil, errors.New("apptheory: unrecognized cognito trigger")
+	}
+
+	evtCtx := a.eventContext(ctx)
+	evtCtx.rawEvent = append(json.RawMessage(nil), event...)
+	trigger := CognitoTriggerEvent{CognitoEventUserPoolsHeader: header, Raw: evtCtx.rawEvent}
+	return a.serveObservedEvent(evtCtx, cognitoObservation(evtCtx, header), trigger, func(ctx *EventContext, event any) (any, error) {
+		ev, ok := event.(CognitoTriggerEvent)
+		if !ok {
+			return nil, errors.New("apptheory: invalid cognito trigger event type")
+		}
+		return handler(ctx, ev)
+	})
+}
+
+// cognitoObservation deliberately leaves out the user name and attributes.
+func cognitoObservation(ctx *EventContext, header events.CognitoEventUserPoolsHeader) eventObservation {
+	requestID := eventContextRequestID(ctx)
+	return eventObservation{
+		Trigger:       eventTriggerCognito,
+		RequestID:     requestID,
+		CorrelationID: requestID,
+		Source:        strings.TrimSpace(header.UserPoolID),
+		EventName:     header.TriggerSource,
+	}
+}
@@ -0,0 +1,127 @@
+package apptheory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const cognitoPreSignUpEvent = `{
+	"version": "1",
+	"triggerSource": "PreSignUp_SignUp",
+	"region": "us-east-1",
+	"userPoolId": "us-east-1_pool",
+	"userName": "alice@example.com",
+	"callerContext": {"awsSdkVersion": "aws-sdk", "clientId": "client"},
+	"request": {"userAttributes": {"email": "alice@example.com"}},
+	"response": {}
+}`
+
+func TestServeCognitoTrigger_TypedHandlerAndMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var logs []LogRecord
+	var metrics []MetricRecord
+	app := New(WithObservability(ObservabilityHooks{
+		Log:    func(rec LogRecord) { logs = append(logs, rec) },
+		Metric: func(rec MetricRecord) { metrics = append(metrics, rec) },
+	}))
+	var seen []string
+	app.UseEvents(func(next EventHandler) EventHandler {
+		return func(ctx *EventContext, event any) (any, error) {
+			trigger, ok := event.(CognitoTriggerEvent)
+			if !ok {
+				t.Fatalf("expected CognitoTriggerEvent, got %T", event)
+			}
+			seen = append(seen, trigger.TriggerSource)
+			return next(ctx, event)
+		}
+	})
+	app.CognitoTrigger("PreSignUp_SignUp", CognitoTriggerFunc(func(_ *EventContext, ev events.CognitoEventUserPoolsPreSignup) (events.CognitoEventUserPoolsPreSignup, error) {
+		if ev.Request.UserAttributes["email"] != "alice@example.com" || ev.UserPoolID != "us-east-1_pool" {
+			t.Fatalf("unexpected decoded event: %#v", ev)
+		}
+		ev.Response.AutoConfirmUser = true
+		return ev, nil
+	}))
+
+	out, err := app.HandleLambda(context.Background(), json.RawMessage(cognitoPreSignUpEvent))
+	if err != nil {
+		t.Fatalf("HandleLambda: %v", err)
+	}
+	resp, ok := out.(events.CognitoEventUserPoolsPreSignup)
+	if !ok || !resp.Response.AutoConfirmUser || resp.TriggerSource != "PreSignUp_SignUp" {
+		t.Fatalf("unexpected response: %#v", out)
+	}
+	if len(seen) != 1 || seen[0] != "PreSignUp_SignUp" {
+		t.Fatalf("expected middleware to run once, got %v", seen)
+	}
+
+	if len(logs) != 1 || logs[0].Trigger != "cognito" || logs[0].EventName != "PreSignUp_SignUp" || logs[0].Source != "us-east-1_pool" {
+		t.Fatalf("unexpected log records: %#v", logs)
+	}
+	raw, err := json.Marshal(logs)
+	if err != nil || strings.Contains(string(raw), "alice") {
+		t.Fatalf("expected user identity to stay out of logs: %s (%v)", raw, err)
+	}
+	if len(metrics) != 1 || metrics[0].Tags["trigger_source"] != "PreSignUp_SignUp" || metrics[0].Tags["outcome"] != "success" {
+		t.Fatalf("unexpected metrics: %#v", metrics)
+	}
+}
+
+func TestServeCognitoTrigger_RoutingAndErrors(t *testing.T) {
+	t.Parallel()
+
+	app := New()
+	app.CognitoTrigger("CustomMessage_*", func(_ *EventContext, ev CognitoTriggerEvent) (any, error) {
+		return ev.TriggerSource, nil
+	})
+	app.CognitoTrigger("PreSignUp_SignUp", func(*EventContext, CognitoTriggerEvent) (any, error) {
+		return nil, CognitoError("email domain not allowed")
+	})
+	app.CognitoTrigger("PostConfirmation_ConfirmSignUp", func(*EventContext, CognitoTriggerEvent) (any, error) {
+		return nil, errors.New("dynamodb: table users not found")
+	})
+	app.CognitoTrigger("PreAuthentication_Authentication", func(*EventContext, CognitoTriggerEvent) (any, error) {
+		panic("boom")
+	})
+	app.CognitoTrigger("", func(*EventContext, CognitoTriggerEvent) (any, error) { return nil, nil })
+	app.CognitoTrigger("TokenGeneration_HostedAuth", nil)
+
+	event := func(source string) json.RawMessage {
+		return json.RawMessage(`{"triggerSource":"` + source + `","userPoolId":"pool","request":{},"response":{}}`)
+	}
+
+	out, err := app.ServeCognitoTrigger(context.Background(), event("CustomMessage_ForgotPassword"))
+	if err != nil || out != "CustomMessage_ForgotPassword" {
+		t.Fatalf("expected prefix route, got %v (%v)", out, err)
+	}
+	if _, err := app.ServeCognitoTrigger(context.Background(), event("PreSignUp_SignUp")); err == nil || err.Error() != "email domain not allowed" {
+		t.Fatalf("expected CognitoError to pass through, got %v", err)
+	}
+	for _, source := range []string{"PostConfirmation_ConfirmSignUp", "PreAuthentication_Authentication"} {
+		if _, err := app.ServeCognitoTrigger(context.Background(), event(source)); err == nil || err.Error() != eventWorkloadFailedMessage {
+			t.Fatalf("%s: expected sanitized error, got %v", source, err)
+		}
+	}
+	if _, err := app.ServeCognitoTrigger(context.Background(), event("TokenGeneration_HostedAuth")); err == nil {
+		t.Fatal("expected unregistered trigger to fail closed")
+	}
+	if _, err := app.ServeCognitoTrigger(context.Background(), json.RawMessage(`[]`)); err == nil {
+		t.Fatal("expected parse error")
+	}
+
+	typed := CognitoTriggerFunc(func(_ *EventContext, ev events.CognitoEventUserPoolsPreSignup) (events.CognitoEventUserPoolsPreSignup, error) {
+		return ev, nil
+	})
+	if _, err := typed(nil, CognitoTriggerEvent{Raw: json.RawMessage(`{"request":1}`)}); err == nil {
+		t.Fatal("expected typed decode error")
+	}
+	if CognitoTriggerFunc[events.CognitoEventUserPoolsPreSignup](nil) != nil {
+		t.Fatal("expected nil adapter for nil handler")
+	}
+}
@@ -0,0 +1,185 @@
+package apptheory
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// DynamoDB Streams event names.
+const (
+	DynamoDBStreamInsert = "INSERT"
+	DynamoDBStreamModify = "MODIFY"
+	DynamoDBStreamRemove = "REMOVE"
+)
+
+const eventPayloadSourceDynamoDB = "aws:dynamodb"
+
+// DynamoDBStreamChange is a typed view of one DynamoDB Streams record.
+type DynamoDBStreamChange[T any] struct {
+	// EventName is DynamoDBStreamInsert, DynamoDBStreamModify, or
+	// DynamoDBStreamRemove.
+	EventName string
+	// New and Old are the decoded images, or nil when the record has no such
+	// image (inserts have no old image, removes no new image, and the stream
+	// view type may omit either).
+	New *T
+	Old *T
+	// Changed lists the attribute names added, removed, or modified between
+	// the old and new images, sorted. It is only complete when the stream
+	// carries both images.
+	Changed []string
+	Record  events.DynamoDBEventRecord
+}
+
+// DecodeDynamoDBStreamChange decodes the images of a DynamoDB Streams record
+// into T with UnmarshalDynamoDBStreamImage.
+func DecodeDynamoDBStreamChange[T any](record events.DynamoDBEventRecord) (DynamoDBStreamChange[T], error) {
+	change := DynamoDBStreamChange[T]{
+		EventName: strings.ToUpper(strings.TrimSpace(record.EventName)),
+		Record:    record,
+	}
+	switch change.EventName {
+	case DynamoDBStreamInsert, DynamoDBStreamModify, DynamoDBStreamRemove:
+	default:
+		return change, fmt.Errorf("apptheory: unsupported dynamodb stream event name %q", record.EventName)
+	}
+
+	var err error
+	if change.New, err = decodeDynamoDBStreamImage[T](record.Change.NewImage); err != nil {
+		return change, err
+	}
+	if change.Old, err = decodeDynamoDBStreamImage[T](record.Change.OldImage); err != nil {
+		return change, err
+	}
+	change.Changed = ChangedDynamoDBAttributes(record.Change.OldImage, record.Change.NewImage)
+	return change, nil
+}
+
+func decodeDynamoDBStreamImage[T any](image map[string]events.DynamoDBAttributeValue) (*T, error) {
+	if len(image) == 0 {
+		return nil, nil
+	}
+	out := new(T)
+	if err := UnmarshalDynamoDBStreamImage(image, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ChangedDynamoDBAttributes returns the sorted names of attributes that differ
+// between two images. Set members are compared without regard to order.
+func ChangedDynamoDBAttributes(oldImage, newImage map[string]events.DynamoDBAttributeValue) []string {
+	changed := []string{}
+	for name, value := range newImage {
+		previous, ok := oldImage[name]
+		if !ok || !dynamoDBAttributesEqual(previous, value) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range oldImage {
+		if _, ok := newImage[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func dynamoDBAttributesEqual(a, b events.DynamoDBAttributeValue) bool {
+	if a.DataType() != b.DataType() {
+		return false
+	}
+	left, right := dynamoDBAttributeAny(a), dynamoDBAttributeAny(b)
+	switch a.DataType() {
+	case events.DataTypeStringSet, events.DataTypeNumberSet, events.DataTypeBinarySet:
+		left, right = sortedDynamoDBSet(left), sortedDynamoDBSet(right)
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func sortedDynamoDBSet(value any) any {
+	items, ok := value.([]any)
+	if !ok {
+		return value
+	}
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = fmt.Sprint(item)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DynamoDBStreamModelConfig configures DynamoDBStreamModel.
+type DynamoDBStreamModelConfig struct {
+	// PKPrefix and SKPrefix select one entity type of a single-table design.
+	// Records whose partition or sort key does not start with the prefix are
+	// skipped. Key attribute names come from the model's `pk` and `sk` tags
+	// and default to "PK" and "SK".
+	PKPrefix string
+	SKPrefix string
+	// Poison receives records whose images cannot be decoded and is required,
+	// as with EventJSONConfig.
+	Poison PoisonHandler
+}
+
+// DynamoDBStreamModel adapts a typed handler into a DynamoDBStreamHandler that
+// decodes each record's images into the tabletheory model T.
+// It panics when config.Poison is nil or when T uses `theorydb` options that
+// UnmarshalDynamoDBStreamImage rejects, such as `encrypted`.
+func DynamoDBStreamModel[T any](
+	config DynamoDBStreamModelConfig,
+	handler func(*EventContext, DynamoDBStreamChange[T]) error,
+) DynamoDBStreamHandler {
+	if handler == nil {
+		return nil
+	}
+	requirePoisonHandler(config.Poison)
+	pkAttr, skAttr := theoryDBDefaultPKAttr, theoryDBDefaultSKAttr
+	if modelType := reflect.TypeOf((*T)(nil)).Elem(); modelType.Kind() == reflect.Struct {
+		model, err := theoryDBModelFor(modelType)
+		if err != nil {
+			panic(err)
+		}
+		pkAttr, skAttr = model.pkAttr, model.skAttr
+	}
+
+	return func(ctx *EventContext, record events.DynamoDBEventRecord) error {
+		if !dynamoDBKeyHasPrefix(record, pkAttr, config.PKPrefix) || !dynamoDBKeyHasPrefix(record, skAttr, config.SKPrefix) {
+			return nil
+		}
+		change, err := DecodeDynamoDBStreamChange[T](record)
+		if err != nil {
+			payload, marshalErr := json.Marshal(record.Change)
+			if marshalErr != nil {
+				payload = nil
+			}
+			return handleEventPayloadError(ctx, config.Poison, &EventPayloadError{
+				Source:  eventPayloadSourceDynamoDB,
+				ID:      record.Change.SequenceNumber,
+				Payload: payload,
+				Err:     err,
+			})
+		}
+		return handler(ctx, change)
+	}
+}
+
+func dynamoDBKeyHasPrefix(record events.DynamoDBEventRecord, attr, prefix string) bool {
+	if prefix == "" {
+		return true
+	}
+	for _, image := range []map[string]events.DynamoDBAttributeValue{
+		record.Change.Keys, record.Change.NewImage, record.Change.OldImage,
+	} {
+		if value, ok := image[attr]; ok && value.DataType() == events.DataTypeString {
+			return strings.HasPrefix(value.String(), prefix)
+		}
+	}
+	return false
+}
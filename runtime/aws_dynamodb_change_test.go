@@ -0,0 +1,235 @@
+package apptheory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type streamTestAudit struct {
+	Actor string
+}
+
+type streamTestOrder struct {
+	PK        string `theorydb:"pk"`
+	SK        string `theorydb:"sk"`
+	Status    string `theorydb:"attr:status"`
+	Total     int64
+	Price     *float64
+	Paid      bool
+	Tags      []string        `theorydb:"set"`
+	Lines     map[string]int  `theorydb:"omitempty"`
+	Notes     json.RawMessage `theorydb:"json"`
+	Audit     streamTestAudit
+	Extra     any
+	CreatedAt time.Time         `theorydb:"created_at"`
+	Ignored   string            `theorydb:"-"`
+	Labels    map[string]string `theorydb:"omitempty"`
+}
+
+type streamTestSnake struct {
+	_            struct{} `theorydb:"naming:snake_case"`
+	ID           string   `theorydb:"index:id-index,pk"`
+	TenantID     string   `theorydb:"pk,attr:tenant"`
+	HTTPStatus   int
+	RetryCount   int
+	CreatedAtUTC time.Time
+}
+
+func streamTestOrderImage(status string, tags ...string) map[string]events.DynamoDBAttributeValue {
+	return map[string]events.DynamoDBAttributeValue{
+		"PK":        events.NewStringAttribute("ORDER#1"),
+		"SK":        events.NewStringAttribute("META"),
+		"status":    events.NewStringAttribute(status),
+		"Total":     events.NewNumberAttribute("42"),
+		"Price":     events.NewNumberAttribute("9.5"),
+		"Paid":      events.NewBooleanAttribute(true),
+		"Tags":      events.NewStringSetAttribute(tags),
+		"Lines":     events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{"sku-1": events.NewNumberAttribute("2")}),
+		"Notes":     events.NewStringAttribute(`{"gift":true}`),
+		"Audit":     events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{"Actor": events.NewStringAttribute("u1")}),
+		"Extra":     events.NewListAttribute([]events.DynamoDBAttributeValue{events.NewNumberAttribute("1.50")}),
+		"CreatedAt": events.NewStringAttribute("2026-01-02T03:04:05.5Z"),
+		"Ignored":   events.NewStringAttribute("x"),
+		"Labels":    events.NewNullAttribute(),
+	}
+}
+
+func TestUnmarshalDynamoDBStreamImage_TheoryDBTags(t *testing.T) {
+	t.Parallel()
+
+	var order streamTestOrder
+	if err := UnmarshalDynamoDBStreamImage(streamTestOrderImage("paid", "b", "a"), &order); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if order.PK != "ORDER#1" || order.Status != "paid" || order.Total != 42 || order.Price == nil || *order.Price != 9.5 || !order.Paid {
+		t.Fatalf("unexpected scalars %#v", order)
+	}
+	if len(order.Tags) != 2 || order.Lines["sku-1"] != 2 || string(order.Notes) != `{"gift":true}` || order.Audit.Actor != "u1" {
+		t.Fatalf("unexpected collections %#v", order)
+	}
+	if extra, ok := order.Extra.([]any); !ok || extra[0] != json.Number("1.50") {
+		t.Fatalf("unexpected any value %#v", order.Extra)
+	}
+	if order.CreatedAt.Nanosecond() != 500000000 || order.Ignored != "" || order.Labels != nil {
+		t.Fatalf("unexpected special fields %#v", order)
+	}
+
+	var snake streamTestSnake
+	if err := UnmarshalDynamoDBStreamImage(map[string]events.DynamoDBAttributeValue{
+		"id":             events.NewStringAttribute("i1"),
+		"tenant":         events.NewStringAttribute("t1"),
+		"http_status":    events.NewNumberAttribute("200"),
+		"retry_count":    events.NewNumberAttribute("3"),
+		"created_at_utc": events.NewNumberAttribute("1767322245"),
+	}, &snake); err != nil {
+		t.Fatalf("unmarshal snake: %v", err)
+	}
+	if snake.ID != "i1" || snake.TenantID != "t1" || snake.HTTPStatus != 200 || snake.RetryCount != 3 || snake.CreatedAtUTC.Unix() != 1767322245 {
+		t.Fatalf("unexpected snake model %#v", snake)
+	}
+	if model, err := theoryDBModelFor(reflect.TypeOf(streamTestSnake{})); err != nil || model.pkAttr != "tenant" || model.skAttr != "SK" {
+		t.Fatalf("expected index roles to be ignored for the primary key, got %#v (%v)", model, err)
+	}
+
+	for name, value := range map[string]events.DynamoDBAttributeValue{
+		"status":    events.NewNumberAttribute("1"),
+		"Total":     events.NewNumberAttribute("1.5"),
+		"Paid":      events.NewStringAttribute("yes"),
+		"Notes":     events.NewStringAttribute("{"),
+		"Tags":      events.NewNumberSetAttribute([]string{"1"}),
+		"CreatedAt": events.NewStringAttribute("yesterday"),
+		"Audit":     events.NewStringAttribute("u1"),
+	} {
+		image := streamTestOrderImage("paid")
+		image[name] = value
+		if err := UnmarshalDynamoDBStreamImage(image, &streamTestOrder{}); err == nil {
+			t.Fatalf("%s: expected type mismatch to fail", name)
+		}
+	}
+	if err := UnmarshalDynamoDBStreamImage(nil, streamTestOrder{}); err == nil {
+		t.Fatal("expected non-pointer target to fail")
+	}
+}
+
+func TestUnmarshalDynamoDBStreamImage_RejectsUnsupportedTags(t *testing.T) {
+	t.Parallel()
+
+	type encrypted struct {
+		Secret string `theorydb:"encrypted"`
+	}
+	type unknown struct {
+		Name string `theorydb:"attr:name,compressed"`
+	}
+	type naming struct {
+		_    struct{} `theorydb:"naming:camelCase"`
+		Name string
+	}
+	type nested struct {
+		Inner encrypted
+	}
+	image := map[string]events.DynamoDBAttributeValue{
+		"Secret": events.NewStringAttribute("ciphertext"),
+		"name":   events.NewStringAttribute("n"),
+		"Name":   events.NewStringAttribute("n"),
+		"Inner":  events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{"Secret": events.NewStringAttribute("c")}),
+	}
+	for name, target := range map[string]any{"encrypted": &encrypted{}, "unknown": &unknown{}, "naming": &naming{}, "nested": &nested{}} {
+		if err := UnmarshalDynamoDBStreamImage(image, target); err == nil {
+			t.Fatalf("%s: expected unsupported tag to fail", name)
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected DynamoDBStreamModel to reject an encrypted model")
+			}
+		}()
+		DynamoDBStreamModel(DynamoDBStreamModelConfig{Poison: AcknowledgePoison}, func(*EventContext, DynamoDBStreamChange[encrypted]) error { return nil })
+	}()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected DynamoDBStreamModel to require Poison")
+			}
+		}()
+		DynamoDBStreamModel(DynamoDBStreamModelConfig{}, func(*EventContext, DynamoDBStreamChange[streamTestOrder]) error { return nil })
+	}()
+}
+
+func TestDynamoDBStreamModel_ClassifiesFiltersAndDiffs(t *testing.T) {
+	t.Parallel()
+
+	var changes []DynamoDBStreamChange[streamTestOrder]
+	var poisoned []string
+	app := New()
+	app.DynamoDB("orders", DynamoDBStreamModel(DynamoDBStreamModelConfig{
+		PKPrefix: "ORDER#",
+		Poison: func(_ *EventContext, err *EventPayloadError) error {
+			poisoned = append(poisoned, err.ID)
+			return nil
+		},
+	}, func(_ *EventContext, change DynamoDBStreamChange[streamTestOrder]) error {
+		changes = append(changes, change)
+		if change.EventName == DynamoDBStreamRemove {
+			return errors.New("retry remove")
+		}
+		return nil
+	}))
+
+	record := func(seq, name string, keys, oldImage, newImage map[string]events.DynamoDBAttributeValue) events.DynamoDBEventRecord {
+		return events.DynamoDBEventRecord{
+			EventName:      name,
+			EventSourceArn: "arn:aws:dynamodb:us-east-1:1:table/orders/stream/2026",
+			Change:         events.DynamoDBStreamRecord{SequenceNumber: seq, Keys: keys, OldImage: oldImage, NewImage: newImage},
+		}
+	}
+	orderKeys := map[string]events.DynamoDBAttributeValue{"PK": events.NewStringAttribute("ORDER#1"), "SK": events.NewStringAttribute("META")}
+	userKeys := map[string]events.DynamoDBAttributeValue{"PK": events.NewStringAttribute("USER#1"), "SK": events.NewStringAttribute("META")}
+	bad := streamTestOrderImage("paid")
+	bad["Total"] = events.NewStringAttribute("lots")
+
+	resp := app.ServeDynamoDBStream(context.Background(), events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		record("1", "INSERT", orderKeys, nil, streamTestOrderImage("new", "a")),
+		record("2", "MODIFY", orderKeys, streamTestOrderImage("new", "a", "b"), streamTestOrderImage("paid", "b", "a")),
+		record("3", "MODIFY", userKeys, nil, nil),
+		record("4", "MODIFY", orderKeys, nil, bad),
+		record("5", "REMOVE", orderKeys, streamTestOrderImage("paid"), nil),
+	}})
+
+	if len(changes) != 3 || len(poisoned) != 1 || poisoned[0] != "4" {
+		t.Fatalf("unexpected dispatch: %d changes, poisoned %v", len(changes), poisoned)
+	}
+	if changes[0].EventName != DynamoDBStreamInsert || changes[0].Old != nil || changes[0].New.Status != "new" {
+		t.Fatalf("unexpected insert %#v", changes[0])
+	}
+	if changes[1].Old.Status != "new" || changes[1].New.Status != "paid" || len(changes[1].Changed) != 1 || changes[1].Changed[0] != "status" {
+		t.Fatalf("unexpected modify %#v", changes[1])
+	}
+	if changes[2].New != nil || changes[2].Old == nil || len(resp.BatchItemFailures) != 1 || resp.BatchItemFailures[0].ItemIdentifier != "5" {
+		t.Fatalf("unexpected remove %#v / %#v", changes[2], resp.BatchItemFailures)
+	}
+
+	if _, err := DecodeDynamoDBStreamChange[streamTestOrder](events.DynamoDBEventRecord{EventName: "TTL"}); err == nil {
+		t.Fatal("expected unknown event name to fail")
+	}
+}
+
+func TestChangedDynamoDBAttributes(t *testing.T) {
+	t.Parallel()
+
+	got := ChangedDynamoDBAttributes(
+		map[string]events.DynamoDBAttributeValue{"a": events.NewStringAttribute("1"), "b": events.NewNumberAttribute("1"), "gone": events.NewBooleanAttribute(true)},
+		map[string]events.DynamoDBAttributeValue{"a": events.NewStringAttribute("1"), "b": events.NewStringAttribute("1"), "added": events.NewNullAttribute()},
+	)
+	want := []string{"added", "b", "gone"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
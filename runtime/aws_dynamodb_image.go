@@ -0,0 +1,451 @@
+package apptheory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	theoryDBTag           = "theorydb"
+	theoryDBNamingSnake   = "naming:snake_case"
+	theoryDBDefaultPKAttr = "PK"
+	theoryDBDefaultSKAttr = "SK"
+	theoryDBAttrPrefix    = "attr:"
+	theoryDBIndexPrefix   = "index:"
+	theoryDBLSIPrefix     = "lsi:"
+	theoryDBRolePK        = "pk"
+	theoryDBRoleSK        = "sk"
+	theoryDBOptionJSON    = "json"
+	theoryDBOptionEncrypt = "encrypted"
+	theoryDBOptionIgnore  = "-"
+)
+
+// theoryDBPassiveOptions are tabletheory tag options that only affect writes,
+// so a stream image decodes the same with or without them.
+var theoryDBPassiveOptions = map[string]bool{
+	"omitempty":  true,
+	"set":        true,
+	"binary":     true,
+	"ttl":        true,
+	"created_at": true,
+	"updated_at": true,
+	"version":    true,
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type theoryDBField struct {
+	index []int
+	name  string
+	json  bool
+}
+
+type theoryDBModel struct {
+	fields []theoryDBField
+	pkAttr string
+	skAttr string
+}
+
+var theoryDBModels sync.Map
+
+type theoryDBModelResult struct {
+	model *theoryDBModel
+	err   error
+}
+
+// theoryDBModelFor reads the attribute layout of a tabletheory model: `attr:`
+// overrides, `naming:snake_case`, the `pk`/`sk` roles, and the `json` and `-`
+// options. Attributes default to the Go field name. Tag options it cannot
+// honour, including `encrypted`, are errors rather than silently zero fields.
+func theoryDBModelFor(t reflect.Type) (*theoryDBModel, error) {
+	if cached, ok := theoryDBModels.Load(t); ok {
+		if result, ok := cached.(theoryDBModelResult); ok {
+			return result.model, result.err
+		}
+	}
+
+	model, err := buildTheoryDBModel(t)
+	theoryDBModels.Store(t, theoryDBModelResult{model: model, err: err})
+	return model, err
+}
+
+func buildTheoryDBModel(t reflect.Type) (*theoryDBModel, error) {
+	model := &theoryDBModel{pkAttr: theoryDBDefaultPKAttr, skAttr: theoryDBDefaultSKAttr}
+	snake := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name != "_" {
+			continue
+		}
+		for _, token := range strings.Split(field.Tag.Get(theoryDBTag), ",") {
+			switch token = strings.TrimSpace(token); token {
+			case "":
+			case theoryDBNamingSnake:
+				snake = true
+			default:
+				return nil, fmt.Errorf("apptheory: theorydb option %q on %s is not supported for stream images", token, t)
+			}
+		}
+	}
+	if err := collectTheoryDBFields(t, nil, snake, model); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+func collectTheoryDBFields(t reflect.Type, parent []int, snake bool, model *theoryDBModel) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get(theoryDBTag)
+		index := append(append([]int(nil), parent...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && tag == "" {
+			if err := collectTheoryDBFields(field.Type, index, snake, model); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() || tag == theoryDBOptionIgnore {
+			continue
+		}
+
+		name := field.Name
+		if snake {
+			name = snakeCaseFieldName(name)
+		}
+		out := theoryDBField{index: index, name: name}
+		pk, sk, err := parseTheoryDBTag(tag, &out)
+		if err != nil {
+			return fmt.Errorf("apptheory: theorydb field %s.%s: %w", t, field.Name, err)
+		}
+		if pk {
+			model.pkAttr = out.name
+		}
+		if sk {
+			model.skAttr = out.name
+		}
+		model.fields = append(model.fields, out)
+	}
+	return nil
+}
+
+// parseTheoryDBTag applies the tag options to field and reports the primary
+// key roles. A role right after index:/lsi: belongs to that index instead.
+// Encrypted fields hold ciphertext in the stream, so they are rejected along
+// with options this decoder does not know.
+func parseTheoryDBTag(tag string, field *theoryDBField) (pk, sk bool, err error) {
+	afterIndex := false
+	for _, token := range strings.Split(tag, ",") {
+		token = strings.TrimSpace(token)
+		switch {
+		case token == "":
+		case strings.HasPrefix(token, theoryDBAttrPrefix):
+			field.name = strings.TrimPrefix(token, theoryDBAttrPrefix)
+		case strings.HasPrefix(token, theoryDBIndexPrefix), strings.HasPrefix(token, theoryDBLSIPrefix):
+			afterIndex = true
+			continue
+		case token == theoryDBRolePK && !afterIndex:
+			pk = true
+		case token == theoryDBRoleSK && !afterIndex:
+			sk = true
+		case token == theoryDBRolePK, token == theoryDBRoleSK:
+		case token == theoryDBOptionJSON:
+			field.json = true
+		case token == theoryDBOptionEncrypt:
+			return false, false, errors.New("encrypted attributes cannot be decoded from stream images")
+		case theoryDBPassiveOptions[token]:
+		default:
+			return false, false, fmt.Errorf("option %q is not supported for stream images", token)
+		}
+		afterIndex = false
+	}
+	return pk, sk, nil
+}
+
+func snakeCaseFieldName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// UnmarshalDynamoDBStreamImage decodes a DynamoDB Streams image into out, a
+// pointer to a struct, using the same `theorydb` tags as tabletheory models.
+//
+// Strings, numbers, booleans, binary, lists, sets, and maps decode into the
+// matching Go kinds; time.Time reads RFC 3339 strings or Unix seconds; fields
+// tagged `json` are unmarshaled from their JSON string, and attributes without
+// a field are ignored. Models with `encrypted` fields or tag options this
+// decoder does not support return an error instead of a partial decode.
+func UnmarshalDynamoDBStreamImage(image map[string]events.DynamoDBAttributeValue, out any) error {
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Pointer || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return errors.New("apptheory: dynamodb image target must be a non-nil struct pointer")
+	}
+	return decodeTheoryDBStruct(image, target.Elem(), "")
+}
+
+func decodeTheoryDBStruct(image map[string]events.DynamoDBAttributeValue, target reflect.Value, path string) error {
+	model, err := theoryDBModelFor(target.Type())
+	if err != nil {
+		return err
+	}
+	for _, field := range model.fields {
+		value, ok := image[field.name]
+		if !ok || value.IsNull() {
+			continue
+		}
+		dest := target.FieldByIndex(field.index)
+		name := path + field.name
+		if field.json {
+			if value.DataType() != events.DataTypeString {
+				return dynamoDBImageTypeError(name, "a JSON string")
+			}
+			if err := json.Unmarshal([]byte(value.String()), dest.Addr().Interface()); err != nil {
+				return fmt.Errorf("apptheory: dynamodb attribute %s is not valid JSON: %w", name, err)
+			}
+			continue
+		}
+		if err := decodeDynamoDBAttribute(value, dest, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dynamoDBImageTypeError(name, want string) error {
+	return fmt.Errorf("apptheory: dynamodb attribute %s must be %s", name, want)
+}
+
+func decodeDynamoDBAttribute(value events.DynamoDBAttributeValue, dest reflect.Value, name string) error {
+	if value.IsNull() {
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+	switch {
+	case dest.Kind() == reflect.Pointer:
+		elem := reflect.New(dest.Type().Elem())
+		if err := decodeDynamoDBAttribute(value, elem.Elem(), name); err != nil {
+			return err
+		}
+		dest.Set(elem)
+		return nil
+	case dest.Type() == timeType:
+		return decodeDynamoDBTime(value, dest, name)
+	case dest.Kind() == reflect.Interface && dest.NumMethod() == 0:
+		if decoded := dynamoDBAttributeAny(value); decoded != nil {
+			dest.Set(reflect.ValueOf(decoded))
+		}
+		return nil
+	case dest.Kind() == reflect.Slice && dest.Type().Elem().Kind() == reflect.Uint8:
+		return decodeDynamoDBBytes(value, dest, name)
+	}
+
+	switch dest.Kind() {
+	case reflect.Slice:
+		return decodeDynamoDBList(value, dest, name)
+	case reflect.Map:
+		return decodeDynamoDBMap(value, dest, name)
+	case reflect.Struct:
+		if value.DataType() != events.DataTypeMap {
+			return dynamoDBImageTypeError(name, "a map")
+		}
+		return decodeTheoryDBStruct(value.Map(), dest, name+".")
+	default:
+		return decodeDynamoDBScalar(value, dest, name)
+	}
+}
+
+func decodeDynamoDBScalar(value events.DynamoDBAttributeValue, dest reflect.Value, name string) error {
+	switch dest.Kind() {
+	case reflect.String:
+		if value.DataType() != events.DataTypeString {
+			return dynamoDBImageTypeError(name, "a string")
+		}
+		dest.SetString(value.String())
+	case reflect.Bool:
+		if value.DataType() != events.DataTypeBoolean {
+			return dynamoDBImageTypeError(name, "a boolean")
+		}
+		dest.SetBool(value.Boolean())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return decodeDynamoDBNumber(value, dest, name)
+	default:
+		return fmt.Errorf("apptheory: dynamodb attribute %s has unsupported field type %s", name, dest.Type())
+	}
+	return nil
+}
+
+func decodeDynamoDBTime(value events.DynamoDBAttributeValue, dest reflect.Value, name string) error {
+	switch value.DataType() {
+	case events.DataTypeString:
+		parsed, err := time.Parse(time.RFC3339Nano, value.String())
+		if err != nil {
+			return fmt.Errorf("apptheory: dynamodb attribute %s is not an RFC 3339 time: %w", name, err)
+		}
+		dest.Set(reflect.ValueOf(parsed))
+	case events.DataTypeNumber:
+		seconds, err := value.Integer()
+		if err != nil {
+			return fmt.Errorf("apptheory: dynamodb attribute %s is not a Unix time: %w", name, err)
+		}
+		dest.Set(reflect.ValueOf(time.Unix(seconds, 0).UTC()))
+	default:
+		return dynamoDBImageTypeError(name, "a time string or number")
+	}
+	return nil
+}
+
+func decodeDynamoDBBytes(value events.DynamoDBAttributeValue, dest reflect.Value, name string) error {
+	var raw []byte
+	switch value.DataType() {
+	case events.DataTypeBinary:
+		raw = value.Binary()
+	case events.DataTypeString:
+		raw = []byte(value.String())
+	default:
+		return dynamoDBImageTypeError(name, "binary or a string")
+	}
+	dest.SetBytes(append([]byte(nil), raw...))
+	return nil
+}
+
+func decodeDynamoDBNumber(value events.DynamoDBAttributeValue, dest reflect.Value, name string) error {
+	if value.DataType() != events.DataTypeNumber {
+		return dynamoDBImageTypeError(name, "a number")
+	}
+	raw := value.Number()
+	var err error
+	switch dest.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(raw, 10, dest.Type().Bits()); err == nil {
+			dest.SetInt(n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		if n, err = strconv.ParseUint(raw, 10, dest.Type().Bits()); err == nil {
+			dest.SetUint(n)
+		}
+	default:
+		var f float64
+		if f, err = strconv.ParseFloat(raw, dest.Type().Bits()); err == nil {
+			dest.SetFloat(f)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("apptheory: dynamodb attribute %s does not fit %s: %w", name, dest.Type(), err)
+	}
+	return nil
+}
+
+func dynamoDBListItems(value events.DynamoDBAttributeValue) ([]events.DynamoDBAttributeValue, bool) {
+	switch value.DataType() {
+	case events.DataTypeList:
+		return value.List(), true
+	case events.DataTypeStringSet:
+		items := make([]events.DynamoDBAttributeValue, 0, len(value.StringSet()))
+		for _, item := range value.StringSet() {
+			items = append(items, events.NewStringAttribute(item))
+		}
+		return items, true
+	case events.DataTypeNumberSet:
+		items := make([]events.DynamoDBAttributeValue, 0, len(value.NumberSet()))
+		for _, item := range value.NumberSet() {
+			items = append(items, events.NewNumberAttribute(item))
+		}
+		return items, true
+	case events.DataTypeBinarySet:
+		items := make([]events.DynamoDBAttributeValue, 0, len(value.BinarySet()))
+		for _, item := range value.BinarySet() {
+			items = append(items, events.NewBinaryAttribute(item))
+		}
+		return items, true
+	default:
+		return nil, false
+	}
+}
+
+func decodeDynamoDBList(value events.DynamoDBAttributeValue, dest reflect.Value, name string) error {
+	items, ok := dynamoDBListItems(value)
+	if !ok {
+		return dynamoDBImageTypeError(name, "a list or set")
+	}
+	out := reflect.MakeSlice(dest.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := decodeDynamoDBAttribute(item, out.Index(i), name+"["+strconv.Itoa(i)+"]"); err != nil {
+			return err
+		}
+	}
+	dest.Set(out)
+	return nil
+}
+
+func decodeDynamoDBMap(value events.DynamoDBAttributeValue, dest reflect.Value, name string) error {
+	if value.DataType() != events.DataTypeMap {
+		return dynamoDBImageTypeError(name, "a map")
+	}
+	if dest.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("apptheory: dynamodb attribute %s has unsupported field type %s", name, dest.Type())
+	}
+	out := reflect.MakeMapWithSize(dest.Type(), len(value.Map()))
+	for key, item := range value.Map() {
+		elem := reflect.New(dest.Type().Elem()).Elem()
+		if err := decodeDynamoDBAttribute(item, elem, name+"."+key); err != nil {
+			return err
+		}
+		out.SetMapIndex(reflect.ValueOf(key).Convert(dest.Type().Key()), elem)
+	}
+	dest.Set(out)
+	return nil
+}
+
+// dynamoDBAttributeAny converts an attribute to plain Go values for `any`
+// fields; numbers become json.Number to keep their precision.
+func dynamoDBAttributeAny(value events.DynamoDBAttributeValue) any {
+	switch value.DataType() {
+	case events.DataTypeString:
+		return value.String()
+	case events.DataTypeNumber:
+		return json.Number(value.Number())
+	case events.DataTypeBoolean:
+		return value.Boolean()
+	case events.DataTypeBinary:
+		return append([]byte(nil), value.Binary()...)
+	case events.DataTypeMap:
+		out := make(map[string]any, len(value.Map()))
+		for key, item := range value.Map() {
+			out[key] = dynamoDBAttributeAny(item)
+		}
+		return out
+	default:
+		items, ok := dynamoDBListItems(value)
+		if !ok {
+			return nil
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			out[i] = dynamoDBAttributeAny(item)
+		}
+		return out
+	}
+}
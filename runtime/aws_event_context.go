@@ -15,12 +15,15 @@ type EventContext struct {
 
 	clock Clock
 	ids   IDGenerator
+	log   func(LogRecord)
 
 	RequestID   string
 	RemainingMS int
 
-	rawEvent json.RawMessage
-	values   map[string]any
+	rawEvent      json.RawMessage
+	s3Record      *S3EventRecord
+	kinesisRecord *KinesisUserRecord
+	values        map[string]any
 }
 
 func (c *EventContext) cloneForRecord() *EventContext {
@@ -33,10 +36,20 @@ func (c *EventContext) cloneForRecord() *EventContext {
 		ids:         c.ids,
 		RequestID:   c.RequestID,
 		RemainingMS: c.RemainingMS,
+		log:         c.log,
 		rawEvent:    append(json.RawMessage(nil), c.rawEvent...),
 	}
 }
 
+// S3Record returns the S3 object notification being handled, when the
+// handler was invoked for one.
+func (c *EventContext) S3Record() (S3EventRecord, bool) {
+	if c == nil || c.s3Record == nil {
+		return S3EventRecord{}, false
+	}
+	return *c.s3Record, true
+}
+
 func (c *EventContext) Context() context.Context {
 	if c == nil || c.ctx == nil {
 		return context.Background()
@@ -88,19 +101,23 @@ func (a *App) eventContext(ctx context.Context) *EventContext {
 		ctx = context.Background()
 	}
 
-	requestID := ""
-	if lc, ok := lambdacontext.FromContext(ctx); ok {
-		requestID = strings.TrimSpace(lc.AwsRequestID)
-	}
-	if requestID == "" {
-		requestID = a.newRequestID()
-	}
-
 	return &EventContext{
 		ctx:         ctx,
 		clock:       a.clock,
 		ids:         a.ids,
-		RequestID:   requestID,
+		RequestID:   a.lambdaRequestID(ctx),
 		RemainingMS: remainingMSFromContext(ctx, a.clock),
+		log:         a.obs.Log,
+	}
+}
+
+// lambdaRequestID returns the Lambda invocation request id, or a generated id
+// outside Lambda.
+func (a *App) lambdaRequestID(ctx context.Context) string {
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		if requestID := strings.TrimSpace(lc.AwsRequestID); requestID != "" {
+			return requestID
+		}
 	}
+	return a.newRequestID()
 }
@@ -13,6 +13,7 @@ type EventBridgeSelector struct {
 	RuleName   string
 	Source     string
 	DetailType string
+	Pattern    *EventPattern
 }
 
 func EventBridgeRule(ruleName string) EventBridgeSelector {
@@ -26,6 +27,12 @@ func EventBridgePattern(source, detailType string) EventBridgeSelector {
 	}
 }
 
+// EventBridgeMatch returns a selector that matches events against a compiled
+// EventBridge event pattern (see ParseEventPattern).
+func EventBridgeMatch(pattern *EventPattern) EventBridgeSelector {
+	return EventBridgeSelector{Pattern: pattern}
+}
+
 type EventBridgeHandler func(*EventContext, events.EventBridgeEvent) (any, error)
 
 type eventBridgeRoute struct {
@@ -38,6 +45,10 @@ type eventBridgeRoute struct {
 // Matching rules:
 // - If selector.RuleName is set, it matches when any event resource ARN refers to that rule name.
 // - Otherwise, it matches on selector.Source + selector.DetailType (when provided).
+// - If selector.Pattern is set, the event must also match the pattern.
+//
+// Routes are evaluated in registration order and the first match wins, so
+// register narrower selectors before broader ones.
 func (a *App) EventBridge(selector EventBridgeSelector, handler EventBridgeHandler) *App {
 	if a == nil {
 		return a
@@ -48,7 +59,7 @@ func (a *App) EventBridge(selector EventBridgeSelector, handler EventBridgeHandl
 	selector.RuleName = strings.TrimSpace(selector.RuleName)
 	selector.Source = strings.TrimSpace(selector.Source)
 	selector.DetailType = strings.TrimSpace(selector.DetailType)
-	if selector.RuleName == "" && selector.Source == "" && selector.DetailType == "" {
+	if selector.RuleName == "" && selector.Source == "" && selector.DetailType == "" && selector.Pattern == nil {
 		return a
 	}
 	a.eventBridgeRoutes = append(a.eventBridgeRoutes, eventBridgeRoute{Selector: selector, Handler: handler})
@@ -88,40 +99,66 @@ func (a *App) eventBridgeHandlerForEvent(event events.EventBridgeEvent) EventBri
 		return nil
 	}
 
+	// The pattern document is built at most once per event, and only when a
+	// pattern route is reached.
+	var (
+		doc      map[string]any
+		docReady bool
+		docOK    bool
+	)
 	for _, route := range a.eventBridgeRoutes {
-		if route.Handler == nil {
+		if route.Handler == nil || !eventBridgeSelectorMatches(route.Selector, event) {
 			continue
 		}
-		sel := route.Selector
-		if sel.RuleName != "" {
-			for _, resource := range event.Resources {
-				if eventBridgeRuleNameFromARN(resource) == sel.RuleName {
-					return route.Handler
-				}
-			}
-			continue
+		if route.Selector.Pattern == nil {
+			return route.Handler
 		}
-
-		if sel.Source != "" && strings.TrimSpace(event.Source) != sel.Source {
-			continue
+		if !docReady {
+			doc, docOK = eventBridgePatternDocument(event)
+			docReady = true
 		}
-		if sel.DetailType != "" && strings.TrimSpace(event.DetailType) != sel.DetailType {
-			continue
+		if docOK && route.Selector.Pattern.matchesDocument(doc) {
+			return route.Handler
 		}
-		return route.Handler
 	}
 
 	return nil
 }
 
+func eventBridgeSelectorMatches(sel EventBridgeSelector, event events.EventBridgeEvent) bool {
+	if sel.RuleName != "" {
+		for _, resource := range event.Resources {
+			if eventBridgeRuleNameFromARN(resource) == sel.RuleName {
+				return true
+			}
+		}
+		return false
+	}
+
+	if sel.Source != "" && strings.TrimSpace(event.Source) != sel.Source {
+		return false
+	}
+	if sel.DetailType != "" && strings.TrimSpace(event.DetailType) != sel.DetailType {
+		return false
+	}
+	return true
+}
+
 // ServeEventBridge routes an EventBridge event to the first matching handler.
 //
+// S3 object events from aws.s3 that match an App.S3 route are handled by that
+// route before the EventBridge routes are considered.
+//
 // If no handler matches, it returns (nil, nil).
 func (a *App) ServeEventBridge(ctx context.Context, event events.EventBridgeEvent) (any, error) {
 	return a.serveEventBridge(ctx, event, nil)
 }
 
-func (a *App) serveEventBridge(ctx context.Context, event events.EventBridgeEvent, raw json.RawMessage) (out any, err error) {
+func (a *App) serveEventBridge(ctx context.Context, event events.EventBridgeEvent, raw json.RawMessage) (any, error) {
+	if handled, err := a.serveS3EventBridge(ctx, event); handled {
+		return nil, err
+	}
+
 	handler := a.eventBridgeHandlerForEvent(event)
 	if handler == nil {
 		return nil, nil
@@ -129,33 +166,11 @@ func (a *App) serveEventBridge(ctx context.Context, event events.EventBridgeEven
 
 	evtCtx := a.eventContext(ctx)
 	evtCtx.rawEvent = append(json.RawMessage(nil), raw...)
-	observation := eventBridgeObservation(evtCtx, event)
-	defer func() {
-		if recovered := recover(); recovered != nil {
-			out = nil
-			err = eventWorkloadFailedError()
-			a.recordEventObservability(observation, "error", "app.internal")
-			return
-		}
-		if err != nil {
-			err = sanitizeEventWorkloadError(err)
-			a.recordEventObservability(observation, "error", "app.internal")
-			return
+	return a.serveObservedEvent(evtCtx, eventBridgeObservation(evtCtx, event), event, func(ctx *EventContext, event any) (any, error) {
+		ev, ok := event.(events.EventBridgeEvent)
+		if !ok {
+			return nil, errors.New("apptheory: invalid eventbridge event type")
 		}
-		a.recordEventObservability(observation, "success", "")
-	}()
-
-	if a != nil && len(a.eventMiddlewares) > 0 {
-		original := handler
-		wrapped := a.applyEventMiddlewares(func(ctx *EventContext, event any) (any, error) {
-			ev, ok := event.(events.EventBridgeEvent)
-			if !ok {
-				return nil, errors.New("apptheory: invalid eventbridge event type")
-			}
-			return original(ctx, ev)
-		})
-		return wrapped(evtCtx, event)
-	}
-
-	return handler(evtCtx, event)
+		return handler(ctx, ev)
+	})
 }
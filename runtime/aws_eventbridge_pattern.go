@@ -0,0 +1,555 @@
+package apptheory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// EventPattern is a compiled EventBridge event pattern.
+//
+// It supports the EventBridge content filters: exact values, null, prefix,
+// suffix, equals-ignore-case, wildcard, anything-but, numeric ranges, exists,
+// cidr, $or, and nested fields at any depth. String returns the pattern as
+// compact JSON, so the same pattern can be passed to an EventBridge rule.
+type EventPattern struct {
+	raw  string
+	root *patternObject
+}
+
+// ParseEventPattern compiles an EventBridge event pattern from its JSON form.
+func ParseEventPattern(pattern string) (*EventPattern, error) {
+	dec := json.NewDecoder(strings.NewReader(pattern))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("apptheory: invalid eventbridge pattern: %w", err)
+	}
+	if len(doc) == 0 {
+		return nil, errors.New("apptheory: eventbridge pattern must be a non-empty object")
+	}
+	root, err := compilePatternObject(doc, "")
+	if err != nil {
+		return nil, fmt.Errorf("apptheory: invalid eventbridge pattern: %w", err)
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(pattern)); err != nil {
+		return nil, fmt.Errorf("apptheory: invalid eventbridge pattern: %w", err)
+	}
+	return &EventPattern{raw: compact.String(), root: root}, nil
+}
+
+// MustParseEventPattern is like ParseEventPattern but panics on an invalid
+// pattern. It is intended for patterns that are constants in the program.
+func MustParseEventPattern(pattern string) *EventPattern {
+	compiled, err := ParseEventPattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// String returns the pattern as compact JSON.
+func (p *EventPattern) String() string {
+	if p == nil {
+		return ""
+	}
+	return p.raw
+}
+
+// MarshalJSON returns the pattern JSON unchanged.
+func (p *EventPattern) MarshalJSON() ([]byte, error) {
+	if p == nil {
+		return []byte("null"), nil
+	}
+	return []byte(p.raw), nil
+}
+
+// Matches reports whether event matches the pattern.
+func (p *EventPattern) Matches(event events.EventBridgeEvent) bool {
+	doc, ok := eventBridgePatternDocument(event)
+	return ok && p.matchesDocument(doc)
+}
+
+func (p *EventPattern) matchesDocument(doc map[string]any) bool {
+	return p != nil && p.root != nil && p.root.matches(doc)
+}
+
+// eventBridgePatternDocument returns the event in the JSON shape patterns are
+// written against ("detail-type", "account", nested "detail", ...).
+func eventBridgePatternDocument(event events.EventBridgeEvent) (map[string]any, bool) {
+	if len(bytes.TrimSpace(event.Detail)) == 0 {
+		event.Detail = nil
+	}
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+type patternObject struct {
+	fields []patternField
+	or     []*patternObject
+}
+
+type patternField struct {
+	key      string
+	object   *patternObject
+	matchers []patternMatcher
+}
+
+// patternMatcher matches one element of a field's match array. exists is set
+// only for {"exists": ...}; every other matcher uses match.
+type patternMatcher struct {
+	exists *bool
+	match  func(any) bool
+}
+
+func compilePatternObject(doc map[string]any, path string) (*patternObject, error) {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := &patternObject{}
+	for _, key := range keys {
+		if key == "$or" {
+			or, err := compilePatternOr(doc[key], path)
+			if err != nil {
+				return nil, err
+			}
+			out.or = or
+			continue
+		}
+		field, err := compilePatternField(key, doc[key], joinPatternPath(path, key))
+		if err != nil {
+			return nil, err
+		}
+		out.fields = append(out.fields, field)
+	}
+	return out, nil
+}
+
+func compilePatternOr(value any, path string) ([]*patternObject, error) {
+	items, ok := value.([]any)
+	if !ok || len(items) < 2 {
+		return nil, fmt.Errorf("%s: $or must be an array of at least two patterns", joinPatternPath(path, "$or"))
+	}
+	out := make([]*patternObject, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok || len(obj) == 0 {
+			return nil, fmt.Errorf("%s: $or entries must be non-empty objects", joinPatternPath(path, "$or"))
+		}
+		compiled, err := compilePatternObject(obj, path)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, compiled)
+	}
+	return out, nil
+}
+
+func compilePatternField(key string, value any, path string) (patternField, error) {
+	switch typed := value.(type) {
+	case map[string]any:
+		if len(typed) == 0 {
+			return patternField{}, fmt.Errorf("%s: nested pattern must not be empty", path)
+		}
+		obj, err := compilePatternObject(typed, path)
+		if err != nil {
+			return patternField{}, err
+		}
+		return patternField{key: key, object: obj}, nil
+	case []any:
+		if len(typed) == 0 {
+			return patternField{}, fmt.Errorf("%s: match values must not be empty", path)
+		}
+		matchers := make([]patternMatcher, 0, len(typed))
+		for _, item := range typed {
+			matcher, err := compilePatternMatcher(item, path)
+			if err != nil {
+				return patternField{}, err
+			}
+			matchers = append(matchers, matcher)
+		}
+		return patternField{key: key, matchers: matchers}, nil
+	default:
+		return patternField{}, fmt.Errorf("%s: must be an object or an array of match values", path)
+	}
+}
+
+func compilePatternMatcher(item any, path string) (patternMatcher, error) {
+	obj, ok := item.(map[string]any)
+	if !ok {
+		if _, isList := item.([]any); isList {
+			return patternMatcher{}, fmt.Errorf("%s: match values must not be arrays", path)
+		}
+		return patternMatcher{match: literalPatternMatch(item)}, nil
+	}
+	if len(obj) != 1 {
+		return patternMatcher{}, fmt.Errorf("%s: content filters must have exactly one operator", path)
+	}
+	for op, arg := range obj {
+		if op == "exists" {
+			want, ok := arg.(bool)
+			if !ok {
+				return patternMatcher{}, fmt.Errorf("%s: exists must be true or false", path)
+			}
+			return patternMatcher{exists: &want}, nil
+		}
+		match, err := compilePatternOperator(op, arg)
+		if err != nil {
+			return patternMatcher{}, fmt.Errorf("%s: %w", path, err)
+		}
+		return patternMatcher{match: match}, nil
+	}
+	return patternMatcher{}, nil
+}
+
+func compilePatternOperator(op string, arg any) (func(any) bool, error) {
+	switch op {
+	case "prefix":
+		return compileAffixMatch(op, arg, strings.HasPrefix)
+	case "suffix":
+		return compileAffixMatch(op, arg, strings.HasSuffix)
+	case "equals-ignore-case":
+		return compileStringMatch(op, arg, strings.EqualFold)
+	case "wildcard":
+		return compileStringMatch(op, arg, wildcardMatch)
+	case "anything-but":
+		return compileAnythingBut(arg)
+	case "numeric":
+		return compileNumericMatch(arg)
+	case "cidr":
+		return compileCIDRMatch(arg)
+	default:
+		return nil, fmt.Errorf("unsupported content filter %q", op)
+	}
+}
+
+func literalPatternMatch(want any) func(any) bool {
+	switch typed := want.(type) {
+	case json.Number:
+		wantNum, err := typed.Float64()
+		return func(value any) bool {
+			got, ok := patternNumber(value)
+			return ok && err == nil && got == wantNum
+		}
+	default:
+		return func(value any) bool {
+			if _, isNumber := value.(json.Number); isNumber {
+				return false
+			}
+			return value == want
+		}
+	}
+}
+
+// compileAffixMatch accepts {"prefix": "x"} and {"prefix": {"equals-ignore-case": "x"}}.
+func compileAffixMatch(op string, arg any, affix func(string, string) bool) (func(any) bool, error) {
+	if nested, ok := arg.(map[string]any); ok {
+		inner, ok := nested["equals-ignore-case"].(string)
+		if len(nested) != 1 || !ok {
+			return nil, fmt.Errorf("%s must be a string or {\"equals-ignore-case\": string}", op)
+		}
+		inner = strings.ToLower(inner)
+		return func(value any) bool {
+			got, ok := value.(string)
+			return ok && affix(strings.ToLower(got), inner)
+		}, nil
+	}
+	return compileStringMatch(op, arg, affix)
+}
+
+func compileStringMatch(op string, arg any, cmp func(string, string) bool) (func(any) bool, error) {
+	want, ok := arg.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string", op)
+	}
+	return func(value any) bool {
+		got, ok := value.(string)
+		return ok && cmp(got, want)
+	}, nil
+}
+
+// compileAnythingBut negates a literal, a list of literals, or one of the
+// prefix, suffix, equals-ignore-case, and wildcard filters. Like EventBridge,
+// it only matches values of the same kind (string or number) it excludes.
+func compileAnythingBut(arg any) (func(any) bool, error) {
+	switch typed := arg.(type) {
+	case map[string]any:
+		if len(typed) != 1 {
+			return nil, errors.New("anything-but filter must have exactly one operator")
+		}
+		for op, inner := range typed {
+			match, err := compileAnythingButOperator(op, inner)
+			if err != nil {
+				return nil, err
+			}
+			return func(value any) bool {
+				_, isString := value.(string)
+				return isString && !match(value)
+			}, nil
+		}
+	case []any:
+		return anythingButAny(typed)
+	}
+	return anythingButAny([]any{arg})
+}
+
+func compileAnythingButOperator(op string, inner any) (func(any) bool, error) {
+	if op != "prefix" && op != "suffix" && op != "equals-ignore-case" && op != "wildcard" {
+		return nil, fmt.Errorf("unsupported anything-but filter %q", op)
+	}
+	items, ok := inner.([]any)
+	if !ok {
+		items = []any{inner}
+	}
+	matches := make([]func(any) bool, 0, len(items))
+	for _, item := range items {
+		match, err := compilePatternOperator(op, item)
+		if err != nil {
+			return nil, fmt.Errorf("anything-but: %w", err)
+		}
+		matches = append(matches, match)
+	}
+	return func(value any) bool {
+		for _, match := range matches {
+			if match(value) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func anythingButAny(values []any) (func(any) bool, error) {
+	if len(values) == 0 {
+		return nil, errors.New("anything-but list must not be empty")
+	}
+	matches := make([]func(any) bool, 0, len(values))
+	numeric := false
+	for _, value := range values {
+		switch value.(type) {
+		case string:
+		case json.Number:
+			numeric = true
+		default:
+			return nil, errors.New("anything-but values must be strings or numbers")
+		}
+		matches = append(matches, literalPatternMatch(value))
+	}
+	return func(value any) bool {
+		if _, isNumber := value.(json.Number); isNumber != numeric {
+			return false
+		}
+		for _, match := range matches {
+			if match(value) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// compileNumericMatch accepts one or two comparisons, e.g. [">", 0, "<=", 5].
+func compileNumericMatch(arg any) (func(any) bool, error) {
+	items, ok := arg.([]any)
+	if !ok || (len(items) != 2 && len(items) != 4) {
+		return nil, errors.New("numeric must be [op, number] or [op, number, op, number]")
+	}
+	checks := make([]func(float64) bool, 0, len(items)/2)
+	for i := 0; i < len(items); i += 2 {
+		op, isString := items[i].(string)
+		bound, isNumber := patternNumber(items[i+1])
+		if !isString || !isNumber {
+			return nil, errors.New("numeric must alternate operators and numbers")
+		}
+		check, err := numericComparison(op, bound)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	return func(value any) bool {
+		got, ok := patternNumber(value)
+		if !ok {
+			return false
+		}
+		for _, check := range checks {
+			if !check(got) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func numericComparison(op string, bound float64) (func(float64) bool, error) {
+	switch op {
+	case "=":
+		return func(v float64) bool { return v == bound }, nil
+	case "<":
+		return func(v float64) bool { return v < bound }, nil
+	case "<=":
+		return func(v float64) bool { return v <= bound }, nil
+	case ">":
+		return func(v float64) bool { return v > bound }, nil
+	case ">=":
+		return func(v float64) bool { return v >= bound }, nil
+	default:
+		return nil, fmt.Errorf("unsupported numeric operator %q", op)
+	}
+}
+
+func compileCIDRMatch(arg any) (func(any) bool, error) {
+	raw, ok := arg.(string)
+	if !ok {
+		return nil, errors.New("cidr must be a string")
+	}
+	prefix, err := netip.ParsePrefix(raw)
+	if err != nil {
+		return nil, fmt.Errorf("cidr: %w", err)
+	}
+	return func(value any) bool {
+		got, ok := value.(string)
+		if !ok {
+			return false
+		}
+		addr, err := netip.ParseAddr(got)
+		return err == nil && prefix.Contains(addr)
+	}, nil
+}
+
+func patternNumber(value any) (float64, bool) {
+	num, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	parsed, err := strconv.ParseFloat(num.String(), 64)
+	return parsed, err == nil
+}
+
+// wildcardMatch matches value against pattern, where "*" matches any run of
+// characters and "\*" a literal asterisk.
+func wildcardMatch(value, pattern string) bool {
+	parts := splitWildcard(pattern)
+	if len(parts) == 1 {
+		return value == parts[0]
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(value, part)
+		if idx < 0 {
+			return false
+		}
+		value = value[idx+len(part):]
+	}
+	return strings.HasSuffix(value, last)
+}
+
+func splitWildcard(pattern string) []string {
+	var (
+		parts   []string
+		current strings.Builder
+	)
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern) && pattern[i+1] == '*':
+			current.WriteByte('*')
+			i++
+		case pattern[i] == '*':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(pattern[i])
+		}
+	}
+	return append(parts, current.String())
+}
+
+func joinPatternPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func (o *patternObject) matches(doc map[string]any) bool {
+	for _, field := range o.fields {
+		value, present := doc[field.key]
+		if !field.matches(value, present) {
+			return false
+		}
+	}
+	if len(o.or) == 0 {
+		return true
+	}
+	for _, alt := range o.or {
+		if alt.matches(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f patternField) matches(value any, present bool) bool {
+	if f.object != nil {
+		nested, ok := value.(map[string]any)
+		if !ok {
+			nested = map[string]any{}
+		}
+		return f.object.matches(nested)
+	}
+
+	// Like EventBridge, exists and the other filters only apply to leaf
+	// values, and an array matches when any of its elements does.
+	_, isObject := value.(map[string]any)
+	present = present && !isObject
+	values := []any{value}
+	if list, ok := value.([]any); ok {
+		values = list
+	}
+	for _, matcher := range f.matchers {
+		if matcher.exists != nil {
+			if *matcher.exists == present {
+				return true
+			}
+			continue
+		}
+		if present && anyPatternValue(values, matcher.match) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyPatternValue(values []any, match func(any) bool) bool {
+	for _, value := range values {
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package apptheory
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func patternTestEvent(detail string) events.EventBridgeEvent {
+	return events.EventBridgeEvent{
+		ID:         "evt-1",
+		Source:     "com.example.orders",
+		DetailType: "Order Placed",
+		AccountID:  "123456789012",
+		Region:     "us-east-1",
+		Resources:  []string{"arn:aws:events:us-east-1:123456789012:rule/orders"},
+		Detail:     json.RawMessage(detail),
+	}
+}
+
+func TestEventPattern_ContentFilters(t *testing.T) {
+	t.Parallel()
+
+	event := patternTestEvent(`{
+		"state": "shipped",
+		"total": 42.5,
+		"count": 3,
+		"express": true,
+		"note": null,
+		"sku": ["ab-1", "cd-2"],
+		"ip": "10.0.3.7",
+		"customer": {"tier": "Gold", "email": "a@example.com"}
+	}`)
+
+	cases := []struct {
+		pattern string
+		want    bool
+	}{
+		{`{"source": ["com.example.orders"]}`, true},
+		{`{"source": ["other"]}`, false},
+		{`{"detail-type": [{"prefix": "Order "}]}`, true},
+		{`{"detail-type": [{"suffix": "Placed"}]}`, true},
+		{`{"detail-type": [{"prefix": {"equals-ignore-case": "order"}}]}`, true},
+		{`{"detail": {"customer": {"tier": [{"equals-ignore-case": "gold"}]}}}`, true},
+		{`{"detail": {"customer": {"email": [{"wildcard": "*@example.com"}]}}}`, true},
+		{`{"detail": {"customer": {"email": [{"wildcard": "*@example.org"}]}}}`, false},
+		{`{"detail": {"state": [{"anything-but": ["pending", "cancelled"]}]}}`, true},
+		{`{"detail": {"state": [{"anything-but": "shipped"}]}}`, false},
+		{`{"detail": {"state": [{"anything-but": {"prefix": "ship"}}]}}`, false},
+		{`{"detail": {"count": [{"anything-but": 4}]}}`, true},
+		{`{"detail": {"total": [{"numeric": [">", 40, "<=", 42.5]}]}}`, true},
+		{`{"detail": {"total": [{"numeric": ["<", 40]}]}}`, false},
+		{`{"detail": {"count": [3]}}`, true},
+		{`{"detail": {"count": ["3"]}}`, false},
+		{`{"detail": {"express": [true]}}`, true},
+		{`{"detail": {"note": [null]}}`, true},
+		{`{"detail": {"sku": ["cd-2"]}}`, true},
+		{`{"detail": {"ip": [{"cidr": "10.0.0.0/16"}]}}`, true},
+		{`{"detail": {"ip": [{"cidr": "10.1.0.0/16"}]}}`, false},
+		{`{"detail": {"missing": [{"exists": false}]}}`, true},
+		{`{"detail": {"state": [{"exists": true}]}}`, true},
+		{`{"detail": {"customer": [{"exists": true}]}}`, false},
+		{`{"detail": {"missing": ["x"]}}`, false},
+		{`{"detail": {"missing": [{"anything-but": "x"}]}}`, false},
+		{`{"detail": {"$or": [{"state": ["pending"]}, {"count": [{"numeric": [">=", 3]}]}]}}`, true},
+		{`{"detail": {"$or": [{"state": ["pending"]}, {"count": [{"numeric": [">", 3]}]}]}}`, false},
+		{`{"source": ["com.example.orders"], "detail": {"state": ["pending"]}}`, false},
+	}
+	for _, tc := range cases {
+		pattern, err := ParseEventPattern(tc.pattern)
+		if err != nil {
+			t.Fatalf("parse %s: %v", tc.pattern, err)
+		}
+		if got := pattern.Matches(event); got != tc.want {
+			t.Fatalf("pattern %s: expected %v, got %v", tc.pattern, tc.want, got)
+		}
+	}
+}
+
+func TestEventPattern_RejectsInvalidPatterns(t *testing.T) {
+	t.Parallel()
+
+	for _, pattern := range []string{
+		``,
+		`{}`,
+		`[]`,
+		`{"source": "x"}`,
+		`{"source": []}`,
+		`{"source": [["x"]]}`,
+		`{"source": [{"prefix": 1}]}`,
+		`{"source": [{"prefix": "a", "suffix": "b"}]}`,
+		`{"source": [{"regex": "a"}]}`,
+		`{"detail": {"n": [{"numeric": [">", "x"]}]}}`,
+		`{"detail": {"n": [{"numeric": ["~", 1]}]}}`,
+		`{"detail": {"n": [{"exists": "yes"}]}}`,
+		`{"detail": {"ip": [{"cidr": "nope"}]}}`,
+		`{"$or": [{"source": ["x"]}]}`,
+		`{"detail": {}}`,
+	} {
+		if _, err := ParseEventPattern(pattern); err == nil {
+			t.Fatalf("expected %q to be rejected", pattern)
+		}
+	}
+}
+
+func TestEventPattern_StringIsCompactJSON(t *testing.T) {
+	t.Parallel()
+
+	pattern := MustParseEventPattern(`{
+		"source": ["com.example.orders"],
+		"detail": {"state": [{"prefix": "ship"}]}
+	}`)
+	want := `{"source":["com.example.orders"],"detail":{"state":[{"prefix":"ship"}]}}`
+	if pattern.String() != want {
+		t.Fatalf("unexpected pattern JSON: %s", pattern.String())
+	}
+	raw, err := json.Marshal(map[string]any{"EventPattern": pattern})
+	if err != nil || string(raw) != `{"EventPattern":`+want+`}` {
+		t.Fatalf("unexpected marshaled pattern: %s (%v)", raw, err)
+	}
+}
+
+func TestEventBridgeMatch_RoutesByPatternInRegistrationOrder(t *testing.T) {
+	t.Parallel()
+
+	app := New()
+	app.EventBridge(EventBridgeMatch(MustParseEventPattern(`{"detail": {"total": [{"numeric": [">=", 100]}]}}`)),
+		func(*EventContext, events.EventBridgeEvent) (any, error) { return "large", nil })
+	app.EventBridge(EventBridgeMatch(MustParseEventPattern(`{"source": [{"prefix": "com.example."}]}`)),
+		func(*EventContext, events.EventBridgeEvent) (any, error) { return "example", nil })
+	app.EventBridge(EventBridgeSelector{Source: "com.example.orders", Pattern: MustParseEventPattern(`{"detail": {"total": [0]}}`)},
+		func(*EventContext, events.EventBridgeEvent) (any, error) { return "unreachable", nil })
+	app.EventBridge(EventBridgeMatch(nil), func(*EventContext, events.EventBridgeEvent) (any, error) { return "nil", nil })
+
+	for detail, want := range map[string]any{
+		`{"total": 150}`: "large",
+		`{"total": 5}`:   "example",
+		`{"total": 0}`:   "example",
+	} {
+		out, err := app.ServeEventBridge(context.Background(), patternTestEvent(detail))
+		if err != nil || out != want {
+			t.Fatalf("detail %s: expected %v, got %v (%v)", detail, want, out, err)
+		}
+	}
+
+	other := patternTestEvent(`{"total": 150}`)
+	other.Source = "aws.s3"
+	other.Detail = json.RawMessage(`{"total": "150"}`)
+	out, err := app.ServeEventBridge(context.Background(), other)
+	if err != nil || out != nil {
+		t.Fatalf("expected no match, got %v (%v)", out, err)
+	}
+}
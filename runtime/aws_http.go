@@ -14,7 +14,11 @@ func (a *App) ServeAPIGatewayV2(ctx context.Context, event events.APIGatewayV2HT
 	if err != nil {
 		return apigatewayV2ResponseFromResponse(a.responseForHTTPError(err))
 	}
-	return apigatewayV2ResponseFromResponse(a.Serve(ctx, req))
+	var authContext map[string]any
+	if event.RequestContext.Authorizer != nil {
+		authContext = event.RequestContext.Authorizer.Lambda
+	}
+	return apigatewayV2ResponseFromResponse(a.serveWithOptions(ctx, req, withAuthorizerContext(authContext)))
 }
 
 func (a *App) ServeLambdaFunctionURL(ctx context.Context, event events.LambdaFunctionURLRequest) events.LambdaFunctionURLResponse {
@@ -0,0 +1,292 @@
+package apptheory
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	kafkaEventSourceMSK         = "aws:kafka"
+	kafkaEventSourceSelfManaged = "SelfManagedKafka"
+
+	kafkaSchemaRegistryMagicByte = 0
+	kafkaSchemaRegistryHeaderLen = 5
+)
+
+// KafkaHeader is one Kafka record header. Kafka allows repeated keys, so
+// headers keep their original order.
+type KafkaHeader struct {
+	Key   string
+	Value []byte
+}
+
+// KafkaRecord is one decoded record from an Amazon MSK or self-managed Kafka
+// event. Key, Value, and header values are the raw record bytes. Decoded holds
+// the result of the route's KafkaValueDecoder, if any.
+type KafkaRecord struct {
+	Topic         string
+	Partition     int64
+	Offset        int64
+	Timestamp     time.Time
+	TimestampType string
+	Key           []byte
+	Value         []byte
+	Headers       []KafkaHeader
+	Decoded       any
+}
+
+// Header returns the value of the first header with key.
+func (r KafkaRecord) Header(key string) ([]byte, bool) {
+	for _, header := range r.Headers {
+		if header.Key == key {
+			return header.Value, true
+		}
+	}
+	return nil, false
+}
+
+// ItemIdentifier identifies the record in a partial KafkaEventResponse.
+func (r KafkaRecord) ItemIdentifier() KafkaItemIdentifier {
+	return KafkaItemIdentifier{Partition: r.Partition, Offset: r.Offset}
+}
+
+// KafkaItemIdentifier is the itemIdentifier Lambda expects for a failed Kafka
+// record: its partition and offset. A Kafka event source mapping reads one
+// topic, so the topic is implied.
+type KafkaItemIdentifier struct {
+	Partition int64 `json:"partition"`
+	Offset    int64 `json:"offset"`
+}
+
+// KafkaBatchItemFailure reports one failed Kafka record.
+type KafkaBatchItemFailure struct {
+	ItemIdentifier KafkaItemIdentifier `json:"itemIdentifier"`
+}
+
+// KafkaEventResponse is the partial batch response for a Kafka event. It only
+// lists failures when WithKafkaPartialBatchResponse is set.
+type KafkaEventResponse struct {
+	BatchItemFailures []KafkaBatchItemFailure `json:"batchItemFailures"`
+}
+
+// WithKafkaPartialBatchResponse makes ServeKafka report failed records in
+// KafkaEventResponse.BatchItemFailures instead of failing the invocation. Only
+// set it when the event source mapping has ReportBatchItemFailures enabled;
+// otherwise Lambda ignores the response and the failed records are lost.
+func WithKafkaPartialBatchResponse() Option {
+	return func(app *App) {
+		app.kafkaPartialBatch = true
+	}
+}
+
+type KafkaHandler func(*EventContext, KafkaRecord) error
+
+// KafkaValueDecoder decodes and validates a record value before the handler
+// runs. A decoder error fails the record without calling the handler.
+type KafkaValueDecoder func(ctx context.Context, topic string, value []byte) (any, error)
+
+// KafkaOption configures an App.Kafka registration.
+type KafkaOption func(*kafkaRoute)
+
+// KafkaDecoder sets the value decoder for a Kafka route.
+func KafkaDecoder(decoder KafkaValueDecoder) KafkaOption {
+	return func(r *kafkaRoute) { r.Decoder = decoder }
+}
+
+// KafkaJSONDecoder decodes record values as JSON, keeping numbers as
+// json.Number. Values that are not a single JSON document are rejected.
+func KafkaJSONDecoder() KafkaValueDecoder {
+	return func(_ context.Context, _ string, value []byte) (any, error) {
+		decoder := json.NewDecoder(bytes.NewReader(value))
+		decoder.UseNumber()
+		var out any
+		if err := decoder.Decode(&out); err != nil {
+			return nil, fmt.Errorf("apptheory: invalid kafka json value: %w", err)
+		}
+		if decoder.More() {
+			return nil, errors.New("apptheory: invalid kafka json value: trailing data")
+		}
+		return out, nil
+	}
+}
+
+// KafkaSchemaRegistryDecoder adapts a schema-aware decoder, such as an Avro or
+// JSON Schema decoder backed by a schema registry, to record values in the
+// schema registry wire format: a zero magic byte, a big-endian 4-byte schema
+// ID, then the encoded payload.
+func KafkaSchemaRegistryDecoder(decode func(ctx context.Context, schemaID uint32, payload []byte) (any, error)) KafkaValueDecoder {
+	return func(ctx context.Context, _ string, value []byte) (any, error) {
+		if decode == nil {
+			return nil, errors.New("apptheory: kafka schema decoder is nil")
+		}
+		if len(value) < kafkaSchemaRegistryHeaderLen || value[0] != kafkaSchemaRegistryMagicByte {
+			return nil, errors.New("apptheory: kafka value is not in schema registry wire format")
+		}
+		schemaID := binary.BigEndian.Uint32(value[1:kafkaSchemaRegistryHeaderLen])
+		return decode(ctx, schemaID, value[kafkaSchemaRegistryHeaderLen:])
+	}
+}
+
+type kafkaRoute struct {
+	Topic   string
+	Handler KafkaHandler
+	Decoder KafkaValueDecoder
+}
+
+// Kafka registers a handler for records from a Kafka topic, delivered by an
+// Amazon MSK or self-managed Kafka event source mapping.
+func (a *App) Kafka(topic string, handler KafkaHandler, opts ...KafkaOption) *App {
+	if a == nil {
+		return a
+	}
+	topic = strings.TrimSpace(topic)
+	if topic == "" || handler == nil {
+		return a
+	}
+	route := kafkaRoute{Topic: topic, Handler: handler}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&route)
+		}
+	}
+	a.kafkaRoutes = append(a.kafkaRoutes, route)
+	return a
+}
+
+func (a *App) kafkaRouteForTopic(topic string) (kafkaRoute, bool) {
+	if a == nil {
+		return kafkaRoute{}, false
+	}
+	for _, route := range a.kafkaRoutes {
+		if route.Topic == topic {
+			return route, true
+		}
+	}
+	return kafkaRoute{}, false
+}
+
+// KafkaRecordFromEvent decodes the base64 key, value, and headers of a Kafka
+// event record.
+func KafkaRecordFromEvent(record events.KafkaRecord) (KafkaRecord, error) {
+	key, err := base64.StdEncoding.DecodeString(record.Key)
+	if err != nil {
+		return KafkaRecord{}, fmt.Errorf("apptheory: invalid kafka record key: %w", err)
+	}
+	value, err := base64.StdEncoding.DecodeString(record.Value)
+	if err != nil {
+		return KafkaRecord{}, fmt.Errorf("apptheory: invalid kafka record value: %w", err)
+	}
+
+	var headers []KafkaHeader
+	for _, entry := range record.Headers {
+		keys := make([]string, 0, len(entry))
+		for headerKey := range entry {
+			keys = append(keys, headerKey)
+		}
+		sort.Strings(keys)
+		for _, headerKey := range keys {
+			headers = append(headers, KafkaHeader{Key: headerKey, Value: append([]byte(nil), entry[headerKey]...)})
+		}
+	}
+
+	return KafkaRecord{
+		Topic:         record.Topic,
+		Partition:     record.Partition,
+		Offset:        record.Offset,
+		Timestamp:     record.Timestamp.Time,
+		TimestampType: record.TimestampType,
+		Key:           key,
+		Value:         value,
+		Headers:       headers,
+	}, nil
+}
+
+func (a *App) wrapKafkaHandler(handler KafkaHandler) KafkaHandler {
+	return wrapEventRecordHandler(
+		a,
+		handler,
+		func(event any) (KafkaRecord, bool) {
+			record, ok := event.(KafkaRecord)
+			return record, ok
+		},
+		"apptheory: invalid kafka record type",
+	)
+}
+
+func (a *App) serveKafkaRecord(evtCtx *EventContext, raw events.KafkaRecord) error {
+	route, ok := a.kafkaRouteForTopic(raw.Topic)
+	if !ok {
+		return errors.New("apptheory: unrecognized kafka topic")
+	}
+	record, err := KafkaRecordFromEvent(raw)
+	if err != nil {
+		return err
+	}
+	if route.Decoder != nil {
+		decoded, err := route.Decoder(evtCtx.Context(), record.Topic, record.Value)
+		if err != nil {
+			return err
+		}
+		record.Decoded = decoded
+	}
+	return a.wrapKafkaHandler(route.Handler)(evtCtx.cloneForRecord(), record)
+}
+
+// ServeKafka routes every record of a Kafka event to the handler registered
+// for its topic.
+//
+// Partitions are processed in key order and records in offset order. By
+// default the first failed record (including unknown topics and undecodable
+// payloads) stops the batch and fails the invocation, so Lambda retries the
+// whole batch; the returned error names the record but not the handler error.
+// With WithKafkaPartialBatchResponse, a failed record and the rest
+// of its partition are instead reported in the response without being handled,
+// since records of one partition are ordered; other partitions continue.
+func (a *App) ServeKafka(ctx context.Context, event events.KafkaEvent) (KafkaEventResponse, error) {
+	failures := []KafkaBatchItemFailure{}
+	var evtCtx *EventContext
+	partial := false
+	if a != nil {
+		evtCtx = a.eventContext(ctx)
+		partial = a.kafkaPartialBatch
+	}
+
+	partitions := make([]string, 0, len(event.Records))
+	for partition := range event.Records {
+		partitions = append(partitions, partition)
+	}
+	sort.Strings(partitions)
+
+	for _, partition := range partitions {
+		records := append([]events.KafkaRecord(nil), event.Records[partition]...)
+		sort.SliceStable(records, func(i, j int) bool { return records[i].Offset < records[j].Offset })
+		failed := false
+		for _, raw := range records {
+			if !failed {
+				err := a.serveKafkaRecord(evtCtx, raw)
+				if err == nil {
+					continue
+				}
+				if !partial {
+					return KafkaEventResponse{BatchItemFailures: []KafkaBatchItemFailure{}},
+						fmt.Errorf("apptheory: kafka record %s-%d@%d failed: %w", raw.Topic, raw.Partition, raw.Offset, sanitizeEventWorkloadError(err))
+				}
+			}
+			failed = true
+			failures = append(failures, KafkaBatchItemFailure{
+				ItemIdentifier: KafkaItemIdentifier{Partition: raw.Partition, Offset: raw.Offset},
+			})
+		}
+	}
+	return KafkaEventResponse{BatchItemFailures: failures}, nil
+}
@@ -0,0 +1,217 @@
+package apptheory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func kafkaTestRecord(topic string, partition, offset int64, value string) events.KafkaRecord {
+	return events.KafkaRecord{
+		Topic:     topic,
+		Partition: partition,
+		Offset:    offset,
+		Key:       base64.StdEncoding.EncodeToString([]byte("k" + value)),
+		Value:     base64.StdEncoding.EncodeToString([]byte(value)),
+	}
+}
+
+func TestServeKafka_PartitionsOrderAndPartialFailures(t *testing.T) {
+	t.Parallel()
+
+	var handled []string
+	app := New(WithKafkaPartialBatchResponse())
+	app.Kafka("orders", func(_ *EventContext, rec KafkaRecord) error {
+		handled = append(handled, fmt.Sprintf("%s-%d@%d=%s", rec.Topic, rec.Partition, rec.Offset, rec.Value))
+		if string(rec.Value) == "bad" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	app.Kafka(" ", func(*EventContext, KafkaRecord) error { return nil })
+	app.Kafka("ignored", nil)
+
+	resp, err := app.ServeKafka(context.Background(), events.KafkaEvent{
+		EventSource: "aws:kafka",
+		Records: map[string][]events.KafkaRecord{
+			"orders-1": {
+				kafkaTestRecord("orders", 1, 12, "after"),
+				kafkaTestRecord("orders", 1, 10, "ok"),
+				kafkaTestRecord("orders", 1, 11, "bad"),
+			},
+			"orders-0":  {kafkaTestRecord("orders", 0, 5, "first")},
+			"unknown-0": {kafkaTestRecord("unknown", 0, 1, "x")},
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("expected partial batch response, got error %v", err)
+	}
+	want := []string{"orders-0@5=first", "orders-1@10=ok", "orders-1@11=bad"}
+	if len(handled) != len(want) {
+		t.Fatalf("expected %v, got %v", want, handled)
+	}
+	for i := range want {
+		if handled[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, handled)
+		}
+	}
+	body, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("marshal response: %v", err)
+	}
+	wantBody := `{"batchItemFailures":[{"itemIdentifier":{"partition":1,"offset":11}},` +
+		`{"itemIdentifier":{"partition":1,"offset":12}},{"itemIdentifier":{"partition":0,"offset":1}}]}`
+	if string(body) != wantBody {
+		t.Fatalf("expected %s, got %s", wantBody, body)
+	}
+}
+
+func TestServeKafka_FailsInvocationByDefault(t *testing.T) {
+	t.Parallel()
+
+	var handled []int64
+	app := New()
+	app.Kafka("orders", func(_ *EventContext, rec KafkaRecord) error {
+		handled = append(handled, rec.Offset)
+		if string(rec.Value) == "bad" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	event := events.KafkaEvent{EventSource: "aws:kafka", Records: map[string][]events.KafkaRecord{
+		"orders-0": {kafkaTestRecord("orders", 0, 1, "ok"), kafkaTestRecord("orders", 0, 2, "bad"), kafkaTestRecord("orders", 0, 3, "after")},
+		"orders-1": {kafkaTestRecord("orders", 1, 1, "later")},
+	}}
+
+	resp, err := app.ServeKafka(context.Background(), event)
+	if err == nil || !strings.Contains(err.Error(), "orders-0@2") || len(resp.BatchItemFailures) != 0 {
+		t.Fatalf("expected invocation error, got %#v (%v)", resp, err)
+	}
+	if len(handled) != 2 || handled[1] != 2 {
+		t.Fatalf("expected the batch to stop at the failed record, handled %v", handled)
+	}
+
+	raw, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	if _, err := app.HandleLambda(context.Background(), raw); err == nil || strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected HandleLambda to fail the invocation without the handler error, got %v", err)
+	}
+
+	var nilApp *App
+	if _, err := nilApp.ServeKafka(context.Background(), events.KafkaEvent{Records: map[string][]events.KafkaRecord{
+		"orders-0": {kafkaTestRecord("orders", 0, 1, "x")},
+	}}); err == nil {
+		t.Fatal("expected nil app to fail closed")
+	}
+}
+
+func TestKafkaRecordFromEvent_DecodesKeyValueAndHeaders(t *testing.T) {
+	t.Parallel()
+
+	raw := kafkaTestRecord("orders", 0, 1, "v")
+	raw.Headers = []map[string]events.JSONNumberBytes{{"trace": []byte("a")}, {"trace": []byte("b")}, {"type": []byte("c")}}
+	rec, err := KafkaRecordFromEvent(raw)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if string(rec.Key) != "kv" || string(rec.Value) != "v" || len(rec.Headers) != 3 {
+		t.Fatalf("unexpected record %#v", rec)
+	}
+	if value, ok := rec.Header("trace"); !ok || string(value) != "a" {
+		t.Fatalf("expected first trace header, got %q", value)
+	}
+	if _, ok := rec.Header("missing"); ok {
+		t.Fatal("expected missing header")
+	}
+
+	raw.Value = "%%%"
+	if _, err := KafkaRecordFromEvent(raw); err == nil {
+		t.Fatal("expected invalid base64 value to fail")
+	}
+	raw.Key = "%%%"
+	if _, err := KafkaRecordFromEvent(raw); err == nil {
+		t.Fatal("expected invalid base64 key to fail")
+	}
+}
+
+func TestServeKafka_Decoders(t *testing.T) {
+	t.Parallel()
+
+	var decoded []any
+	record := func(_ *EventContext, rec KafkaRecord) error {
+		decoded = append(decoded, rec.Decoded)
+		return nil
+	}
+	app := New(WithKafkaPartialBatchResponse())
+	app.Kafka("json", record, KafkaDecoder(KafkaJSONDecoder()))
+	app.Kafka("avro", record, KafkaDecoder(KafkaSchemaRegistryDecoder(func(_ context.Context, schemaID uint32, payload []byte) (any, error) {
+		if schemaID != 7 {
+			return nil, errors.New("unknown schema")
+		}
+		return string(payload), nil
+	})))
+
+	framed := func(schemaID byte, payload string) string {
+		return string(append([]byte{0, 0, 0, 0, schemaID}, payload...))
+	}
+	resp, err := app.ServeKafka(context.Background(), events.KafkaEvent{Records: map[string][]events.KafkaRecord{
+		"avro-0": {kafkaTestRecord("avro", 0, 1, framed(7, "payload")), kafkaTestRecord("avro", 0, 2, framed(8, "x"))},
+		"avro-1": {kafkaTestRecord("avro", 1, 1, "no-frame")},
+		"json-0": {kafkaTestRecord("json", 0, 1, `{"n": 1}`), kafkaTestRecord("json", 0, 2, `{"n": 1} {}`)},
+	}})
+
+	if len(decoded) != 2 || decoded[0] != "payload" {
+		t.Fatalf("unexpected decoded values %#v", decoded)
+	}
+	if doc, ok := decoded[1].(map[string]any); !ok || doc["n"] != json.Number("1") {
+		t.Fatalf("unexpected json value %#v", decoded[1])
+	}
+	if err != nil || len(resp.BatchItemFailures) != 3 {
+		t.Fatalf("expected decoder failures, got %#v (%v)", resp.BatchItemFailures, err)
+	}
+}
+
+func TestHandleLambda_DispatchesKafka(t *testing.T) {
+	t.Parallel()
+
+	var seen []any
+	app := New()
+	app.UseEvents(func(next EventHandler) EventHandler {
+		return func(ctx *EventContext, event any) (any, error) {
+			seen = append(seen, event)
+			return next(ctx, event)
+		}
+	})
+	app.Kafka("orders", func(*EventContext, KafkaRecord) error { return nil })
+
+	for _, source := range []string{"aws:kafka", "SelfManagedKafka"} {
+		event := json.RawMessage(`{"eventSource":"` + source + `","bootstrapServers":"b-1:9092","records":{"orders-0":[` +
+			`{"topic":"orders","partition":0,"offset":3,"timestamp":1700000000000,"timestampType":"CREATE_TIME",` +
+			`"value":"e30=","headers":[{"h":[104,105]}]}]}}`)
+		out, err := app.HandleLambda(context.Background(), event)
+		resp, ok := out.(KafkaEventResponse)
+		if err != nil || !ok || len(resp.BatchItemFailures) != 0 {
+			t.Fatalf("%s: unexpected result %#v (%v)", source, out, err)
+		}
+	}
+	if len(seen) != 2 {
+		t.Fatalf("expected event middleware per record, got %d", len(seen))
+	}
+	rec, ok := seen[0].(KafkaRecord)
+	if !ok || string(rec.Value) != "{}" || rec.Timestamp.UnixMilli() != 1700000000000 {
+		t.Fatalf("unexpected record %#v", seen[0])
+	}
+
+	if _, err := app.HandleLambda(context.Background(), json.RawMessage(`{"eventSource":"aws:kafka","records":[]}`)); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
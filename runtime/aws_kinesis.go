@@ -12,6 +12,7 @@ type KinesisHandler func(*EventContext, events.KinesisEventRecord) error
 type kinesisRoute struct {
 	StreamName string
 	Handler    KinesisHandler
+	Window     KinesisWindowHandler
 }
 
 // Kinesis registers a handler for a Kinesis stream by stream name.
@@ -54,7 +55,7 @@ func (a *App) kinesisHandlerForEvent(event events.KinesisEvent) KinesisHandler {
 			continue
 		}
 		for _, route := range a.kinesisRoutes {
-			if route.StreamName == streamName {
+			if route.StreamName == streamName && route.Handler != nil {
 				return route.Handler
 			}
 		}
@@ -70,7 +71,39 @@ var kinesisBatchSpec = newBatchEventSpec[events.KinesisEventRecord, events.Kines
 
 // ServeKinesis routes a Kinesis event to the registered stream handler and returns a partial batch failure response.
 //
+// Records written with KPL aggregation are de-aggregated, and the handler runs once per user record with the
+// user record's data and partition key; EventContext.KinesisUserRecord identifies it. Lambda checkpoints whole
+// Kinesis records, so a failing user record reports the sequence number of the record that carried it and skips
+// the rest of that record's user records. User records that succeeded before it are delivered again on retry, so
+// handlers should be idempotent on (SequenceNumber, SubSequenceNumber).
+//
 // If the stream is unrecognized, it fails closed by returning all records as failures.
 func (a *App) ServeKinesis(ctx context.Context, event events.KinesisEvent) events.KinesisEventResponse {
-	return serveBatchEvent(ctx, a, event.Records, a.kinesisHandlerForEvent(event), kinesisBatchSpec)
+	handler := wrapEventRecordHandler(a, a.kinesisHandlerForEvent(event), kinesisBatchSpec.coerce, kinesisBatchSpec.invalidTypeError)
+	failures := serveBatchItemFailures(
+		ctx, a, event.Records, kinesisUserRecordHandler(handler), kinesisBatchSpec.recordID, kinesisBatchSpec.failureForID,
+	)
+	return kinesisBatchSpec.responseForFailures(failures)
+}
+
+// kinesisUserRecordHandler runs handler for each user record of a Kinesis
+// record, stopping at the first failure.
+func kinesisUserRecordHandler(handler KinesisHandler) KinesisHandler {
+	if handler == nil {
+		return nil
+	}
+	return func(evtCtx *EventContext, record events.KinesisEventRecord) error {
+		users, err := DeaggregateKinesisRecord(record)
+		if err != nil {
+			return err
+		}
+		for i := range users {
+			recordCtx := evtCtx.cloneForRecord()
+			recordCtx.kinesisRecord = &users[i]
+			if err = handler(recordCtx, users[i].KinesisEventRecord); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 }
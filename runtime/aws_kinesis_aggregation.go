@@ -0,0 +1,323 @@
+package apptheory
+
+import (
+	"bytes"
+	"crypto/md5" // #nosec G501 -- the KPL aggregation format checksums records with MD5; it is not used for security.
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// kplAggregationMagic prefixes Kinesis Producer Library aggregated records.
+var kplAggregationMagic = []byte{0xF3, 0x89, 0x9A, 0xC2}
+
+const (
+	kplAggregatedRecordPartitionKeyTable    = 1
+	kplAggregatedRecordExplicitHashKeyTable = 2
+	kplAggregatedRecordRecords              = 3
+
+	kplRecordPartitionKeyIndex    = 1
+	kplRecordExplicitHashKeyIndex = 2
+	kplRecordData                 = 3
+
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+	protoWireFixed32 = 5
+)
+
+// KinesisUserRecord is one user record carried by a Kinesis record.
+//
+// Producers using Kinesis Producer Library (KPL) aggregation pack several user
+// records into one Kinesis record. Each user record keeps the Kinesis metadata
+// of the record that carried it, with its own data and partition key, and is
+// identified by (SequenceNumber, SubSequenceNumber).
+type KinesisUserRecord struct {
+	events.KinesisEventRecord
+	// SubSequenceNumber is the position of the user record in its aggregated
+	// record. It is zero for records that are not aggregated.
+	SubSequenceNumber int
+	// ExplicitHashKey is the explicit hash key the producer set for the user
+	// record, if any.
+	ExplicitHashKey string
+	// Aggregated reports whether the record was de-aggregated from a KPL
+	// aggregated record.
+	Aggregated bool
+}
+
+// DeaggregateKinesisRecord returns the user records carried by a Kinesis
+// record.
+//
+// Records without the KPL magic prefix, or whose MD5 checksum does not match,
+// are returned unchanged as a single user record, as the Kinesis Client
+// Library does. An aggregated record with a valid checksum but a malformed
+// body is an error.
+func DeaggregateKinesisRecord(record events.KinesisEventRecord) ([]KinesisUserRecord, error) {
+	body, ok := kplAggregatedBody(record.Kinesis.Data)
+	if !ok {
+		return []KinesisUserRecord{{KinesisEventRecord: record}}, nil
+	}
+
+	aggregated, err := decodeKPLAggregatedRecord(body)
+	if err != nil {
+		return nil, fmt.Errorf("apptheory: invalid kinesis aggregated record %s: %w", record.Kinesis.SequenceNumber, err)
+	}
+
+	out := make([]KinesisUserRecord, 0, len(aggregated.records))
+	for i, sub := range aggregated.records {
+		user := KinesisUserRecord{
+			KinesisEventRecord: record,
+			SubSequenceNumber:  i,
+			Aggregated:         true,
+		}
+		user.Kinesis.Data = sub.data
+		user.Kinesis.PartitionKey = aggregated.partitionKeys[sub.partitionKeyIndex]
+		if sub.hasExplicitHashKey {
+			user.ExplicitHashKey = aggregated.explicitHashKeys[sub.explicitHashKeyIndex]
+		}
+		out = append(out, user)
+	}
+	return out, nil
+}
+
+// KinesisUserRecord returns the Kinesis user record being handled, when the
+// handler was invoked by ServeKinesis.
+func (c *EventContext) KinesisUserRecord() (KinesisUserRecord, bool) {
+	if c == nil || c.kinesisRecord == nil {
+		return KinesisUserRecord{}, false
+	}
+	return *c.kinesisRecord, true
+}
+
+func kplAggregatedBody(data []byte) ([]byte, bool) {
+	if len(data) < len(kplAggregationMagic)+md5.Size || !bytes.HasPrefix(data, kplAggregationMagic) {
+		return nil, false
+	}
+	body := data[len(kplAggregationMagic) : len(data)-md5.Size]
+	digest := md5.Sum(body) // #nosec G401 -- integrity checksum mandated by the KPL aggregation format.
+	if !bytes.Equal(digest[:], data[len(data)-md5.Size:]) {
+		return nil, false
+	}
+	return body, true
+}
+
+type kplAggregatedRecord struct {
+	partitionKeys    []string
+	explicitHashKeys []string
+	records          []kplRecord
+}
+
+type kplRecord struct {
+	partitionKeyIndex    uint64
+	explicitHashKeyIndex uint64
+	hasPartitionKey      bool
+	hasExplicitHashKey   bool
+	data                 []byte
+}
+
+func decodeKPLAggregatedRecord(body []byte) (kplAggregatedRecord, error) {
+	var aggregated kplAggregatedRecord
+	err := forEachProtoField(body, func(field uint64, wireType byte, _ uint64, payload []byte) error {
+		if wireType != protoWireBytes {
+			return nil
+		}
+		switch field {
+		case kplAggregatedRecordPartitionKeyTable:
+			aggregated.partitionKeys = append(aggregated.partitionKeys, string(payload))
+		case kplAggregatedRecordExplicitHashKeyTable:
+			aggregated.explicitHashKeys = append(aggregated.explicitHashKeys, string(payload))
+		case kplAggregatedRecordRecords:
+			record, err := decodeKPLRecord(payload)
+			if err != nil {
+				return err
+			}
+			aggregated.records = append(aggregated.records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return aggregated, err
+	}
+
+	for i, record := range aggregated.records {
+		if record.partitionKeyIndex >= uint64(len(aggregated.partitionKeys)) {
+			return aggregated, fmt.Errorf("user record %d: partition key index out of range", i)
+		}
+		if record.hasExplicitHashKey && record.explicitHashKeyIndex >= uint64(len(aggregated.explicitHashKeys)) {
+			return aggregated, fmt.Errorf("user record %d: explicit hash key index out of range", i)
+		}
+	}
+	return aggregated, nil
+}
+
+func decodeKPLRecord(buf []byte) (kplRecord, error) {
+	record := kplRecord{data: []byte{}}
+	err := forEachProtoField(buf, func(field uint64, wireType byte, value uint64, payload []byte) error {
+		switch {
+		case field == kplRecordPartitionKeyIndex && wireType == protoWireVarint:
+			record.partitionKeyIndex, record.hasPartitionKey = value, true
+		case field == kplRecordExplicitHashKeyIndex && wireType == protoWireVarint:
+			record.explicitHashKeyIndex, record.hasExplicitHashKey = value, true
+		case field == kplRecordData && wireType == protoWireBytes:
+			record.data = append([]byte(nil), payload...)
+		}
+		return nil
+	})
+	if err == nil && !record.hasPartitionKey {
+		err = errors.New("user record missing partition key index")
+	}
+	return record, err
+}
+
+// forEachProtoField walks the fields of a protobuf message, passing varint
+// values and length-delimited payloads to fn. Fixed-width fields are skipped.
+func forEachProtoField(buf []byte, fn func(field uint64, wireType byte, value uint64, payload []byte) error) error {
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return errors.New("malformed protobuf field key")
+		}
+		buf = buf[n:]
+		field, wireType := key>>3, byte(key&0x7)
+
+		var value uint64
+		var payload []byte
+		switch wireType {
+		case protoWireVarint:
+			if value, n = binary.Uvarint(buf); n <= 0 {
+				return errors.New("malformed protobuf varint")
+			}
+			buf = buf[n:]
+		case protoWireBytes:
+			length, m := binary.Uvarint(buf)
+			if m <= 0 || length > uint64(len(buf)-m) {
+				return errors.New("malformed protobuf length")
+			}
+			end := m + int(length) // #nosec G115 -- length is bounded by len(buf)
+			payload, buf = buf[m:end], buf[end:]
+		case protoWireFixed64, protoWireFixed32:
+			size := 8
+			if wireType == protoWireFixed32 {
+				size = 4
+			}
+			if len(buf) < size {
+				return errors.New("truncated protobuf fixed-width field")
+			}
+			buf = buf[size:]
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", wireType)
+		}
+
+		if err := fn(field, wireType, value, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AggregateKinesisRecords packs records into one KPL aggregated record, the
+// inverse of DeaggregateKinesisRecord. The aggregated record is routed by the
+// partition key and explicit hash key of the first record.
+func AggregateKinesisRecords(records []KinesisJSONRecord) (KinesisJSONRecord, error) {
+	if len(records) == 0 {
+		return KinesisJSONRecord{}, errors.New(kinesisJSONRecordInvalidMessage + ": no records to aggregate")
+	}
+	var aggregator kplAggregator
+	for i, record := range records {
+		normalized, err := normalizeKinesisProducerRecord(record)
+		if err != nil {
+			return KinesisJSONRecord{}, fmt.Errorf("%s at index %d", err.Error(), i)
+		}
+		aggregator.add(normalized)
+	}
+	if aggregator.encodedSize() > kinesisMaxRecordDataBytes {
+		return KinesisJSONRecord{}, fmt.Errorf(
+			"%s: aggregated record size %d exceeds %d",
+			kinesisJSONRecordInvalidMessage,
+			aggregator.encodedSize(),
+			kinesisMaxRecordDataBytes,
+		)
+	}
+	return aggregator.record(), nil
+}
+
+// kplAggregator incrementally encodes a KPL aggregated record.
+type kplAggregator struct {
+	partitionKeys    []string
+	explicitHashKeys []string
+	tables           []byte
+	body             []byte
+	records          []KinesisJSONRecord
+}
+
+// encodedSize is the size of the aggregated record data.
+func (g *kplAggregator) encodedSize() int {
+	return len(kplAggregationMagic) + len(g.tables) + len(g.body) + md5.Size
+}
+
+// sizeWith is the encodedSize after adding record.
+func (g *kplAggregator) sizeWith(record KinesisJSONRecord) int {
+	size := g.encodedSize()
+	if !slices.Contains(g.partitionKeys, record.PartitionKey) {
+		size += protoBytesFieldSize(len(record.PartitionKey))
+	}
+	if record.ExplicitHashKey != "" && !slices.Contains(g.explicitHashKeys, record.ExplicitHashKey) {
+		size += protoBytesFieldSize(len(record.ExplicitHashKey))
+	}
+	// Bound the two key index fields by a key byte and a maximal varint each.
+	inner := protoBytesFieldSize(len(record.Data)) + 2*(1+binary.MaxVarintLen64)
+	return size + protoBytesFieldSize(inner)
+}
+
+func (g *kplAggregator) add(record KinesisJSONRecord) {
+	pkIndex := g.keyIndex(&g.partitionKeys, kplAggregatedRecordPartitionKeyTable, record.PartitionKey)
+	inner := appendProtoVarintField(nil, kplRecordPartitionKeyIndex, pkIndex)
+	if record.ExplicitHashKey != "" {
+		ehkIndex := g.keyIndex(&g.explicitHashKeys, kplAggregatedRecordExplicitHashKeyTable, record.ExplicitHashKey)
+		inner = appendProtoVarintField(inner, kplRecordExplicitHashKeyIndex, ehkIndex)
+	}
+	inner = appendProtoBytesField(inner, kplRecordData, record.Data)
+	g.body = appendProtoBytesField(g.body, kplAggregatedRecordRecords, inner)
+	g.records = append(g.records, record)
+}
+
+func (g *kplAggregator) keyIndex(table *[]string, field uint64, key string) uint64 {
+	index := slices.Index(*table, key)
+	if index < 0 {
+		index = len(*table)
+		*table = append(*table, key)
+		g.tables = appendProtoBytesField(g.tables, field, []byte(key))
+	}
+	return uint64(index)
+}
+
+func (g *kplAggregator) record() KinesisJSONRecord {
+	body := append(append([]byte(nil), g.tables...), g.body...)
+	digest := md5.Sum(body) // #nosec G401 -- integrity checksum mandated by the KPL aggregation format.
+	data := append(append(append([]byte(nil), kplAggregationMagic...), body...), digest[:]...)
+	record := KinesisJSONRecord{
+		PartitionKey:    g.records[0].PartitionKey,
+		ExplicitHashKey: g.records[0].ExplicitHashKey,
+		Data:            data,
+	}
+	record.SafeSummary = kinesisJSONRecordSafeSummary(record)
+	return record
+}
+
+func protoBytesFieldSize(length int) int {
+	return 1 + len(binary.AppendUvarint(nil, uint64(length))) + length
+}
+
+func appendProtoVarintField(buf []byte, field, value uint64) []byte {
+	buf = binary.AppendUvarint(buf, field<<3|protoWireVarint)
+	return binary.AppendUvarint(buf, value)
+}
+
+func appendProtoBytesField(buf []byte, field uint64, payload []byte) []byte {
+	buf = binary.AppendUvarint(buf, field<<3|protoWireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(payload)))
+	return append(buf, payload...)
+}
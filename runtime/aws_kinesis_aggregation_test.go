@@ -0,0 +1,186 @@
+package apptheory
+
+import (
+	"context"
+	"crypto/md5" // #nosec G501 -- KPL test fixtures.
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func kplTestField(buf []byte, field uint64, payload []byte) []byte {
+	buf = binary.AppendUvarint(buf, field<<3|protoWireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(payload)))
+	return append(buf, payload...)
+}
+
+func kplTestRecord(partitionKeyIndex uint64, data string) []byte {
+	record := binary.AppendUvarint(nil, kplRecordPartitionKeyIndex<<3)
+	record = binary.AppendUvarint(record, partitionKeyIndex)
+	return kplTestField(record, kplRecordData, []byte(data))
+}
+
+func kplTestAggregate(body []byte) []byte {
+	digest := md5.Sum(body) // #nosec G401 -- KPL test fixtures.
+	out := append(append([]byte(nil), kplAggregationMagic...), body...)
+	return append(out, digest[:]...)
+}
+
+func TestDeaggregateKinesisRecord(t *testing.T) {
+	t.Parallel()
+
+	body := kplTestField(nil, kplAggregatedRecordPartitionKeyTable, []byte("pk-a"))
+	body = kplTestField(body, kplAggregatedRecordPartitionKeyTable, []byte("pk-b"))
+	body = kplTestField(body, kplAggregatedRecordExplicitHashKeyTable, []byte("42"))
+	hashed := binary.AppendUvarint(kplTestRecord(1, "two"), kplRecordExplicitHashKeyIndex<<3)
+	hashed = binary.AppendUvarint(hashed, 0)
+	body = kplTestField(body, kplAggregatedRecordRecords, kplTestRecord(0, "one"))
+	body = kplTestField(body, kplAggregatedRecordRecords, hashed)
+
+	record := events.KinesisEventRecord{Kinesis: events.KinesisRecord{SequenceNumber: "7", PartitionKey: "outer", Data: kplTestAggregate(body)}}
+	users, err := DeaggregateKinesisRecord(record)
+	if err != nil || len(users) != 2 {
+		t.Fatalf("unexpected user records %#v (%v)", users, err)
+	}
+	if string(users[0].Kinesis.Data) != "one" || users[0].Kinesis.PartitionKey != "pk-a" || users[0].ExplicitHashKey != "" || !users[0].Aggregated {
+		t.Fatalf("unexpected first user record %#v", users[0])
+	}
+	if string(users[1].Kinesis.Data) != "two" || users[1].Kinesis.PartitionKey != "pk-b" || users[1].ExplicitHashKey != "42" ||
+		users[1].SubSequenceNumber != 1 || users[1].Kinesis.SequenceNumber != "7" {
+		t.Fatalf("unexpected second user record %#v", users[1])
+	}
+
+	tampered := kplTestAggregate(body)
+	tampered[len(tampered)-1] ^= 0xFF
+	for _, data := range [][]byte{[]byte("plain"), tampered, kplAggregationMagic} {
+		users, err = DeaggregateKinesisRecord(events.KinesisEventRecord{Kinesis: events.KinesisRecord{Data: data}})
+		if err != nil || len(users) != 1 || users[0].Aggregated || string(users[0].Kinesis.Data) != string(data) {
+			t.Fatalf("expected %q to pass through, got %#v (%v)", data, users, err)
+		}
+	}
+
+	for name, bad := range map[string][]byte{
+		"index out of range": kplTestField(nil, kplAggregatedRecordRecords, kplTestRecord(3, "x")),
+		"missing key index":  kplTestField(nil, kplAggregatedRecordRecords, kplTestField(nil, kplRecordData, []byte("x"))),
+		"truncated":          {kplAggregatedRecordRecords<<3 | protoWireBytes, 9, 1},
+		"bad wire type":      {0x0B},
+	} {
+		if _, err := DeaggregateKinesisRecord(events.KinesisEventRecord{Kinesis: events.KinesisRecord{Data: kplTestAggregate(bad)}}); err == nil {
+			t.Fatalf("%s: expected de-aggregation to fail", name)
+		}
+	}
+}
+
+func TestServeKinesis_DeaggregatesAndFailsAggregateRecords(t *testing.T) {
+	t.Parallel()
+
+	arn := "arn:aws:kinesis:us-east-1:1:stream/orders"
+	body := kplTestField(nil, kplAggregatedRecordPartitionKeyTable, []byte("pk"))
+	for _, data := range []string{"a", "fail", "c"} {
+		body = kplTestField(body, kplAggregatedRecordRecords, kplTestRecord(0, data))
+	}
+	var seen []string
+	app := New()
+	app.Kinesis("orders", func(ctx *EventContext, record events.KinesisEventRecord) error {
+		user, ok := ctx.KinesisUserRecord()
+		if !ok || string(user.Kinesis.Data) != string(record.Kinesis.Data) {
+			return errors.New("missing user record")
+		}
+		seen = append(seen, record.Kinesis.SequenceNumber+"/"+string(record.Kinesis.Data))
+		if string(record.Kinesis.Data) == "fail" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	resp := app.ServeKinesis(context.Background(), events.KinesisEvent{Records: []events.KinesisEventRecord{
+		{EventSourceArn: arn, Kinesis: events.KinesisRecord{SequenceNumber: "1", Data: kplTestAggregate(body)}},
+		{EventSourceArn: arn, Kinesis: events.KinesisRecord{SequenceNumber: "2", Data: []byte("plain")}},
+		{EventSourceArn: arn, Kinesis: events.KinesisRecord{SequenceNumber: "3", Data: kplTestAggregate([]byte{0x0B})}},
+	}})
+	if len(seen) != 3 || seen[0] != "1/a" || seen[1] != "1/fail" || seen[2] != "2/plain" {
+		t.Fatalf("unexpected handled records %v", seen)
+	}
+	if len(resp.BatchItemFailures) != 2 || resp.BatchItemFailures[0].ItemIdentifier != "1" || resp.BatchItemFailures[1].ItemIdentifier != "3" {
+		t.Fatalf("unexpected failures %#v", resp.BatchItemFailures)
+	}
+}
+
+func TestServeKinesisWindow_FoldsStateAndFailsClosed(t *testing.T) {
+	t.Parallel()
+
+	arn := "arn:aws:kinesis:us-east-1:1:stream/clicks"
+	app := New()
+	app.KinesisTumblingWindow("clicks", func(_ *EventContext, window KinesisWindow) (map[string]string, error) {
+		for _, record := range window.Records {
+			if string(record.Kinesis.Data) == "fail" {
+				return nil, errors.New("boom")
+			}
+			window.State["count"] += "+"
+		}
+		if window.IsFinalInvokeForWindow {
+			window.State["final"] = window.End.Sub(window.Start).String()
+		}
+		return window.State, nil
+	})
+	app.Kinesis("plain", func(*EventContext, events.KinesisEventRecord) error { return nil })
+
+	raw := []byte(`{"Records":[{"eventSource":"aws:kinesis","eventSourceARN":"` + arn + `","kinesis":{"sequenceNumber":"1","data":"YQ=="}}],` +
+		`"window":{"start":"2026-01-01T00:00:00Z","end":"2026-01-01T00:01:00Z"},"state":{"count":"+"},"shardId":"s1",` +
+		`"eventSourceARN":"` + arn + `","isFinalInvokeForWindow":true}`)
+	out, err := app.HandleLambda(context.Background(), raw)
+	resp, ok := out.(events.KinesisTimeWindowEventResponse)
+	if err != nil || !ok || resp.State["count"] != "++" || resp.State["final"] != time.Minute.String() || len(resp.BatchItemFailures) != 0 {
+		t.Fatalf("unexpected window response %#v (%v)", out, err)
+	}
+
+	event := events.KinesisTimeWindowEvent{
+		KinesisEvent: events.KinesisEvent{Records: []events.KinesisEventRecord{
+			{EventSourceArn: arn, Kinesis: events.KinesisRecord{SequenceNumber: "1", Data: []byte("fail")}},
+			{EventSourceArn: arn, Kinesis: events.KinesisRecord{SequenceNumber: "2", Data: []byte("b")}},
+		}},
+		TimeWindowProperties: events.TimeWindowProperties{EventSourceARN: arn, State: map[string]string{"count": "+"}},
+	}
+	resp, err = app.ServeKinesisWindow(context.Background(), event)
+	if err == nil || resp.State["count"] != "+" || len(resp.BatchItemFailures) != 2 || event.State["count"] != "+" {
+		t.Fatalf("expected failure to fail the invocation and keep the previous state, got %#v (%v)", resp, err)
+	}
+
+	event.EventSourceARN = "arn:aws:kinesis:us-east-1:1:stream/plain"
+	event.Records = []events.KinesisEventRecord{{EventSourceArn: event.EventSourceARN, Kinesis: events.KinesisRecord{SequenceNumber: "1"}}}
+	if resp, err = app.ServeKinesisWindow(context.Background(), event); err != nil || len(resp.BatchItemFailures) != 0 || resp.State["count"] != "+" {
+		t.Fatalf("expected per-record routes to pass state through, got %#v (%v)", resp, err)
+	}
+
+	event.EventSourceARN = "arn:aws:kinesis:us-east-1:1:stream/unknown"
+	event.Records[0].EventSourceArn = event.EventSourceARN
+	if resp, err = app.ServeKinesisWindow(context.Background(), event); err == nil || len(resp.BatchItemFailures) != 1 {
+		t.Fatalf("expected unknown stream to fail closed, got %#v (%v)", resp, err)
+	}
+
+	empty, err := json.Marshal(map[string]any{"Records": []any{}, "window": map[string]string{"start": "2026-01-01T00:00:00Z", "end": "2026-01-01T00:01:00Z"},
+		"eventSourceARN": arn, "isFinalInvokeForWindow": true})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	out, err = app.HandleLambda(context.Background(), empty)
+	if resp, ok = out.(events.KinesisTimeWindowEventResponse); err != nil || !ok || resp.State["final"] != time.Minute.String() {
+		t.Fatalf("expected empty final invocation to reach the handler, got %#v (%v)", out, err)
+	}
+
+	// A final invocation has no records to report, so a failing handler must
+	// fail the invocation for Lambda to retry it.
+	failFinal := New()
+	failFinal.KinesisTumblingWindow("clicks", func(*EventContext, KinesisWindow) (map[string]string, error) {
+		return nil, errors.New("emit failed")
+	})
+	out, err = failFinal.HandleLambda(context.Background(), empty)
+	if err == nil || strings.Contains(err.Error(), "emit failed") {
+		t.Fatalf("expected failed final invocation to return an error, got %#v (%v)", out, err)
+	}
+}
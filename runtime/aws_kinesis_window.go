@@ -0,0 +1,174 @@
+package apptheory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// KinesisWindow is one invocation of a Kinesis tumbling window.
+//
+// Lambda invokes the function several times per window and per shard,
+// carrying the state returned by each invocation into the next one.
+type KinesisWindow struct {
+	Start          time.Time
+	End            time.Time
+	ShardID        string
+	EventSourceARN string
+	// State is the state returned by the previous invocation of the window,
+	// empty at the start of a window.
+	State map[string]string
+	// IsFinalInvokeForWindow marks the last invocation of the window, where
+	// handlers emit their aggregates. The next invocation starts a new window
+	// with empty state.
+	IsFinalInvokeForWindow bool
+	// IsWindowTerminatedEarly reports that the window ended before its end
+	// time, for example because the state grew past the Lambda limit.
+	IsWindowTerminatedEarly bool
+	// Records are the de-aggregated user records of the invocation. The final
+	// invocation of a window may carry none.
+	Records []KinesisUserRecord
+}
+
+// KinesisWindowHandler folds one invocation of a tumbling window into the
+// window state and returns the state to carry into the next invocation.
+//
+// Returning an error fails the invocation and keeps the previous state, so
+// Lambda retries the records against the state they have not yet been folded
+// into.
+type KinesisWindowHandler func(*EventContext, KinesisWindow) (map[string]string, error)
+
+// KinesisTumblingWindow registers a tumbling window handler for a Kinesis
+// stream by stream name. It serves event source mappings configured with a
+// tumbling window; see ServeKinesisWindow.
+func (a *App) KinesisTumblingWindow(streamName string, handler KinesisWindowHandler) *App {
+	if a == nil {
+		return a
+	}
+	streamName = strings.TrimSpace(streamName)
+	if streamName == "" || handler == nil {
+		return a
+	}
+	a.kinesisRoutes = append(a.kinesisRoutes, kinesisRoute{StreamName: streamName, Window: handler})
+	return a
+}
+
+func (a *App) kinesisWindowHandlerForEvent(event events.KinesisTimeWindowEvent) KinesisWindowHandler {
+	if a == nil {
+		return nil
+	}
+	arn := event.EventSourceARN
+	if strings.TrimSpace(arn) == "" && len(event.Records) > 0 {
+		arn = event.Records[0].EventSourceArn
+	}
+	streamName := kinesisStreamNameFromARN(arn)
+	if streamName == "" {
+		return nil
+	}
+	for _, route := range a.kinesisRoutes {
+		if route.StreamName == streamName && route.Window != nil {
+			return route.Window
+		}
+	}
+	return nil
+}
+
+// ServeKinesisWindow routes a Kinesis tumbling window invocation to the
+// registered KinesisTumblingWindow handler and returns the new window state
+// with any batch item failures.
+//
+// Streams registered only with Kinesis run their per-record handler as
+// ServeKinesis does, and the window state passes through unchanged. When the
+// window handler fails, or the stream is unrecognized, it returns an error so
+// Lambda retries the invocation with the previous state; this also covers
+// final invocations that carry no records to report as failures. The returned
+// error does not carry the handler error.
+func (a *App) ServeKinesisWindow(ctx context.Context, event events.KinesisTimeWindowEvent) (events.KinesisTimeWindowEventResponse, error) {
+	handler := a.kinesisWindowHandlerForEvent(event)
+	if handler == nil {
+		if a.kinesisHandlerForEvent(event.KinesisEvent) != nil {
+			return kinesisWindowResponse(event.State, a.ServeKinesis(ctx, event.KinesisEvent).BatchItemFailures), nil
+		}
+		return kinesisWindowResponse(event.State, kinesisWindowFailures(event.Records)), errors.New("apptheory: unrecognized kinesis stream")
+	}
+
+	window, err := kinesisWindowFromEvent(event)
+	if err == nil {
+		var state map[string]string
+		if state, err = a.wrapKinesisWindowHandler(handler)(a.eventContext(ctx), window); err == nil {
+			return kinesisWindowResponse(state, nil), nil
+		}
+	}
+	return kinesisWindowResponse(event.State, kinesisWindowFailures(event.Records)), fmt.Errorf("apptheory: kinesis window failed: %w", sanitizeEventWorkloadError(err))
+}
+
+func (a *App) wrapKinesisWindowHandler(handler KinesisWindowHandler) KinesisWindowHandler {
+	wrapped := wrapEventRecordHandlerWithOutput(
+		a,
+		func(ctx *EventContext, window KinesisWindow) (any, error) {
+			return handler(ctx, window)
+		},
+		func(event any) (KinesisWindow, bool) {
+			window, ok := event.(KinesisWindow)
+			return window, ok
+		},
+		"apptheory: invalid kinesis window type",
+	)
+	return func(ctx *EventContext, window KinesisWindow) (map[string]string, error) {
+		out, err := wrapped(ctx, window)
+		if err != nil || out == nil {
+			return nil, err
+		}
+		state, ok := out.(map[string]string)
+		if !ok {
+			return nil, errors.New("apptheory: invalid kinesis window state type")
+		}
+		return state, nil
+	}
+}
+
+func kinesisWindowFromEvent(event events.KinesisTimeWindowEvent) (KinesisWindow, error) {
+	window := KinesisWindow{
+		Start:                   event.Window.Start.Time,
+		End:                     event.Window.End.Time,
+		ShardID:                 event.ShardID,
+		EventSourceARN:          event.EventSourceARN,
+		State:                   maps.Clone(event.State),
+		IsFinalInvokeForWindow:  event.IsFinalInvokeForWindow,
+		IsWindowTerminatedEarly: event.IsWindowTerminatedEarly,
+		Records:                 make([]KinesisUserRecord, 0, len(event.Records)),
+	}
+	if window.State == nil {
+		window.State = map[string]string{}
+	}
+	for _, record := range event.Records {
+		users, err := DeaggregateKinesisRecord(record)
+		if err != nil {
+			return window, err
+		}
+		window.Records = append(window.Records, users...)
+	}
+	return window, nil
+}
+
+func kinesisWindowFailures(records []events.KinesisEventRecord) []events.KinesisBatchItemFailure {
+	return batchItemFailures(records, nil, kinesisBatchSpec.recordID, kinesisBatchSpec.failureForID)
+}
+
+func kinesisWindowResponse(state map[string]string, failures []events.KinesisBatchItemFailure) events.KinesisTimeWindowEventResponse {
+	if state == nil {
+		state = map[string]string{}
+	}
+	if failures == nil {
+		failures = []events.KinesisBatchItemFailure{}
+	}
+	return events.KinesisTimeWindowEventResponse{
+		TimeWindowEventResponseProperties: events.TimeWindowEventResponseProperties{State: state},
+		BatchItemFailures:                 failures,
+	}
+}
@@ -13,10 +13,18 @@ import (
 
 type lambdaEnvelope struct {
 	Records        json.RawMessage `json:"Records"`
+	EventSource    *string         `json:"eventSource"`
 	RequestContext json.RawMessage `json:"requestContext"`
 	RouteKey       *string         `json:"routeKey"`
 	DetailType     *string         `json:"detail-type"`
 	DetailTypeAlt  *string         `json:"detailType"`
+	TriggerSource  *string         `json:"triggerSource"`
+	UserPoolID     *string         `json:"userPoolId"`
+	Type           *string         `json:"type"`
+	MethodArn      *string         `json:"methodArn"`
+	RouteArn       *string         `json:"routeArn"`
+	Window         json.RawMessage `json:"window"`
+	EventSourceARN string          `json:"eventSourceARN"`
 }
 
 type recordProbe struct {
@@ -24,6 +32,21 @@ type recordProbe struct {
 	EventSourceAlt string `json:"EventSource"`
 }
 
+// handleLambdaKinesisWindow handles Kinesis tumbling window invocations, which
+// carry window properties next to the records and may carry no records at all.
+func (a *App) handleLambdaKinesisWindow(ctx context.Context, event json.RawMessage, env lambdaEnvelope) (any, bool, error) {
+	arnParts := strings.Split(strings.TrimSpace(env.EventSourceARN), ":")
+	if len(env.Window) == 0 || len(arnParts) < 3 || arnParts[2] != "kinesis" {
+		return nil, false, nil
+	}
+	var window events.KinesisTimeWindowEvent
+	if err := json.Unmarshal(event, &window); err != nil {
+		return nil, true, fmt.Errorf("apptheory: parse kinesis window event: %w", err)
+	}
+	resp, err := a.ServeKinesisWindow(ctx, window)
+	return resp, true, err
+}
+
 func (a *App) handleLambdaRecords(ctx context.Context, event json.RawMessage, env lambdaEnvelope) (any, bool, error) {
 	if len(env.Records) == 0 {
 		return nil, false, nil
@@ -65,11 +88,55 @@ func (a *App) handleLambdaRecords(ctx context.Context, event json.RawMessage, en
 		}
 		out, err := a.ServeSNS(ctx, sns)
 		return out, true, err
+	case "aws:s3":
+		var s3 events.S3Event
+		if err := json.Unmarshal(event, &s3); err != nil {
+			return nil, true, fmt.Errorf("apptheory: parse s3 event: %w", err)
+		}
+		return nil, true, a.ServeS3(ctx, s3)
 	default:
 		return nil, false, nil
 	}
 }
 
+func (a *App) handleLambdaCloudFront(ctx context.Context, event json.RawMessage, env lambdaEnvelope) (any, bool, error) {
+	if len(env.Records) == 0 {
+		return nil, false, nil
+	}
+
+	var probes []struct {
+		CF json.RawMessage `json:"cf"`
+	}
+	if err := json.Unmarshal(env.Records, &probes); err != nil || len(probes) == 0 || len(probes[0].CF) == 0 {
+		return nil, false, nil
+	}
+
+	var cf CloudFrontEvent
+	if err := json.Unmarshal(event, &cf); err != nil {
+		return nil, true, fmt.Errorf("apptheory: parse cloudfront event: %w", err)
+	}
+	out, err := a.ServeCloudFront(ctx, cf)
+	return out, true, err
+}
+
+func (a *App) handleLambdaKafka(ctx context.Context, event json.RawMessage, env lambdaEnvelope) (any, bool, error) {
+	if env.EventSource == nil {
+		return nil, false, nil
+	}
+	switch strings.TrimSpace(*env.EventSource) {
+	case kafkaEventSourceMSK, kafkaEventSourceSelfManaged:
+	default:
+		return nil, false, nil
+	}
+
+	var kafka events.KafkaEvent
+	if err := json.Unmarshal(event, &kafka); err != nil {
+		return nil, true, fmt.Errorf("apptheory: parse kafka event: %w", err)
+	}
+	resp, err := a.ServeKafka(ctx, kafka)
+	return resp, true, err
+}
+
 func (a *App) handleLambdaEventBridge(ctx context.Context, event json.RawMessage, env lambdaEnvelope) (any, bool, error) {
 	if env.DetailType == nil && env.DetailTypeAlt == nil {
 		return nil, false, nil
@@ -86,7 +153,46 @@ func (a *App) handleLambdaEventBridge(ctx context.Context, event json.RawMessage
 	return out, true, err
 }
 
-func (a *App) handleLambdaAppSync(ctx context.Context, event json.RawMessage) (any, bool, error) {
+func (a *App) handleLambdaCognito(ctx context.Context, event json.RawMessage, env lambdaEnvelope) (any, bool, error) {
+	if env.TriggerSource == nil || env.UserPoolID == nil {
+		return nil, false, nil
+	}
+	out, err := a.ServeCognitoTrigger(ctx, event)
+	return out, true, err
+}
+
+func (a *App) handleLambdaAuthorizer(ctx context.Context, event json.RawMessage, env lambdaEnvelope) (any, bool, error) {
+	if env.Type == nil || (env.MethodArn == nil && env.RouteArn == nil) {
+		return nil, false, nil
+	}
+
+	if env.RouteArn != nil {
+		var v2 events.APIGatewayV2CustomAuthorizerV2Request
+		if err := json.Unmarshal(event, &v2); err != nil {
+			return nil, true, fmt.Errorf("apptheory: parse apigw v2 authorizer event: %w", err)
+		}
+		out, err := a.ServeAPIGatewayV2Authorizer(ctx, v2)
+		return out, true, err
+	}
+
+	if strings.EqualFold(strings.TrimSpace(*env.Type), authorizerTypeToken) {
+		var token events.APIGatewayCustomAuthorizerRequest
+		if err := json.Unmarshal(event, &token); err != nil {
+			return nil, true, fmt.Errorf("apptheory: parse apigw token authorizer event: %w", err)
+		}
+		out, err := a.ServeAPIGatewayTokenAuthorizer(ctx, token)
+		return out, true, err
+	}
+
+	var request events.APIGatewayCustomAuthorizerRequestTypeRequest
+	if err := json.Unmarshal(event, &request); err != nil {
+		return nil, true, fmt.Errorf("apptheory: parse apigw request authorizer event: %w", err)
+	}
+	out, err := a.ServeAPIGatewayRequestAuthorizer(ctx, request)
+	return out, true, err
+}
+
+func (a *App) handleLambdaAppSync(ctx context.Context, event json.RawMessage, _ lambdaEnvelope) (any, bool, error) {
 	appSyncEvent, ok, err := appSyncEventFromRawMessage(event)
 	if err != nil {
 		return nil, true, fmt.Errorf("apptheory: parse appsync event: %w", err)
@@ -168,9 +274,14 @@ func (a *App) handleLambdaRequestContext(ctx context.Context, event json.RawMess
 // - Application Load Balancer (Target Group)
 // - Lambda Function URL
 // - SQS
-// - Kinesis
+// - Kinesis (including tumbling windows)
+// - Kafka (Amazon MSK and self-managed)
 // - SNS
+// - Lambda@Edge (CloudFront viewer/origin requests and responses)
+// - S3 (direct notifications and EventBridge object events)
 // - EventBridge
+// - Cognito User Pools triggers
+// - API Gateway Lambda authorizers (REST TOKEN/REQUEST and HTTP API)
 // - DynamoDB Streams
 // - AppSync Lambda resolver
 // - API Gateway v2 (WebSocket API)
@@ -187,36 +298,25 @@ func (a *App) HandleLambda(ctx context.Context, event json.RawMessage) (any, err
 		return nil, fmt.Errorf("apptheory: parse event envelope: %w", err)
 	}
 
-	out, ok, err := a.handleLambdaRecords(ctx, event, env)
-	if err != nil {
-		return nil, err
-	}
-	if ok {
-		return out, nil
-	}
-
-	out, ok, err = a.handleLambdaEventBridge(ctx, event, env)
-	if err != nil {
-		return nil, err
-	}
-	if ok {
-		return out, nil
+	handlers := []func(context.Context, json.RawMessage, lambdaEnvelope) (any, bool, error){
+		a.handleLambdaKinesisWindow,
+		a.handleLambdaRecords,
+		a.handleLambdaCloudFront,
+		a.handleLambdaKafka,
+		a.handleLambdaEventBridge,
+		a.handleLambdaCognito,
+		a.handleLambdaAppSync,
+		a.handleLambdaAuthorizer,
+		a.handleLambdaRequestContext,
 	}
-
-	out, ok, err = a.handleLambdaAppSync(ctx, event)
-	if err != nil {
-		return nil, err
-	}
-	if ok {
-		return out, nil
-	}
-
-	out, ok, err = a.handleLambdaRequestContext(ctx, event, env)
-	if err != nil {
-		return nil, err
-	}
-	if ok {
-		return out, nil
+	for _, handle := range handlers {
+		out, ok, err := handle(ctx, event, env)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return out, nil
+		}
 	}
 
 	return nil, fmt.Errorf("apptheory: unknown event type")
@@ -0,0 +1,293 @@
+package apptheory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/objectstore"
+)
+
+// S3EventRecord is one S3 object notification, normalized from either a direct
+// S3 event notification record or an EventBridge "Object Created" / "Object
+// Deleted" event from aws.s3.
+//
+// Key is always the decoded object key. EventName uses the S3 notification
+// names (for example "ObjectCreated:Put" or "ObjectRemoved:Delete") for both
+// delivery paths.
+type S3EventRecord struct {
+	EventName string
+	EventTime time.Time
+	Region    string
+	Bucket    string
+	Key       string
+	Size      int64
+	ETag      string
+	VersionID string
+	Sequencer string
+}
+
+// ObjectRef returns the notified object as an objectstore reference.
+func (r S3EventRecord) ObjectRef() objectstore.ObjectRef {
+	return objectstore.ObjectRef{Bucket: r.Bucket, Key: r.Key, VersionID: r.VersionID}
+}
+
+type S3Handler func(*EventContext, S3EventRecord) error
+
+// S3Option narrows an App.S3 registration.
+type S3Option func(*s3Route)
+
+// S3KeyPrefix restricts a route to object keys starting with prefix.
+func S3KeyPrefix(prefix string) S3Option {
+	return func(r *s3Route) { r.Prefix = prefix }
+}
+
+// S3KeySuffix restricts a route to object keys ending with suffix.
+func S3KeySuffix(suffix string) S3Option {
+	return func(r *s3Route) { r.Suffix = suffix }
+}
+
+// S3Events restricts a route to the given S3 event names. A name ending in
+// "*" matches every event with that prefix (for example "ObjectCreated:*");
+// an "s3:" prefix is ignored.
+func S3Events(names ...string) S3Option {
+	return func(r *s3Route) {
+		for _, name := range names {
+			name = strings.TrimPrefix(strings.TrimSpace(name), "s3:")
+			if name != "" {
+				r.Events = append(r.Events, name)
+			}
+		}
+	}
+}
+
+type s3Route struct {
+	Bucket  string
+	Prefix  string
+	Suffix  string
+	Events  []string
+	Handler S3Handler
+}
+
+// S3 registers a handler for object notifications from bucket.
+//
+// Routes are evaluated in registration order and each record goes to the
+// first route whose bucket, key prefix/suffix, and event names all match.
+// Both direct S3 notifications and EventBridge object events are routed here.
+func (a *App) S3(bucket string, handler S3Handler, opts ...S3Option) *App {
+	if a == nil {
+		return a
+	}
+	bucket = strings.TrimSpace(bucket)
+	if bucket == "" || handler == nil {
+		return a
+	}
+	route := s3Route{Bucket: bucket, Handler: handler}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&route)
+		}
+	}
+	a.s3Routes = append(a.s3Routes, route)
+	return a
+}
+
+func (r s3Route) matches(record S3EventRecord) bool {
+	if r.Bucket != record.Bucket {
+		return false
+	}
+	if !strings.HasPrefix(record.Key, r.Prefix) || !strings.HasSuffix(record.Key, r.Suffix) {
+		return false
+	}
+	if len(r.Events) == 0 {
+		return true
+	}
+	for _, name := range r.Events {
+		if prefix, ok := strings.CutSuffix(name, "*"); ok && strings.HasPrefix(record.EventName, prefix) {
+			return true
+		}
+		if name == record.EventName {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *App) s3HandlerForRecord(record S3EventRecord) S3Handler {
+	if a == nil {
+		return nil
+	}
+	for _, route := range a.s3Routes {
+		if route.matches(record) {
+			return route.Handler
+		}
+	}
+	return nil
+}
+
+// S3EventRecordFromNotification normalizes a direct S3 notification record,
+// decoding its URL-encoded object key.
+func S3EventRecordFromNotification(record events.S3EventRecord) (S3EventRecord, error) {
+	key, err := url.QueryUnescape(record.S3.Object.Key)
+	if err != nil {
+		return S3EventRecord{}, fmt.Errorf("apptheory: invalid s3 object key: %w", err)
+	}
+	return S3EventRecord{
+		EventName: strings.TrimPrefix(strings.TrimSpace(record.EventName), "s3:"),
+		EventTime: record.EventTime,
+		Region:    strings.TrimSpace(record.AWSRegion),
+		Bucket:    strings.TrimSpace(record.S3.Bucket.Name),
+		Key:       key,
+		Size:      record.S3.Object.Size,
+		ETag:      record.S3.Object.ETag,
+		VersionID: record.S3.Object.VersionID,
+		Sequencer: record.S3.Object.Sequencer,
+	}, nil
+}
+
+type s3EventBridgeDetail struct {
+	Bucket struct {
+		Name string `json:"name"`
+	} `json:"bucket"`
+	Object struct {
+		Key       string `json:"key"`
+		Size      int64  `json:"size"`
+		ETag      string `json:"etag"`
+		VersionID string `json:"version-id"`
+		Sequencer string `json:"sequencer"`
+	} `json:"object"`
+	Reason       string `json:"reason"`
+	DeletionType string `json:"deletion-type"`
+}
+
+// S3EventRecordFromEventBridge normalizes an aws.s3 "Object Created" or
+// "Object Deleted" EventBridge event. It reports false for any other event.
+// EventBridge delivers object keys unencoded, so Key is used as-is.
+func S3EventRecordFromEventBridge(event events.EventBridgeEvent) (S3EventRecord, bool) {
+	if strings.TrimSpace(event.Source) != "aws.s3" {
+		return S3EventRecord{}, false
+	}
+	var detail s3EventBridgeDetail
+	if err := json.Unmarshal(event.Detail, &detail); err != nil {
+		return S3EventRecord{}, false
+	}
+
+	var eventName string
+	switch strings.TrimSpace(event.DetailType) {
+	case "Object Created":
+		eventName = "ObjectCreated:" + s3CreatedEventSuffix(detail.Reason)
+	case "Object Deleted":
+		eventName = "ObjectRemoved:Delete"
+		if detail.DeletionType == "Delete Marker Created" {
+			eventName = "ObjectRemoved:DeleteMarkerCreated"
+		}
+	default:
+		return S3EventRecord{}, false
+	}
+
+	return S3EventRecord{
+		EventName: eventName,
+		EventTime: event.Time,
+		Region:    strings.TrimSpace(event.Region),
+		Bucket:    strings.TrimSpace(detail.Bucket.Name),
+		Key:       detail.Object.Key,
+		Size:      detail.Object.Size,
+		ETag:      detail.Object.ETag,
+		VersionID: detail.Object.VersionID,
+		Sequencer: detail.Object.Sequencer,
+	}, true
+}
+
+func s3CreatedEventSuffix(reason string) string {
+	reason = strings.TrimSpace(reason)
+	switch reason {
+	case "PutObject":
+		return "Put"
+	case "POST Object":
+		return "Post"
+	case "CopyObject":
+		return "Copy"
+	case "CompleteMultipartUpload":
+		return "CompleteMultipartUpload"
+	default:
+		return reason
+	}
+}
+
+// serveS3Record runs the S3 handler matching record through the event
+// middleware chain and records its outcome. Handler errors and panics are
+// replaced with the generic event workload error, tagged with the object.
+func (a *App) serveS3Record(evtCtx *EventContext, record S3EventRecord) error {
+	handler := a.s3HandlerForRecord(record)
+	if handler == nil {
+		return errors.New("apptheory: unrecognized s3 object notification")
+	}
+	recordCtx := evtCtx.cloneForRecord()
+	recordCtx.s3Record = &record
+	_, err := a.serveObservedEvent(recordCtx, s3Observation(recordCtx, record), record, func(ctx *EventContext, event any) (any, error) {
+		rec, ok := event.(S3EventRecord)
+		if !ok {
+			return nil, errors.New("apptheory: invalid s3 record type")
+		}
+		return nil, handler(ctx, rec)
+	})
+	if err != nil {
+		return fmt.Errorf("apptheory: s3 object %s/%s: %w", record.Bucket, record.Key, err)
+	}
+	return nil
+}
+
+func s3Observation(ctx *EventContext, record S3EventRecord) eventObservation {
+	return eventObservation{
+		Trigger:       eventTriggerS3,
+		RequestID:     eventContextRequestID(ctx),
+		CorrelationID: record.Sequencer,
+		Source:        record.Bucket,
+		EventID:       record.Sequencer,
+		EventName:     record.EventName,
+	}
+}
+
+// ServeS3 routes each record of a direct S3 notification to its matching S3
+// handler, in order.
+//
+// It fails closed: the first record with no matching route or a failing
+// handler stops processing and returns an error, so Lambda retries the event.
+// Handler errors are reported through the observability hooks and returned as
+// the generic event workload error for the failing object.
+func (a *App) ServeS3(ctx context.Context, event events.S3Event) error {
+	if a == nil {
+		return errors.New("apptheory: nil app")
+	}
+	evtCtx := a.eventContext(ctx)
+	for _, raw := range event.Records {
+		record, err := S3EventRecordFromNotification(raw)
+		if err != nil {
+			return err
+		}
+		if err := a.serveS3Record(evtCtx, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serveS3EventBridge handles an EventBridge S3 object event when an S3 route
+// matches it. It reports false when the event should fall through to the
+// EventBridge routes.
+func (a *App) serveS3EventBridge(ctx context.Context, event events.EventBridgeEvent) (bool, error) {
+	if a == nil || len(a.s3Routes) == 0 {
+		return false, nil
+	}
+	record, ok := S3EventRecordFromEventBridge(event)
+	if !ok || a.s3HandlerForRecord(record) == nil {
+		return false, nil
+	}
+	return true, a.serveS3Record(a.eventContext(ctx), record)
+}
@@ -0,0 +1,258 @@
+package apptheory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/objectstore"
+)
+
+func s3TestRecord(bucket, key, eventName string) events.S3EventRecord {
+	return events.S3EventRecord{
+		EventSource: "aws:s3",
+		EventName:   eventName,
+		AWSRegion:   "us-east-1",
+		S3: events.S3Entity{
+			Bucket: events.S3Bucket{Name: bucket},
+			Object: events.S3Object{Key: key, Size: 7, VersionID: "v1"},
+		},
+	}
+}
+
+func TestServeS3_RoutesByBucketKeyAndEventName(t *testing.T) {
+	t.Parallel()
+
+	var got []string
+	record := func(name string) S3Handler {
+		return func(ctx *EventContext, rec S3EventRecord) error {
+			fromCtx, ok := ctx.S3Record()
+			if !ok || fromCtx != rec {
+				t.Fatalf("expected S3 record on event context, got %#v", fromCtx)
+			}
+			got = append(got, name+":"+rec.Key)
+			return nil
+		}
+	}
+
+	app := New()
+	app.S3("uploads", record("csv"), S3KeyPrefix("incoming/"), S3KeySuffix(".csv"), S3Events("s3:ObjectCreated:*"))
+	app.S3("uploads", record("removed"), S3Events("ObjectRemoved:Delete"))
+	app.S3("uploads", record("any"))
+	app.S3(" ", record("ignored"))
+	app.S3("uploads", nil)
+
+	err := app.ServeS3(context.Background(), events.S3Event{Records: []events.S3EventRecord{
+		s3TestRecord("uploads", "incoming/Q1+report%281%29.csv", "ObjectCreated:Put"),
+		s3TestRecord("uploads", "incoming/a.csv", "ObjectRemoved:Delete"),
+		s3TestRecord("uploads", "incoming/a.json", "ObjectCreated:Copy"),
+	}})
+	if err != nil {
+		t.Fatalf("ServeS3: %v", err)
+	}
+	want := []string{"csv:incoming/Q1 report(1).csv", "removed:incoming/a.csv", "any:incoming/a.json"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+
+	err = app.ServeS3(context.Background(), events.S3Event{Records: []events.S3EventRecord{
+		s3TestRecord("other", "a.csv", "ObjectCreated:Put"),
+	}})
+	if err == nil {
+		t.Fatal("expected unrecognized bucket to fail closed")
+	}
+	err = app.ServeS3(context.Background(), events.S3Event{Records: []events.S3EventRecord{
+		s3TestRecord("uploads", "bad%zz", "ObjectCreated:Put"),
+	}})
+	if err == nil {
+		t.Fatal("expected invalid key encoding to fail")
+	}
+	var nilApp *App
+	if err := nilApp.ServeS3(context.Background(), events.S3Event{}); err == nil {
+		t.Fatal("expected nil app error")
+	}
+}
+
+func TestServeS3_StopsAtFirstFailureAndRunsEventMiddleware(t *testing.T) {
+	t.Parallel()
+
+	app := New()
+	var seen []any
+	app.UseEvents(func(next EventHandler) EventHandler {
+		return func(ctx *EventContext, event any) (any, error) {
+			seen = append(seen, event)
+			return next(ctx, event)
+		}
+	})
+	calls := 0
+	app.S3("uploads", func(_ *EventContext, rec S3EventRecord) error {
+		calls++
+		if rec.Key == "bad" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	err := app.ServeS3(context.Background(), events.S3Event{Records: []events.S3EventRecord{
+		s3TestRecord("uploads", "bad", "ObjectCreated:Put"),
+		s3TestRecord("uploads", "good", "ObjectCreated:Put"),
+	}})
+	if err == nil || strings.Contains(err.Error(), "boom") || !strings.Contains(err.Error(), "uploads/bad") {
+		t.Fatalf("expected sanitized handler error naming the object, got %v", err)
+	}
+	if calls != 1 || len(seen) != 1 {
+		t.Fatalf("expected processing to stop after the failure, calls=%d seen=%d", calls, len(seen))
+	}
+	if _, ok := seen[0].(S3EventRecord); !ok {
+		t.Fatalf("expected middleware to see S3EventRecord, got %T", seen[0])
+	}
+}
+
+func TestServeS3_RecordsOutcomeAndRecoversPanics(t *testing.T) {
+	t.Parallel()
+
+	var logs []LogRecord
+	var metrics []MetricRecord
+	app := New(WithObservability(ObservabilityHooks{
+		Log:    func(record LogRecord) { logs = append(logs, record) },
+		Metric: func(record MetricRecord) { metrics = append(metrics, record) },
+	}))
+	app.S3("uploads", func(_ *EventContext, rec S3EventRecord) error {
+		if rec.Key == "panic" {
+			panic("secret detail")
+		}
+		return nil
+	})
+
+	if err := app.ServeS3(context.Background(), events.S3Event{Records: []events.S3EventRecord{
+		s3TestRecord("uploads", "good", "ObjectCreated:Put"),
+	}}); err != nil {
+		t.Fatalf("ServeS3: %v", err)
+	}
+	_, err := app.ServeEventBridge(context.Background(), events.EventBridgeEvent{
+		Source:     "aws.s3",
+		DetailType: "Object Created",
+		Detail:     json.RawMessage(`{"bucket":{"name":"uploads"},"object":{"key":"panic"},"reason":"PutObject"}`),
+	})
+	if err == nil || strings.Contains(err.Error(), "secret") || !strings.Contains(err.Error(), eventWorkloadFailedMessage) {
+		t.Fatalf("expected sanitized panic error, got %v", err)
+	}
+
+	if len(logs) != 2 || len(metrics) != 2 {
+		t.Fatalf("expected one log and metric per record, logs=%d metrics=%d", len(logs), len(metrics))
+	}
+	if logs[0].Trigger != eventTriggerS3 || logs[0].Source != "uploads" || logs[0].EventName != "ObjectCreated:Put" || logs[0].ErrorCode != "" {
+		t.Fatalf("unexpected success log: %#v", logs[0])
+	}
+	if logs[1].Level != logLevelError || logs[1].ErrorCode != "app.internal" {
+		t.Fatalf("unexpected failure log: %#v", logs[1])
+	}
+	if metrics[1].Tags["bucket"] != "uploads" || metrics[1].Tags["outcome"] != "error" {
+		t.Fatalf("unexpected failure metric tags: %#v", metrics[1].Tags)
+	}
+}
+
+func TestS3EventRecord_ObjectRef(t *testing.T) {
+	t.Parallel()
+
+	rec, err := S3EventRecordFromNotification(s3TestRecord("uploads", "a/b+c.txt", "s3:ObjectCreated:Put"))
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if rec.EventName != "ObjectCreated:Put" {
+		t.Fatalf("unexpected event name %q", rec.EventName)
+	}
+	want := objectstore.ObjectRef{Bucket: "uploads", Key: "a/b c.txt", VersionID: "v1"}
+	if rec.ObjectRef() != want {
+		t.Fatalf("expected %#v, got %#v", want, rec.ObjectRef())
+	}
+}
+
+func TestS3EventRecordFromEventBridge(t *testing.T) {
+	t.Parallel()
+
+	event := events.EventBridgeEvent{
+		Source:     "aws.s3",
+		DetailType: "Object Created",
+		Region:     "us-west-2",
+		Detail: json.RawMessage(`{"bucket":{"name":"uploads"},"object":{"key":"in/a b.csv","size":9,` +
+			`"etag":"e","version-id":"v2","sequencer":"01"},"reason":"CopyObject"}`),
+	}
+	rec, ok := S3EventRecordFromEventBridge(event)
+	if !ok {
+		t.Fatal("expected S3 object event")
+	}
+	want := S3EventRecord{
+		EventName: "ObjectCreated:Copy", Region: "us-west-2", Bucket: "uploads", Key: "in/a b.csv",
+		Size: 9, ETag: "e", VersionID: "v2", Sequencer: "01",
+	}
+	if rec != want {
+		t.Fatalf("expected %#v, got %#v", want, rec)
+	}
+
+	event.DetailType = "Object Deleted"
+	event.Detail = json.RawMessage(`{"bucket":{"name":"uploads"},"object":{"key":"k"},"deletion-type":"Delete Marker Created"}`)
+	if rec, ok := S3EventRecordFromEventBridge(event); !ok || rec.EventName != "ObjectRemoved:DeleteMarkerCreated" {
+		t.Fatalf("unexpected delete record %#v (%v)", rec, ok)
+	}
+
+	event.DetailType = "Object Restore Completed"
+	if _, ok := S3EventRecordFromEventBridge(event); ok {
+		t.Fatal("expected unsupported detail type to be ignored")
+	}
+	event.DetailType = "Object Created"
+	event.Source = "custom"
+	if _, ok := S3EventRecordFromEventBridge(event); ok {
+		t.Fatal("expected non-S3 source to be ignored")
+	}
+}
+
+func TestHandleLambda_DispatchesS3Notifications(t *testing.T) {
+	t.Parallel()
+
+	var keys []string
+	app := New()
+	app.S3("uploads", func(_ *EventContext, rec S3EventRecord) error {
+		keys = append(keys, rec.EventName+" "+rec.Key)
+		return nil
+	}, S3KeyPrefix("in/"))
+	app.EventBridge(EventBridgePattern("aws.s3", "Object Created"), func(*EventContext, events.EventBridgeEvent) (any, error) {
+		return "eventbridge", nil
+	})
+
+	direct := json.RawMessage(`{"Records":[{"eventSource":"aws:s3","eventName":"ObjectCreated:Put",` +
+		`"s3":{"bucket":{"name":"uploads"},"object":{"key":"in/a%2Bb.csv","size":1}}}]}`)
+	if out, err := app.HandleLambda(context.Background(), direct); err != nil || out != nil {
+		t.Fatalf("direct S3: unexpected result %v (%v)", out, err)
+	}
+
+	viaEventBridge := json.RawMessage(`{"source":"aws.s3","detail-type":"Object Created",` +
+		`"detail":{"bucket":{"name":"uploads"},"object":{"key":"in/c.csv"},"reason":"PutObject"}}`)
+	if out, err := app.HandleLambda(context.Background(), viaEventBridge); err != nil || out != nil {
+		t.Fatalf("eventbridge S3: unexpected result %v (%v)", out, err)
+	}
+
+	unrouted := json.RawMessage(`{"source":"aws.s3","detail-type":"Object Created",` +
+		`"detail":{"bucket":{"name":"uploads"},"object":{"key":"out/c.csv"},"reason":"PutObject"}}`)
+	if out, err := app.HandleLambda(context.Background(), unrouted); err != nil || out != "eventbridge" {
+		t.Fatalf("expected fall through to EventBridge routes, got %v (%v)", out, err)
+	}
+
+	want := []string{"ObjectCreated:Put in/a+b.csv", "ObjectCreated:Put in/c.csv"}
+	if len(keys) != 2 || keys[0] != want[0] || keys[1] != want[1] {
+		t.Fatalf("expected %v, got %v", want, keys)
+	}
+
+	if _, err := app.HandleLambda(context.Background(), json.RawMessage(`{"Records":[{"eventSource":"aws:s3","s3":1}]}`)); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
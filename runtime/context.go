@@ -25,8 +25,12 @@ type Context struct {
 
 	ws              *WebSocketContext
 	appsync         *AppSyncContext
+	cloudfront      *CloudFrontContext
 	securePrincipal *SecurePrincipal
 
+	authorizer        *AuthorizerRequest
+	authorizerContext map[string]any
+
 	values map[string]any
 }
 
@@ -0,0 +1,200 @@
+package apptheory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	eventPayloadSourceSQS         = "aws:sqs"
+	eventPayloadSourceSNS         = "aws:sns"
+	eventPayloadSourceKinesis     = "aws:kinesis"
+	eventPayloadSourceEventBridge = "aws:events"
+
+	snsNotificationType = "Notification"
+)
+
+// EventPayloadError reports an event payload that failed typed decoding or
+// validation. Retrying the record cannot fix it, so adapters hand it to the
+// configured PoisonHandler instead of the normal retry path.
+type EventPayloadError struct {
+	// Source is the event source, such as "aws:sqs" or "aws:events".
+	Source string
+	// ID identifies the record: the message ID, sequence number, or event ID.
+	ID string
+	// Payload is the raw JSON payload, after SNS envelope unwrapping.
+	Payload []byte
+	Err     error
+}
+
+func (e *EventPayloadError) Error() string {
+	return fmt.Sprintf("apptheory: invalid %s payload %s: %v", e.Source, e.ID, e.Err)
+}
+
+func (e *EventPayloadError) Unwrap() error { return e.Err }
+
+// PoisonHandler receives event payloads that cannot be decoded or validated,
+// for example to park them in a dead-letter store. A nil return acknowledges
+// the record so it is not retried; an error fails it as usual.
+type PoisonHandler func(*EventContext, *EventPayloadError) error
+
+// AcknowledgePoison is a PoisonHandler that drops the payload and acknowledges
+// the record. The drop is reported as an "event.payload_rejected" record
+// through the ObservabilityHooks log when one is configured; the payload
+// itself is not logged.
+func AcknowledgePoison(ctx *EventContext, err *EventPayloadError) error {
+	if ctx == nil || ctx.log == nil || err == nil {
+		return nil
+	}
+	ctx.log(LogRecord{
+		Level:     "warn",
+		Event:     "event.payload_rejected",
+		RequestID: ctx.RequestID,
+		ErrorCode: errorCodeValidationFailed,
+		Source:    err.Source,
+		EventID:   err.ID,
+	})
+	return nil
+}
+
+// EventJSONConfig controls the typed event payload adapters SQSJSON, SNSJSON,
+// KinesisJSON, and EventBridgeDetail.
+//
+// Payloads are decoded as strict JSON (unknown fields and trailing data are
+// rejected) and checked against `validate` struct tags, as with BindHandler.
+type EventJSONConfig[T any] struct {
+	Validate func(*EventContext, T) error
+	// Poison receives payloads that fail decoding or validation and is
+	// required; use AcknowledgePoison to drop them.
+	Poison PoisonHandler
+}
+
+// SQSJSON adapts a typed handler into an SQSHandler that decodes the message
+// body into T. Bodies delivered through an SNS subscription without raw
+// message delivery are unwrapped to the SNS message first.
+func SQSJSON[T any](config EventJSONConfig[T], handler func(*EventContext, T) error) SQSHandler {
+	if handler == nil {
+		return nil
+	}
+	requirePoisonHandler(config.Poison)
+	return func(ctx *EventContext, msg events.SQSMessage) error {
+		value, err := decodeEventJSON(ctx, config, eventPayloadSourceSQS, msg.MessageId, unwrapSNSEnvelope([]byte(msg.Body)))
+		if err != nil {
+			return handleEventPayloadError(ctx, config.Poison, err)
+		}
+		return handler(ctx, value)
+	}
+}
+
+// SNSJSON adapts a typed handler into an SNSHandler that decodes the SNS
+// message into T.
+func SNSJSON[T any](config EventJSONConfig[T], handler func(*EventContext, T) (any, error)) SNSHandler {
+	if handler == nil {
+		return nil
+	}
+	requirePoisonHandler(config.Poison)
+	return func(ctx *EventContext, record events.SNSEventRecord) (any, error) {
+		value, err := decodeEventJSON(ctx, config, eventPayloadSourceSNS, record.SNS.MessageID, []byte(record.SNS.Message))
+		if err != nil {
+			return nil, handleEventPayloadError(ctx, config.Poison, err)
+		}
+		return handler(ctx, value)
+	}
+}
+
+// KinesisJSON adapts a typed handler into a KinesisHandler that decodes the
+// record data into T.
+func KinesisJSON[T any](config EventJSONConfig[T], handler func(*EventContext, T) error) KinesisHandler {
+	if handler == nil {
+		return nil
+	}
+	requirePoisonHandler(config.Poison)
+	return func(ctx *EventContext, record events.KinesisEventRecord) error {
+		value, err := decodeEventJSON(ctx, config, eventPayloadSourceKinesis, record.Kinesis.SequenceNumber, record.Kinesis.Data)
+		if err != nil {
+			return handleEventPayloadError(ctx, config.Poison, err)
+		}
+		return handler(ctx, value)
+	}
+}
+
+// EventBridgeDetail adapts a typed handler into an EventBridgeHandler that
+// decodes the event detail into T.
+func EventBridgeDetail[T any](config EventJSONConfig[T], handler func(*EventContext, T) (any, error)) EventBridgeHandler {
+	if handler == nil {
+		return nil
+	}
+	requirePoisonHandler(config.Poison)
+	return func(ctx *EventContext, event events.EventBridgeEvent) (any, error) {
+		value, err := decodeEventJSON(ctx, config, eventPayloadSourceEventBridge, event.ID, event.Detail)
+		if err != nil {
+			return nil, handleEventPayloadError(ctx, config.Poison, err)
+		}
+		return handler(ctx, value)
+	}
+}
+
+func decodeEventJSON[T any](ctx *EventContext, config EventJSONConfig[T], source, id string, payload []byte) (T, error) {
+	var value T
+	err := decodeStrictEventPayload(&value, payload)
+	if err == nil && config.Validate != nil {
+		err = config.Validate(ctx, value)
+	}
+	if err != nil {
+		return value, &EventPayloadError{Source: source, ID: id, Payload: append([]byte(nil), payload...), Err: err}
+	}
+	return value, nil
+}
+
+func decodeStrictEventPayload(target any, payload []byte) error {
+	if len(bytes.TrimSpace(payload)) == 0 {
+		return errors.New("apptheory: empty event payload")
+	}
+	presence := newValidationPresence()
+	if err := bindStrictBody(target, payload, presence); err != nil {
+		return err
+	}
+	return validateBoundRequestWithPresence(target, presence)
+}
+
+// requirePoisonHandler rejects typed adapters without a poison path, so what
+// happens to an undecodable payload is always an explicit choice.
+func requirePoisonHandler(poison PoisonHandler) {
+	if poison == nil {
+		panic("apptheory: typed event adapter requires a Poison handler")
+	}
+}
+
+func handleEventPayloadError(ctx *EventContext, poison PoisonHandler, err error) error {
+	var payloadErr *EventPayloadError
+	if !errors.As(err, &payloadErr) {
+		return err
+	}
+	return poison(ctx, payloadErr)
+}
+
+// unwrapSNSEnvelope returns the SNS message carried in an SQS body, or body
+// itself when it is not an SNS notification envelope.
+func unwrapSNSEnvelope(body []byte) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return body
+	}
+	var envelope struct {
+		Type     string  `json:"Type"`
+		TopicArn string  `json:"TopicArn"`
+		Message  *string `json:"Message"`
+	}
+	if err := json.Unmarshal(trimmed, &envelope); err != nil {
+		return body
+	}
+	if envelope.Type != snsNotificationType || strings.TrimSpace(envelope.TopicArn) == "" || envelope.Message == nil {
+		return body
+	}
+	return []byte(*envelope.Message)
+}
@@ -0,0 +1,195 @@
+package apptheory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type eventJSONOrder struct {
+	ID    string `json:"id" validate:"required"`
+	Total int    `json:"total" validate:"min=1"`
+}
+
+func TestSQSJSON_DecodesUnwrapsAndRoutesPoison(t *testing.T) {
+	t.Parallel()
+
+	var handled []eventJSONOrder
+	var poisoned []string
+	config := EventJSONConfig[eventJSONOrder]{
+		Validate: func(_ *EventContext, order eventJSONOrder) error {
+			if order.ID == "blocked" {
+				return errors.New("blocked order")
+			}
+			return nil
+		},
+		Poison: func(_ *EventContext, err *EventPayloadError) error {
+			poisoned = append(poisoned, err.ID+":"+string(err.Payload))
+			if err.ID == "m-retry" {
+				return errors.New("dead-letter store unavailable")
+			}
+			return nil
+		},
+	}
+
+	app := New()
+	app.SQS("orders", SQSJSON(config, func(_ *EventContext, order eventJSONOrder) error {
+		handled = append(handled, order)
+		return nil
+	}))
+
+	envelope, err := json.Marshal(map[string]string{
+		"Type": "Notification", "TopicArn": "arn:aws:sns:us-east-1:1:orders", "Message": `{"id":"o2","total":2}`,
+	})
+	if err != nil {
+		t.Fatalf("marshal envelope: %v", err)
+	}
+	arn := "arn:aws:sqs:us-east-1:1:orders"
+	resp := app.ServeSQS(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", EventSourceARN: arn, Body: `{"id":"o1","total":1}`},
+		{MessageId: "m2", EventSourceARN: arn, Body: string(envelope)},
+		{MessageId: "m3", EventSourceARN: arn, Body: `{"id":"o3","total":1,"extra":true}`},
+		{MessageId: "m4", EventSourceARN: arn, Body: `{"id":"o4","total":0}`},
+		{MessageId: "m5", EventSourceARN: arn, Body: `{"id":"blocked","total":1}`},
+		{MessageId: "m-retry", EventSourceARN: arn, Body: `not json`},
+	}})
+
+	if len(handled) != 2 || handled[0].ID != "o1" || handled[1].ID != "o2" {
+		t.Fatalf("unexpected handled orders %#v", handled)
+	}
+	if len(poisoned) != 4 || poisoned[3] != "m-retry:not json" {
+		t.Fatalf("unexpected poisoned payloads %v", poisoned)
+	}
+	if len(resp.BatchItemFailures) != 1 || resp.BatchItemFailures[0].ItemIdentifier != "m-retry" {
+		t.Fatalf("expected only the failed poison hand-off to retry, got %#v", resp.BatchItemFailures)
+	}
+}
+
+func TestEventJSONAdapters_AcknowledgePoisonLogsAndAcknowledges(t *testing.T) {
+	t.Parallel()
+
+	config := EventJSONConfig[eventJSONOrder]{Poison: AcknowledgePoison}
+	var logged []LogRecord
+	ctx := &EventContext{RequestID: "req-1", log: func(record LogRecord) { logged = append(logged, record) }}
+
+	kinesis := KinesisJSON(config, func(*EventContext, eventJSONOrder) error { return nil })
+	if err := kinesis(ctx, events.KinesisEventRecord{Kinesis: events.KinesisRecord{SequenceNumber: "49", Data: []byte(`{"id":"o1"}`)}}); err != nil {
+		t.Fatalf("expected invalid kinesis payload to be acknowledged, got %v", err)
+	}
+
+	var got eventJSONOrder
+	sns := SNSJSON(config, func(_ *EventContext, order eventJSONOrder) (any, error) {
+		got = order
+		return order.ID, nil
+	})
+	out, err := sns(ctx, events.SNSEventRecord{SNS: events.SNSEntity{MessageID: "s1", Message: `{"id":"o1","total":3}`}})
+	if err != nil || out != "o1" || got.Total != 3 {
+		t.Fatalf("unexpected sns result %v %#v (%v)", out, got, err)
+	}
+	if out, err := sns(ctx, events.SNSEventRecord{SNS: events.SNSEntity{MessageID: "s2", Message: ""}}); err != nil || out != nil {
+		t.Fatalf("expected empty sns message to be acknowledged, got %v (%v)", out, err)
+	}
+
+	detail := EventBridgeDetail(config, func(_ *EventContext, order eventJSONOrder) (any, error) { return order.Total, nil })
+	out, err = detail(ctx, events.EventBridgeEvent{ID: "e1", Detail: json.RawMessage(`{"id":"o1","total":5}`)})
+	if err != nil || out != 5 {
+		t.Fatalf("unexpected eventbridge result %v (%v)", out, err)
+	}
+	if _, err := detail(ctx, events.EventBridgeEvent{ID: "e2", Detail: json.RawMessage(`{"id":"o1","total":5} {}`)}); err != nil {
+		t.Fatalf("expected trailing data to be acknowledged, got %v", err)
+	}
+
+	want := []LogRecord{
+		{Level: "warn", Event: "event.payload_rejected", RequestID: "req-1", ErrorCode: "app.validation_failed", Source: "aws:kinesis", EventID: "49"},
+		{Level: "warn", Event: "event.payload_rejected", RequestID: "req-1", ErrorCode: "app.validation_failed", Source: "aws:sns", EventID: "s2"},
+		{Level: "warn", Event: "event.payload_rejected", RequestID: "req-1", ErrorCode: "app.validation_failed", Source: "aws:events", EventID: "e2"},
+	}
+	if !reflect.DeepEqual(logged, want) {
+		t.Fatalf("expected rejected payload logs %#v, got %#v", want, logged)
+	}
+
+	// Handler errors are not payload errors and still fail the record.
+	failing := KinesisJSON(config, func(*EventContext, eventJSONOrder) error { return errors.New("boom") })
+	if err := failing(ctx, events.KinesisEventRecord{Kinesis: events.KinesisRecord{SequenceNumber: "50", Data: []byte(`{"id":"o1","total":1}`)}}); err == nil {
+		t.Fatal("expected handler error to fail the record")
+	}
+
+	if SQSJSON[eventJSONOrder](config, nil) != nil || EventBridgeDetail[eventJSONOrder](config, nil) != nil {
+		t.Fatal("expected nil handlers to stay nil")
+	}
+
+	// Without a log hook the record is still acknowledged.
+	if err := kinesis(&EventContext{}, events.KinesisEventRecord{Kinesis: events.KinesisRecord{SequenceNumber: "51", Data: []byte(`{}`)}}); err != nil {
+		t.Fatalf("expected acknowledgement without a log hook, got %v", err)
+	}
+}
+
+func TestEventJSONAdapters_RequirePoison(t *testing.T) {
+	t.Parallel()
+
+	for name, build := range map[string]func(){
+		"sqs": func() {
+			SQSJSON(EventJSONConfig[eventJSONOrder]{}, func(*EventContext, eventJSONOrder) error { return nil })
+		},
+		"sns": func() {
+			SNSJSON(EventJSONConfig[eventJSONOrder]{}, func(*EventContext, eventJSONOrder) (any, error) { return nil, nil })
+		},
+		"kinesis": func() {
+			KinesisJSON(EventJSONConfig[eventJSONOrder]{}, func(*EventContext, eventJSONOrder) error { return nil })
+		},
+		"eventbridge": func() {
+			EventBridgeDetail(EventJSONConfig[eventJSONOrder]{}, func(*EventContext, eventJSONOrder) (any, error) { return nil, nil })
+		},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected a panic without Poison", name)
+				}
+			}()
+			build()
+		}()
+	}
+}
+
+func TestSQSJSON_AcknowledgePoisonReportsThroughObservabilityLog(t *testing.T) {
+	t.Parallel()
+
+	var logged []LogRecord
+	app := New(WithObservability(ObservabilityHooks{Log: func(record LogRecord) {
+		if record.Event == "event.payload_rejected" {
+			logged = append(logged, record)
+		}
+	}}))
+	app.SQS("orders", SQSJSON(EventJSONConfig[eventJSONOrder]{Poison: AcknowledgePoison}, func(*EventContext, eventJSONOrder) error { return nil }))
+
+	resp := app.ServeSQS(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "m1", EventSourceARN: "arn:aws:sqs:us-east-1:1:orders", Body: `not json`},
+	}})
+	if len(resp.BatchItemFailures) != 0 {
+		t.Fatalf("expected invalid payload to be acknowledged, got %#v", resp.BatchItemFailures)
+	}
+	if len(logged) != 1 || logged[0].Source != "aws:sqs" || logged[0].EventID != "m1" {
+		t.Fatalf("expected one rejected payload log, got %#v", logged)
+	}
+}
+
+func TestUnwrapSNSEnvelope(t *testing.T) {
+	t.Parallel()
+
+	for body, want := range map[string]string{
+		`{"Type":"Notification","TopicArn":"arn","Message":"{\"a\":1}"}`:     `{"a":1}`,
+		`{"Type":"Notification","Message":"x"}`:                              `{"Type":"Notification","Message":"x"}`,
+		`{"Type":"SubscriptionConfirmation","TopicArn":"arn","Message":"x"}`: `{"Type":"SubscriptionConfirmation","TopicArn":"arn","Message":"x"}`,
+		`[1]`:  `[1]`,
+		`{bad`: `{bad`,
+	} {
+		if got := string(unwrapSNSEnvelope([]byte(body))); got != want {
+			t.Fatalf("%s: expected %s, got %s", body, want, got)
+		}
+	}
+}
@@ -133,6 +133,33 @@ func sanitizeEventWorkloadError(err error) error {
 	return eventWorkloadFailedError()
 }
 
+// serveObservedEvent runs handler for a single-event trigger through the event
+// middleware chain, records the outcome, and replaces panics and unsafe errors
+// with the generic event workload error.
+func (a *App) serveObservedEvent(
+	evtCtx *EventContext,
+	observation eventObservation,
+	event any,
+	handler EventHandler,
+) (out any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			out = nil
+			err = eventWorkloadFailedError()
+			a.recordEventObservability(observation, "error", "app.internal")
+			return
+		}
+		if err != nil {
+			err = sanitizeEventWorkloadError(err)
+			a.recordEventObservability(observation, "error", "app.internal")
+			return
+		}
+		a.recordEventObservability(observation, "success", "")
+	}()
+
+	return a.applyEventMiddlewares(handler)(evtCtx, event)
+}
+
 func eventBridgeObservation(ctx *EventContext, event events.EventBridgeEvent) eventObservation {
 	envelope := NormalizeEventBridgeWorkloadEnvelope(ctx, event)
 	return eventObservation{
@@ -0,0 +1,398 @@
+package apptheory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/theory-cloud/apptheory/v3/internal/retry"
+)
+
+const (
+	kinesisProducerInvalidMessage = "apptheory: kinesis producer invalid"
+	kinesisMaxPutRecordsBytes     = 5 * 1024 * 1024
+
+	defaultKinesisProducerMaxAttempts    = 5
+	defaultKinesisProducerRetryBaseDelay = 100 * time.Millisecond
+	defaultKinesisProducerRetryMaxDelay  = 5 * time.Second
+	defaultKinesisProducerDeadlineMargin = 500 * time.Millisecond
+)
+
+// KinesisPutRecordsClient sends one PutRecords request.
+//
+// Implementations map the records into their AWS SDK client call at the edge and return one result per record, in
+// request order. An error fails the whole request.
+type KinesisPutRecordsClient interface {
+	PutRecords(ctx context.Context, streamName string, records []KinesisJSONRecord) ([]KinesisPutRecordsResultRecord, error)
+}
+
+// KinesisProducer buffers Kinesis records and sends them in batches.
+type KinesisProducer interface {
+	// Put buffers record, sending a batch when the buffer reaches the PutRecords limits.
+	Put(ctx context.Context, record KinesisJSONRecord) error
+	// Flush sends every buffered record. Call it before the handler returns.
+	Flush(ctx context.Context) error
+}
+
+// KinesisProducerConfig configures NewKinesisProducer.
+type KinesisProducerConfig struct {
+	StreamName string
+	Client     KinesisPutRecordsClient
+	// MaxAttempts bounds the PutRecords attempts per record, including the first. Defaults to 5.
+	MaxAttempts int
+	// RetryBaseDelay and RetryMaxDelay bound the jittered exponential backoff between attempts. They default to
+	// 100ms and 5s.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// Aggregate packs buffered records into KPL aggregated records of up to 1 MB, which ServeKinesis and the Kinesis
+	// Client Library de-aggregate. Only records with the same partition key and explicit hash key share an aggregated
+	// record, so every record still lands on the shard its own key routes to.
+	Aggregate bool
+	// DeadlineMargin is how long before the context deadline of Put buffered records are flushed, so records are
+	// not lost when a Lambda invocation times out. Flush waits for a deadline flush still in flight and returns its
+	// error. Defaults to 500ms.
+	DeadlineMargin time.Duration
+}
+
+// KinesisProducerError reports records a KinesisProducer could not deliver within its attempts.
+type KinesisProducerError struct {
+	// Records are the undelivered records. Records with the same partition key keep the order they were put in.
+	Records []KinesisJSONRecord
+	// Failures are the safe failure summaries of the last attempt, indexed by that attempt's request.
+	Failures []KinesisPutRecordsFailure
+	// Err is the error of the last attempt, when the request failed as a whole.
+	Err error
+}
+
+func (e *KinesisProducerError) Error() string {
+	message := fmt.Sprintf("apptheory: kinesis producer failed to deliver %d records", len(e.Records))
+	if e.Err != nil {
+		return message + ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *KinesisProducerError) Unwrap() error { return e.Err }
+
+// BatchKinesisProducer is the KinesisProducer for one stream. It is safe for concurrent use.
+//
+// Batches respect the PutRecords limits of 500 records and 5 MB. Only the records that fail are retried, with
+// backoff, and records still failing after MaxAttempts are returned in a *KinesisProducerError.
+type BatchKinesisProducer struct {
+	cfg   KinesisProducerConfig
+	sleep func(context.Context, time.Duration) error
+
+	mu           sync.Mutex
+	pending      []KinesisJSONRecord
+	pendingBytes int
+	deadline     *time.Timer
+	deadlineGen  uint64
+	deadlineErr  error
+	// deadlineDone is closed once the latest deadline flush, and every one
+	// started before it, has recorded its error.
+	deadlineDone chan struct{}
+}
+
+var _ KinesisProducer = (*BatchKinesisProducer)(nil)
+
+// kinesisProducerEntry is one record of a PutRecords request and the records it carries.
+type kinesisProducerEntry struct {
+	record  KinesisJSONRecord
+	records []KinesisJSONRecord
+}
+
+// NewKinesisProducer validates cfg and fills in defaults.
+func NewKinesisProducer(cfg KinesisProducerConfig) (*BatchKinesisProducer, error) {
+	cfg.StreamName = strings.TrimSpace(cfg.StreamName)
+	if cfg.StreamName == "" || cfg.Client == nil {
+		return nil, errors.New(kinesisProducerInvalidMessage + ": stream name and client are required")
+	}
+	if cfg.MaxAttempts < 0 || cfg.RetryBaseDelay < 0 || cfg.RetryMaxDelay < 0 || cfg.DeadlineMargin < 0 {
+		return nil, errors.New(kinesisProducerInvalidMessage + ": limits must not be negative")
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = defaultKinesisProducerMaxAttempts
+	}
+	if cfg.RetryBaseDelay == 0 {
+		cfg.RetryBaseDelay = defaultKinesisProducerRetryBaseDelay
+	}
+	if cfg.RetryMaxDelay == 0 {
+		cfg.RetryMaxDelay = defaultKinesisProducerRetryMaxDelay
+	}
+	if cfg.DeadlineMargin == 0 {
+		cfg.DeadlineMargin = defaultKinesisProducerDeadlineMargin
+	}
+	return &BatchKinesisProducer{cfg: cfg, sleep: retry.Sleep}, nil
+}
+
+func (p *BatchKinesisProducer) Put(ctx context.Context, record KinesisJSONRecord) error {
+	record, err := normalizeKinesisProducerRecord(record)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.pending = append(p.pending, record)
+	p.pendingBytes += kinesisRecordRequestBytes(record)
+	full := p.pendingBytes >= kinesisMaxPutRecordsBytes || (!p.cfg.Aggregate && len(p.pending) >= kinesisMaxPutRecordsRecords)
+	flushNow := full || p.armDeadlineLocked(ctx)
+	var records []KinesisJSONRecord
+	if flushNow {
+		records = p.takeLocked()
+	}
+	p.mu.Unlock()
+
+	return p.send(ctx, records)
+}
+
+func (p *BatchKinesisProducer) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	records := p.takeLocked()
+	inFlight := p.deadlineDone
+	p.mu.Unlock()
+
+	err := p.send(ctx, records)
+	if inFlight != nil {
+		select {
+		case <-inFlight:
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		}
+	}
+
+	p.mu.Lock()
+	deadlineErr := p.deadlineErr
+	p.deadlineErr = nil
+	p.mu.Unlock()
+
+	return errors.Join(deadlineErr, err)
+}
+
+// armDeadlineLocked schedules a flush DeadlineMargin before the context
+// deadline. It reports true when that point has already passed.
+func (p *BatchKinesisProducer) armDeadlineLocked(ctx context.Context) bool {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return false
+	}
+	wait := time.Until(deadline) - p.cfg.DeadlineMargin
+	if wait <= 0 {
+		return true
+	}
+	if p.deadline == nil {
+		p.deadlineGen++
+		gen := p.deadlineGen
+		p.deadline = time.AfterFunc(wait, func() { p.flushAtDeadline(ctx, gen) })
+	}
+	return false
+}
+
+// flushAtDeadline sends the buffered records when the timer armed as gen
+// fires, unless a Put or Flush took them first. Flush waits for it before
+// reading deadlineErr.
+func (p *BatchKinesisProducer) flushAtDeadline(ctx context.Context, gen uint64) {
+	p.mu.Lock()
+	if p.deadline == nil || p.deadlineGen != gen {
+		p.mu.Unlock()
+		return
+	}
+	records := p.takeLocked()
+	previous, done := p.deadlineDone, make(chan struct{})
+	p.deadlineDone = done
+	p.mu.Unlock()
+
+	err := p.send(ctx, records)
+	if previous != nil {
+		<-previous
+	}
+
+	p.mu.Lock()
+	p.deadlineErr = errors.Join(p.deadlineErr, err)
+	if p.deadlineDone == done {
+		p.deadlineDone = nil
+	}
+	p.mu.Unlock()
+	close(done)
+}
+
+func (p *BatchKinesisProducer) takeLocked() []KinesisJSONRecord {
+	if p.deadline != nil {
+		p.deadline.Stop()
+		p.deadline = nil
+	}
+	records := p.pending
+	p.pending, p.pendingBytes = nil, 0
+	return records
+}
+
+func (p *BatchKinesisProducer) send(ctx context.Context, records []KinesisJSONRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	entries := kinesisProducerEntries(records, p.cfg.Aggregate)
+
+	var failed *KinesisProducerError
+	for _, batch := range kinesisPutRecordsBatches(entries) {
+		remaining, failures, err := p.putWithRetry(ctx, batch)
+		if len(remaining) == 0 {
+			continue
+		}
+		if failed == nil {
+			failed = &KinesisProducerError{}
+		}
+		for _, entry := range remaining {
+			failed.Records = append(failed.Records, entry.records...)
+		}
+		failed.Failures, failed.Err = failures, err
+	}
+	if failed == nil {
+		return nil
+	}
+	return failed
+}
+
+// putWithRetry sends batch, retrying the failed entries, and returns the
+// entries that still failed after the last attempt.
+func (p *BatchKinesisProducer) putWithRetry(
+	ctx context.Context,
+	batch []kinesisProducerEntry,
+) ([]kinesisProducerEntry, []KinesisPutRecordsFailure, error) {
+	for attempt := 1; ; attempt++ {
+		records := make([]KinesisJSONRecord, len(batch))
+		for i, entry := range batch {
+			records[i] = entry.record
+		}
+
+		var failures []KinesisPutRecordsFailure
+		results, err := p.cfg.Client.PutRecords(ctx, p.cfg.StreamName, records)
+		if err == nil {
+			var report KinesisPutRecordsFailureReport
+			if report, err = ReportKinesisPutRecordsFailures(records, results); err == nil {
+				if report.FailedRecordCount == 0 {
+					return nil, nil, nil
+				}
+				failures = report.Failures
+				batch = failedKinesisProducerEntries(batch, failures)
+			}
+		}
+
+		if attempt >= p.cfg.MaxAttempts {
+			return batch, failures, err
+		}
+		if sleepErr := p.sleep(ctx, retry.JitteredBackoff(p.cfg.RetryBaseDelay, p.cfg.RetryMaxDelay, attempt-1)); sleepErr != nil {
+			return batch, failures, errors.Join(err, sleepErr)
+		}
+	}
+}
+
+func failedKinesisProducerEntries(
+	batch []kinesisProducerEntry,
+	failures []KinesisPutRecordsFailure,
+) []kinesisProducerEntry {
+	failed := make([]kinesisProducerEntry, 0, len(failures))
+	for _, failure := range failures {
+		failed = append(failed, batch[failure.Index])
+	}
+	return failed
+}
+
+// kinesisRoutingKey is what Kinesis routes a record to a shard by.
+type kinesisRoutingKey struct {
+	partitionKey    string
+	explicitHashKey string
+}
+
+// kinesisProducerEntries turns buffered records into PutRecords entries. When
+// aggregate is set, records sharing a routing key are packed into aggregated
+// records of up to 1 MB, keeping their order within the key.
+func kinesisProducerEntries(records []KinesisJSONRecord, aggregate bool) []kinesisProducerEntry {
+	entries := make([]kinesisProducerEntry, 0, len(records))
+	if !aggregate {
+		for _, record := range records {
+			entries = append(entries, kinesisProducerEntry{record: record, records: []KinesisJSONRecord{record}})
+		}
+		return entries
+	}
+
+	var keys []kinesisRoutingKey
+	aggregators := map[kinesisRoutingKey]*kplAggregator{}
+	for _, record := range records {
+		key := kinesisRoutingKey{partitionKey: record.PartitionKey, explicitHashKey: record.ExplicitHashKey}
+		aggregator, ok := aggregators[key]
+		if !ok {
+			aggregator = &kplAggregator{}
+			aggregators[key] = aggregator
+			keys = append(keys, key)
+		}
+		if len(aggregator.records) > 0 && aggregator.sizeWith(record) > kinesisMaxRecordDataBytes {
+			entries = append(entries, aggregator.entry())
+			*aggregator = kplAggregator{}
+		}
+		aggregator.add(record)
+	}
+	for _, key := range keys {
+		entries = append(entries, aggregators[key].entry())
+	}
+	return entries
+}
+
+// entry returns the aggregated record as a PutRecords entry. A single record
+// is sent as is, as the KPL does.
+func (g *kplAggregator) entry() kinesisProducerEntry {
+	if len(g.records) == 1 {
+		return kinesisProducerEntry{record: g.records[0], records: g.records}
+	}
+	return kinesisProducerEntry{record: g.record(), records: g.records}
+}
+
+// kinesisPutRecordsBatches splits entries into PutRecords requests within the
+// record count and request size limits.
+func kinesisPutRecordsBatches(entries []kinesisProducerEntry) [][]kinesisProducerEntry {
+	var batches [][]kinesisProducerEntry
+	var batch []kinesisProducerEntry
+	batchBytes := 0
+	for _, entry := range entries {
+		size := kinesisRecordRequestBytes(entry.record)
+		if len(batch) > 0 && (len(batch) >= kinesisMaxPutRecordsRecords || batchBytes+size > kinesisMaxPutRecordsBytes) {
+			batches = append(batches, batch)
+			batch, batchBytes = nil, 0
+		}
+		batch = append(batch, entry)
+		batchBytes += size
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// kinesisRecordRequestBytes is the size a record counts toward the PutRecords
+// request limit: its data plus its partition key.
+func kinesisRecordRequestBytes(record KinesisJSONRecord) int {
+	return len(record.Data) + len(record.PartitionKey)
+}
+
+func normalizeKinesisProducerRecord(record KinesisJSONRecord) (KinesisJSONRecord, error) {
+	partitionKey, err := normalizeKinesisPartitionKey(record.PartitionKey)
+	if err != nil {
+		return KinesisJSONRecord{}, err
+	}
+	explicitHashKey, err := normalizeKinesisExplicitHashKey(record.ExplicitHashKey)
+	if err != nil {
+		return KinesisJSONRecord{}, err
+	}
+	if len(record.Data) == 0 || len(record.Data) > kinesisMaxRecordDataBytes {
+		return KinesisJSONRecord{}, fmt.Errorf(
+			"%s: record data size %d must be between 1 and %d",
+			kinesisJSONRecordInvalidMessage,
+			len(record.Data),
+			kinesisMaxRecordDataBytes,
+		)
+	}
+	record.PartitionKey = partitionKey
+	record.ExplicitHashKey = explicitHashKey
+	return record, nil
+}
@@ -0,0 +1,287 @@
+package apptheory
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type producerTestClient struct {
+	mu    sync.Mutex
+	calls [][]KinesisJSONRecord
+	fail  func(call, index int) string
+	err   error
+}
+
+func (c *producerTestClient) PutRecords(_ context.Context, _ string, records []KinesisJSONRecord) ([]KinesisPutRecordsResultRecord, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, records)
+	if c.err != nil {
+		return nil, c.err
+	}
+	results := make([]KinesisPutRecordsResultRecord, len(records))
+	for i := range records {
+		if c.fail != nil {
+			results[i].ErrorCode = c.fail(len(c.calls), i)
+		}
+	}
+	return results, nil
+}
+
+func (c *producerTestClient) callSizes() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sizes := make([]int, len(c.calls))
+	for i, call := range c.calls {
+		sizes[i] = len(call)
+	}
+	return sizes
+}
+
+// blockingProducerClient holds its PutRecords call until release is closed.
+type blockingProducerClient struct {
+	started chan struct{}
+	release chan struct{}
+	err     error
+}
+
+func (c *blockingProducerClient) PutRecords(context.Context, string, []KinesisJSONRecord) ([]KinesisPutRecordsResultRecord, error) {
+	close(c.started)
+	<-c.release
+	return nil, c.err
+}
+
+func newTestKinesisProducer(t *testing.T, client *producerTestClient, cfg KinesisProducerConfig) (*BatchKinesisProducer, *[]time.Duration) {
+	t.Helper()
+	cfg.StreamName, cfg.Client = "orders", client
+	producer, err := NewKinesisProducer(cfg)
+	if err != nil {
+		t.Fatalf("new producer: %v", err)
+	}
+	var delays []time.Duration
+	producer.sleep = func(_ context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return nil
+	}
+	return producer, &delays
+}
+
+func producerTestRecord(i int, size int) KinesisJSONRecord {
+	return KinesisJSONRecord{PartitionKey: "pk-" + strconv.Itoa(i), Data: []byte(strings.Repeat("x", size))}
+}
+
+func TestBatchKinesisProducer_RespectsPutRecordsLimits(t *testing.T) {
+	t.Parallel()
+
+	client := &producerTestClient{}
+	producer, _ := newTestKinesisProducer(t, client, KinesisProducerConfig{})
+	for i := range 501 {
+		if err := producer.Put(context.Background(), producerTestRecord(i, 10)); err != nil {
+			t.Fatalf("put %d: %v", i, err)
+		}
+	}
+	if err := producer.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if sizes := client.callSizes(); len(sizes) != 2 || sizes[0] != 500 || sizes[1] != 1 {
+		t.Fatalf("expected 500 + 1 records, got %v", sizes)
+	}
+
+	client = &producerTestClient{}
+	producer, _ = newTestKinesisProducer(t, client, KinesisProducerConfig{})
+	for i := range 5 {
+		if err := producer.Put(context.Background(), producerTestRecord(i, kinesisMaxRecordDataBytes)); err != nil {
+			t.Fatalf("put %d: %v", i, err)
+		}
+	}
+	if sizes := client.callSizes(); len(sizes) != 2 || sizes[0] != 4 || sizes[1] != 1 {
+		t.Fatalf("expected the 5 MB buffer to split into 4 + 1 records, got %v", sizes)
+	}
+
+	if err := producer.Put(context.Background(), KinesisJSONRecord{PartitionKey: " ", Data: []byte("x")}); err == nil {
+		t.Fatal("expected invalid record to fail")
+	}
+	if _, err := NewKinesisProducer(KinesisProducerConfig{StreamName: "orders"}); err == nil {
+		t.Fatal("expected missing client to fail")
+	}
+}
+
+func TestBatchKinesisProducer_RetriesOnlyFailedRecords(t *testing.T) {
+	t.Parallel()
+
+	client := &producerTestClient{fail: func(call, index int) string {
+		if call == 1 && index != 0 || call == 2 && index == 1 {
+			return "ProvisionedThroughputExceededException"
+		}
+		return ""
+	}}
+	producer, delays := newTestKinesisProducer(t, client, KinesisProducerConfig{RetryBaseDelay: time.Second, RetryMaxDelay: time.Minute})
+	for i := range 3 {
+		if err := producer.Put(context.Background(), producerTestRecord(i, 1)); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	if err := producer.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(client.calls) != 3 || client.calls[1][0].PartitionKey != "pk-1" || client.calls[2][0].PartitionKey != "pk-2" {
+		t.Fatalf("unexpected retries %v", client.callSizes())
+	}
+	if len(*delays) != 2 || (*delays)[0] < 500*time.Millisecond || (*delays)[0] > time.Second || (*delays)[1] < time.Second {
+		t.Fatalf("unexpected backoff %v", *delays)
+	}
+
+	client = &producerTestClient{fail: func(_, index int) string {
+		if index == 0 {
+			return "InternalFailure"
+		}
+		return ""
+	}}
+	producer, _ = newTestKinesisProducer(t, client, KinesisProducerConfig{MaxAttempts: 2})
+	for i := range 2 {
+		if err := producer.Put(context.Background(), producerTestRecord(i, 1)); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	var producerErr *KinesisProducerError
+	if err := producer.Flush(context.Background()); !errors.As(err, &producerErr) ||
+		len(producerErr.Records) != 1 || producerErr.Records[0].PartitionKey != "pk-0" || producerErr.Failures[0].ErrorCode != "InternalFailure" {
+		t.Fatalf("expected undelivered pk-0, got %v", err)
+	}
+
+	boom := errors.New("boom")
+	client = &producerTestClient{err: boom}
+	producer, _ = newTestKinesisProducer(t, client, KinesisProducerConfig{MaxAttempts: 3})
+	if err := producer.Put(context.Background(), producerTestRecord(0, 1)); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := producer.Flush(context.Background()); !errors.Is(err, boom) || len(client.calls) != 3 {
+		t.Fatalf("expected client error after 3 attempts, got %v (%d calls)", err, len(client.calls))
+	}
+}
+
+func TestBatchKinesisProducer_AggregatesRecords(t *testing.T) {
+	t.Parallel()
+
+	client := &producerTestClient{}
+	producer, _ := newTestKinesisProducer(t, client, KinesisProducerConfig{Aggregate: true})
+	for i := range 1200 {
+		record := producerTestRecord(i%3, 2000)
+		if i%100 == 0 {
+			record.ExplicitHashKey = "7"
+		}
+		if err := producer.Put(context.Background(), record); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	if err := producer.Flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(client.calls) != 1 || len(client.calls[0]) != 6 {
+		t.Fatalf("expected one request of six aggregated records, got %v", client.callSizes())
+	}
+
+	total := 0
+	seen := map[string]bool{}
+	for i, record := range client.calls[0] {
+		if len(record.Data) > kinesisMaxRecordDataBytes {
+			t.Fatalf("aggregated record %d: size %d exceeds the record limit", i, len(record.Data))
+		}
+		users, err := DeaggregateKinesisRecord(events.KinesisEventRecord{Kinesis: events.KinesisRecord{Data: record.Data}})
+		if err != nil || !users[0].Aggregated {
+			t.Fatalf("aggregated record %d did not round-trip: %v", i, err)
+		}
+		for _, user := range users {
+			if user.Kinesis.PartitionKey != record.PartitionKey || user.ExplicitHashKey != record.ExplicitHashKey {
+				t.Fatalf("aggregated record %d routed by %s/%s holds a record for %s/%s", i,
+					record.PartitionKey, record.ExplicitHashKey, user.Kinesis.PartitionKey, user.ExplicitHashKey)
+			}
+		}
+		key := record.PartitionKey + "/" + record.ExplicitHashKey
+		if seen[key] {
+			t.Fatalf("expected one aggregated record per key, got a second for %s", key)
+		}
+		seen[key] = true
+		total += len(users)
+	}
+	if total != 1200 {
+		t.Fatalf("expected 1200 user records, got %d", total)
+	}
+
+	if _, err := AggregateKinesisRecords(nil); err == nil {
+		t.Fatal("expected empty aggregation to fail")
+	}
+}
+
+func TestBatchKinesisProducer_FlushesBeforeDeadline(t *testing.T) {
+	t.Parallel()
+
+	client := &producerTestClient{}
+	producer, _ := newTestKinesisProducer(t, client, KinesisProducerConfig{DeadlineMargin: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := producer.Put(ctx, producerTestRecord(0, 1)); err != nil || len(client.callSizes()) != 1 {
+		t.Fatalf("expected put inside the margin to send immediately, got %v (%v)", client.callSizes(), err)
+	}
+
+	client = &producerTestClient{}
+	producer, _ = newTestKinesisProducer(t, client, KinesisProducerConfig{DeadlineMargin: 100 * time.Millisecond})
+	ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := producer.Put(ctx, producerTestRecord(0, 1)); err != nil || len(client.callSizes()) != 0 {
+		t.Fatalf("expected put to buffer, got %v (%v)", client.callSizes(), err)
+	}
+	for start := time.Now(); len(client.callSizes()) == 0; time.Sleep(5 * time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatal("expected the deadline timer to flush")
+		}
+	}
+	if err := producer.Flush(context.Background()); err != nil || len(client.callSizes()) != 1 {
+		t.Fatalf("expected nothing left to flush, got %v (%v)", client.callSizes(), err)
+	}
+}
+
+func TestBatchKinesisProducer_FlushWaitsForDeadlineFlush(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("boom")
+	client := &blockingProducerClient{started: make(chan struct{}), release: make(chan struct{}), err: boom}
+	producer, err := NewKinesisProducer(KinesisProducerConfig{StreamName: "orders", Client: client, MaxAttempts: 1, DeadlineMargin: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("new producer: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	if err := producer.Put(ctx, producerTestRecord(0, 1)); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	select {
+	case <-client.started:
+	case <-time.After(time.Second):
+		t.Fatal("expected the deadline timer to flush")
+	}
+	flushed := make(chan error, 1)
+	go func() { flushed <- producer.Flush(context.Background()) }()
+	select {
+	case err := <-flushed:
+		t.Fatalf("expected flush to wait for the deadline flush, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(client.release)
+	var producerErr *KinesisProducerError
+	if err := <-flushed; !errors.Is(err, boom) || !errors.As(err, &producerErr) || len(producerErr.Records) != 1 {
+		t.Fatalf("expected flush to return the deadline flush error, got %v", err)
+	}
+	if err := producer.Flush(context.Background()); err != nil {
+		t.Fatalf("expected the error to be returned once, got %v", err)
+	}
+}
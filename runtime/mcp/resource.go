@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
 	"sync"
 )
@@ -41,6 +42,10 @@ type ResourceContent struct {
 // ResourceHandler resolves and returns the content for a resource.
 type ResourceHandler func(ctx context.Context) ([]ResourceContent, error)
 
+// ResourceTemplateHandler resolves a URI that matches a registered resource
+// template. vars holds the decoded value of each template variable.
+type ResourceTemplateHandler func(ctx context.Context, uri string, vars map[string]string) ([]ResourceContent, error)
+
 // ResourceSubscription identifies a resource subscription request for a
 // session.
 type ResourceSubscription struct {
@@ -57,6 +62,12 @@ type registeredResource struct {
 	handler ResourceHandler
 }
 
+type resourceTemplateRoute struct {
+	pattern *regexp.Regexp
+	names   []string
+	handler ResourceTemplateHandler
+}
+
 // ResourceRegistry manages registered MCP resources.
 type ResourceRegistry struct {
 	mu            sync.RWMutex
@@ -64,6 +75,7 @@ type ResourceRegistry struct {
 	index         map[string]int
 	templates     []ResourceTemplateDef
 	templateIndex map[string]int
+	routes        []resourceTemplateRoute
 }
 
 // NewResourceRegistry creates an empty resource registry.
@@ -112,16 +124,45 @@ func (r *ResourceRegistry) RegisterResource(def ResourceDef, handler ResourceHan
 
 // RegisterResourceTemplate registers a parameterized resource template.
 func (r *ResourceRegistry) RegisterResourceTemplate(def ResourceTemplateDef) error {
+	return r.registerResourceTemplate(def, nil)
+}
+
+// RegisterResourceTemplateHandler registers a resource template whose matching
+// URIs resources/read resolves through handler. Concrete resources registered
+// with RegisterResource take precedence; otherwise templates are tried in
+// registration order.
+//
+// Templates use RFC 6570 simple ({name}) and reserved ({+name}) expansion. A
+// simple variable matches one path segment and is percent-decoded; a reserved
+// variable may span segments.
+func (r *ResourceRegistry) RegisterResourceTemplateHandler(def ResourceTemplateDef, handler ResourceTemplateHandler) error {
+	if handler == nil {
+		return fmt.Errorf("resource template handler must not be nil")
+	}
+	return r.registerResourceTemplate(def, handler)
+}
+
+func (r *ResourceRegistry) registerResourceTemplate(def ResourceTemplateDef, handler ResourceTemplateHandler) error {
 	def.URITemplate = strings.TrimSpace(def.URITemplate)
 	if def.URITemplate == "" {
 		return fmt.Errorf("resource template uriTemplate must not be empty")
 	}
-	if !validResourceURI(def.URITemplate) {
+	// Variables may sit in the authority, as in "docs://{key}", which does not
+	// parse as a URL until they are filled in.
+	if !validResourceURI(uriTemplateVariable.ReplaceAllString(def.URITemplate, "x")) {
 		return fmt.Errorf("resource template uriTemplate must be absolute: %s", def.URITemplate)
 	}
 	if def.Name == "" {
 		return fmt.Errorf("resource template name must not be empty")
 	}
+	var route resourceTemplateRoute
+	if handler != nil {
+		pattern, names, err := compileURITemplate(def.URITemplate)
+		if err != nil {
+			return err
+		}
+		route = resourceTemplateRoute{pattern: pattern, names: names, handler: handler}
+	}
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -132,6 +173,9 @@ func (r *ResourceRegistry) RegisterResourceTemplate(def ResourceTemplateDef) err
 
 	r.templateIndex[def.URITemplate] = len(r.templates)
 	r.templates = append(r.templates, def)
+	if handler != nil {
+		r.routes = append(r.routes, route)
+	}
 	return nil
 }
 
@@ -182,7 +226,13 @@ func (r *ResourceRegistry) Read(ctx context.Context, uri string) ([]ResourceCont
 	r.mu.RLock()
 	idx, ok := r.index[uri]
 	if !ok {
+		routes := r.routes
 		r.mu.RUnlock()
+		for _, route := range routes {
+			if vars, matched := route.match(uri); matched {
+				return route.handler(ctx, uri, vars)
+			}
+		}
 		return nil, fmt.Errorf("resource not found: %s", uri)
 	}
 	handler := r.resources[idx].handler
@@ -191,6 +241,80 @@ func (r *ResourceRegistry) Read(ctx context.Context, uri string) ([]ResourceCont
 	return handler(ctx)
 }
 
+func (t resourceTemplateRoute) match(uri string) (map[string]string, bool) {
+	groups := t.pattern.FindStringSubmatch(uri)
+	if groups == nil {
+		return nil, false
+	}
+	vars := make(map[string]string, len(t.names))
+	for i, name := range t.names {
+		value, err := url.PathUnescape(groups[i+1])
+		if err != nil {
+			return nil, false
+		}
+		vars[name] = value
+	}
+	return vars, true
+}
+
+var uriTemplateVariable = regexp.MustCompile(`\{(\+?)([A-Za-z0-9_]+)\}`)
+
+// compileURITemplate turns a URI template into an anchored pattern with one
+// capture group per variable, in order.
+func compileURITemplate(template string) (*regexp.Regexp, []string, error) {
+	var (
+		pattern strings.Builder
+		names   []string
+		last    int
+	)
+	pattern.WriteString("^")
+	for _, loc := range uriTemplateVariable.FindAllStringSubmatchIndex(template, -1) {
+		literal := template[last:loc[0]]
+		if strings.ContainsAny(literal, "{}") {
+			return nil, nil, fmt.Errorf("resource template uriTemplate has an unsupported expression: %s", template)
+		}
+		pattern.WriteString(regexp.QuoteMeta(literal))
+		if loc[3] > loc[2] {
+			pattern.WriteString("(.+)")
+		} else {
+			pattern.WriteString("([^/?#]+)")
+		}
+		names = append(names, template[loc[4]:loc[5]])
+		last = loc[1]
+	}
+	if strings.ContainsAny(template[last:], "{}") {
+		return nil, nil, fmt.Errorf("resource template uriTemplate has an unsupported expression: %s", template)
+	}
+	if len(names) == 0 {
+		return nil, nil, fmt.Errorf("resource template uriTemplate must contain a variable: %s", template)
+	}
+	pattern.WriteString(regexp.QuoteMeta(template[last:]))
+	pattern.WriteString("$")
+	compiled, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, nil, fmt.Errorf("resource template uriTemplate is invalid: %w", err)
+	}
+	return compiled, names, nil
+}
+
+// expandURITemplate fills a URI template compiled by compileURITemplate.
+// Simple variables are escaped as one path segment; reserved variables keep
+// their slashes.
+func expandURITemplate(template string, vars map[string]string) string {
+	return uriTemplateVariable.ReplaceAllStringFunc(template, func(expr string) string {
+		groups := uriTemplateVariable.FindStringSubmatch(expr)
+		value := vars[groups[2]]
+		if groups[1] == "" {
+			return url.PathEscape(value)
+		}
+		segments := strings.Split(value, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		return strings.Join(segments, "/")
+	})
+}
+
 func validResourceURI(uri string) bool {
 	uri = strings.TrimSpace(uri)
 	if uri == "" || strings.ContainsAny(uri, " \t\r\n") {
@@ -0,0 +1,321 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/vectorstore"
+)
+
+const (
+	// DefaultSemanticSearchToolName is the tool name RegisterSemanticSearch
+	// uses when SemanticSearchConfig.ToolName is empty.
+	DefaultSemanticSearchToolName = "search"
+	// DefaultSemanticSearchMaxTopK caps the topK argument when
+	// SemanticSearchConfig.MaxTopK is zero.
+	DefaultSemanticSearchMaxTopK = 50
+)
+
+// SemanticSearchConfig configures RegisterSemanticSearch.
+//
+// URITemplate addresses one indexed record and must contain a {key} or {+key}
+// variable, for example "docs://{+key}". FilterKeys lists the metadata keys
+// clients may filter on with equality matches; they become properties of the
+// tool's "filters" argument.
+//
+// Scope provides tenant scoping. When Index.RequiredMetadataKeys is not empty,
+// Scope is required and must return a value for every required key, usually
+// from the authenticated principal that a ToolContextHook placed on ctx. Those
+// values are ANDed into every search and checked on every resource read, so a
+// client can never see another tenant's records.
+//
+// TextMetadataKey names the metadata value served as the text/plain body of a
+// resource. When it is empty or missing on a record, the resource body is the
+// record's key and metadata as JSON.
+type SemanticSearchConfig struct {
+	Index           *vectorstore.SemanticIndex
+	ToolName        string
+	ToolTitle       string
+	ToolDescription string
+	URITemplate     string
+	ResourceName    string
+	FilterKeys      []string
+	MaxTopK         int
+	ReturnMetadata  bool
+	TextMetadataKey string
+	Scope           func(context.Context) (map[string]any, error)
+	Telemetry       ToolLifecycleTelemetry
+}
+
+type semanticSearchArgs struct {
+	Query   string         `json:"query"`
+	TopK    int            `json:"topK,omitempty"`
+	Filters map[string]any `json:"filters,omitempty"`
+}
+
+type semanticSearch struct {
+	cfg      SemanticSearchConfig
+	required []string
+	filters  map[string]bool
+}
+
+var errSemanticSearchScope = errors.New("semantic search scope is missing a required metadata value")
+
+// RegisterSemanticSearch registers a semantic search tool backed by
+// cfg.Index on tools and a resource template on resources that reads the
+// records it returns.
+//
+// The tool takes a query, an optional topK, and optional equality filters,
+// and answers with one resource_link content block per hit, closest first.
+// Its structured content lists each hit's key, URI, distance, and, when
+// ReturnMetadata is set, metadata.
+func RegisterSemanticSearch(tools *ToolRegistry, resources *ResourceRegistry, cfg SemanticSearchConfig) error {
+	search, err := newSemanticSearch(cfg)
+	if err != nil {
+		return err
+	}
+	if tools == nil || resources == nil {
+		return fmt.Errorf("semantic search requires tool and resource registries")
+	}
+	schema, err := search.inputSchema()
+	if err != nil {
+		return err
+	}
+	readOnly := true
+	def := ToolDef{
+		Name:        search.cfg.ToolName,
+		Title:       search.cfg.ToolTitle,
+		Description: search.cfg.ToolDescription,
+		Annotations: &ToolAnnotations{ReadOnlyHint: &readOnly},
+		InputSchema: schema,
+	}
+	template := ResourceTemplateDef{
+		URITemplate: search.cfg.URITemplate,
+		Name:        search.cfg.ResourceName,
+		Description: "A record returned by the " + search.cfg.ToolName + " tool.",
+	}
+	if err := resources.RegisterResourceTemplateHandler(template, search.read); err != nil {
+		return err
+	}
+	return tools.RegisterTool(def, WrapTool(ToolLifecycleOptions[semanticSearchArgs]{
+		Name:        search.cfg.ToolName,
+		StrictJSON:  true,
+		Validate:    search.validate,
+		HandleError: handleSemanticSearchError,
+		Telemetry:   search.cfg.Telemetry,
+	}, search.call))
+}
+
+func newSemanticSearch(cfg SemanticSearchConfig) (*semanticSearch, error) {
+	if cfg.Index == nil {
+		return nil, fmt.Errorf("semantic search index must not be nil")
+	}
+	cfg.URITemplate = strings.TrimSpace(cfg.URITemplate)
+	if !strings.Contains(cfg.URITemplate, "{key}") && !strings.Contains(cfg.URITemplate, "{+key}") {
+		return nil, fmt.Errorf("semantic search uriTemplate must contain {key} or {+key}: %s", cfg.URITemplate)
+	}
+	if cfg.MaxTopK < 0 || cfg.MaxTopK > vectorstore.MaxQueryTopK {
+		return nil, fmt.Errorf("semantic search maxTopK must be between 1 and %d", vectorstore.MaxQueryTopK)
+	}
+	if cfg.MaxTopK == 0 {
+		cfg.MaxTopK = DefaultSemanticSearchMaxTopK
+	}
+	if strings.TrimSpace(cfg.ToolName) == "" {
+		cfg.ToolName = DefaultSemanticSearchToolName
+	}
+	if strings.TrimSpace(cfg.ResourceName) == "" {
+		cfg.ResourceName = cfg.ToolName + "-result"
+	}
+	if cfg.ToolDescription == "" {
+		cfg.ToolDescription = "Semantic search. Returns links to the closest matching records, closest first."
+	}
+	search := &semanticSearch{cfg: cfg, required: trimmedKeys(cfg.Index.RequiredMetadataKeys), filters: map[string]bool{}}
+	if len(search.required) > 0 && cfg.Scope == nil {
+		return nil, fmt.Errorf("semantic search scope is required when the index has required metadata keys")
+	}
+	for _, key := range trimmedKeys(cfg.FilterKeys) {
+		search.filters[key] = true
+	}
+	return search, nil
+}
+
+func trimmedKeys(keys []string) []string {
+	out := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+func (s *semanticSearch) inputSchema() (json.RawMessage, error) {
+	properties := map[string]any{
+		"query": map[string]any{"type": "string", "minLength": 1, "description": "Natural-language search text."},
+		"topK":  map[string]any{"type": "integer", "minimum": 1, "maximum": s.cfg.MaxTopK, "description": "Maximum number of results."},
+	}
+	if len(s.filters) > 0 {
+		filterProperties := make(map[string]any, len(s.filters))
+		for key := range s.filters {
+			filterProperties[key] = map[string]any{"type": []string{"string", "number", "boolean"}}
+		}
+		properties["filters"] = map[string]any{
+			"type":                 "object",
+			"description":          "Metadata values results must equal.",
+			"properties":           filterProperties,
+			"additionalProperties": false,
+		}
+	}
+	return json.Marshal(map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             []string{"query"},
+		"additionalProperties": false,
+	})
+}
+
+func (s *semanticSearch) validate(_ context.Context, args semanticSearchArgs) error {
+	if strings.TrimSpace(args.Query) == "" || args.TopK < 0 || args.TopK > s.cfg.MaxTopK {
+		return errToolLifecycleValidation
+	}
+	for key, value := range args.Filters {
+		if !s.filters[key] {
+			return errToolLifecycleValidation
+		}
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			return errToolLifecycleValidation
+		}
+	}
+	return nil
+}
+
+func (s *semanticSearch) call(ctx context.Context, args semanticSearchArgs) (*ToolResult, error) {
+	where, err := s.where(ctx, args.Filters)
+	if err != nil {
+		return nil, err
+	}
+	topK := args.TopK
+	if topK == 0 {
+		topK = min(vectorstore.DefaultQueryTopK, s.cfg.MaxTopK)
+	}
+	hits, err := s.cfg.Index.QueryText(ctx, args.Query, vectorstore.QueryInput{
+		TopK:           topK,
+		Where:          where,
+		ReturnMetadata: s.cfg.ReturnMetadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+	content := make([]ContentBlock, 0, len(hits))
+	structured := make([]any, 0, len(hits))
+	for _, hit := range hits {
+		uri := expandURITemplate(s.cfg.URITemplate, map[string]string{"key": hit.Key})
+		content = append(content, ContentBlock{
+			Type:        "resource_link",
+			URI:         uri,
+			Name:        hit.Key,
+			Description: fmt.Sprintf("distance %.4f", hit.Distance),
+		})
+		item := map[string]any{"key": hit.Key, "uri": uri, "distance": hit.Distance}
+		if s.cfg.ReturnMetadata && len(hit.Metadata) > 0 {
+			item["metadata"] = hit.Metadata
+		}
+		structured = append(structured, item)
+	}
+	if len(content) == 0 {
+		content = append(content, ContentBlock{Type: "text", Text: "No results."})
+	}
+	return &ToolResult{Content: content, StructuredContent: map[string]any{"hits": structured}}, nil
+}
+
+// where ANDs the client's equality filters with the tenant scope, so filters
+// can only narrow what the scope allows.
+func (s *semanticSearch) where(ctx context.Context, filters map[string]any) (*vectorstore.Filter, error) {
+	scope, err := s.scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	clauses := make([]vectorstore.Filter, 0, len(keys)+len(s.required))
+	for _, key := range keys {
+		clauses = append(clauses, vectorstore.Eq(key, filters[key]))
+	}
+	for _, key := range s.required {
+		clauses = append(clauses, vectorstore.Eq(key, scope[key]))
+	}
+	switch len(clauses) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &clauses[0], nil
+	default:
+		where := vectorstore.And(clauses...)
+		return &where, nil
+	}
+}
+
+func (s *semanticSearch) scope(ctx context.Context) (map[string]any, error) {
+	if len(s.required) == 0 {
+		return nil, nil
+	}
+	scope, err := s.cfg.Scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range s.required {
+		if scope[key] == nil {
+			return nil, errSemanticSearchScope
+		}
+	}
+	return scope, nil
+}
+
+// read serves one record. Records outside the caller's scope read as not
+// found, the same as missing ones.
+func (s *semanticSearch) read(ctx context.Context, uri string, vars map[string]string) ([]ResourceContent, error) {
+	key := vars["key"]
+	scope, err := s.scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records, err := s.cfg.Index.Store.GetVectors(ctx, vectorstore.GetInput{Keys: []string{key}, ReturnMetadata: true})
+	if errors.Is(err, vectorstore.ErrNotFound) || errors.Is(err, vectorstore.ErrInvalidInput) || (err == nil && len(records) == 0) {
+		return nil, fmt.Errorf("resource not found: %s", uri)
+	}
+	if err != nil {
+		return nil, err
+	}
+	record := records[0]
+	for _, key := range s.required {
+		if ok, matchErr := vectorstore.Eq(key, scope[key]).Match(record.Metadata); matchErr != nil || !ok {
+			return nil, fmt.Errorf("resource not found: %s", uri)
+		}
+	}
+	if text, ok := record.Metadata[s.cfg.TextMetadataKey].(string); ok && s.cfg.TextMetadataKey != "" {
+		return []ResourceContent{{URI: uri, MimeType: "text/plain", Text: text}}, nil
+	}
+	body, err := json.Marshal(map[string]any{"key": record.Key, "metadata": record.Metadata})
+	if err != nil {
+		return nil, err
+	}
+	return []ResourceContent{{URI: uri, MimeType: "application/json", Text: string(body)}}, nil
+}
+
+// handleSemanticSearchError turns bad filter values and other invalid input
+// into a tool error result; anything else stays an internal error.
+func handleSemanticSearchError(_ context.Context, err error) (*ToolResult, bool) {
+	if !errors.Is(err, vectorstore.ErrInvalidInput) && !errors.Is(err, vectorstore.ErrInvalidFilter) {
+		return nil, false
+	}
+	return &ToolResult{IsError: true, Content: []ContentBlock{{Type: "text", Text: "Invalid search request."}}}, true
+}
@@ -0,0 +1,197 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/vectorstore"
+)
+
+type tenantContextKey struct{}
+
+// keywordEmbedder maps texts onto two axes so tests control which records are
+// closest: "alpha" texts point along x, everything else along y.
+type keywordEmbedder struct{}
+
+func (keywordEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	if strings.Contains(text, "alpha") {
+		return []float32{1, 0}, nil
+	}
+	return []float32{0, 1}, nil
+}
+
+func (e keywordEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vector, err := e.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vector)
+	}
+	return out, nil
+}
+
+func newSemanticSearchFixture(t *testing.T) (*ToolRegistry, *ResourceRegistry) {
+	t.Helper()
+	ctx := context.Background()
+	index := &vectorstore.SemanticIndex{
+		Store:                vectorstore.NewFakeStore(2),
+		Embedder:             keywordEmbedder{},
+		Dimension:            2,
+		RequiredMetadataKeys: []string{"tenant"},
+	}
+	if err := index.PutText(ctx, []vectorstore.SemanticRecord{
+		{Key: "guides/alpha", Text: "alpha guide", Metadata: map[string]any{"tenant": "t1", "kind": "guide", "text": "alpha guide"}},
+		{Key: "notes/alpha", Text: "alpha note", Metadata: map[string]any{"tenant": "t1", "kind": "note"}},
+		{Key: "other/alpha", Text: "alpha other", Metadata: map[string]any{"tenant": "t2", "kind": "guide", "text": "secret"}},
+	}); err != nil {
+		t.Fatalf("PutText: %v", err)
+	}
+
+	tools, resources := NewToolRegistry(), NewResourceRegistry()
+	err := RegisterSemanticSearch(tools, resources, SemanticSearchConfig{
+		Index:           index,
+		URITemplate:     "docs://{+key}",
+		FilterKeys:      []string{"kind"},
+		MaxTopK:         5,
+		TextMetadataKey: "text",
+		Scope: func(ctx context.Context) (map[string]any, error) {
+			tenant, ok := ctx.Value(tenantContextKey{}).(string)
+			if !ok {
+				return map[string]any{}, nil
+			}
+			return map[string]any{"tenant": tenant}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterSemanticSearch: %v", err)
+	}
+	return tools, resources
+}
+
+func TestRegisterSemanticSearch_ScopesSearchAndResources(t *testing.T) {
+	tools, resources := newSemanticSearchFixture(t)
+	ctx := context.WithValue(context.Background(), tenantContextKey{}, "t1")
+
+	defs := tools.List()
+	if len(defs) != 1 || defs[0].Name != DefaultSemanticSearchToolName || defs[0].Annotations == nil || !*defs[0].Annotations.ReadOnlyHint {
+		t.Fatalf("unexpected tool defs: %+v", defs)
+	}
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Required   []string                   `json:"required"`
+	}
+	if err := json.Unmarshal(defs[0].InputSchema, &schema); err != nil {
+		t.Fatalf("unmarshal schema: %v", err)
+	}
+	if _, ok := schema.Properties["filters"]; !ok || len(schema.Required) != 1 || !strings.Contains(string(schema.Properties["topK"]), `"maximum":5`) {
+		t.Fatalf("unexpected input schema: %s", defs[0].InputSchema)
+	}
+	if templates := resources.ListTemplates(); len(templates) != 1 || templates[0].URITemplate != "docs://{+key}" {
+		t.Fatalf("unexpected templates: %+v", templates)
+	}
+
+	result, err := tools.Call(ctx, "search", json.RawMessage(`{"query":"alpha","filters":{"kind":"guide"}}`))
+	if err != nil {
+		t.Fatalf("call search: %v", err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Type != "resource_link" || result.Content[0].URI != "docs://guides/alpha" {
+		t.Fatalf("unexpected content: %+v", result.Content)
+	}
+	hits, ok := result.StructuredContent["hits"].([]any)
+	if !ok || len(hits) != 1 {
+		t.Fatalf("unexpected structured content: %+v", result.StructuredContent)
+	}
+
+	result, err = tools.Call(ctx, "search", json.RawMessage(`{"query":"alpha"}`))
+	if err != nil || len(result.Content) != 2 {
+		t.Fatalf("unscoped filter search = %+v, %v; want both t1 records only", result, err)
+	}
+
+	contents, err := resources.Read(ctx, "docs://guides/alpha")
+	if err != nil || len(contents) != 1 || contents[0].Text != "alpha guide" || contents[0].MimeType != "text/plain" {
+		t.Fatalf("read guide = %+v, %v", contents, err)
+	}
+	contents, err = resources.Read(ctx, "docs://notes/alpha")
+	if err != nil || contents[0].MimeType != "application/json" || !strings.Contains(contents[0].Text, `"kind":"note"`) {
+		t.Fatalf("read note = %+v, %v", contents, err)
+	}
+	for _, uri := range []string{"docs://other/alpha", "docs://missing"} {
+		if _, err := resources.Read(ctx, uri); err == nil || !isNotFound(err, "resource not found:") {
+			t.Fatalf("read %s error = %v, want resource not found", uri, err)
+		}
+	}
+}
+
+func TestRegisterSemanticSearch_RejectsBadInput(t *testing.T) {
+	tools, _ := newSemanticSearchFixture(t)
+	ctx := context.WithValue(context.Background(), tenantContextKey{}, "t1")
+
+	for _, args := range []string{
+		`{"query":" "}`,
+		`{"query":"alpha","topK":6}`,
+		`{"query":"alpha","filters":{"tenant":"t2"}}`,
+		`{"query":"alpha","filters":{"kind":["guide"]}}`,
+		`{"query":"alpha","extra":true}`,
+	} {
+		if _, err := tools.Call(ctx, "search", json.RawMessage(args)); err == nil {
+			t.Fatalf("call search %s: expected invalid params", args)
+		}
+	}
+	if _, err := tools.Call(context.Background(), "search", json.RawMessage(`{"query":"alpha"}`)); err == nil {
+		t.Fatalf("call search without tenant scope: expected error")
+	}
+
+	index := &vectorstore.SemanticIndex{Store: vectorstore.NewFakeStore(2), Embedder: keywordEmbedder{}, Dimension: 2, RequiredMetadataKeys: []string{"tenant"}}
+	for _, cfg := range []SemanticSearchConfig{
+		{URITemplate: "docs://{key}"},
+		{Index: index, URITemplate: "docs://{key}"},
+		{Index: &vectorstore.SemanticIndex{}, URITemplate: "docs://static"},
+	} {
+		if err := RegisterSemanticSearch(NewToolRegistry(), NewResourceRegistry(), cfg); err == nil {
+			t.Fatalf("RegisterSemanticSearch(%+v): expected error", cfg)
+		}
+	}
+}
+
+func TestResourceRegistry_TemplateHandlers(t *testing.T) {
+	r := NewResourceRegistry()
+	if err := r.RegisterResource(ResourceDef{URI: "notes://pinned", Name: "pinned"}, func(context.Context) ([]ResourceContent, error) {
+		return []ResourceContent{{URI: "notes://pinned", Text: "static"}}, nil
+	}); err != nil {
+		t.Fatalf("register resource: %v", err)
+	}
+	err := r.RegisterResourceTemplateHandler(ResourceTemplateDef{URITemplate: "notes://{id}", Name: "note"}, func(_ context.Context, uri string, vars map[string]string) ([]ResourceContent, error) {
+		return []ResourceContent{{URI: uri, Text: "note " + vars["id"]}}, nil
+	})
+	if err != nil {
+		t.Fatalf("register template handler: %v", err)
+	}
+
+	if contents, err := r.Read(context.Background(), "notes://pinned"); err != nil || contents[0].Text != "static" {
+		t.Fatalf("read pinned = %+v, %v; want the concrete resource", contents, err)
+	}
+	if contents, err := r.Read(context.Background(), "notes://a%20b"); err != nil || contents[0].Text != "note a b" {
+		t.Fatalf("read templated = %+v, %v", contents, err)
+	}
+	if _, err := r.Read(context.Background(), "notes://a/b"); err == nil {
+		t.Fatalf("read across segments: expected not found for a simple variable")
+	}
+	if got := expandURITemplate("docs://{+key}/{name}", map[string]string{"key": "a b/c", "name": "x/y"}); got != "docs://a%20b/c/x%2Fy" {
+		t.Fatalf("expandURITemplate = %q", got)
+	}
+
+	for _, template := range []string{"notes://static", "notes://{?query}", "notes://{id"} {
+		if err := r.RegisterResourceTemplateHandler(ResourceTemplateDef{URITemplate: template, Name: "bad"}, func(context.Context, string, map[string]string) ([]ResourceContent, error) {
+			return nil, nil
+		}); err == nil {
+			t.Fatalf("register %q: expected error", template)
+		}
+	}
+	if err := r.RegisterResourceTemplateHandler(ResourceTemplateDef{URITemplate: "other://{id}", Name: "nil"}, nil); err == nil {
+		t.Fatalf("register nil handler: expected error")
+	}
+}
@@ -239,7 +239,7 @@ func (d *DynamoStreamStore) newStreamEventRecord(
 
 	spilled := false
 	if d.shouldSpillStreamData(len(payload)) {
-		ref, err := d.spillStreamData(ctx, sessionID, eventID, payload, expiresAt, record.DataSHA256)
+		ref, err := d.spillStore.put(ctx, sessionID, eventID, payload, expiresAt, record.DataSHA256)
 		if err != nil {
 			return nil, false, err
 		}
@@ -316,7 +316,7 @@ func (d *DynamoStreamStore) cleanupSpilledStreamEvent(
 	appendErr error,
 ) error {
 	if spilled && record != nil {
-		if cleanupErr := d.spillStore.delete(ctx, record.DataRef); cleanupErr != nil {
+		if cleanupErr := d.spillStore.delete(ctx, record.DataRef, record.EventID); cleanupErr != nil {
 			return errors.Join(appendErr, cleanupErr)
 		}
 	}
@@ -753,7 +753,7 @@ func (d *DynamoStreamStore) deleteRecord(ctx context.Context, record dynamoStrea
 		if d.spillStore == nil {
 			return errors.New("stream spill store not configured")
 		}
-		if err := d.spillStore.delete(ctx, record.DataRef); err != nil {
+		if err := d.spillStore.delete(ctx, record.DataRef, record.EventID); err != nil {
 			return err
 		}
 	}
@@ -769,29 +769,6 @@ func (d *DynamoStreamStore) shouldSpillStreamData(size int) bool {
 	return d.spillStore != nil && size > clampDynamoStreamSpillInlineMaxBytes(d.inlineMaxBytes)
 }
 
-func (d *DynamoStreamStore) spillStreamData(
-	ctx context.Context,
-	sessionID string,
-	eventID string,
-	payload []byte,
-	expiresAt int64,
-	sha256Hex string,
-) (string, error) {
-	if keyer, ok := d.spillStore.(interface{ objectKey(string, string) string }); ok {
-		key := keyer.objectKey(sessionID, eventID)
-		if err := d.spillStore.put(ctx, key, payload, expiresAt, sha256Hex); err != nil {
-			return "", err
-		}
-		return key, nil
-	}
-
-	key := "sessions/" + dynamoStreamPayloadSHA256([]byte(sessionID)) + "/events/" + eventID + ".json"
-	if err := d.spillStore.put(ctx, key, payload, expiresAt, sha256Hex); err != nil {
-		return "", err
-	}
-	return key, nil
-}
-
 func (d *DynamoStreamStore) streamRecordData(ctx context.Context, record dynamoStreamRecord) (json.RawMessage, error) {
 	if d.streamRecordExpired(record, d.now().UTC()) {
 		return nil, ErrEventNotFound
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/theory-cloud/apptheory/v3/pkg/objectstore"
 )
@@ -19,6 +20,7 @@ const (
 	defaultDynamoStreamMaxInlineBytes      = 350 * 1024
 	defaultDynamoStreamMaxEventBytes       = 10 * 1024 * 1024
 	defaultDynamoStreamSpillPrefix         = "mcp-stream-events"
+	defaultDynamoStreamSpillRefreshAfter   = 15 * time.Minute
 	dynamoStreamDataStorageS3              = "s3"
 	envStreamSpillBucket                   = "MCP_STREAM_SPILL_BUCKET"
 	envStreamSpillPrefix                   = "MCP_STREAM_SPILL_PREFIX"
@@ -26,10 +28,12 @@ const (
 	envStreamMaxEventBytes                 = "MCP_STREAM_MAX_EVENT_BYTES"
 )
 
+// dynamoStreamSpillStore keeps spilled event payloads. put returns the object
+// key recorded as the event's DataRef; delete releases the event's reference.
 type dynamoStreamSpillStore interface {
-	put(ctx context.Context, key string, data []byte, expiresAt int64, sha256Hex string) error
+	put(ctx context.Context, sessionID string, eventID string, data []byte, expiresAt int64, sha256Hex string) (string, error)
 	get(ctx context.Context, key string, maxBytes int) ([]byte, error)
-	delete(ctx context.Context, key string) error
+	delete(ctx context.Context, key string, eventID string) error
 }
 
 type dynamoStreamObjectSpillStore struct {
@@ -58,25 +62,33 @@ func newDynamoStreamSpillStoreFromEnv() dynamoStreamSpillStore {
 	}
 }
 
-func (s *dynamoStreamObjectSpillStore) put(ctx context.Context, key string, data []byte, expiresAt int64, sha256Hex string) error {
+// put stores data content-addressed within the session, so an event payload
+// repeated in one session is stored once. Deduplication never crosses
+// sessions, which keeps DeleteSession able to remove every spilled byte. The
+// "expires-at" metadata is renewed with the blob's LastModified, so it can lag
+// the newest sharing event by up to defaultDynamoStreamSpillRefreshAfter.
+func (s *dynamoStreamObjectSpillStore) put(ctx context.Context, sessionID string, eventID string, data []byte, expiresAt int64, sha256Hex string) (string, error) {
 	if s == nil {
-		return errors.New("stream spill store not configured")
+		return "", errors.New("stream spill store not configured")
 	}
-	store, err := s.objectStore(ctx)
+	content, err := s.contentStore(ctx, s.contentPrefix(sessionID))
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	_, err = store.Put(ctx, objectstore.PutInput{
-		Ref:         objectstore.ObjectRef{Bucket: s.bucket, Key: key},
+	out, err := content.Put(ctx, objectstore.ContentPutInput{
 		Payload:     data,
 		ContentType: "application/json",
-		Metadata: map[string]string{
-			"expires-at": strconv.FormatInt(expiresAt, 10),
-			"sha256":     sha256Hex,
-		},
+		Holder:      eventID,
+		Metadata:    map[string]string{"expires-at": strconv.FormatInt(expiresAt, 10)},
 	})
-	return err
+	if err != nil {
+		return "", err
+	}
+	if out.Digest != objectstore.ContentDigestPrefix+sha256Hex {
+		return "", errors.New("stream spill payload hash mismatch")
+	}
+	return out.Ref.Key, nil
 }
 
 func (s *dynamoStreamObjectSpillStore) get(ctx context.Context, key string, maxBytes int) ([]byte, error) {
@@ -91,14 +103,18 @@ func (s *dynamoStreamObjectSpillStore) get(ctx context.Context, key string, maxB
 	if maxBytes <= 0 {
 		maxBytes = dynamoStreamMaxEventBytes()
 	}
-	out, err := store.Get(ctx, objectstore.GetInput{
+	input := objectstore.GetInput{
 		Ref:      objectstore.ObjectRef{Bucket: s.bucket, Key: key},
 		MaxBytes: int64(maxBytes),
-	})
+	}
+	out, err := s.getObject(ctx, store, input)
 	if err != nil {
 		if errors.Is(err, objectstore.ErrObjectTooLarge) {
 			return nil, fmt.Errorf("stream spill object exceeds max event bytes")
 		}
+		if errors.Is(err, objectstore.ErrContentDigestMismatch) {
+			return nil, errors.New("stream spill payload hash mismatch")
+		}
 		return nil, err
 	}
 	payload := make([]byte, len(out.Payload))
@@ -106,7 +122,23 @@ func (s *dynamoStreamObjectSpillStore) get(ctx context.Context, key string, maxB
 	return payload, nil
 }
 
-func (s *dynamoStreamObjectSpillStore) delete(ctx context.Context, key string) error {
+// getObject verifies content-addressed keys against their digest. Events
+// spilled before content addressing keep their per-event keys.
+func (s *dynamoStreamObjectSpillStore) getObject(ctx context.Context, store objectstore.Store, input objectstore.GetInput) (*objectstore.GetOutput, error) {
+	prefix, ok := dynamoStreamContentPrefix(input.Ref.Key)
+	if !ok {
+		return store.Get(ctx, input)
+	}
+	content, err := newDynamoStreamContentStore(store, s.bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return content.Get(ctx, input)
+}
+
+// delete releases eventID's reference and removes the payload once no other
+// event in the session shares it.
+func (s *dynamoStreamObjectSpillStore) delete(ctx context.Context, key string, eventID string) error {
 	if s == nil {
 		return errors.New("stream spill store not configured")
 	}
@@ -115,7 +147,36 @@ func (s *dynamoStreamObjectSpillStore) delete(ctx context.Context, key string) e
 		return err
 	}
 
-	return store.Delete(ctx, objectstore.DeleteInput{Ref: objectstore.ObjectRef{Bucket: s.bucket, Key: key}})
+	ref := objectstore.ObjectRef{Bucket: s.bucket, Key: key}
+	prefix, ok := dynamoStreamContentPrefix(key)
+	if !ok {
+		return store.Delete(ctx, objectstore.DeleteInput{Ref: ref})
+	}
+	content, err := s.contentStore(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	_, err = content.Release(ctx, objectstore.ContentReleaseInput{Ref: ref, Holder: eventID, DeleteUnreferenced: true})
+	return err
+}
+
+func (s *dynamoStreamObjectSpillStore) contentStore(ctx context.Context, prefix string) (*objectstore.ContentStore, error) {
+	store, err := s.objectStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newDynamoStreamContentStore(store, s.bucket, prefix)
+}
+
+// newDynamoStreamContentStore refreshes a shared payload at most every
+// defaultDynamoStreamSpillRefreshAfter instead of copying it on every repeat.
+// Lifecycle rules count in days, so the lag never expires a live payload.
+func newDynamoStreamContentStore(store objectstore.Store, bucket string, prefix string) (*objectstore.ContentStore, error) {
+	return objectstore.NewContentStore(store, objectstore.ContentStoreConfig{
+		Bucket:       bucket,
+		Prefix:       prefix,
+		RefreshAfter: defaultDynamoStreamSpillRefreshAfter,
+	})
 }
 
 func (s *dynamoStreamObjectSpillStore) objectStore(ctx context.Context) (objectstore.Store, error) {
@@ -145,9 +206,8 @@ func newDynamoStreamObjectStore(ctx context.Context) (objectstore.Store, error)
 	})
 }
 
-func (s *dynamoStreamObjectSpillStore) objectKey(sessionID, eventID string) string {
-	sessionHash := dynamoStreamPayloadSHA256([]byte(sessionID))
-	name := fmt.Sprintf("sessions/%s/events/%s.json", sessionHash, eventID)
+func (s *dynamoStreamObjectSpillStore) contentPrefix(sessionID string) string {
+	name := "sessions/" + dynamoStreamPayloadSHA256([]byte(sessionID)) + "/content"
 	prefix := strings.Trim(s.prefix, "/")
 	if prefix == "" {
 		return name
@@ -155,6 +215,16 @@ func (s *dynamoStreamObjectSpillStore) objectKey(sessionID, eventID string) stri
 	return prefix + "/" + name
 }
 
+// dynamoStreamContentPrefix returns the content store prefix of a
+// content-addressed spill key, or false for a legacy per-event key.
+func dynamoStreamContentPrefix(key string) (string, bool) {
+	if _, err := objectstore.ContentDigest(objectstore.ObjectRef{Bucket: "spill", Key: key}); err != nil {
+		return "", false
+	}
+	prefix, _, ok := strings.Cut(key, "/sha256/")
+	return prefix, ok && strings.HasSuffix(prefix, "/content")
+}
+
 func dynamoStreamPayloadSHA256(data []byte) string {
 	sum := sha256.Sum256(data)
 	return hex.EncodeToString(sum[:])
@@ -23,9 +23,9 @@ func TestDynamoStreamSpillStoreFromEnvAndObjectKey(t *testing.T) {
 	require.Equal(t, "spill-bucket", store.bucket)
 	require.Equal(t, "custom/prefix", store.prefix)
 
-	key := store.objectKey("session-1", "event-1")
-	require.True(t, strings.HasPrefix(key, "custom/prefix/sessions/"))
-	require.True(t, strings.HasSuffix(key, "/events/event-1.json"))
+	prefix := store.contentPrefix("session-1")
+	require.True(t, strings.HasPrefix(prefix, "custom/prefix/sessions/"))
+	require.True(t, strings.HasSuffix(prefix, "/content"))
 
 	t.Setenv(envStreamSpillPrefix, " ")
 	store, ok = newDynamoStreamSpillStoreFromEnv().(*dynamoStreamObjectSpillStore)
@@ -44,32 +44,79 @@ func TestDynamoStreamS3SpillStorePutGetDelete(t *testing.T) {
 			return backend, nil
 		},
 	}
+	ctx := context.Background()
+	payload := []byte(`{"seq":1}`)
+	sum := dynamoStreamPayloadSHA256(payload)
 
-	err := store.put(context.Background(), "key-1", []byte(`{"seq":1}`), 123, dynamoStreamPayloadSHA256([]byte(`{"seq":1}`)))
+	key, err := store.put(ctx, "session-1", "event-1", payload, 123, sum)
 	require.NoError(t, err)
 	require.Equal(t, 1, loads)
+	require.Equal(t, store.contentPrefix("session-1")+"/sha256/"+sum[:2]+"/"+sum, key)
 
-	calls := backend.Calls()
-	require.Len(t, calls, 1)
-	require.Equal(t, objectstoretest.OperationPut, calls[0].Operation)
-	require.Equal(t, objectstore.ObjectRef{Bucket: "bucket-a", Key: "key-1"}, calls[0].Ref)
-	require.Equal(t, []byte(`{"seq":1}`), calls[0].Payload)
-	require.Equal(t, "application/json", calls[0].ContentType)
-	require.Equal(t, "123", calls[0].Metadata["expires-at"])
-	require.NotEmpty(t, calls[0].Metadata["sha256"])
+	again, err := store.put(ctx, "session-1", "event-2", payload, 123, sum)
+	require.NoError(t, err)
+	require.Equal(t, key, again, "repeated payloads in a session share one object")
+
+	var blobPuts int
+	for _, call := range backend.Calls() {
+		if call.Operation == objectstoretest.OperationPut && call.Ref.Key == key {
+			blobPuts++
+			require.Equal(t, "application/json", call.ContentType)
+			require.Equal(t, sum, call.Metadata["sha256"])
+			require.Equal(t, "123", call.Metadata["expires-at"])
+		}
+		require.NotEqual(t, objectstoretest.OperationCopy, call.Operation, "a fresh blob is not refreshed")
+	}
+	require.Equal(t, 2, blobPuts, "the duplicate put is rejected by its IfNoneMatch condition")
 
-	got, err := store.get(context.Background(), "key-1", 0)
+	got, err := store.get(ctx, key, 0)
 	require.NoError(t, err)
-	require.Equal(t, []byte(`{"seq":1}`), got)
+	require.Equal(t, payload, got)
 	require.Equal(t, 1, loads, "cached object store should be reused")
 
-	require.NoError(t, store.delete(context.Background(), "key-1"))
-	calls = backend.Calls()
-	require.Len(t, calls, 3)
-	require.Equal(t, objectstoretest.OperationGet, calls[1].Operation)
-	require.Equal(t, int64(defaultDynamoStreamMaxEventBytes), calls[1].MaxBytes)
-	require.Equal(t, objectstoretest.OperationDelete, calls[2].Operation)
-	require.Equal(t, objectstore.ObjectRef{Bucket: "bucket-a", Key: "key-1"}, calls[2].Ref)
+	require.NoError(t, store.delete(ctx, key, "event-1"))
+	_, err = backend.Head(ctx, objectstore.HeadInput{Ref: objectstore.ObjectRef{Bucket: "bucket-a", Key: key}})
+	require.NoError(t, err, "payload stays while event-2 references it")
+
+	require.NoError(t, store.delete(ctx, key, "event-2"))
+	_, err = backend.Head(ctx, objectstore.HeadInput{Ref: objectstore.ObjectRef{Bucket: "bucket-a", Key: key}})
+	require.ErrorIs(t, err, objectstore.ErrObjectNotFound)
+
+	other, err := store.put(ctx, "session-2", "event-1", payload, 123, sum)
+	require.NoError(t, err)
+	require.NotEqual(t, key, other, "deduplication never crosses sessions")
+}
+
+func TestDynamoStreamS3SpillStoreLegacyKeysAndTamper(t *testing.T) {
+	backend := objectstoretest.NewStore()
+	store := &dynamoStreamObjectSpillStore{
+		bucket: "bucket-a",
+		loadStore: func(context.Context) (objectstore.Store, error) {
+			return backend, nil
+		},
+	}
+	ctx := context.Background()
+	legacy := objectstore.ObjectRef{Bucket: "bucket-a", Key: "mcp-stream-events/sessions/abc/events/event-1.json"}
+	_, err := backend.Put(ctx, objectstore.PutInput{Ref: legacy, Payload: []byte(`{"seq":1}`)})
+	require.NoError(t, err)
+
+	got, err := store.get(ctx, legacy.Key, 0)
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"seq":1}`), got)
+	require.NoError(t, store.delete(ctx, legacy.Key, "event-1"))
+	_, err = backend.Head(ctx, objectstore.HeadInput{Ref: legacy})
+	require.ErrorIs(t, err, objectstore.ErrObjectNotFound)
+
+	sum := dynamoStreamPayloadSHA256([]byte(`{"seq":2}`))
+	key, err := store.put(ctx, "session-1", "event-2", []byte(`{"seq":2}`), 123, sum)
+	require.NoError(t, err)
+	_, err = backend.Put(ctx, objectstore.PutInput{Ref: objectstore.ObjectRef{Bucket: "bucket-a", Key: key}, Payload: []byte(`{"seq":3}`)})
+	require.NoError(t, err)
+	_, err = store.get(ctx, key, 0)
+	require.ErrorContains(t, err, "hash mismatch")
+
+	_, err = store.put(ctx, "session-1", "event-3", []byte(`{"seq":4}`), 123, sum)
+	require.ErrorContains(t, err, "hash mismatch")
 }
 
 func TestDynamoStreamS3SpillStoreGetBoundsPayload(t *testing.T) {
@@ -105,8 +152,9 @@ func TestDynamoStreamS3SpillStoreNilAndLoadErrors(t *testing.T) {
 	var nilStore *dynamoStreamObjectSpillStore
 	_, err := nilStore.get(context.Background(), "key", 0)
 	require.ErrorContains(t, err, "not configured")
-	require.ErrorContains(t, nilStore.put(context.Background(), "key", []byte("{}"), 0, ""), "not configured")
-	require.ErrorContains(t, nilStore.delete(context.Background(), "key"), "not configured")
+	_, err = nilStore.put(context.Background(), "session", "event", []byte("{}"), 0, "")
+	require.ErrorContains(t, err, "not configured")
+	require.ErrorContains(t, nilStore.delete(context.Background(), "key", "event"), "not configured")
 
 	loadErr := errors.New("load failed")
 	store := &dynamoStreamObjectSpillStore{
@@ -117,6 +165,7 @@ func TestDynamoStreamS3SpillStoreNilAndLoadErrors(t *testing.T) {
 	}
 	_, err = store.get(context.Background(), "key", 0)
 	require.ErrorIs(t, err, loadErr)
-	require.ErrorIs(t, store.put(context.Background(), "key", []byte("{}"), 0, ""), loadErr)
-	require.ErrorIs(t, store.delete(context.Background(), "key"), loadErr)
+	_, err = store.put(context.Background(), "session", "event", []byte("{}"), 123, "")
+	require.ErrorIs(t, err, loadErr)
+	require.ErrorIs(t, store.delete(context.Background(), "key", "event"), loadErr)
 }
@@ -983,7 +983,7 @@ func TestDynamoStreamStore_StreamRecordDataValidationBranches(t *testing.T) {
 
 	spill := newFakeDynamoStreamSpillStore()
 	store.spillStore = spill
-	require.NoError(t, spill.put(context.Background(), "key", []byte(`{"ok":true}`), 0, dynamoStreamPayloadSHA256([]byte(`{"ok":true}`))))
+	spill.set("key", []byte(`{"ok":true}`))
 	_, err = store.streamRecordData(context.Background(), dynamoStreamRecord{DataRef: "key", DataBytes: 1})
 	require.ErrorContains(t, err, "exceeds max event bytes")
 	_, err = store.streamRecordData(context.Background(), dynamoStreamRecord{DataRef: "key", DataBytes: int64(len(`{"ok":true}`) + 1)})
@@ -1008,15 +1008,17 @@ func TestDynamoStreamStore_SpillStreamDataS3BranchAndWaitCancel(t *testing.T) {
 		},
 	}
 
-	ref, err := store.spillStreamData(context.Background(), "sess-1", "event-1", []byte(`{"ok":true}`), 123, dynamoStreamPayloadSHA256([]byte(`{"ok":true}`)))
+	ref, err := store.spillStore.put(context.Background(), "sess-1", "event-1", []byte(`{"ok":true}`), 123, dynamoStreamPayloadSHA256([]byte(`{"ok":true}`)))
 	require.NoError(t, err)
 	require.Contains(t, ref, "prefix-a/sessions/")
 
 	calls := backend.Calls()
-	require.Len(t, calls, 1)
+	require.Len(t, calls, 2)
 	require.Equal(t, objectstoretest.OperationPut, calls[0].Operation)
-	require.Equal(t, ref, calls[0].Ref.Key)
-	require.Equal(t, "bucket-a", calls[0].Ref.Bucket)
+	require.Contains(t, calls[0].Ref.Key, "/holders/")
+	require.Equal(t, objectstoretest.OperationPut, calls[1].Operation)
+	require.Equal(t, ref, calls[1].Ref.Key)
+	require.Equal(t, "bucket-a", calls[1].Ref.Bucket)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
@@ -28,7 +28,8 @@ func newFakeDynamoStreamSpillStore() *fakeDynamoStreamSpillStore {
 	return &fakeDynamoStreamSpillStore{objects: make(map[string][]byte)}
 }
 
-func (s *fakeDynamoStreamSpillStore) put(_ context.Context, key string, data []byte, _ int64, _ string) error {
+func (s *fakeDynamoStreamSpillStore) put(_ context.Context, sessionID string, eventID string, data []byte, _ int64, _ string) (string, error) {
+	key := "sessions/" + dynamoStreamPayloadSHA256([]byte(sessionID)) + "/events/" + eventID + ".json"
 	if s.beforePut != nil {
 		s.beforePut(key)
 	}
@@ -39,7 +40,7 @@ func (s *fakeDynamoStreamSpillStore) put(_ context.Context, key string, data []b
 	payload := make([]byte, len(data))
 	copy(payload, data)
 	s.objects[key] = payload
-	return nil
+	return key, nil
 }
 
 func (s *fakeDynamoStreamSpillStore) get(_ context.Context, key string, maxBytes int) ([]byte, error) {
@@ -59,7 +60,7 @@ func (s *fakeDynamoStreamSpillStore) get(_ context.Context, key string, maxBytes
 	return out, nil
 }
 
-func (s *fakeDynamoStreamSpillStore) delete(_ context.Context, key string) error {
+func (s *fakeDynamoStreamSpillStore) delete(_ context.Context, key string, _ string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -10,6 +10,9 @@ const (
 	logLevelError              = "error"
 	eventTriggerEventBridge    = "eventbridge"
 	eventTriggerDynamoDBStream = "dynamodb_stream"
+	eventTriggerCognito        = "cognito"
+	eventTriggerAuthorizer     = "authorizer"
+	eventTriggerS3             = "s3"
 )
 
 type LogRecord struct {
@@ -182,6 +185,14 @@ func eventMetricTags(observation eventObservation, outcome string, errorCode str
 	case eventTriggerDynamoDBStream:
 		tags["event_name"] = observation.EventName
 		tags["table_name"] = observation.TableName
+	case eventTriggerCognito:
+		tags["trigger_source"] = observation.EventName
+		tags["user_pool_id"] = observation.Source
+	case eventTriggerAuthorizer:
+		tags["authorizer_type"] = observation.Source
+	case eventTriggerS3:
+		tags["bucket"] = observation.Source
+		tags["event_name"] = observation.EventName
 	}
 	return tags
 }
@@ -192,6 +203,12 @@ func eventSpanName(observation eventObservation) string {
 		return eventTriggerEventBridge + " " + observation.Source + " " + observation.DetailType
 	case eventTriggerDynamoDBStream:
 		return eventTriggerDynamoDBStream + " " + observation.TableName + " " + observation.EventName
+	case eventTriggerCognito:
+		return eventTriggerCognito + " " + observation.EventName
+	case eventTriggerAuthorizer:
+		return eventTriggerAuthorizer + " " + observation.Source
+	case eventTriggerS3:
+		return eventTriggerS3 + " " + observation.Source + " " + observation.EventName
 	default:
 		return observation.Trigger
 	}
@@ -212,6 +229,15 @@ func eventSpanAttributes(observation eventObservation, outcome string, errorCode
 		attrs["dynamodb.event_id"] = observation.EventID
 		attrs["dynamodb.event_name"] = observation.EventName
 		attrs["dynamodb.table_name"] = observation.TableName
+	case eventTriggerCognito:
+		attrs["cognito.trigger_source"] = observation.EventName
+		attrs["cognito.user_pool_id"] = observation.Source
+	case eventTriggerAuthorizer:
+		attrs["authorizer.type"] = observation.Source
+	case eventTriggerS3:
+		attrs["s3.bucket"] = observation.Source
+		attrs["s3.event_name"] = observation.EventName
+		attrs["s3.sequencer"] = observation.EventID
 	}
 	return attrs
 }
@@ -580,6 +580,9 @@ func (a *SecureApp) ServeAppSync(ctx context.Context, event AppSyncResolverEvent
 func (a *SecureApp) ServeWebSocket(ctx context.Context, event events.APIGatewayWebsocketProxyRequest) events.APIGatewayProxyResponse {
 	return a.requireCore().ServeWebSocket(ctx, event)
 }
+func (a *SecureApp) ServeCognitoTrigger(ctx context.Context, event json.RawMessage) (any, error) {
+	return a.requireCore().ServeCognitoTrigger(ctx, event)
+}
 func (a *SecureApp) ServeDynamoDBStream(ctx context.Context, event events.DynamoDBEvent) events.DynamoDBEventResponse {
 	return a.requireCore().ServeDynamoDBStream(ctx, event)
 }
@@ -589,12 +592,36 @@ func (a *SecureApp) ServeEventBridge(ctx context.Context, event events.EventBrid
 func (a *SecureApp) ServeKinesis(ctx context.Context, event events.KinesisEvent) events.KinesisEventResponse {
 	return a.requireCore().ServeKinesis(ctx, event)
 }
+func (a *SecureApp) ServeKinesisWindow(
+	ctx context.Context,
+	event events.KinesisTimeWindowEvent,
+) (events.KinesisTimeWindowEventResponse, error) {
+	return a.requireCore().ServeKinesisWindow(ctx, event)
+}
+func (a *SecureApp) ServeKafka(ctx context.Context, event events.KafkaEvent) (KafkaEventResponse, error) {
+	return a.requireCore().ServeKafka(ctx, event)
+}
+func (a *SecureApp) ServeCloudFront(ctx context.Context, event CloudFrontEvent) (any, error) {
+	return a.requireCore().ServeCloudFront(ctx, event)
+}
+func (a *SecureApp) ServeS3(ctx context.Context, event events.S3Event) error {
+	return a.requireCore().ServeS3(ctx, event)
+}
 func (a *SecureApp) ServeSNS(ctx context.Context, event events.SNSEvent) ([]any, error) {
 	return a.requireCore().ServeSNS(ctx, event)
 }
 func (a *SecureApp) ServeSQS(ctx context.Context, event events.SQSEvent) events.SQSEventResponse {
 	return a.requireCore().ServeSQS(ctx, event)
 }
+func (a *SecureApp) ServeAPIGatewayTokenAuthorizer(ctx context.Context, event events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	return a.requireCore().ServeAPIGatewayTokenAuthorizer(ctx, event)
+}
+func (a *SecureApp) ServeAPIGatewayRequestAuthorizer(ctx context.Context, event events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	return a.requireCore().ServeAPIGatewayRequestAuthorizer(ctx, event)
+}
+func (a *SecureApp) ServeAPIGatewayV2Authorizer(ctx context.Context, event events.APIGatewayV2CustomAuthorizerV2Request) (events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+	return a.requireCore().ServeAPIGatewayV2Authorizer(ctx, event)
+}
 func (a *SecureApp) HandleLambda(ctx context.Context, event json.RawMessage) (any, error) {
 	return a.requireCore().HandleLambda(ctx, event)
 }
@@ -605,6 +632,10 @@ func (a *SecureApp) SQS(queueName string, handler SQSHandler) *SecureApp {
 	a.requireCore().SQS(queueName, handler)
 	return a
 }
+func (a *SecureApp) Kafka(topic string, handler KafkaHandler, opts ...KafkaOption) *SecureApp {
+	a.requireCore().Kafka(topic, handler, opts...)
+	return a
+}
 func (a *SecureApp) SNS(topicName string, handler SNSHandler) *SecureApp {
 	a.requireCore().SNS(topicName, handler)
 	return a
@@ -613,11 +644,39 @@ func (a *SecureApp) Kinesis(streamName string, handler KinesisHandler) *SecureAp
 	a.requireCore().Kinesis(streamName, handler)
 	return a
 }
+func (a *SecureApp) KinesisTumblingWindow(streamName string, handler KinesisWindowHandler) *SecureApp {
+	a.requireCore().KinesisTumblingWindow(streamName, handler)
+	return a
+}
 func (a *SecureApp) EventBridge(selector EventBridgeSelector, handler EventBridgeHandler) *SecureApp {
 	a.requireCore().EventBridge(selector, handler)
 	return a
 }
+func (a *SecureApp) S3(bucket string, handler S3Handler, opts ...S3Option) *SecureApp {
+	a.requireCore().S3(bucket, handler, opts...)
+	return a
+}
 func (a *SecureApp) DynamoDB(tableName string, handler DynamoDBStreamHandler) *SecureApp {
 	a.requireCore().DynamoDB(tableName, handler)
 	return a
 }
+func (a *SecureApp) CognitoTrigger(triggerSource string, handler CognitoTriggerHandler) *SecureApp {
+	a.requireCore().CognitoTrigger(triggerSource, handler)
+	return a
+}
+func (a *SecureApp) Authorizer(handler AuthorizerHandler) *SecureApp {
+	a.requireCore().Authorizer(handler)
+	return a
+}
+
+// PrincipalAuthorizer registers the configured PrincipalResolver as the app's
+// API Gateway Lambda authorizer (see PrincipalAuthorizer). Backend apps can
+// then use AuthorizerContextPrincipalResolver to trust its decision.
+func (a *SecureApp) PrincipalAuthorizer() *SecureApp {
+	core := a.requireCore()
+	if core.secureResolver == nil {
+		panic("apptheory: secure app has no principal resolver")
+	}
+	core.Authorizer(PrincipalAuthorizer(core.secureResolver))
+	return a
+}
@@ -83,7 +83,11 @@ func TestSecureConstructionAndFacadeSurface(t *testing.T) {
 		"ServeLambdaFunctionURL", "ServeAppSync", "ServeWebSocket",
 		"ServeDynamoDBStream", "ServeEventBridge", "ServeKinesis", "ServeSNS",
 		"ServeSQS", "HandleLambda", "Use", "UseEvents", "IsLambda", "SQS",
-		"SNS", "Kinesis", "EventBridge", "DynamoDB",
+		"SNS", "Kinesis", "EventBridge", "DynamoDB", "Authorizer",
+		"ServeAPIGatewayTokenAuthorizer", "ServeAPIGatewayRequestAuthorizer",
+		"ServeAPIGatewayV2Authorizer", "Kafka", "ServeKafka",
+		"ServeCloudFront", "KinesisTumblingWindow", "ServeKinesisWindow",
+		"S3", "ServeS3", "CognitoTrigger", "ServeCognitoTrigger",
 	} {
 		if _, ok := typeOf.MethodByName(name); !ok {
 			t.Errorf("SecureApp missing forwarded method %s", name)
@@ -153,9 +153,19 @@ type serveOptions struct {
 	errorResponder    requestErrorResponder
 	fallbackRequestID string
 	surface           SecureRouteSurface
+	// fallback, when set, serves requests that match no route.
+	fallback *route
 }
 
 func (a *App) matchRoute(method, path string, opts serveOptions) (*routeMatch, []string) {
+	match, allowed := a.matchRegisteredRoute(method, path, opts)
+	if match == nil && opts.fallback != nil {
+		return &routeMatch{Route: *opts.fallback}, nil
+	}
+	return match, allowed
+}
+
+func (a *App) matchRegisteredRoute(method, path string, opts serveOptions) (*routeMatch, []string) {
 	if a != nil && a.secure {
 		surface := opts.surface
 		if surface == "" {
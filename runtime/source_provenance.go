@@ -9,6 +9,7 @@ const (
 	sourceProvenanceProviderAPIGatewayV2  = "apigw-v2"
 	sourceProvenanceProviderLambdaURL     = "lambda-url"
 	sourceProvenanceProviderAPIGatewayV1  = "apigw-v1"
+	sourceProvenanceProviderCloudFront    = "cloudfront"
 	sourceProvenanceProviderUnknown       = "unknown"
 	sourceProvenanceSourceProviderContext = "provider_request_context"
 	sourceProvenanceSourceUnknown         = "unknown"
@@ -84,7 +85,8 @@ func knownSourceProvenanceProvider(provider string) bool {
 	switch provider {
 	case sourceProvenanceProviderAPIGatewayV2,
 		sourceProvenanceProviderLambdaURL,
-		sourceProvenanceProviderAPIGatewayV1:
+		sourceProvenanceProviderAPIGatewayV1,
+		sourceProvenanceProviderCloudFront:
 		return true
 	default:
 		return false
@@ -0,0 +1,76 @@
+package testkit
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	apptheory "github.com/theory-cloud/apptheory/v3/runtime"
+)
+
+const defaultAuthorizerARNPrefix = "arn:aws:execute-api:us-east-1:123456789012:api-1/"
+
+// APIGatewayTokenAuthorizerRequest builds a REST API TOKEN authorizer event.
+// An empty methodARN defaults to a GET on path "/" in stage "dev".
+func APIGatewayTokenAuthorizerRequest(token, methodARN string) events.APIGatewayCustomAuthorizerRequest {
+	if strings.TrimSpace(methodARN) == "" {
+		methodARN = defaultAuthorizerARNPrefix + "dev/GET/"
+	}
+	return events.APIGatewayCustomAuthorizerRequest{
+		Type:               "TOKEN",
+		AuthorizationToken: token,
+		MethodArn:          methodARN,
+	}
+}
+
+// APIGatewayV2AuthorizerRequest builds an HTTP API REQUEST authorizer event
+// (payload format 2.0) for the given method and path.
+func APIGatewayV2AuthorizerRequest(method, path string, opts HTTPEventOptions) events.APIGatewayV2CustomAuthorizerV2Request {
+	request := APIGatewayV2Request(method, path, opts)
+	method = request.RequestContext.HTTP.Method
+	request.RequestContext.Stage = "$default"
+	request.RequestContext.Authorizer = nil
+	return events.APIGatewayV2CustomAuthorizerV2Request{
+		Version:               "2.0",
+		Type:                  "REQUEST",
+		RouteArn:              defaultAuthorizerARNPrefix + "$default/" + method + request.RawPath,
+		RouteKey:              method + " " + request.RawPath,
+		RawPath:               request.RawPath,
+		RawQueryString:        request.RawQueryString,
+		Cookies:               request.Cookies,
+		Headers:               request.Headers,
+		QueryStringParameters: request.QueryStringParameters,
+		RequestContext:        request.RequestContext,
+	}
+}
+
+func cloneAuthorizerContext(in map[string]any) map[string]any {
+	out := make(map[string]any, len(in))
+	for key, value := range in {
+		out[key] = value
+	}
+	return out
+}
+
+func (e *Env) InvokeAPIGatewayTokenAuthorizer(
+	ctx context.Context,
+	app *apptheory.App,
+	event events.APIGatewayCustomAuthorizerRequest,
+) (events.APIGatewayCustomAuthorizerResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return app.ServeAPIGatewayTokenAuthorizer(ctx, event)
+}
+
+func (e *Env) InvokeAPIGatewayV2Authorizer(
+	ctx context.Context,
+	app *apptheory.App,
+	event events.APIGatewayV2CustomAuthorizerV2Request,
+) (events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return app.ServeAPIGatewayV2Authorizer(ctx, event)
+}
@@ -0,0 +1,53 @@
+package testkit_test
+
+import (
+	"context"
+	"testing"
+
+	apptheory "github.com/theory-cloud/apptheory/v3/runtime"
+	"github.com/theory-cloud/apptheory/v3/testkit"
+)
+
+func TestInvokeAPIGatewayAuthorizers(t *testing.T) {
+	env := testkit.New()
+	app := env.App()
+	app.Authorizer(func(ctx *apptheory.Context) (apptheory.AuthorizerResult, error) {
+		if ctx.Header("authorization") != "Bearer ok" {
+			return apptheory.AuthorizerResult{}, nil
+		}
+		return apptheory.AuthorizerResult{Allow: true, PrincipalID: "u1", Context: map[string]any{"sub": "u1"}}, nil
+	})
+
+	token, err := env.InvokeAPIGatewayTokenAuthorizer(context.TODO(), app, testkit.APIGatewayTokenAuthorizerRequest("Bearer ok", ""))
+	if err != nil || token.PrincipalID != "u1" || token.PolicyDocument.Statement[0].Effect != "Allow" {
+		t.Fatalf("unexpected token authorizer response %#v (%v)", token, err)
+	}
+
+	event := testkit.APIGatewayV2AuthorizerRequest("get", "/pets/1?x=1", testkit.HTTPEventOptions{
+		Headers: map[string]string{"authorization": "Bearer ok"},
+	})
+	if event.RouteKey != "GET /pets/1" || event.RawQueryString != "x=1" {
+		t.Fatalf("unexpected authorizer event %#v", event)
+	}
+	simple, err := env.InvokeAPIGatewayV2Authorizer(context.TODO(), app, event)
+	if err != nil || !simple.IsAuthorized || simple.Context["sub"] != "u1" {
+		t.Fatalf("unexpected simple response %#v (%v)", simple, err)
+	}
+}
+
+func TestAPIGatewayV2Request_AuthorizerContext(t *testing.T) {
+	env := testkit.New()
+	app := env.App()
+	app.Get("/me", func(ctx *apptheory.Context) (*apptheory.Response, error) {
+		sub, ok := ctx.AuthorizerContext()["sub"].(string)
+		if !ok {
+			return apptheory.Text(401, ""), nil
+		}
+		return apptheory.Text(200, sub), nil
+	})
+
+	event := testkit.APIGatewayV2Request("GET", "/me", testkit.HTTPEventOptions{AuthorizerContext: map[string]any{"sub": "u1"}})
+	if resp := env.InvokeAPIGatewayV2(context.TODO(), app, event); resp.StatusCode != 200 || resp.Body != "u1" {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode, resp.Body)
+	}
+}
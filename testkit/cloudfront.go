@@ -0,0 +1,73 @@
+package testkit
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strings"
+
+	apptheory "github.com/theory-cloud/apptheory/v3/runtime"
+)
+
+// CloudFrontEvent builds a Lambda@Edge event of the given type (for example
+// apptheory.CloudFrontViewerRequest) for method and path. Response events
+// carry an empty 200 origin response. The body, if any, is included as a
+// read-only base64 body.
+func CloudFrontEvent(eventType, method, path string, opts HTTPEventOptions) apptheory.CloudFrontEvent {
+	rawPath, rawQuery := splitPathAndQuery(path, opts.Query)
+
+	headers := apptheory.CloudFrontHeaders{}
+	names := make([]string, 0, len(opts.Headers))
+	for name := range opts.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		headers.Add(http.CanonicalHeaderKey(name), opts.Headers[name])
+	}
+	for name, values := range opts.MultiHeaders {
+		for _, value := range values {
+			headers.Add(http.CanonicalHeaderKey(name), value)
+		}
+	}
+	if len(opts.Cookies) > 0 {
+		headers.Set("Cookie", strings.Join(opts.Cookies, "; "))
+	}
+
+	request := apptheory.CloudFrontRequest{
+		ClientIP:    opts.SourceIP,
+		Method:      strings.ToUpper(strings.TrimSpace(method)),
+		URI:         rawPath,
+		QueryString: rawQuery,
+		Headers:     headers,
+	}
+	if len(opts.Body) > 0 {
+		request.Body = &apptheory.CloudFrontRequestBody{
+			Action:   "read-only",
+			Encoding: "base64",
+			Data:     base64.StdEncoding.EncodeToString(opts.Body),
+		}
+	}
+
+	record := apptheory.CloudFrontRecord{
+		Config: apptheory.CloudFrontConfig{
+			DistributionDomainName: "d111111abcdef8.cloudfront.net",
+			DistributionID:         "EDFDVBD6EXAMPLE",
+			EventType:              eventType,
+			RequestID:              "cloudfront-request-1",
+		},
+		Request: request,
+	}
+	if eventType == apptheory.CloudFrontOriginResponse || eventType == apptheory.CloudFrontViewerResponse {
+		record.Response = &apptheory.CloudFrontResponse{Status: "200", StatusDescription: "OK", Headers: apptheory.CloudFrontHeaders{}}
+	}
+	return apptheory.CloudFrontEvent{Records: []apptheory.CloudFrontEventRecord{{CF: record}}}
+}
+
+func (e *Env) InvokeCloudFront(ctx context.Context, app *apptheory.App, event apptheory.CloudFrontEvent) (any, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return app.ServeCloudFront(ctx, event)
+}
@@ -0,0 +1,43 @@
+package testkit_test
+
+import (
+	"context"
+	"testing"
+
+	apptheory "github.com/theory-cloud/apptheory/v3/runtime"
+	"github.com/theory-cloud/apptheory/v3/testkit"
+)
+
+func TestInvokeCloudFront(t *testing.T) {
+	env := testkit.New()
+	app := env.App()
+	app.Get("/account", func(ctx *apptheory.Context) (*apptheory.Response, error) {
+		if ctx.Header("cookie") == "" {
+			return &apptheory.Response{Status: 302, Headers: map[string][]string{"location": {"/login"}}}, nil
+		}
+		cf := ctx.AsCloudFront()
+		cf.Request.Headers.Set("X-Session", ctx.Query("tab"))
+		return cf.Forward()
+	})
+
+	out, err := env.InvokeCloudFront(context.TODO(), app, testkit.CloudFrontEvent(apptheory.CloudFrontViewerRequest, "get", "/account?tab=1", testkit.HTTPEventOptions{
+		Cookies:  []string{"session=abc"},
+		SourceIP: "198.51.100.7",
+	}))
+	request, ok := out.(*apptheory.CloudFrontRequest)
+	if err != nil || !ok || request.Headers.Get("x-session") != "1" || request.Headers.Get("cookie") != "session=abc" {
+		t.Fatalf("unexpected forwarded request %#v (%v)", out, err)
+	}
+
+	out, err = env.InvokeCloudFront(context.TODO(), app, testkit.CloudFrontEvent(apptheory.CloudFrontViewerRequest, "GET", "/account", testkit.HTTPEventOptions{}))
+	response, ok := out.(apptheory.CloudFrontResponse)
+	if err != nil || !ok || response.Status != "302" || response.Headers.Get("location") != "/login" {
+		t.Fatalf("unexpected generated response %#v (%v)", out, err)
+	}
+
+	event := testkit.CloudFrontEvent(apptheory.CloudFrontOriginResponse, "GET", "/other", testkit.HTTPEventOptions{})
+	out, err = env.InvokeCloudFront(context.TODO(), app, event)
+	if passed, ok := out.(*apptheory.CloudFrontResponse); err != nil || !ok || passed.Status != "200" {
+		t.Fatalf("expected origin response to pass through, got %#v (%v)", out, err)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -304,6 +306,80 @@ func KinesisEvent(opts KinesisEventOptions) events.KinesisEvent {
 	return out
 }
 
+type KafkaEventOptions struct {
+	// EventSource defaults to "aws:kafka" (Amazon MSK); use "SelfManagedKafka"
+	// for self-managed clusters.
+	EventSource      string
+	EventSourceARN   string
+	BootstrapServers string
+	Records          []KafkaRecordOptions
+}
+
+type KafkaRecordOptions struct {
+	Topic     string
+	Partition int64
+	// Offset defaults to the record's position within its partition.
+	Offset    *int64
+	Timestamp time.Time
+	Key       []byte
+	Value     []byte
+	Headers   []apptheory.KafkaHeader
+}
+
+// KafkaEvent builds a Lambda Kafka event, base64-encoding keys and values and
+// grouping records by "<topic>-<partition>" as the event source mapping does.
+func KafkaEvent(opts KafkaEventOptions) events.KafkaEvent {
+	source := strings.TrimSpace(opts.EventSource)
+	if source == "" {
+		source = "aws:kafka"
+	}
+	arn := strings.TrimSpace(opts.EventSourceARN)
+	if arn == "" && source == "aws:kafka" {
+		arn = "arn:aws:kafka:us-east-1:000000000000:cluster/apptheory/abc-1"
+	}
+	servers := strings.TrimSpace(opts.BootstrapServers)
+	if servers == "" {
+		servers = "b-1.apptheory.kafka.us-east-1.amazonaws.com:9092"
+	}
+
+	out := events.KafkaEvent{
+		EventSource:      source,
+		EventSourceARN:   arn,
+		BootstrapServers: servers,
+		Records:          map[string][]events.KafkaRecord{},
+	}
+	for _, rec := range opts.Records {
+		partitionKey := fmt.Sprintf("%s-%d", rec.Topic, rec.Partition)
+		offset := int64(len(out.Records[partitionKey]))
+		if rec.Offset != nil {
+			offset = *rec.Offset
+		}
+		timestamp := rec.Timestamp
+		if timestamp.IsZero() {
+			timestamp = time.Unix(0, 0).UTC()
+		}
+		headers := make([]map[string]events.JSONNumberBytes, 0, len(rec.Headers))
+		for _, header := range rec.Headers {
+			headers = append(headers, map[string]events.JSONNumberBytes{header.Key: append([]byte(nil), header.Value...)})
+		}
+
+		record := events.KafkaRecord{
+			Topic:         rec.Topic,
+			Partition:     rec.Partition,
+			Offset:        offset,
+			Timestamp:     events.MilliSecondsEpochTime{Time: timestamp},
+			TimestampType: "CREATE_TIME",
+			Value:         base64.StdEncoding.EncodeToString(rec.Value),
+			Headers:       headers,
+		}
+		if rec.Key != nil {
+			record.Key = base64.StdEncoding.EncodeToString(rec.Key)
+		}
+		out.Records[partitionKey] = append(out.Records[partitionKey], record)
+	}
+	return out
+}
+
 type SNSEventOptions struct {
 	TopicARN string
 	Records  []SNSRecordOptions
@@ -344,6 +420,172 @@ func SNSEvent(opts SNSEventOptions) events.SNSEvent {
 	return out
 }
 
+type S3EventOptions struct {
+	Bucket  string
+	Region  string
+	Records []S3RecordOptions
+}
+
+type S3RecordOptions struct {
+	Bucket    string
+	Key       string
+	EventName string
+	Size      int64
+	ETag      string
+	VersionID string
+}
+
+// S3Event builds a direct S3 notification. Keys are given decoded and are
+// URL-encoded the way S3 encodes them in notifications.
+func S3Event(opts S3EventOptions) events.S3Event {
+	region := strings.TrimSpace(opts.Region)
+	if region == "" {
+		region = "us-east-1"
+	}
+	out := events.S3Event{Records: make([]events.S3EventRecord, 0, len(opts.Records))}
+	for _, rec := range opts.Records {
+		bucket := strings.TrimSpace(rec.Bucket)
+		if bucket == "" {
+			bucket = strings.TrimSpace(opts.Bucket)
+		}
+		eventName := strings.TrimSpace(rec.EventName)
+		if eventName == "" {
+			eventName = "ObjectCreated:Put"
+		}
+		out.Records = append(out.Records, events.S3EventRecord{
+			EventVersion: "2.1",
+			EventSource:  "aws:s3",
+			AWSRegion:    region,
+			EventTime:    time.Unix(0, 0).UTC(),
+			EventName:    eventName,
+			S3: events.S3Entity{
+				SchemaVersion: "1.0",
+				Bucket: events.S3Bucket{
+					Name: bucket,
+					Arn:  "arn:aws:s3:::" + bucket,
+				},
+				Object: events.S3Object{
+					Key:           s3NotificationKey(rec.Key),
+					URLDecodedKey: rec.Key,
+					Size:          rec.Size,
+					ETag:          rec.ETag,
+					VersionID:     rec.VersionID,
+					Sequencer:     fmt.Sprintf("%016X", len(out.Records)+1),
+				},
+			},
+		})
+	}
+	return out
+}
+
+// s3NotificationKey encodes key like S3 does in notifications: form encoding
+// per path segment, with "/" left as-is.
+func s3NotificationKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.QueryEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+type S3EventBridgeEventOptions struct {
+	Bucket     string
+	Key        string
+	DetailType string
+	Reason     string
+	Size       int64
+	ETag       string
+	VersionID  string
+}
+
+// S3EventBridgeEvent builds an aws.s3 EventBridge object event. DetailType
+// defaults to "Object Created" and Reason to "PutObject".
+func S3EventBridgeEvent(opts S3EventBridgeEventOptions) events.EventBridgeEvent {
+	detailType := strings.TrimSpace(opts.DetailType)
+	if detailType == "" {
+		detailType = "Object Created"
+	}
+	reason := strings.TrimSpace(opts.Reason)
+	if reason == "" && detailType == "Object Created" {
+		reason = "PutObject"
+	}
+	bucket := strings.TrimSpace(opts.Bucket)
+
+	object := map[string]any{"key": opts.Key, "size": opts.Size, "etag": opts.ETag, "sequencer": "0000000000000001"}
+	if opts.VersionID != "" {
+		object["version-id"] = opts.VersionID
+	}
+	detail := map[string]any{
+		"version": "0",
+		"bucket":  map[string]any{"name": bucket},
+		"object":  object,
+		"reason":  reason,
+	}
+	if detailType == "Object Deleted" {
+		detail["deletion-type"] = "Permanently Deleted"
+	}
+
+	return EventBridgeEvent(EventBridgeEventOptions{
+		Source:     "aws.s3",
+		DetailType: detailType,
+		Resources:  []string{"arn:aws:s3:::" + bucket},
+		Detail:     detail,
+	})
+}
+
+type CognitoTriggerEventOptions struct {
+	TriggerSource  string
+	UserPoolID     string
+	UserName       string
+	ClientID       string
+	Region         string
+	UserAttributes map[string]string
+	Request        map[string]any
+	Response       map[string]any
+}
+
+// CognitoTriggerEvent builds a raw Cognito User Pools trigger event.
+// TriggerSource defaults to "PreSignUp_SignUp". UserAttributes is merged into
+// Request as "userAttributes".
+func CognitoTriggerEvent(opts CognitoTriggerEventOptions) json.RawMessage {
+	triggerSource := strings.TrimSpace(opts.TriggerSource)
+	if triggerSource == "" {
+		triggerSource = "PreSignUp_SignUp"
+	}
+	region := strings.TrimSpace(opts.Region)
+	if region == "" {
+		region = "us-east-1"
+	}
+	userPoolID := strings.TrimSpace(opts.UserPoolID)
+	if userPoolID == "" {
+		userPoolID = region + "_testpool"
+	}
+	clientID := strings.TrimSpace(opts.ClientID)
+	if clientID == "" {
+		clientID = "test-client"
+	}
+
+	request := cloneAppSyncAnyMap(opts.Request)
+	if len(opts.UserAttributes) > 0 {
+		request["userAttributes"] = cloneHeaderMap(opts.UserAttributes)
+	}
+	event := map[string]any{
+		"version":       "1",
+		"triggerSource": triggerSource,
+		"region":        region,
+		"userPoolId":    userPoolID,
+		"userName":      strings.TrimSpace(opts.UserName),
+		"callerContext": map[string]any{"awsSdkVersion": "aws-sdk-unknown-unknown", "clientId": clientID},
+		"request":       request,
+		"response":      cloneAppSyncAnyMap(opts.Response),
+	}
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return raw
+}
+
 type AppSyncEventOptions struct {
 	FieldName      string
 	ParentTypeName string
@@ -434,6 +676,13 @@ func (e *Env) InvokeKinesis(
 	return app.ServeKinesis(ctx, event)
 }
 
+func (e *Env) InvokeKafka(ctx context.Context, app *apptheory.App, event events.KafkaEvent) (apptheory.KafkaEventResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return app.ServeKafka(ctx, event)
+}
+
 func (e *Env) InvokeSNS(ctx context.Context, app *apptheory.App, event events.SNSEvent) ([]any, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -441,6 +690,20 @@ func (e *Env) InvokeSNS(ctx context.Context, app *apptheory.App, event events.SN
 	return app.ServeSNS(ctx, event)
 }
 
+func (e *Env) InvokeS3(ctx context.Context, app *apptheory.App, event events.S3Event) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return app.ServeS3(ctx, event)
+}
+
+func (e *Env) InvokeCognitoTrigger(ctx context.Context, app *apptheory.App, event json.RawMessage) (any, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return app.ServeCognitoTrigger(ctx, event)
+}
+
 func (e *Env) InvokeAppSync(ctx context.Context, app *apptheory.App, event apptheory.AppSyncResolverEvent) any {
 	if ctx == nil {
 		ctx = context.Background()
@@ -161,6 +161,105 @@ func TestSNSEvent_Defaults(t *testing.T) {
 	}
 }
 
+func TestS3Event_EncodesKeysAndRoundTripsThroughApp(t *testing.T) {
+	out := S3Event(S3EventOptions{
+		Bucket:  "uploads",
+		Records: []S3RecordOptions{{Key: "in/Q1 report+final.csv", Size: 3}},
+	})
+	if len(out.Records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(out.Records))
+	}
+	rec := out.Records[0]
+	if rec.EventSource != "aws:s3" || rec.EventName != "ObjectCreated:Put" || rec.S3.Bucket.Name != "uploads" {
+		t.Fatalf("unexpected s3 record: %#v", rec)
+	}
+	if rec.S3.Object.Key != "in/Q1+report%2Bfinal.csv" {
+		t.Fatalf("unexpected encoded key: %q", rec.S3.Object.Key)
+	}
+
+	var keys []string
+	app := apptheory.New()
+	app.S3("uploads", func(_ *apptheory.EventContext, rec apptheory.S3EventRecord) error {
+		keys = append(keys, rec.Key)
+		return nil
+	}, apptheory.S3KeySuffix(".csv"))
+
+	env := New()
+	if err := env.InvokeS3(context.Background(), app, out); err != nil {
+		t.Fatalf("InvokeS3: %v", err)
+	}
+	ebEvent := S3EventBridgeEvent(S3EventBridgeEventOptions{Bucket: "uploads", Key: "in/b c.csv"})
+	if _, err := env.InvokeEventBridge(context.Background(), app, ebEvent); err != nil {
+		t.Fatalf("InvokeEventBridge: %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "in/Q1 report+final.csv" || keys[1] != "in/b c.csv" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	deleted := S3EventBridgeEvent(S3EventBridgeEventOptions{Bucket: "uploads", Key: "k", DetailType: "Object Deleted"})
+	rec2, ok := apptheory.S3EventRecordFromEventBridge(deleted)
+	if !ok || rec2.EventName != "ObjectRemoved:Delete" {
+		t.Fatalf("unexpected deleted record: %#v", rec2)
+	}
+}
+
+func TestKafkaEvent_GroupsByPartitionAndRoundTripsThroughApp(t *testing.T) {
+	explicit := int64(40)
+	out := KafkaEvent(KafkaEventOptions{Records: []KafkaRecordOptions{
+		{Topic: "orders", Partition: 0, Key: []byte("a"), Value: []byte("one")},
+		{Topic: "orders", Partition: 0, Value: []byte("two"), Headers: []apptheory.KafkaHeader{{Key: "h", Value: []byte("v")}}},
+		{Topic: "orders", Partition: 1, Offset: &explicit, Value: []byte("three")},
+	}})
+	if out.EventSource != "aws:kafka" || out.EventSourceARN == "" || out.BootstrapServers == "" {
+		t.Fatalf("unexpected kafka defaults: %#v", out)
+	}
+	if len(out.Records["orders-0"]) != 2 || out.Records["orders-0"][1].Offset != 1 || out.Records["orders-1"][0].Offset != 40 {
+		t.Fatalf("unexpected kafka partitions: %#v", out.Records)
+	}
+	if self := KafkaEvent(KafkaEventOptions{EventSource: "SelfManagedKafka"}); self.EventSourceARN != "" {
+		t.Fatalf("expected no ARN for self-managed kafka, got %q", self.EventSourceARN)
+	}
+
+	var values []string
+	app := apptheory.New()
+	app.Kafka("orders", func(_ *apptheory.EventContext, rec apptheory.KafkaRecord) error {
+		values = append(values, string(rec.Value))
+		if header, ok := rec.Header("h"); ok && string(header) != "v" {
+			t.Fatalf("unexpected header %q", header)
+		}
+		return nil
+	})
+	resp, err := New().InvokeKafka(context.Background(), app, out)
+	if err != nil || len(resp.BatchItemFailures) != 0 || len(values) != 3 || values[0] != "one" || values[2] != "three" {
+		t.Fatalf("unexpected kafka result %#v %v", resp, values)
+	}
+}
+
+func TestCognitoTriggerEvent_DecodesThroughTypedHandler(t *testing.T) {
+	raw := CognitoTriggerEvent(CognitoTriggerEventOptions{
+		UserName:       "alice",
+		UserAttributes: map[string]string{"email": "alice@example.com"},
+	})
+
+	app := apptheory.New()
+	app.CognitoTrigger("PreSignUp_*", apptheory.CognitoTriggerFunc(func(_ *apptheory.EventContext, ev events.CognitoEventUserPoolsPreSignup) (events.CognitoEventUserPoolsPreSignup, error) {
+		if ev.UserPoolID != "us-east-1_testpool" || ev.CallerContext.ClientID != "test-client" || ev.UserName != "alice" {
+			t.Fatalf("unexpected cognito header: %#v", ev.CognitoEventUserPoolsHeader)
+		}
+		ev.Response.AutoVerifyEmail = ev.Request.UserAttributes["email"] != ""
+		return ev, nil
+	}))
+
+	out, err := New().InvokeCognitoTrigger(context.Background(), app, raw)
+	if err != nil {
+		t.Fatalf("InvokeCognitoTrigger: %v", err)
+	}
+	resp, ok := out.(events.CognitoEventUserPoolsPreSignup)
+	if !ok || !resp.Response.AutoVerifyEmail || resp.TriggerSource != "PreSignUp_SignUp" {
+		t.Fatalf("unexpected response: %#v", out)
+	}
+}
+
 func TestAppSyncEvent_Defaults(t *testing.T) {
 	out := AppSyncEvent(AppSyncEventOptions{
 		Arguments: map[string]any{"id": "thing_123"},
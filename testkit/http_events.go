@@ -20,6 +20,9 @@ type HTTPEventOptions struct {
 	Body         []byte
 	IsBase64     bool
 	SourceIP     string
+	// AuthorizerContext is attached as the Lambda authorizer context of
+	// API Gateway v2 requests, as if set by an upstream authorizer.
+	AuthorizerContext map[string]any
 }
 
 func APIGatewayV2Request(method, path string, opts HTTPEventOptions) events.APIGatewayV2HTTPRequest {
@@ -38,7 +41,7 @@ func APIGatewayV2Request(method, path string, opts HTTPEventOptions) events.APIG
 		}
 	}
 
-	return events.APIGatewayV2HTTPRequest{
+	event := events.APIGatewayV2HTTPRequest{
 		Version:        "2.0",
 		RouteKey:       "$default",
 		RawPath:        rawPath,
@@ -61,6 +64,12 @@ func APIGatewayV2Request(method, path string, opts HTTPEventOptions) events.APIG
 		Body:            body,
 		IsBase64Encoded: opts.IsBase64,
 	}
+	if opts.AuthorizerContext != nil {
+		event.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+			Lambda: cloneAuthorizerContext(opts.AuthorizerContext),
+		}
+	}
+	return event
 }
 
 func LambdaFunctionURLRequest(method, path string, opts HTTPEventOptions) events.LambdaFunctionURLRequest {
@@ -0,0 +1,192 @@
+// Package jobs provides deterministic test doubles for AppTheory job-ledger code.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/jobs"
+)
+
+// ErrNotSupported is returned by the FakeLedger semaphore methods.
+var ErrNotSupported = errors.New("testkit/jobs: operation is not supported by FakeLedger")
+
+// FakeLedger is an in-memory jobs.JobLedger for tests.
+//
+// It keeps jobs, record statuses, leases, and idempotency records, and reports
+// conflicts as *jobs.Error values of type jobs.ErrorTypeConflict the way
+// DynamoJobLedger does. Leases never expire. Semaphores are not supported.
+type FakeLedger struct {
+	mu       sync.Mutex
+	jobs     map[string]*jobs.JobMeta
+	leases   map[string]string
+	records  map[ledgerKey]jobs.RecordStatus
+	requests map[ledgerKey]*jobs.JobRequest
+}
+
+var _ jobs.JobLedger = (*FakeLedger)(nil)
+
+type ledgerKey struct {
+	jobID string
+	id    string
+}
+
+// NewLedger creates an empty FakeLedger.
+func NewLedger() *FakeLedger {
+	return &FakeLedger{
+		jobs:     make(map[string]*jobs.JobMeta),
+		leases:   make(map[string]string),
+		records:  make(map[ledgerKey]jobs.RecordStatus),
+		requests: make(map[ledgerKey]*jobs.JobRequest),
+	}
+}
+
+// Job returns a copy of the job's metadata.
+func (l *FakeLedger) Job(jobID string) (jobs.JobMeta, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	meta, ok := l.jobs[jobID]
+	if !ok {
+		return jobs.JobMeta{}, false
+	}
+	return *meta, true
+}
+
+// RecordStatus returns the status last written for a record, or "" if none was.
+func (l *FakeLedger) RecordStatus(jobID, recordID string) jobs.RecordStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.records[ledgerKey{jobID: jobID, id: recordID}]
+}
+
+// LeaseOwner returns the owner holding the job's lease, or "" if it is free.
+func (l *FakeLedger) LeaseOwner(jobID string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.leases[jobID]
+}
+
+// SetLeaseOwner hands the job's lease to owner. Passing "" releases it.
+func (l *FakeLedger) SetLeaseOwner(jobID, owner string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if owner == "" {
+		delete(l.leases, jobID)
+		return
+	}
+	l.leases[jobID] = owner
+}
+
+func (l *FakeLedger) CreateJob(_ context.Context, in jobs.CreateJobInput) (*jobs.JobMeta, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.jobs[in.JobID]; ok {
+		return nil, jobs.NewError(jobs.ErrorTypeConflict, "job already exists")
+	}
+	meta := jobs.NewJobMeta(in.JobID)
+	meta.TenantID, meta.Status, meta.Version = in.TenantID, in.Status, 1
+	l.jobs[in.JobID] = &meta
+	out := meta
+	return &out, nil
+}
+
+func (l *FakeLedger) TransitionJobStatus(_ context.Context, in jobs.TransitionJobStatusInput) (*jobs.JobMeta, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	meta, ok := l.jobs[in.JobID]
+	if !ok || meta.Version != in.ExpectedVersion || (in.FromStatus != "" && meta.Status != in.FromStatus) {
+		return nil, jobs.NewError(jobs.ErrorTypeConflict, "job status transition conflict")
+	}
+	meta.Status = in.ToStatus
+	meta.Version++
+	out := *meta
+	return &out, nil
+}
+
+func (l *FakeLedger) UpsertRecordStatus(_ context.Context, in jobs.UpsertRecordStatusInput) (*jobs.JobRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records[ledgerKey{jobID: in.JobID, id: in.RecordID}] = in.Status
+	record := jobs.NewJobRecord(in.JobID, in.RecordID)
+	record.Status = in.Status
+	return &record, nil
+}
+
+func (l *FakeLedger) AcquireLease(_ context.Context, in jobs.AcquireLeaseInput) (*jobs.JobLock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if owner, ok := l.leases[in.JobID]; ok && owner != in.Owner {
+		return nil, jobs.NewError(jobs.ErrorTypeConflict, "lease already held")
+	}
+	l.leases[in.JobID] = in.Owner
+	lock := jobs.NewJobLock(in.JobID)
+	return &lock, nil
+}
+
+func (l *FakeLedger) RefreshLease(_ context.Context, in jobs.RefreshLeaseInput) (*jobs.JobLock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.leases[in.JobID] != in.Owner {
+		return nil, jobs.NewError(jobs.ErrorTypeConflict, "lease refresh conflict")
+	}
+	lock := jobs.NewJobLock(in.JobID)
+	return &lock, nil
+}
+
+func (l *FakeLedger) ReleaseLease(_ context.Context, in jobs.ReleaseLeaseInput) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if owner, ok := l.leases[in.JobID]; ok && owner != in.Owner {
+		return jobs.NewError(jobs.ErrorTypeConflict, "lease not owned")
+	}
+	delete(l.leases, in.JobID)
+	return nil
+}
+
+func (l *FakeLedger) AcquireSemaphoreSlot(context.Context, jobs.AcquireSemaphoreSlotInput) (*jobs.SemaphoreLease, error) {
+	return nil, ErrNotSupported
+}
+
+func (l *FakeLedger) RefreshSemaphoreSlot(context.Context, jobs.RefreshSemaphoreSlotInput) (*jobs.SemaphoreLease, error) {
+	return nil, ErrNotSupported
+}
+
+func (l *FakeLedger) ReleaseSemaphoreSlot(context.Context, jobs.ReleaseSemaphoreSlotInput) error {
+	return ErrNotSupported
+}
+
+func (l *FakeLedger) InspectSemaphore(context.Context, jobs.InspectSemaphoreInput) (*jobs.SemaphoreInspection, error) {
+	return nil, ErrNotSupported
+}
+
+func (l *FakeLedger) CreateIdempotencyRecord(_ context.Context, in jobs.CreateIdempotencyRecordInput) (*jobs.JobRequest, jobs.IdempotencyCreateOutcome, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := ledgerKey{jobID: in.JobID, id: in.IdempotencyKey}
+	if existing, ok := l.requests[key]; ok {
+		out := *existing
+		if existing.Status == jobs.IdempotencyStatusCompleted {
+			return &out, jobs.IdempotencyOutcomeAlreadyCompleted, nil
+		}
+		return &out, jobs.IdempotencyOutcomeAlreadyInProgress, nil
+	}
+	request := jobs.NewJobRequest(in.JobID, in.IdempotencyKey)
+	request.Status = jobs.IdempotencyStatusInProgress
+	l.requests[key] = &request
+	out := request
+	return &out, jobs.IdempotencyOutcomeCreated, nil
+}
+
+func (l *FakeLedger) CompleteIdempotencyRecord(_ context.Context, in jobs.CompleteIdempotencyRecordInput) (*jobs.JobRequest, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	request, ok := l.requests[ledgerKey{jobID: in.JobID, id: in.IdempotencyKey}]
+	if !ok {
+		return nil, jobs.NewError(jobs.ErrorTypeNotFound, "idempotency record not found")
+	}
+	request.Status = jobs.IdempotencyStatusCompleted
+	request.Result = jobs.SanitizeFields(in.Result)
+	out := *request
+	return &out, nil
+}
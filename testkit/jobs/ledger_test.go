@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/theory-cloud/apptheory/v3/pkg/jobs"
+)
+
+func TestFakeLedgerJobsLeasesAndIdempotency(t *testing.T) {
+	ctx := context.Background()
+	ledger := NewLedger()
+
+	if _, err := ledger.CreateJob(ctx, jobs.CreateJobInput{JobID: "j1", TenantID: "t1", Status: jobs.JobStatusRunning}); err != nil {
+		t.Fatalf("CreateJob() error = %v", err)
+	}
+	if _, err := ledger.CreateJob(ctx, jobs.CreateJobInput{JobID: "j1", TenantID: "t1"}); !jobs.IsConflict(err) {
+		t.Fatalf("duplicate CreateJob() error = %v, want conflict", err)
+	}
+	if _, err := ledger.TransitionJobStatus(ctx, jobs.TransitionJobStatusInput{JobID: "j1", ExpectedVersion: 2, ToStatus: jobs.JobStatusSucceeded}); !jobs.IsConflict(err) {
+		t.Fatalf("stale TransitionJobStatus() error = %v, want conflict", err)
+	}
+	if _, err := ledger.TransitionJobStatus(ctx, jobs.TransitionJobStatusInput{JobID: "j1", ExpectedVersion: 1, ToStatus: jobs.JobStatusSucceeded}); err != nil {
+		t.Fatalf("TransitionJobStatus() error = %v", err)
+	}
+	if job, ok := ledger.Job("j1"); !ok || job.Status != jobs.JobStatusSucceeded || job.Version != 2 {
+		t.Fatalf("Job() = %+v, %v", job, ok)
+	}
+
+	if _, err := ledger.AcquireLease(ctx, jobs.AcquireLeaseInput{JobID: "j1", Owner: "a"}); err != nil {
+		t.Fatalf("AcquireLease() error = %v", err)
+	}
+	if _, err := ledger.AcquireLease(ctx, jobs.AcquireLeaseInput{JobID: "j1", Owner: "b"}); !jobs.IsConflict(err) {
+		t.Fatalf("held AcquireLease() error = %v, want conflict", err)
+	}
+	if err := ledger.ReleaseLease(ctx, jobs.ReleaseLeaseInput{JobID: "j1", Owner: "a"}); err != nil || ledger.LeaseOwner("j1") != "" {
+		t.Fatalf("ReleaseLease() error = %v, owner %q", err, ledger.LeaseOwner("j1"))
+	}
+
+	if _, err := ledger.UpsertRecordStatus(ctx, jobs.UpsertRecordStatusInput{JobID: "j1", RecordID: "r1", Status: jobs.RecordStatusFailed}); err != nil {
+		t.Fatalf("UpsertRecordStatus() error = %v", err)
+	}
+	if ledger.RecordStatus("j1", "r1") != jobs.RecordStatusFailed || ledger.RecordStatus("j2", "r1") != "" {
+		t.Fatalf("RecordStatus() is not scoped to its job")
+	}
+
+	if _, outcome, err := ledger.CreateIdempotencyRecord(ctx, jobs.CreateIdempotencyRecordInput{JobID: "j1", IdempotencyKey: "k"}); err != nil || outcome != jobs.IdempotencyOutcomeCreated {
+		t.Fatalf("CreateIdempotencyRecord() = %s, %v", outcome, err)
+	}
+	if _, err := ledger.CompleteIdempotencyRecord(ctx, jobs.CompleteIdempotencyRecordInput{JobID: "j1", IdempotencyKey: "k", Result: map[string]any{"cursor": "c"}}); err != nil {
+		t.Fatalf("CompleteIdempotencyRecord() error = %v", err)
+	}
+	request, outcome, err := ledger.CreateIdempotencyRecord(ctx, jobs.CreateIdempotencyRecordInput{JobID: "j1", IdempotencyKey: "k"})
+	if err != nil || outcome != jobs.IdempotencyOutcomeAlreadyCompleted || request.Result["cursor"] != "c" {
+		t.Fatalf("completed CreateIdempotencyRecord() = %+v, %s, %v", request, outcome, err)
+	}
+
+	if _, err := ledger.AcquireSemaphoreSlot(ctx, jobs.AcquireSemaphoreSlotInput{}); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("AcquireSemaphoreSlot() error = %v, want ErrNotSupported", err)
+	}
+}
@@ -0,0 +1,255 @@
+package testkit
+
+import (
+	"context"
+	"crypto/md5" // #nosec G501 -- the KPL aggregation format checksums records with MD5.
+	"encoding/binary"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	apptheory "github.com/theory-cloud/apptheory/v3/runtime"
+)
+
+var kplAggregationMagic = []byte{0xF3, 0x89, 0x9A, 0xC2}
+
+// KinesisUserRecordOptions is one user record of a KPL aggregated Kinesis
+// record.
+type KinesisUserRecordOptions struct {
+	PartitionKey    string
+	ExplicitHashKey string
+	Data            []byte
+}
+
+// KinesisAggregatedData encodes user records in the Kinesis Producer Library
+// aggregation format, for use as KinesisRecordOptions.Data.
+func KinesisAggregatedData(records []KinesisUserRecordOptions) []byte {
+	var partitionKeys, explicitHashKeys []string
+	keyIndex := func(table *[]string, key string) uint64 {
+		index := slices.Index(*table, key)
+		if index < 0 {
+			index = len(*table)
+			*table = append(*table, key)
+		}
+		return uint64(index)
+	}
+
+	var body []byte
+	for _, record := range records {
+		var encoded []byte
+		encoded = appendProtoVarint(encoded, 1, keyIndex(&partitionKeys, record.PartitionKey))
+		if record.ExplicitHashKey != "" {
+			encoded = appendProtoVarint(encoded, 2, keyIndex(&explicitHashKeys, record.ExplicitHashKey))
+		}
+		encoded = appendProtoBytes(encoded, 3, record.Data)
+		body = appendProtoBytes(body, 3, encoded)
+	}
+
+	var tables []byte
+	for _, key := range partitionKeys {
+		tables = appendProtoBytes(tables, 1, []byte(key))
+	}
+	for _, key := range explicitHashKeys {
+		tables = appendProtoBytes(tables, 2, []byte(key))
+	}
+	body = append(tables, body...)
+
+	digest := md5.Sum(body) // #nosec G401 -- integrity checksum mandated by the KPL aggregation format.
+	out := append([]byte(nil), kplAggregationMagic...)
+	out = append(out, body...)
+	return append(out, digest[:]...)
+}
+
+func appendProtoVarint(buf []byte, field, value uint64) []byte {
+	buf = binary.AppendUvarint(buf, field<<3)
+	return binary.AppendUvarint(buf, value)
+}
+
+func appendProtoBytes(buf []byte, field uint64, payload []byte) []byte {
+	buf = binary.AppendUvarint(buf, field<<3|2)
+	buf = binary.AppendUvarint(buf, uint64(len(payload)))
+	return append(buf, payload...)
+}
+
+// KinesisPutRecordsCall is one PutRecords request recorded by FakeKinesisClient.
+type KinesisPutRecordsCall struct {
+	StreamName string
+	Records    []apptheory.KinesisJSONRecord
+}
+
+// FakeKinesisClient is an in-memory apptheory.KinesisPutRecordsClient.
+type FakeKinesisClient struct {
+	mu sync.Mutex
+
+	Calls []KinesisPutRecordsCall
+	// Delivered holds the accepted records in order, with KPL aggregated
+	// records de-aggregated.
+	Delivered []apptheory.KinesisJSONRecord
+
+	// PutRecordsErr fails every request.
+	PutRecordsErr error
+	// FailRecord returns the error code for record index of call (counted
+	// from 1), or "" to accept it.
+	FailRecord func(call, index int) string
+}
+
+var _ apptheory.KinesisPutRecordsClient = (*FakeKinesisClient)(nil)
+
+func NewFakeKinesisClient() *FakeKinesisClient {
+	return &FakeKinesisClient{}
+}
+
+func (f *FakeKinesisClient) PutRecords(
+	_ context.Context,
+	streamName string,
+	records []apptheory.KinesisJSONRecord,
+) ([]apptheory.KinesisPutRecordsResultRecord, error) {
+	f.mu.Lock()
+	f.Calls = append(f.Calls, KinesisPutRecordsCall{
+		StreamName: streamName,
+		Records:    append([]apptheory.KinesisJSONRecord(nil), records...),
+	})
+	defer f.mu.Unlock()
+
+	if f.PutRecordsErr != nil {
+		return nil, f.PutRecordsErr
+	}
+	call := len(f.Calls)
+	results := make([]apptheory.KinesisPutRecordsResultRecord, len(records))
+	for i, record := range records {
+		if f.FailRecord != nil {
+			if code := f.FailRecord(call, i); code != "" {
+				results[i] = apptheory.KinesisPutRecordsResultRecord{ErrorCode: code, ErrorMessage: "testkit: injected failure"}
+				continue
+			}
+		}
+		results[i] = apptheory.KinesisPutRecordsResultRecord{
+			SequenceNumber: strconv.Itoa(call) + "-" + strconv.Itoa(i),
+			ShardID:        "shardId-000000000000",
+		}
+		f.Delivered = append(f.Delivered, deliveredKinesisRecords(record)...)
+	}
+	return results, nil
+}
+
+func deliveredKinesisRecords(record apptheory.KinesisJSONRecord) []apptheory.KinesisJSONRecord {
+	users, err := apptheory.DeaggregateKinesisRecord(events.KinesisEventRecord{
+		Kinesis: events.KinesisRecord{PartitionKey: record.PartitionKey, Data: record.Data},
+	})
+	if err != nil || (len(users) == 1 && !users[0].Aggregated) {
+		return []apptheory.KinesisJSONRecord{record}
+	}
+	out := make([]apptheory.KinesisJSONRecord, 0, len(users))
+	for _, user := range users {
+		out = append(out, apptheory.KinesisJSONRecord{
+			PartitionKey:    user.Kinesis.PartitionKey,
+			ExplicitHashKey: user.ExplicitHashKey,
+			Data:            user.Kinesis.Data,
+		})
+	}
+	return out
+}
+
+// FakeKinesisProducer is an in-memory apptheory.KinesisProducer that keeps
+// put records until Flush.
+type FakeKinesisProducer struct {
+	mu sync.Mutex
+
+	Pending []apptheory.KinesisJSONRecord
+	Flushed []apptheory.KinesisJSONRecord
+	Flushes int
+
+	PutErr   error
+	FlushErr error
+}
+
+var _ apptheory.KinesisProducer = (*FakeKinesisProducer)(nil)
+
+func NewFakeKinesisProducer() *FakeKinesisProducer {
+	return &FakeKinesisProducer{}
+}
+
+func (f *FakeKinesisProducer) Put(_ context.Context, record apptheory.KinesisJSONRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.PutErr != nil {
+		return f.PutErr
+	}
+	f.Pending = append(f.Pending, record)
+	return nil
+}
+
+func (f *FakeKinesisProducer) Flush(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Flushes++
+	if f.FlushErr != nil {
+		return f.FlushErr
+	}
+	f.Flushed = append(f.Flushed, f.Pending...)
+	f.Pending = nil
+	return nil
+}
+
+type KinesisWindowEventOptions struct {
+	KinesisEventOptions
+	// Start defaults to the Unix epoch and End to one minute after Start.
+	Start                   time.Time
+	End                     time.Time
+	ShardID                 string
+	State                   map[string]string
+	IsFinalInvokeForWindow  bool
+	IsWindowTerminatedEarly bool
+}
+
+// KinesisWindowEvent builds a Kinesis tumbling window invocation. Its event
+// source ARN is StreamARN.
+func KinesisWindowEvent(opts KinesisWindowEventOptions) events.KinesisTimeWindowEvent {
+	start := opts.Start
+	if start.IsZero() {
+		start = time.Unix(0, 0).UTC()
+	}
+	end := opts.End
+	if end.IsZero() {
+		end = start.Add(time.Minute)
+	}
+	shardID := strings.TrimSpace(opts.ShardID)
+	if shardID == "" {
+		shardID = "shardId-000000000000"
+	}
+
+	state := make(map[string]string, len(opts.State))
+	for key, value := range opts.State {
+		state[key] = value
+	}
+
+	return events.KinesisTimeWindowEvent{
+		KinesisEvent: KinesisEvent(opts.KinesisEventOptions),
+		TimeWindowProperties: events.TimeWindowProperties{
+			Window: events.Window{
+				Start: events.RFC3339EpochTime{Time: start},
+				End:   events.RFC3339EpochTime{Time: end},
+			},
+			State:                   state,
+			ShardID:                 shardID,
+			EventSourceARN:          strings.TrimSpace(opts.StreamARN),
+			IsFinalInvokeForWindow:  opts.IsFinalInvokeForWindow,
+			IsWindowTerminatedEarly: opts.IsWindowTerminatedEarly,
+		},
+	}
+}
+
+func (e *Env) InvokeKinesisWindow(
+	ctx context.Context,
+	app *apptheory.App,
+	event events.KinesisTimeWindowEvent,
+) (events.KinesisTimeWindowEventResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return app.ServeKinesisWindow(ctx, event)
+}
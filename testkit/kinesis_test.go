@@ -0,0 +1,106 @@
+package testkit_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	apptheory "github.com/theory-cloud/apptheory/v3/runtime"
+	"github.com/theory-cloud/apptheory/v3/testkit"
+)
+
+func TestInvokeKinesisWindow_AggregatedRecords(t *testing.T) {
+	env := testkit.New()
+	app := env.App()
+	var keys []string
+	app.KinesisTumblingWindow("clicks", func(_ *apptheory.EventContext, window apptheory.KinesisWindow) (map[string]string, error) {
+		for _, record := range window.Records {
+			keys = append(keys, record.Kinesis.PartitionKey+"/"+record.ExplicitHashKey+"/"+strconv.Itoa(record.SubSequenceNumber))
+		}
+		window.State["records"] = strconv.Itoa(len(window.Records))
+		return window.State, nil
+	})
+
+	data := testkit.KinesisAggregatedData([]testkit.KinesisUserRecordOptions{
+		{PartitionKey: "a", Data: []byte("1")},
+		{PartitionKey: "b", ExplicitHashKey: "7", Data: []byte("2")},
+		{PartitionKey: "a", Data: []byte("3")},
+	})
+	resp, err := env.InvokeKinesisWindow(context.TODO(), app, testkit.KinesisWindowEvent(testkit.KinesisWindowEventOptions{
+		KinesisEventOptions: testkit.KinesisEventOptions{
+			StreamARN: "arn:aws:kinesis:us-east-1:000000000000:stream/clicks",
+			Records:   []testkit.KinesisRecordOptions{{Data: data}},
+		},
+		State: map[string]string{"records": "0"},
+	}))
+
+	if err != nil || resp.State["records"] != "3" || len(resp.BatchItemFailures) != 0 {
+		t.Fatalf("unexpected window response %#v (%v)", resp, err)
+	}
+	if len(keys) != 3 || keys[0] != "a//0" || keys[1] != "b/7/1" || keys[2] != "a//2" {
+		t.Fatalf("unexpected user records %v", keys)
+	}
+
+	users, err := apptheory.DeaggregateKinesisRecord(events.KinesisEventRecord{Kinesis: events.KinesisRecord{Data: data}})
+	if err != nil || len(users) != 3 || string(users[2].Kinesis.Data) != "3" {
+		t.Fatalf("unexpected de-aggregation %#v (%v)", users, err)
+	}
+}
+
+func TestFakeKinesisClient_WithProducer(t *testing.T) {
+	client := testkit.NewFakeKinesisClient()
+	client.FailRecord = func(call, index int) string {
+		if call == 1 && index == 0 {
+			return "ProvisionedThroughputExceededException"
+		}
+		return ""
+	}
+	producer, err := apptheory.NewKinesisProducer(apptheory.KinesisProducerConfig{
+		StreamName:     "orders",
+		Client:         client,
+		Aggregate:      true,
+		RetryBaseDelay: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("new producer: %v", err)
+	}
+
+	for _, key := range []string{"a", "b", "a"} {
+		if err := producer.Put(context.TODO(), apptheory.KinesisJSONRecord{PartitionKey: key, Data: []byte(key)}); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	if err := producer.Flush(context.TODO()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(client.Calls) != 2 || client.Calls[1].StreamName != "orders" || len(client.Calls[1].Records) != 1 || len(client.Delivered) != 3 ||
+		client.Delivered[0].PartitionKey != "b" || client.Delivered[1].PartitionKey != "a" || client.Delivered[2].PartitionKey != "a" {
+		t.Fatalf("unexpected calls %#v / delivered %#v", client.Calls, client.Delivered)
+	}
+}
+
+func TestFakeKinesisProducer(t *testing.T) {
+	var producer apptheory.KinesisProducer = testkit.NewFakeKinesisProducer()
+	fake, ok := producer.(*testkit.FakeKinesisProducer)
+	if !ok {
+		t.Fatal("expected fake producer")
+	}
+	if err := producer.Put(context.TODO(), apptheory.KinesisJSONRecord{PartitionKey: "a", Data: []byte("1")}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if len(fake.Pending) != 1 || len(fake.Flushed) != 0 {
+		t.Fatalf("expected record to stay pending, got %#v", fake)
+	}
+	if err := producer.Flush(context.TODO()); err != nil || len(fake.Flushed) != 1 || len(fake.Pending) != 0 || fake.Flushes != 1 {
+		t.Fatalf("unexpected flush %#v (%v)", fake, err)
+	}
+
+	fake.FlushErr = errors.New("boom")
+	if err := producer.Flush(context.TODO()); err == nil {
+		t.Fatal("expected injected flush error")
+	}
+}
@@ -2,10 +2,17 @@
 package objectstore
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	store "github.com/theory-cloud/apptheory/v3/pkg/objectstore"
 )
@@ -20,22 +27,43 @@ const (
 	OperationGet Operation = "Get"
 	// OperationDelete records a Store.Delete call.
 	OperationDelete Operation = "Delete"
+	// OperationPutReader records a Store.PutReader call.
+	OperationPutReader Operation = "PutReader"
+	// OperationGetReader records a Store.GetReader call.
+	OperationGetReader Operation = "GetReader"
+	// OperationHead records a Store.Head call.
+	OperationHead Operation = "Head"
+	// OperationList records a Store.List call.
+	OperationList Operation = "List"
+	// OperationCopy records a Store.Copy call.
+	OperationCopy Operation = "Copy"
 )
 
+// fakeEpoch anchors FakeStore's deterministic LastModified clock.
+var fakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
 // Call is one recorded FakeStore operation.
+//
+// Copy records the destination in Ref and the source in Source. List records
+// the bucket in Ref.Bucket and the requested prefix in Prefix.
 type Call struct {
 	Operation   Operation
 	Ref         store.ObjectRef
+	Source      store.ObjectRef
+	Prefix      string
 	MaxBytes    int64
 	ContentType string
 	Metadata    map[string]string
 	Payload     []byte
+	Condition   store.WriteCondition
 }
 
 // FakeStore is an in-memory Store for tests.
 //
 // FakeStore records calls in order, injects per-operation failures, and copies
-// all byte slices and metadata maps at its boundary.
+// all byte slices and metadata maps at its boundary. Every write creates a new
+// version; ETags are content digests and LastModified advances one second per
+// write so assertions stay deterministic.
 type FakeStore struct {
 	mu       sync.Mutex
 	seq      int64
@@ -45,7 +73,10 @@ type FakeStore struct {
 	failures map[Operation]error
 }
 
-var _ store.Store = (*FakeStore)(nil)
+var (
+	_ store.Store      = (*FakeStore)(nil)
+	_ store.ETagPutter = (*FakeStore)(nil)
+)
 
 type objectName struct {
 	bucket string
@@ -58,10 +89,12 @@ type objectVersion struct {
 }
 
 type storedObject struct {
-	ref         store.ObjectRef
-	payload     []byte
-	contentType string
-	metadata    map[string]string
+	ref          store.ObjectRef
+	payload      []byte
+	contentType  string
+	metadata     map[string]string
+	etag         string
+	lastModified time.Time
 }
 
 // NewStore creates an empty FakeStore.
@@ -99,12 +132,9 @@ func (s *FakeStore) Calls() []Call {
 
 // Put stores a payload copy and returns a version-aware reference.
 func (s *FakeStore) Put(_ context.Context, input store.PutInput) (store.ObjectRef, error) {
-	if err := input.Ref.Validate(); err != nil {
+	if err := validateWrite(input.Ref, input.Condition); err != nil {
 		return store.ObjectRef{}, err
 	}
-	if input.Ref.VersionID != "" {
-		return store.ObjectRef{}, store.ErrInvalidObjectRef
-	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -115,23 +145,52 @@ func (s *FakeStore) Put(_ context.Context, input store.PutInput) (store.ObjectRe
 		Payload:     cloneBytes(input.Payload),
 		ContentType: input.ContentType,
 		Metadata:    cloneMetadata(input.Metadata),
+		Condition:   input.Condition,
 	})
 	if err := s.failureLocked(OperationPut); err != nil {
 		return store.ObjectRef{}, err
 	}
+	return s.writeLocked(input.Ref, cloneBytes(input.Payload), input.ContentType, input.Metadata, input.Condition)
+}
 
-	s.seq++
-	ref := input.Ref
-	ref.VersionID = fmt.Sprintf("v%020d", s.seq)
-	name := objectName{bucket: ref.Bucket, key: ref.Key}
-	s.latest[name] = ref.VersionID
-	s.objects[objectVersion{name: name, version: ref.VersionID}] = storedObject{
-		ref:         ref,
-		payload:     cloneBytes(input.Payload),
-		contentType: input.ContentType,
-		metadata:    cloneMetadata(input.Metadata),
+// PutETag stores like Put and also returns the new object's ETag.
+func (s *FakeStore) PutETag(ctx context.Context, input store.PutInput) (store.ObjectRef, string, error) {
+	ref, err := s.Put(ctx, input)
+	if err != nil {
+		return store.ObjectRef{}, "", err
 	}
-	return ref, nil
+	sum := sha256.Sum256(input.Payload)
+	return ref, `"` + hex.EncodeToString(sum[:]) + `"`, nil
+}
+
+// PutReader reads Body fully, then stores it like Put.
+func (s *FakeStore) PutReader(_ context.Context, input store.PutReaderInput) (store.ObjectRef, error) {
+	if err := validateWrite(input.Ref, input.Condition); err != nil {
+		return store.ObjectRef{}, err
+	}
+	if input.Body == nil || (input.PartSize != 0 && input.PartSize < store.MinMultipartPartSize) {
+		return store.ObjectRef{}, store.ErrInvalidStreamInput
+	}
+	payload, err := io.ReadAll(input.Body)
+	if err != nil {
+		return store.ObjectRef{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLocked()
+	s.recordLocked(Call{
+		Operation:   OperationPutReader,
+		Ref:         input.Ref,
+		Payload:     payload,
+		ContentType: input.ContentType,
+		Metadata:    cloneMetadata(input.Metadata),
+		Condition:   input.Condition,
+	})
+	if err := s.failureLocked(OperationPutReader); err != nil {
+		return store.ObjectRef{}, err
+	}
+	return s.writeLocked(input.Ref, payload, input.ContentType, input.Metadata, input.Condition)
 }
 
 // Get returns a bounded payload copy. MaxBytes must be positive.
@@ -163,9 +222,173 @@ func (s *FakeStore) Get(_ context.Context, input store.GetInput) (*store.GetOutp
 		Payload:     cloneBytes(obj.payload),
 		ContentType: obj.contentType,
 		Metadata:    cloneMetadata(obj.metadata),
+		ETag:        obj.etag,
+	}, nil
+}
+
+// GetReader returns a reader over a payload copy. MaxBytes must be positive.
+func (s *FakeStore) GetReader(_ context.Context, input store.GetInput) (*store.GetReaderOutput, error) {
+	if err := input.Ref.Validate(); err != nil {
+		return nil, err
+	}
+	if input.MaxBytes <= 0 {
+		return nil, store.ErrInvalidGetLimit
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLocked()
+	s.recordLocked(Call{Operation: OperationGetReader, Ref: input.Ref, MaxBytes: input.MaxBytes})
+	if err := s.failureLocked(OperationGetReader); err != nil {
+		return nil, err
+	}
+
+	obj, ok := s.objectLocked(input.Ref)
+	if !ok {
+		return nil, store.ErrObjectNotFound
+	}
+	if int64(len(obj.payload)) > input.MaxBytes {
+		return nil, store.ErrObjectTooLarge
+	}
+	return &store.GetReaderOutput{
+		Info: obj.info(),
+		Body: io.NopCloser(bytes.NewReader(cloneBytes(obj.payload))),
 	}, nil
 }
 
+// Head returns the attributes of the referenced object.
+func (s *FakeStore) Head(_ context.Context, input store.HeadInput) (*store.ObjectInfo, error) {
+	if err := input.Ref.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLocked()
+	s.recordLocked(Call{Operation: OperationHead, Ref: input.Ref})
+	if err := s.failureLocked(OperationHead); err != nil {
+		return nil, err
+	}
+
+	obj, ok := s.objectLocked(input.Ref)
+	if !ok {
+		return nil, store.ErrObjectNotFound
+	}
+	info := obj.info()
+	return &info, nil
+}
+
+// List pages through the latest version of each key with S3 ListObjectsV2
+// prefix, delimiter, and continuation semantics.
+func (s *FakeStore) List(_ context.Context, input store.ListInput) (*store.ListOutput, error) {
+	startAfter, maxKeys, err := validateListInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLocked()
+	s.recordLocked(Call{Operation: OperationList, Ref: store.ObjectRef{Bucket: input.Bucket}, Prefix: input.Prefix})
+	if err := s.failureLocked(OperationList); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(s.latest))
+	for name := range s.latest {
+		if name.bucket == input.Bucket && strings.HasPrefix(name.key, input.Prefix) {
+			keys = append(keys, name.key)
+		}
+	}
+	sort.Strings(keys)
+
+	out := &store.ListOutput{}
+	last := ""
+	for _, key := range keys {
+		entry := key
+		if input.Delimiter != "" {
+			if idx := strings.Index(key[len(input.Prefix):], input.Delimiter); idx >= 0 {
+				entry = key[:len(input.Prefix)+idx+len(input.Delimiter)]
+			}
+		}
+		if entry <= startAfter || entry == last {
+			continue
+		}
+		if len(out.Objects)+len(out.CommonPrefixes) == maxKeys {
+			out.IsTruncated = true
+			out.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(last))
+			break
+		}
+		last = entry
+		if entry != key {
+			out.CommonPrefixes = append(out.CommonPrefixes, entry)
+			continue
+		}
+		obj, _ := s.objectLocked(store.ObjectRef{Bucket: input.Bucket, Key: key})
+		info := obj.info()
+		info.ContentType = ""
+		info.Metadata = nil
+		out.Objects = append(out.Objects, info)
+	}
+	return out, nil
+}
+
+// validateListInput returns the key to list after and the page size.
+func validateListInput(input store.ListInput) (string, int, error) {
+	if err := (store.ObjectRef{Bucket: input.Bucket, Key: "-"}).Validate(); err != nil {
+		return "", 0, store.ErrInvalidListInput
+	}
+	if input.MaxKeys < 0 || input.MaxKeys > store.MaxListKeys {
+		return "", 0, store.ErrInvalidListInput
+	}
+	startAfter, err := decodeListToken(input.ContinuationToken)
+	if err != nil {
+		return "", 0, err
+	}
+	if input.MaxKeys == 0 {
+		return startAfter, store.MaxListKeys, nil
+	}
+	return startAfter, input.MaxKeys, nil
+}
+
+// Copy stores a new version of Destination with the Source payload.
+func (s *FakeStore) Copy(_ context.Context, input store.CopyInput) (store.ObjectRef, error) {
+	if err := input.Source.Validate(); err != nil {
+		return store.ObjectRef{}, err
+	}
+	if err := validateWrite(input.Destination, input.Condition); err != nil {
+		return store.ObjectRef{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLocked()
+	s.recordLocked(Call{
+		Operation:   OperationCopy,
+		Ref:         input.Destination,
+		Source:      input.Source,
+		ContentType: input.ContentType,
+		Metadata:    cloneMetadata(input.Metadata),
+		Condition:   input.Condition,
+	})
+	if err := s.failureLocked(OperationCopy); err != nil {
+		return store.ObjectRef{}, err
+	}
+
+	source, ok := s.objectLocked(input.Source)
+	if !ok {
+		return store.ObjectRef{}, store.ErrObjectNotFound
+	}
+	if input.SourceIfMatch != "" && source.etag != input.SourceIfMatch {
+		return store.ObjectRef{}, store.ErrPreconditionFailed
+	}
+	contentType, metadata := source.contentType, source.metadata
+	if input.ReplaceMetadata {
+		contentType, metadata = input.ContentType, input.Metadata
+	}
+	return s.writeLocked(input.Destination, cloneBytes(source.payload), contentType, metadata, input.Condition)
+}
+
 // Delete removes the referenced object. An unversioned ref removes all versions for the bucket/key.
 func (s *FakeStore) Delete(_ context.Context, input store.DeleteInput) error {
 	if err := input.Ref.Validate(); err != nil {
@@ -198,6 +421,33 @@ func (s *FakeStore) Delete(_ context.Context, input store.DeleteInput) error {
 	return nil
 }
 
+// writeLocked stores payload as a new version of ref after checking condition
+// against the latest version. payload must already be a private copy.
+func (s *FakeStore) writeLocked(ref store.ObjectRef, payload []byte, contentType string, metadata map[string]string, condition store.WriteCondition) (store.ObjectRef, error) {
+	current, exists := s.objectLocked(ref)
+	if condition.IfNoneMatch == "*" && exists {
+		return store.ObjectRef{}, store.ErrPreconditionFailed
+	}
+	if condition.IfMatch != "" && (!exists || current.etag != condition.IfMatch) {
+		return store.ObjectRef{}, store.ErrPreconditionFailed
+	}
+
+	s.seq++
+	ref.VersionID = fmt.Sprintf("v%020d", s.seq)
+	sum := sha256.Sum256(payload)
+	name := objectName{bucket: ref.Bucket, key: ref.Key}
+	s.latest[name] = ref.VersionID
+	s.objects[objectVersion{name: name, version: ref.VersionID}] = storedObject{
+		ref:          ref,
+		payload:      payload,
+		contentType:  contentType,
+		metadata:     cloneMetadata(metadata),
+		etag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		lastModified: fakeEpoch.Add(time.Duration(s.seq) * time.Second),
+	}
+	return ref, nil
+}
+
 func (s *FakeStore) ensureLocked() {
 	if s.latest == nil {
 		s.latest = make(map[objectName]string)
@@ -231,6 +481,41 @@ func (s *FakeStore) failureLocked(operation Operation) error {
 	return s.failures[operation]
 }
 
+func (o storedObject) info() store.ObjectInfo {
+	return store.ObjectInfo{
+		Ref:          o.ref,
+		Size:         int64(len(o.payload)),
+		ETag:         o.etag,
+		LastModified: o.lastModified,
+		ContentType:  o.contentType,
+		Metadata:     cloneMetadata(o.metadata),
+	}
+}
+
+func validateWrite(ref store.ObjectRef, condition store.WriteCondition) error {
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+	if ref.VersionID != "" {
+		return store.ErrInvalidObjectRef
+	}
+	if condition.IfNoneMatch != "" && (condition.IfNoneMatch != "*" || condition.IfMatch != "") {
+		return store.ErrInvalidWriteCondition
+	}
+	return nil
+}
+
+func decodeListToken(token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) == 0 {
+		return "", store.ErrInvalidListInput
+	}
+	return string(raw), nil
+}
+
 func cloneCall(call Call) Call {
 	call.Payload = cloneBytes(call.Payload)
 	call.Metadata = cloneMetadata(call.Metadata)
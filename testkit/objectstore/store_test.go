@@ -3,6 +3,9 @@ package objectstore
 import (
 	"context"
 	"errors"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
 
 	store "github.com/theory-cloud/apptheory/v3/pkg/objectstore"
@@ -142,3 +145,98 @@ func TestFakeStoreFailClosedValidation(t *testing.T) {
 		t.Fatalf("Delete() invalid ref error = %v, want ErrInvalidObjectRef", err)
 	}
 }
+
+func TestFakeStoreStreamingHeadAndCopy(t *testing.T) {
+	fake := NewStore()
+	ctx := context.Background()
+	source := store.ObjectRef{Bucket: "bucket-a", Key: "source"}
+
+	sourceRef, err := fake.PutReader(ctx, store.PutReaderInput{
+		Ref:         source,
+		Body:        strings.NewReader("payload"),
+		ContentType: "text/plain",
+		Condition:   store.WriteCondition{IfNoneMatch: "*"},
+	})
+	if err != nil {
+		t.Fatalf("PutReader() error = %v", err)
+	}
+	if _, err := fake.Put(ctx, store.PutInput{Ref: source, Condition: store.WriteCondition{IfNoneMatch: "*"}}); !errors.Is(err, store.ErrPreconditionFailed) {
+		t.Fatalf("Put() existing If-None-Match error = %v, want ErrPreconditionFailed", err)
+	}
+
+	info, err := fake.Head(ctx, store.HeadInput{Ref: source})
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	if info.Ref != sourceRef || info.Size != 7 || info.ETag == "" || info.ContentType != "text/plain" || info.LastModified.IsZero() {
+		t.Fatalf("Head() = %#v", info)
+	}
+
+	out, err := fake.GetReader(ctx, store.GetInput{Ref: source, MaxBytes: 7})
+	if err != nil {
+		t.Fatalf("GetReader() error = %v", err)
+	}
+	body, err := io.ReadAll(out.Body)
+	if err != nil || string(body) != "payload" || out.Info.ETag != info.ETag {
+		t.Fatalf("GetReader() = %q, %#v, %v", body, out.Info, err)
+	}
+
+	dest := store.ObjectRef{Bucket: "bucket-b", Key: "dest"}
+	if _, err := fake.Copy(ctx, store.CopyInput{Source: source, Destination: dest, SourceIfMatch: `"stale"`}); !errors.Is(err, store.ErrPreconditionFailed) {
+		t.Fatalf("Copy() stale source error = %v, want ErrPreconditionFailed", err)
+	}
+	destRef, err := fake.Copy(ctx, store.CopyInput{Source: source, Destination: dest, SourceIfMatch: info.ETag})
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	got, err := fake.Get(ctx, store.GetInput{Ref: destRef, MaxBytes: 7})
+	if err != nil || string(got.Payload) != "payload" || got.ContentType != "text/plain" || got.ETag != info.ETag {
+		t.Fatalf("Get() copy = %#v, %v", got, err)
+	}
+	if _, err := fake.Put(ctx, store.PutInput{Ref: dest, Payload: []byte("v2"), Condition: store.WriteCondition{IfMatch: info.ETag}}); err != nil {
+		t.Fatalf("Put() If-Match error = %v", err)
+	}
+
+	calls := fake.Calls()
+	last := calls[len(calls)-1]
+	if last.Operation != OperationPut || last.Condition.IfMatch != info.ETag {
+		t.Fatalf("last call = %#v", last)
+	}
+	copyCall := calls[len(calls)-3]
+	if copyCall.Operation != OperationCopy || copyCall.Source != source || copyCall.Ref != dest {
+		t.Fatalf("copy call = %#v", copyCall)
+	}
+}
+
+func TestFakeStoreList(t *testing.T) {
+	fake := NewStore()
+	ctx := context.Background()
+	for _, key := range []string{"logs/a", "logs/b/1", "logs/b/2", "logs/c", "other"} {
+		if _, err := fake.Put(ctx, store.PutInput{Ref: store.ObjectRef{Bucket: "bucket-a", Key: key}, Payload: []byte(key)}); err != nil {
+			t.Fatalf("Put(%q) error = %v", key, err)
+		}
+	}
+
+	page, err := fake.List(ctx, store.ListInput{Bucket: "bucket-a", Prefix: "logs/", Delimiter: "/", MaxKeys: 2})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page.Objects) != 1 || page.Objects[0].Ref.Key != "logs/a" || !reflect.DeepEqual(page.CommonPrefixes, []string{"logs/b/"}) || !page.IsTruncated {
+		t.Fatalf("List() first page = %#v", page)
+	}
+	page, err = fake.List(ctx, store.ListInput{Bucket: "bucket-a", Prefix: "logs/", Delimiter: "/", MaxKeys: 2, ContinuationToken: page.NextContinuationToken})
+	if err != nil {
+		t.Fatalf("List() second page error = %v", err)
+	}
+	if len(page.Objects) != 1 || page.Objects[0].Ref.Key != "logs/c" || len(page.CommonPrefixes) != 0 || page.IsTruncated {
+		t.Fatalf("List() second page = %#v", page)
+	}
+
+	if _, err := fake.List(ctx, store.ListInput{Bucket: "bucket-a", ContinuationToken: "!"}); !errors.Is(err, store.ErrInvalidListInput) {
+		t.Fatalf("List() bad token error = %v, want ErrInvalidListInput", err)
+	}
+	calls := fake.Calls()
+	if last := calls[len(calls)-1]; last.Operation != OperationList || last.Ref.Bucket != "bucket-a" || last.Prefix != "logs/" {
+		t.Fatalf("List call = %#v", last)
+	}
+}